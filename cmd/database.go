@@ -0,0 +1,135 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/hexya-erp/hexya/src/dbmanager"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var databaseCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage Hexya databases",
+	Long:  `Create, duplicate, drop, backup and restore Hexya databases.`,
+}
+
+var dbListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List databases",
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		dbs, err := dbmanager.List()
+		exitOnError(err)
+		for _, db := range dbs {
+			fmt.Println(db)
+		}
+	},
+}
+
+var dbCreateCmd = &cobra.Command{
+	Use:   "create name",
+	Short: "Create a new database",
+	Long: `Create a new database and install the project modules in it.
+This command must be run from the project directory.
+Use the --demo flag to load demo data into the new database.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		exitOnError(dbmanager.Create(args[0], viper.GetBool("Demo")))
+	},
+}
+
+var dbDuplicateCmd = &cobra.Command{
+	Use:   "duplicate name newName",
+	Short: "Duplicate a database",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		exitOnError(dbmanager.Duplicate(args[0], args[1]))
+	},
+}
+
+var dbDropCmd = &cobra.Command{
+	Use:   "drop name",
+	Short: "Drop a database and its filestore",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		exitOnError(dbmanager.Drop(args[0]))
+	},
+}
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup name file",
+	Short: "Backup a database and its filestore to a zip file",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		f, err := os.Create(args[1])
+		exitOnError(err)
+		defer f.Close()
+		exitOnError(dbmanager.Backup(args[0], f))
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore name file",
+	Short: "Restore a database from a zip backup file",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+		f, err := os.Open(args[1])
+		exitOnError(err)
+		defer f.Close()
+		fi, err := f.Stat()
+		exitOnError(err)
+		exitOnError(dbmanager.Restore(args[0], f, fi.Size()))
+	},
+}
+
+// exitOnError prints the given error and exits if it is not nil
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// initDatabase installs the project modules in the given database by running
+// the 'updatedb' command of the current executable on this database.
+func initDatabase(dbName string, demo bool) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, "updatedb")
+	cmd.Env = append(os.Environ(), "HEXYA_DB_NAME="+dbName, "HEXYA_DEMO="+strconv.FormatBool(demo))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func init() {
+	dbmanager.InitDatabase = initDatabase
+	databaseCmd.AddCommand(dbListCmd, dbCreateCmd, dbDuplicateCmd, dbDropCmd, dbBackupCmd, dbRestoreCmd)
+	HexyaCmd.AddCommand(databaseCmd)
+}
@@ -25,6 +25,7 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/hexya-erp/hexya/src/server"
 	"github.com/hexya-erp/hexya/src/tools/generate"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -37,8 +38,9 @@ const (
 	// ResDirRel is the name of the resources directory (relative to the current project root)
 	ResDirRel = "res"
 	// TempEmpty is the name of the temporary go file in the pool directory for startup
-	TempEmpty     = "temp.go"
-	startFileName = "main.go"
+	TempEmpty      = "temp.go"
+	startFileName  = "main.go"
+	assetsFileName = "assets.go"
 )
 
 var generateCmd = &cobra.Command{
@@ -48,27 +50,41 @@ var generateCmd = &cobra.Command{
 This command also :
 - creates the resource directory by symlinking all modules resources into the project directory.
 - creates or updates the main.go of the project.
+- with --embed-assets, creates the assets.go file of the project which embeds the
+  static files, resources and translations of the modules in the binary.
 This command must be rerun after each source code modification, including module import.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) == 0 {
 			fmt.Println("You must specify the project directory ")
 			os.Exit(1)
 		}
+		if scaffoldTests && !testEnabled {
+			fmt.Println("--scaffold-tests can only be used with --test")
+			os.Exit(1)
+		}
 		runGenerate(args[0])
 	},
 }
 
 var symlinkDirs = []string{"static", "data", "demo", "resources", "i18n"}
 
+// embeddedDirs are the resource directories that are embedded in the binary
+// with --embed-assets. Data and demo CSV files are always read from the disk.
+var embeddedDirs = []string{"static", "resources", "i18n", server.ManifestsDir}
+
 var (
 	generateEmptyPool bool
 	testEnabled       bool
+	scaffoldTests     bool
+	embedAssets       bool
 )
 
 func init() {
 	HexyaCmd.AddCommand(generateCmd)
 	generateCmd.Flags().BoolVarP(&testEnabled, "test", "t", false, "Generate pool for testing a module. When set projectDir must be the source directory of the module.")
 	generateCmd.Flags().BoolVar(&generateEmptyPool, "empty", false, "Generate an empty pool package and returns. When set, resource dir and main.go are untouched.")
+	generateCmd.Flags().BoolVar(&scaffoldTests, "scaffold-tests", false, "With --test, create CRUD test scaffolds for the models of the module. Existing test files are not overwritten.")
+	generateCmd.Flags().BoolVar(&embedAssets, "embed-assets", false, "Embed the static files, resources and translations of the modules in the binary, so that it can run without resource directory. Files found in the resource directory still override embedded ones.")
 }
 
 func runGenerate(projectDir string) {
@@ -115,11 +131,18 @@ func runGenerate(projectDir string) {
 	}
 	fmt.Println("Ok")
 
-	fmt.Print("5/5 - Creating main.go in project...")
 	if testEnabled {
-		fmt.Println("SKIPPED")
+		fmt.Print("5/5 - Creating test scaffolds in module...")
+		if scaffoldTests {
+			generate.CreateTestScaffolds(mods, projectDir)
+			fmt.Println("Ok")
+		} else {
+			fmt.Println("SKIPPED")
+		}
 	} else {
+		fmt.Print("5/5 - Creating main.go in project...")
 		createStartFile(projectDir, targetPaths)
+		createAssetsFile(projectDir)
 		fmt.Println("Ok")
 	}
 
@@ -139,6 +162,20 @@ func createStartFile(projectDir string, targetPaths []string) {
 	generate.CreateFileFromTemplate(sfn, startFileTemplate, tmplData)
 }
 
+// createAssetsFile creates the file embedding the module assets in the
+// project if --embed-assets is set, and removes a previously generated
+// one otherwise.
+func createAssetsFile(projectDir string) {
+	afn := filepath.Join(projectDir, assetsFileName)
+	if !embedAssets {
+		if content, err := ioutil.ReadFile(afn); err == nil && bytes.Contains(content, []byte(generate.AssetFSPath)) {
+			os.Remove(afn)
+		}
+		return
+	}
+	generate.CreateAssetsFile(filepath.Join(projectDir, ResDirRel), embeddedDirs, afn)
+}
+
 func createSymlinks(modules []*generate.ModuleInfo, projectDir string) {
 	cleanModuleSymlinks(projectDir)
 	for _, m := range modules {
@@ -233,7 +270,8 @@ func writeFileFromTemplate(fileName string, tmpl *template.Template, data interf
 }
 
 // createModuleSymlinks create the symlinks of the given module in the
-// project directory.
+// project directory. The manifest of the module, if any, is linked in
+// the manifests directory as <module>.json.
 func createModuleSymlinks(mod *generate.ModuleInfo, projectDir string) {
 	for _, dir := range symlinkDirs {
 		mDir := filepath.Dir(mod.GoFiles[0])
@@ -250,12 +288,24 @@ func createModuleSymlinks(mod *generate.ModuleInfo, projectDir string) {
 			panic(err)
 		}
 	}
+	manifestPath := filepath.Join(filepath.Dir(mod.GoFiles[0]), server.ManifestFileName)
+	if _, err := os.Stat(manifestPath); err != nil {
+		// Module without manifest
+		return
+	}
+	dstPath := filepath.Join(projectDir, ResDirRel, server.ManifestsDir)
+	if err := os.MkdirAll(dstPath, 0755); err != nil {
+		panic(err)
+	}
+	if err := os.Symlink(manifestPath, filepath.Join(dstPath, mod.Name+".json")); err != nil {
+		panic(err)
+	}
 }
 
 // cleanModuleSymlinks removes all symlinks in the server symlink directories.
 // Note that this function actually removes and recreates the symlink directories.
 func cleanModuleSymlinks(projectDir string) {
-	for _, dir := range symlinkDirs {
+	for _, dir := range append(symlinkDirs, server.ManifestsDir) {
 		dirPath := filepath.Join(projectDir, ResDirRel, dir)
 		os.RemoveAll(dirPath)
 		os.Mkdir(dirPath, 0775)
@@ -321,6 +371,29 @@ func main() {
 	}
 	hexyaCmd.AddCommand(updateDBCmd)
 
+	var graphCmd = &cobra.Command{
+		Use:   "graph",
+		Short: "Export the entity relationship diagram of the models",
+		Long: "Export the entity relationship diagram of the models in the Graphviz DOT format or in the PlantUML format.",
+		Run: func(c *cobra.Command, args []string) {
+			cmd.Graph()
+		},
+	}
+	hexyaCmd.AddCommand(graphCmd)
+	cmd.SetGraphFlags(graphCmd)
+
+	var scrambleDBCmd = &cobra.Command{
+		Use:   "scramble-db dbName",
+		Short: "Overwrite the personal data of a database with fake data",
+		Long: "Overwrite the personal data declared by the modules in the database 'dbName' with fake data.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			cmd.ScrambleDB(args[0])
+		},
+	}
+	hexyaCmd.AddCommand(scrambleDBCmd)
+	cmd.SetScrambleDBFlags(scrambleDBCmd)
+
 	cobra.OnInitialize(cmd.InitConfig)
 
 	if err := hexyaCmd.Execute(); err != nil {
@@ -0,0 +1,98 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Output formats of the graph command
+const (
+	GraphFormatDOT      = "dot"
+	GraphFormatPlantUML = "plantuml"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [projectDir]",
+	Short: "Export the entity relationship diagram of the models",
+	Long: `Export the entity relationship diagram of the models of the project in 'projectDir'.
+Tables are colored by module and linked by their foreign keys and many2many link tables.
+The diagram is written in the Graphviz DOT format or in the PlantUML format.
+If projectDir is omitted, defaults to the current directory.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectDir := "."
+		if len(args) > 0 {
+			projectDir = args[0]
+		}
+		runProject(projectDir, "graph", append(graphArgs(), args...))
+	},
+}
+
+// SetGraphFlags adds the graph flags to the given command.
+func SetGraphFlags(c *cobra.Command) {
+	c.PersistentFlags().StringP("format", "f", GraphFormatDOT, "Output format of the diagram. Should be one of 'dot' or 'plantuml'")
+	viper.BindPFlag("Graph.Format", c.PersistentFlags().Lookup("format"))
+	c.PersistentFlags().StringSlice("module", []string{}, "Comma separated list of module names whose models should be exported. Defaults to all modules")
+	viper.BindPFlag("Graph.Modules", c.PersistentFlags().Lookup("module"))
+	c.PersistentFlags().String("prefix", "", "Only export the models whose name starts with this prefix")
+	viper.BindPFlag("Graph.Prefix", c.PersistentFlags().Lookup("prefix"))
+	c.PersistentFlags().String("output", "", "File to which the diagram is written. Defaults to stdout")
+	viper.BindPFlag("Graph.Output", c.PersistentFlags().Lookup("output"))
+}
+
+// graphArgs returns the command line flags to pass to the graph command of the project
+func graphArgs() []string {
+	res := []string{"--format", viper.GetString("Graph.Format"), "--prefix", viper.GetString("Graph.Prefix")}
+	if modules := viper.GetStringSlice("Graph.Modules"); len(modules) > 0 {
+		res = append(res, "--module", strings.Join(modules, ","))
+	}
+	if output := viper.GetString("Graph.Output"); output != "" {
+		res = append(res, "--output", output)
+	}
+	return res
+}
+
+// Graph exports the entity relationship diagram of the models. It is meant
+// to be called from a project start file which imports all the project's module.
+//
+// Only the models metadata are bootstrapped, without database connection
+// nor server setup.
+func Graph() {
+	setupLogger()
+	defer log.Sync()
+	server.PreInitModules()
+	models.BootStrapMetadata()
+	graph := models.Registry.RelationGraph().Filter(viper.GetStringSlice("Graph.Modules"), viper.GetString("Graph.Prefix"))
+	var diagram string
+	switch format := viper.GetString("Graph.Format"); format {
+	case GraphFormatDOT:
+		diagram = graph.EntityRelationDOT()
+	case GraphFormatPlantUML:
+		diagram = graph.EntityRelationPlantUML()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown graph format '%s'\n", format)
+		os.Exit(1)
+	}
+	output := viper.GetString("Graph.Output")
+	if output == "" {
+		fmt.Print(diagram)
+		return
+	}
+	if err := ioutil.WriteFile(output, []byte(diagram), 0644); err != nil {
+		log.Panic("Unable to write diagram", "file", output, "error", err)
+	}
+}
+
+func init() {
+	SetGraphFlags(graphCmd)
+	HexyaCmd.AddCommand(graphCmd)
+}
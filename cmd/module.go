@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"text/template"
 
+	"github.com/hexya-erp/hexya/src/server"
 	"github.com/spf13/cobra"
 )
 
@@ -131,11 +132,35 @@ You should use this command before committing your work.`,
 	},
 }
 
+var moduleLintCmd = &cobra.Command{
+	Use:   "lint [MODULE_DIR]",
+	Short: "Check the module manifest and data files",
+	Long: `Check the module in MODULE_DIR (defaults to the current directory):
+- the manifest.json file exists and is valid,
+- the data and demo files declared in the manifest exist and are valid CSV files,
+- the XML files of the resources directory and the PO files of the i18n directory parse.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		moduleDir := "."
+		if len(args) > 0 {
+			moduleDir = args[0]
+		}
+		problems := server.LintModule(moduleDir)
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("Module is valid")
+	},
+}
+
 func init() {
 	HexyaCmd.AddCommand(moduleCmd)
 	moduleCmd.AddCommand(moduleInitCmd)
 	moduleCmd.AddCommand(moduleNewCmd)
 	moduleCmd.AddCommand(moduleCleanCmd)
+	moduleCmd.AddCommand(moduleLintCmd)
 }
 
 var hexyaGoTmpl = template.Must(template.New("").Parse(`
@@ -0,0 +1,52 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hexya-erp/hexya/src/tools/generate"
+	"github.com/spf13/cobra"
+)
+
+var scaffoldCmd = &cobra.Command{
+	Use:   "scaffold MODULE_NAME",
+	Short: "Create the skeleton of a new module",
+	Long: `Create the skeleton of a new module in a MODULE_NAME subdirectory of the current directory (or of --dir).
+The skeleton holds:
+- manifest.json which describes the module,
+- 000hexya.go which registers the module and declares its user group,
+- a first model with its fields and a method,
+- security.go which grants access to the model to the user group,
+- resources/MODULE_NAME.xml with the views, action and menus of the model,
+- demo data for the model,
+- the other standard module directories.
+
+The model is named after the module unless --model is set.
+Add the module to the project modules and run 'hexya generate' before building.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			fmt.Println("You must specify a module name.")
+			os.Exit(1)
+		}
+		if err := generate.ScaffoldModule(scaffoldDir, args[0], scaffoldModel); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Module created in", filepath.Join(scaffoldDir, args[0]))
+	},
+}
+
+var (
+	scaffoldDir   string
+	scaffoldModel string
+)
+
+func init() {
+	HexyaCmd.AddCommand(scaffoldCmd)
+	scaffoldCmd.Flags().StringVar(&scaffoldDir, "dir", ".", "Directory in which the module directory is created.")
+	scaffoldCmd.Flags().StringVar(&scaffoldModel, "model", "", "Name of the first model of the module. Defaults to the module name in camel case.")
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package cmd
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var scrambleDBCmd = &cobra.Command{
+	Use:   "scramble-db dbName [projectDir]",
+	Short: "Overwrite the personal data of a database with fake data",
+	Long: `Overwrite the personal data declared by the modules in the database 'dbName'
+with fake data, so that a copy of a production database can be safely handed to developers.
+The database is modified in place: run this command on a duplicate of the production database.
+If projectDir is omitted, defaults to the current directory.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		projectDir := "."
+		if len(args) > 1 {
+			projectDir = args[1]
+		}
+		runProject(projectDir, "scramble-db", append(scrambleDBArgs(), args[0]))
+	},
+}
+
+// SetScrambleDBFlags adds the scramble-db flags to the given command.
+func SetScrambleDBFlags(c *cobra.Command) {
+	c.PersistentFlags().Int64("seed", 0, "Seed of the generated fake data. Defaults to a random seed")
+	viper.BindPFlag("Scramble.Seed", c.PersistentFlags().Lookup("seed"))
+}
+
+// scrambleDBArgs returns the command line flags to pass to the scramble-db command of the project
+func scrambleDBArgs() []string {
+	return []string{"--seed", strconv.FormatInt(viper.GetInt64("Scramble.Seed"), 10)}
+}
+
+// ScrambleDB overwrites the personal data of the given database with fake
+// data (see models.ScrambleDatabase). It is meant to be called from a project
+// start file which imports all the project's module.
+//
+// The database name is given explicitly instead of being read from the
+// configuration so that a production database is not scrambled by mistake.
+func ScrambleDB(dbName string) {
+	setupLogger()
+	defer log.Sync()
+	resourceDir, err := filepath.Abs(viper.GetString("ResourceDir"))
+	if err != nil {
+		log.Panic("Unable to find Resource directory", "error", err)
+	}
+	server.ResourceDir = resourceDir
+	server.LoadManifests(resourceDir)
+	server.PreInit()
+	viper.Set("DB.Name", dbName)
+	connectToDB()
+	models.BootStrap()
+	seed := viper.GetInt64("Scramble.Seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	var count int
+	err = models.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		count = models.ScrambleDatabase(env, seed)
+	})
+	if err != nil {
+		log.Panic("Unable to scramble database", "database", dbName, "error", err)
+	}
+	log.Info("Database scrambled successfully", "database", dbName, "values", count, "seed", seed)
+}
+
+func init() {
+	SetScrambleDBFlags(scrambleDBCmd)
+	HexyaCmd.AddCommand(scrambleDBCmd)
+}
@@ -15,22 +15,42 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
-	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 	"github.com/hexya-erp/hexya/src/actions"
+	// Register the graph and pivot views data controller
+	_ "github.com/hexya-erp/hexya/src/analytics"
+	// Register the API documentation controllers
+	_ "github.com/hexya-erp/hexya/src/apidoc"
+	"github.com/hexya-erp/hexya/src/auth"
 	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/dbmanager"
+	// Register the filestore garbage collector and content controller
+	_ "github.com/hexya-erp/hexya/src/filestore"
 	"github.com/hexya-erp/hexya/src/i18n"
+	// Register the per database languages and translations
+	_ "github.com/hexya-erp/hexya/src/languages"
+	// Register the installed modules tracking
+	_ "github.com/hexya-erp/hexya/src/lifecycle"
 	"github.com/hexya-erp/hexya/src/menus"
 	"github.com/hexya-erp/hexya/src/models"
+	// Register the report controller
+	_ "github.com/hexya-erp/hexya/src/reports"
 	"github.com/hexya-erp/hexya/src/server"
+	// Register the search panel controllers
+	_ "github.com/hexya-erp/hexya/src/searchpanel"
 	"github.com/hexya-erp/hexya/src/templates"
 	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/hexya-erp/hexya/src/tools/startup"
 	"github.com/hexya-erp/hexya/src/views"
+	// Register the web client bootstrap controllers
+	_ "github.com/hexya-erp/hexya/src/webclient"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -76,16 +96,20 @@ func StartServer() {
 		log.Panic("Unable to find Resource directory", "error", err)
 	}
 	server.ResourceDir = resourceDir
+	server.LoadManifests(resourceDir)
 	server.PreInit()
+	dbmanager.SetupMultiTenancy(server.GetServer())
 	connectToDB()
+	setupCallDepth()
 	i18n.BootStrap()
 	models.BootStrap()
+	setupSharedCaches()
+	auth.BootStrap()
 	models.RunWorkerLoop()
-	server.LoadTranslations(resourceDir, i18n.Langs)
-	server.LoadInternalResources(resourceDir)
-	views.BootStrap()
-	templates.BootStrap()
-	actions.BootStrap()
+	loadResources(resourceDir)
+	if viper.GetBool("Server.Dev") {
+		server.StartDevMode(resourceDir)
+	}
 	controllers.BootStrap()
 	menus.BootStrap()
 	server.PostInit()
@@ -104,34 +128,90 @@ func StartServer() {
 	}
 }
 
+// loadResources loads the translations and the internal resources of all
+// modules and bootstraps views, templates and actions. Independent stages
+// run concurrently.
+func loadResources(resourceDir string) {
+	startup.Run(
+		startup.Stage{Name: "translations", Run: func() {
+			server.LoadTranslations(resourceDir, i18n.Langs)
+		}},
+		startup.Stage{Name: "resources", Run: func() {
+			server.LoadInternalResources(resourceDir)
+		}},
+		startup.Stage{Name: "views", Depends: []string{"translations", "resources"}, Run: views.BootStrap},
+		startup.Stage{Name: "templates", Depends: []string{"translations", "resources"}, Run: templates.BootStrap},
+		startup.Stage{Name: "actions", Depends: []string{"views"}, Run: actions.BootStrap},
+	)
+}
+
 // setupLogger initializes the logger
 func setupLogger() {
 	logging.Initialize()
 	log = logging.GetLogger("init")
 }
 
-// setupDebug updates the server for debugging if Debug is enabled
+// setupDebug updates the server for debugging if Debug is enabled.
+//
+// Profiling endpoints are also registered in debug mode by server.PreInit.
 func setupDebug() {
 	if !viper.GetBool("Debug") {
 		return
 	}
 	gin.SetMode(gin.DebugMode)
-	pprof.Register(server.GetServer().Engine)
 }
 
 // connectToDB creates the connection to the database
 func connectToDB() {
-	models.DBConnect(viper.GetString("DB.Driver"), models.ConnectionParams{
-		Host:     viper.GetString("DB.Host"),
-		Port:     viper.GetString("DB.Port"),
-		User:     viper.GetString("DB.User"),
-		Password: viper.GetString("DB.Password"),
-		DBName:   viper.GetString("DB.Name"),
-		SSLMode:  viper.GetString("DB.SSLMode"),
-		SSLCert:  viper.GetString("DB.SSLCert"),
-		SSLKey:   viper.GetString("DB.SSLKey"),
-		SSLCA:    viper.GetString("DB.SSLCA"),
-	})
+	setEncryptionKeys()
+	models.DBConnect(viper.GetString("DB.Driver"), dbmanager.ConnectionParams(viper.GetString("DB.Name")))
+}
+
+// setupCallDepth sets the maximum number of nested method
+// calls from the Models.MaxCallDepth configuration.
+func setupCallDepth() {
+	if depth := viper.GetInt("Models.MaxCallDepth"); depth > 0 {
+		models.SetMaxCallDepth(depth)
+	}
+}
+
+// setupSharedCaches enables the shared cache of the models listed in the
+// Models.SharedCache configuration, e.g.
+//
+//	Models:
+//	  SharedCache:
+//	    Currency:
+//	      MaxEntries: 1000
+//	      TTL: 10m
+func setupSharedCaches() {
+	for _, model := range models.Registry.All() {
+		key := fmt.Sprintf("Models.SharedCache.%s", model.Name())
+		if !viper.IsSet(key) {
+			continue
+		}
+		maxEntries := viper.GetInt(key + ".MaxEntries")
+		ttl := viper.GetDuration(key + ".TTL")
+		model.EnableSharedCache(maxEntries, ttl)
+		log.Info("Shared cache enabled", "model", model.Name(), "maxEntries", maxEntries, "ttl", ttl)
+	}
+}
+
+// setEncryptionKeys sets the keys used to encrypt the values of encrypted
+// fields from the DB.EncryptionKeys configuration, which is a list of base64
+// encoded keys. The first key is the current one, the others are old keys
+// kept to decrypt values after a key rotation.
+func setEncryptionKeys() {
+	var keys [][]byte
+	for _, k := range viper.GetStringSlice("DB.EncryptionKeys") {
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			log.Panic("Invalid key in DB.EncryptionKeys", "error", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := models.SetEncryptionKeys(keys...); err != nil {
+		log.Panic("Unable to set encryption keys", "error", err)
+	}
 }
 
 // SetServerFlags adds the server flags to the given command.
@@ -148,6 +228,56 @@ func SetServerFlags(c *cobra.Command) {
 	viper.BindPFlag("Server.Certificate", c.PersistentFlags().Lookup("certificate"))
 	c.PersistentFlags().StringP("private-key", "K", "", "Private key file for HTTPS.")
 	viper.BindPFlag("Server.PrivateKey", c.PersistentFlags().Lookup("private-key"))
+	c.PersistentFlags().StringSlice("trusted-proxies", []string{}, "Comma separated list of IP addresses or CIDR networks of the reverse proxies whose X-Forwarded-For and X-Real-Ip headers are trusted.")
+	viper.BindPFlag("Server.TrustedProxies", c.PersistentFlags().Lookup("trusted-proxies"))
+	c.PersistentFlags().Int("rate-limit", 0, "Maximum number of requests per client IP per rate limit period. 0 means no limit.")
+	viper.BindPFlag("Server.RateLimit", c.PersistentFlags().Lookup("rate-limit"))
+	c.PersistentFlags().Int("user-rate-limit", 0, "Maximum number of requests per logged in user per rate limit period. 0 means no limit.")
+	viper.BindPFlag("Server.UserRateLimit", c.PersistentFlags().Lookup("user-rate-limit"))
+	c.PersistentFlags().Duration("rate-limit-period", time.Minute, "Period over which rate limits are computed.")
+	viper.BindPFlag("Server.RateLimitPeriod", c.PersistentFlags().Lookup("rate-limit-period"))
+	c.PersistentFlags().Int("rate-limit-burst", 0, "Maximum number of requests that can be made at once. Defaults to the rate limit.")
+	viper.BindPFlag("Server.RateLimitBurst", c.PersistentFlags().Lookup("rate-limit-burst"))
+	c.PersistentFlags().Int64("max-body-size", 0, "Maximum size in bytes of request bodies. 0 means no limit.")
+	viper.BindPFlag("Server.MaxBodySize", c.PersistentFlags().Lookup("max-body-size"))
+	c.PersistentFlags().Duration("idempotency-key-ttl", time.Hour, "Duration during which the responses of RPC calls with an Idempotency-Key header are replayed.")
+	viper.BindPFlag("Server.IdempotencyKeyTTL", c.PersistentFlags().Lookup("idempotency-key-ttl"))
+	c.PersistentFlags().Int("idempotency-max-keys", 10000, "Maximum number of Idempotency-Key headers whose responses are kept. The oldest responses are evicted first.")
+	viper.BindPFlag("Server.IdempotencyMaxKeys", c.PersistentFlags().Lookup("idempotency-max-keys"))
+	c.PersistentFlags().Duration("session-idle-timeout", 0, "Duration without request after which sessions are closed. 0 means no timeout.")
+	viper.BindPFlag("Server.Session.IdleTimeout", c.PersistentFlags().Lookup("session-idle-timeout"))
+	c.PersistentFlags().Duration("session-absolute-timeout", 0, "Duration after login at which sessions are closed. 0 means no timeout.")
+	viper.BindPFlag("Server.Session.AbsoluteTimeout", c.PersistentFlags().Lookup("session-absolute-timeout"))
+	c.PersistentFlags().Bool("session-bind-ip", false, "Reject the requests of sessions that do not come from the login IP address.")
+	viper.BindPFlag("Server.Session.BindIP", c.PersistentFlags().Lookup("session-bind-ip"))
+	c.PersistentFlags().StringSlice("session-allowed-ips", []string{}, "Comma separated list of IP addresses or CIDR networks from which users can connect.")
+	viper.BindPFlag("Server.Session.AllowedIPs", c.PersistentFlags().Lookup("session-allowed-ips"))
+	c.PersistentFlags().Int("session-max-sessions", 0, "Maximum number of concurrent sessions per user. 0 means no limit.")
+	viper.BindPFlag("Server.Session.MaxSessions", c.PersistentFlags().Lookup("session-max-sessions"))
+	c.PersistentFlags().Bool("profiling", false, "Expose the pprof profiling endpoints under /debug/pprof.")
+	viper.BindPFlag("Server.Profiling", c.PersistentFlags().Lookup("profiling"))
+	c.PersistentFlags().StringSlice("profiling-allowed-ips", []string{"127.0.0.1", "::1"}, "Comma separated list of IP addresses or CIDR networks allowed to access the profiling endpoints.")
+	viper.BindPFlag("Server.ProfilingAllowedIPs", c.PersistentFlags().Lookup("profiling-allowed-ips"))
+	c.PersistentFlags().Bool("method-profiling", false, "Record the time spent in each layer of model methods, exposed under /debug/pprof/methods when profiling is enabled.")
+	viper.BindPFlag("Server.MethodProfiling", c.PersistentFlags().Lookup("method-profiling"))
+	c.PersistentFlags().String("tracing-endpoint", "", "URL of the OpenTelemetry HTTP endpoint to which traces are exported (e.g. http://localhost:4318/v1/traces). Tracing is disabled if empty.")
+	viper.BindPFlag("Tracing.Endpoint", c.PersistentFlags().Lookup("tracing-endpoint"))
+	c.PersistentFlags().Bool("csrf", false, "Check CSRF tokens on unsafe requests of authenticated sessions.")
+	viper.BindPFlag("Server.CSRFProtection", c.PersistentFlags().Lookup("csrf"))
+	c.PersistentFlags().StringSlice("cors-origins", []string{}, "Comma separated list of origins allowed to make cross origin requests. '*' allows all origins.")
+	viper.BindPFlag("Server.CORS.AllowedOrigins", c.PersistentFlags().Lookup("cors-origins"))
+	c.PersistentFlags().Bool("cors-credentials", false, "Allow cross origin requests to send cookies.")
+	viper.BindPFlag("Server.CORS.AllowCredentials", c.PersistentFlags().Lookup("cors-credentials"))
+	c.PersistentFlags().String("master-password", "", "Password to access the database manager endpoints. Can be a pbkdf2 hash. Leave empty to disable the database manager.")
+	viper.BindPFlag("Server.MasterPassword", c.PersistentFlags().Lookup("master-password"))
+	c.PersistentFlags().Bool("multi-tenant", false, "Serve several databases from this server. The database is selected with db-filter or the 'db' query parameter.")
+	viper.BindPFlag("Server.MultiTenant", c.PersistentFlags().Lookup("multi-tenant"))
+	c.PersistentFlags().String("db-filter", "", "Select the database from the request hostname in multi tenant mode. '%h' is replaced by the hostname and '%d' by its first subdomain.")
+	viper.BindPFlag("Server.DBFilter", c.PersistentFlags().Lookup("db-filter"))
+	c.PersistentFlags().Bool("dev", false, "Enable dev mode: templates are not cached and views, actions, menus, templates and data files are reloaded when they change. Do not use in production.")
+	viper.BindPFlag("Server.Dev", c.PersistentFlags().Lookup("dev"))
+	c.PersistentFlags().String("plugin-dir", "", "Directory from which module plugins (.so files built with -buildmode=plugin) are loaded at startup. Plugins are disabled if empty.")
+	viper.BindPFlag("Server.PluginDir", c.PersistentFlags().Lookup("plugin-dir"))
 }
 
 func runCommand(c string, args ...string) error {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
 	"github.com/hexya-erp/hexya/src/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,15 +31,28 @@ var updateDBCmd = &cobra.Command{
 func UpdateDB() {
 	setupLogger()
 	setupDebug()
-	server.PreInit()
-	connectToDB()
-	models.BootStrap()
-	models.SyncDatabase()
 	resourceDir, err := filepath.Abs(viper.GetString("ResourceDir"))
 	if err != nil {
 		log.Panic("Unable to find Resource directory", "error", err)
 	}
 	server.ResourceDir = resourceDir
+	// Manifests are loaded first so that auto install modules
+	// are resolved before the modules are initialized.
+	server.LoadManifests(resourceDir)
+	server.PreInit()
+	connectToDB()
+	models.BootStrap()
+	models.SyncDatabase()
+	if len(viper.GetStringSlice("DB.EncryptionKeys")) > 0 {
+		err := models.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			if count := models.ReencryptFields(env); count > 0 {
+				log.Info("Encrypted field values with the current key", "count", count)
+			}
+		})
+		if err != nil {
+			log.Panic("Unable to encrypt field values", "error", err)
+		}
+	}
 	server.LoadDataRecords(resourceDir)
 	if viper.GetBool("Demo") {
 		log.Info("Demo mode detected: loading demo data")
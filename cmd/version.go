@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/hexya-erp/hexya/src/server"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +26,7 @@ var versionCmd = &cobra.Command{
 	Short: "Print the version Hexya",
 	Long:  `Print the version of the Hexya framework`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Hexya version 0.1")
+		fmt.Println("Hexya version", server.Version)
 	},
 }
 
@@ -4,6 +4,7 @@ go 1.13
 
 require (
 	github.com/beevik/etree v1.1.0
+	github.com/boombuler/barcode v1.0.1
 	github.com/cockroachdb/apd v1.1.0 // indirect
 	github.com/cockroachdb/apd/v2 v2.0.1
 	github.com/disintegration/imaging v1.6.0
@@ -24,6 +25,7 @@ require (
 	github.com/pelletier/go-toml v1.6.0 // indirect
 	github.com/smartystreets/goconvey v0.0.0-20190306220146-200a235640ff
 	github.com/spf13/afero v1.2.2 // indirect
+	github.com/spf13/cast v1.3.0
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
@@ -32,7 +34,7 @@ require (
 	go.uber.org/multierr v1.4.0 // indirect
 	go.uber.org/zap v1.12.0
 	golang.org/x/crypto v0.0.0-20191107222254-f4817d981bb6
-	golang.org/x/net v0.0.0-20191108063844-7e6e90b9ea88 // indirect
+	golang.org/x/net v0.0.0-20191108063844-7e6e90b9ea88
 	golang.org/x/sys v0.0.0-20191105231009-c1f44814a5cd // indirect
 	golang.org/x/tools v0.0.0-20191107235519-f7ea15e60b12
 	gopkg.in/yaml.v2 v2.2.5 // indirect
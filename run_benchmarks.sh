@@ -0,0 +1,32 @@
+#!/usr/bin/env bash
+
+# Compares the ORM benchmarks of the working tree with those of a git reference
+# and fails if operations per second regressed beyond a threshold.
+#
+# Usage: ./run_benchmarks.sh <base-ref> [threshold-percent]
+
+set -e
+
+BASE_REF=${1:?usage: $0 <base-ref> [threshold-percent]}
+THRESHOLD=${2:-10}
+COUNT=${BENCH_COUNT:-5}
+COMPOSE_FILE=src/benchmarks/docker-compose.yml
+WORK_DIR=$(mktemp -d)
+
+cleanup() {
+    git worktree remove --force "$WORK_DIR/base" > /dev/null 2>&1 || true
+    docker-compose -f "$COMPOSE_FILE" down > /dev/null
+    rm -rf "$WORK_DIR"
+}
+trap cleanup EXIT
+
+docker-compose -f "$COMPOSE_FILE" up -d
+until docker-compose -f "$COMPOSE_FILE" exec -T postgres pg_isready -U hexya > /dev/null 2>&1; do
+    sleep 1
+done
+
+git worktree add "$WORK_DIR/base" "$BASE_REF" > /dev/null
+(cd "$WORK_DIR/base" && go test ./src/benchmarks -run XXX -bench . -count "$COUNT") > "$WORK_DIR/old.txt"
+go test ./src/benchmarks -run XXX -bench . -count "$COUNT" > "$WORK_DIR/new.txt"
+
+go run ./src/benchmarks/benchgate -threshold "$THRESHOLD" "$WORK_DIR/old.txt" "$WORK_DIR/new.txt"
@@ -0,0 +1,131 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package accesslog
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+	"github.com/spf13/viper"
+)
+
+// Keys of the call info in the request context
+const (
+	callModelKey  = "accesslog_model"
+	callMethodKey = "accesslog_method"
+	callIDsKey    = "accesslog_ids"
+)
+
+// An Entry is the access log entry of a request
+type Entry struct {
+	Time       time.Time `json:"time"`
+	DB         string    `json:"db,omitempty"`
+	UID        int64     `json:"uid,omitempty"`
+	RealUID    int64     `json:"real_uid,omitempty"`
+	IP         string    `json:"ip"`
+	HTTPMethod string    `json:"http_method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	Duration   float64   `json:"duration_ms"`
+	Model      string    `json:"model,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	IDs        []int64   `json:"ids,omitempty"`
+}
+
+// IsCall returns true if this entry is the entry of a call to a model method
+func (e Entry) IsCall() bool {
+	return e.Model != ""
+}
+
+// A Sink writes access log entries
+type Sink interface {
+	// Write writes the given entry. It must not block the request
+	// for long and must handle its errors itself.
+	Write(entry Entry)
+}
+
+// SetCallInfo sets the model, the method and the record ids called by the
+// current request, for controllers whose parameters cannot be extracted
+// automatically.
+func SetCallInfo(ctx *server.Context, model, method string, ids []int64) {
+	ctx.Set(callModelKey, model)
+	ctx.Set(callMethodKey, method)
+	ctx.Set(callIDsKey, ids)
+}
+
+// Middleware returns a middleware that writes an access log entry
+// to the given sinks for each request, except for static files.
+func Middleware(sinks ...Sink) server.HandlerFunc {
+	return func(c *server.Context) {
+		if strings.Contains(c.Request.URL.Path, "/static/") {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		call, _ := c.RPCCall()
+		c.Next()
+		uid, realUID := sessionUIDs(c)
+		entry := Entry{
+			Time:       start.UTC(),
+			DB:         c.DBName(),
+			UID:        uid,
+			RealUID:    realUID,
+			IP:         c.ClientIP(),
+			HTTPMethod: c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			Duration:   float64(time.Since(start)) / float64(time.Millisecond),
+			Model:      call.Model,
+			Method:     call.Method,
+			IDs:        call.IDs,
+		}
+		if m, ok := c.Get(callModelKey); ok {
+			entry.Model = m.(string)
+			entry.Method = c.GetString(callMethodKey)
+			ids, _ := c.Get(callIDsKey)
+			entry.IDs, _ = ids.([]int64)
+		}
+		for _, sink := range sinks {
+			sink.Write(entry)
+		}
+	}
+}
+
+// sessionUIDs returns the id of the user logged in the session of the request
+// and the id of the administrator impersonating this user if any.
+// Ids are 0 if they are not set.
+func sessionUIDs(c *server.Context) (int64, int64) {
+	if _, ok := c.Get(sessions.DefaultKey); !ok {
+		return 0, 0
+	}
+	uid, _ := nbutils.CastToInteger(c.Session().Get("uid"))
+	return uid, c.RealUID()
+}
+
+// setupAccessLog adds the access log middleware to the server
+// with the sinks set in the configuration.
+func setupAccessLog() {
+	var sinks []Sink
+	if fileName := viper.GetString("AccessLog.File"); fileName != "" {
+		maxSize := viper.GetInt64("AccessLog.MaxSize")
+		if maxSize <= 0 {
+			maxSize = 100
+		}
+		maxBackups := viper.GetInt("AccessLog.MaxBackups")
+		if maxBackups <= 0 {
+			maxBackups = 5
+		}
+		sinks = append(sinks, NewFileSink(fileName, maxSize<<20, maxBackups))
+	}
+	if viper.GetBool("AccessLog.Database") {
+		sinks = append(sinks, NewDatabaseSink())
+	}
+	if len(sinks) == 0 {
+		return
+	}
+	server.GetServer().AddMiddleWare(Middleware(sinks...))
+}
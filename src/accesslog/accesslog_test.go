@@ -0,0 +1,115 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/server"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type memorySink struct {
+	entries []Entry
+}
+
+func (ms *memorySink) Write(entry Entry) {
+	ms.entries = append(ms.entries, entry)
+}
+
+func TestMiddleware(t *testing.T) {
+	Convey("Testing the access log middleware", t, func() {
+		sink := new(memorySink)
+		srv := &server.Server{Engine: gin.New()}
+		srv.AddMiddleWare(Middleware(sink))
+		grp := srv.Group("/")
+		var body string
+		grp.POST("/web/dataset/call_kw", func(c *server.Context) {
+			data, _ := ioutil.ReadAll(c.Request.Body)
+			body = string(data)
+			c.String(http.StatusOK, "ok")
+		})
+		grp.POST("/custom", func(c *server.Context) {
+			SetCallInfo(c, "Partner", "Unlink", []int64{4})
+			c.String(http.StatusOK, "ok")
+		})
+		grp.GET("/web/static/app.js", func(c *server.Context) {
+			c.String(http.StatusOK, "ok")
+		})
+		Convey("RPC calls should be logged with their model, method and ids", func() {
+			payload := `{"jsonrpc":"2.0","id":1,"params":{"model":"Partner","method":"Write","args":[[1,2],{"name":"x"}]}}`
+			req := httptest.NewRequest(http.MethodPost, "/web/dataset/call_kw", strings.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			srv.ServeHTTP(httptest.NewRecorder(), req)
+			So(body, ShouldEqual, payload)
+			So(sink.entries, ShouldHaveLength, 1)
+			entry := sink.entries[0]
+			So(entry.IsCall(), ShouldBeTrue)
+			So(entry.Model, ShouldEqual, "Partner")
+			So(entry.Method, ShouldEqual, "Write")
+			So(entry.IDs, ShouldResemble, []int64{1, 2})
+			So(entry.Path, ShouldEqual, "/web/dataset/call_kw")
+			So(entry.Status, ShouldEqual, http.StatusOK)
+		})
+		Convey("Call info set by controllers should be logged", func() {
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/custom", nil))
+			So(sink.entries, ShouldHaveLength, 1)
+			So(sink.entries[0].Model, ShouldEqual, "Partner")
+			So(sink.entries[0].Method, ShouldEqual, "Unlink")
+			So(sink.entries[0].IDs, ShouldResemble, []int64{4})
+		})
+		Convey("Other requests should be logged without call info, except static files", func() {
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/web/static/app.js", nil))
+			So(sink.entries, ShouldBeEmpty)
+			srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+			So(sink.entries, ShouldHaveLength, 1)
+			So(sink.entries[0].IsCall(), ShouldBeFalse)
+			So(sink.entries[0].Status, ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	Convey("Testing the file sink", t, func() {
+		dir, err := ioutil.TempDir("", "accesslog")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		fileName := filepath.Join(dir, "access.log")
+		line, _ := json.Marshal(Entry{Model: "Partner", Method: "Read"})
+		fs := NewFileSink(fileName, int64(len(line)+1)*2, 2)
+		for i := 0; i < 7; i++ {
+			fs.Write(Entry{Model: "Partner", Method: "Read"})
+		}
+		So(fs.Close(), ShouldBeNil)
+		for _, name := range []string{fileName, fileName + ".1", fileName + ".2"} {
+			file, err := os.Open(name)
+			So(err, ShouldBeNil)
+			var lines int
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				var entry Entry
+				So(json.Unmarshal(scanner.Bytes(), &entry), ShouldBeNil)
+				So(entry.Method, ShouldEqual, "Read")
+				lines++
+			}
+			file.Close()
+			if name == fileName {
+				So(lines, ShouldEqual, 1)
+			} else {
+				So(lines, ShouldEqual, 2)
+			}
+		}
+		_, err = os.Stat(fileName + ".3")
+		So(os.IsNotExist(err), ShouldBeTrue)
+	})
+}
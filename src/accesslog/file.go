@@ -0,0 +1,122 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// A FileSink appends access log entries as JSON lines to a file,
+// which is rotated when it reaches a maximum size.
+//
+// Rotated files are renamed with a numbered suffix, .1 being the most
+// recent one. Files beyond the maximum number of backups are removed.
+type FileSink struct {
+	sync.Mutex
+	fileName   string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileSink returns a FileSink writing to the given file, which is
+// rotated when it reaches maxSize bytes, keeping maxBackups old files.
+func NewFileSink(fileName string, maxSize int64, maxBackups int) *FileSink {
+	return &FileSink{
+		fileName:   fileName,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+}
+
+// Write appends the given entry to the file of this sink
+func (fs *FileSink) Write(entry Entry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Warn("Unable to marshal access log entry", "error", err)
+		return
+	}
+	line = append(line, '\n')
+	fs.Lock()
+	defer fs.Unlock()
+	if err := fs.open(); err != nil {
+		log.Warn("Unable to open access log file", "file", fs.fileName, "error", err)
+		return
+	}
+	if fs.size > 0 && fs.size+int64(len(line)) > fs.maxSize {
+		if err := fs.rotate(); err != nil {
+			log.Warn("Unable to rotate access log file", "file", fs.fileName, "error", err)
+			return
+		}
+	}
+	n, err := fs.file.Write(line)
+	fs.size += int64(n)
+	if err != nil {
+		log.Warn("Unable to write access log entry", "file", fs.fileName, "error", err)
+	}
+}
+
+// open opens the file of this sink if it is not already open.
+// fs must be locked.
+func (fs *FileSink) open() error {
+	if fs.file != nil {
+		return nil
+	}
+	file, err := os.OpenFile(fs.fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	fs.file = file
+	fs.size = info.Size()
+	return nil
+}
+
+// rotate closes the current file, shifts the backups and opens a new file.
+// fs must be locked.
+func (fs *FileSink) rotate() error {
+	if err := fs.file.Close(); err != nil {
+		return err
+	}
+	fs.file = nil
+	os.Remove(fs.backupName(fs.maxBackups))
+	for i := fs.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(fs.backupName(i), fs.backupName(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if fs.maxBackups > 0 {
+		if err := os.Rename(fs.fileName, fs.backupName(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(fs.fileName); err != nil {
+		return err
+	}
+	return fs.open()
+}
+
+// backupName returns the name of the i-th backup file
+func (fs *FileSink) backupName(i int) string {
+	return fmt.Sprintf("%s.%d", fs.fileName, i)
+}
+
+// Close closes the file of this sink
+func (fs *FileSink) Close() error {
+	fs.Lock()
+	defer fs.Unlock()
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
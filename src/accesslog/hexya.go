@@ -0,0 +1,43 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package accesslog is a Hexya module that records an access log entry for
+// each request, with the user, the client IP, and for RPC calls the model,
+// the method and the record ids that have been called, for security reviews.
+//
+// Entries are written according to the configuration:
+//
+// - AccessLog.File: path of a file to which entries are appended as JSON
+// lines. The file is rotated when it reaches AccessLog.MaxSize megabytes
+// (defaults to 100), keeping AccessLog.MaxBackups old files (defaults to 5).
+//
+// - AccessLog.Database: if true, entries are also stored as AccessLogEntry
+// records, so that they can be searched from the application. Only the
+// entries of RPC calls are stored in the database.
+//
+// The model, method and ids of JSON-RPC requests whose params have the
+// form {"model": "...", "method": "...", "args": [ids, ...]} are extracted
+// automatically. Controllers with other parameters can set them with
+// SetCallInfo.
+package accesslog
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "accesslog"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("accesslog")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: setupAccessLog,
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package accesslog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// entryModel is the name of the model of the access log entries
+const entryModel = "AccessLogEntry"
+
+// databaseQueueSize is the number of entries that can wait to be stored
+// in the database. Entries are dropped when the queue is full.
+const databaseQueueSize = 1000
+
+func declareModels() {
+	entry := models.NewModel(entryModel)
+	entry.SetDefaultOrder("ID desc")
+	entry.AddFields(map[string]models.FieldDefinition{
+		"Date":       fields.DateTime{Required: true, Index: true},
+		"UserID":     fields.Integer{String: "User ID", Index: true},
+		"RealUserID": fields.Integer{String: "Impersonating User ID", Help: "ID of the administrator impersonating the user"},
+		"IP":         fields.Char{String: "IP Address", Index: true},
+		"HTTPMethod": fields.Char{String: "HTTP Method"},
+		"Path":       fields.Char{},
+		"Status":     fields.Integer{},
+		"Duration":   fields.Float{String: "Duration (ms)"},
+		"ResModel":   fields.Char{String: "Model", Index: true},
+		"Method":     fields.Char{Index: true},
+		"ResIDs":     fields.Char{String: "Record IDs", Help: "Comma separated IDs of the records on which the method was called"},
+	})
+}
+
+// A DatabaseSink stores the entries of RPC calls as AccessLogEntry
+// records in the database of the request.
+//
+// Entries are stored asynchronously so as not to slow down requests.
+type DatabaseSink struct {
+	entries chan Entry
+}
+
+// NewDatabaseSink returns a new DatabaseSink and starts storing its entries
+func NewDatabaseSink() *DatabaseSink {
+	ds := &DatabaseSink{
+		entries: make(chan Entry, databaseQueueSize),
+	}
+	go ds.run()
+	return ds
+}
+
+// Write queues the given entry to be stored if it is the entry of a call
+func (ds *DatabaseSink) Write(entry Entry) {
+	if !entry.IsCall() {
+		return
+	}
+	select {
+	case ds.entries <- entry:
+	default:
+		log.Warn("Access log queue is full, dropping entry", "uid", entry.UID, "model", entry.Model, "method", entry.Method)
+	}
+}
+
+// run stores the queued entries in the database
+func (ds *DatabaseSink) run() {
+	for entry := range ds.entries {
+		err := models.ExecuteInTenantEnvironment(entry.DB, security.SuperUserID, func(env models.Environment) {
+			storeEntry(env, entry)
+		})
+		if err != nil {
+			log.Warn("Unable to store access log entry", "uid", entry.UID, "model", entry.Model, "method", entry.Method, "error", err)
+		}
+	}
+}
+
+// storeEntry creates an AccessLogEntry record for the given entry
+func storeEntry(env models.Environment, entry Entry) {
+	ids := make([]string, len(entry.IDs))
+	for i, id := range entry.IDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	rs := env.Pool(entryModel)
+	mi := rs.Model()
+	rs.Call("Create", models.NewModelData(mi).
+		Set(mi.FieldName("Date"), dates.DateTime{Time: entry.Time}).
+		Set(mi.FieldName("UserID"), entry.UID).
+		Set(mi.FieldName("RealUserID"), entry.RealUID).
+		Set(mi.FieldName("IP"), entry.IP).
+		Set(mi.FieldName("HTTPMethod"), entry.HTTPMethod).
+		Set(mi.FieldName("Path"), entry.Path).
+		Set(mi.FieldName("Status"), int64(entry.Status)).
+		Set(mi.FieldName("Duration"), entry.Duration).
+		Set(mi.FieldName("ResModel"), entry.Model).
+		Set(mi.FieldName("Method"), entry.Method).
+		Set(mi.FieldName("ResIDs"), strings.Join(ids, ",")))
+}
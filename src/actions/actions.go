@@ -38,8 +38,37 @@ const (
 	ActionServer      ActionType = "ir.actions.server"
 	ActionClient      ActionType = "ir.actions.client"
 	ActionCloseWindow ActionType = "ir.actions.act_window_close"
+	ActionReport      ActionType = "ir.actions.report"
+	ActionDashboard   ActionType = "ir.actions.dashboard"
 )
 
+// ReportType defines the output format of a report action
+type ReportType string
+
+// Report types
+const (
+	ReportTypePDF  ReportType = "qweb-pdf"
+	ReportTypeHTML ReportType = "qweb-html"
+	ReportTypeCSV  ReportType = "csv"
+	ReportTypeXLSX ReportType = "xlsx"
+)
+
+// A ReportSheet is a sheet of a spreadsheet report
+type ReportSheet struct {
+	Name    string         `json:"name" xml:"name,attr"`
+	Columns []ReportColumn `json:"columns" xml:"column"`
+}
+
+// A ReportColumn is a column of a spreadsheet report sheet. Field is the
+// path of the field of the report's model displayed in the column. If
+// Aggregate is set to one of sum, avg, min, max or count, the aggregated
+// value of the column is displayed below the records.
+type ReportColumn struct {
+	Field     string `json:"field" xml:"field,attr"`
+	String    string `json:"string" xml:"string,attr"`
+	Aggregate string `json:"aggregate" xml:"aggregate,attr"`
+}
+
 // ActionViewType defines the type of view of an action
 type ActionViewType string
 
@@ -151,6 +180,7 @@ type Collection struct {
 	actions     map[string]*Action
 	actionsByID map[int64]*Action
 	links       map[string][]*Action
+	reports     map[string][]*Action
 }
 
 // NewCollection returns a pointer to a new
@@ -160,6 +190,7 @@ func NewCollection() *Collection {
 		actions:     make(map[string]*Action),
 		actionsByID: make(map[int64]*Action),
 		links:       make(map[string][]*Action),
+		reports:     make(map[string][]*Action),
 	}
 	return &res
 }
@@ -178,6 +209,9 @@ func (ar *Collection) Add(a *Action) {
 	ar.actions[a.XMLID] = a
 	ar.actionsByID[a.ID] = a
 	ar.links[a.SrcModel] = append(ar.links[a.SrcModel], a)
+	if a.Type == ActionReport && a.BindingModel != "" {
+		ar.reports[a.BindingModel] = append(ar.reports[a.BindingModel], a)
+	}
 }
 
 // GetByXMLID returns the Action with the given xmlid
@@ -228,6 +262,35 @@ func (ar *Collection) GetActionLinksForModel(modelName string) []*Action {
 	return ar.links[modelName]
 }
 
+// GetReportsForModel returns the list of report actions
+// bound to the model with the given name
+func (ar *Collection) GetReportsForModel(modelName string) []*Action {
+	return ar.reports[modelName]
+}
+
+// A Toolbar holds the actions that are proposed in the
+// views of a model, in the format expected by the client.
+type Toolbar struct {
+	Print  []*Action `json:"print"`
+	Action []*Action `json:"action"`
+}
+
+// GetToolbar returns the Toolbar of the model with the given name.
+// Print entries are the reports bound to the model.
+func (ar *Collection) GetToolbar(modelName string) Toolbar {
+	res := Toolbar{
+		Print:  make([]*Action, 0),
+		Action: make([]*Action, 0),
+	}
+	res.Print = append(res.Print, ar.reports[modelName]...)
+	for _, a := range ar.links[modelName] {
+		if a.Type != ActionReport {
+			res.Action = append(res.Action, a)
+		}
+	}
+	return res
+}
+
 // LoadFromEtree reads the action given etree.Element, creates or updates the action
 // and adds it to the given Collection if it not already.
 func (ar *Collection) LoadFromEtree(element *etree.Element) {
@@ -279,6 +342,11 @@ type Action struct {
 	Context      *types.Context         `json:"context" xml:"context,attr"`
 	Flags        map[string]interface{} `json:"flags"`
 	Tag          string                 `json:"tag"`
+	ReportName   string                 `json:"report_name" xml:"report_name,attr"`
+	ReportType   ReportType             `json:"report_type" xml:"report_type,attr"`
+	PaperFormat  string                 `json:"paperformat_id" xml:"paperformat,attr"`
+	BindingModel string                 `json:"binding_model_id" xml:"binding_model,attr"`
+	Sheets       []ReportSheet          `json:"-" xml:"sheet"`
 	names        map[string]string
 }
 
@@ -298,7 +366,74 @@ func (a *Action) Sanitize() {
 	switch a.Type {
 	case ActionActWindow:
 		a.sanitizeActWindow()
+	case ActionReport:
+		a.sanitizeReport()
+	case ActionDashboard:
+		a.sanitizeDashboard()
+	}
+}
+
+// sanitizeDashboard checks that the view of a dashboard action is a valid
+// board view and sets it as the only view of the action.
+func (a *Action) sanitizeDashboard() {
+	a.Help = a.HelpXML.Content
+	if a.View.IsNull() {
+		log.Panic("Dashboard action without board view", "action", a.XMLID)
+	}
+	view := views.Registry.GetByID(a.View.ID())
+	if view == nil {
+		log.Panic("Unknown board view of dashboard action", "action", a.XMLID, "view", a.View.ID())
 	}
+	if _, err := view.Board(""); err != nil {
+		log.Panic("Invalid board view of dashboard action", "action", a.XMLID, "error", err)
+	}
+	if a.Model == "" {
+		a.Model = view.Model
+	}
+	a.Views = []views.ViewTuple{{ID: view.ID, Type: views.ViewTypeBoard}}
+	a.ViewMode = string(views.ViewTypeBoard)
+}
+
+// Board returns the layout of the board view of this dashboard action in
+// the given language. It panics if this action is not a dashboard action.
+func (a *Action) Board(lang string) *views.Board {
+	if a.Type != ActionDashboard {
+		log.Panic("Action is not a dashboard", "action", a.XMLID, "type", a.Type)
+	}
+	board, err := views.Registry.GetByID(a.View.ID()).Board(lang)
+	if err != nil {
+		log.Panic("Invalid board view of dashboard action", "action", a.XMLID, "error", err)
+	}
+	return board
+}
+
+// CheckBoard returns an error if one of the actions of the
+// given board does not exist or is not an act_window action.
+func (ar *Collection) CheckBoard(board *views.Board) error {
+	if err := board.Validate(); err != nil {
+		return err
+	}
+	for _, id := range board.ActionIDs() {
+		action := ar.GetByXMLID(id)
+		switch {
+		case action == nil:
+			return fmt.Errorf("unknown action '%s' in board", id)
+		case action.Type != ActionActWindow:
+			return fmt.Errorf("action '%s' of board is not an %s action", id, ActionActWindow)
+		}
+	}
+	return nil
+}
+
+// sanitizeReport sets the default values of report actions
+func (a *Action) sanitizeReport() {
+	if a.ReportType == "" {
+		a.ReportType = ReportTypePDF
+	}
+	if a.Model == "" {
+		a.Model = a.BindingModel
+	}
+	a.Help = a.HelpXML.Content
 }
 
 // sanitizeActWindow makes the necessary updates to action definitions. In particular:
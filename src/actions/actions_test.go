@@ -183,3 +183,103 @@ func TestActions(t *testing.T) {
 		So(string(d), ShouldEqual, "false")
 	})
 }
+
+var reportDef = `
+<action id="my_report" name="Partner Card" type="ir.actions.report" report_name="partner_card"
+        binding_model="Partner" paperformat="base_paperformat_a4"/>
+`
+
+func TestReportActions(t *testing.T) {
+	Convey("Testing report actions", t, func() {
+		report, _ := xmlutils.XMLToElement(reportDef)
+		LoadFromEtree(report)
+		action := Registry.MustGetByXMLID("my_report")
+		action.Sanitize()
+		So(action.Type, ShouldEqual, ActionReport)
+		So(action.ReportName, ShouldEqual, "partner_card")
+		So(action.ReportType, ShouldEqual, ReportTypePDF)
+		So(action.PaperFormat, ShouldEqual, "base_paperformat_a4")
+		So(action.Model, ShouldEqual, "Partner")
+		So(Registry.GetReportsForModel("Partner"), ShouldResemble, []*Action{action})
+		toolbar := Registry.GetToolbar("Partner")
+		So(toolbar.Print, ShouldResemble, []*Action{action})
+		So(toolbar.Action, ShouldBeEmpty)
+		So(Registry.GetToolbar("User").Action, ShouldHaveLength, 1)
+	})
+	Convey("Testing spreadsheet report actions", t, func() {
+		report, _ := xmlutils.XMLToElement(xlsxReportDef)
+		LoadFromEtree(report)
+		action := Registry.MustGetByXMLID("my_xlsx_report")
+		action.Sanitize()
+		So(action.ReportType, ShouldEqual, ReportTypeXLSX)
+		So(action.Sheets, ShouldResemble, []ReportSheet{
+			{Name: "Orders", Columns: []ReportColumn{
+				{Field: "Name", String: "Reference"},
+				{Field: "Partner.Name"},
+				{Field: "AmountTotal", String: "Total", Aggregate: "sum"},
+			}},
+		})
+	})
+}
+
+var xlsxReportDef = `
+<action id="my_xlsx_report" name="Orders" type="ir.actions.report" report_name="orders_xlsx"
+        report_type="xlsx" binding_model="Order">
+	<sheet name="Orders">
+		<column field="Name" string="Reference"/>
+		<column field="Partner.Name"/>
+		<column field="AmountTotal" string="Total" aggregate="sum"/>
+	</sheet>
+</action>
+`
+
+func TestToggleActiveAction(t *testing.T) {
+	Convey("Testing archive actions", t, func() {
+		action := toggleActiveAction(models.Registry.MustGet("Partner"))
+		So(action.XMLID, ShouldEqual, "action_toggle_active_partner")
+		So(action.Type, ShouldEqual, ActionServer)
+		So(action.SrcModel, ShouldEqual, "Partner")
+		So(action.Method, ShouldEqual, "ToggleActive")
+		So(action.Groups, ShouldContain, "admin")
+		So(Registry.GetByXMLID("action_toggle_active_partner"), ShouldBeNil)
+	})
+}
+
+var boardDef = `
+<view id="my_board" model="User">
+	<board style="1">
+		<column>
+			<action name="my_action" string="Partners"/>
+		</column>
+	</board>
+</view>
+`
+
+func TestDashboardActions(t *testing.T) {
+	Convey("Testing dashboard actions", t, func() {
+		board, _ := xmlutils.XMLToElement(boardDef)
+		views.LoadFromEtree(board)
+		dashboard, _ := xmlutils.XMLToElement(`<action id="my_dashboard" name="My Dashboard" type="ir.actions.dashboard" view_id="my_board"/>`)
+		LoadFromEtree(dashboard)
+		action := Registry.MustGetByXMLID("my_dashboard")
+		action.Sanitize()
+		So(action.Model, ShouldEqual, "User")
+		So(action.Views, ShouldResemble, []views.ViewTuple{{ID: "my_board", Type: views.ViewTypeBoard}})
+		So(action.Board("").ActionIDs(), ShouldResemble, []string{"my_action"})
+		So(Registry.CheckBoard(action.Board("")), ShouldBeNil)
+		So(func() { Registry.MustGetByXMLID("my_action").Board("") }, ShouldPanic)
+		Convey("Boards may only display existing act_window actions", func() {
+			So(Registry.CheckBoard(&views.Board{Style: "1", Columns: []views.BoardColumn{
+				{Actions: []views.BoardAction{{Action: "unknown_action"}}},
+			}}), ShouldNotBeNil)
+			So(Registry.CheckBoard(&views.Board{Style: "1", Columns: []views.BoardColumn{
+				{Actions: []views.BoardAction{{Action: "my_report"}}},
+			}}), ShouldNotBeNil)
+		})
+		Convey("Dashboard actions without board view should panic", func() {
+			invalid, _ := xmlutils.XMLToElement(`<action id="my_invalid_dashboard" name="Invalid" type="ir.actions.dashboard" view_id="my_id"/>`)
+			LoadFromEtree(invalid)
+			So(Registry.MustGetByXMLID("my_invalid_dashboard").Sanitize, ShouldPanic)
+		})
+	})
+}
@@ -4,7 +4,11 @@
 package actions
 
 import (
+	"fmt"
+
 	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
 	"github.com/hexya-erp/hexya/src/tools/logging"
 )
 
@@ -13,6 +17,7 @@ var log logging.Logger
 // BootStrap actions.
 // This function must be called prior to any access to the actions Registry.
 func BootStrap() {
+	addToggleActiveActions()
 	for _, a := range Registry.actions {
 		a.Sanitize()
 		// Populate translations
@@ -24,9 +29,66 @@ func BootStrap() {
 			a.names[lang] = nameTrans
 		}
 	}
+	// Check boards once all actions are sanitized
+	for _, a := range Registry.actions {
+		if a.Type != ActionDashboard {
+			continue
+		}
+		if err := Registry.CheckBoard(a.Board("")); err != nil {
+			log.Panic("Invalid dashboard action", "action", a.XMLID, "error", err)
+		}
+	}
+}
+
+// toggleActiveAction returns the server action that archives
+// and unarchives the records of the given model.
+//
+// The action is restricted to the groups that can write on the model.
+func toggleActiveAction(model *models.Model) *Action {
+	var groups []string
+	if write, ok := model.Methods().Get("Write"); ok {
+		for _, group := range write.AllowedGroups() {
+			groups = append(groups, group.ID)
+		}
+	}
+	return &Action{
+		XMLID:    fmt.Sprintf("action_toggle_active_%s", model.TableName()),
+		Type:     ActionServer,
+		Name:     "Archive / Unarchive",
+		Model:    model.Name(),
+		SrcModel: model.Name(),
+		Method:   "ToggleActive",
+		Multi:    true,
+		Groups:   groups,
+	}
+}
+
+// addToggleActiveActions binds a toggleActiveAction to all the
+// models with an Active field, so that it appears in their toolbar.
+func addToggleActiveActions() {
+	for _, model := range models.Registry.All() {
+		if model.IsMixin() || model.IsTransient() || model.IsM2MLink() {
+			continue
+		}
+		fi, ok := model.Fields().Get("Active")
+		if !ok || model.FieldsGet(fi)[fi.JSON()].Type != fieldtype.Boolean {
+			continue
+		}
+		action := toggleActiveAction(model)
+		if Registry.GetByXMLID(action.XMLID) != nil {
+			continue
+		}
+		Registry.Add(action)
+	}
+}
+
+// ResetRegistry replaces the Registry by an empty collection,
+// so that actions can be loaded again.
+func ResetRegistry() {
+	Registry = NewCollection()
 }
 
 func init() {
 	log = logging.GetLogger("actions")
-	Registry = NewCollection()
+	ResetRegistry()
 }
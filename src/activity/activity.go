@@ -0,0 +1,173 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package activity
+
+import (
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// States of activities
+const (
+	StateOverdue = "overdue"
+	StateToday   = "today"
+	StatePlanned = "planned"
+	StateDone    = "done"
+)
+
+// Delay units of activity types
+const (
+	DelayDays   = "days"
+	DelayWeeks  = "weeks"
+	DelayMonths = "months"
+)
+
+// State returns the state of a pending activity with the given deadline
+// at the given date, that is StateOverdue, StateToday or StatePlanned.
+func State(deadline, today dates.Date) string {
+	switch {
+	case deadline.Lower(today):
+		return StateOverdue
+	case deadline.Equal(today):
+		return StateToday
+	default:
+		return StatePlanned
+	}
+}
+
+// Deadline returns the deadline of an activity scheduled at the
+// given date with the given delay in the given delay unit.
+func Deadline(from dates.Date, count int, unit string) dates.Date {
+	switch unit {
+	case DelayWeeks:
+		return from.AddWeeks(count)
+	case DelayMonths:
+		return from.AddDate(0, count, 0)
+	default:
+		return from.AddDate(0, 0, count)
+	}
+}
+
+// today returns the current date in the timezone of the user of env
+func today(env models.Environment) dates.Date {
+	location, err := time.LoadLocation(env.Context().GetString("tz"))
+	if err != nil {
+		location = time.UTC
+	}
+	return dates.Now().In(location).ToDate()
+}
+
+// Activities returns the pending activities of the records
+// of the given RecordSet, ordered by deadline.
+func Activities(rs models.RecordSet) models.RecordSet {
+	rc := rs.Collection()
+	activities := rc.Env().Pool("Activity")
+	mi := activities.Model()
+	return activities.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("ResID")).In(rc.Ids()).
+		And().Field(mi.FieldName("Done")).Equals(false))
+}
+
+// ComputeState computes the state of the activity from its deadline
+func activity_ComputeState(rc *models.RecordCollection) *models.ModelData {
+	mi := rc.Model()
+	state := StateDone
+	if !rc.Get(mi.FieldName("Done")).(bool) {
+		state = State(rc.Get(mi.FieldName("DateDeadline")).(dates.Date), today(rc.Env()))
+	}
+	return models.NewModelData(mi).Set(mi.FieldName("State"), state)
+}
+
+// MarkDone marks the activities of this RecordSet as done today
+// with the given feedback, which may be empty.
+func activity_MarkDone(rc *models.RecordCollection, feedback string) bool {
+	mi := rc.Model()
+	data := models.NewModelData(mi).
+		Set(mi.FieldName("Done"), true).
+		Set(mi.FieldName("DoneDate"), today(rc.Env()))
+	if feedback != "" {
+		data.Set(mi.FieldName("Feedback"), feedback)
+	}
+	return rc.Call("Write", data).(bool)
+}
+
+// ComputeActivityState computes the state and the deadline
+// of the next pending activity of the record.
+func activityMixin_ComputeActivityState(rc *models.RecordCollection) *models.ModelData {
+	mi := rc.Model()
+	res := models.NewModelData(mi).
+		Set(mi.FieldName("ActivityState"), "").
+		Set(mi.FieldName("ActivityDateDeadline"), dates.Date{})
+	activities := Activities(rc).Collection()
+	if activities.IsEmpty() {
+		return res
+	}
+	deadline := activities.Records()[0].Get(activities.Model().FieldName("DateDeadline")).(dates.Date)
+	return res.
+		Set(mi.FieldName("ActivityState"), State(deadline, today(rc.Env()))).
+		Set(mi.FieldName("ActivityDateDeadline"), deadline)
+}
+
+// ScheduleActivity plans an activity of the given type on each record of this
+// RecordSet for the user with the given ID.
+//
+// activityTypeID may be 0 for an activity without type. If summary is empty,
+// the summary of the activity type is used. If deadline is zero, it is
+// computed from the delay of the activity type. If userID is 0, the activity
+// is assigned to the current user.
+func activityMixin_ScheduleActivity(rc *models.RecordCollection, activityTypeID int64, summary string, deadline dates.Date, userID int64) {
+	activities := rc.Env().Pool("Activity")
+	mi := activities.Model()
+	data := models.NewModelData(mi).Set(mi.FieldName("ResModel"), rc.ModelName())
+	if activityTypeID != 0 {
+		activityType := rc.Env().Pool("ActivityType").Call("BrowseOne", activityTypeID).(models.RecordSet).Collection()
+		typeMI := activityType.Model()
+		if summary == "" {
+			summary = activityType.Get(typeMI.FieldName("Summary")).(string)
+		}
+		if deadline.IsZero() {
+			deadline = Deadline(today(rc.Env()),
+				int(activityType.Get(typeMI.FieldName("DelayCount")).(int64)),
+				activityType.Get(typeMI.FieldName("DelayUnit")).(string))
+		}
+		data.Set(mi.FieldName("ActivityType"), activityType)
+	}
+	if deadline.IsZero() {
+		deadline = today(rc.Env())
+	}
+	data.Set(mi.FieldName("Summary"), summary).
+		Set(mi.FieldName("DateDeadline"), deadline)
+	if _, ok := mi.Fields().Get("User"); ok {
+		if userID == 0 {
+			userID = rc.Env().Uid()
+		}
+		data.Set(mi.FieldName("User"), rc.Env().Pool("User").Call("BrowseOne", userID))
+	}
+	for _, rec := range rc.Records() {
+		activities.Call("Create", data.Copy().Set(mi.FieldName("ResID"), rec.Ids()[0]))
+	}
+}
+
+// MarkActivitiesDone marks all pending activities of the records
+// of this RecordSet as done with the given feedback.
+func activityMixin_MarkActivitiesDone(rc *models.RecordCollection, feedback string) {
+	Activities(rc).Call("MarkDone", feedback)
+}
+
+// SearchActivityOverdue returns the records of this model
+// that have at least one overdue activity.
+func activityMixin_SearchActivityOverdue(rc *models.RecordCollection) *models.RecordCollection {
+	activities := rc.Env().Pool("Activity")
+	mi := activities.Model()
+	overdue := activities.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("Done")).Equals(false).
+		And().Field(mi.FieldName("DateDeadline")).Lower(today(rc.Env())))
+	var ids []int64
+	for _, act := range overdue.Records() {
+		ids = append(ids, act.Get(mi.FieldName("ResID")).(int64))
+	}
+	return rc.Env().Pool(rc.ModelName()).Search(rc.Model().Field(models.ID).In(ids))
+}
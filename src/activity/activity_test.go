@@ -0,0 +1,91 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package activity
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tests"
+	_ "github.com/lib/pq"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMain(m *testing.M) {
+	task := models.NewModel("ActivityTestTask")
+	task.InheritModel(models.Registry.MustGet("ActivityMixin"))
+	task.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+
+func TestActivities(t *testing.T) {
+	Convey("Testing activities", t, func() {
+		today := dates.ParseDate("2019-03-15")
+		Convey("The state of an activity should depend on its deadline", func() {
+			So(State(dates.ParseDate("2019-03-14"), today), ShouldEqual, StateOverdue)
+			So(State(today, today), ShouldEqual, StateToday)
+			So(State(dates.ParseDate("2019-03-16"), today), ShouldEqual, StatePlanned)
+		})
+		Convey("Deadlines should be computed from the delay", func() {
+			So(Deadline(today, 3, DelayDays), ShouldResemble, dates.ParseDate("2019-03-18"))
+			So(Deadline(today, 2, DelayWeeks), ShouldResemble, dates.ParseDate("2019-03-29"))
+			So(Deadline(today, 1, DelayMonths), ShouldResemble, dates.ParseDate("2019-04-15"))
+		})
+	})
+}
+
+func TestActivityMixin(t *testing.T) {
+	Convey("Testing ActivityMixin", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			tasks := env.Pool("ActivityTestTask")
+			mi := tasks.Model()
+			createTask := func(name string) *models.RecordCollection {
+				return tasks.Call("Create", models.NewModelData(mi).Set(mi.FieldName("Name"), name)).(models.RecordSet).Collection()
+			}
+			types := env.Pool("ActivityType")
+			tmi := types.Model()
+			callType := types.Call("Create", models.NewModelData(tmi).
+				Set(tmi.FieldName("Name"), "Call").
+				Set(tmi.FieldName("Summary"), "Call the customer").
+				Set(tmi.FieldName("DelayCount"), 3).
+				Set(tmi.FieldName("DelayUnit"), DelayDays)).(models.RecordSet).Collection()
+			ami := env.Pool("Activity").Model()
+			now := today(env)
+			planned, late := createTask("Planned task"), createTask("Late task")
+			planned.Call("ScheduleActivity", callType.Ids()[0], "", dates.Date{}, int64(0))
+			late.Call("ScheduleActivity", int64(0), "Send the quotation", now.AddDate(0, 0, -1), int64(0))
+			Convey("Scheduled activities should take their defaults from their type", func() {
+				activities := Activities(planned).Collection()
+				So(activities.Len(), ShouldEqual, 1)
+				So(activities.Get(ami.FieldName("Summary")), ShouldEqual, "Call the customer")
+				So(activities.Get(ami.FieldName("ResModel")), ShouldEqual, "ActivityTestTask")
+				So(activities.Get(ami.FieldName("ResID")), ShouldEqual, planned.Ids()[0])
+				So(activities.Get(ami.FieldName("DateDeadline")).(dates.Date).String(), ShouldEqual, now.AddDate(0, 0, 3).String())
+				So(activities.Get(ami.FieldName("State")), ShouldEqual, StatePlanned)
+				So(planned.Get(mi.FieldName("ActivityState")), ShouldEqual, StatePlanned)
+				So(planned.Get(mi.FieldName("ActivityDateDeadline")).(dates.Date).String(), ShouldEqual, now.AddDate(0, 0, 3).String())
+			})
+			Convey("Records with past activities should be overdue", func() {
+				So(late.Get(mi.FieldName("ActivityState")), ShouldEqual, StateOverdue)
+				So(tasks.Call("SearchActivityOverdue").(models.RecordSet).Ids(), ShouldResemble, late.Ids())
+			})
+			Convey("Activities marked as done should not be pending anymore", func() {
+				activity := Activities(late).Collection()
+				late.Call("MarkActivitiesDone", "Sent by email")
+				So(Activities(late).IsEmpty(), ShouldBeTrue)
+				So(activity.Get(ami.FieldName("Done")), ShouldBeTrue)
+				So(activity.Get(ami.FieldName("Feedback")), ShouldEqual, "Sent by email")
+				So(activity.Get(ami.FieldName("State")), ShouldEqual, StateDone)
+				So(late.Get(mi.FieldName("ActivityState")), ShouldEqual, "")
+				So(tasks.Call("SearchActivityOverdue").(models.RecordSet).IsEmpty(), ShouldBeTrue)
+				So(Activities(planned).Collection().Len(), ShouldEqual, 1)
+			})
+		}), ShouldBeNil)
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package activity
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// ActivityData is the data of an activity sent to the client
+// with the other data of the chatter of a record.
+type ActivityData struct {
+	ID           int64      `json:"id"`
+	ActivityType string     `json:"activity_type"`
+	Icon         string     `json:"icon"`
+	Summary      string     `json:"summary"`
+	Note         string     `json:"note"`
+	DateDeadline dates.Date `json:"date_deadline"`
+	State        string     `json:"state"`
+	UserID       int64      `json:"user_id"`
+	UserName     string     `json:"user_name"`
+}
+
+// ActivitiesData returns the data of the pending activities of the records of rs
+func ActivitiesData(rs models.RecordSet) []ActivityData {
+	activities := Activities(rs).Collection()
+	mi := activities.Model()
+	_, hasUser := mi.Fields().Get("User")
+	res := make([]ActivityData, 0, activities.Len())
+	for _, act := range activities.Records() {
+		data := ActivityData{
+			ID:           act.Ids()[0],
+			Summary:      act.Get(mi.FieldName("Summary")).(string),
+			Note:         act.Get(mi.FieldName("Note")).(string),
+			DateDeadline: act.Get(mi.FieldName("DateDeadline")).(dates.Date),
+			State:        act.Get(mi.FieldName("State")).(string),
+		}
+		if actType := act.Get(mi.FieldName("ActivityType")).(models.RecordSet).Collection(); !actType.IsEmpty() {
+			data.ActivityType = actType.Get(actType.Model().FieldName("Name")).(string)
+			data.Icon = actType.Get(actType.Model().FieldName("Icon")).(string)
+		}
+		if hasUser {
+			if user := act.Get(mi.FieldName("User")).(models.RecordSet).Collection(); !user.IsEmpty() {
+				data.UserID = user.Ids()[0]
+				data.UserName = user.Call("NameGet").(string)
+			}
+		}
+		res = append(res, data)
+	}
+	return res
+}
+
+// getActivities is the controller that returns the pending
+// activities of the record given by its model and ID.
+func getActivities(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		Model string `json:"model"`
+		ResID int64  `json:"res_id"`
+	}
+	ctx.BindRPCParams(&params)
+	mi, ok := models.Registry.Get(params.Model)
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var res []ActivityData
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		// We search the record so that access rules of the model apply
+		res = ActivitiesData(env.Pool(mi.Name()).Search(mi.Field(models.ID).Equals(params.ResID)))
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/activity")
+	grp.AddController(http.MethodPost, "/get_activities", getActivities)
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package activity is a Hexya module that provides activities, that is
+// to-do items with a deadline planned on records for a user.
+//
+// An Activity is linked to its record by the name of the model of the record
+// and its ID. Models on which activities can be planned inherit the
+// ActivityMixin model, which provides the methods to schedule activities,
+// to mark them as done and to search records with overdue activities:
+//
+//	h.Lead().InheritModel(h.ActivityMixin())
+package activity
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "activity"
+)
+
+// addUserField assigns activities to a user, if the User model exists
+func addUserField() {
+	user, ok := models.Registry.Get("User")
+	if !ok {
+		return
+	}
+	models.Registry.MustGet("Activity").AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{String: "Assigned To", RelationModel: user, Index: true,
+			Default: func(env models.Environment) interface{} {
+				return env.Pool("User").Call("BrowseOne", env.Uid())
+			}},
+	})
+}
+
+func init() {
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: addUserField,
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package activity
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+func declareModels() {
+	activityType := models.NewModel("ActivityType")
+	activityType.SetDefaultOrder("Name")
+	activityType.AddFields(map[string]models.FieldDefinition{
+		"Name":    fields.Char{Required: true, Translate: true},
+		"Summary": fields.Char{Translate: true, Help: "Default summary of the activities of this type"},
+		"DelayCount": fields.Integer{String: "Delay",
+			Help: "Default number of delay units between the scheduling and the deadline of activities"},
+		"DelayUnit": fields.Selection{String: "Delay Unit", Required: true,
+			Selection: types.Selection{DelayDays: "Days", DelayWeeks: "Weeks", DelayMonths: "Months"},
+			Default:   models.DefaultValue(DelayDays)},
+		"Icon": fields.Char{Help: "Font awesome icon of the activities of this type (e.g. fa-tasks)"},
+	})
+
+	activity := models.NewModel("Activity")
+	activity.SetDefaultOrder("DateDeadline", "ID")
+	activity.NewMethod("ComputeState", activity_ComputeState)
+	activity.NewMethod("MarkDone", activity_MarkDone)
+	activity.AddFields(map[string]models.FieldDefinition{
+		"ActivityType": fields.Many2One{RelationModel: activityType, OnDelete: models.Restrict},
+		"Summary":      fields.Char{},
+		"Note":         fields.Text{},
+		"DateDeadline": fields.Date{String: "Due Date", Required: true, Index: true,
+			Default: func(env models.Environment) interface{} {
+				return dates.Today()
+			}},
+		"ResModel": fields.Char{String: "Related Document Model", Required: true, Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Required: true, Index: true},
+		"State": fields.Selection{
+			Selection: types.Selection{StateOverdue: "Overdue", StateToday: "Today", StatePlanned: "Planned", StateDone: "Done"},
+			Compute:   activity.Methods().MustGet("ComputeState")},
+		"Done":     fields.Boolean{ReadOnly: true, NoCopy: true},
+		"DoneDate": fields.Date{ReadOnly: true, NoCopy: true},
+		"Feedback": fields.Text{NoCopy: true},
+	})
+
+	activityMixin := models.NewMixinModel("ActivityMixin")
+	activityMixin.NewMethod("ComputeActivityState", activityMixin_ComputeActivityState)
+	activityMixin.NewMethod("ScheduleActivity", activityMixin_ScheduleActivity)
+	activityMixin.NewMethod("MarkActivitiesDone", activityMixin_MarkActivitiesDone)
+	activityMixin.NewMethod("SearchActivityOverdue", activityMixin_SearchActivityOverdue)
+	activityMixin.AddFields(map[string]models.FieldDefinition{
+		"ActivityState": fields.Selection{String: "Activity State",
+			Selection: types.Selection{StateOverdue: "Overdue", StateToday: "Today", StatePlanned: "Planned"},
+			Compute:   activityMixin.Methods().MustGet("ComputeActivityState")},
+		"ActivityDateDeadline": fields.Date{String: "Next Activity Deadline",
+			Compute: activityMixin.Methods().MustGet("ComputeActivityState")},
+	})
+}
@@ -0,0 +1,522 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package analytics computes the datasets of the graph and pivot views on
+// the server, so that clients do not have to read the records to analyse.
+//
+// A dataset is a tree of groups of records: the root group holds all the
+// records matching the domain of the request, and each level of the tree
+// groups the records of its parent by a field. Date and datetime fields are
+// grouped by buckets of a day, a week, a month, a quarter or a year, in the
+// timezone of the user:
+//
+//	{"model": "SaleOrder", "domain": [["State", "=", "sale"]],
+//	 "groupby": ["Partner", "DateOrder:quarter"], "measures": ["__count", "AmountTotal"]}
+//
+// The measures of each group are aggregated with the group operator of their
+// field, which defaults to sum. A comparison period may be given to compute
+// the measures of the same groups on the previous period or on the previous
+// year, together with their variation.
+//
+// Datasets are served at /web/analytics/data.
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/i18n/format"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+)
+
+// CountMeasure is the measure of the number of records of a group
+const CountMeasure = "__count"
+
+// Comparison periods
+const (
+	// ComparePreviousPeriod compares with the period of the same
+	// duration that ends at the start of the compared period
+	ComparePreviousPeriod = "previous_period"
+	// ComparePreviousYear compares with the same period one year before
+	ComparePreviousYear = "previous_year"
+)
+
+var log logging.Logger
+
+// A Request defines the dataset to compute
+type Request struct {
+	Model  string        `json:"model"`
+	Domain []interface{} `json:"domain"`
+	// GroupBy are the fields records are grouped by, from the first level to
+	// the last, given as 'Field' or as 'Field:interval' (see ParseGroupBy).
+	GroupBy []string `json:"groupby"`
+	// Measures are CountMeasure or names of integer and float fields
+	Measures   []string    `json:"measures"`
+	Comparison *Comparison `json:"comparison"`
+}
+
+// A Comparison restricts a dataset to the records the date or datetime Field
+// of which is between Start (included) and Stop (excluded), and compares its
+// measures with those of an earlier Period.
+type Comparison struct {
+	Field  string         `json:"field"`
+	Start  dates.DateTime `json:"start"`
+	Stop   dates.DateTime `json:"stop"`
+	Period string         `json:"period"`
+}
+
+// offset returns the function that shifts a date of the compared period
+// into the period of this comparison.
+func (c *Comparison) offset() func(time.Time) time.Time {
+	if c.Period == ComparePreviousYear {
+		return func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	}
+	duration := c.Stop.Sub(c.Start)
+	return func(t time.Time) time.Time { return t.Add(duration) }
+}
+
+// previous returns the bounds of the compared period
+func (c *Comparison) previous() (dates.DateTime, dates.DateTime) {
+	if c.Period == ComparePreviousYear {
+		return c.Start.AddDate(-1, 0, 0), c.Stop.AddDate(-1, 0, 0)
+	}
+	return c.Start.Add(-c.Stop.Sub(c.Start)), c.Start
+}
+
+// validate returns an error if this comparison is not valid
+func (c *Comparison) validate() error {
+	switch c.Period {
+	case ComparePreviousPeriod, ComparePreviousYear:
+	default:
+		return fmt.Errorf("unknown comparison period '%s'", c.Period)
+	}
+	if !c.Start.Lower(c.Stop) {
+		return errors.New("comparison start should be before stop")
+	}
+	return nil
+}
+
+// A Group is a group of records of a dataset
+type Group struct {
+	// Value is the value of the grouped field shared by the records of the
+	// group: the ID of many2one records, the start date of date buckets or
+	// the value of other fields. It is false for records without value and
+	// nil for the root group.
+	Value  interface{}        `json:"value"`
+	Label  string             `json:"label"`
+	Count  int                `json:"count"`
+	Values map[string]float64 `json:"values"`
+	// ComparisonValues are the measures of the group in the compared period
+	ComparisonValues map[string]float64 `json:"comparison_values,omitempty"`
+	// Variations are the relative variations in percent of the measures
+	// from the compared period. Measures that were zero are omitted.
+	Variations map[string]float64 `json:"variations,omitempty"`
+	Groups     []*Group           `json:"groups,omitempty"`
+	sortKey    interface{}
+	children   map[string]*Group
+	aggregates map[string]*aggregate
+}
+
+// newGroup returns a new empty group
+func newGroup(value interface{}, label string, sortKey interface{}) *Group {
+	return &Group{
+		Value:      value,
+		Label:      label,
+		Values:     make(map[string]float64),
+		sortKey:    sortKey,
+		children:   make(map[string]*Group),
+		aggregates: make(map[string]*aggregate),
+	}
+}
+
+// child returns the subgroup of g with the given key, which is created if
+// it does not exist yet.
+func (g *Group) child(key groupKey) *Group {
+	k := fmt.Sprint(key.value)
+	res, ok := g.children[k]
+	if !ok {
+		res = newGroup(key.value, key.label, key.sortKey)
+		g.children[k] = res
+		g.Groups = append(g.Groups, res)
+	}
+	return res
+}
+
+// add adds a record with the given measures to this group
+func (g *Group) add(measures []measure, values []float64) {
+	g.Count++
+	for i, m := range measures {
+		agg, ok := g.aggregates[m.name]
+		if !ok {
+			agg = &aggregate{operator: m.operator}
+			g.aggregates[m.name] = agg
+		}
+		agg.add(values[i])
+	}
+}
+
+// finalize computes the values of this group and of its subgroups,
+// and sorts the subgroups.
+func (g *Group) finalize() {
+	for name, agg := range g.aggregates {
+		g.Values[name] = agg.value()
+	}
+	sort.SliceStable(g.Groups, func(i, j int) bool {
+		return lessKey(g.Groups[i].sortKey, g.Groups[j].sortKey)
+	})
+	for _, sub := range g.Groups {
+		sub.finalize()
+	}
+}
+
+// compare sets the comparison values and the variations of this group and
+// of its subgroups from the given group of the compared period. Subgroups
+// that only exist in the compared period are added with zero measures.
+func (g *Group) compare(previous *Group, measures []measure) {
+	g.ComparisonValues = make(map[string]float64)
+	g.Variations = make(map[string]float64)
+	for _, m := range measures {
+		var prev float64
+		if previous != nil {
+			prev = previous.Values[m.name]
+		}
+		g.ComparisonValues[m.name] = prev
+		if prev != 0 {
+			g.Variations[m.name] = (g.Values[m.name] - prev) / abs(prev) * 100
+		}
+	}
+	if previous != nil {
+		for k, prevSub := range previous.children {
+			if _, exists := g.children[k]; exists {
+				continue
+			}
+			sub := newGroup(prevSub.Value, prevSub.Label, prevSub.sortKey)
+			for _, m := range measures {
+				sub.Values[m.name] = 0
+			}
+			g.children[k] = sub
+			g.Groups = append(g.Groups, sub)
+		}
+		sort.SliceStable(g.Groups, func(i, j int) bool {
+			return lessKey(g.Groups[i].sortKey, g.Groups[j].sortKey)
+		})
+	}
+	for k, sub := range g.children {
+		var prevSub *Group
+		if previous != nil {
+			prevSub = previous.children[k]
+		}
+		sub.compare(prevSub, measures)
+	}
+}
+
+// An aggregate computes the value of a measure over the records of a group
+type aggregate struct {
+	operator string
+	count    int
+	total    float64
+}
+
+// add adds the given value to this aggregate
+func (a *aggregate) add(value float64) {
+	a.count++
+	switch {
+	case a.count == 1:
+		a.total = value
+	case a.operator == "min" && value < a.total:
+		a.total = value
+	case a.operator == "max" && value > a.total:
+		a.total = value
+	case a.operator != "min" && a.operator != "max":
+		a.total += value
+	}
+}
+
+// value returns the value of this aggregate
+func (a *aggregate) value() float64 {
+	if a.operator == "avg" && a.count > 0 {
+		return a.total / float64(a.count)
+	}
+	return a.total
+}
+
+// abs returns the absolute value of x
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// lessKey returns true if a group with sort key a comes before a group with
+// sort key b. Groups without value (nil sort key) come last.
+func lessKey(a, b interface{}) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	}
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return av < bv
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			return av.Before(bv)
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			return !av && bv
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// A measure is a measure of a dataset
+type measure struct {
+	name     string
+	field    models.FieldName
+	operator string
+}
+
+// A groupBy is a level of grouping of a dataset with its field
+type groupBy struct {
+	GroupBy
+	field models.FieldName
+	info  *models.FieldInfo
+}
+
+// A groupKey identifies the group of a record at one level
+type groupKey struct {
+	value   interface{}
+	label   string
+	sortKey interface{}
+}
+
+// A dataset holds the parameters of the computation of a dataset
+type dataset struct {
+	rs        *models.RecordCollection
+	groupBys  []groupBy
+	measures  []measure
+	formatter *format.Formatter
+	months    [12]string
+	none      string
+}
+
+// Compute returns the root group of the dataset defined by the given
+// request, computed in env so that access rules apply.
+func Compute(env models.Environment, req Request) (*Group, error) {
+	mi, ok := models.Registry.Get(req.Model)
+	if !ok {
+		return nil, fmt.Errorf("unknown model '%s'", req.Model)
+	}
+	rs := env.Pool(mi.Name())
+	infos := rs.Call("FieldsGet", models.FieldsGetArgs{}).(map[string]*models.FieldInfo)
+	ds := dataset{
+		rs:        rs,
+		formatter: format.NewFormatter(env),
+		none:      rs.T("None"),
+		months: [12]string{rs.T("January"), rs.T("February"), rs.T("March"), rs.T("April"),
+			rs.T("May"), rs.T("June"), rs.T("July"), rs.T("August"), rs.T("September"),
+			rs.T("October"), rs.T("November"), rs.T("December")},
+	}
+	for _, spec := range req.GroupBy {
+		gb, err := ds.groupBy(mi, infos, spec)
+		if err != nil {
+			return nil, err
+		}
+		ds.groupBys = append(ds.groupBys, gb)
+	}
+	measures := req.Measures
+	if len(measures) == 0 {
+		measures = []string{CountMeasure}
+	}
+	for _, name := range measures {
+		m, err := ds.measure(mi, infos, name)
+		if err != nil {
+			return nil, err
+		}
+		ds.measures = append(ds.measures, m)
+	}
+	cond, err := models.ParseDomain(mi, req.Domain)
+	if err != nil {
+		return nil, err
+	}
+	if req.Comparison == nil {
+		return ds.compute(cond, "", nil), nil
+	}
+	if err = req.Comparison.validate(); err != nil {
+		return nil, err
+	}
+	fi, ok := mi.Fields().Get(req.Comparison.Field)
+	if !ok {
+		return nil, fmt.Errorf("unknown comparison field '%s' in model %s", req.Comparison.Field, mi.Name())
+	}
+	switch infos[fi.JSON()].Type {
+	case fieldtype.Date, fieldtype.DateTime:
+	default:
+		return nil, fmt.Errorf("comparison field '%s' is not a date field", req.Comparison.Field)
+	}
+	res := ds.compute(periodCondition(mi, cond, fi.Name(), infos[fi.JSON()].Type, req.Comparison.Start, req.Comparison.Stop), "", nil)
+	prevStart, prevStop := req.Comparison.previous()
+	previous := ds.compute(periodCondition(mi, cond, fi.Name(), infos[fi.JSON()].Type, prevStart, prevStop), fi.Name(), req.Comparison.offset())
+	res.compare(previous, ds.measures)
+	return res, nil
+}
+
+// periodCondition returns the given condition restricted to the records
+// the given date field of which is between start (included) and stop
+// (excluded).
+func periodCondition(mi *models.Model, cond *models.Condition, field string, fType fieldtype.Type, start, stop dates.DateTime) *models.Condition {
+	var from, to interface{} = start, stop
+	if fType == fieldtype.Date {
+		from, to = start.ToDate(), stop.ToDate()
+	}
+	fName := mi.FieldName(field)
+	return cond.AndCond(mi.Field(fName).GreaterOrEqual(from).And().Field(fName).Lower(to))
+}
+
+// groupBy returns the groupBy of the given specification
+func (ds *dataset) groupBy(mi *models.Model, infos map[string]*models.FieldInfo, spec string) (groupBy, error) {
+	field := strings.SplitN(spec, ":", 2)[0]
+	fi, ok := mi.Fields().Get(field)
+	if !ok {
+		return groupBy{}, fmt.Errorf("unknown field '%s' in model %s", field, mi.Name())
+	}
+	info := infos[fi.JSON()]
+	gb, err := ParseGroupBy(spec, info.Type)
+	if err != nil {
+		return groupBy{}, err
+	}
+	return groupBy{GroupBy: gb, field: mi.FieldName(fi.Name()), info: info}, nil
+}
+
+// measure returns the measure with the given name
+func (ds *dataset) measure(mi *models.Model, infos map[string]*models.FieldInfo, name string) (measure, error) {
+	if name == CountMeasure {
+		return measure{name: name}, nil
+	}
+	fi, ok := mi.Fields().Get(name)
+	if !ok {
+		return measure{}, fmt.Errorf("unknown measure '%s' in model %s", name, mi.Name())
+	}
+	switch infos[fi.JSON()].Type {
+	case fieldtype.Integer, fieldtype.Float:
+	default:
+		return measure{}, fmt.Errorf("measure '%s' is not a number field", name)
+	}
+	return measure{name: name, field: mi.FieldName(fi.Name()), operator: fi.Describe().GroupOperator}, nil
+}
+
+// compute returns the root group of the records matching cond.
+//
+// If shift is not nil, it is applied to the values of the given date field
+// before putting them in buckets.
+func (ds *dataset) compute(cond *models.Condition, shiftField string, shift func(time.Time) time.Time) *Group {
+	records := ds.rs.SearchAll()
+	if !cond.IsEmpty() {
+		records = ds.rs.Search(cond)
+	}
+	fields := make([]models.FieldName, 0, len(ds.groupBys)+len(ds.measures))
+	for _, gb := range ds.groupBys {
+		fields = append(fields, gb.field)
+	}
+	for _, m := range ds.measures {
+		if m.field != nil {
+			fields = append(fields, m.field)
+		}
+	}
+	records = records.Load(fields...)
+	root := newGroup(nil, ds.rs.T("Total"), nil)
+	values := make([]float64, len(ds.measures))
+	for _, rec := range records.Records() {
+		for i, m := range ds.measures {
+			values[i] = 1
+			if m.field == nil {
+				continue
+			}
+			val, err := nbutils.CastToFloat(rec.Get(m.field))
+			if err != nil {
+				log.Panic("Unable to read measure", "model", ds.rs.ModelName(), "measure", m.name, "error", err)
+			}
+			values[i] = val
+		}
+		group := root
+		group.add(ds.measures, values)
+		for _, gb := range ds.groupBys {
+			var dateShift func(time.Time) time.Time
+			if gb.field.Name() == shiftField {
+				dateShift = shift
+			}
+			group = group.child(ds.key(rec, gb, dateShift))
+			group.add(ds.measures, values)
+		}
+	}
+	root.finalize()
+	return root
+}
+
+// key returns the key of the group of the given record for the given group
+// by. If shift is not nil, it is applied to date values.
+func (ds *dataset) key(rec *models.RecordCollection, gb groupBy, shift func(time.Time) time.Time) groupKey {
+	none := groupKey{value: false, label: ds.none}
+	value := rec.Get(gb.field)
+	switch gb.info.Type {
+	case fieldtype.Many2One, fieldtype.One2One:
+		rel, _ := value.(models.RecordSet)
+		if rel == nil || rel.IsEmpty() {
+			return none
+		}
+		label := rel.Collection().Call("NameGet").(string)
+		return groupKey{value: rel.Ids()[0], label: label, sortKey: label}
+	case fieldtype.Date, fieldtype.DateTime:
+		var t time.Time
+		switch v := value.(type) {
+		case dates.Date:
+			t = v.Time
+		case dates.DateTime:
+			t = v.In(ds.formatter.Location).Time
+		}
+		if t.IsZero() {
+			return none
+		}
+		if shift != nil {
+			t = shift(t)
+		}
+		start := bucketStart(t, gb.Interval, ds.formatter.Locale.WeekStart)
+		label := bucketLabel(start, gb.Interval, ds.months, func(day time.Time) string {
+			return ds.formatter.Date(dates.Date{Time: day})
+		})
+		return groupKey{value: start.Format(dates.DefaultServerDateFormat), label: label, sortKey: start}
+	case fieldtype.Selection:
+		key := fmt.Sprint(value)
+		if key == "" {
+			return none
+		}
+		return groupKey{value: key, label: ds.formatter.Value(gb.info, key), sortKey: key}
+	case fieldtype.Boolean:
+		b, _ := value.(bool)
+		return groupKey{value: b, label: ds.formatter.Value(gb.info, b), sortKey: b}
+	case fieldtype.Integer:
+		i, _ := nbutils.CastToFloat(value)
+		return groupKey{value: value, label: ds.formatter.Value(gb.info, value), sortKey: i}
+	}
+	label := ds.formatter.Value(gb.info, value)
+	if label == "" {
+		return none
+	}
+	return groupKey{value: value, label: label, sortKey: label}
+}
+
+func init() {
+	log = logging.GetLogger("analytics")
+}
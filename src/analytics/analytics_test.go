@@ -0,0 +1,119 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGroupBy(t *testing.T) {
+	Convey("Testing group by specifications", t, func() {
+		gb, err := ParseGroupBy("DateOrder:week", fieldtype.Date)
+		So(err, ShouldBeNil)
+		So(gb, ShouldResemble, GroupBy{Field: "DateOrder", Interval: IntervalWeek})
+		gb, err = ParseGroupBy("CreateDate", fieldtype.DateTime)
+		So(err, ShouldBeNil)
+		So(gb.Interval, ShouldEqual, DefaultInterval)
+		gb, err = ParseGroupBy("Partner", fieldtype.Many2One)
+		So(err, ShouldBeNil)
+		So(gb, ShouldResemble, GroupBy{Field: "Partner"})
+		_, err = ParseGroupBy("Partner:month", fieldtype.Many2One)
+		So(err, ShouldNotBeNil)
+		_, err = ParseGroupBy("DateOrder:century", fieldtype.Date)
+		So(err, ShouldNotBeNil)
+		_, err = ParseGroupBy("Tags", fieldtype.Many2Many)
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Testing date buckets", t, func() {
+		day := time.Date(2019, time.August, 14, 15, 30, 0, 0, time.UTC)
+		So(bucketStart(day, IntervalDay, time.Monday), ShouldEqual, time.Date(2019, time.August, 14, 0, 0, 0, 0, time.UTC))
+		So(bucketStart(day, IntervalWeek, time.Monday), ShouldEqual, time.Date(2019, time.August, 12, 0, 0, 0, 0, time.UTC))
+		So(bucketStart(day, IntervalWeek, time.Sunday), ShouldEqual, time.Date(2019, time.August, 11, 0, 0, 0, 0, time.UTC))
+		So(bucketStart(day, IntervalMonth, time.Monday), ShouldEqual, time.Date(2019, time.August, 1, 0, 0, 0, 0, time.UTC))
+		So(bucketStart(day, IntervalQuarter, time.Monday), ShouldEqual, time.Date(2019, time.July, 1, 0, 0, 0, 0, time.UTC))
+		So(bucketStart(day, IntervalYear, time.Monday), ShouldEqual, time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC))
+		months := [12]string{"Janvier", "Février", "Mars", "Avril", "Mai", "Juin",
+			"Juillet", "Août", "Septembre", "Octobre", "Novembre", "Décembre"}
+		formatDay := func(t time.Time) string { return t.Format("02/01/2006") }
+		start := bucketStart(day, IntervalWeek, time.Monday)
+		So(bucketLabel(start, IntervalWeek, months, formatDay), ShouldEqual, "W33 2019")
+		So(bucketLabel(day, IntervalDay, months, formatDay), ShouldEqual, "14/08/2019")
+		So(bucketLabel(day, IntervalMonth, months, formatDay), ShouldEqual, "Août 2019")
+		So(bucketLabel(day, IntervalQuarter, months, formatDay), ShouldEqual, "Q3 2019")
+		So(bucketLabel(day, IntervalYear, months, formatDay), ShouldEqual, "2019")
+	})
+}
+
+func TestGroups(t *testing.T) {
+	Convey("Testing the aggregation of groups", t, func() {
+		measures := []measure{{name: CountMeasure}, {name: "Amount"}, {name: "Price", operator: "avg"}, {name: "Max", operator: "max"}}
+		root := newGroup(nil, "Total", nil)
+		for _, rec := range []struct {
+			partner string
+			values  []float64
+		}{
+			{"Bob", []float64{1, 10, 4, 3}},
+			{"Alice", []float64{1, 20, 2, 7}},
+			{"Bob", []float64{1, 30, 6, 1}},
+		} {
+			root.add(measures, rec.values)
+			root.child(groupKey{value: rec.partner, label: rec.partner, sortKey: rec.partner}).add(measures, rec.values)
+		}
+		root.child(groupKey{value: false, label: "None"})
+		root.finalize()
+		So(root.Count, ShouldEqual, 3)
+		So(root.Values, ShouldResemble, map[string]float64{CountMeasure: 3, "Amount": 60, "Price": 4, "Max": 7})
+		So(root.Groups, ShouldHaveLength, 3)
+		So(root.Groups[0].Label, ShouldEqual, "Alice")
+		So(root.Groups[1].Label, ShouldEqual, "Bob")
+		So(root.Groups[1].Values, ShouldResemble, map[string]float64{CountMeasure: 2, "Amount": 40, "Price": 5, "Max": 3})
+		So(root.Groups[2].Label, ShouldEqual, "None")
+		Convey("Comparing with a previous period", func() {
+			previous := newGroup(nil, "Total", nil)
+			previous.add(measures, []float64{1, 80, 5, 2})
+			previous.child(groupKey{value: "Bob", label: "Bob", sortKey: "Bob"}).add(measures, []float64{1, 50, 5, 2})
+			previous.child(groupKey{value: "Carol", label: "Carol", sortKey: "Carol"}).add(measures, []float64{1, 30, 5, 2})
+			previous.finalize()
+			root.compare(previous, measures)
+			So(root.ComparisonValues["Amount"], ShouldEqual, 80)
+			So(root.Variations["Amount"], ShouldEqual, -25)
+			So(root.Groups, ShouldHaveLength, 4)
+			So(root.Groups[0].Label, ShouldEqual, "Alice")
+			So(root.Groups[0].ComparisonValues["Amount"], ShouldEqual, 0)
+			So(root.Groups[0].Variations, ShouldNotContainKey, "Amount")
+			So(root.Groups[1].Variations["Amount"], ShouldEqual, -20)
+			So(root.Groups[2].Label, ShouldEqual, "Carol")
+			So(root.Groups[2].Values["Amount"], ShouldEqual, 0)
+			So(root.Groups[2].Variations["Amount"], ShouldEqual, -100)
+		})
+	})
+	Convey("Testing comparison periods", t, func() {
+		comparison := Comparison{
+			Field:  "DateOrder",
+			Start:  dates.ParseDateTime("2019-04-01 00:00:00"),
+			Stop:   dates.ParseDateTime("2019-07-01 00:00:00"),
+			Period: ComparePreviousYear,
+		}
+		So(comparison.validate(), ShouldBeNil)
+		start, stop := comparison.previous()
+		So(start, ShouldResemble, dates.ParseDateTime("2018-04-01 00:00:00"))
+		So(stop, ShouldResemble, dates.ParseDateTime("2018-07-01 00:00:00"))
+		So(comparison.offset()(start.Time), ShouldEqual, comparison.Start.Time)
+		comparison.Period = ComparePreviousPeriod
+		start, stop = comparison.previous()
+		So(start, ShouldResemble, dates.ParseDateTime("2018-12-31 00:00:00"))
+		So(stop, ShouldResemble, comparison.Start)
+		So(comparison.offset()(start.Time), ShouldEqual, comparison.Start.Time)
+		comparison.Period = "last_century"
+		So(comparison.validate(), ShouldNotBeNil)
+		comparison.Period = ComparePreviousPeriod
+		comparison.Stop = comparison.Start
+		So(comparison.validate(), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+)
+
+// Intervals of the date buckets of date and datetime group bys
+const (
+	IntervalDay     = "day"
+	IntervalWeek    = "week"
+	IntervalMonth   = "month"
+	IntervalQuarter = "quarter"
+	IntervalYear    = "year"
+)
+
+// DefaultInterval is the interval of date group bys that do not give one
+const DefaultInterval = IntervalMonth
+
+var intervals = map[string]bool{
+	IntervalDay:     true,
+	IntervalWeek:    true,
+	IntervalMonth:   true,
+	IntervalQuarter: true,
+	IntervalYear:    true,
+}
+
+// groupableTypes are the types of the fields records can be grouped by
+var groupableTypes = map[fieldtype.Type]bool{
+	fieldtype.Boolean:   true,
+	fieldtype.Char:      true,
+	fieldtype.Date:      true,
+	fieldtype.DateTime:  true,
+	fieldtype.Integer:   true,
+	fieldtype.Many2One:  true,
+	fieldtype.One2One:   true,
+	fieldtype.Selection: true,
+}
+
+// A GroupBy is a level of grouping of a dataset
+type GroupBy struct {
+	Field string
+	// Interval is the interval of the buckets of date and datetime fields.
+	// It is empty for other fields.
+	Interval string
+}
+
+// ParseGroupBy parses a group by specification of the form 'Field' or
+// 'Field:interval' for a field of the given type. Interval is only allowed
+// for date and datetime fields and defaults to DefaultInterval.
+func ParseGroupBy(spec string, fType fieldtype.Type) (GroupBy, error) {
+	tokens := strings.SplitN(spec, ":", 2)
+	res := GroupBy{Field: tokens[0]}
+	if !groupableTypes[fType] {
+		return res, fmt.Errorf("cannot group by field '%s' of type %s", res.Field, fType)
+	}
+	isDate := fType == fieldtype.Date || fType == fieldtype.DateTime
+	switch {
+	case len(tokens) == 2 && !isDate:
+		return res, fmt.Errorf("interval given for field '%s' of type %s", res.Field, fType)
+	case len(tokens) == 2:
+		res.Interval = tokens[1]
+	case isDate:
+		res.Interval = DefaultInterval
+	}
+	if isDate && !intervals[res.Interval] {
+		return res, fmt.Errorf("unknown interval '%s' for field '%s'", res.Interval, res.Field)
+	}
+	return res, nil
+}
+
+// bucketStart returns the start of the bucket of the given interval that
+// contains t. Weeks start on weekStart.
+func bucketStart(t time.Time, interval string, weekStart time.Weekday) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case IntervalWeek:
+		offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+		return day.AddDate(0, 0, -offset)
+	case IntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	case IntervalQuarter:
+		month := time.Month((int(day.Month())-1)/3*3 + 1)
+		return time.Date(day.Year(), month, 1, 0, 0, 0, 0, day.Location())
+	case IntervalYear:
+		return time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+// bucketLabel returns the label of the bucket of the given interval
+// starting at start. months are the names of the months.
+// Days are formatted with formatDay.
+func bucketLabel(start time.Time, interval string, months [12]string, formatDay func(time.Time) string) string {
+	switch interval {
+	case IntervalWeek:
+		year, week := start.AddDate(0, 0, 3).ISOWeek()
+		return fmt.Sprintf("W%02d %d", week, year)
+	case IntervalMonth:
+		return fmt.Sprintf("%s %d", months[start.Month()-1], start.Year())
+	case IntervalQuarter:
+		return fmt.Sprintf("Q%d %d", (int(start.Month())-1)/3+1, start.Year())
+	case IntervalYear:
+		return fmt.Sprintf("%d", start.Year())
+	}
+	return formatDay(start)
+}
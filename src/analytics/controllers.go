@@ -0,0 +1,37 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package analytics
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// getData is the controller that returns the dataset
+// of a graph or a pivot view defined by a Request.
+func getData(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params Request
+	ctx.BindRPCParams(&params)
+	var res *Group
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		var err error
+		if res, err = Compute(env, params); err != nil {
+			log.Panic("Invalid analytics request", "model", params.Model, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/analytics")
+	grp.AddController(http.MethodPost, "/data", getData)
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package apidoc renders the API documentation of the models of the registry,
+// with their fields and methods, in Markdown or HTML.
+//
+// Documentation is built from the model descriptions of the registry. The doc
+// comments of methods are extracted from the sources of the modules by the
+// generator and registered by the generated pool.
+//
+// In debug mode, the documentation is served at /web/doc.
+package apidoc
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Markdown returns the documentation of the given models in Markdown
+func Markdown(descs []models.ModelDescription) string {
+	var buf strings.Builder
+	buf.WriteString("# API Documentation\n\n")
+	for _, desc := range descs {
+		buf.WriteString(modelMarkdown(desc))
+	}
+	return buf.String()
+}
+
+// markdownEscaper escapes the characters that would break Markdown tables
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ")
+
+// modelMarkdown returns the documentation of the given model in Markdown
+func modelMarkdown(desc models.ModelDescription) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "## %s\n\n", desc.Name)
+	fmt.Fprintf(&buf, "- Module: `%s`\n- Type: %s\n", desc.Module, desc.Type)
+	if desc.TableName != "" {
+		fmt.Fprintf(&buf, "- Table: `%s`\n", desc.TableName)
+	}
+	if len(desc.Mixins) > 0 {
+		fmt.Fprintf(&buf, "- Mixins: %s\n", strings.Join(desc.Mixins, ", "))
+	}
+	buf.WriteString("\n### Fields\n\n")
+	buf.WriteString("| Name | JSON | Type | Relation | Description |\n")
+	buf.WriteString("|------|------|------|----------|-------------|\n")
+	for _, fd := range desc.Fields {
+		description := fd.String
+		if fd.Help != "" {
+			description += ": " + fd.Help
+		}
+		fmt.Fprintf(&buf, "| %s | `%s` | %s | %s | %s |\n",
+			fd.Name, fd.JSON, fd.Type, fd.Relation, markdownEscaper.Replace(description))
+	}
+	buf.WriteString("\n### Methods\n\n")
+	for _, md := range desc.Methods {
+		fmt.Fprintf(&buf, "#### %s\n\n```go\n%s\n```\n\n", md.Name, md.Signature)
+		if md.Doc != "" {
+			fmt.Fprintf(&buf, "%s\n\n", md.Doc)
+		}
+	}
+	return buf.String()
+}
+
+// HTML returns the documentation of the given models as an HTML page
+func HTML(descs []models.ModelDescription) (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, descs); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var htmlTemplate = template.Must(template.New("apidoc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>API Documentation</title>
+	<style>
+		body { font-family: sans-serif; margin: 2em; }
+		table { border-collapse: collapse; }
+		th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; }
+		pre { background: #f4f4f4; padding: 0.5em; }
+	</style>
+</head>
+<body>
+	<h1>API Documentation</h1>
+	<ul>
+	{{- range . }}
+		<li><a href="#{{ .Name }}">{{ .Name }}</a></li>
+	{{- end }}
+	</ul>
+	{{- range . }}
+	<h2 id="{{ .Name }}">{{ .Name }}</h2>
+	<p>Module: <code>{{ .Module }}</code> - Type: {{ .Type }}{{ if .TableName }} - Table: <code>{{ .TableName }}</code>{{ end }}</p>
+	{{- if .Mixins }}
+	<p>Mixins:{{ range .Mixins }} <a href="#{{ . }}">{{ . }}</a>{{ end }}</p>
+	{{- end }}
+	<h3>Fields</h3>
+	<table>
+		<tr><th>Name</th><th>JSON</th><th>Type</th><th>Relation</th><th>Description</th></tr>
+		{{- range .Fields }}
+		<tr>
+			<td>{{ .Name }}</td>
+			<td><code>{{ .JSON }}</code></td>
+			<td>{{ .Type }}</td>
+			<td>{{ if .Relation }}<a href="#{{ .Relation }}">{{ .Relation }}</a>{{ end }}</td>
+			<td>{{ .String }}{{ if .Help }}: {{ .Help }}{{ end }}</td>
+		</tr>
+		{{- end }}
+	</table>
+	<h3>Methods</h3>
+	{{- range .Methods }}
+	<h4>{{ .Name }}</h4>
+	<pre>{{ .Signature }}</pre>
+	{{- if .Doc }}
+	<p>{{ .Doc }}</p>
+	{{- end }}
+	{{- end }}
+	{{- end }}
+</body>
+</html>
+`))
+
+func init() {
+	log = logging.GetLogger("apidoc")
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package apidoc
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRendering(t *testing.T) {
+	descs := []models.ModelDescription{{
+		Name:      "Partner",
+		Module:    "base",
+		Type:      models.ModelTypeModel,
+		TableName: "partner",
+		Fields: []models.FieldDescription{
+			{Name: "Name", JSON: "name", Type: fieldtype.Char, String: "Name", Help: "Name | title"},
+			{Name: "Parent", JSON: "parent_id", Type: fieldtype.Many2One, String: "Parent", Relation: "Partner"},
+		},
+		Methods: []models.MethodDescription{
+			{Name: "Greet", Doc: "Greet returns a <greeting> for the partner.", Signature: "Greet(string) string"},
+		},
+	}}
+	Convey("Testing API documentation rendering", t, func() {
+		Convey("Markdown should list fields and methods", func() {
+			md := Markdown(descs)
+			So(md, ShouldContainSubstring, "## Partner\n")
+			So(md, ShouldContainSubstring, "| Name | `name` | char |  | Name: Name \\| title |\n")
+			So(md, ShouldContainSubstring, "| Parent | `parent_id` | many2one | Partner | Parent |\n")
+			So(md, ShouldContainSubstring, "#### Greet\n\n```go\nGreet(string) string\n```\n\nGreet returns a <greeting> for the partner.\n")
+		})
+		Convey("HTML should be escaped and link relations", func() {
+			page, err := HTML(descs)
+			So(err, ShouldBeNil)
+			So(page, ShouldContainSubstring, `<h2 id="Partner">Partner</h2>`)
+			So(page, ShouldContainSubstring, `<a href="#Partner">Partner</a></td>`)
+			So(page, ShouldContainSubstring, "Greet returns a &lt;greeting&gt; for the partner.")
+		})
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package apidoc
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/viper"
+)
+
+// FormatMarkdown is the value of the 'format' query parameter
+// to get the documentation in Markdown instead of HTML.
+const FormatMarkdown = "markdown"
+
+// serveDoc writes the documentation of the given models in the format requested by ctx
+func serveDoc(ctx *server.Context, descs []models.ModelDescription) {
+	if ctx.Query("format") == FormatMarkdown {
+		ctx.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(Markdown(descs)))
+		return
+	}
+	page, err := HTML(descs)
+	if err != nil {
+		log.Warn("Unable to render API documentation", "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// docAvailable aborts the request and returns false if the documentation
+// cannot be served, that is if the server is not in debug mode.
+func docAvailable(ctx *server.Context) bool {
+	if !viper.GetBool("Debug") || !models.BootStrapped() {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// allModelsDoc is the controller of the documentation of all the models
+func allModelsDoc(ctx *server.Context) {
+	if !docAvailable(ctx) {
+		return
+	}
+	serveDoc(ctx, models.Registry.Describe())
+}
+
+// modelDoc is the controller of the documentation of a single model
+func modelDoc(ctx *server.Context) {
+	if !docAvailable(ctx) {
+		return
+	}
+	model, ok := models.Registry.Get(ctx.Param("model"))
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	serveDoc(ctx, []models.ModelDescription{model.Describe()})
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/doc")
+	grp.AddController(http.MethodGet, "", allModelsDoc)
+	grp.AddController(http.MethodGet, "/:model", modelDoc)
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package auth provides external authentication providers (LDAP and
+// OAuth2 / OpenID Connect) on top of the security.AuthBackend interface.
+//
+// It also issues short-lived service tokens (JWT) with which technical
+// users can call given model methods without a session.
+//
+// Providers are configured in the 'Auth' section of the configuration.
+// Each setting can be overridden for a given database in the
+// 'Auth.Databases.<dbName>' section.
+package auth
+
+import (
+	"errors"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/spf13/viper"
+)
+
+var log logging.Logger
+
+// DBContextKey is the key of the authentication context that holds
+// the name of the database on which the user logs in.
+const DBContextKey = "db"
+
+// UserInfo holds the data of an externally authenticated user
+type UserInfo struct {
+	Login string
+	Name  string
+	Email string
+}
+
+// ResolveUser returns the ID of the user with the given info in the database
+// of env. If no such user exists and create is true, the user is created.
+// Otherwise, it must return a security.UserNotFoundError.
+//
+// External authentication, password resets and signups fail as long as
+// it is not set.
+var ResolveUser func(env models.Environment, info UserInfo, create bool) (int64, error)
+
+// setting returns the value of the given key of the Auth configuration
+// section for the given database.
+func setting(dbName, key string) interface{} {
+	if dbName != "" {
+		dbKey := "Auth.Databases." + dbName + "." + key
+		if viper.IsSet(dbKey) {
+			return viper.Get(dbKey)
+		}
+	}
+	return viper.Get("Auth." + key)
+}
+
+// settingString returns the value of the given key of the Auth
+// configuration section for the given database as a string.
+func settingString(dbName, key string) string {
+	res, _ := setting(dbName, key).(string)
+	return res
+}
+
+// settingBool returns the value of the given key of the Auth
+// configuration section for the given database as a bool.
+func settingBool(dbName, key string) bool {
+	res, _ := setting(dbName, key).(bool)
+	return res
+}
+
+// resolveUser returns the ID of the user with the given info in the given
+// database, provisioning it if provision is true.
+func resolveUser(dbName string, info UserInfo, provision bool) (int64, error) {
+	if ResolveUser == nil {
+		return 0, errors.New("no user resolver defined for external authentication")
+	}
+	var uid int64
+	var rErr error
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		uid, rErr = ResolveUser(env, info, provision)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return uid, rErr
+}
+
+// BootStrap registers the LDAP authentication backend on top of the
+// authentication registry, so that it is tried before password authentication,
+// and sets up the service token middleware if service tokens are enabled.
+//
+// It must be called after all modules have registered their own backends.
+func BootStrap() {
+	security.AuthenticationRegistry.RegisterBackend(new(LDAPBackend))
+	setupServiceTokens()
+}
+
+func init() {
+	log = logging.GetLogger("auth")
+}
@@ -0,0 +1,430 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+)
+
+// fakeLDAPServer starts an LDAP server that accepts binds with the given
+// dn and password only. It returns its URL.
+func fakeLDAPServer(dn, password string) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			request, err := berRead(conn)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			elements, _ := request.children()
+			bind, _ := elements[1].children()
+			code := int64(ldapInvalidCredentials)
+			if string(bind[1].content) == dn && string(bind[2].content) == password {
+				code = ldapResultSuccess
+			}
+			conn.Write(berEncode(berTagSequence,
+				berInteger(berTagInteger, elements[0].integer()),
+				berEncode(ldapBindResponse,
+					berInteger(berTagEnumerated, code),
+					berEncode(berTagOctetString),
+					berEncode(berTagOctetString, []byte("message")))))
+			conn.Close()
+		}
+	}()
+	return "ldap://" + ln.Addr().String(), func() { ln.Close() }
+}
+
+func TestBER(t *testing.T) {
+	Convey("Testing BER encoding", t, func() {
+		Convey("Integers should be encoded and decoded", func() {
+			for _, value := range []int64{0, 1, 127, 128, 256, -1, -129, 65535} {
+				elem, n, err := berDecode(berInteger(berTagInteger, value))
+				So(err, ShouldBeNil)
+				So(n, ShouldBeGreaterThan, 2)
+				So(elem.integer(), ShouldEqual, value)
+			}
+		})
+		Convey("Long elements should be encoded and decoded", func() {
+			content := make([]byte, 300)
+			elem, _, err := berDecode(berEncode(berTagOctetString, content))
+			So(err, ShouldBeNil)
+			So(elem.content, ShouldHaveLength, 300)
+		})
+		Convey("Truncated elements should return an error", func() {
+			_, _, err := berDecode(berEncode(berTagOctetString, []byte("hello"))[:4])
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestLDAP(t *testing.T) {
+	Convey("Testing LDAP authentication", t, func() {
+		serverURL, stop := fakeLDAPServer("uid=john\\+doe,dc=example", "secret")
+		defer stop()
+		Convey("DN values should be escaped", func() {
+			So(escapeDN("john+doe"), ShouldEqual, "john\\+doe")
+			So(escapeDN(" #a,b "), ShouldEqual, "\\ #a\\,b\\ ")
+		})
+		Convey("Binding with valid credentials should succeed", func() {
+			So(ldapBind(serverURL, "uid=john\\+doe,dc=example", "secret"), ShouldBeNil)
+		})
+		Convey("Binding with invalid credentials should fail", func() {
+			err := ldapBind(serverURL, "uid=john\\+doe,dc=example", "wrong")
+			So(err, ShouldHaveSameTypeAs, ldapError{})
+			So(err.(ldapError).code, ShouldEqual, ldapInvalidCredentials)
+		})
+		Convey("Backend should let other backends authenticate on failure", func() {
+			viper.Set("Auth.LDAP.URL", serverURL)
+			viper.Set("Auth.LDAP.UserDN", "uid=%s,dc=example")
+			defer viper.Set("Auth.LDAP.URL", "")
+			_, err := new(LDAPBackend).Authenticate("john+doe", "wrong", nil)
+			So(err, ShouldHaveSameTypeAs, security.UserNotFoundError(""))
+			_, err = new(LDAPBackend).Authenticate("john+doe", "", nil)
+			So(err, ShouldHaveSameTypeAs, security.UserNotFoundError(""))
+		})
+	})
+}
+
+func TestOAuth2(t *testing.T) {
+	Convey("Testing OAuth2 provider", t, func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			code := r.PostFormValue("code")
+			if (code != "valid" && code != "unverified") || r.PostFormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": code + "-token"})
+		})
+		mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+			switch r.Header.Get("Authorization") {
+			case "Bearer valid-token":
+				json.NewEncoder(w).Encode(map[string]interface{}{"sub": "123", "email": "john@example.com", "email_verified": true})
+			case "Bearer unverified-token":
+				json.NewEncoder(w).Encode(map[string]interface{}{"sub": "456", "email": "john@example.com", "email_verified": "false"})
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		})
+		ts := httptest.NewServer(mux)
+		defer ts.Close()
+		viper.Set("Auth.OAuth2.test.ClientID", "hexya")
+		viper.Set("Auth.OAuth2.test.ClientSecret", "secret")
+		viper.Set("Auth.OAuth2.test.AuthURL", ts.URL+"/auth")
+		viper.Set("Auth.OAuth2.test.TokenURL", ts.URL+"/token")
+		viper.Set("Auth.OAuth2.test.UserInfoURL", ts.URL+"/userinfo")
+		viper.Set("Auth.Databases.other.OAuth2.test.ClientID", "")
+		provider, ok := GetOAuth2Provider("", "test")
+		So(ok, ShouldBeTrue)
+		Convey("Providers can be disabled per database", func() {
+			_, ok := GetOAuth2Provider("other", "test")
+			So(ok, ShouldBeFalse)
+		})
+		Convey("Consent page URL should hold the client data", func() {
+			authURL := provider.AuthCodeURL("http://localhost/callback", "state")
+			So(authURL, ShouldStartWith, ts.URL+"/auth?")
+			So(authURL, ShouldContainSubstring, "client_id=hexya")
+			So(authURL, ShouldContainSubstring, "state=state")
+		})
+		Convey("Valid codes should give user info", func() {
+			token, err := provider.Exchange("valid", "http://localhost/callback")
+			So(err, ShouldBeNil)
+			info, err := provider.UserInfo(token)
+			So(err, ShouldBeNil)
+			So(info.Login, ShouldEqual, "john@example.com")
+			So(info.Name, ShouldEqual, "john@example.com")
+		})
+		Convey("Invalid codes should fail", func() {
+			_, err := provider.Exchange("invalid", "http://localhost/callback")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Unverified emails should not be used as login", func() {
+			token, err := provider.Exchange("unverified", "http://localhost/callback")
+			So(err, ShouldBeNil)
+			_, err = provider.UserInfo(token)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestTOTP(t *testing.T) {
+	Convey("Testing TOTP", t, func() {
+		// RFC 6238 test secret "12345678901234567890"
+		secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+		Convey("Codes should match RFC 6238 test vectors", func() {
+			code, err := TOTPCode(secret, time.Unix(59, 0))
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, "287082")
+			code, err = TOTPCode(secret, time.Unix(1111111109, 0))
+			So(err, ShouldBeNil)
+			So(code, ShouldEqual, "081804")
+		})
+		Convey("Codes should be verified with clock drift", func() {
+			So(VerifyTOTP(secret, "287082", time.Unix(59, 0)), ShouldBeTrue)
+			So(VerifyTOTP(secret, "287082", time.Unix(80, 0)), ShouldBeTrue)
+			So(VerifyTOTP(secret, "287082", time.Unix(200, 0)), ShouldBeFalse)
+			So(VerifyTOTP(secret, "28708", time.Unix(59, 0)), ShouldBeFalse)
+		})
+		Convey("Generated secrets should be usable", func() {
+			newSecret, err := GenerateTOTPSecret()
+			So(err, ShouldBeNil)
+			code, err := TOTPCode(newSecret, time.Now())
+			So(err, ShouldBeNil)
+			So(VerifyTOTP(newSecret, code, time.Now()), ShouldBeTrue)
+		})
+		Convey("Provisioning URI should be rendered as a QR code", func() {
+			uri := TOTPProvisioningURI("Hexya", "john@example.com", secret)
+			So(uri, ShouldStartWith, "otpauth://totp/Hexya:john@example.com?")
+			img, err := TOTPQRCode(uri, 128)
+			So(err, ShouldBeNil)
+			So(string(img[1:4]), ShouldEqual, "PNG")
+		})
+		Convey("Only local redirects should be followed after the second factor", func() {
+			So(localRedirect("/web"), ShouldEqual, "/web")
+			So(localRedirect("//evil.example.com"), ShouldEqual, "")
+			So(localRedirect("/\\evil.example.com"), ShouldEqual, "")
+			So(localRedirect("https://evil.example.com"), ShouldEqual, "")
+		})
+		Convey("Trusted device tokens should be checked", func() {
+			token := trustedDeviceToken("hexya", 2, time.Now().Add(time.Hour))
+			So(checkTrustedDeviceToken(token, "hexya", 2), ShouldBeTrue)
+			So(checkTrustedDeviceToken(token, "hexya", 3), ShouldBeFalse)
+			So(checkTrustedDeviceToken(token, "other", 2), ShouldBeFalse)
+			So(checkTrustedDeviceToken(token+"x", "hexya", 2), ShouldBeFalse)
+			expired := trustedDeviceToken("hexya", 2, time.Now().Add(-time.Hour))
+			So(checkTrustedDeviceToken(expired, "hexya", 2), ShouldBeFalse)
+		})
+		Convey("TOTP codes of a user should only be checked a few times", func() {
+			totpAttempts = server.NewRateLimiter(maxTOTPAttempts, totpAttemptsPeriod, 0)
+			srv := &server.Server{Engine: gin.New()}
+			srv.Group("/").POST("/check/:uid", func(c *server.Context) {
+				uid, _ := strconv.ParseInt(c.Param("uid"), 10, 64)
+				c.JSON(http.StatusOK, checkTOTPCode(c, uid, secret, c.PostForm("code")))
+			})
+			check := func(uid, code string) string {
+				w := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, "/check/"+uid, strings.NewReader("code="+code))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				srv.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+			code, _ := TOTPCode(secret, time.Now())
+			wrong := strconv.Itoa((int(code[0]-'0')+5)%10) + code[1:]
+			for i := 0; i < maxTOTPAttempts; i++ {
+				So(check("2", wrong), ShouldEqual, "false")
+			}
+			So(check("2", code), ShouldEqual, "false")
+			So(check("3", code), ShouldEqual, "true")
+		})
+	})
+}
+
+func TestAccountTokens(t *testing.T) {
+	Convey("Testing signup and reset helpers", t, func() {
+		Convey("Signed tokens should hold their fields until expiry", func() {
+			token := SignedToken(time.Now().Add(time.Hour), resetTokenPurpose, "hexya", "2")
+			fields, ok := ParseSignedToken(token)
+			So(ok, ShouldBeTrue)
+			So(fields, ShouldResemble, []string{resetTokenPurpose, "hexya", "2"})
+			_, ok = ParseSignedToken(token[1:])
+			So(ok, ShouldBeFalse)
+			_, ok = ParseSignedToken(SignedToken(time.Now().Add(-time.Second), resetTokenPurpose))
+			So(ok, ShouldBeFalse)
+		})
+		Convey("Signup policy should default to none", func() {
+			So(SignupPolicy(""), ShouldEqual, SignupNone)
+			viper.Set("Auth.Signup", "free")
+			viper.Set("Auth.Databases.private.Signup", "invite")
+			defer viper.Set("Auth.Signup", "")
+			So(SignupPolicy(""), ShouldEqual, SignupFree)
+			So(SignupPolicy("private"), ShouldEqual, SignupInvite)
+		})
+		Convey("Short passwords should be rejected", func() {
+			So(checkPassword("", "short"), ShouldNotBeNil)
+			So(checkPassword("", "long enough"), ShouldBeNil)
+		})
+		Convey("Account links should point to the configured base URL", func() {
+			_, err := accountBaseURL("")
+			So(err, ShouldNotBeNil)
+			viper.Set("Server.Domain", "erp.example.com")
+			defer viper.Set("Server.Domain", "")
+			base, _ := accountBaseURL("")
+			So(base, ShouldEqual, "https://erp.example.com")
+			viper.Set("Auth.BaseURL", "https://www.example.com/")
+			defer viper.Set("Auth.BaseURL", "")
+			base, _ = accountBaseURL("")
+			So(base, ShouldEqual, "https://www.example.com")
+		})
+	})
+}
+
+func TestServiceTokens(t *testing.T) {
+	Convey("Testing service tokens", t, func() {
+		Convey("Tokens should hold their claims until expiry", func() {
+			token := IssueServiceToken("hexya", 5, []string{"Partner.Read", "Sale.*"}, time.Hour)
+			So(strings.Count(token, "."), ShouldEqual, 2)
+			claims, err := ParseServiceToken(token)
+			So(err, ShouldBeNil)
+			So(claims.UID(), ShouldEqual, 5)
+			So(claims.Database, ShouldEqual, "hexya")
+			So(claims.Scopes(), ShouldResemble, []string{"Partner.Read", "Sale.*"})
+			So(claims.Allows("Partner", "Read"), ShouldBeTrue)
+			So(claims.Allows("Partner", "Write"), ShouldBeFalse)
+			So(claims.Allows("Sale", "Confirm"), ShouldBeTrue)
+			_, err = ParseServiceToken(IssueServiceToken("hexya", 5, []string{"Partner.Read"}, -time.Second))
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Tampered tokens should be rejected", func() {
+			parts := strings.Split(IssueServiceToken("hexya", 5, []string{"Partner.Read"}, time.Hour), ".")
+			claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1","db":"hexya","scope":"Partner.*","exp":9999999999}`))
+			_, err := ParseServiceToken(parts[0] + "." + claims + "." + parts[2])
+			So(err, ShouldNotBeNil)
+			none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+			_, err = ParseServiceToken(none + "." + parts[1] + ".")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Middleware should only allow calls within the token scope", func() {
+			srv := &server.Server{Engine: gin.New()}
+			srv.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+			srv.AddMiddleWare(ServiceTokenAuth())
+			srv.Group("/").POST("/call", func(c *server.Context) {
+				uid, _ := c.Session().Get("uid").(int64)
+				c.Session().Save()
+				c.String(http.StatusOK, strconv.FormatInt(uid, 10))
+			})
+			call := func(token, method string) *httptest.ResponseRecorder {
+				payload := `{"jsonrpc":"2.0","id":1,"params":{"model":"Partner","method":"` + method + `","args":[[1]]}}`
+				req := httptest.NewRequest(http.MethodPost, "/call", strings.NewReader(payload))
+				req.Header.Set("Content-Type", "application/json")
+				if token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, req)
+				return w
+			}
+			token := IssueServiceToken("", 5, []string{"Partner.Read"}, time.Hour)
+			w := call(token, "Read")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "5")
+			So(w.Header().Get("Set-Cookie"), ShouldBeEmpty)
+			So(call(token, "Write").Code, ShouldEqual, http.StatusForbidden)
+			So(call(token[:len(token)-2], "Read").Code, ShouldEqual, http.StatusUnauthorized)
+			So(call(IssueServiceToken("other", 5, []string{"Partner.Read"}, time.Hour), "Read").Code, ShouldEqual, http.StatusUnauthorized)
+			So(call("", "Read").Body.String(), ShouldEqual, "0")
+		})
+	})
+}
+
+// auditLogger records the impersonation events logged by this package
+type auditLogger struct {
+	logging.Logger
+	events [][]interface{}
+}
+
+// Info records the message if it is an impersonation event
+func (l *auditLogger) Info(msg string, ctx ...interface{}) {
+	if msg == "Impersonation" {
+		l.events = append(l.events, ctx)
+	}
+	l.Logger.Info(msg, ctx...)
+}
+
+func TestImpersonation(t *testing.T) {
+	Convey("Testing impersonation", t, func() {
+		const adminUID, userUID, otherAdminUID int64 = 901, 902, 903
+		memberships := security.Registry.ForDatabase("")
+		memberships.AddMembership(adminUID, security.GroupAdmin)
+		memberships.AddMembership(otherAdminUID, security.GroupAdmin)
+		defer memberships.RemoveMembership(adminUID, security.GroupAdmin)
+		defer memberships.RemoveMembership(otherAdminUID, security.GroupAdmin)
+		al := &auditLogger{Logger: log}
+		log = al
+		defer func() { log = al.Logger }()
+
+		srv := &server.Server{Engine: gin.New()}
+		srv.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+		grp := srv.Group("/")
+		grp.POST("/login", func(c *server.Context) {
+			uid, _ := strconv.ParseInt(c.PostForm("uid"), 10, 64)
+			c.Session().Set("uid", uid)
+			c.Session().Save()
+		})
+		grp.POST("/start", impersonate)
+		grp.POST("/stop", stopImpersonation)
+		grp.GET("/uid", func(c *server.Context) {
+			uid, _ := c.Session().Get("uid").(int64)
+			c.String(http.StatusOK, "%d:%d", uid, c.RealUID())
+		})
+		var cookies []*http.Cookie
+		call := func(method, path string, uid int64) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, path, strings.NewReader("uid="+strconv.FormatInt(uid, 10)))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if res := w.Result().Cookies(); len(res) > 0 {
+				cookies = res
+			}
+			return w
+		}
+		Convey("Only administrators should impersonate users", func() {
+			call(http.MethodPost, "/login", userUID)
+			So(call(http.MethodPost, "/start", 904).Code, ShouldEqual, http.StatusForbidden)
+			So(call(http.MethodGet, "/uid", 0).Body.String(), ShouldEqual, "902:0")
+			So(al.events, ShouldBeEmpty)
+		})
+		Convey("Superuser and administrators should not be impersonated", func() {
+			call(http.MethodPost, "/login", adminUID)
+			So(call(http.MethodPost, "/start", security.SuperUserID).Code, ShouldEqual, http.StatusForbidden)
+			So(call(http.MethodPost, "/start", otherAdminUID).Code, ShouldEqual, http.StatusForbidden)
+			So(call(http.MethodGet, "/uid", 0).Body.String(), ShouldEqual, "901:0")
+			So(al.events, ShouldBeEmpty)
+		})
+		Convey("Administrators should impersonate users and switch back", func() {
+			call(http.MethodPost, "/login", adminUID)
+			So(call(http.MethodPost, "/start", userUID).Code, ShouldEqual, http.StatusOK)
+			So(call(http.MethodGet, "/uid", 0).Body.String(), ShouldEqual, "902:901")
+			Convey("Impersonated admin rights should not be usable to impersonate admins", func() {
+				So(call(http.MethodPost, "/start", otherAdminUID).Code, ShouldEqual, http.StatusForbidden)
+				So(call(http.MethodGet, "/uid", 0).Body.String(), ShouldEqual, "902:901")
+			})
+			So(call(http.MethodPost, "/stop", 0).Code, ShouldEqual, http.StatusOK)
+			So(call(http.MethodGet, "/uid", 0).Body.String(), ShouldEqual, "901:0")
+			Convey("Start and stop should be audited with the real user", func() {
+				So(al.events, ShouldHaveLength, 2)
+				So(al.events[0][:6], ShouldResemble, []interface{}{"start", true, "realUID", adminUID, "uid", userUID})
+				So(al.events[1][:6], ShouldResemble, []interface{}{"start", false, "realUID", adminUID, "uid", userUID})
+			})
+		})
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER identifiers used by the LDAP protocol
+const (
+	berClassApplication byte = 0x40
+	berClassContext     byte = 0x80
+	berConstructed      byte = 0x20
+	berTagInteger       byte = 0x02
+	berTagOctetString   byte = 0x04
+	berTagEnumerated    byte = 0x0a
+	berTagSequence      byte = 0x30
+)
+
+// berMaxLength is the maximum length of a BER element that we accept to read
+const berMaxLength = 1 << 20
+
+// A berElement is a decoded BER element
+type berElement struct {
+	tag     byte
+	content []byte
+}
+
+// children decodes the content of this constructed element
+func (be berElement) children() ([]berElement, error) {
+	var res []berElement
+	data := be.content
+	for len(data) > 0 {
+		child, n, err := berDecode(data)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, child)
+		data = data[n:]
+	}
+	return res, nil
+}
+
+// integer returns the content of this INTEGER or ENUMERATED element as an int64
+func (be berElement) integer() int64 {
+	var res int64
+	for i, b := range be.content {
+		if i == 0 && b&0x80 != 0 {
+			res = -1
+		}
+		res = res<<8 | int64(b)
+	}
+	return res
+}
+
+// berEncode returns the BER encoding of an element with the given tag and content
+func berEncode(tag byte, content ...[]byte) []byte {
+	var body []byte
+	for _, c := range content {
+		body = append(body, c...)
+	}
+	res := []byte{tag}
+	l := len(body)
+	switch {
+	case l < 0x80:
+		res = append(res, byte(l))
+	default:
+		var lenBytes []byte
+		for ; l > 0; l >>= 8 {
+			lenBytes = append([]byte{byte(l)}, lenBytes...)
+		}
+		res = append(res, 0x80|byte(len(lenBytes)))
+		res = append(res, lenBytes...)
+	}
+	return append(res, body...)
+}
+
+// berInteger returns the BER encoding of the given value with the given tag
+func berInteger(tag byte, value int64) []byte {
+	var content []byte
+	for {
+		content = append([]byte{byte(value)}, content...)
+		value >>= 8
+		if (value == 0 && content[0]&0x80 == 0) || (value == -1 && content[0]&0x80 != 0) {
+			break
+		}
+	}
+	return berEncode(tag, content)
+}
+
+// berDecode decodes the first BER element of data and
+// returns it with the number of bytes read.
+func berDecode(data []byte) (berElement, int, error) {
+	if len(data) < 2 {
+		return berElement{}, 0, errors.New("BER element too short")
+	}
+	l, n := int(data[1]), 2
+	if l&0x80 != 0 {
+		nBytes := l & 0x7f
+		if nBytes == 0 || nBytes > 4 || len(data) < 2+nBytes {
+			return berElement{}, 0, errors.New("invalid BER length")
+		}
+		l = 0
+		for _, b := range data[2 : 2+nBytes] {
+			l = l<<8 | int(b)
+		}
+		n += nBytes
+	}
+	if l > berMaxLength || len(data) < n+l {
+		return berElement{}, 0, fmt.Errorf("invalid BER length %d", l)
+	}
+	return berElement{tag: data[0], content: data[n : n+l]}, n + l, nil
+}
+
+// berRead reads a whole BER element from r
+func berRead(r io.Reader) (berElement, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return berElement{}, err
+	}
+	l := int(header[1])
+	if l&0x80 != 0 {
+		nBytes := l & 0x7f
+		if nBytes == 0 || nBytes > 4 {
+			return berElement{}, errors.New("invalid BER length")
+		}
+		lenBytes := make([]byte, nBytes)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return berElement{}, err
+		}
+		l = 0
+		for _, b := range lenBytes {
+			l = l<<8 | int(b)
+		}
+	}
+	if l > berMaxLength {
+		return berElement{}, fmt.Errorf("invalid BER length %d", l)
+	}
+	content := make([]byte, l)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return berElement{}, err
+	}
+	return berElement{tag: header[0], content: content}, nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// AuditImpersonation is called each time an administrator starts (start is true)
+// or stops impersonating a user, in an Environment of the real user.
+// It can be set by modules to keep a persistent audit trail. Impersonations are
+// always logged by this package.
+var AuditImpersonation func(env models.Environment, realUID, uid int64, start bool)
+
+// audit logs and records the given impersonation event
+func audit(ctx *server.Context, realUID, uid int64, start bool) {
+	log.Info("Impersonation", "start", start, "realUID", realUID, "uid", uid,
+		"database", ctx.DBName(), "ip", ctx.ClientIP())
+	if AuditImpersonation == nil {
+		return
+	}
+	err := models.ExecuteInTenantEnvironment(ctx.DBName(), realUID, func(env models.Environment) {
+		AuditImpersonation(env, realUID, uid, start)
+	})
+	if err != nil {
+		log.Warn("Unable to record impersonation audit", "realUID", realUID, "uid", uid, "error", err)
+	}
+}
+
+// Impersonate switches the session of ctx to the given user. The current user
+// of the session must be an administrator. The superuser and administrators
+// cannot be impersonated, so that impersonation never grants more rights than
+// those of the real user. If the session is already impersonating a user,
+// the original administrator is kept as real user.
+func Impersonate(ctx *server.Context, uid int64) error {
+	currentUID, _ := ctx.Session().Get("uid").(int64)
+	realUID := ctx.RealUID()
+	if realUID == 0 {
+		realUID = currentUID
+	}
+	memberships := security.Registry.ForDatabase(ctx.DBName())
+	if realUID == 0 || !memberships.HasMembership(realUID, security.GroupAdmin) {
+		return errors.New("only administrators can impersonate users")
+	}
+	if uid <= 0 {
+		return errors.New("invalid user to impersonate")
+	}
+	if uid == security.SuperUserID || memberships.HasMembership(uid, security.GroupAdmin) {
+		return errors.New("administrators cannot be impersonated")
+	}
+	ctx.Session().Set("uid", uid)
+	ctx.Session().Set(server.RealUIDSessionKey, realUID)
+	ctx.Session().Set(server.ImpersonatingSessionKey, true)
+	if err := ctx.Session().Save(); err != nil {
+		return err
+	}
+	audit(ctx, realUID, uid, true)
+	return nil
+}
+
+// StopImpersonation switches the session of ctx back to the real user.
+// It does nothing if the session is not impersonating a user.
+func StopImpersonation(ctx *server.Context) error {
+	realUID := ctx.RealUID()
+	if realUID == 0 {
+		return nil
+	}
+	uid, _ := ctx.Session().Get("uid").(int64)
+	ctx.Session().Set("uid", realUID)
+	ctx.Session().Delete(server.RealUIDSessionKey)
+	ctx.Session().Delete(server.ImpersonatingSessionKey)
+	if err := ctx.Session().Save(); err != nil {
+		return err
+	}
+	audit(ctx, realUID, uid, false)
+	return nil
+}
+
+// impersonate is the controller to start impersonating a user
+func impersonate(ctx *server.Context) {
+	uid, err := strconv.ParseInt(ctx.PostForm("uid"), 10, 64)
+	if err == nil {
+		err = Impersonate(ctx, uid)
+	}
+	if err != nil {
+		log.Warn("Impersonation refused", "uid", ctx.PostForm("uid"), "ip", ctx.ClientIP(), "error", err)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]bool{"result": true})
+}
+
+// stopImpersonation is the controller to stop impersonating a user
+func stopImpersonation(ctx *server.Context) {
+	if err := StopImpersonation(ctx); err != nil {
+		log.Panic("Unable to stop impersonation", "error", err)
+	}
+	ctx.JSON(http.StatusOK, map[string]bool{"result": true})
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/auth/impersonate")
+	grp.AddController(http.MethodPost, "/start", impersonate)
+	grp.AddController(http.MethodPost, "/stop", stopImpersonation)
+}
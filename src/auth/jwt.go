@@ -0,0 +1,291 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// defaultServiceTokenTTL is the validity of service tokens
+// if Auth.JWT.TTL is not set.
+const defaultServiceTokenTTL = 5 * time.Minute
+
+// jwtHeader is the encoded header of the service tokens
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// ServiceClaims are the claims of a service token, which is a JWT allowing
+// a technical user to call the given model methods of a database.
+type ServiceClaims struct {
+	ID        string `json:"jti"`
+	Subject   string `json:"sub"`
+	Database  string `json:"db"`
+	Scope     string `json:"scope"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// UID returns the ID of the user of the token
+func (sc ServiceClaims) UID() int64 {
+	uid, _ := strconv.ParseInt(sc.Subject, 10, 64)
+	return uid
+}
+
+// Scopes returns the list of the model methods allowed by the token,
+// in the form 'Model.Method' or 'Model.*' for all methods of a model.
+func (sc ServiceClaims) Scopes() []string {
+	return strings.Fields(sc.Scope)
+}
+
+// Allows returns true if the given method of the given model
+// can be called with the token.
+func (sc ServiceClaims) Allows(model, method string) bool {
+	for _, scope := range sc.Scopes() {
+		if scope == model+"."+method || scope == model+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScopes returns an error if one of the given scopes is malformed
+// or refers to a model or a method that does not exist.
+func checkScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("no scope given")
+	}
+	for _, scope := range scopes {
+		parts := strings.Split(scope, ".")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid scope '%s'", scope)
+		}
+		model, ok := models.Registry.Get(parts[0])
+		if !ok {
+			return fmt.Errorf("unknown model in scope '%s'", scope)
+		}
+		if parts[1] == "*" {
+			continue
+		}
+		if _, ok := model.Methods().Get(parts[1]); !ok {
+			return fmt.Errorf("unknown method in scope '%s'", scope)
+		}
+	}
+	return nil
+}
+
+// IssueServiceToken returns a service token for the given user of the given
+// database, allowing to call the model methods of the given scopes, and
+// valid for the given duration.
+func IssueServiceToken(dbName string, uid int64, scopes []string, ttl time.Duration) string {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		log.Panic("Unable to generate token ID", "error", err)
+	}
+	now := time.Now()
+	claims, err := json.Marshal(ServiceClaims{
+		ID:        base64.RawURLEncoding.EncodeToString(jti),
+		Subject:   strconv.FormatInt(uid, 10),
+		Database:  dbName,
+		Scope:     strings.Join(scopes, " "),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		log.Panic("Unable to marshal token claims", "error", err)
+	}
+	payload := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + sign(payload)
+}
+
+// ParseServiceToken checks the given service token and returns its claims.
+// It returns an error if the token is malformed, has an invalid signature
+// or is expired.
+func ParseServiceToken(token string) (ServiceClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ServiceClaims{}, errors.New("malformed token")
+	}
+	// We only issue HS256 tokens, so that we require this exact header
+	// rather than trusting the algorithm given by the token.
+	if parts[0] != jwtHeader {
+		return ServiceClaims{}, errors.New("unsupported token header")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(payload))) {
+		return ServiceClaims{}, errors.New("invalid token signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ServiceClaims{}, err
+	}
+	var claims ServiceClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return ServiceClaims{}, err
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return ServiceClaims{}, errors.New("token expired")
+	}
+	if claims.UID() == 0 {
+		return ServiceClaims{}, errors.New("invalid token subject")
+	}
+	return claims, nil
+}
+
+// isServiceUser returns true if the given user of the given database is
+// a member of one of the groups listed in the Auth.JWT.Groups configuration,
+// and can therefore obtain service tokens.
+func isServiceUser(dbName string, uid int64) bool {
+	for _, groupID := range cast.ToStringSlice(setting(dbName, "JWT.Groups")) {
+		group := security.Registry.GetGroup(groupID)
+		if group != nil && security.Registry.ForDatabase(dbName).HasMembership(uid, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceTokenError aborts the request of ctx with the given
+// status and OAuth2 error code.
+func serviceTokenError(ctx *server.Context, status int, code string) {
+	ctx.AbortWithStatusJSON(status, map[string]string{"error": code})
+}
+
+// serviceToken issues a service token following the OAuth2 client
+// credentials grant: the technical user authenticates with its login and
+// password as client_id and client_secret, either as form values or with
+// HTTP basic authentication, and requests space separated scopes.
+func serviceToken(ctx *server.Context) {
+	if !viper.GetBool("Auth.JWT.Enabled") {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if ctx.PostForm("grant_type") != "client_credentials" {
+		serviceTokenError(ctx, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+	login, secret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		login, secret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+	dbName := ctx.DBName()
+	uid, err := security.AuthenticationRegistry.Authenticate(login, secret, types.NewContext().WithKey(DBContextKey, dbName))
+	if err != nil {
+		log.Warn("Service token authentication failed", "login", login, "ip", ctx.ClientIP(), "error", err)
+		serviceTokenError(ctx, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if !isServiceUser(dbName, uid) {
+		log.Warn("Service token refused to non service user", "uid", uid, "ip", ctx.ClientIP())
+		serviceTokenError(ctx, http.StatusForbidden, "unauthorized_client")
+		return
+	}
+	scopes := strings.Fields(ctx.PostForm("scope"))
+	if err := checkScopes(scopes); err != nil {
+		log.Warn("Invalid service token scope", "uid", uid, "error", err)
+		serviceTokenError(ctx, http.StatusBadRequest, "invalid_scope")
+		return
+	}
+	ttl := cast.ToDuration(setting(dbName, "JWT.TTL"))
+	if ttl <= 0 {
+		ttl = defaultServiceTokenTTL
+	}
+	token := IssueServiceToken(dbName, uid, scopes, ttl)
+	log.Info("Service token issued", "uid", uid, "database", dbName, "scope", scopes, "ip", ctx.ClientIP())
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+// A serviceSession is the session of a request authenticated by a service
+// token. It only lives for the duration of the request and is never saved,
+// so that no session cookie is issued to services.
+type serviceSession struct {
+	values map[interface{}]interface{}
+}
+
+func (ss *serviceSession) Get(key interface{}) interface{}            { return ss.values[key] }
+func (ss *serviceSession) Set(key interface{}, val interface{})       { ss.values[key] = val }
+func (ss *serviceSession) Delete(key interface{})                     { delete(ss.values, key) }
+func (ss *serviceSession) Clear()                                     { ss.values = make(map[interface{}]interface{}) }
+func (ss *serviceSession) AddFlash(value interface{}, vars ...string) {}
+func (ss *serviceSession) Flashes(vars ...string) []interface{}       { return nil }
+func (ss *serviceSession) Options(sessions.Options)                   {}
+func (ss *serviceSession) Save() error                                { return nil }
+
+var _ sessions.Session = new(serviceSession)
+
+// ServiceTokenAuth returns a middleware that authenticates the requests
+// bearing a service token in their Authorization header.
+//
+// The request must be a JSON-RPC call to a model method allowed by the scopes
+// of the token, on the database of the token. It is then served with the user
+// of the token logged in a session that is not saved. Other requests bearing a
+// token are rejected, and requests without token are left untouched.
+func ServiceTokenAuth() server.HandlerFunc {
+	return func(ctx *server.Context) {
+		header := ctx.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			ctx.Next()
+			return
+		}
+		claims, err := ParseServiceToken(strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")))
+		if err == nil && claims.Database != ctx.DBName() {
+			err = errors.New("token issued for another database")
+		}
+		if err != nil {
+			log.Warn("Invalid service token", "ip", ctx.ClientIP(), "path", ctx.Request.URL.Path, "error", err)
+			ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		call, ok := ctx.RPCCall()
+		if !ok || !claims.Allows(call.Model, call.Method) {
+			log.Warn("Service token used out of its scope", "uid", claims.UID(), "model", call.Model,
+				"method", call.Method, "path", ctx.Request.URL.Path)
+			ctx.Header("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		session := &serviceSession{values: make(map[interface{}]interface{})}
+		session.Set("uid", claims.UID())
+		session.Set(server.DBNameKey, claims.Database)
+		ctx.Set(sessions.DefaultKey, session)
+		ctx.Next()
+	}
+}
+
+// setupServiceTokens adds the service token middleware to the server
+// if service tokens are enabled by the Auth.JWT.Enabled configuration.
+func setupServiceTokens() {
+	if !viper.GetBool("Auth.JWT.Enabled") {
+		return
+	}
+	server.GetServer().AddMiddleWare(ServiceTokenAuth())
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/auth/service")
+	grp.AddController(http.MethodPost, "/token", serviceToken)
+}
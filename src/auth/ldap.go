@@ -0,0 +1,179 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+// ldapTimeout is the timeout of connections to LDAP servers
+const ldapTimeout = 10 * time.Second
+
+// LDAP protocol constants
+const (
+	ldapVersion             = 3
+	ldapBindRequest         = berClassApplication | berConstructed | 0
+	ldapBindResponse        = berClassApplication | berConstructed | 1
+	ldapUnbindRequest       = berClassApplication | 2
+	ldapSimpleAuth          = berClassContext | 0
+	ldapInvalidCredentials  = 49
+	ldapResultSuccess       = 0
+	ldapDefaultPort         = "389"
+	ldapDefaultSecurePort   = "636"
+	ldapSecureScheme        = "ldaps"
+	ldapDNSpecialCharacters = `\,+"<>;=`
+)
+
+// An ldapError is an error returned by an LDAP server
+type ldapError struct {
+	code    int64
+	message string
+}
+
+// Error returns the error message
+func (le ldapError) Error() string {
+	return fmt.Sprintf("LDAP error %d: %s", le.code, le.message)
+}
+
+// LDAPBackend is an authentication backend that authenticates users by
+// binding to an LDAP server with their credentials.
+//
+// It is configured with the following keys of the Auth configuration section:
+// - LDAP.URL: URL of the server (e.g. ldaps://ldap.example.com),
+// - LDAP.UserDN: DN template of users where %s is replaced by the login
+// (e.g. uid=%s,ou=people,dc=example,dc=com),
+// - LDAP.Provision: if true, users are created on their first login.
+//
+// The backend returns a security.UserNotFoundError when LDAP authentication
+// fails, so that other backends (e.g. password authentication) are tried.
+type LDAPBackend struct{}
+
+// Authenticate the user with the given login and secret on the LDAP server
+// configured for the database given by the DBContextKey of the context.
+func (lb *LDAPBackend) Authenticate(login, secret string, context *types.Context) (int64, error) {
+	var dbName string
+	if context != nil {
+		dbName = context.GetString(DBContextKey)
+	}
+	serverURL := settingString(dbName, "LDAP.URL")
+	userDN := settingString(dbName, "LDAP.UserDN")
+	if serverURL == "" || userDN == "" || secret == "" {
+		// We never try to bind with an empty password which would be an anonymous bind
+		return 0, security.UserNotFoundError(login)
+	}
+	err := ldapBind(serverURL, fmt.Sprintf(userDN, escapeDN(login)), secret)
+	if err != nil {
+		if le, ok := err.(ldapError); !ok || le.code != ldapInvalidCredentials {
+			log.Warn("LDAP authentication failed", "login", login, "url", serverURL, "error", err)
+		}
+		return 0, security.UserNotFoundError(login)
+	}
+	uid, err := resolveUser(dbName, UserInfo{Login: login, Name: login}, settingBool(dbName, "LDAP.Provision"))
+	if err != nil {
+		return 0, err
+	}
+	return uid, nil
+}
+
+var _ security.AuthBackend = new(LDAPBackend)
+
+// escapeDN escapes the given value so that it can be used as an attribute
+// value in a distinguished name.
+func escapeDN(value string) string {
+	var res strings.Builder
+	for i, r := range value {
+		switch {
+		case strings.ContainsRune(ldapDNSpecialCharacters, r),
+			i == 0 && (r == ' ' || r == '#'),
+			i == len(value)-1 && r == ' ':
+			res.WriteRune('\\')
+			res.WriteRune(r)
+		case r == 0:
+			res.WriteString(`\00`)
+		default:
+			res.WriteRune(r)
+		}
+	}
+	return res.String()
+}
+
+// ldapDial opens a connection to the LDAP server with the given URL
+func ldapDial(serverURL string) (net.Conn, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	dialer := &net.Dialer{Timeout: ldapTimeout}
+	if u.Scheme == ldapSecureScheme {
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), ldapDefaultSecurePort)
+		}
+		return tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	}
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), ldapDefaultPort)
+	}
+	return dialer.Dial("tcp", host)
+}
+
+// ldapBind makes a simple bind to the LDAP server with the given URL
+// with the given DN and password. It returns nil if the bind is successful.
+func ldapBind(serverURL, dn, password string) error {
+	conn, err := ldapDial(serverURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(ldapTimeout)); err != nil {
+		return err
+	}
+	request := berEncode(berTagSequence,
+		berInteger(berTagInteger, 1),
+		berEncode(ldapBindRequest,
+			berInteger(berTagInteger, ldapVersion),
+			berEncode(berTagOctetString, []byte(dn)),
+			berEncode(ldapSimpleAuth, []byte(password))))
+	if _, err = conn.Write(request); err != nil {
+		return err
+	}
+	response, err := berRead(conn)
+	if err != nil {
+		return err
+	}
+	resErr := parseBindResponse(response)
+	conn.Write(berEncode(berTagSequence, berInteger(berTagInteger, 2), berEncode(ldapUnbindRequest)))
+	return resErr
+}
+
+// parseBindResponse returns the error of the given bind response
+// or nil if the bind was successful.
+func parseBindResponse(response berElement) error {
+	elements, err := response.children()
+	if err != nil {
+		return err
+	}
+	if len(elements) < 2 || elements[1].tag != ldapBindResponse {
+		return fmt.Errorf("unexpected LDAP response")
+	}
+	result, err := elements[1].children()
+	if err != nil {
+		return err
+	}
+	if len(result) < 3 || result[0].tag != berTagEnumerated {
+		return fmt.Errorf("invalid LDAP bind response")
+	}
+	if code := result[0].integer(); code != ldapResultSuccess {
+		return ldapError{code: code, message: string(result[2].content)}
+	}
+	return nil
+}
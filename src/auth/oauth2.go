@@ -0,0 +1,224 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/cast"
+)
+
+// oauthStateSessionKey is the session key under which the OAuth2 state is stored
+const oauthStateSessionKey = "oauth_state"
+
+// oauthTimeout is the timeout of requests to OAuth2 providers
+const oauthTimeout = 10 * time.Second
+
+// An OAuth2Provider is an OAuth2 / OpenID Connect identity provider.
+type OAuth2Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+	Provision    bool
+}
+
+// GetOAuth2Provider returns the OAuth2 provider with the given name for the given
+// database from the 'Auth.OAuth2.<name>' configuration section.
+// The returned boolean is false if this provider is not configured.
+func GetOAuth2Provider(dbName, name string) (OAuth2Provider, bool) {
+	prefix := "OAuth2." + name + "."
+	provider := OAuth2Provider{
+		Name:         name,
+		ClientID:     settingString(dbName, prefix+"ClientID"),
+		ClientSecret: settingString(dbName, prefix+"ClientSecret"),
+		AuthURL:      settingString(dbName, prefix+"AuthURL"),
+		TokenURL:     settingString(dbName, prefix+"TokenURL"),
+		UserInfoURL:  settingString(dbName, prefix+"UserInfoURL"),
+		Scopes:       []string{"openid", "email", "profile"},
+		Provision:    settingBool(dbName, prefix+"Provision"),
+	}
+	if scopes := settingString(dbName, prefix+"Scopes"); scopes != "" {
+		provider.Scopes = strings.Fields(scopes)
+	}
+	if provider.ClientID == "" || provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "" {
+		return OAuth2Provider{}, false
+	}
+	return provider, true
+}
+
+// AuthCodeURL returns the URL of the provider's consent page to which
+// the user must be redirected to log in.
+func (op OAuth2Provider) AuthCodeURL(redirectURL, state string) string {
+	values := url.Values{
+		"response_type": {"code"},
+		"client_id":     {op.ClientID},
+		"redirect_uri":  {redirectURL},
+		"scope":         {strings.Join(op.Scopes, " ")},
+		"state":         {state},
+	}
+	sep := "?"
+	if strings.Contains(op.AuthURL, "?") {
+		sep = "&"
+	}
+	return op.AuthURL + sep + values.Encode()
+}
+
+// Exchange exchanges the given authorization code for an access token.
+func (op OAuth2Provider) Exchange(code, redirectURL string) (string, error) {
+	client := http.Client{Timeout: oauthTimeout}
+	resp, err := client.PostForm(op.TokenURL, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {op.ClientID},
+		"client_secret": {op.ClientSecret},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("unable to get access token from %s: %s", op.Name, token.Error)
+	}
+	return token.AccessToken, nil
+}
+
+// UserInfo returns the info of the user authenticated by the given access token.
+// The login of the user is its email, which must have been verified by the
+// provider, so that an account of the provider cannot log in as the local user
+// with the same email.
+func (op OAuth2Provider) UserInfo(accessToken string) (UserInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, op.UserInfoURL, nil)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	client := http.Client{Timeout: oauthTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return UserInfo{}, fmt.Errorf("unable to get user info from %s: %s", op.Name, resp.Status)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+		Name    string `json:"name"`
+		Email   string `json:"email"`
+		// EmailVerified is a string for some providers
+		EmailVerified interface{} `json:"email_verified"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return UserInfo{}, err
+	}
+	if claims.Email == "" {
+		return UserInfo{}, fmt.Errorf("no email returned by %s for subject %s", op.Name, claims.Subject)
+	}
+	if !cast.ToBool(claims.EmailVerified) {
+		return UserInfo{}, fmt.Errorf("email of subject %s is not verified by %s", claims.Subject, op.Name)
+	}
+	info := UserInfo{Login: claims.Email, Name: claims.Name, Email: claims.Email}
+	if info.Name == "" {
+		info.Name = info.Login
+	}
+	return info, nil
+}
+
+// redirectURL returns the callback URL of the given provider for this server
+func redirectURL(ctx *server.Context, provider string) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/auth/oauth/%s/callback", scheme, ctx.Request.Host, provider)
+}
+
+// oauthLogin redirects the user to the consent page of the provider
+func oauthLogin(ctx *server.Context) {
+	provider, ok := GetOAuth2Provider(ctx.DBName(), ctx.Param("provider"))
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		log.Panic("Unable to generate OAuth2 state", "error", err)
+	}
+	state := base64.RawURLEncoding.EncodeToString(buf)
+	ctx.Session().Set(oauthStateSessionKey, state)
+	if err := ctx.Session().Save(); err != nil {
+		log.Warn("Unable to save OAuth2 state in session", "error", err)
+	}
+	ctx.Redirect(http.StatusFound, provider.AuthCodeURL(redirectURL(ctx, provider.Name), state))
+}
+
+// oauthCallback logs the user in after its authentication by the provider.
+// Users who must pass the second factor are redirected to its form.
+func oauthCallback(ctx *server.Context) {
+	provider, ok := GetOAuth2Provider(ctx.DBName(), ctx.Param("provider"))
+	if !ok {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	state, _ := ctx.Session().Get(oauthStateSessionKey).(string)
+	ctx.Session().Delete(oauthStateSessionKey)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(ctx.Query("state"))) != 1 {
+		log.Warn("Invalid OAuth2 state", "provider", provider.Name, "ip", ctx.ClientIP())
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	token, err := provider.Exchange(ctx.Query("code"), redirectURL(ctx, provider.Name))
+	if err != nil {
+		log.Warn("OAuth2 authentication failed", "provider", provider.Name, "error", err)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	info, err := provider.UserInfo(token)
+	if err != nil {
+		log.Warn("OAuth2 authentication failed", "provider", provider.Name, "error", err)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	uid, err := resolveUser(ctx.DBName(), info, provider.Provision)
+	if err != nil {
+		log.Warn("OAuth2 user not allowed", "provider", provider.Name, "login", info.Login, "error", err)
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	ctx.Session().Set("login", info.Login)
+	if !CheckSecondFactor(ctx, uid) {
+		ctx.Redirect(http.StatusFound, "/auth/totp/verify?redirect=%2Fweb")
+		return
+	}
+	completeLogin(ctx, uid)
+	ctx.Redirect(http.StatusFound, "/web")
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/auth/oauth")
+	grp.AddController(http.MethodGet, "/:provider", oauthLogin)
+	grp.AddController(http.MethodGet, "/:provider/callback", oauthCallback)
+}
@@ -0,0 +1,396 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/mail"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/emailutils"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// Signup policies, set in the Auth.Signup configuration
+const (
+	// SignupNone disables signup. This is the default.
+	SignupNone = "none"
+	// SignupInvite allows only invited users to sign up.
+	SignupInvite = "invite"
+	// SignupFree allows anyone to sign up.
+	SignupFree = "free"
+)
+
+// Token purposes
+const (
+	resetTokenPurpose  = "reset"
+	signupTokenPurpose = "signup"
+)
+
+const (
+	// defaultResetTokenValidity is the default validity of password reset tokens
+	defaultResetTokenValidity = 24 * time.Hour
+	// defaultSignupTokenValidity is the default validity of invitation tokens
+	defaultSignupTokenValidity = 7 * 24 * time.Hour
+	// defaultPasswordMinLength is the default minimum length of new passwords
+	defaultPasswordMinLength = 8
+)
+
+// ErrInvalidToken is returned when a signup or reset token is invalid or expired
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// SetPassword sets the password of the given user.
+//
+// Password resets and signups fail as long as it is not set.
+var SetPassword func(env models.Environment, uid int64, password string)
+
+// UserEmail returns the email address of the given user, to which reset
+// and invitation links are sent. If it is not set or returns an empty
+// string, the links are sent to the login of the user when it is an
+// email address.
+var UserEmail func(env models.Environment, uid int64) string
+
+// UserTokenSalt returns a string that changes each time the credentials of
+// the given user change (e.g. its password hash). It is included in signup
+// and reset tokens, in addition to the last update of the User record.
+//
+// This function is optional.
+var UserTokenSalt func(env models.Environment, uid int64) string
+
+// SignupPolicy returns the signup policy of the given database
+func SignupPolicy(dbName string) string {
+	switch policy := settingString(dbName, "Signup"); policy {
+	case SignupInvite, SignupFree:
+		return policy
+	default:
+		return SignupNone
+	}
+}
+
+// userTokenSalt returns the token salt of the given user. It holds the last
+// update of the User record, so that tokens are invalidated when the password
+// is set, and the result of UserTokenSalt if it is set.
+func userTokenSalt(env models.Environment, uid int64) string {
+	var salt string
+	if userModel, ok := models.Registry.Get("User"); ok {
+		user := env.Pool(userModel.Name()).Sudo().Search(userModel.Field(models.ID).Equals(uid))
+		if !user.IsEmpty() {
+			salt = strconv.FormatInt(user.Get(userModel.FieldName("LastUpdate")).(dates.DateTime).UnixNano(), 10)
+		}
+	}
+	if UserTokenSalt != nil {
+		salt += "\x00" + UserTokenSalt(env, uid)
+	}
+	return salt
+}
+
+// accountBaseURL returns the base URL of the links sent by email to the users
+// of the given database. It is given by the Auth.BaseURL setting, or derived
+// from Server.Domain. The host of the request is never used since it is given
+// by the client.
+func accountBaseURL(dbName string) (string, error) {
+	if base := settingString(dbName, "BaseURL"); base != "" {
+		return strings.TrimSuffix(base, "/"), nil
+	}
+	if domain := viper.GetString("Server.Domain"); domain != "" {
+		return "https://" + domain, nil
+	}
+	return "", errors.New("no base URL configured for account links: set Auth.BaseURL")
+}
+
+// userToken returns a signed token for the given purpose and user
+func userToken(env models.Environment, purpose string, uid int64, validity time.Duration) string {
+	return SignedToken(time.Now().Add(validity), purpose, env.DBName(), strconv.FormatInt(uid, 10), sign(userTokenSalt(env, uid)))
+}
+
+// checkUserToken returns the user ID of the given token if it is a valid
+// token for the given purposes in the database of env.
+func checkUserToken(env models.Environment, token string, purposes ...string) (int64, error) {
+	fields, ok := ParseSignedToken(token)
+	if !ok || len(fields) != 4 || fields[1] != env.DBName() {
+		return 0, ErrInvalidToken
+	}
+	validPurpose := false
+	for _, p := range purposes {
+		if fields[0] == p {
+			validPurpose = true
+		}
+	}
+	uid, err := strconv.ParseInt(fields[2], 10, 64)
+	if !validPurpose || err != nil || fields[3] != sign(userTokenSalt(env, uid)) {
+		return 0, ErrInvalidToken
+	}
+	return uid, nil
+}
+
+// sendTokenEmail sends to the given user an email with a link
+// holding a token for the given purpose.
+func sendTokenEmail(env models.Environment, uid int64, login, purpose string) error {
+	baseURL, err := accountBaseURL(env.DBName())
+	if err != nil {
+		return err
+	}
+	email := login
+	if UserEmail != nil {
+		if e := UserEmail(env, uid); e != "" {
+			email = e
+		}
+	}
+	if !emailutils.IsValidAddress(email) {
+		return fmt.Errorf("no valid email address for user %d", uid)
+	}
+	var subject, path, intro string
+	var validity time.Duration
+	switch purpose {
+	case resetTokenPurpose:
+		subject, path = "Password reset", "/auth/account/reset_password"
+		intro = "A password reset was requested for your account."
+		validity = cast.ToDuration(setting(env.DBName(), "ResetTokenValidity"))
+		if validity <= 0 {
+			validity = defaultResetTokenValidity
+		}
+	default:
+		subject, path = "Invitation", "/auth/account/signup"
+		intro = "You have been invited to create your account."
+		validity = cast.ToDuration(setting(env.DBName(), "SignupTokenValidity"))
+		if validity <= 0 {
+			validity = defaultSignupTokenValidity
+		}
+	}
+	link := fmt.Sprintf("%s%s?token=%s", baseURL, path, userToken(env, purpose, uid, validity))
+	mail.Enqueue(mail.Message{
+		To:      []string{email},
+		Subject: subject,
+		Body:    fmt.Sprintf("%s\n\nFollow this link to set your password:\n%s\n\nThis link is valid until %s.\n", intro, link, time.Now().Add(validity).Format(time.RFC1123)),
+	})
+	return nil
+}
+
+// RequestPasswordReset sends a password reset email to the user with the given login.
+// The reset link points to the base URL given by the Auth.BaseURL setting.
+func RequestPasswordReset(env models.Environment, login string) error {
+	if ResolveUser == nil {
+		return errors.New("no user resolver defined")
+	}
+	uid, err := ResolveUser(env, UserInfo{Login: login}, false)
+	if err != nil {
+		return err
+	}
+	return sendTokenEmail(env, uid, login, resetTokenPurpose)
+}
+
+// InviteUser sends an invitation email to the given existing user so that it
+// can set its password. The link points to the base URL given by the
+// Auth.BaseURL setting.
+func InviteUser(env models.Environment, uid int64, login string) error {
+	return sendTokenEmail(env, uid, login, signupTokenPurpose)
+}
+
+// checkPassword returns an error if the given password is too weak
+func checkPassword(dbName, password string) error {
+	minLength := cast.ToInt(setting(dbName, "PasswordMinLength"))
+	if minLength <= 0 {
+		minLength = defaultPasswordMinLength
+	}
+	if len([]rune(password)) < minLength {
+		return fmt.Errorf("password must have at least %d characters", minLength)
+	}
+	return nil
+}
+
+// ResetPassword sets the password of the user of the given
+// reset or invitation token.
+func ResetPassword(env models.Environment, token, password string) (int64, error) {
+	if SetPassword == nil {
+		return 0, errors.New("no password setter defined")
+	}
+	uid, err := checkUserToken(env, token, resetTokenPurpose, signupTokenPurpose)
+	if err != nil {
+		return 0, err
+	}
+	if err = checkPassword(env.DBName(), password); err != nil {
+		return 0, err
+	}
+	SetPassword(env, uid, password)
+	log.Info("Password reset", "uid", uid, "database", env.DBName())
+	return uid, nil
+}
+
+// Signup creates a new user with the given info and password.
+// It fails unless the signup policy of the database is SignupFree.
+func Signup(env models.Environment, info UserInfo, password string) (int64, error) {
+	if SignupPolicy(env.DBName()) != SignupFree {
+		return 0, errors.New("signup is not allowed")
+	}
+	if ResolveUser == nil || SetPassword == nil {
+		return 0, errors.New("no user resolver or password setter defined")
+	}
+	if !emailutils.IsValidAddress(info.Login) {
+		return 0, errors.New("login must be a valid email address")
+	}
+	if err := checkPassword(env.DBName(), password); err != nil {
+		return 0, err
+	}
+	if _, err := ResolveUser(env, info, false); err == nil {
+		return 0, errors.New("a user with this login already exists")
+	}
+	uid, err := ResolveUser(env, info, true)
+	if err != nil {
+		return 0, err
+	}
+	SetPassword(env, uid, password)
+	log.Info("User signed up", "uid", uid, "login", info.Login, "database", env.DBName())
+	return uid, nil
+}
+
+// accountFormTemplate is the template of the public reset and signup forms
+var accountFormTemplate = template.Must(template.New("account").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{ .Title }}</title></head>
+<body>
+<h1>{{ .Title }}</h1>
+{{ if .Message }}<p class="message">{{ .Message }}</p>{{ end }}
+{{ if .QRCodeURL }}<p><img src="{{ .QRCodeURL }}" alt="QR code to scan with an authenticator app"></p>{{ end }}
+{{ if .ShowForm }}<form method="post"{{ if .Action }} action="{{ .Action }}"{{ end }}>
+<input type="hidden" name="csrf_token" value="{{ .CSRFToken }}">
+{{ if .Token }}<input type="hidden" name="token" value="{{ .Token }}">{{ end }}
+{{ if .Redirect }}<input type="hidden" name="redirect" value="{{ .Redirect }}">{{ end }}
+{{ if .AskLogin }}<label>Email <input type="email" name="login" required></label><br>{{ end }}
+{{ if .AskName }}<label>Name <input type="text" name="name" required></label><br>{{ end }}
+{{ if .AskPassword }}<label>Password <input type="password" name="password" required></label><br>{{ end }}
+{{ if .AskCode }}<label>Code <input type="text" name="code" inputmode="numeric" autocomplete="one-time-code" required></label><br>{{ end }}
+<button type="submit">{{ .Title }}</button>
+</form>{{ end }}
+</body></html>`))
+
+// accountForm holds the data of the account form template
+type accountForm struct {
+	Title       string
+	Message     string
+	Token       string
+	Redirect    string
+	Action      string
+	QRCodeURL   string
+	CSRFToken   string
+	ShowForm    bool
+	AskLogin    bool
+	AskName     bool
+	AskPassword bool
+	AskCode     bool
+}
+
+// renderAccountForm renders the account form with the given data
+func renderAccountForm(ctx *server.Context, code int, form accountForm) {
+	form.CSRFToken = ctx.CSRFToken()
+	ctx.Status(code)
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	if err := accountFormTemplate.Execute(ctx.Writer, form); err != nil {
+		log.Warn("Unable to render account form", "error", err)
+	}
+}
+
+// resetPasswordRequestForm displays the form to request a password reset
+func resetPasswordRequestForm(ctx *server.Context) {
+	renderAccountForm(ctx, http.StatusOK, accountForm{Title: "Reset password", ShowForm: true, AskLogin: true})
+}
+
+// resetPasswordRequest sends a password reset email.
+// It always answers the same way so as not to disclose which logins exist.
+func resetPasswordRequest(ctx *server.Context) {
+	login := ctx.PostForm("login")
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		if err := RequestPasswordReset(env, login); err != nil {
+			log.Info("Password reset not sent", "login", login, "error", err)
+		}
+	})
+	if err != nil {
+		log.Warn("Unable to request password reset", "login", login, "error", err)
+	}
+	renderAccountForm(ctx, http.StatusOK, accountForm{Title: "Reset password",
+		Message: "If an account exists for this login, an email has been sent with instructions to reset the password."})
+}
+
+// resetPasswordForm displays the form to set a new password with a token
+func resetPasswordForm(ctx *server.Context) {
+	renderAccountForm(ctx, http.StatusOK, accountForm{Title: "Set password", ShowForm: true,
+		AskPassword: true, Token: ctx.Query("token")})
+}
+
+// resetPassword sets a new password with a token
+func resetPassword(ctx *server.Context) {
+	var rErr error
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		_, rErr = ResetPassword(env, ctx.PostForm("token"), ctx.PostForm("password"))
+	})
+	if err == nil {
+		err = rErr
+	}
+	if err != nil {
+		renderAccountForm(ctx, http.StatusBadRequest, accountForm{Title: "Set password", Message: err.Error(),
+			ShowForm: err != ErrInvalidToken, AskPassword: true, Token: ctx.PostForm("token")})
+		return
+	}
+	renderAccountForm(ctx, http.StatusOK, accountForm{Title: "Set password", Message: "Your password has been set. You can now log in."})
+}
+
+// signupForm displays the signup form. With a token, it displays
+// the invitation form to set the password of an invited user.
+func signupForm(ctx *server.Context) {
+	if token := ctx.Query("token"); token != "" {
+		resetPasswordForm(ctx)
+		return
+	}
+	if SignupPolicy(ctx.DBName()) != SignupFree {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	renderAccountForm(ctx, http.StatusOK, accountForm{Title: "Sign up", ShowForm: true,
+		AskLogin: true, AskName: true, AskPassword: true})
+}
+
+// signup creates a new account, or sets the password of an invited user
+func signup(ctx *server.Context) {
+	if ctx.PostForm("token") != "" {
+		resetPassword(ctx)
+		return
+	}
+	if SignupPolicy(ctx.DBName()) != SignupFree {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	info := UserInfo{Login: ctx.PostForm("login"), Name: ctx.PostForm("name"), Email: ctx.PostForm("login")}
+	var rErr error
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		_, rErr = Signup(env, info, ctx.PostForm("password"))
+	})
+	if err == nil {
+		err = rErr
+	}
+	if err != nil {
+		renderAccountForm(ctx, http.StatusBadRequest, accountForm{Title: "Sign up", Message: err.Error(),
+			ShowForm: true, AskLogin: true, AskName: true, AskPassword: true})
+		return
+	}
+	renderAccountForm(ctx, http.StatusOK, accountForm{Title: "Sign up", Message: "Your account has been created. You can now log in."})
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/auth/account")
+	grp.AddController(http.MethodGet, "/reset_password/request", resetPasswordRequestForm)
+	grp.AddController(http.MethodPost, "/reset_password/request", resetPasswordRequest)
+	grp.AddController(http.MethodGet, "/reset_password", resetPasswordForm)
+	grp.AddController(http.MethodPost, "/reset_password", resetPassword)
+	grp.AddController(http.MethodGet, "/signup", signupForm)
+	grp.AddController(http.MethodPost, "/signup", signup)
+}
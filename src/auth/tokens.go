@@ -0,0 +1,81 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// tokenSeparator separates the fields of signed tokens
+const tokenSeparator = "|"
+
+var (
+	secretKey     []byte
+	secretKeyOnce sync.Once
+)
+
+// signingKey returns the key used to sign tokens.
+//
+// It is taken from the Auth.SecretKey configuration. If it is not set, a
+// random key is generated, so that all tokens are invalidated when the
+// server restarts.
+func signingKey() []byte {
+	secretKeyOnce.Do(func() {
+		if key := viper.GetString("Auth.SecretKey"); key != "" {
+			secretKey = []byte(key)
+			return
+		}
+		log.Warn("No Auth.SecretKey set: tokens will be invalidated when the server restarts")
+		secretKey = make([]byte, 32)
+		if _, err := rand.Read(secretKey); err != nil {
+			log.Panic("Unable to generate secret key", "error", err)
+		}
+	})
+	return secretKey
+}
+
+// sign returns the signature of the given payload
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignedToken returns a token holding the given fields, signed with
+// the Auth.SecretKey, that expires at the given time. The first field
+// should identify the purpose of the token, so that a token issued for
+// a feature cannot be used for another one.
+func SignedToken(expiry time.Time, fields ...string) string {
+	fields = append([]string{strconv.FormatInt(expiry.Unix(), 10)}, fields...)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(strings.Join(fields, tokenSeparator)))
+	return payload + "." + sign(payload)
+}
+
+// ParseSignedToken returns the fields of the given token.
+// The returned boolean is false if the token is invalid or expired.
+func ParseSignedToken(token string) ([]string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(sign(parts[0]))) {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, false
+	}
+	fields := strings.Split(string(payload), tokenSeparator)
+	expiry, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || time.Now().Unix() >= expiry {
+		return nil, false
+	}
+	return fields[1:], true
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/tools/barcode"
+)
+
+const (
+	// totpPeriod is the validity period of a TOTP code
+	totpPeriod = 30 * time.Second
+	// totpDigits is the number of digits of TOTP codes
+	totpDigits = 6
+	// totpSkew is the number of periods before and after the current
+	// one during which a code is accepted to allow for clock drift.
+	totpSkew = 1
+	// totpSecretSize is the size in bytes of TOTP secrets
+	totpSecretSize = 20
+)
+
+// totpEncoding is the encoding of TOTP secrets as expected by authenticator apps
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random base32 encoded TOTP secret
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPCode returns the TOTP code of the given secret at the given time
+// as defined in RFC 6238.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.Replace(secret, " ", "", -1)))
+	if err != nil {
+		return "", err
+	}
+	return hotpCode(key, uint64(t.Unix()/int64(totpPeriod.Seconds()))), nil
+}
+
+// hotpCode returns the HOTP code of the given key and counter as defined in RFC 4226
+func hotpCode(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// VerifyTOTP returns true if the given code is valid for the given secret
+// at the given time, allowing for a small clock drift.
+func VerifyTOTP(secret, code string, t time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	valid := false
+	for i := -totpSkew; i <= totpSkew; i++ {
+		expected, err := TOTPCode(secret, t.Add(time.Duration(i)*totpPeriod))
+		if err != nil {
+			return false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// TOTPProvisioningURI returns the otpauth URI to register the given secret
+// of the given account in an authenticator app.
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	values := url.Values{
+		"secret": {secret},
+		"issuer": {issuer},
+	}
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, values.Encode())
+}
+
+// TOTPQRCode returns a PNG image of the given size in pixels of the QR code
+// of the given provisioning URI.
+func TOTPQRCode(uri string, size int) ([]byte, error) {
+	return barcode.Generate(barcode.QR, uri, size, size)
+}
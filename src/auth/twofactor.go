@@ -0,0 +1,316 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/cast"
+)
+
+const (
+	// pendingUIDSessionKey is the session key of the user that
+	// has been authenticated but must still pass the second factor.
+	pendingUIDSessionKey = "pending_uid"
+	// pendingSecretSessionKey is the session key of a TOTP secret
+	// that has been provisioned but not confirmed yet.
+	pendingSecretSessionKey = "totp_pending_secret"
+	// TrustedDeviceCookie is the name of the cookie that exempts
+	// a device from the second factor.
+	TrustedDeviceCookie = "hexya_trusted_device"
+	// defaultTrustedDeviceDuration is the default validity of trusted device cookies
+	defaultTrustedDeviceDuration = 30 * 24 * time.Hour
+	// qrCodeSize is the size in pixels of TOTP QR codes
+	qrCodeSize = 256
+	// maxTOTPAttempts is the number of TOTP codes that can be
+	// checked for a user within totpAttemptsPeriod.
+	maxTOTPAttempts = 5
+	// totpAttemptsPeriod is the period over which the TOTP codes
+	// checked for a user are counted.
+	totpAttemptsPeriod = 15 * time.Minute
+)
+
+// totpAttempts limits the number of TOTP codes checked for each user, so that
+// codes cannot be guessed by someone who knows the password of the user.
+var totpAttempts = server.NewRateLimiter(maxTOTPAttempts, totpAttemptsPeriod, 0)
+
+// GetTOTPSecret returns the TOTP secret of the given user, or an empty
+// string if the user has not enabled two-factor authentication.
+//
+// Users log in without second factor as long as it is not set.
+var GetTOTPSecret func(env models.Environment, uid int64) string
+
+// SetTOTPSecret sets the TOTP secret of the given user, enabling
+// two-factor authentication for this user. It is stored with
+// GetTOTPSecret, typically in a field of the User model.
+var SetTOTPSecret func(env models.Environment, uid int64, secret string)
+
+// totpSecret returns the TOTP secret of the given user in the given database
+func totpSecret(dbName string, uid int64) string {
+	if GetTOTPSecret == nil {
+		return ""
+	}
+	var secret string
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		secret = GetTOTPSecret(env, uid)
+	})
+	if err != nil {
+		log.Warn("Unable to get TOTP secret", "uid", uid, "error", err)
+	}
+	return secret
+}
+
+// TwoFactorRequired returns true if the given user of the given database must
+// pass the second factor to log in. This is the case if the user has enabled
+// two-factor authentication or if it is a member of one of the groups listed
+// in the Auth.TOTP.Groups configuration.
+func TwoFactorRequired(dbName string, uid int64) bool {
+	if totpSecret(dbName, uid) != "" {
+		return true
+	}
+	for _, groupID := range cast.ToStringSlice(setting(dbName, "TOTP.Groups")) {
+		group := security.Registry.GetGroup(groupID)
+		if group != nil && security.Registry.ForDatabase(dbName).HasMembership(uid, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSecondFactor must be called by login controllers once the user with the
+// given uid has been authenticated by its password. It returns true if the login
+// can be completed, that is if the second factor is not required or if the
+// request comes from a trusted device.
+//
+// Otherwise, the user is stored as pending in the session and false is returned.
+// The client must then post a TOTP code to /auth/totp/verify, or provision a
+// secret with /auth/totp/qrcode and /auth/totp/enable if it has none yet.
+// Pending users who already have a secret cannot provision a new one.
+func CheckSecondFactor(ctx *server.Context, uid int64) bool {
+	if !TwoFactorRequired(ctx.DBName(), uid) || isTrustedDevice(ctx, uid) {
+		return true
+	}
+	ctx.Session().Set(pendingUIDSessionKey, uid)
+	if err := ctx.Session().Save(); err != nil {
+		log.Warn("Unable to save session", "error", err)
+	}
+	return false
+}
+
+// trustedDeviceToken returns a signed token that marks a device as
+// trusted for the given user and database until the given time.
+func trustedDeviceToken(dbName string, uid int64, expiry time.Time) string {
+	return SignedToken(expiry, TrustedDeviceCookie, dbName, strconv.FormatInt(uid, 10))
+}
+
+// checkTrustedDeviceToken returns true if the given token is a valid
+// trusted device token for the given user and database.
+func checkTrustedDeviceToken(token, dbName string, uid int64) bool {
+	fields, ok := ParseSignedToken(token)
+	return ok && len(fields) == 3 && fields[0] == TrustedDeviceCookie &&
+		fields[1] == dbName && fields[2] == strconv.FormatInt(uid, 10)
+}
+
+// isTrustedDevice returns true if the request comes from a trusted device of the given user
+func isTrustedDevice(ctx *server.Context, uid int64) bool {
+	token, err := ctx.Cookie(TrustedDeviceCookie)
+	return err == nil && checkTrustedDeviceToken(token, ctx.DBName(), uid)
+}
+
+// trustDevice sets the trusted device cookie for the given user
+func trustDevice(ctx *server.Context, uid int64) {
+	duration := cast.ToDuration(setting(ctx.DBName(), "TOTP.TrustedDeviceDuration"))
+	if duration <= 0 {
+		duration = defaultTrustedDeviceDuration
+	}
+	token := trustedDeviceToken(ctx.DBName(), uid, time.Now().Add(duration))
+	ctx.SetCookie(TrustedDeviceCookie, token, int(duration.Seconds()), "/", "", ctx.Request.TLS != nil, true)
+}
+
+// completeLogin logs the pending user of the session in
+func completeLogin(ctx *server.Context, uid int64) {
+	ctx.Session().Delete(pendingUIDSessionKey)
+	ctx.Session().Set("uid", uid)
+	if err := ctx.Session().Save(); err != nil {
+		log.Warn("Unable to save session", "error", err)
+	}
+	if ctx.PostForm("remember") == "true" {
+		trustDevice(ctx, uid)
+	}
+}
+
+// sessionUser returns the logged in or pending user of the session
+// and true if it is pending.
+func sessionUser(ctx *server.Context) (int64, bool) {
+	if uid, ok := ctx.Session().Get("uid").(int64); ok {
+		return uid, false
+	}
+	uid, _ := ctx.Session().Get(pendingUIDSessionKey).(int64)
+	return uid, true
+}
+
+// checkTOTPCode returns true if the given code is valid for the given secret
+// of the given user. It returns false without checking the code if too many
+// codes have been checked for this user recently.
+func checkTOTPCode(ctx *server.Context, uid int64, secret, code string) bool {
+	if !totpAttempts.Allow(fmt.Sprintf("%s:%d", ctx.DBName(), uid)) {
+		log.Warn("Too many TOTP attempts", "uid", uid, "ip", ctx.RemoteIP())
+		return false
+	}
+	return VerifyTOTP(secret, code, time.Now())
+}
+
+// totpQRCode provisions a new TOTP secret for the user
+// and returns the QR code to scan with an authenticator app.
+//
+// Pending users, who have only given their password, can only
+// provision a secret if they have none yet.
+func totpQRCode(ctx *server.Context) {
+	uid, pending := sessionUser(ctx)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if pending && totpSecret(ctx.DBName(), uid) != "" {
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		log.Panic("Unable to generate TOTP secret", "error", err)
+	}
+	ctx.Session().Set(pendingSecretSessionKey, secret)
+	if err = ctx.Session().Save(); err != nil {
+		log.Warn("Unable to save session", "error", err)
+	}
+	issuer := settingString(ctx.DBName(), "TOTP.Issuer")
+	if issuer == "" {
+		issuer = "Hexya"
+	}
+	account, _ := ctx.Session().Get("login").(string)
+	if account == "" {
+		account = strconv.FormatInt(uid, 10)
+	}
+	img, err := TOTPQRCode(TOTPProvisioningURI(issuer, account, secret), qrCodeSize)
+	if err != nil {
+		log.Panic("Unable to generate TOTP QR code", "error", err)
+	}
+	ctx.Data(http.StatusOK, "image/png", img)
+}
+
+// totpEnable confirms the provisioned secret with a code and enables
+// two-factor authentication for the user.
+//
+// Users who already have a secret must be logged in and give a valid
+// code of their current secret in the current_code field to replace it.
+func totpEnable(ctx *server.Context) {
+	uid, pending := sessionUser(ctx)
+	secret, _ := ctx.Session().Get(pendingSecretSessionKey).(string)
+	if uid == 0 || secret == "" || SetTOTPSecret == nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if current := totpSecret(ctx.DBName(), uid); current != "" {
+		if pending {
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		if !checkTOTPCode(ctx, uid, current, ctx.PostForm("current_code")) {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+	if !VerifyTOTP(secret, ctx.PostForm("code"), time.Now()) {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		SetTOTPSecret(env, uid, secret)
+	})
+	if err != nil {
+		log.Panic("Unable to save TOTP secret", "uid", uid, "error", err)
+	}
+	ctx.Session().Delete(pendingSecretSessionKey)
+	log.Info("Two-factor authentication enabled", "uid", uid, "database", ctx.DBName())
+	if pending {
+		completeLogin(ctx, uid)
+	} else if err = ctx.Session().Save(); err != nil {
+		log.Warn("Unable to save session", "error", err)
+	}
+	if redirect := localRedirect(ctx.PostForm("redirect")); redirect != "" {
+		ctx.Redirect(http.StatusSeeOther, redirect)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]bool{"result": true})
+}
+
+// totpVerifyForm displays the form with which the pending user gives its
+// TOTP code, or provisions a secret if it has none yet. Login controllers
+// that are not called by the web client, such as the OAuth2 callback,
+// redirect the users who must pass the second factor to this form.
+func totpVerifyForm(ctx *server.Context) {
+	uid, pending := sessionUser(ctx)
+	if uid == 0 || !pending {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	form := accountForm{Title: "Two-factor authentication", ShowForm: true,
+		AskCode: true, Redirect: localRedirect(ctx.Query("redirect"))}
+	if totpSecret(ctx.DBName(), uid) == "" {
+		form.Message = "Scan this QR code with your authenticator app and enter the code it displays."
+		form.QRCodeURL = "/auth/totp/qrcode"
+		form.Action = "/auth/totp/enable"
+	}
+	renderAccountForm(ctx, http.StatusOK, form)
+}
+
+// localRedirect returns the given URL if it is a path of this server,
+// or an empty string otherwise.
+func localRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return ""
+	}
+	return redirect
+}
+
+// totpVerify checks the TOTP code of the pending user and logs it in.
+// Only maxTOTPAttempts codes are checked for a user within totpAttemptsPeriod.
+//
+// If a local redirect URL is posted, as by totpVerifyForm, the user is
+// redirected to it once logged in.
+func totpVerify(ctx *server.Context) {
+	uid, pending := sessionUser(ctx)
+	if uid == 0 || !pending {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	secret := totpSecret(ctx.DBName(), uid)
+	if secret == "" || !checkTOTPCode(ctx, uid, secret, ctx.PostForm("code")) {
+		log.Warn("Invalid TOTP code", "uid", uid, "ip", ctx.RemoteIP())
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	completeLogin(ctx, uid)
+	if redirect := localRedirect(ctx.PostForm("redirect")); redirect != "" {
+		ctx.Redirect(http.StatusSeeOther, redirect)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]bool{"result": true})
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/auth/totp")
+	grp.AddController(http.MethodGet, "/qrcode", totpQRCode)
+	grp.AddController(http.MethodPost, "/enable", totpEnable)
+	grp.AddController(http.MethodGet, "/verify", totpVerifyForm)
+	grp.AddController(http.MethodPost, "/verify", totpVerify)
+}
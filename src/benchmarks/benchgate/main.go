@@ -0,0 +1,65 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Command benchgate compares two outputs of go test -bench and exits with
+// a non zero status if a benchmark present in both regressed by more than
+// the given threshold in operations per second.
+//
+// Usage:
+//
+//	benchgate [-threshold percent] old.txt new.txt
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hexya-erp/hexya/src/benchmarks"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 10, "Maximum allowed drop of operations per second in percent")
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: benchgate [-threshold percent] old.txt new.txt")
+		os.Exit(2)
+	}
+	oldResults := readResults(flag.Arg(0))
+	newResults := readResults(flag.Arg(1))
+	comparisons := benchmarks.Compare(oldResults, newResults)
+	if len(comparisons) == 0 {
+		fmt.Fprintln(os.Stderr, "no common benchmark found")
+		os.Exit(2)
+	}
+	var regressions int
+	for _, comp := range comparisons {
+		line := comp.String()
+		if comp.Regressed(*threshold) {
+			line += "  REGRESSION"
+			regressions++
+		}
+		fmt.Println(line)
+	}
+	if regressions > 0 {
+		fmt.Printf("%d benchmark(s) regressed by more than %.1f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+// readResults reads the benchmark results of the given file
+// and exits if it cannot be read.
+func readResults(fileName string) map[string]*benchmarks.Result {
+	f, err := os.Open(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer f.Close()
+	res, err := benchmarks.ParseResults(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return res
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package benchmarks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// benchLineRE matches the result lines of the output of go test -bench,
+// capturing the benchmark name without its GOMAXPROCS suffix and ns/op.
+var benchLineRE = regexp.MustCompile(`^(Benchmark\S+?)(?:-\d+)?\s+\d+\s+([\d.]+) ns/op`)
+
+// A Result holds the timings of all the runs of a benchmark
+type Result struct {
+	Name    string
+	NsPerOp []float64
+}
+
+// MedianNsPerOp returns the median duration of an operation
+// in nanoseconds over all runs of the benchmark.
+func (r *Result) MedianNsPerOp() float64 {
+	if len(r.NsPerOp) == 0 {
+		return 0
+	}
+	values := make([]float64, len(r.NsPerOp))
+	copy(values, r.NsPerOp)
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+// OpsPerSec returns the number of operations per second of the benchmark
+// computed from the median duration of its runs.
+func (r *Result) OpsPerSec() float64 {
+	ns := r.MedianNsPerOp()
+	if ns == 0 {
+		return 0
+	}
+	return 1e9 / ns
+}
+
+// ParseResults parses the output of go test -bench and returns the
+// results by benchmark name. Benchmarks run several times (with -count)
+// are aggregated into a single Result.
+func ParseResults(r io.Reader) (map[string]*Result, error) {
+	res := make(map[string]*Result)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := benchLineRE.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		ns, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timing for %s: %s", match[1], err)
+		}
+		if _, ok := res[match[1]]; !ok {
+			res[match[1]] = &Result{Name: match[1]}
+		}
+		res[match[1]].NsPerOp = append(res[match[1]].NsPerOp, ns)
+	}
+	return res, scanner.Err()
+}
+
+// A Comparison is the comparison of the results of a benchmark
+// before and after a change.
+type Comparison struct {
+	Name string
+	// Old is the number of operations per second before the change
+	Old float64
+	// New is the number of operations per second after the change
+	New float64
+	// Delta is the variation of operations per second in percent
+	Delta float64
+}
+
+// Regressed returns true if the number of operations per second
+// dropped by more than threshold percent.
+func (c Comparison) Regressed(threshold float64) bool {
+	return c.Delta < -threshold
+}
+
+// String returns a human readable line describing this Comparison
+func (c Comparison) String() string {
+	return fmt.Sprintf("%-50s %12.1f ops/s %12.1f ops/s %+8.2f%%", c.Name, c.Old, c.New, c.Delta)
+}
+
+// Compare compares the benchmarks present in both old and new results
+// and returns the comparisons sorted by benchmark name.
+func Compare(oldResults, newResults map[string]*Result) []Comparison {
+	var res []Comparison
+	for name, oldRes := range oldResults {
+		newRes, ok := newResults[name]
+		if !ok {
+			continue
+		}
+		comp := Comparison{Name: name, Old: oldRes.OpsPerSec(), New: newRes.OpsPerSec()}
+		if comp.Old != 0 {
+			comp.Delta = (comp.New - comp.Old) / comp.Old * 100
+		}
+		res = append(res, comp)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package benchmarks
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const oldOutput = `goos: linux
+goarch: amd64
+pkg: github.com/hexya-erp/hexya/src/benchmarks
+BenchmarkSearch/records=10-8         	    2000	    500000 ns/op	   12000 B/op	     300 allocs/op
+BenchmarkSearch/records=10-8         	    2000	    520000 ns/op	   12000 B/op	     300 allocs/op
+BenchmarkSearch/records=10-8         	    2000	    480000 ns/op	   12000 B/op	     300 allocs/op
+BenchmarkWrite/records=10-8          	    1000	   1000000 ns/op
+BenchmarkCreate/records=10-8         	    1000	   1000000 ns/op
+PASS
+ok  	github.com/hexya-erp/hexya/src/benchmarks	12.345s
+`
+
+const newOutput = `BenchmarkSearch/records=10-8         	    2000	    400000 ns/op
+BenchmarkWrite/records=10-8          	    1000	   1250000 ns/op
+BenchmarkPrefetch/records=10-8       	    1000	   1000000 ns/op
+`
+
+func TestCompare(t *testing.T) {
+	Convey("Testing benchmark results comparison", t, func() {
+		oldResults, err := ParseResults(strings.NewReader(oldOutput))
+		So(err, ShouldBeNil)
+		newResults, err := ParseResults(strings.NewReader(newOutput))
+		So(err, ShouldBeNil)
+		Convey("Results should be parsed and aggregated by name", func() {
+			So(oldResults, ShouldHaveLength, 3)
+			So(oldResults["BenchmarkSearch/records=10"].NsPerOp, ShouldHaveLength, 3)
+			So(oldResults["BenchmarkSearch/records=10"].MedianNsPerOp(), ShouldEqual, 500000)
+			So(oldResults["BenchmarkWrite/records=10"].OpsPerSec(), ShouldEqual, 1000)
+		})
+		Convey("Only common benchmarks should be compared", func() {
+			comps := Compare(oldResults, newResults)
+			So(comps, ShouldHaveLength, 2)
+			So(comps[0].Name, ShouldEqual, "BenchmarkSearch/records=10")
+			So(comps[0].Delta, ShouldAlmostEqual, 25)
+			So(comps[0].Regressed(10), ShouldBeFalse)
+			So(comps[1].Name, ShouldEqual, "BenchmarkWrite/records=10")
+			So(comps[1].Delta, ShouldAlmostEqual, -20)
+			So(comps[1].Regressed(10), ShouldBeTrue)
+			So(comps[1].Regressed(25), ShouldBeFalse)
+		})
+	})
+}
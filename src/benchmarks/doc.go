@@ -0,0 +1,28 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+/*
+Package benchmarks holds the benchmarks of the ORM and the tools to compare
+their results between two versions of Hexya.
+
+Benchmarks cover the creation, search, update, prefetching and computation
+of records at several scales. They run against a PostgreSQL database which
+can be started with the docker-compose file of this directory:
+
+	docker-compose -f src/benchmarks/docker-compose.yml up -d
+	go test ./src/benchmarks -run XXX -bench . -count 5 > new.txt
+
+The database connection is configured with the same environment variables
+as the tests (HEXYA_DB_DRIVER, HEXYA_DB_USER, HEXYA_DB_PASSWORD and
+HEXYA_DB_PREFIX). The database is only set up when benchmarks are run, so
+that running the tests of this package does not require PostgreSQL.
+
+The benchgate command compares the results of two runs and fails if a
+benchmark regressed beyond a threshold in operations per second:
+
+	go run ./src/benchmarks/benchgate -threshold 10 old.txt new.txt
+
+The run_benchmarks.sh script at the root of the repository does all these
+steps to compare the working tree with a given git reference.
+*/
+package benchmarks
@@ -0,0 +1,87 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package benchmarks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+)
+
+// runScaled runs the given benchmark function at each scale. Each operation
+// is run in a new environment that is rolled back afterwards, so that the
+// database is left unchanged between operations.
+func runScaled(b *testing.B, fnct func(env models.Environment, size int)) {
+	for _, size := range scales {
+		b.Run(fmt.Sprintf("records=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+					fnct(env, size)
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// searchPartners returns the first size seeded partners
+func searchPartners(env models.Environment, size int) *models.RecordCollection {
+	return env.Pool("BenchPartner").SearchAll().Limit(size).Fetch()
+}
+
+func BenchmarkCreate(b *testing.B) {
+	runScaled(b, func(env models.Environment, size int) {
+		category := env.Pool("BenchCategory").SearchAll().Limit(1)
+		tag := env.Pool("BenchTag").SearchAll().Limit(1)
+		for i := 0; i < size; i++ {
+			createPartner(env, seedSize+i, category, tag)
+		}
+	})
+}
+
+func BenchmarkSearch(b *testing.B) {
+	partnerModel := models.Registry.MustGet("BenchPartner")
+	runScaled(b, func(env models.Environment, size int) {
+		partners := env.Pool("BenchPartner").Search(partnerModel.Field(partnerModel.FieldName("Age")).GreaterOrEqual(20)).
+			OrderBy("Name").Limit(size)
+		for _, partner := range partners.Records() {
+			partner.Get(partnerModel.FieldName("Email"))
+		}
+	})
+}
+
+func BenchmarkWrite(b *testing.B) {
+	partnerModel := models.Registry.MustGet("BenchPartner")
+	runScaled(b, func(env models.Environment, size int) {
+		for _, partner := range searchPartners(env, size).Records() {
+			partner.Set(partnerModel.FieldName("Email"), "updated@example.com")
+		}
+	})
+}
+
+func BenchmarkPrefetch(b *testing.B) {
+	partnerModel := models.Registry.MustGet("BenchPartner")
+	categoryModel := models.Registry.MustGet("BenchCategory")
+	runScaled(b, func(env models.Environment, size int) {
+		for _, partner := range searchPartners(env, size).Records() {
+			partner.Get(partnerModel.FieldName("Category")).(models.RecordSet).Collection().Get(categoryModel.FieldName("Name"))
+			partner.Get(partnerModel.FieldName("Tags"))
+		}
+	})
+}
+
+func BenchmarkCompute(b *testing.B) {
+	partnerModel := models.Registry.MustGet("BenchPartner")
+	runScaled(b, func(env models.Environment, size int) {
+		partners := searchPartners(env, size)
+		partners.Set(partnerModel.FieldName("Amount"), float64(size))
+		for _, partner := range partners.Records() {
+			partner.Get(partnerModel.FieldName("Total"))
+		}
+	})
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package benchmarks
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+)
+
+// scales are the numbers of records handled by each benchmark operation
+var scales = []int{10, 100, 1000}
+
+// seedSize is the number of partners created before running the benchmarks
+const seedSize = 1000
+
+var dbArgs struct {
+	Driver   string
+	User     string
+	Password string
+	DB       string
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if flag.Lookup("test.bench").Value.String() == "" {
+		// Only unit tests are run, they do not need a database
+		os.Exit(m.Run())
+	}
+	initializeBenchmarks()
+	res := m.Run()
+	tearDownBenchmarks()
+	os.Exit(res)
+}
+
+// getEnv returns the value of the given environment variable or defValue if it is empty
+func getEnv(key, defValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defValue
+}
+
+// adminDB returns a connection to the administration database
+func adminDB() *sqlx.DB {
+	return sqlx.MustConnect(dbArgs.Driver, fmt.Sprintf("dbname=postgres sslmode=disable user=%s password=%s", dbArgs.User, dbArgs.Password))
+}
+
+func initializeBenchmarks() {
+	dbArgs.Driver = getEnv("HEXYA_DB_DRIVER", "postgres")
+	dbArgs.User = getEnv("HEXYA_DB_USER", "hexya")
+	dbArgs.Password = getEnv("HEXYA_DB_PASSWORD", "hexya")
+	dbArgs.DB = fmt.Sprintf("%s_benchmarks", getEnv("HEXYA_DB_PREFIX", "hexya"))
+
+	viper.Set("LogLevel", "panic")
+	logging.Initialize()
+
+	admDB := adminDB()
+	admDB.MustExec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", dbArgs.DB))
+	admDB.MustExec(fmt.Sprintf("CREATE DATABASE %s", dbArgs.DB))
+	admDB.Close()
+
+	models.DBConnect(dbArgs.Driver, models.ConnectionParams{
+		DBName:   dbArgs.DB,
+		User:     dbArgs.User,
+		Password: dbArgs.Password,
+		SSLMode:  "disable",
+	})
+	declareModels()
+	models.BootStrap()
+	models.SyncDatabase()
+	seedData()
+}
+
+func tearDownBenchmarks() {
+	models.DBClose()
+	admDB := adminDB()
+	admDB.MustExec(fmt.Sprintf("DROP DATABASE %s", dbArgs.DB))
+	admDB.Close()
+}
+
+// declareModels declares the models used by the benchmarks
+func declareModels() {
+	category := models.NewModel("BenchCategory")
+	tag := models.NewModel("BenchTag")
+	partner := models.NewModel("BenchPartner")
+
+	partner.NewMethod("ComputeTotal",
+		func(rc *models.RecordCollection) *models.ModelData {
+			amount := rc.Get(rc.Model().FieldName("Amount")).(float64)
+			rate := rc.Get(rc.Model().FieldName("Category")).(models.RecordSet).Collection().Get(category.FieldName("Rate")).(float64)
+			return models.NewModelData(rc.Model()).Set(rc.Model().FieldName("Total"), amount*(1+rate))
+		})
+
+	category.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true},
+		"Rate": fields.Float{},
+	})
+	tag.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true},
+	})
+	partner.AddFields(map[string]models.FieldDefinition{
+		"Name":     fields.Char{Required: true, Index: true},
+		"Email":    fields.Char{},
+		"Age":      fields.Integer{},
+		"Amount":   fields.Float{},
+		"Category": fields.Many2One{RelationModel: category},
+		"Tags":     fields.Many2Many{RelationModel: tag},
+		"Total": fields.Float{Compute: partner.Methods().MustGet("ComputeTotal"),
+			Depends: []string{"Amount", "Category", "Category.Rate"}, Stored: true},
+	})
+}
+
+// seedData creates the records searched by the benchmarks
+func seedData() {
+	err := models.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		var categories, tags []models.RecordSet
+		for i := 0; i < 10; i++ {
+			categories = append(categories, env.Pool("BenchCategory").Call("Create",
+				models.NewModelData(models.Registry.MustGet("BenchCategory"), models.FieldMap{
+					"Name": fmt.Sprintf("Category %d", i),
+					"Rate": float64(i) / 100,
+				})).(models.RecordSet))
+			tags = append(tags, env.Pool("BenchTag").Call("Create",
+				models.NewModelData(models.Registry.MustGet("BenchTag"), models.FieldMap{
+					"Name": fmt.Sprintf("Tag %d", i),
+				})).(models.RecordSet))
+		}
+		for i := 0; i < seedSize; i++ {
+			createPartner(env, i, categories[i%len(categories)], tags[i%len(tags)])
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// createPartner creates the i-th partner with the given category and tag
+func createPartner(env models.Environment, i int, category, tag models.RecordSet) models.RecordSet {
+	return env.Pool("BenchPartner").Call("Create",
+		models.NewModelData(models.Registry.MustGet("BenchPartner"), models.FieldMap{
+			"Name":     fmt.Sprintf("Partner %d", i),
+			"Email":    fmt.Sprintf("partner%d@example.com", i),
+			"Age":      int64(20 + i%50),
+			"Amount":   float64(i),
+			"Category": category,
+			"Tags":     tag,
+		})).(models.RecordSet)
+}
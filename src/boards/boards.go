@@ -0,0 +1,104 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package boards
+
+import (
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/views"
+)
+
+// dashboard returns the dashboard action with the given ID
+func dashboard(actionID string) (*actions.Action, error) {
+	action := actions.Registry.GetByXMLID(actionID)
+	switch {
+	case action == nil:
+		return nil, fmt.Errorf("unknown action '%s'", actionID)
+	case action.Type != actions.ActionDashboard:
+		return nil, fmt.Errorf("action '%s' is not a dashboard", actionID)
+	}
+	return action, nil
+}
+
+// userLayout returns the BoardLayout record of the given
+// user for the given dashboard, which may be empty
+func userLayout(env models.Environment, uid int64, actionID string) *models.RecordCollection {
+	rs := env.Pool("BoardLayout").Sudo()
+	mi := rs.Model()
+	return rs.Search(mi.Field(mi.FieldName("UserID")).Equals(uid).
+		And().Field(mi.FieldName("Action")).Equals(actionID)).Limit(1)
+}
+
+// Layout returns the layout of the given dashboard action for the given
+// user: the layout saved by the user if any, or the layout of the board view
+// of the action in the language of env otherwise.
+//
+// A saved layout that is not valid anymore, for instance because one of its
+// actions has been removed, is ignored.
+func Layout(env models.Environment, uid int64, actionID string) (*views.Board, error) {
+	action, err := dashboard(actionID)
+	if err != nil {
+		return nil, err
+	}
+	rec := userLayout(env, uid, actionID)
+	if rec.IsEmpty() {
+		return action.Board(env.Lang()), nil
+	}
+	board, err := views.ParseBoard(rec.Get(rec.Model().FieldName("Arch")).(string))
+	if err == nil {
+		err = actions.Registry.CheckBoard(board)
+	}
+	if err != nil {
+		log.Warn("Ignoring invalid dashboard layout", "uid", uid, "action", actionID, "error", err)
+		return action.Board(env.Lang()), nil
+	}
+	return board, nil
+}
+
+// SaveLayout saves the given layout of the given dashboard action for the
+// given user. It returns an error if the layout is not valid.
+func SaveLayout(env models.Environment, uid int64, actionID string, board *views.Board) error {
+	if _, err := dashboard(actionID); err != nil {
+		return err
+	}
+	if err := actions.Registry.CheckBoard(board); err != nil {
+		return err
+	}
+	rec := userLayout(env, uid, actionID)
+	mi := rec.Model()
+	data := models.NewModelData(mi).Set(mi.FieldName("Arch"), board.Arch())
+	if rec.IsEmpty() {
+		rec.Call("Create", data.
+			Set(mi.FieldName("UserID"), uid).
+			Set(mi.FieldName("Action"), actionID))
+		return nil
+	}
+	rec.Call("Write", data)
+	return nil
+}
+
+// ResetLayout removes the layout saved by the given user for the given
+// dashboard action, so that the layout of its board view is used again.
+func ResetLayout(env models.Environment, uid int64, actionID string) {
+	rec := userLayout(env, uid, actionID)
+	if !rec.IsEmpty() {
+		rec.Call("Unlink")
+	}
+}
+
+// AddAction adds the given action at the top of the first column of the
+// layout of the given dashboard action for the given user and saves it.
+func AddAction(env models.Environment, uid int64, actionID string, action views.BoardAction) error {
+	board, err := Layout(env, uid, actionID)
+	if err != nil {
+		return err
+	}
+	if len(board.Columns) == 0 {
+		board.Columns = append(board.Columns, views.BoardColumn{})
+	}
+	board.Columns[0].Actions = append([]views.BoardAction{action}, board.Columns[0].Actions...)
+	return SaveLayout(env, uid, actionID, board)
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package boards
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/views"
+)
+
+// loadLayout is the controller that returns the layout of
+// a dashboard action for the logged in user.
+func loadLayout(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		ActionID string `json:"action_id"`
+	}
+	ctx.BindRPCParams(&params)
+	var res *views.Board
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		var err error
+		if res, err = Layout(env, uid, params.ActionID); err != nil {
+			log.Panic("Unable to load dashboard layout", "uid", uid, "action", params.ActionID, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// saveLayout is the controller that saves the layout of a dashboard action
+// for the logged in user. The layout is given as a board arch.
+func saveLayout(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		ActionID string `json:"action_id"`
+		Arch     string `json:"arch"`
+	}
+	ctx.BindRPCParams(&params)
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		board, err := views.ParseBoard(params.Arch)
+		if err == nil {
+			err = SaveLayout(env, uid, params.ActionID, board)
+		}
+		if err != nil {
+			log.Panic("Invalid dashboard layout", "uid", uid, "action", params.ActionID, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, true, err)
+}
+
+// resetLayout is the controller that restores the layout of the
+// board view of a dashboard action for the logged in user.
+func resetLayout(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		ActionID string `json:"action_id"`
+	}
+	ctx.BindRPCParams(&params)
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		ResetLayout(env, uid, params.ActionID)
+	})
+	ctx.RPC(http.StatusOK, true, err)
+}
+
+// addToDashboard is the controller that adds an act_window action to
+// the layout of a dashboard action for the logged in user.
+func addToDashboard(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		ActionID string            `json:"action_id"`
+		Action   views.BoardAction `json:"action"`
+	}
+	ctx.BindRPCParams(&params)
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		if err := AddAction(env, uid, params.ActionID, params.Action); err != nil {
+			log.Panic("Unable to add action to dashboard", "uid", uid, "action", params.ActionID, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, true, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/board")
+	grp.AddController(http.MethodPost, "/load", loadLayout)
+	grp.AddController(http.MethodPost, "/save", saveLayout)
+	grp.AddController(http.MethodPost, "/reset", resetLayout)
+	grp.AddController(http.MethodPost, "/add_to_dashboard", addToDashboard)
+}
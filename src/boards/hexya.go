@@ -0,0 +1,44 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package boards is a Hexya module that lets each user customize the layout
+// of dashboards.
+//
+// A dashboard is an action of type ir.actions.dashboard, the view of which
+// is a board view that displays act_window actions in columns:
+//
+//	<view id="sale_board" model="Board">
+//	    <board style="2-1">
+//	        <column>
+//	            <action name="sale_action_orders" string="My Orders" view_mode="list"/>
+//	        </column>
+//	        <column/>
+//	    </board>
+//	</view>
+//	<action id="sale_action_dashboard" name="Sales Dashboard" type="ir.actions.dashboard" view_id="sale_board"/>
+//
+// Users can move, fold and remove the actions of a dashboard and add new ones.
+// Their layout is saved in a BoardLayout record, and the layout of the board
+// view is used again when they reset it. The web client reads and updates the
+// layouts of the logged in user with the endpoints of the /web/board group.
+package boards
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "boards"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("boards")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package boards
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	// Board is the model of board views, which display no record
+	models.NewMixinModel("Board")
+
+	boardLayout := models.NewModel("BoardLayout")
+	boardLayout.SetDefaultOrder("UserID", "Action")
+	boardLayout.AddFields(map[string]models.FieldDefinition{
+		"UserID": fields.Integer{String: "User ID", Required: true, Index: true},
+		"Action": fields.Char{Required: true, Help: "ID of the dashboard action"},
+		"Arch":   fields.Text{Required: true, Help: "Board arch of the layout of the user"},
+	})
+	boardLayout.AddSQLConstraint("user_action_uniq", "unique(user_id, action)",
+		"The layout of a dashboard must be unique for each user")
+}
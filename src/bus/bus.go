@@ -0,0 +1,124 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package bus provides a notification bus to push events to clients.
+//
+// Notifications are sent on named channels of a database. Clients receive the
+// notifications of their channels by long polling the /longpolling/poll
+// controller with the ID of the last notification they received.
+//
+// The bus is held in memory, so that notifications are only delivered to the
+// clients of the server process that sent them.
+package bus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// maxNotifications is the number of notifications kept in memory
+// for clients that have not polled them yet.
+const maxNotifications = 1000
+
+// A Notification is a message sent on a channel of the bus
+type Notification struct {
+	ID      int64       `json:"id"`
+	Channel string      `json:"channel"`
+	Message interface{} `json:"message"`
+}
+
+// A bus holds the last notifications and wakes up pollers
+type bus struct {
+	sync.Mutex
+	lastID        int64
+	notifications []Notification
+	wakeup        chan struct{}
+}
+
+// send adds a notification with the given message on the given channel
+func (b *bus) send(channel string, message interface{}) {
+	b.Lock()
+	defer b.Unlock()
+	b.lastID++
+	b.notifications = append(b.notifications, Notification{ID: b.lastID, Channel: channel, Message: message})
+	if len(b.notifications) > maxNotifications {
+		b.notifications = b.notifications[len(b.notifications)-maxNotifications:]
+	}
+	close(b.wakeup)
+	b.wakeup = make(chan struct{})
+}
+
+// pending returns the notifications of the given channels after the given ID and
+// a channel that is closed when a new notification is sent.
+func (b *bus) pending(channels map[string]bool, last int64) ([]Notification, <-chan struct{}) {
+	b.Lock()
+	defer b.Unlock()
+	var res []Notification
+	for _, notif := range b.notifications {
+		if notif.ID > last && channels[notif.Channel] {
+			res = append(res, notif)
+		}
+	}
+	return res, b.wakeup
+}
+
+var defaultBus = &bus{wakeup: make(chan struct{})}
+
+// Channel returns the name of the channel with the given name in the given database
+func Channel(dbName, name string) string {
+	return fmt.Sprintf("%s:%s", dbName, name)
+}
+
+// UserChannel returns the private channel of the user
+// with the given ID in the given database.
+func UserChannel(dbName string, uid int64) string {
+	return Channel(dbName, fmt.Sprintf("%s%d", userChannelPrefix, uid))
+}
+
+// userChannelPrefix is the prefix of the names of the private channels of users
+const userChannelPrefix = "user/"
+
+// Send sends the given message on the given channel.
+// The message is serialized in JSON when sent to clients.
+func Send(channel string, message interface{}) {
+	log.Debug("Sending bus notification", "channel", channel)
+	defaultBus.send(channel, message)
+}
+
+// LastID returns the ID of the last notification sent on the bus.
+// Clients should start polling from this ID.
+func LastID() int64 {
+	defaultBus.Lock()
+	defer defaultBus.Unlock()
+	return defaultBus.lastID
+}
+
+// Poll returns the notifications of the given channels whose ID is greater
+// than last. If there is none, it waits for new notifications until ctx is
+// done, in which case it returns nil.
+func Poll(ctx context.Context, channels []string, last int64) []Notification {
+	chans := make(map[string]bool)
+	for _, ch := range channels {
+		chans[ch] = true
+	}
+	for {
+		res, wakeup := defaultBus.pending(chans, last)
+		if len(res) > 0 {
+			return res
+		}
+		select {
+		case <-wakeup:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func init() {
+	log = logging.GetLogger("bus")
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package bus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBus(t *testing.T) {
+	Convey("Testing the notification bus", t, func() {
+		userChannel := UserChannel("testdb", 2)
+		So(userChannel, ShouldEqual, "testdb:user/2")
+		last := LastID()
+		Convey("Pending notifications should be returned immediately", func() {
+			Send(userChannel, "hello")
+			Send(Channel("testdb", "other"), "ignored")
+			res := Poll(context.Background(), []string{userChannel}, last)
+			So(res, ShouldHaveLength, 1)
+			So(res[0].Message, ShouldEqual, "hello")
+			So(res[0].ID, ShouldEqual, last+1)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			So(Poll(ctx, []string{userChannel}, last+2), ShouldBeEmpty)
+		})
+		Convey("Pollers should be woken up by new notifications", func() {
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				Send(userChannel, "wake up")
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			res := Poll(ctx, []string{userChannel}, last)
+			So(res, ShouldHaveLength, 1)
+			So(res[0].Message, ShouldEqual, "wake up")
+		})
+		Convey("Polling should return nil when the context is done", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			So(Poll(ctx, []string{userChannel}, LastID()), ShouldBeNil)
+		})
+	})
+}
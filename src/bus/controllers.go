@@ -0,0 +1,52 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package bus
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// pollTimeout is the maximum time a poll request waits for notifications
+const pollTimeout = 50 * time.Second
+
+// poll is the long polling controller. It returns the notifications of the
+// requested channels and of the private channel of the user.
+func poll(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		Channels []string `json:"channels"`
+		Last     int64    `json:"last"`
+	}
+	ctx.BindRPCParams(&params)
+	channels := []string{UserChannel(ctx.DBName(), uid)}
+	for _, name := range params.Channels {
+		if strings.HasPrefix(name, userChannelPrefix) {
+			// Clients cannot listen to private channels of other users
+			continue
+		}
+		channels = append(channels, Channel(ctx.DBName(), name))
+	}
+	pollCtx, cancel := context.WithTimeout(ctx.Request.Context(), pollTimeout)
+	defer cancel()
+	res := Poll(pollCtx, channels, params.Last)
+	if res == nil {
+		res = []Notification{}
+	}
+	ctx.RPC(http.StatusOK, res)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/longpolling")
+	grp.AddController(http.MethodPost, "/poll", poll)
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/recurrence"
+)
+
+// defaultDuration is the default duration of new events
+const defaultDuration = time.Hour
+
+// An Occurrence of an event, as displayed in calendar views.
+// All occurrences of a recurrent event have the ID of the event.
+type Occurrence struct {
+	ID        int64          `json:"id"`
+	Name      string         `json:"name"`
+	Start     dates.DateTime `json:"start"`
+	Stop      dates.DateTime `json:"stop"`
+	AllDay    bool           `json:"allday"`
+	Recurrent bool           `json:"recurrent"`
+}
+
+// EventICS returns the given event record as an ICSEvent
+func EventICS(rec models.RecordSet) ICSEvent {
+	rc := rec.Collection()
+	mi := rc.Model()
+	res := ICSEvent{
+		UID:         fmt.Sprintf("event-%d@%s", rc.Ids()[0], rc.Env().DBName()),
+		Summary:     rc.Get(mi.FieldName("Name")).(string),
+		Description: rc.Get(mi.FieldName("Description")).(string),
+		Location:    rc.Get(mi.FieldName("Location")).(string),
+		Start:       rc.Get(mi.FieldName("Start")).(dates.DateTime).Time,
+		Stop:        rc.Get(mi.FieldName("Stop")).(dates.DateTime).Time,
+		AllDay:      rc.Get(mi.FieldName("AllDay")).(bool),
+		Stamp:       rc.Get(mi.FieldName("LastUpdate")).(dates.DateTime).Time,
+	}
+	if rc.Get(mi.FieldName("Recurrency")).(bool) {
+		res.RRule = rc.Get(mi.FieldName("RRule")).(string)
+	}
+	if tz := rc.Get(mi.FieldName("Timezone")).(string); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			res.Timezone = loc
+		}
+	}
+	return res
+}
+
+// rule returns the recurrence rule of the given event starting at start.
+// Occurrences of all day events are computed in UTC like their dates.
+func rule(event ICSEvent, start time.Time) (*recurrence.Rule, error) {
+	r, err := recurrence.ParseRule(event.RRule)
+	if err != nil {
+		return nil, err
+	}
+	r.DTStart = start
+	if event.Timezone != nil && !event.AllDay {
+		r.DTStart = start.In(event.Timezone)
+	}
+	return r, nil
+}
+
+// bounds returns the start and the end of the given event.
+// All day events last from the start of their first day
+// to the end of their last day.
+func bounds(event ICSEvent) (time.Time, time.Time) {
+	if !event.AllDay {
+		return event.Start, event.Stop
+	}
+	start := time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, event.Start.Location())
+	stop := time.Date(event.Stop.Year(), event.Stop.Month(), event.Stop.Day(), 0, 0, 0, 0, event.Stop.Location())
+	return start, stop.AddDate(0, 0, 1)
+}
+
+// occurrences returns the occurrences of the given event
+// with the given ID that overlap the [from, to) period.
+func occurrences(id int64, event ICSEvent, from, to time.Time) []Occurrence {
+	start, stop := bounds(event)
+	duration := stop.Sub(start)
+	starts := []time.Time{start}
+	if event.RRule != "" {
+		r, err := rule(event, start)
+		if err != nil {
+			log.Warn("Invalid recurrence rule", "event", id, "rrule", event.RRule, "error", err)
+			return nil
+		}
+		starts = nil
+		it := r.Iterator()
+		for s, ok := it.Next(); ok && s.Before(to); s, ok = it.Next() {
+			if s.Add(duration).After(from) || duration == 0 && !s.Before(from) {
+				starts = append(starts, s)
+			}
+		}
+	} else if !start.Before(to) || !stop.After(from) && !(duration == 0 && start.Equal(from)) {
+		return nil
+	}
+	res := make([]Occurrence, len(starts))
+	for i, s := range starts {
+		res[i] = Occurrence{
+			ID:        id,
+			Name:      event.Summary,
+			Start:     dates.DateTime{Time: s.UTC()},
+			Stop:      dates.DateTime{Time: s.Add(duration).UTC()},
+			AllDay:    event.AllDay,
+			Recurrent: event.RRule != "",
+		}
+		if event.AllDay {
+			// Stop is the last day of all day events
+			res[i].Stop = dates.DateTime{Time: s.Add(duration).AddDate(0, 0, -1).UTC()}
+		}
+	}
+	return res
+}
+
+// SearchEvents returns the events of env that may have occurrences between from and to
+func SearchEvents(env models.Environment, from, to dates.DateTime) models.RecordSet {
+	mi := models.Registry.MustGet("Event")
+	// All day events end at the end of their Stop day
+	cond := mi.Field(mi.FieldName("Start")).Lower(to).AndCond(
+		mi.Field(mi.FieldName("Stop")).GreaterOrEqual(from.AddDate(0, 0, -1)).
+			Or().Field(mi.FieldName("Recurrency")).Equals(true))
+	return env.Pool(mi.Name()).Search(cond)
+}
+
+// Occurrences returns the occurrences of the events of rs between from and to,
+// sorted by start time.
+func Occurrences(rs models.RecordSet, from, to dates.DateTime) []Occurrence {
+	res := make([]Occurrence, 0)
+	for _, rec := range rs.Collection().Records() {
+		res = append(res, occurrences(rec.Ids()[0], EventICS(rec), from.Time, to.Time)...)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Start.Lower(res[j].Start)
+	})
+	return res
+}
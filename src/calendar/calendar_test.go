@@ -0,0 +1,125 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestOccurrences(t *testing.T) {
+	Convey("Testing event occurrences", t, func() {
+		from, to := date("2019-03-18 00:00"), date("2019-03-25 00:00")
+		Convey("Single events should only be returned if they overlap the period", func() {
+			So(occurrences(1, ICSEvent{Start: date("2019-03-17 23:00"), Stop: date("2019-03-18 01:00")}, from, to), ShouldHaveLength, 1)
+			So(occurrences(1, ICSEvent{Start: date("2019-03-17 10:00"), Stop: date("2019-03-17 11:00")}, from, to), ShouldBeEmpty)
+			So(occurrences(1, ICSEvent{Start: date("2019-03-25 00:00"), Stop: date("2019-03-25 01:00")}, from, to), ShouldBeEmpty)
+		})
+		Convey("All day events should last the whole day", func() {
+			occ := occurrences(1, ICSEvent{Start: date("2019-03-17 12:00"), Stop: date("2019-03-18 12:00"), AllDay: true}, from, to)
+			So(occ, ShouldHaveLength, 1)
+			So(occ[0].Start.Time, ShouldEqual, date("2019-03-17 00:00"))
+			So(occ[0].Stop.Time, ShouldEqual, date("2019-03-18 00:00"))
+			So(occurrences(1, ICSEvent{Start: date("2019-03-17 12:00"), Stop: date("2019-03-17 13:00"), AllDay: true}, from, to), ShouldBeEmpty)
+		})
+		Convey("Recurrent events should be expanded", func() {
+			occ := occurrences(3, ICSEvent{Summary: "Standup", Start: date("2019-03-01 09:00"), Stop: date("2019-03-01 09:15"),
+				RRule: "FREQ=WEEKLY;BYDAY=MO,WE"}, from, to)
+			So(occ, ShouldHaveLength, 2)
+			So(occ[1], ShouldResemble, Occurrence{ID: 3, Name: "Standup", Start: occ[1].Start, Stop: occ[1].Stop, Recurrent: true})
+			So(occ[1].Start.Time, ShouldEqual, date("2019-03-20 09:00"))
+			So(occ[1].Stop.Time, ShouldEqual, date("2019-03-20 09:15"))
+		})
+		Convey("Recurrent events should keep their local time in their timezone", func() {
+			paris, err := time.LoadLocation("Europe/Paris")
+			So(err, ShouldBeNil)
+			event := ICSEvent{Start: date("2019-03-22 09:00"), Stop: date("2019-03-22 10:00"), RRule: "FREQ=WEEKLY;COUNT=3"}
+			occ := occurrences(4, event, from, date("2019-04-06 00:00"))
+			So(occ, ShouldHaveLength, 3)
+			So(occ[2].Start.Time, ShouldEqual, date("2019-04-05 09:00"))
+			event.Timezone = paris
+			occ = occurrences(4, event, from, date("2019-04-06 00:00"))
+			So(occ, ShouldHaveLength, 3)
+			So(occ[1].Start.Time, ShouldEqual, date("2019-03-29 09:00"))
+			So(occ[2].Start.Time, ShouldEqual, date("2019-04-05 08:00"))
+			So(occ[2].Stop.Time, ShouldEqual, date("2019-04-05 09:00"))
+		})
+		Convey("Invalid recurrence rules should give no occurrence", func() {
+			So(occurrences(5, ICSEvent{Start: from, Stop: to, RRule: "FREQ=SOMETIMES"}, from, to), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestICS(t *testing.T) {
+	Convey("Testing ICS feeds", t, func() {
+		paris, err := time.LoadLocation("Europe/Paris")
+		So(err, ShouldBeNil)
+		var buf bytes.Buffer
+		err = WriteICS(&buf, "John", []ICSEvent{
+			{UID: "event-1@test", Summary: "Meeting; with, John", Description: "Line 1\nLine 2 " + strings.Repeat("é", 40),
+				Start: date("2019-03-15 10:00"), Stop: date("2019-03-15 11:00"), Stamp: date("2019-03-01 08:00"),
+				RRule: "FREQ=WEEKLY;COUNT=2"},
+			{UID: "event-2@test", Summary: "Holidays", Start: date("2019-03-18 00:00"), Stop: date("2019-03-22 00:00"), AllDay: true},
+			{UID: "event-3@test", Summary: "Standup", Start: date("2019-03-18 08:00"), Stop: date("2019-03-18 08:15"),
+				RRule: "FREQ=DAILY", Timezone: paris},
+		})
+		So(err, ShouldBeNil)
+		ics := buf.String()
+		lines := strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n")
+		So(lines[0], ShouldEqual, "BEGIN:VCALENDAR")
+		So(lines[len(lines)-1], ShouldEqual, "END:VCALENDAR")
+		Convey("Events should be written with escaped texts", func() {
+			So(ics, ShouldContainSubstring, "\r\nDTSTART:20190315T100000Z\r\nDTEND:20190315T110000Z\r\nRRULE:FREQ=WEEKLY;COUNT=2\r\n")
+			So(ics, ShouldContainSubstring, "\r\nSUMMARY:Meeting\\; with\\, John\r\n")
+			So(ics, ShouldContainSubstring, "\r\nDTSTAMP:20190301T080000Z\r\n")
+			So(ics, ShouldContainSubstring, "\r\nDTSTART;VALUE=DATE:20190318\r\nDTEND;VALUE=DATE:20190323\r\n")
+			So(ics, ShouldContainSubstring, "\r\nDTSTART;TZID=Europe/Paris:20190318T090000\r\nDTEND;TZID=Europe/Paris:20190318T091500\r\n")
+		})
+		Convey("Long lines should be folded without splitting characters", func() {
+			var description string
+			for _, line := range lines {
+				So(len(line), ShouldBeLessThanOrEqualTo, icsLineLength)
+				switch {
+				case strings.HasPrefix(line, "DESCRIPTION:"):
+					description = line
+				case strings.HasPrefix(line, " ") && description != "":
+					description += line[1:]
+				default:
+					if description != "" {
+						So(description, ShouldEqual, "DESCRIPTION:Line 1\\nLine 2 "+strings.Repeat("é", 40))
+					}
+					description = ""
+				}
+			}
+		})
+	})
+}
+
+func TestColors(t *testing.T) {
+	Convey("Testing colors of calendar records", t, func() {
+		Convey("Integer values should be color indexes", func() {
+			So(ColorIndex(int64(3)), ShouldEqual, 3)
+			So(ColorIndex(int64(ColorCount+2)), ShouldEqual, 2)
+			So(ColorIndex(int64(-5)), ShouldEqual, 5)
+			So(ColorIndex(nil), ShouldEqual, 0)
+		})
+		Convey("Other values should have a stable color", func() {
+			So(ColorIndex("draft"), ShouldEqual, ColorIndex("draft"))
+			So(ColorIndex("draft"), ShouldBeBetweenOrEqual, 0, ColorCount-1)
+			So(ColorIndex(true), ShouldBeBetweenOrEqual, 0, ColorCount-1)
+		})
+	})
+}
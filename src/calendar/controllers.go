@@ -0,0 +1,192 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package calendar
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// getEvents is the controller that returns the occurrences of the events
+// between the given start and stop dates, for the calendar view.
+//
+// If user_ids is given, only the events organized or attended
+// by one of these users are returned.
+func getEvents(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		Start   dates.DateTime `json:"start"`
+		Stop    dates.DateTime `json:"stop"`
+		UserIDs []int64        `json:"user_ids"`
+	}
+	ctx.BindRPCParams(&params)
+	var res []Occurrence
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		events := SearchEvents(env, params.Start, params.Stop).Collection()
+		if len(params.UserIDs) > 0 {
+			events = events.Search(userCondition(events.Model(), params.UserIDs...))
+		}
+		res = Occurrences(events, params.Start, params.Stop)
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// getRecords is the controller that returns the occurrences of the records
+// of any model between the given start and stop dates, for its calendar view.
+func getRecords(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params RecordsParams
+	ctx.BindRPCParams(&params)
+	var res RecordsResult
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		var err error
+		if res, err = Records(env, params); err != nil {
+			log.Panic("Invalid calendar view", "model", params.Model, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// moveRecord is the controller that writes the new dates of a
+// record moved or resized in the calendar view of its model.
+func moveRecord(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params MoveParams
+	ctx.BindRPCParams(&params)
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		if err := Move(env, params); err != nil {
+			log.Panic("Unable to move record", "model", params.Model, "id", params.ID, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, true, err)
+}
+
+// userCondition returns the condition on events organized
+// or attended by one of the users with the given ids.
+func userCondition(mi *models.Model, uids ...int64) *models.Condition {
+	return mi.Field(mi.FieldName("User")).In(uids).Or().Field(mi.FieldName("Attendees")).In(uids)
+}
+
+// newToken returns a new random token for ICS feeds
+func newToken() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Panic("Unable to generate calendar token", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// getICSURL is the controller that returns the URL of the ICS feed of the
+// user. The token of the feed is generated on the first call, and is
+// generated again if regenerate is true, so that the former URL is revoked.
+func getICSURL(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		Regenerate bool `json:"regenerate"`
+	}
+	ctx.BindRPCParams(&params)
+	var token string
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		// Feeds can only be accessed by administrators
+		feeds := env.Pool("CalendarFeed").Sudo()
+		mi := feeds.Model()
+		feed := feeds.Search(mi.Field(mi.FieldName("User")).Equals(uid)).Limit(1)
+		switch {
+		case feed.IsEmpty():
+			token = newToken()
+			feeds.Call("Create", models.NewModelData(mi, models.FieldMap{"User": uid, "Token": token}))
+		case params.Regenerate:
+			token = newToken()
+			feed.Set(mi.FieldName("Token"), token)
+		default:
+			token = feed.Get(mi.FieldName("Token")).(string)
+		}
+	})
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	ctx.RPC(http.StatusOK, fmt.Sprintf("%s://%s/calendar/ics/%s.ics", scheme, ctx.Request.Host, token), err)
+}
+
+// getICS is the controller that returns the ICS feed of the events organized
+// or attended by the user with the given token. It needs no session so that
+// other calendar applications can subscribe to it.
+func getICS(ctx *server.Context) {
+	token := strings.TrimSuffix(ctx.Param("token"), ".ics")
+	if token == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var (
+		uid  int64
+		name string
+	)
+	err := models.ExecuteInTenantEnvironment(ctx.DBName(), security.SuperUserID, func(env models.Environment) {
+		mi := models.Registry.MustGet("CalendarFeed")
+		feed := env.Pool(mi.Name()).Search(mi.Field(mi.FieldName("Token")).Equals(token)).Limit(1)
+		if feed.IsEmpty() {
+			return
+		}
+		user := feed.Get(mi.FieldName("User")).(models.RecordSet).Collection()
+		uid = user.Ids()[0]
+		name = user.Call("NameGet").(string)
+	})
+	if err != nil || uid == 0 {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var buf bytes.Buffer
+	err = models.ExecuteInTenantEnvironment(ctx.DBName(), uid, func(env models.Environment) {
+		mi := models.Registry.MustGet("Event")
+		var events []ICSEvent
+		for _, rec := range env.Pool(mi.Name()).Search(userCondition(mi, uid)).Records() {
+			events = append(events, EventICS(rec))
+		}
+		if err := WriteICS(&buf, name, events); err != nil {
+			log.Panic("Unable to write ICS feed", "user", uid, "error", err)
+		}
+	})
+	if err != nil {
+		log.Warn("Unable to render ICS feed", "user", uid, "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(http.StatusOK, icsContentType, buf.Bytes())
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/calendar")
+	grp.AddController(http.MethodPost, "/events", getEvents)
+	grp.AddController(http.MethodPost, "/records", getRecords)
+	grp.AddController(http.MethodPost, "/move", moveRecord)
+	grp.AddController(http.MethodPost, "/ics_url", getICSURL)
+	grp.AddController(http.MethodGet, "/ics/:token", getICS)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package calendar is a Hexya module that provides calendar events.
+//
+// Events may be recurrent, in which case their occurrences are computed
+// from the RRULE of the event (RFC 5545) in the timezone of the event
+// when the calendar is displayed.
+// The events of each user can be subscribed to by other calendar
+// applications through a private ICS feed:
+//
+//	/calendar/ics/<token>.ics
+//
+// where token is the Token of the CalendarFeed of the user. Feed tokens are
+// kept in their own model on which no permission is granted, so that they
+// can only be read by administrators. Users get the URL of their own feed
+// through /calendar/ics_url.
+//
+// The calendar view of any model is served by Records at /calendar/records,
+// given the date fields of the model and the displayed range. Records are
+// colored by the value of a field, and moving or resizing a record in the
+// view writes its new dates through Move at /calendar/move, with the access
+// rights of the user.
+package calendar
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "calendar"
+)
+
+var log logging.Logger
+
+// addUserFields declares the ICS feeds of users, and adds the organizer
+// and the attendees of events.
+func addUserFields() {
+	user, ok := models.Registry.Get("User")
+	if !ok {
+		return
+	}
+	feed := models.NewModel("CalendarFeed")
+	feed.AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{RelationModel: user, Required: true, Index: true, OnDelete: models.Cascade},
+		"Token": fields.Char{Required: true, Unique: true, NoCopy: true,
+			Help: "Secret token of the ICS feed of the events of the user"},
+	})
+	feed.AddSQLConstraint("user_uniq", "unique(user_id)", "Each user can only have one calendar feed")
+	models.Registry.MustGet("Event").AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{String: "Organizer", RelationModel: user, Index: true,
+			Default: func(env models.Environment) interface{} {
+				return env.Pool("User").Call("BrowseOne", env.Uid())
+			}},
+		"Attendees": fields.Many2Many{RelationModel: user, JSON: "attendee_ids"},
+	})
+}
+
+func init() {
+	log = logging.GetLogger("calendar")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: addUserFields,
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// icsContentType is the content type of ICS feeds
+	icsContentType = "text/calendar; charset=utf-8"
+	// icsDateFormat is the format of dates in ICS files
+	icsDateFormat = "20060102"
+	// icsDateTimeFormat is the format of UTC date times in ICS files
+	icsDateTimeFormat = "20060102T150405Z"
+	// icsLocalDateTimeFormat is the format of local date times in ICS files
+	icsLocalDateTimeFormat = "20060102T150405"
+	// icsLineLength is the maximum length in octets of ICS lines
+	icsLineLength = 75
+)
+
+// An ICSEvent is an event of an ICS feed
+type ICSEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	Stop        time.Time
+	AllDay      bool
+	RRule       string
+	Stamp       time.Time
+	// Timezone is the location in which the occurrences of
+	// a recurrent event are computed. Nil means UTC.
+	Timezone *time.Location
+}
+
+// WriteICS writes an ICS calendar (RFC 5545) with the given name and events into w
+func WriteICS(w io.Writer, name string, events []ICSEvent) error {
+	bw := bufio.NewWriter(w)
+	writeICSLine(bw, "BEGIN:VCALENDAR")
+	writeICSLine(bw, "VERSION:2.0")
+	writeICSLine(bw, "PRODID:-//Hexya//Calendar//EN")
+	writeICSLine(bw, "CALSCALE:GREGORIAN")
+	if name != "" {
+		writeICSLine(bw, "X-WR-CALNAME:"+escapeICSText(name))
+	}
+	for _, event := range events {
+		writeICSLine(bw, "BEGIN:VEVENT")
+		writeICSLine(bw, "UID:"+event.UID)
+		writeICSLine(bw, "DTSTAMP:"+event.Stamp.UTC().Format(icsDateTimeFormat))
+		if event.AllDay {
+			writeICSLine(bw, "DTSTART;VALUE=DATE:"+event.Start.Format(icsDateFormat))
+			// The end date of all day events is exclusive
+			writeICSLine(bw, "DTEND;VALUE=DATE:"+event.Stop.AddDate(0, 0, 1).Format(icsDateFormat))
+		} else if event.RRule != "" && event.Timezone != nil && event.Timezone != time.UTC {
+			// Recurrent events keep their local time across DST transitions
+			tzid := ";TZID=" + event.Timezone.String() + ":"
+			writeICSLine(bw, "DTSTART"+tzid+event.Start.In(event.Timezone).Format(icsLocalDateTimeFormat))
+			writeICSLine(bw, "DTEND"+tzid+event.Stop.In(event.Timezone).Format(icsLocalDateTimeFormat))
+		} else {
+			writeICSLine(bw, "DTSTART:"+event.Start.UTC().Format(icsDateTimeFormat))
+			writeICSLine(bw, "DTEND:"+event.Stop.UTC().Format(icsDateTimeFormat))
+		}
+		if event.RRule != "" {
+			writeICSLine(bw, "RRULE:"+strings.TrimPrefix(event.RRule, "RRULE:"))
+		}
+		writeICSLine(bw, "SUMMARY:"+escapeICSText(event.Summary))
+		if event.Location != "" {
+			writeICSLine(bw, "LOCATION:"+escapeICSText(event.Location))
+		}
+		if event.Description != "" {
+			writeICSLine(bw, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		writeICSLine(bw, "END:VEVENT")
+	}
+	writeICSLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeICSLine writes the given content line into w, folded
+// into lines of at most 75 octets as required by RFC 5545.
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := icsLineLength
+	for len(line) > limit {
+		// Do not split multi-byte characters
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = icsLineLength - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// escapeICSText escapes the given value of a TEXT property
+func escapeICSText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(value)
+}
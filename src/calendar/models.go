@@ -0,0 +1,76 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package calendar
+
+import (
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/recurrence"
+)
+
+func declareModels() {
+	event := models.NewModel("Event")
+	event.SetDefaultOrder("Start desc", "ID")
+	event.NewMethod("CheckDates", event_CheckDates)
+	event.NewMethod("CheckRRule", event_CheckRRule)
+	event.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Meeting Subject", Required: true},
+		"Start": fields.DateTime{Required: true, Index: true,
+			Constraint: event.Methods().MustGet("CheckDates"),
+			Default: func(env models.Environment) interface{} {
+				return dates.Now()
+			}},
+		"Stop": fields.DateTime{Required: true, Index: true,
+			Constraint: event.Methods().MustGet("CheckDates"),
+			Default: func(env models.Environment) interface{} {
+				return dates.Now().Add(defaultDuration)
+			}},
+		"AllDay":      fields.Boolean{String: "All Day"},
+		"Location":    fields.Char{},
+		"Description": fields.Text{},
+		"Recurrency": fields.Boolean{String: "Recurrent",
+			Constraint: event.Methods().MustGet("CheckRRule")},
+		"RRule": fields.Char{String: "Recurrent Rule", Constraint: event.Methods().MustGet("CheckRRule"),
+			Help: "Recurrence rule of the event in RFC 5545 format (e.g. FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10)"},
+		"Timezone": fields.Char{Constraint: event.Methods().MustGet("CheckRRule"),
+			Help: "Timezone in which the occurrences of a recurrent event are computed, so that they keep their local time",
+			Default: func(env models.Environment) interface{} {
+				return env.Context().GetString("tz")
+			}},
+	})
+}
+
+// event_CheckDates checks that events do not stop before they start
+func event_CheckDates(rs *models.RecordCollection) {
+	mi := rs.Model()
+	for _, rec := range rs.Records() {
+		start := rec.Get(mi.FieldName("Start")).(dates.DateTime)
+		stop := rec.Get(mi.FieldName("Stop")).(dates.DateTime)
+		if stop.Lower(start) {
+			log.Panic("The end of an event cannot be before its start", "event", rec.Ids()[0], "start", start, "stop", stop)
+		}
+	}
+}
+
+// event_CheckRRule checks that recurrent events have a valid recurrence rule and timezone
+func event_CheckRRule(rs *models.RecordCollection) {
+	mi := rs.Model()
+	for _, rec := range rs.Records() {
+		if tz := rec.Get(mi.FieldName("Timezone")).(string); tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				log.Panic("Unknown timezone", "event", rec.Ids()[0], "timezone", tz)
+			}
+		}
+		if !rec.Get(mi.FieldName("Recurrency")).(bool) {
+			continue
+		}
+		rrule := rec.Get(mi.FieldName("RRule")).(string)
+		if _, err := recurrence.ParseRule(rrule); err != nil {
+			log.Panic("Invalid recurrence rule", "event", rec.Ids()[0], "rrule", rrule, "error", err)
+		}
+	}
+}
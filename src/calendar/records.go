@@ -0,0 +1,312 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package calendar
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// ColorCount is the number of colors of the palette of the calendar view.
+// Color indexes are interpreted by the client.
+const ColorCount = 12
+
+// RecordsParams are the parameters of the calendar view of a model.
+// Field names are the names of fields of the model.
+type RecordsParams struct {
+	Model string `json:"model"`
+	// DateStart is the date or datetime field of the start of the records
+	DateStart string `json:"date_start"`
+	// DateStop is the date or datetime field of the end of the records. If it
+	// is empty, records last one hour or one day for date fields.
+	DateStop string `json:"date_stop"`
+	// AllDay is the boolean field of the records lasting whole days.
+	// Records are all day if their start field is a date field.
+	AllDay string `json:"all_day"`
+	// RRule is the char field of the RFC 5545 recurrence rule of
+	// recurrent records. Records with an empty rule are not recurrent.
+	RRule string `json:"rrule"`
+	// Color is the field whose values give the colors of the records
+	Color  string         `json:"color"`
+	Domain []interface{}  `json:"domain"`
+	Start  dates.DateTime `json:"start"`
+	Stop   dates.DateTime `json:"stop"`
+}
+
+// A RecordOccurrence is an occurrence of a record in a calendar view
+type RecordOccurrence struct {
+	Occurrence
+	// Color is the value of the color field: the ID of the record of
+	// a many2one field, or the value of other fields.
+	Color      interface{} `json:"color"`
+	ColorLabel string      `json:"color_label"`
+	ColorIndex int         `json:"color_index"`
+}
+
+// RecordsResult is the result of Records
+type RecordsResult struct {
+	Events []RecordOccurrence `json:"events"`
+	// Editable is true if the user can move and resize the records
+	Editable bool `json:"editable"`
+}
+
+// MoveParams are the parameters of Move
+type MoveParams struct {
+	RecordsParams
+	ID int64 `json:"id"`
+	// OccurrenceStart is the start of the moved occurrence. It is the start
+	// of the record if it is empty. All the occurrences of recurrent records
+	// are moved by the same delay.
+	OccurrenceStart dates.DateTime `json:"occurrence_start"`
+}
+
+// A calendarField is a field of a model displayed in a calendar view
+type calendarField struct {
+	name models.FieldName
+	info *models.FieldInfo
+}
+
+// calendarFields returns the calendarField of each of the given fields of
+// the given model, by field name. Empty names are skipped. It returns an
+// error if a field does not exist or has not one of its allowed types.
+func calendarFields(env models.Environment, mi *models.Model, fieldTypes map[string][]fieldtype.Type) (map[string]calendarField, error) {
+	var names models.FieldNames
+	for field := range fieldTypes {
+		if field == "" {
+			continue
+		}
+		fi, ok := mi.Fields().Get(field)
+		if !ok {
+			return nil, fmt.Errorf("unknown field '%s' in model %s", field, mi.Name())
+		}
+		names = append(names, mi.FieldName(fi.Name()))
+	}
+	infos := env.Pool(mi.Name()).Call("FieldsGet", models.FieldsGetArgs{Fields: names}).(map[string]*models.FieldInfo)
+	res := make(map[string]calendarField)
+	for _, name := range names {
+		info := infos[name.JSON()]
+		allowed := len(fieldTypes[name.Name()]) == 0
+		for _, t := range fieldTypes[name.Name()] {
+			allowed = allowed || info.Type == t
+		}
+		if !allowed {
+			return nil, fmt.Errorf("field '%s' of type %s cannot be used in the calendar view", name.Name(), info.Type)
+		}
+		res[name.Name()] = calendarField{name: name, info: info}
+	}
+	return res, nil
+}
+
+// recordsFields returns the calendarFields of the given parameters
+func recordsFields(env models.Environment, params RecordsParams) (*models.Model, map[string]calendarField, error) {
+	mi, ok := models.Registry.Get(params.Model)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown model '%s'", params.Model)
+	}
+	if params.DateStart == "" {
+		return nil, nil, fmt.Errorf("no start field given for model %s", params.Model)
+	}
+	dateTypes := []fieldtype.Type{fieldtype.Date, fieldtype.DateTime}
+	fields, err := calendarFields(env, mi, map[string][]fieldtype.Type{
+		params.DateStart: dateTypes,
+		params.DateStop:  dateTypes,
+		params.AllDay:    {fieldtype.Boolean},
+		params.RRule:     {fieldtype.Char},
+		params.Color:     nil,
+	})
+	return mi, fields, err
+}
+
+// timeValue returns the time of the given date or datetime value, and
+// whether it is a date.
+func timeValue(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case dates.Date:
+		return v.Time, true
+	case dates.DateTime:
+		return v.Time, false
+	}
+	return time.Time{}, false
+}
+
+// dateArg returns the given datetime as the argument of a condition on the given field
+func dateArg(field calendarField, value dates.DateTime) interface{} {
+	if field.info.Type == fieldtype.Date {
+		return value.ToDate()
+	}
+	return value
+}
+
+// ColorIndex returns the index in the palette of the calendar view of the
+// given value of a color field: integer values are color indexes, and other
+// values are given a color by their hash.
+func ColorIndex(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case int64:
+		if v < 0 {
+			v = -v
+		}
+		return int(v % ColorCount)
+	case int:
+		return ColorIndex(int64(v))
+	}
+	h := fnv.New32a()
+	fmt.Fprint(h, value)
+	return int(h.Sum32() % ColorCount)
+}
+
+// colorValue returns the key and the label of the given value of a color field
+func colorValue(field calendarField, value interface{}) (interface{}, string) {
+	switch v := value.(type) {
+	case models.RecordSet:
+		if v.IsEmpty() {
+			return nil, ""
+		}
+		rec := v.Collection().Records()[0]
+		return rec.Ids()[0], rec.Call("NameGet").(string)
+	case string:
+		if label, ok := field.info.Selection[v]; ok {
+			return v, label
+		}
+		return v, v
+	}
+	return value, fmt.Sprint(value)
+}
+
+// recordsCondition returns the condition on the records that may
+// have occurrences in the range of the given parameters
+func recordsCondition(mi *models.Model, fields map[string]calendarField, params RecordsParams) (*models.Condition, error) {
+	cond, err := models.ParseDomain(mi, params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	start := fields[params.DateStart]
+	// All day records end at the end of their stop day
+	inRange := mi.Field(start.name).Lower(dateArg(start, params.Stop))
+	from := params.Start.AddDate(0, 0, -1)
+	var overlap *models.Condition
+	if stop, ok := fields[params.DateStop]; ok {
+		overlap = mi.Field(stop.name).GreaterOrEqual(dateArg(stop, from))
+	} else {
+		overlap = mi.Field(start.name).GreaterOrEqual(dateArg(start, from.Add(-defaultDuration)))
+	}
+	if rrule, ok := fields[params.RRule]; ok {
+		overlap = overlap.Or().Field(rrule.name).IsNotNull()
+	}
+	return cond.AndCond(inRange.AndCond(overlap)), nil
+}
+
+// Records returns the occurrences of the records of the given model that
+// match the given domain between the given start and stop, sorted by start.
+// Recurrent records are expanded in their occurrences.
+func Records(env models.Environment, params RecordsParams) (RecordsResult, error) {
+	var res RecordsResult
+	mi, fields, err := recordsFields(env, params)
+	if err != nil {
+		return res, err
+	}
+	cond, err := recordsCondition(mi, fields, params)
+	if err != nil {
+		return res, err
+	}
+	rs := env.Pool(mi.Name())
+	res.Editable = rs.CheckExecutionPermission(mi.Methods().MustGet("Write"), true) && !fields[params.DateStart].info.ReadOnly
+	res.Events = make([]RecordOccurrence, 0)
+	for _, rec := range rs.Search(cond).Records() {
+		event := ICSEvent{Summary: rec.Call("NameGet").(string)}
+		event.Start, event.AllDay = timeValue(rec.Get(fields[params.DateStart].name))
+		if event.Start.IsZero() {
+			continue
+		}
+		event.Stop = event.Start
+		if !event.AllDay {
+			event.Stop = event.Start.Add(defaultDuration)
+		}
+		if stop, ok := fields[params.DateStop]; ok {
+			if t, _ := timeValue(rec.Get(stop.name)); !t.IsZero() && !t.Before(event.Start) {
+				event.Stop = t
+			}
+		}
+		if allDay, ok := fields[params.AllDay]; ok && rec.Get(allDay.name).(bool) {
+			event.AllDay = true
+		}
+		if rrule, ok := fields[params.RRule]; ok {
+			event.RRule = rec.Get(rrule.name).(string)
+		}
+		var occ RecordOccurrence
+		if color, ok := fields[params.Color]; ok {
+			occ.Color, occ.ColorLabel = colorValue(color, rec.Get(color.name))
+			occ.ColorIndex = ColorIndex(occ.Color)
+			if color.info.Type == fieldtype.Integer {
+				occ.ColorLabel = ""
+			}
+		}
+		for _, o := range occurrences(rec.Ids()[0], event, params.Start.Time, params.Stop.Time) {
+			occ.Occurrence = o
+			res.Events = append(res.Events, occ)
+		}
+	}
+	sort.SliceStable(res.Events, func(i, j int) bool {
+		return res.Events[i].Start.Lower(res.Events[j].Start)
+	})
+	return res, nil
+}
+
+// Move writes the new start and stop of the record moved or resized in the
+// calendar view by the user of env, whose access rights are checked. Start
+// and Stop are the new bounds of the occurrence that started at
+// OccurrenceStart.
+func Move(env models.Environment, params MoveParams) error {
+	mi, fields, err := recordsFields(env, params.RecordsParams)
+	if err != nil {
+		return err
+	}
+	if params.Stop.Lower(params.Start) {
+		return fmt.Errorf("the end of a record cannot be before its start")
+	}
+	rec := env.Pool(mi.Name()).Search(mi.Field(models.ID).Equals(params.ID))
+	if rec.IsEmpty() {
+		return fmt.Errorf("unknown record %d of model %s", params.ID, mi.Name())
+	}
+	start := fields[params.DateStart]
+	if start.info.ReadOnly {
+		return fmt.Errorf("field '%s' of model %s is read only", params.DateStart, mi.Name())
+	}
+	recStart, _ := timeValue(rec.Get(start.name))
+	occurrenceStart := params.OccurrenceStart.Time
+	if occurrenceStart.IsZero() {
+		occurrenceStart = recStart
+	}
+	newStart := params.Start
+	if !recStart.IsZero() {
+		newStart = dates.DateTime{Time: recStart.Add(params.Start.Time.Sub(occurrenceStart))}
+	}
+	data := models.NewModelData(mi)
+	if start.info.Type == fieldtype.Date {
+		data.Set(start.name, newStart.ToDate())
+	} else {
+		data.Set(start.name, newStart)
+	}
+	if stop, ok := fields[params.DateStop]; ok {
+		if stop.info.ReadOnly {
+			return fmt.Errorf("field '%s' of model %s is read only", params.DateStop, mi.Name())
+		}
+		newStop := newStart.Add(params.Stop.Sub(params.Start))
+		if stop.info.Type == fieldtype.Date {
+			data.Set(stop.name, newStop.ToDate())
+		} else {
+			data.Set(stop.name, newStop)
+		}
+	}
+	rec.Call("Write", data)
+	return nil
+}
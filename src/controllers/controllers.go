@@ -3,7 +3,14 @@
 
 package controllers
 
-import "github.com/hexya-erp/hexya/src/server"
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/assetfs"
+)
 
 // Registry is the central collection of all the application controllers
 var Registry *Group
@@ -22,6 +29,7 @@ type Group struct {
 	controllers  map[Route]*Controller
 	groups       map[string]*Group
 	static       map[string]string
+	sitemap      map[string]bool
 	middleWares  []server.HandlerFunc
 }
 
@@ -32,6 +40,7 @@ func newGroup(relativePath string) *Group {
 		controllers:  make(map[Route]*Controller),
 		groups:       make(map[string]*Group),
 		static:       make(map[string]string),
+		sitemap:      make(map[string]bool),
 	}
 	return &res
 }
@@ -122,6 +131,9 @@ func (g *Group) OverrideController(method, relativePath string, fnct server.Hand
 
 // AddStatic creates a new route at relativePath that will serve
 // the static files found at fsPath on the file system.
+//
+// If fsPath is inside the resource directory, the embedded assets of the
+// same directory are also served, files on the disk taking precedence.
 func (g *Group) AddStatic(relativePath, fsPath string) {
 	if _, exists := g.static[relativePath]; exists {
 		log.Panic("Static path already exists in this group", "path", relativePath, "group", g.relativePath)
@@ -139,6 +151,40 @@ func (g *Group) AddMiddleWare(fnct server.HandlerFunc) {
 	g.middleWares = append([]server.HandlerFunc{fnct}, g.middleWares...)
 }
 
+// AddToSitemap flags the GET route of this group at the given relativePath
+// as a public page to be listed in the sitemap of the website.
+//
+// It panics if relativePath has parameters, since the sitemap only lists
+// actual URLs.
+func (g *Group) AddToSitemap(relativePath string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		log.Panic("Routes with parameters cannot be added to the sitemap", "path", relativePath, "group", g.relativePath)
+	}
+	g.sitemap[relativePath] = true
+}
+
+// SitemapPaths returns the absolute paths of the routes flagged with
+// AddToSitemap in this group and its sub groups, sorted.
+func (g *Group) SitemapPaths() []string {
+	res := g.sitemapPaths("/")
+	sort.Strings(res)
+	return res
+}
+
+// sitemapPaths returns the paths of the routes flagged with AddToSitemap
+// in this group and its sub groups, given the absolute path of its parent.
+func (g *Group) sitemapPaths(base string) []string {
+	base = path.Join(base, g.relativePath)
+	var res []string
+	for relativePath := range g.sitemap {
+		res = append(res, path.Join(base, relativePath))
+	}
+	for _, grp := range g.groups {
+		res = append(res, grp.sitemapPaths(base)...)
+	}
+	return res
+}
+
 // MustGetGroup returns the sub group of this group for the given relativePath
 // It panics if this group does not exist
 func (g *Group) MustGetGroup(relativePath string) *Group {
@@ -170,7 +216,7 @@ func (g *Group) createRoutes(base *server.RouterGroup) {
 		base.Handle(route.Method, route.Path, ctlr.handlers...)
 	}
 	for path, fsPath := range g.static {
-		base.Static(path, fsPath)
+		base.StaticFS(path, assetfs.Dir(server.ResourceDir, fsPath))
 	}
 }
 
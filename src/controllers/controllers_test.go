@@ -139,6 +139,12 @@ func TestControllers(t *testing.T) {
 		Convey("Overriding a controller that does not exist should fail", func() {
 			So(func() { registry.OverrideController(http.MethodGet, "/nonexistent", func(ctx *server.Context) {}) }, ShouldPanic)
 		})
+		Convey("Routes added to the sitemap should be listed with their absolute path", func() {
+			registry.AddToSitemap("/about")
+			registry.MustGetGroup("/test").AddGroup("/shop").AddToSitemap("/products")
+			So(registry.SitemapPaths(), ShouldResemble, []string{"/about", "/test/shop/products"})
+			So(func() { registry.AddToSitemap("/products/:id") }, ShouldPanic)
+		})
 		Convey("Boostrap should not panic", func() {
 			So(BootStrap, ShouldNotPanic)
 		})
@@ -10,9 +10,11 @@ import (
 
 var log logging.Logger
 
-// BootStrap creates the actual controllers from the controllers registry.
+// BootStrap sets up the CORS and CSRF middlewares and creates the actual
+// controllers from the controllers registry.
 // This function must be called before starting the http server.
 func BootStrap() {
+	setupSecurityMiddleWares(server.GetServer())
 	Registry.createRoutes(server.GetServer().Group("/"))
 }
 
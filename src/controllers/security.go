@@ -0,0 +1,160 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package controllers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/strutils"
+	"github.com/spf13/viper"
+)
+
+// CSRFHeader is the HTTP header in which clients should send the CSRF token
+const CSRFHeader = "X-CSRF-Token"
+
+// CSRFFormKey is the form field in which clients can send the CSRF token
+const CSRFFormKey = "csrf_token"
+
+// csrfSafeMethods are the HTTP methods that are not checked for CSRF
+var csrfSafeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// A CORSPolicy defines which cross origin requests are allowed by the server
+type CORSPolicy struct {
+	// AllowedOrigins is the list of origins allowed to make cross origin requests.
+	// "*" allows all origins.
+	AllowedOrigins []string
+	// AllowedMethods is the list of methods that can be used in cross origin requests.
+	AllowedMethods []string
+	// AllowedHeaders is the list of non simple headers that clients can send.
+	AllowedHeaders []string
+	// AllowCredentials allows clients to send cookies with cross origin requests.
+	// Credentials are only allowed for the origins listed explicitly, never for
+	// the origins that are only allowed by "*".
+	AllowCredentials bool
+	// MaxAge is the duration for which the result of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// matchOrigin returns true if the given origin is allowed by this policy.
+// The second returned value is true if the origin is only allowed by "*".
+func (cp CORSPolicy) matchOrigin(origin string) (bool, bool) {
+	var wildcard bool
+	for _, o := range cp.AllowedOrigins {
+		switch {
+		case strings.EqualFold(o, origin):
+			return true, false
+		case o == "*":
+			wildcard = true
+		}
+	}
+	return wildcard, wildcard
+}
+
+// CORS returns a middleware that applies the given CORS policy.
+//
+// Preflight requests of allowed origins are answered directly with
+// a 204 No Content status. Requests from origins that are not allowed
+// are served without CORS headers, so that the browser blocks them.
+func CORS(policy CORSPolicy) server.HandlerFunc {
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	return func(ctx *server.Context) {
+		origin := ctx.GetHeader("Origin")
+		allowed, wildcard := policy.matchOrigin(origin)
+		if origin == "" || !allowed {
+			ctx.Next()
+			return
+		}
+		ctx.Header("Access-Control-Allow-Origin", origin)
+		ctx.Header("Vary", "Origin")
+		if policy.AllowCredentials && !wildcard {
+			ctx.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if ctx.Request.Method != http.MethodOptions || ctx.GetHeader("Access-Control-Request-Method") == "" {
+			ctx.Next()
+			return
+		}
+		// Preflight request
+		ctx.Header("Access-Control-Allow-Methods", methods)
+		ctx.Header("Access-Control-Allow-Headers", headers)
+		if policy.MaxAge > 0 {
+			ctx.Header("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+		}
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// CSRFProtect returns a middleware that checks the CSRF token of unsafe requests
+// (i.e. POST, PUT, PATCH, DELETE) made within an authenticated session.
+//
+// The token issued by server.Context.CSRFToken must be sent either in the
+// X-CSRF-Token header or in the csrf_token form value.
+//
+// Requests with a JSON body are not checked unless checkJSON is set, since
+// browsers cannot send them cross origin without a CORS preflight request.
+// checkJSON must be set when a CORS policy allows credentials, because the
+// allowed origins can then send JSON requests with the session cookie.
+func CSRFProtect(checkJSON bool) server.HandlerFunc {
+	return func(ctx *server.Context) {
+		if csrfSafeMethods[ctx.Request.Method] || ctx.Session().Get("uid") == nil {
+			ctx.Next()
+			return
+		}
+		if !checkJSON && strings.HasPrefix(ctx.ContentType(), "application/json") {
+			ctx.Next()
+			return
+		}
+		expected, _ := ctx.Session().Get(server.CSRFSessionKey).(string)
+		token := ctx.GetHeader(CSRFHeader)
+		if token == "" {
+			token = ctx.PostForm(CSRFFormKey)
+		}
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			log.Warn("Invalid CSRF token", "path", ctx.Request.URL.Path, "ip", ctx.ClientIP())
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// setupSecurityMiddleWares adds the CORS and CSRF middlewares to the
+// given server according to the configuration.
+func setupSecurityMiddleWares(srv *server.Server) {
+	var credentialedCORS bool
+	if origins := viper.GetStringSlice("Server.CORS.AllowedOrigins"); len(origins) > 0 {
+		methods := viper.GetStringSlice("Server.CORS.AllowedMethods")
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+		}
+		headers := viper.GetStringSlice("Server.CORS.AllowedHeaders")
+		if len(headers) == 0 {
+			headers = []string{"Content-Type", "Authorization", CSRFHeader}
+		}
+		credentialedCORS = viper.GetBool("Server.CORS.AllowCredentials")
+		if credentialedCORS && strutils.IsIn("*", origins...) {
+			log.Warn("Credentials are not allowed for the origins only allowed by '*'")
+		}
+		srv.AddMiddleWare(CORS(CORSPolicy{
+			AllowedOrigins:   origins,
+			AllowedMethods:   methods,
+			AllowedHeaders:   headers,
+			AllowCredentials: credentialedCORS,
+			MaxAge:           viper.GetDuration("Server.CORS.MaxAge"),
+		}))
+	}
+	if viper.GetBool("Server.CSRFProtection") {
+		srv.AddMiddleWare(CSRFProtect(credentialedCORS))
+	}
+}
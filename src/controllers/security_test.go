@@ -0,0 +1,152 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/hexya-erp/hexya/src/server"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCORS(t *testing.T) {
+	Convey("Testing CORS middleware", t, func() {
+		srv := newServer()
+		srv.AddMiddleWare(CORS(CORSPolicy{
+			AllowedOrigins: []string{"https://allowed.example.com"},
+			AllowedMethods: []string{http.MethodGet, http.MethodPost},
+			AllowedHeaders: []string{"Content-Type"},
+		}))
+		srv.Group("/").GET("/ping", func(ctx *server.Context) {
+			ctx.String(http.StatusOK, "pong")
+		})
+		Convey("Allowed origins should get CORS headers", func() {
+			req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+			req.Header.Set("Origin", "https://allowed.example.com")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://allowed.example.com")
+		})
+		Convey("Other origins should not get CORS headers", func() {
+			req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+			req.Header.Set("Origin", "https://evil.example.com")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldBeEmpty)
+		})
+		Convey("Preflight requests should be answered directly", func() {
+			req, _ := http.NewRequest(http.MethodOptions, "/ping", nil)
+			req.Header.Set("Origin", "https://allowed.example.com")
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(w.Header().Get("Access-Control-Allow-Methods"), ShouldEqual, "GET, POST")
+		})
+	})
+	Convey("Testing CORS credentials", t, func() {
+		srv := newServer()
+		srv.AddMiddleWare(CORS(CORSPolicy{
+			AllowedOrigins:   []string{"*", "https://allowed.example.com"},
+			AllowCredentials: true,
+		}))
+		srv.Group("/").GET("/ping", func(ctx *server.Context) {
+			ctx.String(http.StatusOK, "pong")
+		})
+		get := func(origin string) http.Header {
+			req, _ := http.NewRequest(http.MethodGet, "/ping", nil)
+			req.Header.Set("Origin", origin)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			return w.Header()
+		}
+		Convey("Explicitly allowed origins should be allowed credentials", func() {
+			So(get("https://allowed.example.com").Get("Access-Control-Allow-Credentials"), ShouldEqual, "true")
+		})
+		Convey("Origins only allowed by '*' should not be allowed credentials", func() {
+			header := get("https://evil.example.com")
+			So(header.Get("Access-Control-Allow-Origin"), ShouldEqual, "https://evil.example.com")
+			So(header.Get("Access-Control-Allow-Credentials"), ShouldBeEmpty)
+		})
+	})
+}
+
+func TestCSRF(t *testing.T) {
+	Convey("Testing CSRF protection", t, func() {
+		srv := newServer()
+		srv.Use(sessions.Sessions("hexya-session", cookie.NewStore([]byte("secret"))))
+		srv.AddMiddleWare(CSRFProtect(false))
+		grp := srv.Group("/")
+		grp.GET("/login", func(ctx *server.Context) {
+			ctx.Session().Set("uid", int64(2))
+			ctx.String(http.StatusOK, ctx.CSRFToken())
+		})
+		grp.POST("/submit", func(ctx *server.Context) {
+			ctx.String(http.StatusOK, "ok")
+		})
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+		token := w.Body.String()
+		sessionCookie := w.Header().Get("Set-Cookie")
+		So(token, ShouldNotBeEmpty)
+		postForm := func(values url.Values) int {
+			req := httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(values.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Cookie", sessionCookie)
+			rw := httptest.NewRecorder()
+			srv.ServeHTTP(rw, req)
+			return rw.Code
+		}
+		Convey("POST without token should be forbidden", func() {
+			So(postForm(url.Values{}), ShouldEqual, http.StatusForbidden)
+		})
+		Convey("POST with wrong token should be forbidden", func() {
+			So(postForm(url.Values{CSRFFormKey: {"wrong"}}), ShouldEqual, http.StatusForbidden)
+		})
+		Convey("POST with valid token should succeed", func() {
+			So(postForm(url.Values{CSRFFormKey: {token}}), ShouldEqual, http.StatusOK)
+		})
+		Convey("POST without session should not be checked", func() {
+			req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+			rw := httptest.NewRecorder()
+			srv.ServeHTTP(rw, req)
+			So(rw.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+	Convey("Testing CSRF protection of JSON requests", t, func() {
+		for _, checkJSON := range []bool{false, true} {
+			srv := newServer()
+			srv.Use(sessions.Sessions("hexya-session", cookie.NewStore([]byte("secret"))))
+			srv.AddMiddleWare(CSRFProtect(checkJSON))
+			grp := srv.Group("/")
+			grp.GET("/login", func(ctx *server.Context) {
+				ctx.Session().Set("uid", int64(2))
+				ctx.String(http.StatusOK, ctx.CSRFToken())
+			})
+			grp.POST("/rpc", func(ctx *server.Context) {
+				ctx.String(http.StatusOK, "ok")
+			})
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+			req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"params":{}}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Cookie", w.Header().Get("Set-Cookie"))
+			rw := httptest.NewRecorder()
+			srv.ServeHTTP(rw, req)
+			if checkJSON {
+				So(rw.Code, ShouldEqual, http.StatusForbidden)
+			} else {
+				So(rw.Code, ShouldEqual, http.StatusOK)
+			}
+		}
+	})
+}
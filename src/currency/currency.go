@@ -0,0 +1,82 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package currency converts amounts between currencies.
+//
+// Rates are stored in the CurrencyRate model, which is defined by a module
+// with the Currency model. All rates of a company are expressed relatively to
+// the same base currency (whose rate is 1), so that an amount is converted
+// from one currency to another by the ratio of their rates.
+package currency
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// ErrNoRate is returned when a currency has no rate at the conversion date
+var ErrNoRate = errors.New("no currency rate")
+
+// A Currency in which amounts can be converted
+type Currency interface {
+	i18n.Currency
+	// Code returns the ISO 4217 code of the currency (e.g. EUR)
+	Code() string
+}
+
+// GetRate returns the rate of the currency with the given ISO code for the
+// given company at the given date, that is the rate of the most recent
+// CurrencyRate record of this currency and company at or before date.
+// The second returned value is false if there is no such rate.
+//
+// Rate returns an error as long as it is not set.
+var GetRate func(env models.Environment, code string, date dates.Date, companyID int64) (float64, bool)
+
+// SetRates stores the given rates indexed by ISO code for the given date
+// for all companies. Rates of unknown currencies are ignored.
+//
+// It is used to store the rates fetched from the European Central Bank,
+// which are not fetched at all as long as it is not set.
+var SetRates func(env models.Environment, date dates.Date, rates map[string]float64)
+
+// Rate returns the rate of the given currency for the given company at the
+// given date. It returns ErrNoRate if the currency has no rate at this date.
+func Rate(env models.Environment, currency Currency, date dates.Date, companyID int64) (float64, error) {
+	if GetRate == nil {
+		return 0, errors.New("currency rates are not available: no module defines the CurrencyRate model")
+	}
+	rate, ok := GetRate(env, currency.Code(), date, companyID)
+	if !ok || rate == 0 {
+		return 0, fmt.Errorf("%w for %s at %s", ErrNoRate, currency.Code(), date)
+	}
+	return rate, nil
+}
+
+// Convert returns the given amount in currency from converted into currency to
+// with the rates of the given company at the given date. The result is rounded
+// according to the precision of currency to.
+func Convert(env models.Environment, amount float64, from, to Currency, date dates.Date, companyID int64) (float64, error) {
+	if from.Code() == to.Code() {
+		return to.Round(amount), nil
+	}
+	fromRate, err := Rate(env, from, date, companyID)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := Rate(env, to, date, companyID)
+	if err != nil {
+		return 0, err
+	}
+	return to.Round(amount * toRate / fromRate), nil
+}
+
+func init() {
+	log = logging.GetLogger("currency")
+}
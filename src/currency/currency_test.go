@@ -0,0 +1,95 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package currency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testCurrency struct {
+	code     string
+	rounding float64
+}
+
+func (c testCurrency) Code() string                { return c.code }
+func (c testCurrency) Symbol() string              { return c.code }
+func (c testCurrency) Position() string            { return "after" }
+func (c testCurrency) DecimalPlaces() int          { return 2 }
+func (c testCurrency) Round(value float64) float64 { return nbutils.Round(value, c.rounding) }
+
+var ecbRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2019-03-15">
+			<Cube currency="USD" rate="1.1328"/>
+			<Cube currency="JPY" rate="126.39"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestConvert(t *testing.T) {
+	eur := testCurrency{code: "EUR", rounding: 0.01}
+	usd := testCurrency{code: "USD", rounding: 0.01}
+	jpy := testCurrency{code: "JPY", rounding: 1}
+	chf := testCurrency{code: "CHF", rounding: 0.05}
+	GetRate = func(env models.Environment, code string, date dates.Date, companyID int64) (float64, bool) {
+		rates := map[string]float64{"EUR": 1, "USD": 1.1328, "JPY": 126.39}
+		if companyID == 2 {
+			rates["USD"] = 1.2
+		}
+		rate, ok := rates[code]
+		return rate, ok
+	}
+	var env models.Environment
+	date := dates.ParseDate("2019-03-15")
+	Convey("Testing currency conversion", t, func() {
+		Convey("Amounts should be converted with the ratio of the rates", func() {
+			res, err := Convert(env, 100, eur, usd, date, 1)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, 113.28)
+			res, err = Convert(env, 100, usd, jpy, date, 1)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, 11157)
+			res, err = Convert(env, 100, eur, usd, date, 2)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, 120)
+		})
+		Convey("Amounts in the same currency should only be rounded", func() {
+			res, err := Convert(env, 10.123, usd, usd, date, 1)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, 10.12)
+		})
+		Convey("Currencies without rates should fail", func() {
+			_, err := Convert(env, 100, eur, chf, date, 1)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestECBRates(t *testing.T) {
+	Convey("Testing ECB rates", t, func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(ecbRates))
+		}))
+		defer srv.Close()
+		date, rates, err := FetchECBRates(srv.Client(), srv.URL)
+		So(err, ShouldBeNil)
+		So(date.String(), ShouldEqual, "2019-03-15")
+		So(rates, ShouldResemble, map[string]float64{"EUR": 1, "USD": 1.1328, "JPY": 126.39})
+		_, _, err = FetchECBRates(srv.Client(), srv.URL+"/notfound")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package currency
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ECBRatesURL is the URL of the daily reference rates of the European Central Bank
+	ECBRatesURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	// ecbUpdatePeriod is the time between two updates of the rates
+	ecbUpdatePeriod = 12 * time.Hour
+	// ecbTimeout is the timeout of requests to the European Central Bank
+	ecbTimeout = 30 * time.Second
+)
+
+// ecbEnvelope is the XML document of the reference rates of the European Central Bank
+type ecbEnvelope struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// FetchECBRates downloads the reference rates of the European Central Bank
+// from the given URL. Rates are relative to the Euro, whose rate is 1.
+func FetchECBRates(client *http.Client, url string) (dates.Date, map[string]float64, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return dates.Date{}, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return dates.Date{}, nil, fmt.Errorf("unable to fetch ECB rates: %s", resp.Status)
+	}
+	var envelope ecbEnvelope
+	if err = xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return dates.Date{}, nil, err
+	}
+	date, err := dates.ParseDateWithLayout(dates.DefaultServerDateFormat, envelope.Cube.Cube.Time)
+	if err != nil {
+		return dates.Date{}, nil, err
+	}
+	rates := map[string]float64{"EUR": 1}
+	for _, rate := range envelope.Cube.Cube.Rates {
+		rates[rate.Currency] = rate.Rate
+	}
+	return date, rates, nil
+}
+
+// UpdateECBRates fetches the reference rates of the European
+// Central Bank and stores them in the given database.
+func UpdateECBRates(dbName string) error {
+	if SetRates == nil {
+		return nil
+	}
+	date, rates, err := FetchECBRates(&http.Client{Timeout: ecbTimeout}, ECBRatesURL)
+	if err != nil {
+		return err
+	}
+	return models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		SetRates(env, date, rates)
+	})
+}
+
+// updateAllECBRates updates the rates of all connected databases
+// if enabled with the Currency.ECBRates configuration key.
+func updateAllECBRates() {
+	if !viper.GetBool("Currency.ECBRates") || SetRates == nil {
+		return
+	}
+	for _, dbName := range models.ConnectedDBNames() {
+		if err := UpdateECBRates(dbName); err != nil {
+			log.Warn("Unable to update currency rates", "database", dbName, "error", err)
+		}
+	}
+}
+
+func init() {
+	models.RegisterWorker(models.NewWorkerFunction(updateAllECBRates, ecbUpdatePeriod))
+}
@@ -0,0 +1,229 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package dbmanager
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// dumpFileName is the name of the database dump inside backup archives
+	dumpFileName = "dump.pgdump"
+	// pgDumpMagic is the header of pg_dump custom format dumps
+	pgDumpMagic = "PGDMP"
+	// fileStorePrefix is the directory of the filestore inside backup archives
+	fileStorePrefix = "filestore/"
+)
+
+// pgEnv returns the environment to run PostgreSQL client tools
+// with the credentials of the configuration.
+func pgEnv() []string {
+	params := ConnectionParams("")
+	env := os.Environ()
+	for key, value := range map[string]string{
+		"PGHOST":        params.Host,
+		"PGPORT":        params.Port,
+		"PGUSER":        params.User,
+		"PGPASSWORD":    params.Password,
+		"PGSSLMODE":     params.SSLMode,
+		"PGSSLCERT":     params.SSLCert,
+		"PGSSLKEY":      params.SSLKey,
+		"PGSSLROOTCERT": params.SSLCA,
+	} {
+		if value != "" {
+			env = append(env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	return env
+}
+
+// runPGTool runs the given PostgreSQL client tool with the given
+// arguments, stdin and stdout.
+func runPGTool(stdin io.Reader, stdout io.Writer, tool string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(tool, args...)
+	cmd.Env = pgEnv()
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %s: %s", tool, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Backup writes to w a zip archive of the database with the given name.
+//
+// The archive contains a dump of the database made with pg_dump in its
+// custom format and the database filestore.
+func Backup(name string, w io.Writer) error {
+	if err := checkDBName(name); err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	dump, err := zw.Create(dumpFileName)
+	if err != nil {
+		return err
+	}
+	if err = runPGTool(nil, dump, "pg_dump", "--no-owner", "--format=custom", name); err != nil {
+		return err
+	}
+	fsDir := FileStoreDir(name)
+	if _, err = os.Stat(fsDir); err == nil {
+		err = filepath.Walk(fsDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			relPath, err := filepath.Rel(fsDir, path)
+			if err != nil {
+				return err
+			}
+			fw, err := zw.Create(fileStorePrefix + filepath.ToSlash(relPath))
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(fw, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Database backed up", "database", name)
+	return zw.Close()
+}
+
+// Restore creates the database with the given name from the backup archive
+// read from r, which must be of the given size.
+//
+// The database must not exist. If the restoration fails, the partially
+// restored database is dropped.
+func Restore(name string, r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	var dump *zip.File
+	for _, f := range zr.File {
+		if f.Name == dumpFileName {
+			dump = f
+			break
+		}
+	}
+	if dump == nil {
+		return fmt.Errorf("invalid backup: %s not found", dumpFileName)
+	}
+	if err = checkDump(dump); err != nil {
+		return err
+	}
+	if err = createEmpty(name); err != nil {
+		return err
+	}
+	if err = restoreArchive(name, dump, zr.File); err != nil {
+		if dErr := Drop(name); dErr != nil {
+			log.Warn("Unable to drop partially restored database", "database", name, "error", dErr)
+		}
+		return err
+	}
+	resetTenant(name)
+	log.Info("Database restored", "database", name)
+	return nil
+}
+
+// checkDump returns an error if the given dump is not in pg_dump custom format
+func checkDump(dump *zip.File) error {
+	dr, err := dump.Open()
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+	magic := make([]byte, len(pgDumpMagic))
+	if _, err = io.ReadFull(dr, magic); err != nil || string(magic) != pgDumpMagic {
+		return fmt.Errorf("invalid backup: %s is not a pg_dump custom format dump", dumpFileName)
+	}
+	return nil
+}
+
+// restoreArchive loads the given dump into the database name
+// and extracts the filestore files into its filestore directory.
+func restoreArchive(name string, dump *zip.File, files []*zip.File) error {
+	dr, err := dump.Open()
+	if err != nil {
+		return err
+	}
+	defer dr.Close()
+	// The dump is loaded with pg_restore and not with psql, so that the
+	// archive cannot run psql meta-commands such as shell escapes.
+	if err = runPGTool(dr, nil, "pg_restore", "--no-owner", "--exit-on-error", "--dbname", name); err != nil {
+		return err
+	}
+	fsDir := FileStoreDir(name)
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, fileStorePrefix) || f.FileInfo().IsDir() {
+			continue
+		}
+		target := filepath.Join(fsDir, filepath.FromSlash(strings.TrimPrefix(f.Name, fileStorePrefix)))
+		if !strings.HasPrefix(target, filepath.Clean(fsDir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid file path in backup: %s", f.Name)
+		}
+		if err = extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the content of the given zip file to target
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// copyFile copies the src file to dst, creating its parent directory if needed.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package dbmanager
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// MasterPasswordKey is the form field in which clients must send
+// the master password to access the database manager endpoints.
+const MasterPasswordKey = "master_pwd"
+
+// requireMasterPassword is a middleware that aborts requests
+// that do not provide the master password.
+func requireMasterPassword(ctx *server.Context) {
+	if !CheckMasterPassword(ctx.PostForm(MasterPasswordKey)) {
+		log.Warn("Access denied to database manager", "ip", ctx.ClientIP(), "path", ctx.Request.URL.Path)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "access denied"})
+		return
+	}
+	ctx.Next()
+}
+
+// respond writes a JSON response with the given data or the given error
+func respond(ctx *server.Context, data interface{}, err error) {
+	if err != nil {
+		log.Warn("Database manager operation failed", "path", ctx.Request.URL.Path, "error", err)
+		ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]interface{}{"result": data})
+}
+
+// listDatabases returns the list of databases
+func listDatabases(ctx *server.Context) {
+	dbs, err := List()
+	respond(ctx, dbs, err)
+}
+
+// createDatabase creates a new database
+func createDatabase(ctx *server.Context) {
+	err := Create(ctx.PostForm("name"), ctx.PostForm("demo") == "true")
+	respond(ctx, true, err)
+}
+
+// duplicateDatabase duplicates a database
+func duplicateDatabase(ctx *server.Context) {
+	err := Duplicate(ctx.PostForm("name"), ctx.PostForm("new_name"))
+	respond(ctx, true, err)
+}
+
+// dropDatabase drops a database
+func dropDatabase(ctx *server.Context) {
+	err := Drop(ctx.PostForm("name"))
+	respond(ctx, true, err)
+}
+
+// backupDatabase streams a zip backup of a database to the client.
+//
+// Errors can only be reported to the client if they occur before the
+// first bytes of the archive are sent. Otherwise, the connection is
+// aborted and the client gets a truncated archive.
+func backupDatabase(ctx *server.Context) {
+	name := ctx.PostForm("name")
+	fileName := fmt.Sprintf("%s_%s.zip", name, time.Now().Format("2006-01-02_15-04-05"))
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	ctx.Header("Content-Type", "application/zip")
+	if err := Backup(name, ctx.Writer); err != nil {
+		if ctx.Writer.Written() {
+			log.Warn("Database backup interrupted", "database", name, "error", err)
+			ctx.Abort()
+			return
+		}
+		ctx.Writer.Header().Del("Content-Disposition")
+		ctx.Writer.Header().Del("Content-Type")
+		respond(ctx, nil, err)
+	}
+}
+
+// restoreDatabase restores a database from an uploaded backup file
+func restoreDatabase(ctx *server.Context) {
+	fh, err := ctx.FormFile("backup_file")
+	if err != nil {
+		respond(ctx, nil, err)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respond(ctx, nil, err)
+		return
+	}
+	defer f.Close()
+	err = Restore(ctx.PostForm("name"), f, fh.Size)
+	respond(ctx, true, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/database")
+	grp.AddMiddleWare(requireMasterPassword)
+	grp.AddController(http.MethodPost, "/list", listDatabases)
+	grp.AddController(http.MethodPost, "/create", createDatabase)
+	grp.AddController(http.MethodPost, "/duplicate", duplicateDatabase)
+	grp.AddController(http.MethodPost, "/drop", dropDatabase)
+	grp.AddController(http.MethodPost, "/backup", backupDatabase)
+	grp.AddController(http.MethodPost, "/restore", restoreDatabase)
+}
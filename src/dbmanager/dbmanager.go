@@ -0,0 +1,267 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package dbmanager provides functions to create, duplicate, drop,
+// backup and restore Hexya databases.
+//
+// All operations are made through an administration connection to the
+// 'postgres' maintenance database with the credentials of the configuration.
+package dbmanager
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/tools/password"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
+)
+
+// maintenanceDB is the database to which we connect to manage the other databases
+const maintenanceDB = "postgres"
+
+// dbNameRegex is the pattern that valid database names must match
+var dbNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// InitDatabase is called after a database has been created to install
+// the base modules into it, with demo data if demo is true.
+//
+// It is set by the cmd package to run the 'updatedb' command of
+// the project on the new database.
+var InitDatabase func(dbName string, demo bool) error
+
+// ConnectionParams returns the connection parameters for the given database name
+// built from the DB section of the configuration.
+func ConnectionParams(dbName string) models.ConnectionParams {
+	return models.ConnectionParams{
+		Host:     viper.GetString("DB.Host"),
+		Port:     viper.GetString("DB.Port"),
+		User:     viper.GetString("DB.User"),
+		Password: viper.GetString("DB.Password"),
+		DBName:   dbName,
+		SSLMode:  viper.GetString("DB.SSLMode"),
+		SSLCert:  viper.GetString("DB.SSLCert"),
+		SSLKey:   viper.GetString("DB.SSLKey"),
+		SSLCA:    viper.GetString("DB.SSLCA"),
+	}
+}
+
+// FileStoreDir returns the path to the directory in which the
+// files of the given database are stored.
+func FileStoreDir(dbName string) string {
+	return filepath.Join(viper.GetString("DataDir"), "filestore", dbName)
+}
+
+// CheckMasterPassword returns true if the given password matches the master
+// password of the configuration. The configured master password can be either
+// in clear text or hashed with the password package.
+//
+// It always returns false if no master password is configured.
+func CheckMasterPassword(pwd string) bool {
+	master := viper.GetString("Server.MasterPassword")
+	if master == "" {
+		return false
+	}
+	if strings.HasPrefix(master, "$pbkdf2-sha256$") {
+		return password.Verify(pwd, master)
+	}
+	return subtle.ConstantTimeCompare([]byte(pwd), []byte(master)) == 1
+}
+
+// checkDBName returns an error if the given name is not a valid database name
+func checkDBName(name string) error {
+	if !dbNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid database name: %q", name)
+	}
+	return nil
+}
+
+// adminConnect opens a connection to the maintenance database.
+// The connection must be closed by the caller.
+func adminConnect() (*sqlx.DB, error) {
+	driver := viper.GetString("DB.Driver")
+	if driver != "postgres" {
+		return nil, fmt.Errorf("database manager is not available for driver %s", driver)
+	}
+	return sqlx.Connect(driver, models.ConnectionString(driver, ConnectionParams(maintenanceDB)))
+}
+
+// exists returns true if the database with the given name exists
+func exists(adm *sqlx.DB, name string) (bool, error) {
+	var count int
+	err := adm.Get(&count, "SELECT COUNT(*) FROM pg_database WHERE datname = $1", name)
+	return count > 0, err
+}
+
+// terminateConnections closes all the connections to the given database
+// so that it can be dropped or used as a template.
+func terminateConnections(adm *sqlx.DB, name string) error {
+	_, err := adm.Exec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE datname = $1 AND pid != pg_backend_pid()`, name)
+	return err
+}
+
+// List returns the names of the databases of the server, excluding templates
+// and the maintenance database.
+func List() ([]string, error) {
+	adm, err := adminConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer adm.Close()
+	var res []string
+	err = adm.Select(&res, `SELECT datname FROM pg_database
+		WHERE datistemplate = false AND datname != $1 ORDER BY datname`, maintenanceDB)
+	return res, err
+}
+
+// Create creates a new empty database with the given name and calls
+// InitDatabase to install the base modules into it.
+func Create(name string, demo bool) error {
+	if err := createEmpty(name); err != nil {
+		return err
+	}
+	if InitDatabase == nil {
+		log.Warn("No database initialization function set: database left empty", "database", name)
+		return nil
+	}
+	if err := InitDatabase(name, demo); err != nil {
+		return fmt.Errorf("unable to initialize database %s: %s", name, err)
+	}
+	resetTenant(name)
+	log.Info("Database created", "database", name, "demo", demo)
+	return nil
+}
+
+// createEmpty creates a new empty database with the given name
+func createEmpty(name string) error {
+	if err := checkDBName(name); err != nil {
+		return err
+	}
+	adm, err := adminConnect()
+	if err != nil {
+		return err
+	}
+	defer adm.Close()
+	if ok, err := exists(adm, name); err != nil || ok {
+		if err == nil {
+			err = fmt.Errorf("database %s already exists", name)
+		}
+		return err
+	}
+	_, err = adm.Exec(fmt.Sprintf("CREATE DATABASE %s ENCODING 'unicode' TEMPLATE template0", pq.QuoteIdentifier(name)))
+	resetTenant(name)
+	return err
+}
+
+// Duplicate creates a new database newName as a copy of the database name,
+// including its filestore.
+//
+// The copy requires closing all the connections to the database name. The
+// main database of this server can therefore not be duplicated and the
+// connection of this server to a tenant database is closed.
+func Duplicate(name, newName string) error {
+	if err := checkDBName(name); err != nil {
+		return err
+	}
+	if err := checkDBName(newName); err != nil {
+		return err
+	}
+	if name == models.MainDBName() {
+		return fmt.Errorf("database %s is the main database of this server and cannot be duplicated", name)
+	}
+	adm, err := adminConnect()
+	if err != nil {
+		return err
+	}
+	defer adm.Close()
+	if ok, err := exists(adm, newName); err != nil || ok {
+		if err == nil {
+			err = fmt.Errorf("database %s already exists", newName)
+		}
+		return err
+	}
+	models.DBCloseTenant(name)
+	if err = terminateConnections(adm, name); err != nil {
+		return err
+	}
+	_, err = adm.Exec(fmt.Sprintf("CREATE DATABASE %s ENCODING 'unicode' TEMPLATE %s",
+		pq.QuoteIdentifier(newName), pq.QuoteIdentifier(name)))
+	resetTenant(newName)
+	if err != nil {
+		return err
+	}
+	if err = copyDir(FileStoreDir(name), FileStoreDir(newName)); err != nil {
+		return err
+	}
+	log.Info("Database duplicated", "database", name, "newDatabase", newName)
+	return nil
+}
+
+// Drop deletes the database with the given name and its filestore.
+//
+// The main database of this server cannot be dropped.
+func Drop(name string) error {
+	if err := checkDBName(name); err != nil {
+		return err
+	}
+	if name == models.MainDBName() {
+		return fmt.Errorf("database %s is the main database of this server and cannot be dropped", name)
+	}
+	adm, err := adminConnect()
+	if err != nil {
+		return err
+	}
+	defer adm.Close()
+	if ok, err := exists(adm, name); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("database %s does not exist", name)
+		}
+		return err
+	}
+	models.DBCloseTenant(name)
+	resetTenant(name)
+	if err = terminateConnections(adm, name); err != nil {
+		return err
+	}
+	if _, err = adm.Exec(fmt.Sprintf("DROP DATABASE %s", pq.QuoteIdentifier(name))); err != nil {
+		return err
+	}
+	if err = os.RemoveAll(FileStoreDir(name)); err != nil {
+		return err
+	}
+	log.Info("Database dropped", "database", name)
+	return nil
+}
+
+// copyDir recursively copies the src directory into dst.
+// It does nothing if src does not exist.
+func copyDir(src, dst string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return errors.New("unable to copy non regular file " + path)
+		}
+		return copyFile(path, target)
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package dbmanager
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/password"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+)
+
+func TestDBManager(t *testing.T) {
+	Convey("Testing database manager helpers", t, func() {
+		Convey("Master password should be checked in clear text", func() {
+			viper.Set("Server.MasterPassword", "secret")
+			So(CheckMasterPassword("secret"), ShouldBeTrue)
+			So(CheckMasterPassword("wrong"), ShouldBeFalse)
+		})
+		Convey("Master password should be checked against a hash", func() {
+			hash, err := password.Hash("secret")
+			So(err, ShouldBeNil)
+			viper.Set("Server.MasterPassword", hash)
+			So(CheckMasterPassword("secret"), ShouldBeTrue)
+			So(CheckMasterPassword("wrong"), ShouldBeFalse)
+		})
+		Convey("Empty master password should deny all access", func() {
+			viper.Set("Server.MasterPassword", "")
+			So(CheckMasterPassword(""), ShouldBeFalse)
+		})
+		Convey("Database names should be validated", func() {
+			So(checkDBName("hexya_test-1.0"), ShouldBeNil)
+			So(checkDBName(""), ShouldNotBeNil)
+			So(checkDBName("-hexya"), ShouldNotBeNil)
+			So(checkDBName(`hexya"; DROP DATABASE x`), ShouldNotBeNil)
+		})
+		Convey("Filestores should be copied recursively", func() {
+			dir, err := ioutil.TempDir("", "hexya-dbmanager")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			viper.Set("DataDir", dir)
+			src := FileStoreDir("src")
+			So(os.MkdirAll(filepath.Join(src, "ab"), 0755), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(src, "ab", "file"), []byte("content"), 0644), ShouldBeNil)
+			So(copyDir(src, FileStoreDir("dst")), ShouldBeNil)
+			data, err := ioutil.ReadFile(filepath.Join(FileStoreDir("dst"), "ab", "file"))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "content")
+			So(copyDir(FileStoreDir("missing"), FileStoreDir("other")), ShouldBeNil)
+		})
+		Convey("Only custom format dumps should be restored", func() {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			fw, err := zw.Create(dumpFileName)
+			So(err, ShouldBeNil)
+			fw.Write([]byte("\\! touch /tmp/hexya-restore\n"))
+			So(zw.Close(), ShouldBeNil)
+			err = Restore("hexya_restored", bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "custom format")
+		})
+	})
+}
+
+func TestBackupEndpoint(t *testing.T) {
+	Convey("Testing the backup endpoint", t, func() {
+		srv := &server.Server{Engine: gin.New()}
+		srv.Group("/").POST("/backup", backupDatabase)
+		Convey("Errors before streaming should be reported as JSON", func() {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/backup", strings.NewReader("name=-invalid"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			srv.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(w.Header().Get("Content-Disposition"), ShouldBeEmpty)
+			So(w.Header().Get("Content-Type"), ShouldStartWith, "application/json")
+			So(w.Body.String(), ShouldContainSubstring, "invalid database name")
+		})
+	})
+}
+
+func TestTenantSelection(t *testing.T) {
+	Convey("Testing database selection", t, func() {
+		Convey("Database should be deduced from hostname", func() {
+			So(databaseFromHost("", "acme.example.com"), ShouldBeEmpty)
+			So(databaseFromHost("%d", "acme.example.com:8080"), ShouldEqual, "acme")
+			So(databaseFromHost("hexya_%d", "acme.example.com"), ShouldEqual, "hexya_acme")
+			So(databaseFromHost("%h", "acme.example.com"), ShouldEqual, "acme.example.com")
+		})
+		Convey("Invalid databases should not be served", func() {
+			srv := &server.Server{Engine: gin.New()}
+			srv.Use(sessions.Sessions("hexya-session", cookie.NewStore([]byte("secret"))))
+			srv.AddMiddleWare(SelectDatabase(""))
+			srv.Group("/").GET("/ping", func(ctx *server.Context) {
+				ctx.String(http.StatusOK, ctx.DBName())
+			})
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldBeEmpty)
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping?db=-invalid", nil))
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+		Convey("Only listed databases should be connected", func() {
+			tenantDatabases.Lock()
+			tenantDatabases.names = map[string]bool{"acme": true}
+			tenantDatabases.listTime = time.Now()
+			tenantDatabases.Unlock()
+			defer resetTenant("acme")
+			So(connectTenant("postgres"), ShouldNotBeNil)
+			So(connectTenant("template1"), ShouldNotBeNil)
+		})
+		Convey("Failed connections should be cached", func() {
+			failure := errors.New("connection refused")
+			tenantDatabases.Lock()
+			tenantDatabases.failures["acme"] = tenantFailure{err: failure, time: time.Now()}
+			tenantDatabases.Unlock()
+			So(connectTenant("acme"), ShouldEqual, failure)
+			resetTenant("acme")
+			tenantDatabases.Lock()
+			_, cached := tenantDatabases.failures["acme"]
+			tenantDatabases.Unlock()
+			So(cached, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package dbmanager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/spf13/viper"
+)
+
+// tenantRetryDelay is the time during which the list of databases of the
+// server is kept and during which a database that could not be connected to
+// is not tried again.
+const tenantRetryDelay = time.Minute
+
+// tenantFailure is a failed connection to a tenant database
+type tenantFailure struct {
+	err  error
+	time time.Time
+}
+
+// tenantDatabases holds the databases that can be selected by requests,
+// i.e. the databases returned by List, and the last failed connection to each
+// of them so that requests to a broken database do not connect again.
+var tenantDatabases = struct {
+	sync.Mutex
+	names    map[string]bool
+	listTime time.Time
+	failures map[string]tenantFailure
+}{
+	failures: make(map[string]tenantFailure),
+}
+
+// databaseFromHost returns the database name to use for the given host
+// according to the given filter.
+//
+// In the filter, '%h' is replaced by the full hostname (without port) and
+// '%d' by its first subdomain. For instance, with filter '%d', requests to
+// 'acme.example.com' are served by the 'acme' database.
+// It returns an empty string if filter is empty.
+func databaseFromHost(filter, host string) string {
+	if filter == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	subDomain := strings.SplitN(host, ".", 2)[0]
+	return strings.NewReplacer("%h", host, "%d", subDomain).Replace(filter)
+}
+
+// SelectDatabase returns a middleware that selects the database of each request
+// among the databases of the server.
+//
+// If filter is not empty, the database is deduced from the request hostname
+// (see databaseFromHost). Otherwise, it is taken from the 'db' query parameter
+// and kept in the session for subsequent requests. Changing the database of a
+// session logs the user out, since user IDs are not shared between databases.
+//
+// Only the databases returned by List can be selected. The selected database
+// is available with server.Context.DBName and requests for another database
+// or for a database that cannot be connected to are aborted with a 404 status.
+func SelectDatabase(filter string) server.HandlerFunc {
+	return func(ctx *server.Context) {
+		name := databaseFromHost(filter, ctx.Request.Host)
+		if name == "" {
+			name = sessionDatabase(ctx)
+		}
+		if name == "" || name == models.MainDBName() {
+			ctx.Next()
+			return
+		}
+		if err := connectTenant(name); err != nil {
+			log.Warn("Unable to select database", "database", name, "host", ctx.Request.Host, "error", err)
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		ctx.Set(server.DBNameKey, name)
+		ctx.Next()
+	}
+}
+
+// sessionDatabase returns the database name stored in the session,
+// updating it first if a 'db' query parameter is given.
+func sessionDatabase(ctx *server.Context) string {
+	name, _ := ctx.Session().Get(server.DBNameKey).(string)
+	queryName := ctx.Query(server.DBNameKey)
+	if queryName == "" || queryName == name {
+		return name
+	}
+	ctx.Session().Clear()
+	ctx.Session().Set(server.DBNameKey, queryName)
+	if err := ctx.Session().Save(); err != nil {
+		log.Warn("Unable to save database in session", "error", err)
+	}
+	return queryName
+}
+
+// connectTenant connects to the given tenant database if it is not connected yet.
+//
+// It returns an error if name is not one of the databases returned by List.
+// If the connection fails, the error is returned again without connecting
+// during tenantRetryDelay.
+func connectTenant(name string) error {
+	if models.TenantConnected(name) {
+		return nil
+	}
+	if err := checkDBName(name); err != nil {
+		return err
+	}
+	tenantDatabases.Lock()
+	if failure, ok := tenantDatabases.failures[name]; ok && time.Since(failure.time) < tenantRetryDelay {
+		tenantDatabases.Unlock()
+		return failure.err
+	}
+	if time.Since(tenantDatabases.listTime) >= tenantRetryDelay {
+		names, err := List()
+		if err != nil {
+			tenantDatabases.Unlock()
+			return err
+		}
+		tenantDatabases.names = make(map[string]bool, len(names))
+		for _, n := range names {
+			tenantDatabases.names[n] = true
+		}
+		tenantDatabases.listTime = time.Now()
+	}
+	listed := tenantDatabases.names[name]
+	tenantDatabases.Unlock()
+	if !listed {
+		return fmt.Errorf("database %s does not exist", name)
+	}
+	err := models.DBConnectTenant(viper.GetString("DB.Driver"), ConnectionParams(name))
+	if err != nil {
+		tenantDatabases.Lock()
+		tenantDatabases.failures[name] = tenantFailure{err: err, time: time.Now()}
+		tenantDatabases.Unlock()
+	}
+	return err
+}
+
+// resetTenant forgets the list of databases and the failed connection to
+// the given database so that the changes made to it by the database manager
+// are taken into account by the next request.
+func resetTenant(name string) {
+	tenantDatabases.Lock()
+	defer tenantDatabases.Unlock()
+	tenantDatabases.listTime = time.Time{}
+	delete(tenantDatabases.failures, name)
+}
+
+// SetupMultiTenancy adds the SelectDatabase middleware to the given
+// server if multi tenancy is enabled in the configuration.
+func SetupMultiTenancy(srv *server.Server) {
+	if !viper.GetBool("Server.MultiTenant") {
+		return
+	}
+	srv.AddMiddleWare(SelectDatabase(viper.GetString("Server.DBFilter")))
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package digest
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Unsubscribe removes the subscription with the given token in the given
+// database. It returns the name of the digest, or an empty string if there
+// is no subscription with this token.
+func Unsubscribe(dbName, token string) (string, error) {
+	var name string
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		subscriptions := env.Pool("DigestSubscription")
+		mi := subscriptions.Model()
+		sub := subscriptions.Search(mi.Field(mi.FieldName("Token")).Equals(token)).Limit(1)
+		if sub.IsEmpty() {
+			return
+		}
+		digest := sub.Get(mi.FieldName("Digest")).(models.RecordSet).Collection()
+		name = digest.Get(digest.Model().FieldName("Name")).(string)
+		sub.Call("Unlink")
+	})
+	return name, err
+}
+
+// unsubscribe is the controller of the unsubscribe links of digest emails
+func unsubscribe(ctx *server.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	name, err := Unsubscribe(ctx.DBName(), token)
+	if err != nil {
+		log.Warn("Unable to unsubscribe from digest", "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if name == "" {
+		ctx.String(http.StatusNotFound, "This link is not valid anymore.")
+		return
+	}
+	ctx.String(http.StatusOK, "You will not receive the %s digest anymore.", name)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/digest")
+	grp.AddController(http.MethodGet, "/unsubscribe", unsubscribe)
+}
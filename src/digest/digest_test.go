@@ -0,0 +1,49 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDigests(t *testing.T) {
+	RegisterKPI(KPI{
+		Name:   "TestRevenue",
+		Label:  "Revenue",
+		Digits: 2,
+		Compute: func(_ models.Environment, _, _ time.Time) float64 {
+			return 1234.5
+		},
+	})
+	Convey("Testing digests", t, func() {
+		Convey("KPIs should be registered once", func() {
+			kpi, ok := GetKPI("TestRevenue")
+			So(ok, ShouldBeTrue)
+			So(kpi.Label, ShouldEqual, "Revenue")
+			So(KPINames(), ShouldContain, "TestRevenue")
+			So(func() { RegisterKPI(kpi) }, ShouldPanic)
+			So(splitKPINames(" TestRevenue, ,Other"), ShouldResemble, []string{"TestRevenue", "Other"})
+		})
+		Convey("Periods should follow the periodicity", func() {
+			stop := time.Date(2019, 3, 31, 8, 0, 0, 0, time.UTC)
+			So(periodStart(PeriodDaily, stop), ShouldEqual, time.Date(2019, 3, 30, 8, 0, 0, 0, time.UTC))
+			So(periodStart(PeriodWeekly, stop), ShouldEqual, time.Date(2019, 3, 24, 8, 0, 0, 0, time.UTC))
+			So(periodStart(PeriodMonthly, stop), ShouldEqual, time.Date(2019, 3, 3, 8, 0, 0, 0, time.UTC))
+			So(nextRun(PeriodMonthly, stop), ShouldEqual, time.Date(2019, 5, 1, 8, 0, 0, 0, time.UTC))
+		})
+		Convey("Digest emails should list the KPI values and the unsubscribe link", func() {
+			kpi, _ := GetKPI("TestRevenue")
+			body := digestBody("Weekly digest", time.Date(2019, 3, 24, 8, 0, 0, 0, time.UTC),
+				time.Date(2019, 3, 31, 8, 0, 0, 0, time.UTC), []kpiValue{{KPI: kpi, Value: 1234.5}}, "http://example.com/unsub")
+			So(strings.HasPrefix(body, "Weekly digest\nFrom 2019-03-24 08:00 to 2019-03-31 08:00\n"), ShouldBeTrue)
+			So(body, ShouldContainSubstring, "Revenue: 1234.50\n")
+			So(body, ShouldContainSubstring, "http://example.com/unsub")
+		})
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package digest is a Hexya module that sends periodic summary emails
+// to users with the key performance indicators of their database.
+//
+// Modules register their KPIs with RegisterKPI. A KPI computes a number
+// for a period, in an Environment of the user who receives the digest:
+//
+//	digest.RegisterKPI(digest.KPI{
+//		Name:  "NewLeads",
+//		Label: "New leads",
+//		Compute: func(env models.Environment, start, stop time.Time) float64 {
+//			leads := h.Lead().Search(env,
+//				q.Lead().CreateDate().GreaterOrEqual(dates.DateTime{Time: start}).
+//					And().CreateDate().Lower(dates.DateTime{Time: stop}))
+//			return float64(leads.SearchCount())
+//		},
+//	})
+//
+// A Digest model record holds a selection of KPIs and a periodicity. Its
+// subscribed users receive the values of the KPIs of each period by email,
+// with a link to unsubscribe.
+package digest
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "digest"
+)
+
+// addUserField adds the subscriber of DigestSubscription records
+func addUserField() {
+	user := models.Registry.MustGet("User")
+	subscription := models.Registry.MustGet("DigestSubscription")
+	subscription.AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{RelationModel: user, Required: true, Index: true, OnDelete: models.Cascade},
+	})
+	subscription.AddSQLConstraint("subscription_uniq", "unique(digest_id, user_id)",
+		"A user can subscribe to a digest only once")
+}
+
+func init() {
+	log = logging.GetLogger("digest")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: addUserField,
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package digest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// Periodicities of digests
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+)
+
+// A KPI is a key performance indicator that can be included in digests
+type KPI struct {
+	// Name is the unique name of the KPI, which is used to select it in digests
+	Name string
+	// Label is the text displayed before the value of the KPI in digests
+	Label string
+	// Digits is the number of digits displayed after the decimal point
+	Digits int
+	// Compute returns the value of the KPI for the period from start
+	// (included) to stop (excluded). It is called in an Environment of
+	// the user who receives the digest, so that access rights apply.
+	Compute func(env models.Environment, start, stop time.Time) float64
+}
+
+// kpis are the registered KPIs by name
+var kpis struct {
+	sync.RWMutex
+	byName map[string]KPI
+}
+
+// RegisterKPI registers the given KPI so that it can be selected in digests.
+// It panics if a KPI with the same name is already registered.
+func RegisterKPI(kpi KPI) {
+	kpis.Lock()
+	defer kpis.Unlock()
+	if kpi.Name == "" || kpi.Compute == nil {
+		log.Panic("KPIs must have a name and a compute function", "kpi", kpi.Name)
+	}
+	if _, exists := kpis.byName[kpi.Name]; exists {
+		log.Panic("KPI already registered", "kpi", kpi.Name)
+	}
+	if kpis.byName == nil {
+		kpis.byName = make(map[string]KPI)
+	}
+	if kpi.Label == "" {
+		kpi.Label = kpi.Name
+	}
+	kpis.byName[kpi.Name] = kpi
+}
+
+// GetKPI returns the registered KPI with the given name
+func GetKPI(name string) (KPI, bool) {
+	kpis.RLock()
+	defer kpis.RUnlock()
+	kpi, ok := kpis.byName[name]
+	return kpi, ok
+}
+
+// KPINames returns the sorted names of the registered KPIs
+func KPINames() []string {
+	kpis.RLock()
+	defer kpis.RUnlock()
+	res := make([]string, 0, len(kpis.byName))
+	for name := range kpis.byName {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// splitKPINames returns the KPI names of the given comma separated list
+func splitKPINames(list string) []string {
+	var res []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// periodStart returns the start of the period of the given periodicity that ends at stop
+func periodStart(periodicity string, stop time.Time) time.Time {
+	switch periodicity {
+	case PeriodDaily:
+		return stop.AddDate(0, 0, -1)
+	case PeriodMonthly:
+		return stop.AddDate(0, -1, 0)
+	default:
+		return stop.AddDate(0, 0, -7)
+	}
+}
+
+// nextRun returns the time at which a digest of the given periodicity
+// sent at t must be sent again
+func nextRun(periodicity string, t time.Time) time.Time {
+	switch periodicity {
+	case PeriodDaily:
+		return t.AddDate(0, 0, 1)
+	case PeriodMonthly:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 7)
+	}
+}
+
+// A kpiValue is the value of a KPI for a period
+type kpiValue struct {
+	KPI   KPI
+	Value float64
+}
+
+// digestBody returns the body of the email of the given digest
+// with the given KPI values and unsubscribe link.
+func digestBody(name string, start, stop time.Time, values []kpiValue, unsubscribeURL string) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%s\n", name)
+	fmt.Fprintf(&buf, "From %s to %s\n\n", start.Format("2006-01-02 15:04"), stop.Format("2006-01-02 15:04"))
+	for _, val := range values {
+		fmt.Fprintf(&buf, "%s: %s\n", val.KPI.Label, strconv.FormatFloat(val.Value, 'f', val.KPI.Digits, 64))
+	}
+	fmt.Fprintf(&buf, "\nTo stop receiving this digest, follow this link:\n%s\n", unsubscribeURL)
+	return buf.String()
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package digest
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+func declareModels() {
+	digest := models.NewModel("Digest")
+	digest.SetDefaultOrder("Name")
+	digest.NewMethod("CheckKPIs", digest_CheckKPIs)
+	digest.NewMethod("SendDigest", digest_SendDigest)
+	digest.NewMethod("Subscribe", digest_Subscribe)
+	digest.NewMethod("Unsubscribe", digest_Unsubscribe)
+	digest.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true, Translate: true},
+		"Periodicity": fields.Selection{Required: true,
+			Selection: types.Selection{PeriodDaily: "Daily", PeriodWeekly: "Weekly", PeriodMonthly: "Monthly"},
+			Default:   models.DefaultValue(PeriodWeekly)},
+		"KPIs": fields.Char{String: "KPIs", Required: true, Constraint: digest.Methods().MustGet("CheckKPIs"),
+			Help: "Comma separated names of the KPIs of the digest"},
+		"NextRun": fields.DateTime{Index: true, NoCopy: true,
+			Help: "Time at which the next digest will be sent",
+			Default: func(env models.Environment) interface{} {
+				return dates.Now()
+			}},
+		"Active": fields.Boolean{Default: models.DefaultValue(true)},
+	})
+
+	subscription := models.NewModel("DigestSubscription")
+	subscription.AddFields(map[string]models.FieldDefinition{
+		"Digest": fields.Many2One{RelationModel: digest, Required: true, Index: true, OnDelete: models.Cascade},
+		"Token": fields.Char{Required: true, Unique: true, NoCopy: true,
+			Help: "Secret token of the unsubscribe link of the user",
+			Default: func(env models.Environment) interface{} {
+				return newToken()
+			}},
+	})
+	digest.AddFields(map[string]models.FieldDefinition{
+		"Subscriptions": fields.One2Many{RelationModel: subscription, ReverseFK: "Digest"},
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package digest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/mail"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/settings"
+)
+
+// sendPeriod is the period at which due digests are sent
+const sendPeriod = 10 * time.Minute
+
+// defaultBaseURL is the base URL of unsubscribe links
+// if the web.base.url parameter is not set
+const defaultBaseURL = "http://localhost:8080"
+
+// newToken returns a new random unsubscribe token
+func newToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate digest token", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// digest_CheckKPIs checks that digests only have registered KPIs
+func digest_CheckKPIs(rs *models.RecordCollection) {
+	for _, rec := range rs.Records() {
+		for _, name := range splitKPINames(rec.Get(rs.Model().FieldName("KPIs")).(string)) {
+			if _, ok := GetKPI(name); !ok {
+				log.Panic("Unknown KPI in digest", "digest", rec.Ids()[0], "kpi", name)
+			}
+		}
+	}
+}
+
+// digest_Subscribe subscribes the users with the given IDs to these digests.
+// Users already subscribed are ignored.
+func digest_Subscribe(rs *models.RecordCollection, userIDs []int64) {
+	subscriptions := rs.Env().Pool("DigestSubscription").Sudo()
+	mi := subscriptions.Model()
+	for _, rec := range rs.Records() {
+		existing := make(map[int64]bool)
+		for _, sub := range subscriptions.Search(mi.Field(mi.FieldName("Digest")).Equals(rec)).Records() {
+			existing[sub.Get(mi.FieldName("User")).(models.RecordSet).Ids()[0]] = true
+		}
+		for _, uid := range userIDs {
+			if existing[uid] {
+				continue
+			}
+			subscriptions.Call("Create", models.NewModelData(mi).
+				Set(mi.FieldName("Digest"), rec).
+				Set(mi.FieldName("User"), rs.Env().Pool("User").Call("BrowseOne", uid)))
+			existing[uid] = true
+		}
+	}
+}
+
+// digest_Unsubscribe unsubscribes the users with the given IDs from these digests
+func digest_Unsubscribe(rs *models.RecordCollection, userIDs []int64) {
+	subscriptions := rs.Env().Pool("DigestSubscription").Sudo()
+	mi := subscriptions.Model()
+	subscriptions.Search(mi.Field(mi.FieldName("Digest")).In(rs.Ids()).
+		And().Field(mi.FieldName("User")).In(userIDs)).Call("Unlink")
+}
+
+// digest_SendDigest sends these digests for the period ending now to their
+// subscribed users, and schedules their next run one period later.
+func digest_SendDigest(rs *models.RecordCollection) {
+	now := time.Now().UTC()
+	mi := rs.Model()
+	for _, rec := range rs.Records() {
+		sendDigest(rec, now)
+		rec.Set(mi.FieldName("NextRun"), dates.DateTime{Time: nextRun(rec.Get(mi.FieldName("Periodicity")).(string), now)})
+	}
+}
+
+// sendDigest enqueues the email of the given digest for the period ending at
+// stop to each subscribed user with an email address. KPIs are computed with
+// the access rights of each user.
+func sendDigest(digest *models.RecordCollection, stop time.Time) {
+	mi := digest.Model()
+	name := digest.Get(mi.FieldName("Name")).(string)
+	start := periodStart(digest.Get(mi.FieldName("Periodicity")).(string), stop)
+	var selected []KPI
+	for _, kpiName := range splitKPINames(digest.Get(mi.FieldName("KPIs")).(string)) {
+		if kpi, ok := GetKPI(kpiName); ok {
+			selected = append(selected, kpi)
+		}
+	}
+	baseURL := settings.GetParam(digest.Env(), "web.base.url", defaultBaseURL)
+	subscriptions := digest.Get(mi.FieldName("Subscriptions")).(models.RecordSet).Collection().Sudo()
+	subMI := subscriptions.Model()
+	for _, sub := range subscriptions.Records() {
+		user := sub.Get(subMI.FieldName("User")).(models.RecordSet).Collection()
+		userMI := user.Model()
+		var email string
+		if _, ok := userMI.Fields().Get("Email"); ok {
+			email, _ = user.Get(userMI.FieldName("Email")).(string)
+		}
+		if email == "" {
+			continue
+		}
+		env := digest.Sudo(user.Ids()[0]).Env()
+		values := make([]kpiValue, len(selected))
+		for i, kpi := range selected {
+			values[i] = kpiValue{KPI: kpi, Value: kpi.Compute(env, start, stop)}
+		}
+		link := fmt.Sprintf("%s/digest/unsubscribe?db=%s&token=%s", baseURL,
+			url.QueryEscape(digest.Env().DBName()), url.QueryEscape(sub.Get(subMI.FieldName("Token")).(string)))
+		mail.Enqueue(mail.Message{
+			To:      []string{email},
+			Subject: name,
+			Body:    digestBody(name, start, stop, values, link),
+		})
+	}
+}
+
+// SendDueDigests sends the active digests of the given database
+// whose next run time is reached.
+func SendDueDigests(dbName string) error {
+	return models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		digests := env.Pool("Digest")
+		mi := digests.Model()
+		digests.Search(mi.Field(mi.FieldName("Active")).Equals(true).
+			And().Field(mi.FieldName("NextRun")).LowerOrEqual(dates.Now())).Call("SendDigest")
+	})
+}
+
+// sendAllDigests sends the due digests of all connected databases
+func sendAllDigests() {
+	for _, dbName := range models.ConnectedDBNames() {
+		if err := SendDueDigests(dbName); err != nil {
+			log.Warn("Unable to send digests", "database", dbName, "error", err)
+		}
+	}
+}
+
+func init() {
+	models.RegisterWorker(models.NewWorkerFunction(sendAllDigests, sendPeriod))
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package eventstream
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/spf13/viper"
+)
+
+// fakeNATS is a minimal NATS server that records the messages it receives
+type fakeNATS struct {
+	listener net.Listener
+	info     string
+	lines    chan string
+}
+
+// newFakeNATS starts a fake NATS server that greets clients with the given INFO
+func newFakeNATS(info string) *fakeNATS {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	So(err, ShouldBeNil)
+	fn := &fakeNATS{listener: l, info: info, lines: make(chan string, 100)}
+	go fn.serve()
+	return fn
+}
+
+func (fn *fakeNATS) serve() {
+	conn, err := fn.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO %s\r\n", fn.info)
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			close(fn.lines)
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+			continue
+		case strings.HasPrefix(line, "PUB bad."):
+			fmt.Fprint(conn, "-ERR 'Permissions Violation'\r\n")
+		}
+		fn.lines <- line
+	}
+}
+
+func (fn *fakeNATS) url(user string) string {
+	return fmt.Sprintf("nats://%s%s", user, fn.listener.Addr())
+}
+
+func TestNATS(t *testing.T) {
+	Convey("Testing the NATS broker", t, func() {
+		Convey("Messages should be published after the CONNECT message", func() {
+			server := newFakeNATS(`{"server_id":"test"}`)
+			defer server.listener.Close()
+			broker, err := Connect("nats", server.url("john:secret@"))
+			So(err, ShouldBeNil)
+			defer broker.Close()
+			connect := <-server.lines
+			So(connect, ShouldStartWith, "CONNECT {")
+			So(connect, ShouldContainSubstring, `"user":"john"`)
+			So(connect, ShouldContainSubstring, `"pass":"secret"`)
+			So(broker.Publish("hexya.Partner.write", []byte(`{"id":"1"}`)), ShouldBeNil)
+			So(broker.Flush(), ShouldBeNil)
+			So(<-server.lines, ShouldEqual, "PUB hexya.Partner.write 10")
+			So(<-server.lines, ShouldEqual, `{"id":"1"}`)
+			So(broker.Publish("invalid subject", nil), ShouldNotBeNil)
+		})
+		Convey("Errors of the server should be returned on flush", func() {
+			server := newFakeNATS(`{}`)
+			defer server.listener.Close()
+			broker, err := Connect("nats", server.url("token@"))
+			So(err, ShouldBeNil)
+			defer broker.Close()
+			So(<-server.lines, ShouldContainSubstring, `"auth_token":"token"`)
+			So(broker.Publish("bad.subject", []byte("x")), ShouldBeNil)
+			So(broker.Flush(), ShouldNotBeNil)
+		})
+		Convey("Servers requiring TLS should be rejected", func() {
+			server := newFakeNATS(`{"tls_required":true}`)
+			defer server.listener.Close()
+			_, err := Connect("nats", server.url(""))
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Unknown brokers should be rejected", func() {
+			_, err := Connect("carrier-pigeon", "")
+			So(err, ShouldNotBeNil)
+			So(func() { RegisterBroker("nats", DialNATS) }, ShouldPanic)
+		})
+	})
+}
+
+func TestEvents(t *testing.T) {
+	Convey("Testing record change events", t, func() {
+		So(Event{Model: "Partner", Event: EventWrite}.Subject("hexya"), ShouldEqual, "hexya.Partner.write")
+		So(Event{Model: "Partner", Event: EventUnlink}.Subject(""), ShouldEqual, "Partner.unlink")
+		So(newEventID(), ShouldHaveLength, 32)
+		Convey("Events should only be stored if a broker is configured", func() {
+			defer viper.Set("EventStream.Broker", "")
+			defer viper.Set("EventStream.Models", nil)
+			So(enabled("Partner"), ShouldBeFalse)
+			viper.Set("EventStream.Broker", "nats")
+			So(enabled("Partner"), ShouldBeTrue)
+			viper.Set("EventStream.Models", []string{"User"})
+			So(enabled("Partner"), ShouldBeFalse)
+			So(enabled("User"), ShouldBeTrue)
+		})
+	})
+}
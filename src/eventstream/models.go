@@ -0,0 +1,18 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package eventstream
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	outbox := models.NewModel("EventOutbox")
+	outbox.SetDefaultOrder("ID")
+	outbox.AddFields(map[string]models.FieldDefinition{
+		"Subject": fields.Char{Required: true},
+		"Payload": fields.Text{Required: true},
+	})
+}
@@ -0,0 +1,170 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package eventstream
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/spf13/viper"
+)
+
+// Types of record change events
+const (
+	EventCreate = "create"
+	EventWrite  = "write"
+	EventUnlink = "unlink"
+)
+
+// outboxModel is the name of the model in which events are stored until they are published
+const outboxModel = "EventOutbox"
+
+// An Event is the change of a record, as published to the broker
+type Event struct {
+	// ID is a unique identifier of the event, with which consumers can discard duplicates
+	ID       string `json:"id"`
+	Database string `json:"database"`
+	Event    string `json:"event"`
+	Model    string `json:"model"`
+	ResID    int64  `json:"res_id"`
+	// Fields are the JSON names of the fields given on creation or update
+	Fields    []string  `json:"fields,omitempty"`
+	UID       int64     `json:"uid"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Subject returns the subject on which the event is published with the given prefix
+func (e Event) Subject(prefix string) string {
+	if prefix == "" {
+		return e.Model + "." + e.Event
+	}
+	return prefix + "." + e.Model + "." + e.Event
+}
+
+// enabled returns true if events of the given model must be stored in the outbox
+func enabled(model string) bool {
+	if viper.GetString("EventStream.Broker") == "" {
+		return false
+	}
+	publishedModels := viper.GetStringSlice("EventStream.Models")
+	if len(publishedModels) == 0 {
+		return true
+	}
+	for _, m := range publishedModels {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectPrefix returns the configured prefix of the subjects of events
+func subjectPrefix() string {
+	if viper.IsSet("EventStream.Prefix") {
+		return viper.GetString("EventStream.Prefix")
+	}
+	return "hexya"
+}
+
+// notifiable returns true if the changes of the records of rc are stored in the outbox
+func notifiable(rc *models.RecordCollection) bool {
+	if rc.ModelName() == outboxModel || rc.Model().IsTransient() || rc.Model().IsMixin() {
+		return false
+	}
+	return enabled(rc.ModelName())
+}
+
+// newEventID returns a new random event ID
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate event ID", "error", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// fieldNames returns the sorted JSON names of the fields of the given values
+func fieldNames(rc *models.RecordCollection, values models.FieldMap) []string {
+	res := make([]string, 0, len(values))
+	for key := range values {
+		if fi, ok := rc.Model().Fields().Get(key); ok {
+			key = fi.JSON()
+		}
+		res = append(res, key)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// store adds an event of the given type for each of the given
+// ids to the outbox, in the transaction of the environment of rc.
+func store(rc *models.RecordCollection, event string, ids []int64, fields []string) {
+	outbox := rc.Env().Pool(outboxModel).Sudo()
+	mi := outbox.Model()
+	prefix := subjectPrefix()
+	for _, id := range ids {
+		evt := Event{
+			ID:        newEventID(),
+			Database:  rc.Env().DBName(),
+			Event:     event,
+			Model:     rc.ModelName(),
+			ResID:     id,
+			Fields:    fields,
+			UID:       rc.Env().Uid(),
+			Timestamp: time.Now().UTC(),
+		}
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			log.Panic("Unable to marshal event", "model", evt.Model, "id", id, "error", err)
+		}
+		outbox.Call("Create", models.NewModelData(mi).
+			Set(mi.FieldName("Subject"), evt.Subject(prefix)).
+			Set(mi.FieldName("Payload"), string(payload)))
+	}
+}
+
+// extendCommonMixin extends the CRUD methods of all models
+// to store their events in the outbox.
+func extendCommonMixin() {
+	commonMixin := models.Registry.MustGet("CommonMixin")
+	commonMixin.Methods().MustGet("Create").Extend(commonMixin_Create)
+	commonMixin.Methods().MustGet("Write").Extend(commonMixin_Write)
+	commonMixin.Methods().MustGet("Unlink").Extend(commonMixin_Unlink)
+}
+
+// commonMixin_Create stores the creation events of records
+func commonMixin_Create(rc *models.RecordCollection, data models.RecordData) *models.RecordCollection {
+	res := rc.Super().Call("Create", data).(models.RecordSet).Collection()
+	if notifiable(rc) {
+		store(res, EventCreate, res.Ids(), fieldNames(res, data.Underlying().FieldMap))
+	}
+	return res
+}
+
+// commonMixin_Write stores the update events of records
+func commonMixin_Write(rc *models.RecordCollection, data models.RecordData) bool {
+	res := rc.Super().Call("Write", data).(bool)
+	if notifiable(rc) && !rc.IsEmpty() {
+		fields := fieldNames(rc, data.Underlying().FieldMap)
+		if len(fields) > 0 {
+			store(rc, EventWrite, rc.Ids(), fields)
+		}
+	}
+	return res
+}
+
+// commonMixin_Unlink stores the deletion events of records
+func commonMixin_Unlink(rc *models.RecordCollection) int64 {
+	if !notifiable(rc) || rc.IsEmpty() {
+		return rc.Super().Call("Unlink").(int64)
+	}
+	ids := rc.Ids()
+	res := rc.Super().Call("Unlink").(int64)
+	store(rc, EventUnlink, ids, nil)
+	return res
+}
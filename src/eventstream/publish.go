@@ -0,0 +1,119 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package eventstream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/spf13/viper"
+)
+
+const (
+	// publishPeriod is the time between two runs of the publishing worker
+	publishPeriod = 5 * time.Second
+	// publishBatch is the maximum number of events published in a transaction
+	publishBatch = 500
+)
+
+// connection is the connection to the configured broker, which is
+// opened on the first publication and reopened after an error.
+var connection struct {
+	sync.Mutex
+	broker Broker
+}
+
+// getBroker returns the connection to the configured broker
+func getBroker() (Broker, error) {
+	if connection.broker != nil {
+		return connection.broker, nil
+	}
+	broker, err := Connect(viper.GetString("EventStream.Broker"), viper.GetString("EventStream.URL"))
+	if err != nil {
+		return nil, err
+	}
+	connection.broker = broker
+	return broker, nil
+}
+
+// resetBroker closes the connection to the broker after an error
+func resetBroker() {
+	if connection.broker == nil {
+		return
+	}
+	connection.broker.Close()
+	connection.broker = nil
+}
+
+// PublishOutbox publishes the events of the outbox of the given database in
+// their order to the configured broker. Events are removed from the outbox in
+// the same transaction once the broker has acknowledged them. It returns the
+// number of published events.
+func PublishOutbox(dbName string) (int, error) {
+	connection.Lock()
+	defer connection.Unlock()
+	var count int
+	for {
+		var published int
+		var pubErr error
+		err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+			outbox := env.Pool(outboxModel)
+			mi := outbox.Model()
+			events := outbox.SearchAll().OrderBy("ID").Limit(publishBatch)
+			if events.IsEmpty() {
+				return
+			}
+			var broker Broker
+			broker, pubErr = getBroker()
+			if pubErr != nil {
+				return
+			}
+			var ids []int64
+			for _, evt := range events.Records() {
+				pubErr = broker.Publish(evt.Get(mi.FieldName("Subject")).(string), []byte(evt.Get(mi.FieldName("Payload")).(string)))
+				if pubErr != nil {
+					break
+				}
+				ids = append(ids, evt.Ids()[0])
+			}
+			if err := broker.Flush(); err != nil {
+				pubErr = err
+				return
+			}
+			if len(ids) > 0 {
+				outbox.Call("Browse", ids).(models.RecordSet).Collection().Call("Unlink")
+			}
+			published = len(ids)
+		})
+		count += published
+		if err == nil {
+			err = pubErr
+		}
+		if err != nil {
+			resetBroker()
+			return count, err
+		}
+		if published < publishBatch {
+			return count, nil
+		}
+	}
+}
+
+// publishAll publishes the outboxes of all connected databases
+func publishAll() {
+	if viper.GetString("EventStream.Broker") == "" {
+		return
+	}
+	for _, dbName := range models.ConnectedDBNames() {
+		if _, err := PublishOutbox(dbName); err != nil {
+			log.Warn("Unable to publish events", "database", dbName, "error", err)
+		}
+	}
+}
+
+func init() {
+	models.RegisterWorker(models.NewWorkerFunction(publishAll, publishPeriod))
+}
@@ -0,0 +1,215 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fetchmail
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of incoming mail servers
+const (
+	ServerIMAP = "imap"
+	ServerPOP  = "pop"
+)
+
+// dialTimeout is the timeout for connecting to incoming mail servers
+const dialTimeout = 30 * time.Second
+
+// A Server holds the connection parameters of an incoming mail server
+type Server struct {
+	Type     string
+	Host     string
+	Port     int
+	SSL      bool
+	Login    string
+	Password string
+}
+
+// A Handler processes a raw incoming message. Messages for which the handler
+// returns nil are deleted (POP3) or flagged as seen (IMAP) on the server, so
+// that they are not fetched again.
+type Handler func(raw []byte) error
+
+// Fetch connects to the server and calls handler for each new message
+func (s Server) Fetch(handler Handler) error {
+	address := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.SSL {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", address, &tls.Config{ServerName: s.Host})
+	} else {
+		conn, err = net.DialTimeout("tcp", address, dialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	switch s.Type {
+	case ServerPOP:
+		return fetchPOP3(conn, s.Login, s.Password, handler)
+	case ServerIMAP:
+		return fetchIMAP(conn, s.Login, s.Password, handler)
+	default:
+		return fmt.Errorf("unknown incoming mail server type %s", s.Type)
+	}
+}
+
+// pop3Response reads a POP3 response line and returns its text
+// after the status indicator or an error if the status is not +OK.
+func pop3Response(conn *textproto.Conn) (string, error) {
+	line, err := conn.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return "", fmt.Errorf("POP3 error: %s", line)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "+OK")), nil
+}
+
+// pop3Cmd sends the given POP3 command and returns the text of the response
+func pop3Cmd(conn *textproto.Conn, format string, args ...interface{}) (string, error) {
+	if err := conn.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	return pop3Response(conn)
+}
+
+// fetchPOP3 fetches the messages of a POP3 mailbox through conn
+func fetchPOP3(rwc io.ReadWriteCloser, login, password string, handler Handler) error {
+	conn := textproto.NewConn(rwc)
+	if _, err := pop3Response(conn); err != nil {
+		return err
+	}
+	if _, err := pop3Cmd(conn, "USER %s", login); err != nil {
+		return err
+	}
+	if _, err := pop3Cmd(conn, "PASS %s", password); err != nil {
+		return err
+	}
+	stat, err := pop3Cmd(conn, "STAT")
+	if err != nil {
+		return err
+	}
+	var count int
+	if _, err = fmt.Sscanf(stat, "%d", &count); err != nil {
+		return fmt.Errorf("invalid POP3 STAT response: %s", stat)
+	}
+	for i := 1; i <= count; i++ {
+		if _, err = pop3Cmd(conn, "RETR %d", i); err != nil {
+			return err
+		}
+		raw, err := ioutil.ReadAll(conn.DotReader())
+		if err != nil {
+			return err
+		}
+		if handler(raw) != nil {
+			continue
+		}
+		if _, err = pop3Cmd(conn, "DELE %d", i); err != nil {
+			return err
+		}
+	}
+	_, err = pop3Cmd(conn, "QUIT")
+	return err
+}
+
+// An imapConn is a connection to an IMAP server
+type imapConn struct {
+	*textproto.Conn
+	tag int
+}
+
+// imapQuote returns s as an IMAP quoted string
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// cmd sends the given IMAP command and returns the untagged response
+// lines and the literals they contain.
+func (c *imapConn) cmd(format string, args ...interface{}) ([]string, [][]byte, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	if err := c.PrintfLine("%s %s", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, nil, err
+	}
+	var (
+		lines    []string
+		literals [][]byte
+	)
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return nil, nil, err
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			if !strings.HasPrefix(line, tag+" OK") {
+				return nil, nil, fmt.Errorf("IMAP error: %s", strings.TrimPrefix(line, tag+" "))
+			}
+			return lines, literals, nil
+		}
+		lines = append(lines, line)
+		if i := strings.LastIndex(line, "{"); i >= 0 && strings.HasSuffix(line, "}") {
+			size, err := strconv.Atoi(line[i+1 : len(line)-1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid IMAP literal: %s", line)
+			}
+			literal := make([]byte, size)
+			if _, err = io.ReadFull(c.R, literal); err != nil {
+				return nil, nil, err
+			}
+			literals = append(literals, literal)
+		}
+	}
+}
+
+// fetchIMAP fetches the unseen messages of the INBOX of an IMAP mailbox through conn
+func fetchIMAP(rwc io.ReadWriteCloser, login, password string, handler Handler) error {
+	conn := &imapConn{Conn: textproto.NewConn(rwc)}
+	if greeting, err := conn.ReadLine(); err != nil {
+		return err
+	} else if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("IMAP error: %s", greeting)
+	}
+	if _, _, err := conn.cmd("LOGIN %s %s", imapQuote(login), imapQuote(password)); err != nil {
+		return err
+	}
+	if _, _, err := conn.cmd("SELECT INBOX"); err != nil {
+		return err
+	}
+	lines, _, err := conn.cmd("SEARCH UNSEEN")
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "* SEARCH") {
+			ids = append(ids, strings.Fields(strings.TrimPrefix(line, "* SEARCH"))...)
+		}
+	}
+	for _, id := range ids {
+		_, literals, err := conn.cmd("FETCH %s BODY.PEEK[]", id)
+		if err != nil {
+			return err
+		}
+		if len(literals) == 0 || handler(literals[0]) != nil {
+			continue
+		}
+		if _, _, err = conn.cmd(`STORE %s +FLAGS (\Seen)`, id); err != nil {
+			return err
+		}
+	}
+	_, _, err = conn.cmd("LOGOUT")
+	return err
+}
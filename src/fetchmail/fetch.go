@@ -0,0 +1,107 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fetchmail
+
+import (
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// fetchPeriod is the time between two fetches of the incoming mail servers
+const fetchPeriod = 5 * time.Minute
+
+// FetchServer fetches the new emails of the IncomingMailServer with the given ID
+// in the given database and processes them.
+//
+// Each email is processed in its own transaction, so that an email that cannot be
+// processed is left on the server without preventing the others to be processed.
+func FetchServer(dbName string, serverID int64) error {
+	var (
+		srv          Server
+		defaultModel string
+	)
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		rec := env.Pool("IncomingMailServer").Call("BrowseOne", serverID).(models.RecordSet).Collection()
+		mi := rec.Model()
+		srv = Server{
+			Type:     rec.Get(mi.FieldName("ServerType")).(string),
+			Host:     rec.Get(mi.FieldName("Host")).(string),
+			Port:     int(rec.Get(mi.FieldName("Port")).(int64)),
+			SSL:      rec.Get(mi.FieldName("SSL")).(bool),
+			Login:    rec.Get(mi.FieldName("Login")).(string),
+			Password: rec.Get(mi.FieldName("Password")).(string),
+		}
+		defaultModel = rec.Get(mi.FieldName("Model")).(string)
+	})
+	if err != nil {
+		return err
+	}
+	fetchErr := srv.Fetch(func(raw []byte) error {
+		msg, err := Parse(raw)
+		if err != nil {
+			log.Warn("Unable to parse incoming email", "server", srv.Host, "error", err)
+			return err
+		}
+		err = models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+			if err := Process(env, msg, defaultModel); err != nil {
+				log.Panic("Unable to process incoming email", "messageID", msg.MessageID, "error", err)
+			}
+		})
+		if err != nil {
+			log.Warn("Unable to process incoming email", "server", srv.Host, "messageID", msg.MessageID, "error", err)
+		}
+		return err
+	})
+	var lastError string
+	if fetchErr != nil {
+		lastError = fetchErr.Error()
+	}
+	err = models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		rec := env.Pool("IncomingMailServer").Call("BrowseOne", serverID).(models.RecordSet).Collection()
+		mi := rec.Model()
+		rec.Call("Write", models.NewModelData(mi).
+			Set(mi.FieldName("LastFetch"), dates.Now()).
+			Set(mi.FieldName("LastError"), lastError))
+	})
+	if fetchErr != nil {
+		return fetchErr
+	}
+	return err
+}
+
+// FetchMail fetches the new emails of these servers and processes them
+func incomingMailServer_FetchMail(rc *models.RecordCollection) {
+	for _, id := range rc.Ids() {
+		if err := FetchServer(rc.Env().DBName(), id); err != nil {
+			log.Warn("Unable to fetch incoming mail server", "database", rc.Env().DBName(), "server", id, "error", err)
+		}
+	}
+}
+
+// fetchAllServers fetches all active incoming mail servers of all connected databases
+func fetchAllServers() {
+	for _, dbName := range models.ConnectedDBNames() {
+		var ids []int64
+		err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+			servers := env.Pool("IncomingMailServer")
+			ids = servers.Search(servers.Model().Field(servers.Model().FieldName("Active")).Equals(true)).Ids()
+		})
+		if err != nil {
+			log.Warn("Unable to list incoming mail servers", "database", dbName, "error", err)
+			continue
+		}
+		for _, id := range ids {
+			if err = FetchServer(dbName, id); err != nil {
+				log.Warn("Unable to fetch incoming mail server", "database", dbName, "server", id, "error", err)
+			}
+		}
+	}
+}
+
+func init() {
+	models.RegisterWorker(models.NewWorkerFunction(fetchAllServers, fetchPeriod))
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fetchmail
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testEmail = "From: =?iso-8859-1?q?Ren=E9?= <rene@example.com>\r\n" +
+	"To: sales@example.com, info@example.com\r\n" +
+	"Subject: =?utf-8?q?Devis_=C3=A9t=C3=A9?=\r\n" +
+	"Date: Fri, 15 Mar 2019 10:30:00 +0100\r\n" +
+	"Message-ID: <reply@example.com>\r\n" +
+	"In-Reply-To: <2.Lead-3@example.com>\r\n" +
+	"References: <1.Lead-3@example.com> <2.Lead-3@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Bonjour, voici le devis =C3=A9t=C3=A9.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Bonjour</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"devis.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"devis.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--outer--\r\n"
+
+// serve runs the given scripted server on the server side of a pipe. The script
+// maps each expected command to its response, and the server returns an error
+// in the given channel if it receives an unexpected command.
+func serve(greeting string, script func(cmd string) (string, bool)) (net.Conn, chan error) {
+	client, srv := net.Pipe()
+	errs := make(chan error, 1)
+	go func() {
+		defer srv.Close()
+		r := bufio.NewReader(srv)
+		fmt.Fprint(srv, greeting)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				errs <- nil
+				return
+			}
+			resp, ok := script(strings.TrimRight(line, "\r\n"))
+			if !ok {
+				errs <- fmt.Errorf("unexpected command %q", line)
+				return
+			}
+			fmt.Fprint(srv, resp)
+		}
+	}()
+	return client, errs
+}
+
+func TestFetchmail(t *testing.T) {
+	Convey("Testing incoming mails", t, func() {
+		Convey("Emails should be parsed", func() {
+			msg, err := Parse([]byte(testEmail))
+			So(err, ShouldBeNil)
+			So(msg.From, ShouldEqual, "rene@example.com")
+			So(msg.To, ShouldResemble, []string{"sales@example.com", "info@example.com"})
+			So(msg.Subject, ShouldEqual, "Devis été")
+			So(msg.MessageID, ShouldEqual, "<reply@example.com>")
+			So(msg.Body, ShouldEqual, "Bonjour, voici le devis été.")
+			So(msg.HTML, ShouldEqual, "<p>Bonjour</p>")
+			So(msg.Attachments, ShouldHaveLength, 1)
+			So(msg.Attachments[0].Name, ShouldEqual, "devis.pdf")
+			So(string(msg.Attachments[0].Data), ShouldEqual, "%PDF-1.4\n")
+			So(msg.ThreadReferences(), ShouldResemble, []string{"<2.Lead-3@example.com>", "<1.Lead-3@example.com>"})
+		})
+		Convey("HTML only emails should have a text body", func() {
+			msg, err := Parse([]byte("Subject: Hi\r\nContent-Type: text/html\r\n\r\n<p>Hello&nbsp;<b>John</b></p><style>p {}</style>"))
+			So(err, ShouldBeNil)
+			So(msg.Body, ShouldEqual, "Hello John")
+		})
+		Convey("Emails should be fetched and deleted from POP3 servers", func() {
+			var deleted []string
+			conn, errs := serve("+OK POP3 ready\r\n", func(cmd string) (string, bool) {
+				switch {
+				case cmd == "USER john", cmd == "PASS secret", cmd == "QUIT":
+					return "+OK\r\n", true
+				case cmd == "STAT":
+					return "+OK 2 320\r\n", true
+				case cmd == "RETR 1", cmd == "RETR 2":
+					return fmt.Sprintf("+OK\r\nSubject: %s\r\n\r\n..dotted\r\n.\r\n", cmd), true
+				case strings.HasPrefix(cmd, "DELE "):
+					deleted = append(deleted, cmd)
+					return "+OK\r\n", true
+				}
+				return "", false
+			})
+			var subjects []string
+			err := fetchPOP3(conn, "john", "secret", func(raw []byte) error {
+				msg, err := Parse(raw)
+				So(err, ShouldBeNil)
+				So(msg.Body, ShouldEqual, ".dotted\n")
+				subjects = append(subjects, msg.Subject)
+				if msg.Subject == "RETR 2" {
+					return fmt.Errorf("unable to process")
+				}
+				return nil
+			})
+			conn.Close()
+			So(err, ShouldBeNil)
+			So(<-errs, ShouldBeNil)
+			So(subjects, ShouldResemble, []string{"RETR 1", "RETR 2"})
+			So(deleted, ShouldResemble, []string{"DELE 1"})
+		})
+		Convey("Unseen emails should be fetched and flagged from IMAP servers", func() {
+			var stored []string
+			raw := "Subject: Hello\r\n\r\nHi\r\n"
+			conn, errs := serve("* OK IMAP ready\r\n", func(cmd string) (string, bool) {
+				parts := strings.SplitN(cmd, " ", 2)
+				tag, command := parts[0], parts[1]
+				switch {
+				case command == `LOGIN "john" "se\"cret"`, command == "SELECT INBOX":
+					return tag + " OK\r\n", true
+				case command == "SEARCH UNSEEN":
+					return "* SEARCH 4\r\n" + tag + " OK\r\n", true
+				case command == "FETCH 4 BODY.PEEK[]":
+					return fmt.Sprintf("* 4 FETCH (BODY[] {%d}\r\n%s)\r\n%s OK\r\n", len(raw), raw, tag), true
+				case command == `STORE 4 +FLAGS (\Seen)`:
+					stored = append(stored, command)
+					return tag + " OK\r\n", true
+				case command == "LOGOUT":
+					return "* BYE\r\n" + tag + " OK\r\n", true
+				}
+				return "", false
+			})
+			var fetched []string
+			err := fetchIMAP(conn, "john", `se"cret`, func(data []byte) error {
+				fetched = append(fetched, string(data))
+				return nil
+			})
+			conn.Close()
+			So(err, ShouldBeNil)
+			So(<-errs, ShouldBeNil)
+			So(fetched, ShouldResemble, []string{raw})
+			So(stored, ShouldHaveLength, 1)
+		})
+	})
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package fetchmail is a Hexya module that fetches incoming emails from
+// IMAP and POP3 servers and posts them on records.
+//
+// Incoming mail servers are configured with the IncomingMailServer model and
+// fetched periodically. Each fetched email is parsed and routed:
+//
+// - answers to messages of a record (found through the In-Reply-To and
+// References headers) are posted on this record,
+// - other emails are routed by the Routers registered with RegisterRouter,
+// - or else create a new record of the model of the server with the
+// MessageNew method of the MailThread mixin.
+package fetchmail
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "fetchmail"
+)
+
+func init() {
+	log = logging.GetLogger("fetchmail")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fetchmail
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+func declareModels() {
+	incomingMailServer := models.NewModel("IncomingMailServer")
+	incomingMailServer.SetDefaultOrder("Name")
+	incomingMailServer.NewMethod("FetchMail", incomingMailServer_FetchMail)
+	incomingMailServer.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true},
+		"ServerType": fields.Selection{String: "Server Type", Required: true,
+			Selection: types.Selection{ServerIMAP: "IMAP Server", ServerPOP: "POP Server"},
+			Default:   models.DefaultValue(ServerIMAP)},
+		"Host": fields.Char{String: "Server Name", Required: true},
+		"Port": fields.Integer{Required: true, Default: models.DefaultValue(993)},
+		"SSL": fields.Boolean{String: "SSL/TLS", Default: models.DefaultValue(true),
+			Help: "Connect to the server with an SSL/TLS connection"},
+		"Login":    fields.Char{Required: true},
+		"Password": fields.Char{},
+		"Model": fields.Char{String: "Create a New Record",
+			Help: "Name of the model of the records created from incoming emails that do not answer " +
+				"an existing thread. The model must inherit MailThread."},
+		"Active":    fields.Boolean{Default: models.DefaultValue(true)},
+		"LastFetch": fields.DateTime{String: "Last Fetch Date", ReadOnly: true, NoCopy: true},
+		"LastError": fields.Text{ReadOnly: true, NoCopy: true},
+	})
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fetchmail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// An Attachment is a file attached to an incoming email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// A ParsedMessage is an incoming email
+type ParsedMessage struct {
+	MessageID  string
+	InReplyTo  string
+	References []string
+	From       string
+	To         []string
+	Subject    string
+	Date       time.Time
+	// Body is the text of the message. If the message has no
+	// text/plain part, it is the text of its HTML part.
+	Body        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// ThreadReferences returns the Message-IDs of the messages this message answers,
+// the most recent first.
+func (pm *ParsedMessage) ThreadReferences() []string {
+	var res []string
+	if pm.InReplyTo != "" {
+		res = append(res, pm.InReplyTo)
+	}
+	for i := len(pm.References) - 1; i >= 0; i-- {
+		if pm.References[i] != pm.InReplyTo {
+			res = append(res, pm.References[i])
+		}
+	}
+	return res
+}
+
+// wordDecoder decodes RFC 2047 encoded words of headers
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// messageIDRegex matches the Message-IDs of the References and In-Reply-To headers
+var messageIDRegex = regexp.MustCompile(`<[^<>\s]+>`)
+
+// htmlTagsRegex matches the tags of HTML bodies
+var htmlTagsRegex = regexp.MustCompile(`(?s)<(style|script)[^>]*>.*?</(style|script)>|<[^>]+>`)
+
+// Parse parses the given RFC 5322 message
+func Parse(raw []byte) (*ParsedMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	pm := &ParsedMessage{
+		MessageID:  strings.TrimSpace(msg.Header.Get("Message-Id")),
+		InReplyTo:  messageIDRegex.FindString(msg.Header.Get("In-Reply-To")),
+		References: messageIDRegex.FindAllString(msg.Header.Get("References"), -1),
+	}
+	if pm.Subject, err = wordDecoder.DecodeHeader(msg.Header.Get("Subject")); err != nil {
+		pm.Subject = msg.Header.Get("Subject")
+	}
+	if from, err := parseAddressList(msg.Header.Get("From")); err == nil && len(from) > 0 {
+		pm.From = from[0]
+	}
+	pm.To, _ = parseAddressList(msg.Header.Get("To"))
+	if date, err := msg.Header.Date(); err == nil {
+		pm.Date = date
+	}
+	if err = pm.parsePart(msg.Header, msg.Body); err != nil {
+		return nil, err
+	}
+	if pm.Body == "" && pm.HTML != "" {
+		pm.Body = htmlToText(pm.HTML)
+	}
+	return pm, nil
+}
+
+// parseAddressList returns the email addresses of the given address list header
+func parseAddressList(header string) ([]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	addresses, err := parser.ParseList(header)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, len(addresses))
+	for i, addr := range addresses {
+		res[i] = addr.Address
+	}
+	return res, nil
+}
+
+// A header gives the value of MIME headers
+type header interface {
+	Get(key string) string
+}
+
+// parsePart parses the MIME part with the given headers and body into pm
+func (pm *ParsedMessage) parsePart(h header, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{"charset": "us-ascii"}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err = pm.parsePart(part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+	data, err := ioutil.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dispParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	fileName := dispParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	if fileName, err = wordDecoder.DecodeHeader(fileName); err != nil {
+		return err
+	}
+	switch {
+	case disposition == "attachment" || fileName != "" || !strings.HasPrefix(mediaType, "text/"):
+		pm.Attachments = append(pm.Attachments, Attachment{Name: fileName, ContentType: mediaType, Data: data})
+	case mediaType == "text/html" && pm.HTML == "":
+		pm.HTML, err = decodeCharset(params["charset"], data)
+	case mediaType != "text/html" && pm.Body == "":
+		pm.Body, err = decodeCharset(params["charset"], data)
+	}
+	return err
+}
+
+// decodeTransfer returns a reader decoding body according to the given Content-Transfer-Encoding
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// A newlineStripper removes line breaks from the data of r
+type newlineStripper struct {
+	r io.Reader
+}
+
+// Read implements io.Reader
+func (ns *newlineStripper) Read(p []byte) (int, error) {
+	n, err := ns.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+// charsetReader returns a reader converting input from the given charset to UTF-8.
+// Only UTF-8, US-ASCII and ISO-8859-1 (and its superset Windows-1252 approximated
+// by ISO-8859-1) are supported.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "iso-8859-15", "windows-1252":
+		data, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	default:
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+}
+
+// decodeCharset returns the given data in the given charset as an UTF-8 string
+func decodeCharset(charset string, data []byte) (string, error) {
+	r, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	res, err := ioutil.ReadAll(r)
+	return string(res), err
+}
+
+// htmlToText returns the text of the given HTML
+func htmlToText(htmlText string) string {
+	text := strings.NewReplacer("<br>", "\n", "<br/>", "\n", "</p>", "\n").Replace(htmlText)
+	return strings.TrimSpace(html.UnescapeString(htmlTagsRegex.ReplaceAllString(text, "")))
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fetchmail
+
+import (
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/messaging"
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// A Router routes an incoming message to a record, which it may create.
+// It returns the model and the ID of the record, or false if it does not
+// handle the message.
+type Router func(env models.Environment, msg *ParsedMessage) (resModel string, resID int64, ok bool)
+
+// routers are the registered Routers, in registration order
+var routers []Router
+
+// RegisterRouter registers the given Router. Routers are tried in
+// registration order for incoming messages that do not answer a
+// message of an existing thread.
+func RegisterRouter(router Router) {
+	routers = append(routers, router)
+}
+
+// SaveAttachment stores the given attachment of an incoming message
+// posted on the record of the given model with the given ID.
+// If it is nil, attachments of incoming messages are discarded.
+var SaveAttachment func(env models.Environment, resModel string, resID int64, attachment Attachment)
+
+// findThread returns the model and the ID of the record on which a message
+// with one of the given Message-IDs has been posted.
+func findThread(env models.Environment, messageIDs []string) (string, int64, bool) {
+	if len(messageIDs) == 0 {
+		return "", 0, false
+	}
+	messages := env.Pool("Message")
+	mi := messages.Model()
+	for _, messageID := range messageIDs {
+		message := messages.Search(mi.Field(mi.FieldName("MessageID")).Equals(messageID)).Limit(1)
+		if message.IsEmpty() {
+			continue
+		}
+		return message.Get(mi.FieldName("ResModel")).(string), message.Get(mi.FieldName("ResID")).(int64), true
+	}
+	return "", 0, false
+}
+
+// Route returns the model and the ID of the record to which the given message must
+// be posted. The record is:
+//
+// - the record of the thread the message answers, found by its references,
+// - or else the record returned by the first Router that handles the message,
+// - or else a new record of defaultModel created by its MessageNew method.
+//
+// It returns an error if the message cannot be routed.
+func Route(env models.Environment, msg *ParsedMessage, defaultModel string) (string, int64, error) {
+	if resModel, resID, ok := findThread(env, msg.ThreadReferences()); ok {
+		return resModel, resID, nil
+	}
+	for _, router := range routers {
+		if resModel, resID, ok := router(env, msg); ok {
+			return resModel, resID, nil
+		}
+	}
+	if defaultModel == "" {
+		return "", 0, fmt.Errorf("no route found for message %s from %s", msg.MessageID, msg.From)
+	}
+	if _, ok := models.Registry.Get(defaultModel); !ok {
+		return "", 0, fmt.Errorf("unknown model %s to create records from incoming messages", defaultModel)
+	}
+	record := env.Pool(defaultModel).Call("MessageNew", msg.Subject, msg.Body, msg.From).(models.RecordSet)
+	return defaultModel, record.Ids()[0], nil
+}
+
+// authorID returns the ID of the user with the given email address or 0
+func authorID(env models.Environment, email string) int64 {
+	users := env.Pool("User")
+	mi := users.Model()
+	if _, ok := mi.Fields().Get("Email"); !ok || email == "" {
+		return 0
+	}
+	user := users.Search(mi.Field(mi.FieldName("Email")).ILike(email)).Limit(1)
+	if user.IsEmpty() {
+		return 0
+	}
+	return user.Ids()[0]
+}
+
+// Process routes the given message and posts it on its record. Messages
+// that have already been processed are ignored.
+func Process(env models.Environment, msg *ParsedMessage, defaultModel string) error {
+	messages := env.Pool("Message")
+	mi := messages.Model()
+	if msg.MessageID != "" && messages.Search(mi.Field(mi.FieldName("MessageID")).Equals(msg.MessageID)).SearchCount() > 0 {
+		log.Debug("Ignoring already processed message", "messageID", msg.MessageID)
+		return nil
+	}
+	resModel, resID, err := Route(env, msg, defaultModel)
+	if err != nil {
+		return err
+	}
+	messaging.Post(env, resModel, resID, messaging.MessageValues{
+		Subject:     msg.Subject,
+		Body:        msg.Body,
+		MessageType: messaging.TypeEmail,
+		AuthorID:    authorID(env, msg.From),
+		EmailFrom:   msg.From,
+		MessageID:   msg.MessageID,
+	})
+	if SaveAttachment != nil {
+		for _, attachment := range msg.Attachments {
+			SaveAttachment(env, resModel, resID, attachment)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package filestore
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// An Attachment holds the data of an attachment needed to download it
+type Attachment struct {
+	Checksum string
+	Name     string
+	MimeType string
+	ModTime  time.Time
+}
+
+// inlineMimeTypes are the MIME types of the attachments that can be displayed
+// inline by the browser. Other attachments are always downloaded, since they
+// may contain scripts that would run with the credentials of the user
+// (e.g. HTML or SVG files).
+var inlineMimeTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+	"image/x-icon":    true,
+	"audio/mpeg":      true,
+	"audio/ogg":       true,
+	"audio/wav":       true,
+	"video/mp4":       true,
+	"video/ogg":       true,
+	"video/webm":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// canDisplayInline returns true if an attachment with the given MIME type
+// can be safely displayed inline by the browser.
+func canDisplayInline(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	return inlineMimeTypes[mediaType]
+}
+
+// GetAttachment returns the attachment with the given id. It must return
+// an error if the user of env is not allowed to read this attachment.
+//
+// The content controller answers 404 to all requests as long as it is not set.
+var GetAttachment func(env models.Environment, id int64) (Attachment, error)
+
+// content streams the content of an attachment.
+//
+// Range requests and conditional requests on the ETag (i.e. the checksum)
+// are supported. The file is sent as an attachment if the 'download'
+// query parameter is 'true' or if its MIME type cannot be safely displayed
+// inline.
+func content(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || GetAttachment == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var (
+		att   Attachment
+		store Store
+		aErr  error
+	)
+	err = ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		att, aErr = GetAttachment(env, id)
+		store = ForDatabase(env.DBName())
+	})
+	if err != nil || aErr != nil {
+		// We do not tell apart missing and forbidden attachments
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	file, err := store.Open(att.Checksum)
+	if os.IsNotExist(err) {
+		log.Warn("Attachment file is missing from filestore", "id", id, "checksum", att.Checksum)
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Panic("Unable to open attachment file", "id", id, "checksum", att.Checksum, "error", err)
+	}
+	defer file.Close()
+	ctx.Header("ETag", strconv.Quote(att.Checksum))
+	ctx.Header("Cache-Control", "private, max-age=0")
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	if att.MimeType != "" {
+		ctx.Header("Content-Type", att.MimeType)
+	}
+	if ctx.Query("download") == "true" || !canDisplayInline(att.MimeType) {
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Name}))
+	}
+	http.ServeContent(ctx.Writer, ctx.Request, att.Name, att.ModTime, file)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/content")
+	grp.AddController(http.MethodGet, "/:id", content)
+}
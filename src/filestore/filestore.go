@@ -0,0 +1,98 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package filestore stores the data of attachments outside of the database.
+//
+// Contents are addressed by their SHA-1 checksum so that identical files are
+// stored only once. The store of each database is configured in the
+// 'Filestore' section of the configuration and can be either a local
+// directory (the default) or an S3 compatible object storage.
+package filestore
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/dbmanager"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/spf13/viper"
+)
+
+var log logging.Logger
+
+// checksumRegex is the pattern of valid content checksums
+var checksumRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ErrInvalidChecksum is returned when a checksum is malformed
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+// A File is a stored content opened for reading
+type File interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// A Store holds contents by checksum
+type Store interface {
+	// Put stores the content read from r and returns its checksum.
+	// Storing a content that already exists is a no-op.
+	Put(r io.Reader) (string, error)
+	// Open returns the content with the given checksum.
+	// The returned error satisfies os.IsNotExist if there is no such content.
+	Open(checksum string) (File, error)
+	// Delete removes the content with the given checksum.
+	Delete(checksum string) error
+	// Walk calls fn with the checksum and the modification time of each
+	// stored content. Walk stops at the first error returned by fn.
+	Walk(fn func(checksum string, modTime time.Time) error) error
+}
+
+// ForDatabase returns the Store of the given database,
+// as defined by the Filestore.Type configuration key.
+func ForDatabase(dbName string) Store {
+	switch viper.GetString("Filestore.Type") {
+	case "", "local":
+		return LocalStore{Dir: dbmanager.FileStoreDir(dbName)}
+	case "s3":
+		return NewS3Store(dbName)
+	default:
+		log.Panic("Unknown filestore type", "type", viper.GetString("Filestore.Type"))
+	}
+	return nil
+}
+
+// newHash returns a new hash to compute checksums
+func newHash() hash.Hash {
+	return sha1.New()
+}
+
+// Checksum returns the checksum of the given data
+func Checksum(data []byte) string {
+	h := newHash()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkChecksum returns an error if the given checksum is malformed.
+func checkChecksum(checksum string) error {
+	if !checksumRegex.MatchString(checksum) {
+		return fmt.Errorf("%w: %s", ErrInvalidChecksum, checksum)
+	}
+	return nil
+}
+
+// relativePath returns the path of the given checksum relative to
+// the root of the store.
+func relativePath(checksum string) string {
+	return checksum[:2] + "/" + checksum
+}
+
+func init() {
+	log = logging.GetLogger("filestore")
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package filestore
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeS3 is a minimal in-memory S3 server
+type fakeS3 struct {
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key], _ = ioutil.ReadAll(r.Body)
+	case http.MethodDelete:
+		delete(f.objects, key)
+	case http.MethodHead, http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			var res s3ListResult
+			for k := range f.objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+					res.Contents = append(res.Contents, struct {
+						Key          string
+						LastModified time.Time
+					}{Key: k, LastModified: time.Now().Add(-2 * time.Hour)})
+				}
+			}
+			xml.NewEncoder(w).Encode(res)
+			return
+		}
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			data = data[start:]
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}
+}
+
+func testStore(store Store) {
+	checksum, err := store.Put(strings.NewReader("Hello World"))
+	So(err, ShouldBeNil)
+	So(checksum, ShouldEqual, "0a4d55a8d778e5022fab701977c5d840bbc486d0")
+	So(checksum, ShouldEqual, Checksum([]byte("Hello World")))
+	_, err = store.Put(strings.NewReader("Hello World"))
+	So(err, ShouldBeNil)
+	file, err := store.Open(checksum)
+	So(err, ShouldBeNil)
+	_, err = file.Seek(6, 0)
+	So(err, ShouldBeNil)
+	data, err := ioutil.ReadAll(file)
+	So(err, ShouldBeNil)
+	So(string(data), ShouldEqual, "World")
+	So(file.Close(), ShouldBeNil)
+	var checksums []string
+	So(store.Walk(func(c string, _ time.Time) error {
+		checksums = append(checksums, c)
+		return nil
+	}), ShouldBeNil)
+	So(checksums, ShouldResemble, []string{checksum})
+	So(store.Delete(checksum), ShouldBeNil)
+	_, err = store.Open(checksum)
+	So(os.IsNotExist(err), ShouldBeTrue)
+	_, err = store.Open("../../etc/passwd")
+	So(err, ShouldNotBeNil)
+}
+
+func TestFileStore(t *testing.T) {
+	Convey("Testing file stores", t, func() {
+		dir, err := ioutil.TempDir("", "hexya-filestore")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		Convey("Local store should store contents by checksum", func() {
+			store := LocalStore{Dir: dir}
+			testStore(store)
+		})
+		Convey("S3 store should store contents by checksum", func() {
+			srv := httptest.NewServer(&fakeS3{objects: make(map[string][]byte)})
+			defer srv.Close()
+			store := &S3Store{Endpoint: srv.URL, Region: "us-east-1", Bucket: "bucket", Prefix: "db/",
+				AccessKey: "key", SecretKey: "secret", Client: srv.Client()}
+			testStore(store)
+		})
+		Convey("Garbage collection should only delete old orphans", func() {
+			store := LocalStore{Dir: dir}
+			kept, _ := store.Put(strings.NewReader("kept"))
+			orphan, _ := store.Put(strings.NewReader("orphan"))
+			count, err := collectGarbage(store, map[string]bool{kept: true}, time.Now().Add(-time.Hour))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 0)
+			count, err = collectGarbage(store, map[string]bool{kept: true}, time.Now().Add(time.Minute))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+			_, err = store.Open(orphan)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			_, err = store.Open(kept)
+			So(err, ShouldBeNil)
+		})
+		Convey("Scrambling should replace and delete the referenced contents", func() {
+			store := LocalStore{Dir: dir}
+			secret, _ := store.Put(strings.NewReader("secret"))
+			replaced := make(map[string]string)
+			count, err := scrambleContents(store, []string{secret}, func(checksum, newChecksum string) {
+				replaced[checksum] = newChecksum
+			})
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+			_, err = store.Open(secret)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			f, err := store.Open(replaced[secret])
+			So(err, ShouldBeNil)
+			defer f.Close()
+			data, err := ioutil.ReadAll(f)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "Scrambled attachment 1\n")
+		})
+		Convey("Only safe MIME types should be displayed inline", func() {
+			So(canDisplayInline("image/png"), ShouldBeTrue)
+			So(canDisplayInline("text/plain; charset=utf-8"), ShouldBeTrue)
+			So(canDisplayInline("Application/PDF"), ShouldBeTrue)
+			So(canDisplayInline("text/html"), ShouldBeFalse)
+			So(canDisplayInline("image/svg+xml"), ShouldBeFalse)
+			So(canDisplayInline("application/xhtml+xml"), ShouldBeFalse)
+			So(canDisplayInline(""), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package filestore
+
+import (
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+)
+
+const (
+	// gcPeriod is the time between two garbage collections of the filestores
+	gcPeriod = 6 * time.Hour
+	// gcGracePeriod is the minimum age of an orphaned file before it is
+	// deleted, so that files of uncommitted transactions are kept.
+	gcGracePeriod = time.Hour
+)
+
+// ReferencedChecksums returns the checksums of all the
+// contents referenced in the database of env.
+//
+// Garbage collection is disabled as long as it is not set.
+var ReferencedChecksums func(env models.Environment) []string
+
+// GarbageCollect deletes the contents of the store of the given database
+// that are not referenced anymore. It returns the number of deleted contents.
+func GarbageCollect(dbName string) (int, error) {
+	if ReferencedChecksums == nil {
+		return 0, nil
+	}
+	var store Store
+	referenced := make(map[string]bool)
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		store = ForDatabase(env.DBName())
+		for _, checksum := range ReferencedChecksums(env) {
+			referenced[checksum] = true
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return collectGarbage(store, referenced, time.Now().Add(-gcGracePeriod))
+}
+
+// collectGarbage deletes the contents of store that are not referenced
+// and that were last modified before the given time.
+func collectGarbage(store Store, referenced map[string]bool, before time.Time) (int, error) {
+	var orphans []string
+	err := store.Walk(func(checksum string, modTime time.Time) error {
+		if !referenced[checksum] && modTime.Before(before) {
+			orphans = append(orphans, checksum)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i, checksum := range orphans {
+		if err = store.Delete(checksum); err != nil {
+			return i, err
+		}
+	}
+	return len(orphans), nil
+}
+
+// collectAllGarbage garbage collects the stores of all connected databases
+func collectAllGarbage() {
+	for _, dbName := range models.ConnectedDBNames() {
+		count, err := GarbageCollect(dbName)
+		if err != nil {
+			log.Warn("Error while collecting filestore garbage", "database", dbName, "error", err)
+			continue
+		}
+		if count > 0 {
+			log.Info("Deleted orphaned files from filestore", "database", dbName, "count", count)
+		}
+	}
+}
+
+func init() {
+	models.RegisterWorker(models.NewWorkerFunction(collectAllGarbage, gcPeriod))
+}
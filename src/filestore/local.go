@@ -0,0 +1,92 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package filestore
+
+import (
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A LocalStore is a Store in a directory of the local file system.
+// The content with checksum 'abcd...' is stored in the file 'ab/abcd...'.
+type LocalStore struct {
+	Dir string
+}
+
+var _ Store = LocalStore{}
+
+// path returns the absolute path of the file of the given checksum
+func (ls LocalStore) path(checksum string) string {
+	return filepath.Join(ls.Dir, filepath.FromSlash(relativePath(checksum)))
+}
+
+// Put stores the content read from r and returns its checksum.
+func (ls LocalStore) Put(r io.Reader) (string, error) {
+	if err := os.MkdirAll(ls.Dir, 0700); err != nil {
+		return "", err
+	}
+	tmpFile, err := ioutil.TempFile(ls.Dir, ".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	h := newHash()
+	_, err = io.Copy(io.MultiWriter(tmpFile, h), r)
+	if cErr := tmpFile.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(h.Sum(nil))
+	target := ls.path(checksum)
+	if _, err = os.Stat(target); err == nil {
+		return checksum, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return "", err
+	}
+	return checksum, os.Rename(tmpFile.Name(), target)
+}
+
+// Open returns the content with the given checksum.
+func (ls LocalStore) Open(checksum string) (File, error) {
+	if err := checkChecksum(checksum); err != nil {
+		return nil, err
+	}
+	return os.Open(ls.path(checksum))
+}
+
+// Delete removes the content with the given checksum.
+func (ls LocalStore) Delete(checksum string) error {
+	if err := checkChecksum(checksum); err != nil {
+		return err
+	}
+	err := os.Remove(ls.path(checksum))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Walk calls fn for each stored content.
+func (ls LocalStore) Walk(fn func(checksum string, modTime time.Time) error) error {
+	err := filepath.Walk(ls.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || checkChecksum(info.Name()) != nil {
+			return nil
+		}
+		return fn(info.Name(), info.ModTime())
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
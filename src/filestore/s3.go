@@ -0,0 +1,289 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package filestore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// s3UnsignedPayload is the payload hash of requests whose body is not signed
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// An S3Store is a Store in a bucket of an S3 compatible object storage.
+// The content with checksum 'abcd...' is stored at the key
+// '<Prefix>ab/abcd...'. Requests are signed with AWS Signature Version 4.
+type S3Store struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+var _ Store = new(S3Store)
+
+// NewS3Store returns the S3Store of the given database from the
+// Filestore.S3 section of the configuration. Contents of each
+// database are stored under a prefix with the database name.
+func NewS3Store(dbName string) *S3Store {
+	region := viper.GetString("Filestore.S3.Region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := viper.GetString("Filestore.S3.Endpoint")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	return &S3Store{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    viper.GetString("Filestore.S3.Bucket"),
+		Prefix:    viper.GetString("Filestore.S3.Prefix") + dbName + "/",
+		AccessKey: viper.GetString("Filestore.S3.AccessKey"),
+		SecretKey: viper.GetString("Filestore.S3.SecretKey"),
+		Client:    http.DefaultClient,
+	}
+}
+
+// objectURL returns the URL of the given key in the bucket
+func (s *S3Store) objectURL(key string) string {
+	return fmt.Sprintf("%s/%s/%s", s.Endpoint, s.Bucket, key)
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the given key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sign adds the AWS Signature Version 4 headers to the given request
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n",
+		req.URL.Host, s3UnsignedPayload, amzDate)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.Replace(req.URL.Query().Encode(), "+", "%20", -1),
+		canonicalHeaders,
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.Region)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// do signs and sends a request to the given URL. It returns an error
+// if the response status is not successful. The caller must close
+// the body of the returned response.
+func (s *S3Store) do(method, rawURL string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, time.Now())
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", method, rawURL, resp.Status, msg)
+	}
+	return resp, nil
+}
+
+// Put stores the content read from r and returns its checksum.
+//
+// The content is first written to a temporary file to compute its checksum.
+func (s *S3Store) Put(r io.Reader) (string, error) {
+	tmpFile, err := ioutil.TempFile("", "hexya-filestore")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+	h := newHash()
+	size, err := io.Copy(io.MultiWriter(tmpFile, h), r)
+	if err != nil {
+		return "", err
+	}
+	if _, err = tmpFile.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(h.Sum(nil))
+	resp, err := s.do(http.MethodPut, s.objectURL(s.Prefix+relativePath(checksum)), tmpFile, size, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return checksum, nil
+}
+
+// Open returns the content with the given checksum.
+func (s *S3Store) Open(checksum string) (File, error) {
+	if err := checkChecksum(checksum); err != nil {
+		return nil, err
+	}
+	objURL := s.objectURL(s.Prefix + relativePath(checksum))
+	resp, err := s.do(http.MethodHead, objURL, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &s3File{store: s, url: objURL, size: resp.ContentLength}, nil
+}
+
+// Delete removes the content with the given checksum.
+func (s *S3Store) Delete(checksum string) error {
+	if err := checkChecksum(checksum); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodDelete, s.objectURL(s.Prefix+relativePath(checksum)), nil, 0, nil)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// s3ListResult is the result of a ListObjectsV2 request
+type s3ListResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// Walk calls fn for each stored content.
+func (s *S3Store) Walk(fn func(checksum string, modTime time.Time) error) error {
+	var token string
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, fmt.Sprintf("%s/%s?%s", s.Endpoint, s.Bucket, query.Encode()), nil, 0, nil)
+		if err != nil {
+			return err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, obj := range result.Contents {
+			checksum := obj.Key[strings.LastIndex(obj.Key, "/")+1:]
+			if checkChecksum(checksum) != nil {
+				continue
+			}
+			if err = fn(checksum, obj.LastModified); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// An s3File reads an S3 object with range requests so that it can be seeked.
+type s3File struct {
+	store  *S3Store
+	url    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+// Read reads the object from the current offset
+func (f *s3File) Read(p []byte) (int, error) {
+	if f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		header := http.Header{"Range": {fmt.Sprintf("bytes=%d-", f.offset)}}
+		resp, err := f.store.do(http.MethodGet, f.url, nil, 0, header)
+		if err != nil {
+			return 0, err
+		}
+		f.body = resp.Body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Seek sets the offset for the next Read
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Close closes the pending request if any
+func (f *s3File) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	err := f.body.Close()
+	f.body = nil
+	return err
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package filestore
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// ReplaceContent makes the attachments of the database of env that reference
+// the content with the given checksum reference the content with newChecksum
+// instead.
+//
+// It is set together with ReferencedChecksums by the module that defines the
+// Attachment model. Attachments are not scrambled as long as it is not set.
+var ReplaceContent func(env models.Environment, checksum, newChecksum string)
+
+// scrambleAttachments replaces the contents referenced in the database of env
+// by fake text contents (see scrambleContents). It returns the number of
+// replaced contents.
+func scrambleAttachments(env models.Environment, _ *rand.Rand) int {
+	if ReferencedChecksums == nil || ReplaceContent == nil {
+		return 0
+	}
+	count, err := scrambleContents(ForDatabase(env.DBName()), ReferencedChecksums(env), func(checksum, newChecksum string) {
+		ReplaceContent(env, checksum, newChecksum)
+	})
+	if err != nil {
+		log.Panic("Unable to scramble attachments", "database", env.DBName(), "error", err)
+	}
+	return count
+}
+
+// scrambleContents stores a fake text content for each of the given checksums,
+// calls replace with the checksums of the original and of the fake content and
+// deletes the original from store, so that it is not left in the filestore of
+// a scrambled database until the next garbage collection.
+func scrambleContents(store Store, checksums []string, replace func(checksum, newChecksum string)) (int, error) {
+	checksums = append([]string(nil), checksums...)
+	sort.Strings(checksums)
+	for i, checksum := range checksums {
+		newChecksum, err := store.Put(strings.NewReader(fmt.Sprintf("Scrambled attachment %d\n", i+1)))
+		if err != nil {
+			return i, err
+		}
+		replace(checksum, newChecksum)
+		if err = store.Delete(checksum); err != nil {
+			return i, err
+		}
+	}
+	return len(checksums), nil
+}
+
+func init() {
+	models.RegisterScrambler(scrambleAttachments)
+}
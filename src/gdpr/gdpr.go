@@ -0,0 +1,233 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package gdpr
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// A Declaration declares the fields of a model that hold personal data
+type Declaration struct {
+	// Subject is the many2one or one2one field linking the records of the
+	// model to their data subject (e.g. "Partner"). It must be empty if the
+	// model is the model of the data subjects itself.
+	Subject string
+	// Fields are the names of the fields holding personal data
+	Fields []string
+	// Replacements are the values to which Anonymize sets the given fields.
+	// Fields that are not in this map are emptied, so that required fields
+	// must have a replacement.
+	Replacements map[string]interface{}
+	// Unlink is true if Anonymize must delete the records instead of
+	// scrubbing their fields.
+	Unlink bool
+}
+
+var (
+	declarations     = make(map[string][]Declaration)
+	declarationsLock sync.RWMutex
+)
+
+// DeclarePersonalData declares the personal data held by the given model.
+// A model may have several declarations, one for each of its data subjects.
+func DeclarePersonalData(model string, decl Declaration) {
+	declarationsLock.Lock()
+	defer declarationsLock.Unlock()
+	declarations[model] = append(declarations[model], decl)
+}
+
+// checkDeclarations checks that the declared models and fields exist.
+// It panics otherwise.
+func checkDeclarations() {
+	declarationsLock.RLock()
+	defer declarationsLock.RUnlock()
+	for modelName, decls := range declarations {
+		model, ok := models.Registry.Get(modelName)
+		if !ok {
+			log.Panic("Unknown model in personal data declaration", "model", modelName)
+		}
+		for _, decl := range decls {
+			if decl.Subject != "" && subjectModel(model, decl) == "" {
+				log.Panic("Subject of personal data declaration is not a relation field", "model", modelName, "field", decl.Subject)
+			}
+			for _, field := range decl.Fields {
+				if _, ok := model.Fields().Get(field); !ok {
+					log.Panic("Unknown field in personal data declaration", "model", modelName, "field", field)
+				}
+			}
+			for field := range decl.Replacements {
+				if _, ok := model.Fields().Get(field); !ok {
+					log.Panic("Unknown replacement field in personal data declaration", "model", modelName, "field", field)
+				}
+			}
+		}
+	}
+}
+
+// subjectModel returns the name of the model of the data subjects of the given
+// declaration of the given model, or an empty string if its subject field is
+// not a relation field.
+func subjectModel(model *models.Model, decl Declaration) string {
+	if decl.Subject == "" {
+		return model.Name()
+	}
+	fi, ok := model.Fields().Get(decl.Subject)
+	if !ok {
+		return ""
+	}
+	return model.FieldsGet(model.FieldName(fi.Name()))[fi.JSON()].Relation
+}
+
+// A declaredSet is a set of records holding personal data with their declaration
+type declaredSet struct {
+	records     *models.RecordCollection
+	declaration Declaration
+}
+
+// declaredSets returns the records holding personal data of the given
+// subject, ordered by model name. The records of the model of the subject
+// come last, so that they are anonymized after the records linked to them.
+func declaredSets(subject *models.RecordCollection) []declaredSet {
+	declarationsLock.RLock()
+	defer declarationsLock.RUnlock()
+	modelNames := make([]string, 0, len(declarations))
+	for modelName := range declarations {
+		modelNames = append(modelNames, modelName)
+	}
+	sort.Slice(modelNames, func(i, j int) bool {
+		iSubject, jSubject := modelNames[i] == subject.ModelName(), modelNames[j] == subject.ModelName()
+		if iSubject != jSubject {
+			return jSubject
+		}
+		return modelNames[i] < modelNames[j]
+	})
+	var res []declaredSet
+	for _, modelName := range modelNames {
+		model := models.Registry.MustGet(modelName)
+		for _, decl := range declarations[modelName] {
+			if subjectModel(model, decl) != subject.ModelName() {
+				continue
+			}
+			records := subject
+			if decl.Subject != "" {
+				records = subject.Env().Pool(modelName).Search(model.Field(model.FieldName(decl.Subject)).In(subject.Ids()))
+			}
+			if records.IsEmpty() {
+				continue
+			}
+			res = append(res, declaredSet{records: records, declaration: decl})
+		}
+	}
+	return res
+}
+
+// An Archive holds the personal data of a data subject
+type Archive struct {
+	Model string    `json:"model"`
+	ID    int64     `json:"id"`
+	Date  time.Time `json:"date"`
+	// Data holds the values of the declared fields of each record, by model
+	Data map[string][]map[string]interface{} `json:"data"`
+}
+
+// ExportPersonalData returns a JSON archive of all the declared personal
+// data of the given data subject, which must be a single record.
+//
+// Data is read as superuser, so that callers must check that the current
+// user is allowed to access the personal data of the subject.
+func ExportPersonalData(subject models.RecordSet) ([]byte, error) {
+	subject.Collection().EnsureOne()
+	archive := Archive{
+		Model: subject.ModelName(),
+		ID:    subject.Ids()[0],
+		Date:  time.Now().UTC(),
+		Data:  make(map[string][]map[string]interface{}),
+	}
+	var count int
+	for _, set := range declaredSets(subject.Collection().Sudo()) {
+		for _, rec := range set.records.Records() {
+			archive.Data[rec.ModelName()] = append(archive.Data[rec.ModelName()], recordValues(rec, set.declaration.Fields))
+		}
+		count += set.records.Len()
+	}
+	res, err := json.Marshal(archive)
+	if err != nil {
+		return nil, err
+	}
+	audit(subject.Env(), RequestExport, subject, count)
+	return res, nil
+}
+
+// recordValues returns the values of the given fields of the given record
+// by JSON field name, with relations converted to ids.
+func recordValues(rec *models.RecordCollection, fields []string) map[string]interface{} {
+	res := map[string]interface{}{"id": rec.Ids()[0]}
+	for _, field := range fields {
+		fi := rec.Model().Fields().MustGet(field)
+		value := rec.Get(rec.Model().FieldName(field))
+		if rs, ok := value.(models.RecordSet); ok {
+			ids := rs.Ids()
+			if ids == nil {
+				ids = []int64{}
+			}
+			value = ids
+		}
+		res[fi.JSON()] = value
+	}
+	return res
+}
+
+// Anonymize irreversibly scrubs all the declared personal data of the given
+// data subject, which must be a single record. Declared fields are set to
+// their replacement value or emptied, and records of declarations with
+// Unlink set are deleted. It returns the number of records that have been
+// anonymized.
+//
+// Data is written as superuser, so that callers must check that the current
+// user is allowed to anonymize the subject.
+func Anonymize(subject models.RecordSet) int {
+	subject.Collection().EnsureOne()
+	var count int
+	for _, set := range declaredSets(subject.Collection().Sudo()) {
+		count += set.records.Len()
+		if set.declaration.Unlink {
+			set.records.Call("Unlink")
+			continue
+		}
+		mi := set.records.Model()
+		data := models.NewModelData(mi)
+		for _, field := range set.declaration.Fields {
+			data.Set(mi.FieldName(field), set.declaration.Replacements[field])
+		}
+		set.records.Call("Write", data)
+	}
+	audit(subject.Env(), RequestAnonymize, subject, count)
+	return count
+}
+
+// scramblePersonalData overwrites the declared personal data of all the
+// records of the database of env with fake data (see models.ScrambleFields).
+func scramblePersonalData(env models.Environment, rnd *rand.Rand) int {
+	declarationsLock.RLock()
+	fields := make(map[string][]string)
+	for modelName, decls := range declarations {
+		declared := make(map[string]bool)
+		for _, decl := range decls {
+			for _, field := range decl.Fields {
+				if !declared[field] {
+					declared[field] = true
+					fields[modelName] = append(fields[modelName], field)
+				}
+			}
+		}
+	}
+	declarationsLock.RUnlock()
+	return models.ScrambleFields(env, rnd, fields)
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package gdpr
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDeclarations(t *testing.T) {
+	Convey("Testing personal data declarations", t, func() {
+		defer func() {
+			declarations = make(map[string][]Declaration)
+		}()
+		request := models.Registry.MustGet(requestModel)
+		Convey("Declarations of several subjects should be kept", func() {
+			DeclarePersonalData(requestModel, Declaration{Fields: []string{"SubjectModel"}})
+			DeclarePersonalData(requestModel, Declaration{Fields: []string{"SubjectID"}, Unlink: true})
+			So(declarations[requestModel], ShouldHaveLength, 2)
+			So(subjectModel(request, declarations[requestModel][0]), ShouldEqual, requestModel)
+			So(checkDeclarations, ShouldNotPanic)
+		})
+		Convey("Declarations of unknown models or fields should panic", func() {
+			DeclarePersonalData("NonExistentModel", Declaration{Fields: []string{"Name"}})
+			So(checkDeclarations, ShouldPanic)
+			declarations = make(map[string][]Declaration)
+			DeclarePersonalData(requestModel, Declaration{Fields: []string{"NonExistentField"}})
+			So(checkDeclarations, ShouldPanic)
+			declarations = make(map[string][]Declaration)
+			DeclarePersonalData(requestModel, Declaration{Subject: "SubjectModel"})
+			So(subjectModel(request, declarations[requestModel][0]), ShouldBeEmpty)
+			So(checkDeclarations, ShouldPanic)
+		})
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package gdpr is a Hexya module that helps complying with personal data
+// regulations such as the GDPR.
+//
+// Modules declare the fields of their models that hold personal data with
+// DeclarePersonalData, together with the field linking each record to its
+// data subject (e.g. a partner):
+//
+//	gdpr.DeclarePersonalData("SaleOrder", gdpr.Declaration{
+//		Subject: "Partner",
+//		Fields:  []string{"ShippingAddress", "Phone"},
+//	})
+//
+// ExportPersonalData then returns all the declared data of a subject as a
+// JSON archive, and Anonymize irreversibly scrubs it. Each export and each
+// anonymization is recorded in a PersonalDataRequest record, which does not
+// hold any personal data.
+//
+// The declared fields are also those that are overwritten with fake data by
+// the scramble-db command.
+package gdpr
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "gdpr"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("gdpr")
+	declareModels()
+	models.RegisterScrambler(scramblePersonalData)
+	server.RegisterModule(&server.Module{
+		Name:     MODULE_NAME,
+		PreInit:  addUserFields,
+		PostInit: checkDeclarations,
+	})
+}
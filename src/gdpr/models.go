@@ -0,0 +1,64 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package gdpr
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// requestModel is the name of the model of the audit entries
+const requestModel = "PersonalDataRequest"
+
+// Types of personal data requests
+const (
+	RequestExport    = "export"
+	RequestAnonymize = "anonymize"
+)
+
+func declareModels() {
+	request := models.NewModel(requestModel)
+	request.SetDefaultOrder("ID desc")
+	request.AddFields(map[string]models.FieldDefinition{
+		"RequestType": fields.Selection{String: "Type", Required: true,
+			Selection: types.Selection{RequestExport: "Export", RequestAnonymize: "Anonymization"}},
+		"SubjectModel": fields.Char{String: "Data Subject Model", Required: true, Index: true},
+		"SubjectID":    fields.Integer{String: "Data Subject ID", Required: true, Index: true},
+		"Date": fields.DateTime{Required: true, Default: func(env models.Environment) interface{} {
+			return dates.Now()
+		}},
+		"RecordsCount": fields.Integer{String: "Records",
+			Help: "Number of records that have been exported or anonymized"},
+	})
+}
+
+// addUserFields adds the user who made the request, if the User model exists
+func addUserFields() {
+	user, ok := models.Registry.Get("User")
+	if !ok {
+		return
+	}
+	models.Registry.MustGet(requestModel).AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{RelationModel: user, Help: "User who made the request"},
+	})
+}
+
+// audit creates a request entry of the given type for the given subject.
+// The entry is created as superuser so that it can be recorded even if
+// the user has no access to the requests.
+func audit(env models.Environment, requestType string, subject models.RecordSet, count int) {
+	requests := env.Pool(requestModel).Sudo()
+	mi := requests.Model()
+	data := models.NewModelData(mi).
+		Set(mi.FieldName("RequestType"), requestType).
+		Set(mi.FieldName("SubjectModel"), subject.ModelName()).
+		Set(mi.FieldName("SubjectID"), subject.Ids()[0]).
+		Set(mi.FieldName("RecordsCount"), int64(count))
+	if _, ok := mi.Fields().Get("User"); ok {
+		data.Set(mi.FieldName("User"), env.Pool("User").Call("BrowseOne", env.Uid()))
+	}
+	requests.Call("Create", data)
+}
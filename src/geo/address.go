@@ -0,0 +1,73 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package geo
+
+import (
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// DefaultAddressFormat is the format of addresses in
+// countries that do not define their own format.
+const DefaultAddressFormat = "%(street)s\n%(street2)s\n%(city)s %(state_code)s %(zip)s\n%(country_name)s"
+
+// An Address to be formatted with FormatAddress
+type Address struct {
+	Street      string
+	Street2     string
+	Zip         string
+	City        string
+	StateCode   string
+	StateName   string
+	CountryCode string
+	CountryName string
+}
+
+// Format returns this address formatted with the given format. Lines
+// that are empty once the placeholders are replaced are removed.
+func (a Address) Format(format string) string {
+	if format == "" {
+		format = DefaultAddressFormat
+	}
+	replacer := strings.NewReplacer(
+		"%(street)s", a.Street,
+		"%(street2)s", a.Street2,
+		"%(zip)s", a.Zip,
+		"%(city)s", a.City,
+		"%(state_code)s", a.StateCode,
+		"%(state_name)s", a.StateName,
+		"%(country_code)s", a.CountryCode,
+		"%(country_name)s", a.CountryName,
+	)
+	var lines []string
+	for _, line := range strings.Split(replacer.Replace(format), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		line = strings.Trim(line, ", ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatAddress returns the given address formatted with the address format
+// of the given Country record. The state and country names and codes of the
+// address are taken from the given CountryState and Country records.
+// Both records may be empty.
+func FormatAddress(country, state models.RecordSet, address Address) string {
+	var format string
+	if !country.IsEmpty() {
+		mi := country.Collection().Model()
+		format = country.Get(mi.FieldName("AddressFormat")).(string)
+		address.CountryCode = country.Get(mi.FieldName("Code")).(string)
+		address.CountryName = country.Get(mi.FieldName("Name")).(string)
+	}
+	if !state.IsEmpty() {
+		mi := state.Collection().Model()
+		address.StateCode = state.Get(mi.FieldName("Code")).(string)
+		address.StateName = state.Get(mi.FieldName("Name")).(string)
+	}
+	return address.Format(format)
+}
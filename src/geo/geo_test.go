@@ -0,0 +1,64 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package geo
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAddressFormat(t *testing.T) {
+	Convey("Testing address formatting", t, func() {
+		address := Address{
+			Street:      "1600 Pennsylvania Avenue NW",
+			City:        "Washington",
+			Zip:         "20500",
+			StateCode:   "DC",
+			CountryName: "United States",
+		}
+		Convey("Default format should be used if none is given", func() {
+			So(address.Format(""), ShouldEqual, "1600 Pennsylvania Avenue NW\nWashington DC 20500\nUnited States")
+		})
+		Convey("Empty placeholders and separators should be removed", func() {
+			us := "%(street)s\n%(street2)s\n%(city)s, %(state_code)s %(zip)s\n%(country_name)s"
+			So(address.Format(us), ShouldEqual, "1600 Pennsylvania Avenue NW\nWashington, DC 20500\nUnited States")
+			So(Address{City: "Paris"}.Format(us), ShouldEqual, "Paris")
+		})
+	})
+}
+
+func TestData(t *testing.T) {
+	Convey("Testing geo data files", t, func() {
+		countries := readCSV("data/010-Country.csv")
+		states := readCSV("data/020-CountryState.csv")
+		So(countries[0], ShouldResemble, []string{"ID", "Code", "Name", "AddressFormat"})
+		So(states[0], ShouldResemble, []string{"ID", "Country", "Code", "Name"})
+		countryIDs := make(map[string]bool)
+		for _, row := range countries[1:] {
+			So(row[1], ShouldHaveLength, 2)
+			So(countryIDs, ShouldNotContainKey, row[0])
+			countryIDs[row[0]] = true
+		}
+		So(len(countryIDs), ShouldBeGreaterThan, 240)
+		for _, row := range states[1:] {
+			So(countryIDs, ShouldContainKey, row[1])
+		}
+	})
+}
+
+func readCSV(fileName string) [][]string {
+	f, err := os.Open(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		panic(err)
+	}
+	return rows
+}
@@ -0,0 +1,24 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package geo is a Hexya module that provides the Country and CountryState
+// models with their ISO codes and address formats, as well as the data of
+// all countries. Modules should reference these records in address fields
+// instead of storing countries and states as free text.
+package geo
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "geo"
+)
+
+func init() {
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package geo
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	country := models.NewModel("Country")
+	countryState := models.NewModel("CountryState")
+
+	country.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Country Name", Required: true, Translate: true},
+		"Code": fields.Char{String: "Country Code", Size: 2, Required: true, Unique: true,
+			Help: "The ISO 3166-1 alpha-2 code of the country"},
+		"AddressFormat": fields.Text{String: "Address Format",
+			Help: `The format of addresses in this country. Available placeholders are
+%(street)s, %(street2)s, %(zip)s, %(city)s, %(state_code)s, %(state_name)s,
+%(country_code)s and %(country_name)s. Empty lines are removed.`},
+		"States": fields.One2Many{RelationModel: countryState, ReverseFK: "Country"},
+	})
+
+	countryState.AddFields(map[string]models.FieldDefinition{
+		"Country": fields.Many2One{RelationModel: country, Required: true, OnDelete: models.Cascade},
+		"Name":    fields.Char{String: "State Name", Required: true},
+		"Code": fields.Char{String: "State Code", Required: true,
+			Help: "The code of the state in its country (ISO 3166-2 without the country prefix)"},
+	})
+	countryState.AddSQLConstraint("code_country_uniq", "unique(country_id, code)",
+		"The code of the state must be unique per country")
+}
@@ -12,8 +12,8 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/hexya-erp/hexya/src/i18n"
 	"github.com/hexya-erp/hexya/src/tools/generate"
-	"github.com/hexya-erp/hexya/src/tools/strutils"
 )
 
 func main() {
@@ -41,29 +41,12 @@ func main() {
 		for i := 0; i < len(headers); i++ {
 			recMap[headers[i]] = record[i]
 		}
-		dtSubst := map[string]string{
-			"%d": "02",
-			"%m": "01",
-			"%Y": "2006",
-			"%y": "06",
-			"%H": "15",
-			"%I": "03",
-			"%M": "04",
-			"%S": "05",
-			"%p": "PM",
-			"%b": "Jan",
-			"%B": "January",
-			"%A": "Monday",
-			"%a": "Mon",
-		}
-		recMap["date_format"] = recMap["date_format"]
-		recMap["time_format"] = recMap["time_format"]
-		recMap["date_format_go"] = strutils.Substitute(recMap["date_format"], dtSubst)
-		recMap["time_format_go"] = strutils.Substitute(recMap["time_format"], dtSubst)
+		recMap["date_format_go"] = i18n.StrftimeToGo(recMap["date_format"])
+		recMap["time_format_go"] = i18n.StrftimeToGo(recMap["time_format"])
 		dir := recMap["direction"]
-		recMap["direction"] = "LanguageDirectionLTR"
+		recMap["direction"] = "LangDirectionLTR"
 		if dir == "Right-to-Left" {
-			recMap["direction"] = "LanguageDirectionRTL"
+			recMap["direction"] = "LangDirectionRTL"
 		}
 		trans, err := strconv.ParseBool(recMap["translatable"])
 		if err != nil {
@@ -90,6 +73,7 @@ var locales = map[string]*Locale{
 		Name: "{{ .name }}",
 		Code: "{{ .code }}",
 		ISOCode: "{{ .iso_code }}",
+		Direction: {{ .direction }},
 		DateFormat: "{{ .date_format }}",
 		TimeFormat: "{{ .time_format }}",
 		DateFormatGo: "{{ .date_format_go }}",
@@ -0,0 +1,205 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package format formats numbers, amounts, dates and field values
+// according to the language and timezone of an Environment.
+//
+// Formatting rules of a language are those of the language model of the
+// database if a module defines one (see GetLangLocale), or the built-in
+// locales of the i18n package otherwise.
+package format
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/markdown"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+)
+
+// Widgets that can be given to Formatter.Field to override the
+// default formatting of the field type.
+const (
+	// WidgetMonetary formats a float field as an amount in a currency
+	WidgetMonetary = "monetary"
+	// WidgetDate formats a datetime field as a date
+	WidgetDate = "date"
+	// WidgetMarkdown renders a markdown or text field to sanitized HTML
+	WidgetMarkdown = "markdown"
+)
+
+// defaultDigits are the digits of float fields without digits
+var defaultDigits = nbutils.Digits{Precision: 16, Scale: 2}
+
+// GetLangLocale returns the Locale of the given language from the Lang model,
+// so that users can customize date formats, separators and grouping of each
+// language. If it is nil or returns nil, the built-in locale of the language
+// is used.
+var GetLangLocale func(env models.Environment, lang string) *i18n.Locale
+
+// Locale returns the Locale of the given language in the database of env,
+// that is the one returned by GetLangLocale if it is set, or the built-in
+// locale of the language otherwise.
+func Locale(env models.Environment, lang string) *i18n.Locale {
+	if GetLangLocale != nil {
+		if locale := GetLangLocale(env, lang); locale != nil {
+			return locale
+		}
+	}
+	return i18n.GetLocale(lang)
+}
+
+// A Formatter formats values in a language and a timezone
+type Formatter struct {
+	Locale   *i18n.Locale
+	Location *time.Location
+}
+
+// NewFormatter returns a Formatter for the language and the timezone
+// given by the 'lang' and 'tz' keys of the context of env.
+func NewFormatter(env models.Environment) *Formatter {
+	locale := Locale(env, env.Context().GetString("lang"))
+	location, err := time.LoadLocation(env.Context().GetString("tz"))
+	if err != nil {
+		location = time.UTC
+	}
+	return &Formatter{
+		Locale:   locale,
+		Location: location,
+	}
+}
+
+// Float returns the given number formatted with the given digits
+func (f *Formatter) Float(value float64, digits nbutils.Digits) string {
+	return f.Locale.FormatFloat(value, digits)
+}
+
+// Integer returns the given integer formatted with thousands separators
+func (f *Formatter) Integer(value int64) string {
+	return f.Locale.FormatFloat(float64(value), nbutils.Digits{Precision: 20})
+}
+
+// Monetary returns the given amount formatted in the given currency
+func (f *Formatter) Monetary(value float64, currency i18n.Currency) string {
+	return f.Locale.FormatMonetary(value, currency)
+}
+
+// Date returns the given date formatted. Empty dates give an empty string.
+func (f *Formatter) Date(value dates.Date) string {
+	if value.IsZero() {
+		return ""
+	}
+	return f.Locale.FormatDate(value)
+}
+
+// DateTime returns the given datetime formatted in the timezone
+// of the Formatter. Empty datetimes give an empty string.
+func (f *Formatter) DateTime(value dates.DateTime) string {
+	if value.IsZero() {
+		return ""
+	}
+	return f.Locale.FormatDateTime(value.In(f.Location))
+}
+
+// Value returns the given value of a field formatted according to the field type:
+//
+// - numbers are formatted with the digits of the field
+// - dates and datetimes with the date and time formats of the language
+// - selections with the label of the value
+// - relations with the display names of the records
+func (f *Formatter) Value(fi *models.FieldInfo, value interface{}) string {
+	switch fi.Type {
+	case fieldtype.Integer:
+		i, err := nbutils.CastToInteger(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return f.Integer(i)
+	case fieldtype.Float:
+		fl, err := nbutils.CastToFloat(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		digits := fi.Digits
+		if digits == (nbutils.Digits{}) {
+			digits = defaultDigits
+		}
+		return f.Float(fl, digits)
+	case fieldtype.Date:
+		d, _ := value.(dates.Date)
+		return f.Date(d)
+	case fieldtype.DateTime:
+		dt, _ := value.(dates.DateTime)
+		return f.DateTime(dt)
+	case fieldtype.Selection:
+		s := fmt.Sprint(value)
+		if label, ok := fi.Selection[s]; ok {
+			return label
+		}
+		return s
+	}
+	if rs, ok := value.(models.RecordSet); ok {
+		return displayNames(rs)
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Field returns the value of the given field of the given record formatted.
+//
+// The widget may be empty to format according to the field type, or one of
+// WidgetMonetary (with the currency of the amount), WidgetDate or
+// WidgetMarkdown. The latter returns HTML that must not be escaped.
+//
+// Selection labels are translated in the language of the record's
+// Environment (see models.Environment.Lang).
+func (f *Formatter) Field(record models.RecordSet, field, widget string, currency ...i18n.Currency) string {
+	if record == nil || record.IsEmpty() {
+		return ""
+	}
+	mi := record.Collection().Model()
+	fName := mi.FieldName(field)
+	infos := mi.FieldsGet(fName)
+	mi.TranslateFieldsInfo(record.Env(), infos)
+	fi := infos[mi.JSONizeFieldName(field)]
+	value := record.Get(fName)
+	switch widget {
+	case WidgetMonetary:
+		fl, err := nbutils.CastToFloat(value)
+		if err != nil || len(currency) == 0 || currency[0] == nil {
+			break
+		}
+		return f.Monetary(fl, currency[0])
+	case WidgetDate:
+		switch v := value.(type) {
+		case dates.DateTime:
+			if v.IsZero() {
+				return ""
+			}
+			return f.Date(v.In(f.Location).ToDate())
+		case dates.Date:
+			return f.Date(v)
+		}
+	case WidgetMarkdown:
+		if s, ok := value.(string); ok {
+			return markdown.ToHTML(s)
+		}
+	}
+	return f.Value(fi, value)
+}
+
+// displayNames returns the display names of the records of rs separated by commas
+func displayNames(rs models.RecordSet) string {
+	var names []string
+	for _, rec := range rs.Collection().Records() {
+		names = append(names, rec.Call("NameGet").(string))
+	}
+	return strings.Join(names, ", ")
+}
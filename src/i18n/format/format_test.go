@@ -0,0 +1,52 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package format
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type euro struct{}
+
+func (euro) Symbol() string              { return "€" }
+func (euro) Position() string            { return "after" }
+func (euro) DecimalPlaces() int          { return 2 }
+func (euro) Round(value float64) float64 { return nbutils.Round(value, 0.01) }
+
+func TestFormat(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		paris = time.FixedZone("CET", 3600)
+	}
+	fr := &Formatter{Locale: i18n.GetLocale("fr"), Location: paris}
+	Convey("Testing formatting helpers", t, func() {
+		Convey("Numbers should use the separators of the language", func() {
+			So(fr.Float(1234567.891, nbutils.Digits{Precision: 16, Scale: 2}), ShouldEqual, "1 234 567,89")
+			So(fr.Integer(1234), ShouldEqual, "1 234")
+			So(fr.Monetary(12.5, euro{}), ShouldEqual, "12,50 €")
+		})
+		Convey("Dates should be formatted in the language and timezone", func() {
+			So(fr.Date(dates.ParseDate("2019-03-15")), ShouldEqual, "15/03/2019")
+			So(fr.Date(dates.Date{}), ShouldBeEmpty)
+			So(fr.DateTime(dates.ParseDateTime("2019-03-15 10:30:00")), ShouldEqual, "15/03/2019 11:30:00")
+		})
+		Convey("Field values should be formatted according to their type", func() {
+			So(fr.Value(&models.FieldInfo{Type: fieldtype.Float}, 3.14159), ShouldEqual, "3,14")
+			So(fr.Value(&models.FieldInfo{Type: fieldtype.Float, Digits: nbutils.Digits{Precision: 6, Scale: 3}}, 3.14159), ShouldEqual, "3,142")
+			So(fr.Value(&models.FieldInfo{Type: fieldtype.Integer}, int64(12000)), ShouldEqual, "12 000")
+			So(fr.Value(&models.FieldInfo{Type: fieldtype.Selection, Selection: types.Selection{"draft": "Draft"}}, "draft"), ShouldEqual, "Draft")
+			So(fr.Value(&models.FieldInfo{Type: fieldtype.Char}, "Hello"), ShouldEqual, "Hello")
+			So(fr.Value(&models.FieldInfo{Type: fieldtype.Char}, nil), ShouldBeEmpty)
+		})
+	})
+}
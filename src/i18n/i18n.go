@@ -4,11 +4,14 @@
 package i18n
 
 import (
+	"net/http"
 	"sort"
 	"strings"
 
 	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/tools/assetfs"
 	"github.com/hexya-erp/hexya/src/tools/po"
+	"github.com/hexya-erp/hexya/src/tools/startup"
 )
 
 const fieldSep string = "."
@@ -24,6 +27,9 @@ type TranslationsCollection struct {
 	resource         map[resourceRef]string
 	code             map[codeRef]string
 	custom           map[customRef]string
+	// fallback is the collection in which translations that
+	// are not found in this collection are looked for.
+	fallback *TranslationsCollection
 }
 
 // TranslateFieldDescription returns the translation for the given model field
@@ -33,6 +39,9 @@ func (tc *TranslationsCollection) TranslateFieldDescription(lang, model, field,
 	key := fieldRef{lang: lang, model: model, field: field}
 	val, ok := tc.fieldDescription[key]
 	if !ok || val == "" {
+		if tc.fallback != nil {
+			return tc.fallback.TranslateFieldDescription(lang, model, field, defaultValue)
+		}
 		return defaultValue
 	}
 	return val
@@ -45,6 +54,9 @@ func (tc *TranslationsCollection) TranslateFieldHelp(lang, model, field, default
 	key := fieldRef{lang: lang, model: model, field: field}
 	val, ok := tc.fieldHelp[key]
 	if !ok || val == "" {
+		if tc.fallback != nil {
+			return tc.fallback.TranslateFieldHelp(lang, model, field, defaultValue)
+		}
 		return defaultValue
 	}
 	return val
@@ -55,17 +67,25 @@ func (tc *TranslationsCollection) TranslateFieldHelp(lang, model, field, default
 func (tc *TranslationsCollection) TranslateFieldSelection(lang, model, field string, selection types.Selection) types.Selection {
 	res := make(types.Selection)
 	for selKey, selItem := range selection {
-		key := selectionRef{lang: lang, model: model, field: field, source: selItem}
-		val, ok := tc.fieldSelection[key]
-		if !ok || val == "" {
-			res[selKey] = selItem
-			continue
-		}
-		res[selKey] = val
+		res[selKey] = tc.translateSelectionItem(lang, model, field, selItem)
 	}
 	return res
 }
 
+// translateSelectionItem returns the translation of the given selection item
+// in the given lang or the item itself if no translation is found.
+func (tc *TranslationsCollection) translateSelectionItem(lang, model, field, selItem string) string {
+	key := selectionRef{lang: lang, model: model, field: field, source: selItem}
+	val, ok := tc.fieldSelection[key]
+	if !ok || val == "" {
+		if tc.fallback != nil {
+			return tc.fallback.translateSelectionItem(lang, model, field, selItem)
+		}
+		return selItem
+	}
+	return val
+}
+
 // TranslateResourceItem returns the translation for the given src of the given resource
 // in the given lang. If no translation is found or if the translation is the
 // empty string src is returned.
@@ -73,6 +93,9 @@ func (tc *TranslationsCollection) TranslateResourceItem(lang, resourceID, src st
 	key := resourceRef{lang: lang, id: resourceID, source: src}
 	val, ok := tc.resource[key]
 	if !ok || val == "" {
+		if tc.fallback != nil {
+			return tc.fallback.TranslateResourceItem(lang, resourceID, src)
+		}
 		return src
 	}
 	return val
@@ -85,6 +108,9 @@ func (tc *TranslationsCollection) TranslateCode(lang, context, src string) strin
 	key := codeRef{lang: lang, context: context, source: src}
 	val, ok := tc.code[key]
 	if !ok || val == "" {
+		if tc.fallback != nil {
+			return tc.fallback.TranslateCode(lang, context, src)
+		}
 		return src
 	}
 	return val
@@ -97,6 +123,9 @@ func (tc *TranslationsCollection) TranslateCustom(lang, id, moduleName string) s
 	key := customRef{lang: lang, id: id, module: moduleName}
 	val, ok := tc.custom[key]
 	if !ok || val == "" {
+		if tc.fallback != nil {
+			return tc.fallback.TranslateCustom(lang, id, moduleName)
+		}
 		return id
 	}
 	return val
@@ -106,59 +135,84 @@ func (tc *TranslationsCollection) TranslateCustom(lang, id, moduleName string) s
 // This function can be called several times to iteratively load translations.
 // It panics in case of errors in the PO file.
 func (tc *TranslationsCollection) LoadPOFile(fileName string) {
+	tc.addPOFile(fileName, parsePOFile(fileName))
+}
+
+// LoadPOFiles loads the given PO files into this TranslationsCollection.
+// Files are parsed concurrently, then loaded in the given order so that
+// translations of later files override those of earlier ones.
+func (tc *TranslationsCollection) LoadPOFiles(fileNames []string) {
+	poFiles := make([]*po.File, len(fileNames))
+	startup.Each(len(fileNames), func(i int) {
+		poFiles[i] = parsePOFile(fileNames[i])
+	})
+	for i, poFile := range poFiles {
+		tc.addPOFile(fileNames[i], poFile)
+	}
+}
+
+// LoadPOFilesFS loads the given PO files of the given file system into
+// this TranslationsCollection. See LoadPOFiles.
+func (tc *TranslationsCollection) LoadPOFilesFS(fsys http.FileSystem, fileNames []string) {
+	poFiles := make([]*po.File, len(fileNames))
+	startup.Each(len(fileNames), func(i int) {
+		content, err := assetfs.ReadFile(fsys, fileNames[i])
+		if err != nil {
+			log.Panic("Error while reading PO file", "file", fileNames[i], "error", err)
+		}
+		poFiles[i], err = po.LoadData(content)
+		if err != nil {
+			log.Panic("Error while parsing PO file", "file", fileNames[i], "error", err)
+		}
+	})
+	for i, poFile := range poFiles {
+		tc.addPOFile(fileNames[i], poFile)
+	}
+}
+
+// parsePOFile parses the given PO file. It panics in case of error.
+func parsePOFile(fileName string) *po.File {
 	poFile, err := po.Load(fileName)
 	if err != nil {
 		log.Panic("Error while parsing PO file", "file", fileName, "error", err)
 	}
-	lang := poFile.MimeHeader.Language
-	if lang == "" {
-		log.Panic("Language should be specified in PO file header", "file", fileName)
-	}
-	for _, msg := range poFile.Messages {
-		for _, line := range strings.Split(msg.ExtractedComment, "\n") {
-			tokens := strings.Split(line, ":")
-			if len(tokens) != 2 {
-				log.Warn("Invalid format for PO comment. Should be '#. key:value'", "file", fileName, "line", msg.StartLine, "comment", line)
+	return poFile
+}
+
+// addPOFile adds the translations of the given parsed PO file
+// to this TranslationsCollection.
+func (tc *TranslationsCollection) addPOFile(fileName string, poFile *po.File) {
+	tc.AddTerms(POFileTerms(fileName, poFile))
+}
+
+// AddTerms adds the given terms to this TranslationsCollection. Terms of
+// the same kind with the same reference and source replace each other.
+// Terms with an invalid kind or reference are ignored.
+func (tc *TranslationsCollection) AddTerms(terms []Term) {
+	for _, term := range terms {
+		switch term.Kind {
+		case TermField, TermHelp, TermSelection:
+			r := strings.Split(term.Ref, fieldSep)
+			if len(r) != 2 {
+				log.Warn("Invalid field reference in translation term", "lang", term.Lang, "kind", term.Kind, "ref", term.Ref)
 				continue
 			}
-			switch tokens[0] {
-			case "field":
-				// #. field:Model.Field
-				meta := strings.Replace(tokens[1], " ", "", -1)
-				r := strings.Split(meta, fieldSep)
-				if len(r) != 2 {
-					log.Panic("Invalid format for PO comment. Field reference should be 'Model.Field'", "file", fileName, "line", msg.StartLine, "comment", line)
-				}
-				tc.fieldDescription[fieldRef{lang: lang, model: r[0], field: r[1]}] = msg.MsgStr
-			case "help":
-				// #. help:Model.Field
-				meta := strings.Replace(tokens[1], " ", "", -1)
-				r := strings.Split(meta, fieldSep)
-				if len(r) != 2 {
-					log.Panic("Invalid format for PO comment. Field reference should be 'Model.Field'", "file", fileName, "line", msg.StartLine, "comment", line)
-				}
-				tc.fieldHelp[fieldRef{lang: lang, model: r[0], field: r[1]}] = msg.MsgStr
-			case "selection":
-				// #. selection:Model.Field
-				meta := strings.Replace(tokens[1], " ", "", -1)
-				r := strings.Split(meta, fieldSep)
-				if len(r) != 2 {
-					log.Panic("Invalid format for PO comment. Field reference should be 'Model.Field'", "file", fileName, "line", msg.StartLine, "comment", line)
-				}
-				tc.fieldSelection[selectionRef{lang: lang, model: r[0], field: r[1], source: msg.MsgId}] = msg.MsgStr
-			case "resource":
-				// #. resource:my_view_id
-				viewID := strings.Replace(tokens[1], " ", "", -1)
-				tc.resource[resourceRef{lang: lang, id: viewID, source: msg.MsgId}] = msg.MsgStr
-			case "code":
-				// #. code:
-				// Translating code. Context may be given as msgctxt
-				tc.code[codeRef{lang: lang, context: msg.MsgContext, source: msg.MsgId}] = msg.MsgStr
-			case "custom":
-				// #. custom: moduleName
-				moduleName := strings.Replace(tokens[1], " ", "", -1)
-				tc.custom[customRef{lang: lang, id: msg.MsgId, module: moduleName}] = msg.MsgStr
+			switch term.Kind {
+			case TermField:
+				tc.fieldDescription[fieldRef{lang: term.Lang, model: r[0], field: r[1]}] = term.Value
+			case TermHelp:
+				tc.fieldHelp[fieldRef{lang: term.Lang, model: r[0], field: r[1]}] = term.Value
+			case TermSelection:
+				tc.fieldSelection[selectionRef{lang: term.Lang, model: r[0], field: r[1], source: term.Source}] = term.Value
 			}
+		case TermResource:
+			tc.resource[resourceRef{lang: term.Lang, id: term.Ref, source: term.Source}] = term.Value
+		case TermCode:
+			tc.code[codeRef{lang: term.Lang, context: term.Context, source: term.Source}] = term.Value
+		case TermCustom:
+			tc.custom[customRef{lang: term.Lang, id: term.Source, module: term.Ref}] = term.Value
+		default:
+			log.Warn("Unknown kind of translation term", "lang", term.Lang, "kind", term.Kind, "ref", term.Ref)
 		}
 	}
 }
@@ -266,6 +320,18 @@ func LoadPOFile(fileName string) {
 	Registry.LoadPOFile(fileName)
 }
 
+// LoadPOFiles loads the given PO files into the default translation Registry.
+// See TranslationsCollection.LoadPOFiles.
+func LoadPOFiles(fileNames []string) {
+	Registry.LoadPOFiles(fileNames)
+}
+
+// LoadPOFilesFS loads the given PO files of the given file system into the
+// default translation Registry. See TranslationsCollection.LoadPOFilesFS.
+func LoadPOFilesFS(fsys http.FileSystem, fileNames []string) {
+	Registry.LoadPOFilesFS(fsys, fileNames)
+}
+
 // GetAllCustomTranslations returns all custom translations by lang and by modules
 func GetAllCustomTranslations() map[string]map[string]map[string]string {
 	res := make(map[string]map[string]map[string]string)
@@ -18,12 +18,19 @@ var (
 
 // BootStrap initializes available languages
 func BootStrap() {
-	Langs = viper.GetStringSlice("Server.Languages")
-	for i, lang := range Langs {
+	Langs = ConfigLangs()
+}
+
+// ConfigLangs returns the languages set in the Server.Languages
+// configuration key, in which 'ALL' stands for all known languages.
+func ConfigLangs() []string {
+	langs := viper.GetStringSlice("Server.Languages")
+	for i, lang := range langs {
 		if strings.ToUpper(lang) == "ALL" {
-			Langs = append(Langs[:i], append(GetAllLanguageList(), Langs[i+1:]...)...)
+			langs = append(langs[:i], append(GetAllLanguageList(), langs[i+1:]...)...)
 		}
 	}
+	return langs
 }
 
 func init() {
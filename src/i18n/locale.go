@@ -7,11 +7,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hexya-erp/hexya/src/models/types/dates"
 	"github.com/hexya-erp/hexya/src/tools/nbutils"
+	"github.com/hexya-erp/hexya/src/tools/strutils"
 )
 
 // A Currency with symbol, position and decimals
@@ -63,6 +65,47 @@ func (nb NumberGrouping) MarshalJSON() ([]byte, error) {
 	return res.Bytes(), nil
 }
 
+// ParseNumberGrouping parses the given grouping given as
+// comma separated values, optionally between brackets, e.g. "[3,0]".
+func ParseNumberGrouping(grouping string) (NumberGrouping, error) {
+	grouping = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(grouping), "["), "]")
+	var res NumberGrouping
+	if grouping == "" {
+		return res, nil
+	}
+	for _, val := range strings.Split(grouping, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number grouping '%s'", grouping)
+		}
+		res = append(res, n)
+	}
+	return res, nil
+}
+
+// strftimeToGo maps strftime directives to Go time layout elements
+var strftimeToGo = map[string]string{
+	"%d": "02",
+	"%m": "01",
+	"%Y": "2006",
+	"%y": "06",
+	"%H": "15",
+	"%I": "03",
+	"%M": "04",
+	"%S": "05",
+	"%p": "PM",
+	"%b": "Jan",
+	"%B": "January",
+	"%A": "Monday",
+	"%a": "Mon",
+}
+
+// StrftimeToGo returns the Go time layout of the given strftime
+// format, as used in the DateFormat and TimeFormat of a Locale.
+func StrftimeToGo(format string) string {
+	return strutils.Substitute(format, strftimeToGo)
+}
+
 // Locale defines the parameters of a language locale
 type Locale struct {
 	Name         string         `json:"name"`
@@ -8,6 +8,7 @@ var locales = map[string]*Locale{
 		Name:         "English",
 		Code:         "en_US",
 		ISOCode:      "en",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%m/%d/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "01/02/2006",
@@ -20,6 +21,7 @@ var locales = map[string]*Locale{
 		Name:         "Albanian / Shqip",
 		Code:         "sq_AL",
 		ISOCode:      "sq",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y-%b-%d",
 		TimeFormat:   "%I.%M.%S.",
 		DateFormatGo: "2006-Jan-02",
@@ -32,6 +34,7 @@ var locales = map[string]*Locale{
 		Name:         "Amharic / አምሃርኛ",
 		Code:         "am_ET",
 		ISOCode:      "am_ET",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%I:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -44,6 +47,7 @@ var locales = map[string]*Locale{
 		Name:         "Arabic / الْعَرَبيّة",
 		Code:         "ar_SY",
 		ISOCode:      "ar",
+		Direction:    LangDirectionRTL,
 		DateFormat:   "%d %b, %Y",
 		TimeFormat:   "%I:%M:%S",
 		DateFormatGo: "02 Jan, 2006",
@@ -56,6 +60,7 @@ var locales = map[string]*Locale{
 		Name:         "Basque / Euskara",
 		Code:         "eu_ES",
 		ISOCode:      "eu_ES",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%a, %Y.eko %bren %da",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "Mon, 2006.eko Janren 02a",
@@ -68,6 +73,7 @@ var locales = map[string]*Locale{
 		Name:         "Bosnian / bosanski jezik",
 		Code:         "bs_BA",
 		ISOCode:      "bs",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -80,6 +86,7 @@ var locales = map[string]*Locale{
 		Name:         "Bulgarian / български език",
 		Code:         "bg_BG",
 		ISOCode:      "bg",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H,%M,%S",
 		DateFormatGo: "02.01.2006",
@@ -92,6 +99,7 @@ var locales = map[string]*Locale{
 		Name:         "Burmese / ဗမာစာ",
 		Code:         "my_MM",
 		ISOCode:      "my",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y %b %d %A",
 		TimeFormat:   "%I:%M:%S %p",
 		DateFormatGo: "2006 Jan 02 Monday",
@@ -104,6 +112,7 @@ var locales = map[string]*Locale{
 		Name:         "Catalan / Català",
 		Code:         "ca_ES",
 		ISOCode:      "ca_ES",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -116,6 +125,7 @@ var locales = map[string]*Locale{
 		Name:         "Chinese (HK)",
 		Code:         "zh_HK",
 		ISOCode:      "zh_HK",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y年%m月%d日 %A",
 		TimeFormat:   "%I時%M分%S秒",
 		DateFormatGo: "2006年01月02日 Monday",
@@ -128,6 +138,7 @@ var locales = map[string]*Locale{
 		Name:         "Chinese (Simplified) / 简体中文",
 		Code:         "zh_CN",
 		ISOCode:      "zh_CN",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y年%m月%d日",
 		TimeFormat:   "%H时%M分%S秒",
 		DateFormatGo: "2006年01月02日",
@@ -140,6 +151,7 @@ var locales = map[string]*Locale{
 		Name:         "Chinese (Traditional) / 正體字",
 		Code:         "zh_TW",
 		ISOCode:      "zh_TW",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y年%m月%d日",
 		TimeFormat:   "%H時%M分%S秒",
 		DateFormatGo: "2006年01月02日",
@@ -152,6 +164,7 @@ var locales = map[string]*Locale{
 		Name:         "Croatian / hrvatski jezik",
 		Code:         "hr_HR",
 		ISOCode:      "hr",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -164,6 +177,7 @@ var locales = map[string]*Locale{
 		Name:         "Czech / Čeština",
 		Code:         "cs_CZ",
 		ISOCode:      "cs_CZ",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -176,6 +190,7 @@ var locales = map[string]*Locale{
 		Name:         "Danish / Dansk",
 		Code:         "da_DK",
 		ISOCode:      "da_DK",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d-%m-%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02-01-2006",
@@ -188,6 +203,7 @@ var locales = map[string]*Locale{
 		Name:         "Dutch (BE) / Nederlands (BE)",
 		Code:         "nl_BE",
 		ISOCode:      "nl_BE",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d-%m-%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02-01-2006",
@@ -200,6 +216,7 @@ var locales = map[string]*Locale{
 		Name:         "Dutch / Nederlands",
 		Code:         "nl_NL",
 		ISOCode:      "nl",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d-%m-%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02-01-2006",
@@ -212,6 +229,7 @@ var locales = map[string]*Locale{
 		Name:         "English (AU)",
 		Code:         "en_AU",
 		ISOCode:      "en_AU",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -224,6 +242,7 @@ var locales = map[string]*Locale{
 		Name:         "English (UK)",
 		Code:         "en_GB",
 		ISOCode:      "en_GB",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -236,6 +255,7 @@ var locales = map[string]*Locale{
 		Name:         "Estonian / Eesti keel",
 		Code:         "et_EE",
 		ISOCode:      "et",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -248,6 +268,7 @@ var locales = map[string]*Locale{
 		Name:         "Finnish / Suomi",
 		Code:         "fi_FI",
 		ISOCode:      "fi",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H.%M.%S",
 		DateFormatGo: "02.01.2006",
@@ -260,6 +281,7 @@ var locales = map[string]*Locale{
 		Name:         "French (BE) / Français (BE)",
 		Code:         "fr_BE",
 		ISOCode:      "fr_BE",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -272,6 +294,7 @@ var locales = map[string]*Locale{
 		Name:         "French (CA) / Français (CA)",
 		Code:         "fr_CA",
 		ISOCode:      "fr_CA",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y-%m-%d",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "2006-01-02",
@@ -284,6 +307,7 @@ var locales = map[string]*Locale{
 		Name:         "French (CH) / Français (CH)",
 		Code:         "fr_CH",
 		ISOCode:      "fr_CH",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d. %m. %Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02. 01. 2006",
@@ -296,6 +320,7 @@ var locales = map[string]*Locale{
 		Name:         "French / Français",
 		Code:         "fr_FR",
 		ISOCode:      "fr",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -308,6 +333,7 @@ var locales = map[string]*Locale{
 		Name:         "Galician / Galego",
 		Code:         "gl_ES",
 		ISOCode:      "gl",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -320,6 +346,7 @@ var locales = map[string]*Locale{
 		Name:         "Georgian / ქართული ენა",
 		Code:         "ka_GE",
 		ISOCode:      "ka",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%m/%d/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "01/02/2006",
@@ -332,6 +359,7 @@ var locales = map[string]*Locale{
 		Name:         "German (CH) / Deutsch (CH)",
 		Code:         "de_CH",
 		ISOCode:      "de_CH",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -344,6 +372,7 @@ var locales = map[string]*Locale{
 		Name:         "German / Deutsch",
 		Code:         "de_DE",
 		ISOCode:      "de",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -356,6 +385,7 @@ var locales = map[string]*Locale{
 		Name:         "Greek / Ελληνικά",
 		Code:         "el_GR",
 		ISOCode:      "el_GR",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%I:%M:%S %p",
 		DateFormatGo: "02/01/2006",
@@ -368,6 +398,7 @@ var locales = map[string]*Locale{
 		Name:         "Gujarati / ગુજરાતી",
 		Code:         "gu_IN",
 		ISOCode:      "gu",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%A %d %b %Y",
 		TimeFormat:   "%I:%M:%S",
 		DateFormatGo: "Monday 02 Jan 2006",
@@ -380,6 +411,7 @@ var locales = map[string]*Locale{
 		Name:         "Hebrew / עִבְרִי",
 		Code:         "he_IL",
 		ISOCode:      "he",
+		Direction:    LangDirectionRTL,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -392,6 +424,7 @@ var locales = map[string]*Locale{
 		Name:         "Hindi / हिंदी",
 		Code:         "hi_IN",
 		ISOCode:      "hi",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%A %d %b %Y",
 		TimeFormat:   "%I:%M:%S",
 		DateFormatGo: "Monday 02 Jan 2006",
@@ -404,6 +437,7 @@ var locales = map[string]*Locale{
 		Name:         "Hungarian / Magyar",
 		Code:         "hu_HU",
 		ISOCode:      "hu",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y-%m-%d",
 		TimeFormat:   "%H.%M.%S",
 		DateFormatGo: "2006-01-02",
@@ -416,6 +450,7 @@ var locales = map[string]*Locale{
 		Name:         "Indonesian / Bahasa Indonesia",
 		Code:         "id_ID",
 		ISOCode:      "id",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -428,6 +463,7 @@ var locales = map[string]*Locale{
 		Name:         "Italian / Italiano",
 		Code:         "it_IT",
 		ISOCode:      "it",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -440,6 +476,7 @@ var locales = map[string]*Locale{
 		Name:         "Japanese / 日本語",
 		Code:         "ja_JP",
 		ISOCode:      "ja",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y年%m月%d日",
 		TimeFormat:   "%H時%M分%S秒",
 		DateFormatGo: "2006年01月02日",
@@ -452,6 +489,7 @@ var locales = map[string]*Locale{
 		Name:         "Kabyle / Taqbaylit",
 		Code:         "kab_DZ",
 		ISOCode:      "kab",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%m/%d/%Y",
 		TimeFormat:   "%I:%M:%S %p",
 		DateFormatGo: "01/02/2006",
@@ -464,6 +502,7 @@ var locales = map[string]*Locale{
 		Name:         "Khmer / ភាសាខ្មែរ",
 		Code:         "km_KH",
 		ISOCode:      "km",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d %B %y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02 January 06",
@@ -476,6 +515,7 @@ var locales = map[string]*Locale{
 		Name:         "Korean (KP) / 한국어 (KP)",
 		Code:         "ko_KP",
 		ISOCode:      "ko_KP",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%m/%d/%Y",
 		TimeFormat:   "%I:%M:%S %p",
 		DateFormatGo: "01/02/2006",
@@ -488,6 +528,7 @@ var locales = map[string]*Locale{
 		Name:         "Korean (KR) / 한국어 (KR)",
 		Code:         "ko_KR",
 		ISOCode:      "ko_KR",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y년 %m월 %d일",
 		TimeFormat:   "%H시 %M분 %S초",
 		DateFormatGo: "2006년 01월 02일",
@@ -500,6 +541,7 @@ var locales = map[string]*Locale{
 		Name:         "Lao / ພາສາລາວ",
 		Code:         "lo_LA",
 		ISOCode:      "lo",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/y",
@@ -512,6 +554,7 @@ var locales = map[string]*Locale{
 		Name:         "Latvian / latviešu valoda",
 		Code:         "lv_LV",
 		ISOCode:      "lv",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y.%m.%d.",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "2006.01.02.",
@@ -524,6 +567,7 @@ var locales = map[string]*Locale{
 		Name:         "Lithuanian / Lietuvių kalba",
 		Code:         "lt_LT",
 		ISOCode:      "lt",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y.%m.%d",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "2006.01.02",
@@ -536,6 +580,7 @@ var locales = map[string]*Locale{
 		Name:         "Macedonian / македонски јазик",
 		Code:         "mk_MK",
 		ISOCode:      "mk",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -548,6 +593,7 @@ var locales = map[string]*Locale{
 		Name:         "Mongolian / монгол",
 		Code:         "mn_MN",
 		ISOCode:      "mn",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y.%m.%d",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "2006.01.02",
@@ -560,6 +606,7 @@ var locales = map[string]*Locale{
 		Name:         "Norwegian Bokmål / Norsk bokmål",
 		Code:         "nb_NO",
 		ISOCode:      "nb_NO",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d. %b %Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02. Jan 2006",
@@ -572,6 +619,7 @@ var locales = map[string]*Locale{
 		Name:         "Persian / فارس",
 		Code:         "fa_IR",
 		ISOCode:      "fa",
+		Direction:    LangDirectionRTL,
 		DateFormat:   "%Y/%m/%d",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "2006/01/02",
@@ -584,6 +632,7 @@ var locales = map[string]*Locale{
 		Name:         "Polish / Język polski",
 		Code:         "pl_PL",
 		ISOCode:      "pl",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -596,6 +645,7 @@ var locales = map[string]*Locale{
 		Name:         "Portuguese (BR) / Português (BR)",
 		Code:         "pt_BR",
 		ISOCode:      "pt_BR",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -608,6 +658,7 @@ var locales = map[string]*Locale{
 		Name:         "Portuguese / Português",
 		Code:         "pt_PT",
 		ISOCode:      "pt",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d-%m-%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02-01-2006",
@@ -620,6 +671,7 @@ var locales = map[string]*Locale{
 		Name:         "Romanian / română",
 		Code:         "ro_RO",
 		ISOCode:      "ro",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -632,6 +684,7 @@ var locales = map[string]*Locale{
 		Name:         "Russian / русский язык",
 		Code:         "ru_RU",
 		ISOCode:      "ru",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -644,6 +697,7 @@ var locales = map[string]*Locale{
 		Name:         "Serbian (Cyrillic) / српски",
 		Code:         "sr_RS",
 		ISOCode:      "sr_RS",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y.",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006.",
@@ -656,6 +710,7 @@ var locales = map[string]*Locale{
 		Name:         "Serbian (Latin) / srpski",
 		Code:         "sr@latin",
 		ISOCode:      "sr@latin",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%m/%d/%Y",
 		TimeFormat:   "%I:%M:%S %p",
 		DateFormatGo: "01/02/2006",
@@ -668,6 +723,7 @@ var locales = map[string]*Locale{
 		Name:         "Slovak / Slovenský jazyk",
 		Code:         "sk_SK",
 		ISOCode:      "sk",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -680,6 +736,7 @@ var locales = map[string]*Locale{
 		Name:         "Slovenian / slovenščina",
 		Code:         "sl_SI",
 		ISOCode:      "sl",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d. %m. %Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02. 01. 2006",
@@ -692,6 +749,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (AR) / Español (AR)",
 		Code:         "es_AR",
 		ISOCode:      "es_AR",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -704,6 +762,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (BO) / Español (BO)",
 		Code:         "es_BO",
 		ISOCode:      "es_BO",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -716,6 +775,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (CL) / Español (CL)",
 		Code:         "es_CL",
 		ISOCode:      "es_CL",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -728,6 +788,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (CO) / Español (CO)",
 		Code:         "es_CO",
 		ISOCode:      "es_CO",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d-%m-%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02-01-2006",
@@ -740,6 +801,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (CR) / Español (CR)",
 		Code:         "es_CR",
 		ISOCode:      "es_CR",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -752,6 +814,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (DO) / Español (DO)",
 		Code:         "es_DO",
 		ISOCode:      "es_DO",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%I:%M:%S %p",
 		DateFormatGo: "02/01/2006",
@@ -764,6 +827,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (EC) / Español (EC)",
 		Code:         "es_EC",
 		ISOCode:      "es_EC",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -776,6 +840,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (GT) / Español (GT)",
 		Code:         "es_GT",
 		ISOCode:      "es_GT",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -788,6 +853,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (MX) / Español (MX)",
 		Code:         "es_MX",
 		ISOCode:      "es_MX",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -800,6 +866,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (PA) / Español (PA)",
 		Code:         "es_PA",
 		ISOCode:      "es_PA",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -812,6 +879,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (PE) / Español (PE)",
 		Code:         "es_PE",
 		ISOCode:      "es_PE",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -824,6 +892,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (PY) / Español (PY)",
 		Code:         "es_PY",
 		ISOCode:      "es_PY",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -836,6 +905,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (UY) / Español (UY)",
 		Code:         "es_UY",
 		ISOCode:      "es_UY",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -848,6 +918,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish (VE) / Español (VE)",
 		Code:         "es_VE",
 		ISOCode:      "es_VE",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -860,6 +931,7 @@ var locales = map[string]*Locale{
 		Name:         "Spanish / Español",
 		Code:         "es_ES",
 		ISOCode:      "es",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -872,6 +944,7 @@ var locales = map[string]*Locale{
 		Name:         "Swedish / svenska",
 		Code:         "sv_SE",
 		ISOCode:      "sv",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%Y-%m-%d",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "2006-01-02",
@@ -884,6 +957,7 @@ var locales = map[string]*Locale{
 		Name:         "Telugu / తెలుగు",
 		Code:         "te_IN",
 		ISOCode:      "te",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%B %d %A %Y",
 		TimeFormat:   "%p%I.%M.%S",
 		DateFormatGo: "January 02 Monday 2006",
@@ -896,6 +970,7 @@ var locales = map[string]*Locale{
 		Name:         "Thai / ภาษาไทย",
 		Code:         "th_TH",
 		ISOCode:      "th",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -908,6 +983,7 @@ var locales = map[string]*Locale{
 		Name:         "Turkish / Türkçe",
 		Code:         "tr_TR",
 		ISOCode:      "tr",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d-%m-%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02-01-2006",
@@ -920,6 +996,7 @@ var locales = map[string]*Locale{
 		Name:         "Ukrainian / українська",
 		Code:         "uk_UA",
 		ISOCode:      "uk",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d.%m.%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02.01.2006",
@@ -932,6 +1009,7 @@ var locales = map[string]*Locale{
 		Name:         "Vietnamese / Tiếng Việt",
 		Code:         "vi_VN",
 		ISOCode:      "vi",
+		Direction:    LangDirectionLTR,
 		DateFormat:   "%d/%m/%Y",
 		TimeFormat:   "%H:%M:%S",
 		DateFormatGo: "02/01/2006",
@@ -86,4 +86,25 @@ func TestLocale(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(string(b), ShouldEqual, `"[1,2,3]"`)
 	})
+	Convey("Testing number grouping parsing", t, func() {
+		grouping, err := ParseNumberGrouping("[3, 2,0]")
+		So(err, ShouldBeNil)
+		So(grouping, ShouldResemble, NumberGrouping{3, 2, 0})
+		grouping, err = ParseNumberGrouping("3")
+		So(err, ShouldBeNil)
+		So(grouping, ShouldResemble, NumberGrouping{3})
+		_, err = ParseNumberGrouping("[3,a]")
+		So(err, ShouldNotBeNil)
+		_, err = ParseNumberGrouping("[-1]")
+		So(err, ShouldNotBeNil)
+	})
+	Convey("Testing locales direction and formats", t, func() {
+		So(GetLocale("ar").Direction, ShouldEqual, LangDirectionRTL)
+		So(GetLocale("he").Direction, ShouldEqual, LangDirectionRTL)
+		So(GetLocale("fr").Direction, ShouldEqual, LangDirectionLTR)
+		for _, lang := range GetAllLanguageList() {
+			So(GetLocale(lang).Check(), ShouldBeNil)
+		}
+		So(StrftimeToGo("%d/%m/%Y %H:%M:%S"), ShouldEqual, "02/01/2006 15:04:05")
+	})
 }
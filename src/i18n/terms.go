@@ -0,0 +1,143 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package i18n
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/tools/po"
+)
+
+// Kinds of translation terms. They are the keys of the
+// '#. key:value' comments of the PO files.
+const (
+	TermField     = "field"
+	TermHelp      = "help"
+	TermSelection = "selection"
+	TermResource  = "resource"
+	TermCode      = "code"
+	TermCustom    = "custom"
+)
+
+// A Term is the translation of a source string in a language
+type Term struct {
+	Lang string
+	Kind string
+	// Ref is 'Model.Field' for field, help and selection terms,
+	// the resource ID for resource terms and the module name
+	// for custom terms. It is empty for code terms.
+	Ref     string
+	Context string
+	Source  string
+	Value   string
+}
+
+// POFileTerms returns the terms of the given parsed PO file.
+// fileName is only used to report errors.
+// It panics if the language of the file is not set or if a
+// field reference is invalid.
+func POFileTerms(fileName string, poFile *po.File) []Term {
+	lang := poFile.MimeHeader.Language
+	if lang == "" {
+		log.Panic("Language should be specified in PO file header", "file", fileName)
+	}
+	var res []Term
+	for _, msg := range poFile.Messages {
+		for _, line := range strings.Split(msg.ExtractedComment, "\n") {
+			tokens := strings.Split(line, ":")
+			if len(tokens) != 2 {
+				log.Warn("Invalid format for PO comment. Should be '#. key:value'", "file", fileName, "line", msg.StartLine, "comment", line)
+				continue
+			}
+			term := Term{Lang: lang, Kind: tokens[0], Source: msg.MsgId, Value: msg.MsgStr}
+			switch tokens[0] {
+			case TermField, TermHelp, TermSelection:
+				// #. field:Model.Field
+				term.Ref = strings.Replace(tokens[1], " ", "", -1)
+				if len(strings.Split(term.Ref, fieldSep)) != 2 {
+					log.Panic("Invalid format for PO comment. Field reference should be 'Model.Field'", "file", fileName, "line", msg.StartLine, "comment", line)
+				}
+			case TermResource, TermCustom:
+				// #. resource:my_view_id
+				// #. custom: moduleName
+				term.Ref = strings.Replace(tokens[1], " ", "", -1)
+			case TermCode:
+				// #. code:
+				// Translating code. Context may be given as msgctxt
+				term.Context = msg.MsgContext
+			default:
+				continue
+			}
+			res = append(res, term)
+		}
+	}
+	return res
+}
+
+// A TermsLoader returns the translation terms of the given language
+// stored in the given database.
+type TermsLoader func(db, lang string) []Term
+
+// A dbLang references the translations of a language in a database
+type dbLang struct {
+	db   string
+	lang string
+}
+
+// dbTranslations holds the translations loaded from the databases
+var dbTranslations struct {
+	sync.Mutex
+	loader      TermsLoader
+	collections map[dbLang]*TranslationsCollection
+}
+
+// SetTermsLoader sets the function used by ForDatabase to load
+// the translations of a database, and empties the translations
+// cache of all databases.
+func SetTermsLoader(loader TermsLoader) {
+	dbTranslations.Lock()
+	defer dbTranslations.Unlock()
+	dbTranslations.loader = loader
+	dbTranslations.collections = make(map[dbLang]*TranslationsCollection)
+}
+
+// ForDatabase returns the translations of the given language in the given
+// database. Translations not found in the database are looked for in the
+// Registry.
+//
+// Terms are loaded with the loader set by SetTermsLoader at the first call
+// for a database and a language, then kept in memory until they are
+// invalidated with InvalidateDatabase. The Registry is returned if no loader
+// is set.
+func ForDatabase(db, lang string) *TranslationsCollection {
+	dbTranslations.Lock()
+	defer dbTranslations.Unlock()
+	if dbTranslations.loader == nil || lang == "" {
+		return Registry
+	}
+	key := dbLang{db: db, lang: lang}
+	if tc, ok := dbTranslations.collections[key]; ok {
+		return tc
+	}
+	tc := NewTranslationsCollection()
+	tc.fallback = Registry
+	tc.AddTerms(dbTranslations.loader(db, lang))
+	dbTranslations.collections[key] = tc
+	return tc
+}
+
+// InvalidateDatabase removes the translations of the given language of
+// the given database from the cache, so that they are loaded again at the
+// next call to ForDatabase. If lang is empty, the translations of all
+// languages of the database are removed.
+func InvalidateDatabase(db, lang string) {
+	dbTranslations.Lock()
+	defer dbTranslations.Unlock()
+	for key := range dbTranslations.collections {
+		if key.db == db && (lang == "" || key.lang == lang) {
+			delete(dbTranslations.collections, key)
+		}
+	}
+}
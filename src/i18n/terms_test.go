@@ -0,0 +1,51 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package i18n
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/tools/po"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDatabaseTranslations(t *testing.T) {
+	Convey("Testing database translations", t, func() {
+		Convey("Terms should be read from PO files", func() {
+			poFile, err := po.Load("testdata/fr.po")
+			So(err, ShouldBeNil)
+			terms := POFileTerms("testdata/fr.po", poFile)
+			So(terms, ShouldContain, Term{Lang: "fr", Kind: TermField, Ref: "User.Active", Source: "Active", Value: "Actif"})
+			So(terms, ShouldContain, Term{Lang: "fr", Kind: TermCustom, Ref: "testModule", Source: "Create", Value: "Créer"})
+		})
+		Convey("Terms should be loaded lazily per database and cached", func() {
+			Registry.AddTerms([]Term{
+				{Lang: "fr", Kind: TermCode, Source: "Registry only", Value: "Registre seulement"},
+				{Lang: "fr", Kind: TermCode, Source: "Overridden", Value: "Registre"},
+			})
+			calls := make(map[string]int)
+			SetTermsLoader(func(db, lang string) []Term {
+				calls[db]++
+				if db != "acme" {
+					return nil
+				}
+				return []Term{{Lang: lang, Kind: TermCode, Source: "Overridden", Value: "Base acme"}}
+			})
+			defer SetTermsLoader(nil)
+			So(ForDatabase("acme", "fr").TranslateCode("fr", "", "Overridden"), ShouldEqual, "Base acme")
+			So(ForDatabase("acme", "fr").TranslateCode("fr", "", "Registry only"), ShouldEqual, "Registre seulement")
+			So(ForDatabase("other", "fr").TranslateCode("fr", "", "Overridden"), ShouldEqual, "Registre")
+			So(ForDatabase("acme", "fr").TranslateCode("fr", "", "Unknown"), ShouldEqual, "Unknown")
+			So(calls, ShouldResemble, map[string]int{"acme": 1, "other": 1})
+			InvalidateDatabase("acme", "")
+			ForDatabase("acme", "fr")
+			ForDatabase("other", "fr")
+			So(calls, ShouldResemble, map[string]int{"acme": 2, "other": 1})
+			So(ForDatabase("acme", ""), ShouldEqual, Registry)
+		})
+		Convey("Without loader, the registry should be used", func() {
+			So(ForDatabase("acme", "fr"), ShouldEqual, Registry)
+		})
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package languages is a Hexya module that manages the languages
+// installed in each database.
+//
+// Activating a Language record imports the terms of the PO files of the
+// activated modules for this language into the Translation model. The
+// translations of a database are then resolved from these records, which
+// are loaded lazily in memory by the i18n package (see i18n.ForDatabase),
+// so that a language can be added to a database without redeploying the
+// application. Terms that are not found in the database fall back to the
+// PO files loaded at startup.
+//
+// Each Language record also holds the direction and the formatting
+// parameters of the language in the database, initialized from the
+// built-in locale of the language. They are used to format values (see the
+// format package) and are sent to the clients.
+//
+// The languages of the Server.Languages configuration are activated when
+// the database is updated, and the terms of the active languages are
+// imported again so that they follow the updates of the modules.
+package languages
+
+import (
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/i18n/format"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "languages"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("languages")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+		PreInit: func() {
+			if format.GetLangLocale == nil {
+				format.GetLangLocale = languageLocale
+			}
+		},
+	})
+	i18n.SetTermsLoader(loadTerms)
+}
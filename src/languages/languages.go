@@ -0,0 +1,200 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package languages
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/assetfs"
+	"github.com/hexya-erp/hexya/src/tools/po"
+)
+
+// Update creates the Language records of the known languages of the
+// database of env that do not exist yet, activates the languages of the
+// configuration and imports again the terms of the active languages.
+func Update(env models.Environment) {
+	languages := env.Pool("Language").Sudo()
+	mi := languages.Model()
+	existing := make(map[string]bool)
+	for _, rec := range languages.SearchAll().Records() {
+		code := rec.Get(mi.FieldName("Code")).(string)
+		existing[code] = rec.Get(mi.FieldName("Active")).(bool)
+		if rec.Get(mi.FieldName("Direction")).(string) == "" {
+			// Language created before the locale fields
+			rec.Call("Write", localeData(mi, code))
+		}
+	}
+	for _, code := range i18n.GetAllLanguageList() {
+		if _, ok := existing[code]; ok {
+			continue
+		}
+		languages.Call("Create", localeData(mi, code).
+			Set(mi.FieldName("Code"), code).
+			Set(mi.FieldName("Name"), i18n.GetLocale(code).Name))
+		existing[code] = false
+	}
+	for _, code := range i18n.ConfigLangs() {
+		existing[code] = true
+	}
+	for _, code := range i18n.GetAllLanguageList() {
+		if existing[code] {
+			ImportTerms(env, code)
+		}
+	}
+}
+
+// localeData returns the values of the locale fields of a Language
+// record, initialized from the built-in locale of the given language.
+func localeData(mi *models.Model, code string) *models.ModelData {
+	locale := i18n.GetLocale(code)
+	grouping, _ := locale.Grouping.MarshalJSON()
+	return models.NewModelData(mi).
+		Set(mi.FieldName("Direction"), string(locale.Direction)).
+		Set(mi.FieldName("DateFormat"), locale.DateFormat).
+		Set(mi.FieldName("TimeFormat"), locale.TimeFormat).
+		Set(mi.FieldName("DecimalPoint"), locale.DecimalPoint).
+		Set(mi.FieldName("ThousandsSep"), locale.ThousandsSep).
+		Set(mi.FieldName("Grouping"), strings.Trim(string(grouping), `"`)).
+		Set(mi.FieldName("WeekStart"), int64(locale.WeekStart))
+}
+
+// languageLocale returns the locale of the given language in the database
+// of env: the built-in locale of the language with the formatting parameters
+// of its Language record. It returns nil if there is no such record.
+func languageLocale(env models.Environment, lang string) *i18n.Locale {
+	languages := env.Pool("Language").Sudo()
+	mi := languages.Model()
+	language := languages.Search(mi.Field(mi.FieldName("Code")).Equals(lang)).Limit(1)
+	if language.IsEmpty() {
+		return nil
+	}
+	locale := *i18n.GetLocale(lang)
+	if direction := language.Get(mi.FieldName("Direction")).(string); direction != "" {
+		locale.Direction = i18n.LangDirection(direction)
+	}
+	if dateFormat := language.Get(mi.FieldName("DateFormat")).(string); dateFormat != "" {
+		locale.DateFormat = dateFormat
+		locale.DateFormatGo = i18n.StrftimeToGo(dateFormat)
+	}
+	if timeFormat := language.Get(mi.FieldName("TimeFormat")).(string); timeFormat != "" {
+		locale.TimeFormat = timeFormat
+		locale.TimeFormatGo = i18n.StrftimeToGo(timeFormat)
+	}
+	if decimalPoint := language.Get(mi.FieldName("DecimalPoint")).(string); decimalPoint != "" {
+		locale.DecimalPoint = decimalPoint
+	}
+	if thousandsSep := language.Get(mi.FieldName("ThousandsSep")).(string); thousandsSep != "" {
+		locale.ThousandsSep = thousandsSep
+	}
+	if grouping, err := i18n.ParseNumberGrouping(language.Get(mi.FieldName("Grouping")).(string)); err == nil && grouping != nil {
+		locale.Grouping = grouping
+	}
+	locale.WeekStart = time.Weekday(language.Get(mi.FieldName("WeekStart")).(int64))
+	return &locale
+}
+
+// ImportTerms imports the terms of the PO files of the activated modules
+// for the given language into the Translation records of the database of
+// env, replacing the existing ones, and activates the language.
+//
+// Translations of the language are loaded again from the database at the
+// next request.
+func ImportTerms(env models.Environment, lang string) {
+	languages := env.Pool("Language").Sudo()
+	mi := languages.Model()
+	language := languages.Search(mi.Field(mi.FieldName("Code")).Equals(lang))
+	if language.IsEmpty() {
+		log.Panic("Unknown language", "lang", lang)
+	}
+	deleteTerms(env, lang)
+	translations := env.Pool("Translation").Sudo()
+	tmi := translations.Model()
+	terms := poTerms(lang)
+	for _, term := range terms {
+		translations.Call("Create", models.NewModelData(tmi).
+			Set(tmi.FieldName("Lang"), term.Lang).
+			Set(tmi.FieldName("Kind"), term.Kind).
+			Set(tmi.FieldName("Ref"), term.Ref).
+			Set(tmi.FieldName("Context"), term.Context).
+			Set(tmi.FieldName("Source"), term.Source).
+			Set(tmi.FieldName("Value"), term.Value))
+	}
+	language.Call("Write", models.NewModelData(mi).
+		Set(mi.FieldName("Active"), true).
+		Set(mi.FieldName("LoadDate"), dates.Now()))
+	i18n.InvalidateDatabase(env.DBName(), lang)
+	log.Info("Language terms imported", "database", env.DBName(), "lang", lang, "terms", len(terms))
+}
+
+// RemoveTerms removes the Translation records of the given language
+// from the database of env and deactivates the language.
+func RemoveTerms(env models.Environment, lang string) {
+	languages := env.Pool("Language").Sudo()
+	mi := languages.Model()
+	deleteTerms(env, lang)
+	languages.Search(mi.Field(mi.FieldName("Code")).Equals(lang)).Call("Write",
+		models.NewModelData(mi).Set(mi.FieldName("Active"), false))
+	i18n.InvalidateDatabase(env.DBName(), lang)
+}
+
+// deleteTerms deletes the Translation records of the given language
+func deleteTerms(env models.Environment, lang string) {
+	translations := env.Pool("Translation").Sudo()
+	tmi := translations.Model()
+	existing := translations.Search(tmi.Field(tmi.FieldName("Lang")).Equals(lang))
+	if !existing.IsEmpty() {
+		existing.Call("Unlink")
+	}
+}
+
+// poTerms returns the terms of the PO files of the activated
+// modules for the given language, in the order of the modules.
+func poTerms(lang string) []i18n.Term {
+	fsys := assetfs.Resources(server.ResourceDir)
+	var res []i18n.Term
+	for _, mod := range server.Modules {
+		fileName := path.Join("i18n", mod.Name, lang+".po")
+		content, err := assetfs.ReadFile(fsys, fileName)
+		if err != nil {
+			continue
+		}
+		poFile, err := po.LoadData(content)
+		if err != nil {
+			log.Panic("Error while parsing PO file", "file", fileName, "error", err)
+		}
+		res = append(res, i18n.POFileTerms(fileName, poFile)...)
+	}
+	return res
+}
+
+// loadTerms is the i18n.TermsLoader that reads the terms
+// of the given language from the given database.
+func loadTerms(db, lang string) []i18n.Term {
+	var res []i18n.Term
+	err := models.ExecuteInTenantEnvironment(db, security.SuperUserID, func(env models.Environment) {
+		translations := env.Pool("Translation")
+		mi := translations.Model()
+		for _, rec := range translations.Search(mi.Field(mi.FieldName("Lang")).Equals(lang)).Records() {
+			res = append(res, i18n.Term{
+				Lang:    lang,
+				Kind:    rec.Get(mi.FieldName("Kind")).(string),
+				Ref:     rec.Get(mi.FieldName("Ref")).(string),
+				Context: rec.Get(mi.FieldName("Context")).(string),
+				Source:  rec.Get(mi.FieldName("Source")).(string),
+				Value:   rec.Get(mi.FieldName("Value")).(string),
+			})
+		}
+	})
+	if err != nil {
+		log.Warn("Unable to load translations from database", "database", db, "lang", lang, "error", err)
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package languages
+
+import (
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+func declareModels() {
+	language := models.NewModel("Language")
+	language.SetDefaultOrder("Name")
+	language.NewMethod("Init", language_Init)
+	language.NewMethod("Activate", language_Activate)
+	language.NewMethod("Deactivate", language_Deactivate)
+	language.NewMethod("CheckGrouping", language_CheckGrouping)
+	language.AddFields(map[string]models.FieldDefinition{
+		"Code": fields.Char{Required: true, Unique: true, ReadOnly: true,
+			Help: "ISO code of the language, as in the PO file names"},
+		"Name":   fields.Char{Required: true},
+		"Active": fields.Boolean{ReadOnly: true},
+		"LoadDate": fields.DateTime{ReadOnly: true,
+			Help: "Last time the terms of this language were imported"},
+		"Direction": fields.Selection{Selection: types.Selection{
+			string(i18n.LangDirectionLTR): "Left-to-Right",
+			string(i18n.LangDirectionRTL): "Right-to-Left",
+		}},
+		"DateFormat":   fields.Char{Help: "Date format with strftime directives, e.g. %m/%d/%Y"},
+		"TimeFormat":   fields.Char{Help: "Time format with strftime directives, e.g. %H:%M:%S"},
+		"DecimalPoint": fields.Char{},
+		"ThousandsSep": fields.Char{String: "Thousands Separator"},
+		"Grouping": fields.Char{Constraint: language.Methods().MustGet("CheckGrouping"),
+			Help: "Sizes of the groups of digits from right to left, e.g. [3,0]. A trailing 0 repeats the previous size"},
+		"WeekStart": fields.Integer{Help: "First day of the week, from 0 (Sunday) to 6 (Saturday)"},
+	})
+
+	translation := models.NewModel("Translation")
+	translation.SetDefaultOrder("Lang", "ID")
+	translation.AddFields(map[string]models.FieldDefinition{
+		"Lang": fields.Char{Required: true, Index: true},
+		"Kind": fields.Selection{Required: true, Selection: types.Selection{
+			i18n.TermField:     "Field Description",
+			i18n.TermHelp:      "Field Help",
+			i18n.TermSelection: "Selection Item",
+			i18n.TermResource:  "Resource",
+			i18n.TermCode:      "Code",
+			i18n.TermCustom:    "Custom",
+		}},
+		"Ref":     fields.Char{Help: "Model.Field for fields, ID of the resource or name of the module for custom terms"},
+		"Context": fields.Char{},
+		"Source":  fields.Text{Required: true},
+		"Value":   fields.Text{},
+	})
+}
+
+// language_Init creates the known languages and imports the terms of the
+// configured and active languages.
+func language_Init(rs *models.RecordCollection) {
+	Update(rs.Env())
+}
+
+// language_Activate imports the terms of the languages of rs
+// and activates them.
+func language_Activate(rs *models.RecordCollection) bool {
+	for _, rec := range rs.Records() {
+		ImportTerms(rs.Env(), rec.Get(rec.Model().FieldName("Code")).(string))
+	}
+	return true
+}
+
+// language_Deactivate deactivates the languages of rs
+// and removes their terms.
+func language_Deactivate(rs *models.RecordCollection) bool {
+	for _, rec := range rs.Records() {
+		RemoveTerms(rs.Env(), rec.Get(rec.Model().FieldName("Code")).(string))
+	}
+	return true
+}
+
+// language_CheckGrouping checks that the grouping of the languages of rs is valid
+func language_CheckGrouping(rs *models.RecordCollection) {
+	for _, rec := range rs.Records() {
+		grouping := rec.Get(rec.Model().FieldName("Grouping")).(string)
+		if _, err := i18n.ParseNumberGrouping(grouping); err != nil {
+			log.Panic("Invalid grouping of language", "lang", rec.Get(rec.Model().FieldName("Code")), "error", err)
+		}
+	}
+}
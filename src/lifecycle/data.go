@@ -0,0 +1,84 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package lifecycle
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// updateModuleData records in the database the data records contributed by
+// each installed module through its data and demo files, so that they can be
+// removed when the module is uninstalled, even if its files are not
+// available anymore.
+func updateModuleData(env models.Environment) {
+	if server.ResourceDir == "" {
+		return
+	}
+	moduleData := env.Pool("ModuleData").Sudo()
+	mi := moduleData.Model()
+	for _, mod := range server.Modules {
+		records, err := server.ModuleDataRecords(server.ResourceDir, mod)
+		if err != nil {
+			log.Panic("Unable to read module data records", "module", mod.Name, "error", err)
+		}
+		existing := make(map[server.DataRecord]bool)
+		for _, rec := range moduleData.Search(mi.Field(mi.FieldName("Module")).Equals(mod.Name)).Records() {
+			existing[server.DataRecord{
+				Model:      rec.Get(mi.FieldName("Model")).(string),
+				ExternalID: rec.Get(mi.FieldName("ExternalID")).(string),
+			}] = true
+		}
+		for _, record := range records {
+			if existing[record] {
+				continue
+			}
+			existing[record] = true
+			moduleData.Call("Create", models.NewModelData(mi).
+				Set(mi.FieldName("Module"), mod.Name).
+				Set(mi.FieldName("Model"), record.Model).
+				Set(mi.FieldName("ExternalID"), record.ExternalID))
+		}
+	}
+}
+
+// removeModuleData is an uninstall hook that deletes the data records
+// contributed by the given module, in the reverse order of their creation.
+//
+// Records that are also contributed by an installed module are kept, as well
+// as records of models that do not exist anymore.
+func removeModuleData(env models.Environment, module string) {
+	moduleData := env.Pool("ModuleData").Sudo()
+	mi := moduleData.Model()
+	kept := make(map[server.DataRecord]bool)
+	others := moduleData.Search(mi.Field(mi.FieldName("Module")).In(server.Modules.Names()))
+	for _, rec := range others.Records() {
+		kept[server.DataRecord{
+			Model:      rec.Get(mi.FieldName("Model")).(string),
+			ExternalID: rec.Get(mi.FieldName("ExternalID")).(string),
+		}] = true
+	}
+	contributed := moduleData.Search(mi.Field(mi.FieldName("Module")).Equals(module)).OrderBy("ID desc")
+	for _, rec := range contributed.Records() {
+		record := server.DataRecord{
+			Model:      rec.Get(mi.FieldName("Model")).(string),
+			ExternalID: rec.Get(mi.FieldName("ExternalID")).(string),
+		}
+		if kept[record] {
+			continue
+		}
+		model, ok := models.Registry.Get(record.Model)
+		if !ok {
+			log.Warn("Unable to remove module record of unknown model", "module", module, "model", record.Model, "externalID", record.ExternalID)
+			continue
+		}
+		// We call Search directly without Call to find archived records too
+		toRemove := env.Pool(model.Name()).Sudo().Search(model.Field(model.FieldName("HexyaExternalID")).Equals(record.ExternalID))
+		if !toRemove.IsEmpty() {
+			log.Debug("Removing module record", "module", module, "model", record.Model, "externalID", record.ExternalID)
+			toRemove.Call("Unlink")
+		}
+	}
+	contributed.Call("Unlink")
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package lifecycle is a Hexya module that records in the database the
+// modules installed in it, so that the changes of the module list of the
+// application can be detected each time the database is updated.
+//
+// A module is installed when it is part of the activated modules of the
+// application (see server.Modules), which includes the auto install modules
+// whose dependencies are all installed. A module that was installed and is
+// not activated anymore is uninstalled, and the uninstall hooks registered
+// with RegisterUninstallHook are run for it.
+//
+// The records created by the data and demo files of each installed module
+// are recorded with their external IDs in the ModuleData model. They are
+// deleted when the module is uninstalled, unless an installed module also
+// defines them. The views, actions and menus of a module are only loaded
+// when the module is activated, so that they disappear together with the
+// extensions of the views of other modules when it is uninstalled (see also
+// server.UnloadModule).
+package lifecycle
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "lifecycle"
+)
+
+// States of the modules
+const (
+	StateInstalled   = "installed"
+	StateUninstalled = "uninstalled"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("lifecycle")
+	declareModels()
+	RegisterUninstallHook(removeModuleData)
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
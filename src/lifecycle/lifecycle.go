@@ -0,0 +1,127 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package lifecycle
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// An UninstallHook is a function called when a module is uninstalled
+// from the database of env.
+type UninstallHook func(env models.Environment, module string)
+
+var uninstallHooks struct {
+	sync.RWMutex
+	hooks []UninstallHook
+}
+
+// RegisterUninstallHook registers the given hook to be called for
+// each module that is uninstalled when the database is updated.
+func RegisterUninstallHook(hook UninstallHook) {
+	uninstallHooks.Lock()
+	defer uninstallHooks.Unlock()
+	uninstallHooks.hooks = append(uninstallHooks.hooks, hook)
+}
+
+// runUninstallHooks calls the uninstall hooks for the given module
+func runUninstallHooks(env models.Environment, module string) {
+	uninstallHooks.RLock()
+	hooks := append([]UninstallHook(nil), uninstallHooks.hooks...)
+	uninstallHooks.RUnlock()
+	for _, hook := range hooks {
+		hook(env, module)
+	}
+}
+
+// A moduleState is the state a module must have in the database
+type moduleState struct {
+	module      *server.Module
+	state       string
+	autoInstall bool
+}
+
+// moduleStates returns the state each module of the application
+// must have in the database, in the order of the modules.
+func moduleStates() []moduleState {
+	var res []moduleState
+	for _, mod := range server.Modules {
+		res = append(res, moduleState{module: mod, state: StateInstalled, autoInstall: mod.Manifest != nil && mod.Manifest.AutoInstall})
+	}
+	for _, mod := range server.UninstalledModules {
+		res = append(res, moduleState{module: mod, state: StateUninstalled, autoInstall: true})
+	}
+	return res
+}
+
+// Update updates the ModuleInfo records of the database of env from the
+// modules of the application:
+//
+// - activated modules are marked as installed,
+// - auto install modules whose dependencies are not all installed are
+// marked as not installed,
+// - modules that were installed and are not part of the application
+// anymore are marked as not installed.
+//
+// The data records contributed by the installed modules are recorded, then
+// the uninstall hooks are called for each module that was installed and is
+// not anymore.
+func Update(env models.Environment) {
+	updateModuleData(env)
+	infos := env.Pool("ModuleInfo").Sudo()
+	mi := infos.Model()
+	existing := make(map[string]*models.RecordCollection)
+	for _, rec := range infos.SearchAll().Records() {
+		existing[rec.Get(mi.FieldName("Name")).(string)] = rec
+	}
+	var uninstalled []string
+	for _, change := range moduleStates() {
+		name := change.module.Name
+		data := models.NewModelData(mi).
+			Set(mi.FieldName("Name"), name).
+			Set(mi.FieldName("State"), change.state).
+			Set(mi.FieldName("AutoInstall"), change.autoInstall)
+		if manifest := change.module.Manifest; manifest != nil {
+			data.Set(mi.FieldName("Version"), manifest.Version).
+				Set(mi.FieldName("Summary"), manifest.Summary).
+				Set(mi.FieldName("Depends"), strings.Join(manifest.Depends, ","))
+		}
+		rec, ok := existing[name]
+		delete(existing, name)
+		wasInstalled := ok && rec.Get(mi.FieldName("State")).(string) == StateInstalled
+		switch {
+		case change.state == StateInstalled && !wasInstalled:
+			data.Set(mi.FieldName("InstallDate"), dates.Now())
+			log.Info("Installing module", "module", name, "autoInstall", change.autoInstall)
+		case change.state == StateUninstalled && wasInstalled:
+			uninstalled = append(uninstalled, name)
+		}
+		if ok {
+			rec.Call("Write", data)
+			continue
+		}
+		infos.Call("Create", data)
+	}
+	removed := make([]string, 0, len(existing))
+	for name := range existing {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		rec := existing[name]
+		if rec.Get(mi.FieldName("State")).(string) == StateInstalled {
+			uninstalled = append(uninstalled, name)
+		}
+		rec.Call("Write", models.NewModelData(mi).Set(mi.FieldName("State"), StateUninstalled))
+	}
+	for _, name := range uninstalled {
+		log.Info("Uninstalling module", "module", name)
+		runUninstallHooks(env, name)
+	}
+}
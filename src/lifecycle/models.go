@@ -0,0 +1,44 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package lifecycle
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+func declareModels() {
+	moduleInfo := models.NewModel("ModuleInfo")
+	moduleInfo.SetDefaultOrder("Name")
+	moduleInfo.NewMethod("Init", moduleInfo_Init)
+	moduleInfo.AddFields(map[string]models.FieldDefinition{
+		"Name":    fields.Char{Required: true, Unique: true, ReadOnly: true},
+		"Version": fields.Char{ReadOnly: true},
+		"Summary": fields.Char{ReadOnly: true},
+		"Depends": fields.Char{ReadOnly: true,
+			Help: "Comma separated names of the modules this module depends on"},
+		"AutoInstall": fields.Boolean{ReadOnly: true,
+			Help: "Auto install modules are installed when all their dependencies are installed"},
+		"State": fields.Selection{Required: true, Index: true, ReadOnly: true,
+			Selection: types.Selection{StateInstalled: "Installed", StateUninstalled: "Not Installed"},
+			Default:   models.DefaultValue(StateUninstalled)},
+		"InstallDate": fields.DateTime{ReadOnly: true},
+	})
+
+	moduleData := models.NewModel("ModuleData")
+	moduleData.SetDefaultOrder("Module", "ID")
+	moduleData.AddFields(map[string]models.FieldDefinition{
+		"Module":     fields.Char{Required: true, Index: true, ReadOnly: true},
+		"Model":      fields.Char{Required: true, ReadOnly: true},
+		"ExternalID": fields.Char{Required: true, Index: true, ReadOnly: true},
+	})
+	moduleData.AddSQLConstraint("module_external_id_unique", "unique (module, model, external_id)",
+		"A module cannot contribute twice the same record")
+}
+
+// moduleInfo_Init updates the module records from the modules of the application
+func moduleInfo_Init(rs *models.RecordCollection) {
+	Update(rs.Env())
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package mail provides an outgoing mail queue.
+//
+// Messages are added to the queue with Enqueue and sent asynchronously
+// by the models worker loop with the configured Sender.
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/spf13/viper"
+)
+
+var log logging.Logger
+
+// maxAttempts is the number of times the queue tries to send a message
+// before discarding it.
+const maxAttempts = 5
+
+// queuePeriod is the period at which the queue is processed
+const queuePeriod = 10 * time.Second
+
+// A Message is an email to send
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+	HTML    bool
+	// Headers are additional headers of the message, such as Message-ID
+	Headers     map[string]string
+	Attachments []Attachment
+}
+
+// An Attachment is a file attached to a message
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Bytes returns the RFC 5322 representation of the message
+func (m Message) Bytes() []byte {
+	var buf bytes.Buffer
+	contentType := "text/plain"
+	if m.HTML {
+		contentType = "text/html"
+	}
+	fmt.Fprintf(&buf, "From: %s\r\n", m.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	headers := make([]string, 0, len(m.Headers))
+	for header := range m.Headers {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header, m.Headers[header])
+	}
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	if len(m.Attachments) == 0 {
+		writeTextPart(&buf, contentType, m.Body)
+		return buf.Bytes()
+	}
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	part, _ := mw.CreatePart(textproto.MIMEHeader{})
+	writeTextPart(part, contentType, m.Body)
+	for _, att := range m.Attachments {
+		attType := att.ContentType
+		if attType == "" {
+			attType = "application/octet-stream"
+		}
+		part, _ = mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(attType, map[string]string{"name": att.Name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": att.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		writeBase64(part, att.Data)
+	}
+	mw.Close()
+	return buf.Bytes()
+}
+
+// writeTextPart writes the headers and the given text body of a part in w
+func writeTextPart(w io.Writer, contentType, body string) {
+	fmt.Fprintf(w, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	fmt.Fprintf(w, "Content-Transfer-Encoding: 8bit\r\n\r\n")
+	io.WriteString(w, strings.Replace(body, "\n", "\r\n", -1))
+}
+
+// writeBase64 writes the given data in w encoded
+// in base64 with lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// A Sender sends email messages
+type Sender interface {
+	Send(msg Message) error
+}
+
+// SMTPSender sends messages through the SMTP server of the Mail section
+// of the configuration (SMTPHost, SMTPPort, SMTPUser and SMTPPassword).
+type SMTPSender struct{}
+
+// Send the given message through the configured SMTP server
+func (s SMTPSender) Send(msg Message) error {
+	host := viper.GetString("Mail.SMTPHost")
+	if host == "" {
+		host = "localhost"
+	}
+	port := viper.GetString("Mail.SMTPPort")
+	if port == "" {
+		port = "25"
+	}
+	var auth smtp.Auth
+	if user := viper.GetString("Mail.SMTPUser"); user != "" {
+		auth = smtp.PlainAuth("", user, viper.GetString("Mail.SMTPPassword"), host)
+	}
+	return smtp.SendMail(net.JoinHostPort(host, port), auth, msg.From, msg.To, msg.Bytes())
+}
+
+var _ Sender = SMTPSender{}
+
+// A queuedMessage is a message in the queue with its sending attempts
+type queuedMessage struct {
+	Message
+	attempts int
+}
+
+// queue is the outgoing mail queue
+var queue struct {
+	sync.Mutex
+	messages []*queuedMessage
+	sender   Sender
+}
+
+// SetSender sets the Sender used by the queue to send messages.
+// The default sender is SMTPSender.
+func SetSender(sender Sender) {
+	queue.Lock()
+	defer queue.Unlock()
+	queue.sender = sender
+}
+
+// DefaultFrom returns the default sender address of outgoing mails
+func DefaultFrom() string {
+	if from := viper.GetString("Mail.From"); from != "" {
+		return from
+	}
+	return "noreply@localhost"
+}
+
+// Enqueue adds the given message to the outgoing mail queue.
+// If the message has no From address, DefaultFrom is used.
+func Enqueue(msg Message) {
+	if msg.From == "" {
+		msg.From = DefaultFrom()
+	}
+	queue.Lock()
+	defer queue.Unlock()
+	queue.messages = append(queue.messages, &queuedMessage{Message: msg})
+}
+
+// QueueLength returns the number of messages waiting to be sent
+func QueueLength() int {
+	queue.Lock()
+	defer queue.Unlock()
+	return len(queue.messages)
+}
+
+// ProcessQueue tries to send all the messages of the queue.
+// Messages that fail are kept in the queue until maxAttempts is reached.
+func ProcessQueue() {
+	queue.Lock()
+	messages, sender := queue.messages, queue.sender
+	queue.messages = nil
+	queue.Unlock()
+	var failed []*queuedMessage
+	for _, msg := range messages {
+		err := sender.Send(msg.Message)
+		if err == nil {
+			continue
+		}
+		msg.attempts++
+		if msg.attempts >= maxAttempts {
+			log.Error("Unable to send email, giving up", "to", msg.To, "subject", msg.Subject, "error", err)
+			continue
+		}
+		log.Warn("Unable to send email, will retry", "to", msg.To, "subject", msg.Subject, "error", err)
+		failed = append(failed, msg)
+	}
+	queue.Lock()
+	queue.messages = append(failed, queue.messages...)
+	queue.Unlock()
+}
+
+func init() {
+	log = logging.GetLogger("mail")
+	queue.sender = SMTPSender{}
+	models.RegisterWorker(models.NewWorkerFunction(ProcessQueue, queuePeriod))
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package mail
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testSender struct {
+	sent []Message
+	fail bool
+}
+
+func (ts *testSender) Send(msg Message) error {
+	if ts.fail {
+		return errors.New("unable to send")
+	}
+	ts.sent = append(ts.sent, msg)
+	return nil
+}
+
+func TestMailQueue(t *testing.T) {
+	Convey("Testing mail queue", t, func() {
+		sender := new(testSender)
+		SetSender(sender)
+		Convey("Messages should be sent when the queue is processed", func() {
+			Enqueue(Message{To: []string{"john@example.com"}, Subject: "Hello", Body: "Hi John"})
+			So(QueueLength(), ShouldEqual, 1)
+			ProcessQueue()
+			So(QueueLength(), ShouldEqual, 0)
+			So(sender.sent, ShouldHaveLength, 1)
+			So(sender.sent[0].From, ShouldEqual, DefaultFrom())
+		})
+		Convey("Failed messages should be retried then discarded", func() {
+			sender.fail = true
+			Enqueue(Message{To: []string{"john@example.com"}, Subject: "Hello", Body: "Hi John"})
+			for i := 1; i < maxAttempts; i++ {
+				ProcessQueue()
+				So(QueueLength(), ShouldEqual, 1)
+			}
+			ProcessQueue()
+			So(QueueLength(), ShouldEqual, 0)
+			So(sender.sent, ShouldBeEmpty)
+		})
+		Convey("Messages should be formatted with headers", func() {
+			data := string(Message{From: "a@example.com", To: []string{"b@example.com"}, Subject: "Été", Body: "line1\nline2"}.Bytes())
+			So(data, ShouldContainSubstring, "From: a@example.com\r\n")
+			So(data, ShouldContainSubstring, "Subject: =?utf-8?q?=C3=89t=C3=A9?=\r\n")
+			So(data, ShouldEndWith, "\r\n\r\nline1\r\nline2")
+			data = string(Message{To: []string{"b@example.com"}, Headers: map[string]string{"Message-ID": "<1@example.com>"}}.Bytes())
+			So(data, ShouldContainSubstring, "Message-ID: <1@example.com>\r\n")
+		})
+		Convey("Attachments should be sent as parts of multipart messages", func() {
+			data := string(Message{To: []string{"b@example.com"}, Body: "See attached", Attachments: []Attachment{
+				{Name: "devis.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4\n")}}}.Bytes())
+			So(data, ShouldContainSubstring, "Content-Type: multipart/mixed; boundary=")
+			So(data, ShouldContainSubstring, "Content-Type: text/plain; charset=utf-8\r\n")
+			So(data, ShouldContainSubstring, "See attached")
+			So(data, ShouldContainSubstring, "Content-Disposition: attachment; filename=devis.pdf\r\n")
+			So(data, ShouldContainSubstring, "JVBERi0xLjQK\r\n")
+		})
+	})
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package mailtemplate
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/mail"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// composerParams are the parameters of the composer controllers
+type composerParams struct {
+	TemplateID int64   `json:"template_id"`
+	ResIDs     []int64 `json:"res_ids"`
+	// Mails are the emails edited in the composer. If set, they are
+	// sent instead of the emails rendered from the template.
+	Mails []Mail `json:"mails"`
+}
+
+// renderForComposer renders the template of the given params for its records.
+// Attachments are not sent to the client, only their names.
+func renderForComposer(env models.Environment, params composerParams) []Mail {
+	template := env.Pool("MailTemplate").Call("BrowseOne", params.TemplateID).(models.RecordSet).Collection()
+	records := env.Pool(template.Get(template.Model().FieldName("Model")).(string))
+	records = records.Search(records.Model().Field(models.ID).In(params.ResIDs))
+	mails, err := Render(template, records)
+	if err != nil {
+		log.Panic("Unable to render mail template", "template", params.TemplateID, "error", err)
+	}
+	return mails
+}
+
+// composerRender is the controller that returns the emails rendered from a template
+// for the given records, so that the user can review them before sending.
+func composerRender(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params composerParams
+	ctx.BindRPCParams(&params)
+	var res []Mail
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		res = renderForComposer(env, params)
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// composerSend is the controller that sends the emails of a template for the given
+// records. Subjects, bodies and recipients edited by the user in the composer replace
+// the rendered ones, while attachments are always those rendered from the template.
+func composerSend(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params composerParams
+	ctx.BindRPCParams(&params)
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		edited := make(map[int64]Mail)
+		for _, m := range params.Mails {
+			edited[m.ResID] = m
+		}
+		mails := renderForComposer(env, params)
+		for i, m := range mails {
+			if e, ok := edited[m.ResID]; ok {
+				mails[i].Subject, mails[i].Body, mails[i].EmailTo = e.Subject, e.Body, e.EmailTo
+			}
+			if len(mails[i].EmailTo) == 0 {
+				log.Panic("No recipient for email", "template", params.TemplateID, "resID", m.ResID)
+			}
+		}
+		for _, m := range mails {
+			mail.Enqueue(m.Message())
+		}
+	})
+	ctx.RPC(http.StatusOK, true, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/mail/composer")
+	grp.AddController(http.MethodPost, "/render", composerRender)
+	grp.AddController(http.MethodPost, "/send", composerSend)
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package mailtemplate is a Hexya module that provides email templates.
+//
+// A MailTemplate is bound to a model. Its subject, body, sender, recipients and
+// language are QWeb templates rendered for each record, in which the record is
+// available as 'object'. A report can be rendered and attached to each email.
+//
+// Emails are sent from Go code with SendMail or from the client with the
+// composer controllers of the /mail/composer group.
+package mailtemplate
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "mailtemplate"
+)
+
+func init() {
+	log = logging.GetLogger("mailtemplate")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package mailtemplate
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/tools/hweb"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testPartner struct {
+	Name  string
+	Email string
+	Age   int
+}
+
+func TestRenderString(t *testing.T) {
+	Convey("Testing mail template rendering", t, func() {
+		context := hweb.Context{"object": testPartner{Name: "Dupont & Fils", Email: "contact@dupont.fr", Age: 42}}
+		Convey("Plain text templates should be unescaped", func() {
+			res, err := RenderString(`Order for <t t-esc="object.Name"/>`, context, false)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, "Order for Dupont & Fils")
+		})
+		Convey("HTML templates should be escaped", func() {
+			res, err := RenderString(`<p>Dear <t t-esc="object.Name"/>,</p><p t-if="object.Age &gt; 40">Hello</p>`, context, true)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, "<p>Dear Dupont &amp; Fils,</p><p>Hello</p>")
+		})
+		Convey("Empty templates should render empty strings", func() {
+			res, err := RenderString("  ", context, false)
+			So(err, ShouldBeNil)
+			So(res, ShouldBeEmpty)
+		})
+		Convey("Invalid templates should return an error", func() {
+			_, err := RenderString(`<p t-esc="object.Name |"/>`, context, true)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Recipient lists should be split", func() {
+			So(splitAddresses(" a@example.com, ,b@example.com"), ShouldResemble, []string{"a@example.com", "b@example.com"})
+		})
+	})
+}
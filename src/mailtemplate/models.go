@@ -0,0 +1,31 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package mailtemplate
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	mailTemplate := models.NewModel("MailTemplate")
+	mailTemplate.SetDefaultOrder("Name")
+	mailTemplate.NewMethod("SendMail", mailTemplate_SendMail)
+	mailTemplate.AddFields(map[string]models.FieldDefinition{
+		"Name":  fields.Char{Required: true, Translate: true},
+		"Model": fields.Char{String: "Applies To", Required: true, Index: true, Help: "Name of the model of the records"},
+		"Subject": fields.Char{Translate: true,
+			Help: "QWeb template of the subject, e.g. 'Order <t t-esc=\"object.Name\"/>'"},
+		"Body": fields.Text{Translate: true, Help: "QWeb template of the HTML body"},
+		"EmailFrom": fields.Char{String: "From",
+			Help: "QWeb template of the sender address. If empty, the default sender address is used"},
+		"EmailTo": fields.Char{String: "To", Help: "QWeb template of the comma separated recipient addresses"},
+		"Lang": fields.Char{String: "Language",
+			Help: "QWeb template of the language in which the email is rendered, e.g. " +
+				"'<t t-esc=\"object.Partner.Lang\"/>'. If empty, the language of the current user is used"},
+		"Report": fields.Char{String: "Report to Attach", Help: "External ID of the report action to attach"},
+		"ReportName": fields.Char{String: "Report File Name",
+			Help: "QWeb template of the name of the attached report file, without extension"},
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package mailtemplate
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/i18n/format"
+	"github.com/hexya-erp/hexya/src/mail"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/reports"
+	"github.com/hexya-erp/hexya/src/templates"
+	"github.com/hexya-erp/hexya/src/tools/hweb"
+)
+
+// A Mail is an email rendered from a MailTemplate for a record
+type Mail struct {
+	ResID       int64             `json:"res_id"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body"`
+	EmailFrom   string            `json:"email_from"`
+	EmailTo     []string          `json:"email_to"`
+	Attachments []mail.Attachment `json:"-"`
+}
+
+// Message returns the mail.Message to send this Mail
+func (m Mail) Message() mail.Message {
+	return mail.Message{
+		From:        m.EmailFrom,
+		To:          m.EmailTo,
+		Subject:     m.Subject,
+		Body:        m.Body,
+		HTML:        true,
+		Attachments: m.Attachments,
+	}
+}
+
+// RenderString renders the given QWeb template source with the given context.
+//
+// If isHTML is false, the result is unescaped and trimmed, so that
+// it can be used as plain text, such as a subject or an address.
+func RenderString(src string, context hweb.Context, isHTML bool) (string, error) {
+	if strings.TrimSpace(src) == "" {
+		return "", nil
+	}
+	tmpl, err := templates.Registry.FromQWebString(src)
+	if err != nil {
+		return "", err
+	}
+	res, err := tmpl.Execute(context)
+	if err != nil {
+		return "", err
+	}
+	if !isHTML {
+		res = strings.TrimSpace(html.UnescapeString(res))
+	}
+	return res, nil
+}
+
+// renderContext returns the context in which templates are rendered for the given record
+func renderContext(record *models.RecordCollection) hweb.Context {
+	formatter := format.NewFormatter(record.Env())
+	return hweb.Context{
+		"object":  record,
+		"lang":    record.Env().Context().GetString("lang"),
+		"format":  formatter,
+		"t_field": formatter.Field,
+	}
+}
+
+// splitAddresses returns the addresses of the given comma separated list
+func splitAddresses(list string) []string {
+	var res []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			res = append(res, addr)
+		}
+	}
+	return res
+}
+
+// Render renders the given MailTemplate for each record of records.
+func Render(template, records models.RecordSet) ([]Mail, error) {
+	tmpl := template.Collection()
+	tmpl.EnsureOne()
+	mi := tmpl.Model()
+	modelName := tmpl.Get(mi.FieldName("Model")).(string)
+	if records.ModelName() != modelName {
+		return nil, fmt.Errorf("mail template %d applies to %s records, not %s", tmpl.Ids()[0], modelName, records.ModelName())
+	}
+	var action *actions.Action
+	if reportID := tmpl.Get(mi.FieldName("Report")).(string); reportID != "" {
+		if action = actions.Registry.GetByXMLID(reportID); action == nil || action.Type != actions.ActionReport {
+			return nil, fmt.Errorf("unknown report %s in mail template %d", reportID, tmpl.Ids()[0])
+		}
+	}
+	res := make([]Mail, 0, records.Len())
+	for _, record := range records.Collection().Records() {
+		lang, err := RenderString(tmpl.Get(mi.FieldName("Lang")).(string), renderContext(record), false)
+		if err != nil {
+			return nil, err
+		}
+		recTmpl := tmpl
+		if lang != "" {
+			record = record.WithContext("lang", lang)
+			recTmpl = tmpl.WithContext("lang", lang)
+		}
+		m, err := renderMail(recTmpl, record, action)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// renderMail renders the given MailTemplate for the given record.
+// If action is not nil, the report is rendered and attached.
+func renderMail(tmpl, record *models.RecordCollection, action *actions.Action) (Mail, error) {
+	mi := tmpl.Model()
+	context := renderContext(record)
+	m := Mail{ResID: record.Ids()[0]}
+	var (
+		emailTo string
+		err     error
+	)
+	for _, f := range []struct {
+		field  string
+		target *string
+		isHTML bool
+	}{
+		{field: "Subject", target: &m.Subject},
+		{field: "Body", target: &m.Body, isHTML: true},
+		{field: "EmailFrom", target: &m.EmailFrom},
+		{field: "EmailTo", target: &emailTo},
+	} {
+		if *f.target, err = RenderString(tmpl.Get(mi.FieldName(f.field)).(string), context, f.isHTML); err != nil {
+			return Mail{}, fmt.Errorf("unable to render %s of mail template %d: %s", f.field, tmpl.Ids()[0], err)
+		}
+	}
+	m.EmailTo = splitAddresses(emailTo)
+	if action == nil {
+		return m, nil
+	}
+	data, contentType, err := reports.Render(record.Env(), action, action.ReportType, record.Ids(), nil)
+	if err != nil {
+		return Mail{}, err
+	}
+	name, err := RenderString(tmpl.Get(mi.FieldName("ReportName")).(string), context, false)
+	if err != nil {
+		return Mail{}, err
+	}
+	if name == "" {
+		name = action.Name
+	}
+	m.Attachments = append(m.Attachments, mail.Attachment{
+		Name:        name + reports.FileExtension(action.ReportType),
+		ContentType: contentType,
+		Data:        data,
+	})
+	return m, nil
+}
+
+// SendMail renders the given MailTemplate for each record of records
+// and adds the emails to the outgoing mail queue.
+//
+// It returns an error without sending any email if the template cannot
+// be rendered or if an email has no recipient.
+func SendMail(template, records models.RecordSet) error {
+	mails, err := Render(template, records)
+	if err != nil {
+		return err
+	}
+	for _, m := range mails {
+		if len(m.EmailTo) == 0 {
+			return fmt.Errorf("no recipient for the email of %s record %d", records.ModelName(), m.ResID)
+		}
+	}
+	for _, m := range mails {
+		mail.Enqueue(m.Message())
+	}
+	return nil
+}
+
+// SendMail renders this template for the records of its model with
+// the given IDs and adds the emails to the outgoing mail queue.
+func mailTemplate_SendMail(rc *models.RecordCollection, resIDs []int64) {
+	rc.EnsureOne()
+	records := rc.Env().Pool(rc.Get(rc.Model().FieldName("Model")).(string))
+	records = records.Search(records.Model().Field(models.ID).In(resIDs))
+	if err := SendMail(rc, records); err != nil {
+		log.Panic("Unable to send emails", "template", rc.Ids()[0], "error", err)
+	}
+}
@@ -47,8 +47,14 @@ func BootStrap() {
 	}
 }
 
-func init() {
+// ResetRegistry replaces the Registry by an empty collection and forgets
+// the menus loaded before bootstrap, so that menus can be loaded again.
+func ResetRegistry() {
 	Registry = NewCollection()
 	bootstrapMap = make(map[string]*Menu)
+}
+
+func init() {
+	ResetRegistry()
 	log = logging.GetLogger("menus")
 }
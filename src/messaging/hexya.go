@@ -0,0 +1,67 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package messaging is a Hexya module that provides messages and followers
+// on records, and the inbox of users.
+//
+// Models on which messages can be posted inherit the MailThread model:
+//
+//	h.Lead().InheritModel(h.MailThread())
+//
+// When a message is posted on a record, the followers of the record and the
+// mentioned users are notified according to their notification settings:
+// either by email or in their inbox. Users are also notified on the bus when a
+// record they follow is updated.
+//
+// Fields declared with Tracking set to true are tracked on MailThread models:
+// when such fields change, a notification listing their old and new values is
+// posted on the record. Relations are rendered by display name and selections
+// by their label.
+//
+//	"Stage": fields.Selection{Selection: stages, Tracking: true},
+package messaging
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "messaging"
+)
+
+// declareUserFields adds the notification preference of users, and the
+// authors, mentioned users, followers and recipients of messages.
+func declareUserFields() {
+	user := models.Registry.MustGet("User")
+	user.AddFields(map[string]models.FieldDefinition{
+		"NotificationType": fields.Selection{String: "Notification", Required: true,
+			Selection: types.Selection{NotificationEmail: "Handle by Emails", NotificationInbox: "Handle in Hexya"},
+			Default:   models.DefaultValue(NotificationInbox),
+			Help:      "Whether notifications are sent by email or displayed in the inbox of the user"},
+	})
+	models.Registry.MustGet("Message").AddFields(map[string]models.FieldDefinition{
+		"Author":   fields.Many2One{RelationModel: user, OnDelete: models.SetNull},
+		"Mentions": fields.Many2Many{String: "Mentioned Users", RelationModel: user},
+	})
+	follower := models.Registry.MustGet("MessageFollower")
+	follower.AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{RelationModel: user, Required: true, Index: true, OnDelete: models.Cascade},
+	})
+	follower.AddSQLConstraint("follower_uniq", "unique(res_model, res_id, user_id)",
+		"A user can follow a record only once")
+	models.Registry.MustGet("MessageNotification").AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{RelationModel: user, Required: true, Index: true, OnDelete: models.Cascade},
+	})
+}
+
+func init() {
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: declareUserFields,
+	})
+}
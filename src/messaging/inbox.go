@@ -0,0 +1,146 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package messaging
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// An InboxItem is a message in the inbox of a user
+type InboxItem struct {
+	ID        int64          `json:"id"`
+	MessageID int64          `json:"message_id"`
+	Subject   string         `json:"subject"`
+	Body      string         `json:"body"`
+	Date      dates.DateTime `json:"date"`
+	Author    string         `json:"author"`
+	ResModel  string         `json:"res_model"`
+	ResID     int64          `json:"res_id"`
+	IsRead    bool           `json:"is_read"`
+	IsMention bool           `json:"is_mention"`
+}
+
+// userNotifications returns the MessageNotification records of the user with the given
+// ID. If unreadOnly is true, only the notifications that have not been read are returned.
+func userNotifications(env models.Environment, uid int64, unreadOnly bool) *models.RecordCollection {
+	notifications := env.Pool("MessageNotification").Sudo()
+	mi := notifications.Model()
+	cond := mi.Field(mi.FieldName("User")).Equals(uid)
+	if unreadOnly {
+		cond = cond.And().Field(mi.FieldName("IsRead")).Equals(false)
+	}
+	return notifications.Search(cond)
+}
+
+// unreadCount returns the number of unread messages in the inbox of the user with the given ID
+func unreadCount(env models.Environment, uid int64) int {
+	return userNotifications(env, uid, true).SearchCount()
+}
+
+// UnreadCount returns the number of unread messages in the inbox of the current user
+func UnreadCount(env models.Environment) int {
+	return unreadCount(env, env.Uid())
+}
+
+// Inbox returns the messages of the inbox of the current user, most
+// recent first. If unreadOnly is true, only unread messages are returned.
+func Inbox(env models.Environment, unreadOnly bool, limit, offset int) []InboxItem {
+	notifications := userNotifications(env, env.Uid(), unreadOnly).Limit(limit).Offset(offset)
+	mi := notifications.Model()
+	res := make([]InboxItem, 0)
+	for _, notif := range notifications.Records() {
+		message := notif.Get(mi.FieldName("Message")).(models.RecordSet).Collection()
+		msgMI := message.Model()
+		item := InboxItem{
+			ID:        notif.Ids()[0],
+			MessageID: message.Ids()[0],
+			Subject:   message.Get(msgMI.FieldName("Subject")).(string),
+			Body:      message.Get(msgMI.FieldName("Body")).(string),
+			Date:      message.Get(msgMI.FieldName("Date")).(dates.DateTime),
+			ResModel:  message.Get(msgMI.FieldName("ResModel")).(string),
+			ResID:     message.Get(msgMI.FieldName("ResID")).(int64),
+			IsRead:    notif.Get(mi.FieldName("IsRead")).(bool),
+			IsMention: notif.Get(mi.FieldName("IsMention")).(bool),
+		}
+		if author := message.Get(msgMI.FieldName("Author")).(models.RecordSet).Collection(); !author.IsEmpty() {
+			item.Author = author.Call("NameGet").(string)
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// MarkRead marks the inbox messages of the current user with the given
+// IDs as read. All messages of the inbox are marked if ids is empty.
+func MarkRead(env models.Environment, ids []int64) {
+	notifications := userNotifications(env, env.Uid(), true)
+	mi := notifications.Model()
+	if len(ids) > 0 {
+		notifications = notifications.Search(mi.Field(models.ID).In(ids))
+	}
+	notifications.Call("Write", models.NewModelData(mi).Set(mi.FieldName("IsRead"), true))
+}
+
+// inboxMessages is the controller that returns the messages of the inbox of the user
+func inboxMessages(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		UnreadOnly bool `json:"unread_only"`
+		Limit      int  `json:"limit"`
+		Offset     int  `json:"offset"`
+	}
+	ctx.BindRPCParams(&params)
+	var res []InboxItem
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		res = Inbox(env, params.UnreadOnly, params.Limit, params.Offset)
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// unreadCounter is the controller that returns the number of unread messages of the user
+func unreadCounter(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var res int
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		res = UnreadCount(env)
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// markRead is the controller that marks messages of the inbox of the user as read
+func markRead(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params struct {
+		IDs []int64 `json:"ids"`
+	}
+	ctx.BindRPCParams(&params)
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		MarkRead(env, params.IDs)
+	})
+	ctx.RPC(http.StatusOK, true, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/mail/inbox")
+	grp.AddController(http.MethodPost, "/messages", inboxMessages)
+	grp.AddController(http.MethodPost, "/unread_counter", unreadCounter)
+	grp.AddController(http.MethodPost, "/mark_read", markRead)
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package messaging
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecipients(t *testing.T) {
+	Convey("Testing the recipients of messages", t, func() {
+		Convey("Followers and mentioned users should be notified, but not the author", func() {
+			So(recipients(1, []int64{1, 2, 3}, []int64{3, 4}), ShouldResemble, map[int64]bool{2: false, 3: true, 4: true})
+		})
+		Convey("Updates should be notified to followers only", func() {
+			So(recipients(2, []int64{1, 2}, nil), ShouldResemble, map[int64]bool{1: false})
+		})
+	})
+}
+
+func TestTrackingBody(t *testing.T) {
+	Convey("Testing the body of tracking messages", t, func() {
+		So(trackingBody([]trackingChange{
+			{Field: "Stage", OldValue: "New", NewValue: "Won"},
+			{Field: "Salesman", OldValue: "", NewValue: "John"},
+		}), ShouldEqual, "Stage: New → Won\nSalesman:  → John")
+	})
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package messaging
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+func declareModels() {
+	message := models.NewModel("Message")
+	message.SetDefaultOrder("Date DESC", "ID DESC")
+	message.AddFields(map[string]models.FieldDefinition{
+		"Subject": fields.Char{},
+		"Body":    fields.Text{},
+		"Date": fields.DateTime{Required: true, Index: true,
+			Default: func(env models.Environment) interface{} {
+				return dates.Now()
+			}},
+		"ResModel": fields.Char{String: "Related Document Model", Required: true, Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Required: true, Index: true},
+		"MessageType": fields.Selection{String: "Type", Required: true,
+			Selection: types.Selection{TypeComment: "Comment", TypeNotification: "System Notification", TypeEmail: "Email"},
+			Default:   models.DefaultValue(TypeComment)},
+		"MessageID": fields.Char{String: "Message-ID", Index: true, NoCopy: true,
+			Help: "Message-ID header of the email of this message, used to thread replies"},
+		"EmailFrom": fields.Char{String: "From", Help: "Sender address of incoming emails"},
+	})
+
+	follower := models.NewModel("MessageFollower")
+	follower.AddFields(map[string]models.FieldDefinition{
+		"ResModel": fields.Char{String: "Related Document Model", Required: true, Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Required: true, Index: true},
+	})
+
+	notification := models.NewModel("MessageNotification")
+	notification.SetDefaultOrder("ID DESC")
+	notification.AddFields(map[string]models.FieldDefinition{
+		"Message":   fields.Many2One{RelationModel: message, Required: true, OnDelete: models.Cascade},
+		"IsRead":    fields.Boolean{String: "Read", Index: true},
+		"IsMention": fields.Boolean{String: "Mentioned"},
+	})
+
+	mailThread := models.NewMixinModel("MailThread")
+	mailThread.NewMethod("MessagePost", mailThread_MessagePost)
+	mailThread.NewMethod("MessageSubscribe", mailThread_MessageSubscribe)
+	mailThread.NewMethod("MessageUnsubscribe", mailThread_MessageUnsubscribe)
+	mailThread.NewMethod("MessageFollowerIDs", mailThread_MessageFollowerIDs)
+	mailThread.NewMethod("MessageNew", mailThread_MessageNew)
+	mailThread.AddEmptyMethod("Write").Extend(mailThread_Write)
+}
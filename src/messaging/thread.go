@@ -0,0 +1,364 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package messaging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/bus"
+	"github.com/hexya-erp/hexya/src/mail"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// Types of messages
+const (
+	TypeComment      = "comment"
+	TypeNotification = "notification"
+	TypeEmail        = "email"
+)
+
+// Notification settings of users
+const (
+	NotificationEmail = "email"
+	NotificationInbox = "inbox"
+)
+
+// Types of the events sent on the bus
+const (
+	// EventMessage is sent to the users notified of a new message
+	EventMessage = "message"
+	// EventRecordUpdated is sent to the followers of an updated record
+	EventRecordUpdated = "record_updated"
+)
+
+// An Event is sent on the private bus channel of a user
+// when a message concerns the user or a followed record is updated.
+type Event struct {
+	Type      string `json:"type"`
+	ResModel  string `json:"res_model"`
+	ResID     int64  `json:"res_id"`
+	MessageID int64  `json:"message_id,omitempty"`
+	Mention   bool   `json:"mention,omitempty"`
+	Unread    int    `json:"unread"`
+}
+
+// recipients returns the users to notify of a message of the given author on a
+// record with the given followers and mentioned users. The value of the map is
+// true if the user is mentioned.
+func recipients(author int64, followers, mentions []int64) map[int64]bool {
+	res := make(map[int64]bool)
+	for _, uid := range followers {
+		res[uid] = false
+	}
+	for _, uid := range mentions {
+		res[uid] = true
+	}
+	delete(res, author)
+	return res
+}
+
+// followerIDs returns the IDs of the users following the
+// record of the given model with the given ID.
+func followerIDs(env models.Environment, resModel string, resID int64) []int64 {
+	followers := env.Pool("MessageFollower").Sudo()
+	mi := followers.Model()
+	followers = followers.Search(mi.Field(mi.FieldName("ResModel")).Equals(resModel).
+		And().Field(mi.FieldName("ResID")).Equals(resID))
+	var res []int64
+	for _, follower := range followers.Records() {
+		res = append(res, follower.Get(mi.FieldName("User")).(models.RecordSet).Ids()...)
+	}
+	return res
+}
+
+// notifyUser notifies the user with the given ID of the given message, according
+// to the notification setting of the user, and sends an Event on the bus.
+func notifyUser(env models.Environment, message *models.RecordCollection, uid int64, mention bool) {
+	msgMI := message.Model()
+	user := env.Pool("User").Sudo().Call("BrowseOne", uid).(models.RecordSet).Collection()
+	userMI := user.Model()
+	email := ""
+	if _, ok := userMI.Fields().Get("Email"); ok {
+		email, _ = user.Get(userMI.FieldName("Email")).(string)
+	}
+	if user.Get(userMI.FieldName("NotificationType")) == NotificationEmail && email != "" {
+		mail.Enqueue(mail.Message{
+			To:      []string{email},
+			Subject: message.Get(msgMI.FieldName("Subject")).(string),
+			Body:    message.Get(msgMI.FieldName("Body")).(string),
+			Headers: map[string]string{"Message-ID": message.Get(msgMI.FieldName("MessageID")).(string)},
+		})
+	} else {
+		notifications := env.Pool("MessageNotification").Sudo()
+		notifMI := notifications.Model()
+		notifications.Call("Create", models.NewModelData(notifMI).
+			Set(notifMI.FieldName("Message"), message).
+			Set(notifMI.FieldName("User"), user).
+			Set(notifMI.FieldName("IsMention"), mention))
+	}
+	bus.Send(bus.UserChannel(env.DBName(), uid), Event{
+		Type:      EventMessage,
+		ResModel:  message.Get(msgMI.FieldName("ResModel")).(string),
+		ResID:     message.Get(msgMI.FieldName("ResID")).(int64),
+		MessageID: message.Ids()[0],
+		Mention:   mention,
+		Unread:    unreadCount(env, uid),
+	})
+}
+
+// MessageValues are the values of a message to post on a record
+type MessageValues struct {
+	Subject     string
+	Body        string
+	MessageType string
+	// AuthorID is the ID of the user who wrote the message, or 0 for
+	// messages of the system or of senders who are not users.
+	AuthorID int64
+	// EmailFrom is the sender address of incoming emails
+	EmailFrom string
+	// MessageID is the Message-ID header of an incoming email.
+	// If empty, a Message-ID is generated for the message.
+	MessageID  string
+	MentionIDs []int64
+}
+
+// Post posts a message with the given values on the record of the given model
+// with the given ID, and notifies the followers of the record and the mentioned
+// users, except the author. It returns the new message.
+func Post(env models.Environment, resModel string, resID int64, values MessageValues) *models.RecordCollection {
+	messages := env.Pool("Message").Sudo()
+	mi := messages.Model()
+	if values.MessageType == "" {
+		values.MessageType = TypeComment
+	}
+	data := models.NewModelData(mi).
+		Set(mi.FieldName("Subject"), values.Subject).
+		Set(mi.FieldName("Body"), values.Body).
+		Set(mi.FieldName("MessageType"), values.MessageType).
+		Set(mi.FieldName("ResModel"), resModel).
+		Set(mi.FieldName("ResID"), resID).
+		Set(mi.FieldName("EmailFrom"), values.EmailFrom).
+		Set(mi.FieldName("Mentions"), env.Pool("User").Call("Browse", values.MentionIDs))
+	if values.AuthorID != 0 {
+		data.Set(mi.FieldName("Author"), env.Pool("User").Call("BrowseOne", values.AuthorID))
+	}
+	message := messages.Call("Create", data).(models.RecordSet).Collection()
+	if values.MessageID == "" {
+		values.MessageID = GenerateMessageID(message.Ids()[0], resModel, resID)
+	}
+	message.Set(mi.FieldName("MessageID"), values.MessageID)
+	for uid, mention := range recipients(values.AuthorID, followerIDs(env, resModel, resID), values.MentionIDs) {
+		notifyUser(env, message, uid, mention)
+	}
+	return message
+}
+
+// GenerateMessageID returns the Message-ID of the message with the given ID posted on
+// the record of the given model with the given ID. It is sent in notification emails
+// so that replies can be routed to the record.
+func GenerateMessageID(id int64, resModel string, resID int64) string {
+	return fmt.Sprintf("<%d.%s-%d@%s>", id, resModel, resID, messageIDDomain())
+}
+
+// messageIDDomain returns the domain of the generated Message-IDs
+func messageIDDomain() string {
+	from := mail.DefaultFrom()
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		return strings.TrimRight(from[i+1:], ">")
+	}
+	return "localhost"
+}
+
+// MessagePost posts a comment with the given subject and body on this record
+// as the current user and notifies the followers of the record and the users
+// with the given IDs, who are mentioned in the message.
+//
+// It returns the ID of the new message.
+func mailThread_MessagePost(rc *models.RecordCollection, subject, body string, mentionIDs []int64) int64 {
+	rc.EnsureOne()
+	message := Post(rc.Env(), rc.ModelName(), rc.Ids()[0], MessageValues{
+		Subject:     subject,
+		Body:        body,
+		MessageType: TypeComment,
+		AuthorID:    rc.Env().Uid(),
+		MentionIDs:  mentionIDs,
+	})
+	return message.Ids()[0]
+}
+
+// MessageSubscribe adds the users with the given IDs to the followers
+// of the records of this RecordSet. Users already following a record
+// are ignored.
+func mailThread_MessageSubscribe(rc *models.RecordCollection, userIDs []int64) {
+	followers := rc.Env().Pool("MessageFollower").Sudo()
+	mi := followers.Model()
+	for _, rec := range rc.Records() {
+		existing := make(map[int64]bool)
+		for _, uid := range followerIDs(rc.Env(), rc.ModelName(), rec.Ids()[0]) {
+			existing[uid] = true
+		}
+		for _, uid := range userIDs {
+			if existing[uid] {
+				continue
+			}
+			followers.Call("Create", models.NewModelData(mi).
+				Set(mi.FieldName("ResModel"), rc.ModelName()).
+				Set(mi.FieldName("ResID"), rec.Ids()[0]).
+				Set(mi.FieldName("User"), rc.Env().Pool("User").Call("BrowseOne", uid)))
+			existing[uid] = true
+		}
+	}
+}
+
+// MessageUnsubscribe removes the users with the given IDs
+// from the followers of the records of this RecordSet.
+func mailThread_MessageUnsubscribe(rc *models.RecordCollection, userIDs []int64) {
+	followers := rc.Env().Pool("MessageFollower").Sudo()
+	mi := followers.Model()
+	followers.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("ResID")).In(rc.Ids()).
+		And().Field(mi.FieldName("User")).In(userIDs)).Call("Unlink")
+}
+
+// MessageFollowerIDs returns the IDs of the users following this record
+func mailThread_MessageFollowerIDs(rc *models.RecordCollection) []int64 {
+	rc.EnsureOne()
+	return followerIDs(rc.Env(), rc.ModelName(), rc.Ids()[0])
+}
+
+// MessageNew creates a new record from an incoming email with the given subject, body
+// and sender address. The email itself is posted on the new record by the caller.
+//
+// The default implementation sets the Name field of the record to the subject if the
+// model has such a field. Models that must be created from incoming emails should
+// extend this method to set their required fields.
+func mailThread_MessageNew(rc *models.RecordCollection, subject, body, emailFrom string) *models.RecordCollection {
+	mi := rc.Model()
+	data := models.NewModelData(mi)
+	if _, ok := mi.Fields().Get("Name"); ok {
+		data.Set(mi.FieldName("Name"), subject)
+	}
+	return rc.Call("Create", data).(models.RecordSet).Collection()
+}
+
+// A trackingChange is the change of the value of a tracked field of a record
+type trackingChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+// trackingBody returns the body of the message listing the given changes
+func trackingBody(changes []trackingChange) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = fmt.Sprintf("%s: %s → %s", change.Field, change.OldValue, change.NewValue)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trackedFields returns the definition of the tracked fields of the model
+// of rc that are updated by data, with their labels in the user's language.
+func trackedFields(rc *models.RecordCollection, data models.RecordData) []*models.FieldInfo {
+	var fNames models.FieldNames
+	for _, fName := range data.Underlying().FieldNames() {
+		if fi, ok := rc.Model().Fields().Get(fName.Name()); ok && fi.IsTracked() {
+			fNames = append(fNames, fName)
+		}
+	}
+	if len(fNames) == 0 {
+		return nil
+	}
+	infos := rc.Call("FieldsGet", models.FieldsGetArgs{Fields: fNames}).(map[string]*models.FieldInfo)
+	res := make([]*models.FieldInfo, len(fNames))
+	for i, fName := range fNames {
+		res[i] = infos[fName.JSON()]
+	}
+	return res
+}
+
+// trackingValue returns the value of the given field of the given record as displayed
+// in tracking messages. Relations are rendered by the display name of the related
+// records and selections by their label.
+func trackingValue(rec *models.RecordCollection, field *models.FieldInfo) string {
+	value := rec.Get(rec.Model().FieldName(field.Name))
+	switch val := value.(type) {
+	case models.RecordSet:
+		var names []string
+		for _, related := range val.Collection().Records() {
+			names = append(names, related.Call("NameGet").(string))
+		}
+		return strings.Join(names, ", ")
+	case dates.Date:
+		if val.IsZero() {
+			return ""
+		}
+	case dates.DateTime:
+		if val.IsZero() {
+			return ""
+		}
+	}
+	if field.Type == fieldtype.Selection {
+		return field.Selection[fmt.Sprintf("%v", value)]
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// trackingValues returns the tracking values of the given fields for each record of rc
+func trackingValues(rc *models.RecordCollection, fields []*models.FieldInfo) map[int64][]string {
+	res := make(map[int64][]string)
+	for _, rec := range rc.Records() {
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			values[i] = trackingValue(rec, field)
+		}
+		res[rec.Ids()[0]] = values
+	}
+	return res
+}
+
+// Write posts a notification with the old and new values of the tracked
+// fields that changed on each record, and notifies the followers of the
+// updated records on the bus.
+func mailThread_Write(rc *models.RecordCollection, data models.RecordData) bool {
+	tracked := trackedFields(rc, data)
+	var oldValues map[int64][]string
+	if len(tracked) > 0 {
+		oldValues = trackingValues(rc, tracked)
+	}
+	res := rc.Super().Call("Write", data).(bool)
+	if len(tracked) > 0 {
+		for id, newValues := range trackingValues(rc, tracked) {
+			var changes []trackingChange
+			for i, field := range tracked {
+				if oldValues[id][i] == newValues[i] {
+					continue
+				}
+				changes = append(changes, trackingChange{Field: field.String, OldValue: oldValues[id][i], NewValue: newValues[i]})
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			Post(rc.Env(), rc.ModelName(), id, MessageValues{
+				Body:        trackingBody(changes),
+				MessageType: TypeNotification,
+				AuthorID:    rc.Env().Uid(),
+			})
+		}
+	}
+	for _, rec := range rc.Records() {
+		for uid := range recipients(rc.Env().Uid(), followerIDs(rc.Env(), rc.ModelName(), rec.Ids()[0]), nil) {
+			bus.Send(bus.UserChannel(rc.Env().DBName(), uid), Event{
+				Type:     EventRecordUpdated,
+				ResModel: rc.ModelName(),
+				ResID:    rec.Ids()[0],
+				Unread:   unreadCount(rc.Env(), uid),
+			})
+		}
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package metadata is a Hexya module that persists the model registry in the
+// database, so that administrators and tools can browse the models of the
+// application, their fields, the modules that declare them and their access
+// rights from the user interface.
+//
+// The ModelInfo, ModelFieldInfo and ModelAccessInfo records are updated each
+// time the database schema is updated. They are read-only, since changing
+// them does not change the models.
+package metadata
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "metadata"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("metadata")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
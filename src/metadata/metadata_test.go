@@ -0,0 +1,33 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestModelMetadata(t *testing.T) {
+	Convey("Testing model metadata", t, func() {
+		modelInfo := models.Registry.MustGet("ModelInfo")
+		Convey("Models and fields should know the module which declared them", func() {
+			So(modelInfo.Module(), ShouldEqual, MODULE_NAME)
+			So(modelInfo.Fields().MustGet("TableName").Module(), ShouldEqual, MODULE_NAME)
+			So(models.Registry.MustGet("CommonMixin").Module(), ShouldBeEmpty)
+		})
+		Convey("Model types should be computed from the model options", func() {
+			So(modelType(modelInfo), ShouldEqual, TypeModel)
+			So(modelType(models.Registry.MustGet("CommonMixin")), ShouldEqual, TypeMixin)
+		})
+		Convey("All models should be listed by name", func() {
+			all := models.Registry.All()
+			So(len(all), ShouldBeGreaterThan, 3)
+			for i := 1; i < len(all); i++ {
+				So(all[i-1].Name(), ShouldBeLessThan, all[i].Name())
+			}
+		})
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package metadata
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+func declareModels() {
+	modelInfo := models.NewModel("ModelInfo")
+	modelFieldInfo := models.NewModel("ModelFieldInfo")
+	modelAccessInfo := models.NewModel("ModelAccessInfo")
+
+	modelInfo.SetDefaultOrder("Name")
+	modelInfo.NewMethod("Init", modelInfo_Init)
+	modelInfo.AddFields(map[string]models.FieldDefinition{
+		"Name":      fields.Char{Required: true, Unique: true, ReadOnly: true},
+		"TableName": fields.Char{ReadOnly: true},
+		"Type": fields.Selection{ReadOnly: true,
+			Selection: types.Selection{TypeModel: "Model", TypeTransient: "Transient", TypeMixin: "Mixin", TypeManual: "Manual"}},
+		"Module": fields.Char{ReadOnly: true, Index: true,
+			Help: "Module which declared this model"},
+		"Fields": fields.One2Many{RelationModel: modelFieldInfo, ReverseFK: "ModelInfo", ReadOnly: true},
+		"Access": fields.One2Many{String: "Access Rights", RelationModel: modelAccessInfo,
+			ReverseFK: "ModelInfo", ReadOnly: true},
+	})
+
+	modelFieldInfo.SetDefaultOrder("ModelInfo", "Name")
+	modelFieldInfo.AddFields(map[string]models.FieldDefinition{
+		"ModelInfo": fields.Many2One{String: "Model", RelationModel: modelInfo, Required: true,
+			OnDelete: models.Cascade, Index: true, ReadOnly: true},
+		"Name":       fields.Char{Required: true, ReadOnly: true},
+		"ColumnName": fields.Char{String: "JSON Name", ReadOnly: true},
+		"String":     fields.Char{String: "Label", ReadOnly: true},
+		"Help":       fields.Text{ReadOnly: true},
+		"Type":       fields.Char{ReadOnly: true},
+		"Relation":   fields.Char{String: "Related Model", ReadOnly: true},
+		"Required":   fields.Boolean{ReadOnly: true},
+		"ReadOnly":   fields.Boolean{String: "Read Only", ReadOnly: true},
+		"Stored":     fields.Boolean{ReadOnly: true},
+		"Indexed":    fields.Boolean{ReadOnly: true},
+		"Module": fields.Char{ReadOnly: true, Index: true,
+			Help: "Module which declared this field"},
+	})
+	modelFieldInfo.AddSQLConstraint("model_name_uniq", "unique(model_info_id, name)",
+		"The name of a field must be unique per model")
+
+	modelAccessInfo.SetDefaultOrder("ModelInfo", "GroupID")
+	modelAccessInfo.AddFields(map[string]models.FieldDefinition{
+		"ModelInfo": fields.Many2One{String: "Model", RelationModel: modelInfo, Required: true,
+			OnDelete: models.Cascade, Index: true, ReadOnly: true},
+		"GroupID":    fields.Char{String: "Group ID", Required: true, ReadOnly: true},
+		"GroupName":  fields.Char{String: "Group", ReadOnly: true},
+		"PermRead":   fields.Boolean{String: "Read Access", ReadOnly: true},
+		"PermCreate": fields.Boolean{String: "Create Access", ReadOnly: true},
+		"PermWrite":  fields.Boolean{String: "Write Access", ReadOnly: true},
+		"PermUnlink": fields.Boolean{String: "Delete Access", ReadOnly: true},
+	})
+}
+
+// modelInfo_Init updates the metadata records from the model registry
+func modelInfo_Init(rs *models.RecordCollection) {
+	Sync(rs.Env())
+}
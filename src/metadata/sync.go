@@ -0,0 +1,162 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package metadata
+
+import (
+	"sort"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+)
+
+// Types of models
+const (
+	TypeModel     = "model"
+	TypeTransient = "transient"
+	TypeMixin     = "mixin"
+	TypeManual    = "manual"
+)
+
+// modelType returns the type of the given model
+func modelType(model *models.Model) string {
+	switch {
+	case model.IsMixin():
+		return TypeMixin
+	case model.IsTransient():
+		return TypeTransient
+	case model.IsManual():
+		return TypeManual
+	}
+	return TypeModel
+}
+
+// An accessRight holds the permissions of a group on the CRUD methods of a model
+type accessRight struct {
+	group                       *security.Group
+	read, create, write, unlink bool
+}
+
+// accessRights returns the permissions of each group on the CRUD methods of the
+// given model, sorted by group ID. Only permissions given for any caller are taken
+// into account.
+func accessRights(model *models.Model) []*accessRight {
+	byGroup := make(map[*security.Group]*accessRight)
+	for _, method := range []string{"Load", "Create", "Write", "Unlink"} {
+		meth, ok := model.Methods().Get(method)
+		if !ok {
+			continue
+		}
+		for _, group := range meth.AllowedGroups() {
+			ar, ok := byGroup[group]
+			if !ok {
+				ar = &accessRight{group: group}
+				byGroup[group] = ar
+			}
+			switch method {
+			case "Load":
+				ar.read = true
+			case "Create":
+				ar.create = true
+			case "Write":
+				ar.write = true
+			case "Unlink":
+				ar.unlink = true
+			}
+		}
+	}
+	res := make([]*accessRight, 0, len(byGroup))
+	for _, ar := range byGroup {
+		res = append(res, ar)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].group.ID < res[j].group.ID
+	})
+	return res
+}
+
+// Sync updates the ModelInfo, ModelFieldInfo and ModelAccessInfo records
+// of the database of env from the model registry. Records of models and
+// fields that do not exist anymore are deleted.
+func Sync(env models.Environment) {
+	infos := env.Pool("ModelInfo").Sudo()
+	mi := infos.Model()
+	existing := make(map[string]*models.RecordCollection)
+	for _, rec := range infos.SearchAll().Records() {
+		existing[rec.Get(mi.FieldName("Name")).(string)] = rec
+	}
+	for _, model := range models.Registry.All() {
+		if model.IsM2MLink() {
+			continue
+		}
+		data := models.NewModelData(mi).
+			Set(mi.FieldName("Name"), model.Name()).
+			Set(mi.FieldName("TableName"), model.TableName()).
+			Set(mi.FieldName("Type"), modelType(model)).
+			Set(mi.FieldName("Module"), model.Module())
+		rec, ok := existing[model.Name()]
+		delete(existing, model.Name())
+		if ok {
+			rec.Call("Write", data)
+		} else {
+			rec = infos.Call("Create", data).(models.RecordSet).Collection()
+		}
+		syncFields(rec, model)
+		syncAccess(rec, model)
+	}
+	for _, rec := range existing {
+		rec.Call("Unlink")
+	}
+}
+
+// syncFields updates the ModelFieldInfo records of the given ModelInfo record
+func syncFields(info *models.RecordCollection, model *models.Model) {
+	fieldInfos := info.Env().Pool("ModelFieldInfo").Sudo()
+	mi := fieldInfos.Model()
+	existing := make(map[string]*models.RecordCollection)
+	for _, rec := range fieldInfos.Search(mi.Field(mi.FieldName("ModelInfo")).Equals(info.Ids()[0])).Records() {
+		existing[rec.Get(mi.FieldName("Name")).(string)] = rec
+	}
+	for _, fInfo := range model.FieldsGet() {
+		data := models.NewModelData(mi).
+			Set(mi.FieldName("ModelInfo"), info).
+			Set(mi.FieldName("Name"), fInfo.Name).
+			Set(mi.FieldName("ColumnName"), fInfo.JSON).
+			Set(mi.FieldName("String"), fInfo.String).
+			Set(mi.FieldName("Help"), fInfo.Help).
+			Set(mi.FieldName("Type"), string(fInfo.Type)).
+			Set(mi.FieldName("Relation"), fInfo.Relation).
+			Set(mi.FieldName("Required"), fInfo.Required).
+			Set(mi.FieldName("ReadOnly"), fInfo.ReadOnly).
+			Set(mi.FieldName("Stored"), fInfo.Store).
+			Set(mi.FieldName("Indexed"), fInfo.Index).
+			Set(mi.FieldName("Module"), model.Fields().MustGet(fInfo.Name).Module())
+		rec, ok := existing[fInfo.Name]
+		delete(existing, fInfo.Name)
+		if ok {
+			rec.Call("Write", data)
+			continue
+		}
+		fieldInfos.Call("Create", data)
+	}
+	for _, rec := range existing {
+		rec.Call("Unlink")
+	}
+}
+
+// syncAccess replaces the ModelAccessInfo records of the given ModelInfo record
+func syncAccess(info *models.RecordCollection, model *models.Model) {
+	accessInfos := info.Env().Pool("ModelAccessInfo").Sudo()
+	mi := accessInfos.Model()
+	accessInfos.Search(mi.Field(mi.FieldName("ModelInfo")).Equals(info.Ids()[0])).Call("Unlink")
+	for _, ar := range accessRights(model) {
+		accessInfos.Call("Create", models.NewModelData(mi).
+			Set(mi.FieldName("ModelInfo"), info).
+			Set(mi.FieldName("GroupID"), ar.group.ID).
+			Set(mi.FieldName("GroupName"), ar.group.Name).
+			Set(mi.FieldName("PermRead"), ar.read).
+			Set(mi.FieldName("PermCreate"), ar.create).
+			Set(mi.FieldName("PermWrite"), ar.write).
+			Set(mi.FieldName("PermUnlink"), ar.unlink))
+	}
+}
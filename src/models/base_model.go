@@ -21,7 +21,6 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
-	"github.com/hexya-erp/hexya/src/i18n"
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
 	"github.com/hexya-erp/hexya/src/models/operator"
 	"github.com/hexya-erp/hexya/src/models/types"
@@ -238,13 +237,7 @@ func commonMixinFieldsGet(rc *RecordCollection, args FieldsGetArgs) map[string]*
 	// Get the field informations
 	res := rc.model.FieldsGet(args.Fields...)
 
-	// Translate attributes when required
-	lang := rc.Env().Context().GetString("lang")
-	for fName, fInfo := range res {
-		res[fName].Help = i18n.Registry.TranslateFieldHelp(lang, rc.model.name, fName, fInfo.Help)
-		res[fName].String = i18n.Registry.TranslateFieldDescription(lang, rc.model.name, fName, fInfo.String)
-		res[fName].Selection = i18n.Registry.TranslateFieldSelection(lang, rc.model.name, fName, fInfo.Selection)
-	}
+	rc.model.TranslateFieldsInfo(rc.Env(), res)
 	return res
 }
 
@@ -664,6 +657,10 @@ func baseMixinComputeDisplayName(rc *RecordCollection) *ModelData {
 func declareModelMixin() {
 	modelMixin := NewMixinModel("ModelMixin")
 	modelMixin.InheritModel(Registry.MustGet("BaseMixin"))
+	modelMixin.addMethod("LoadRecords", modelMixinLoadRecords)
+	modelMixin.addMethod("ToggleActive", modelMixinToggleActive)
+	modelMixin.addMethod("Merge", modelMixinMerge)
+	modelMixin.addMethod("FindDuplicates", modelMixinFindDuplicates)
 	modelMixin.fields.add(&Field{
 		model:       modelMixin,
 		name:        "HexyaExternalID",
@@ -691,6 +688,32 @@ func declareModelMixin() {
 	})
 }
 
+// ToggleActive archives the active records of this RecordSet and
+// unarchives the archived ones by inverting their Active field.
+//
+// It panics if the model has no Active field.
+func modelMixinToggleActive(rc *RecordCollection) bool {
+	activeField, ok := rc.model.fields.Get("Active")
+	if !ok || activeField.fieldType != fieldtype.Boolean {
+		log.Panic("ToggleActive called on a model without Active field", "model", rc.model.name)
+	}
+	var active, archived []int64
+	for _, rec := range rc.Records() {
+		if rec.Get(activeField).(bool) {
+			active = append(active, rec.ids[0])
+			continue
+		}
+		archived = append(archived, rec.ids[0])
+	}
+	if len(active) > 0 {
+		rc.Call("Browse", active).(RecordSet).Collection().Call("Write", NewModelData(rc.model).Set(activeField, false))
+	}
+	if len(archived) > 0 {
+		rc.Call("Browse", archived).(RecordSet).Collection().Call("Write", NewModelData(rc.model).Set(activeField, true))
+	}
+	return true
+}
+
 // ConvertLimitToInt converts the given limit as interface{} to an int
 func ConvertLimitToInt(limit interface{}) int {
 	if l, ok := limit.(bool); ok && !l {
@@ -731,6 +754,7 @@ type FieldInfo struct {
 	InvisibleFunc    func(Environment) (bool, Conditioner) `json:"-"`
 	GoType           reflect.Type                          `json:"-"`
 	Index            bool                                  `json:"-"`
+	Digits           nbutils.Digits                        `json:"-"`
 }
 
 // FieldsGetArgs is the args struct for the FieldsGet method
@@ -38,6 +38,11 @@ var mixed = map[modelCouple]bool{}
 
 // BootStrap freezes model, fields and method caches and syncs the database structure
 // with the declared data.
+//
+// If BootStrapMetadata has already been called, only the remaining steps are run.
+//
+// Once bootstrapped, the registry is immutable so that it is read without
+// locks: declaring models, fields or methods afterwards panics.
 func BootStrap() {
 	log.Info("Bootstrapping models")
 	if Registry.bootstrapped == true {
@@ -49,10 +54,41 @@ func BootStrap() {
 	Registry.Lock()
 	defer Registry.Unlock()
 
+	if !Registry.metadataBootstrapped {
+		bootStrapMetadata()
+	}
+	setupSecurity()
+	RegisterWorker(NewWorkerFunction(FreeTransientModels, freeTransientPeriod))
+
+	Registry.bootstrapped = true
+	Registry.freeze()
+}
+
+// BootStrapMetadata only bootstraps the metadata of the models, that is their
+// fields, relations and methods. It needs neither a database connection nor
+// the other parts of the application (views, actions, menus, etc.), so that
+// it is suitable for command line tools that only introspect the models.
+//
+// Models cannot be used with a database before BootStrap is called.
+func BootStrapMetadata() {
+	log.Info("Bootstrapping models metadata")
+	Registry.Lock()
+	defer Registry.Unlock()
+	if Registry.metadataBootstrapped {
+		log.Panic("Trying to bootstrap models metadata twice !")
+	}
+	bootStrapMetadata()
+}
+
+// bootStrapMetadata runs the steps of the bootstrap that only
+// concern the models metadata. Registry must be locked.
+func bootStrapMetadata() {
 	inflateMixIns()
+	processRemovalsAndRenames()
 	createModelLinks()
 	inflateEmbeddings()
 	processUpdates()
+	checkRemovedFieldsUsage()
 	updateFieldDefs()
 	updateRelatedPaths()
 	syncRelatedFieldInfo()
@@ -63,10 +99,14 @@ func BootStrap() {
 	processDepends()
 	checkFieldMethodsExist()
 	checkComputeMethodsSignature()
-	setupSecurity()
-	RegisterWorker(NewWorkerFunction(FreeTransientModels, freeTransientPeriod))
 
-	Registry.bootstrapped = true
+	Registry.metadataBootstrapped = true
+}
+
+// MetadataBootStrapped returns true if the models metadata have been
+// bootstrapped, either by BootStrapMetadata or by BootStrap.
+func MetadataBootStrapped() bool {
+	return Registry.metadataBootstrapped
 }
 
 // BootStrapped returns true if the models have been bootstrapped
@@ -74,21 +114,38 @@ func BootStrapped() bool {
 	return Registry.bootstrapped
 }
 
+// additiveUpdates are the properties of updates that add to the
+// value of a field property instead of overriding it
+var additiveUpdates = map[string]bool{
+	"selection_add":    true,
+	"selection_remove": true,
+	"contexts_add":     true,
+	"validators_add":   true,
+}
+
 // processUpdates applies all the directives of the update map to the fields
 func processUpdates() {
 	for _, model := range Registry.registryByName {
 		for _, fi := range model.fields.registryByName {
+			detectUpdateConflicts(fi)
 			for _, update := range fi.updates {
 				for property, value := range update {
 					switch property {
+					case updateModuleKey:
 					case "selection_add":
 						for k, v := range value.(types.Selection) {
 							fi.selection[k] = v
 						}
+					case "selection_remove":
+						for _, k := range value.([]string) {
+							delete(fi.selection, k)
+						}
 					case "contexts_add":
 						for k, v := range value.(FieldContexts) {
 							fi.contexts[k] = v
 						}
+					case "validators_add":
+						fi.validators = append(fi.validators, value.([]FieldValidator)...)
 					default:
 						fi.SetProperty(property, value)
 					}
@@ -99,6 +156,223 @@ func processUpdates() {
 	}
 }
 
+// updateProperty returns the property of the given update entry and
+// the name of the module that made it
+func updateProperty(update map[string]interface{}) (string, string) {
+	var property string
+	for key := range update {
+		if key != updateModuleKey {
+			property = key
+		}
+	}
+	module, _ := update[updateModuleKey].(string)
+	return property, module
+}
+
+// detectUpdateConflicts reports the properties of the given field that are
+// overridden by several modules other than the module that declared the field,
+// since the result depends on the order in which the modules are loaded.
+func detectUpdateConflicts(fi *Field) {
+	modules := make(map[string][]string)
+	var properties []string
+	for _, update := range fi.updates {
+		property, module := updateProperty(update)
+		if additiveUpdates[property] || module == "" || module == fi.module {
+			continue
+		}
+		if len(modules[property]) == 0 {
+			properties = append(properties, property)
+		}
+		if !strutils.IsIn(module, modules[property]...) {
+			modules[property] = append(modules[property], module)
+		}
+	}
+	for _, property := range properties {
+		if len(modules[property]) < 2 {
+			continue
+		}
+		log.Warn("Field property overridden by several modules", "model", fi.model.name, "field", fi.name,
+			"property", property, "modules", modules[property])
+		Registry.fieldConflicts = append(Registry.fieldConflicts, FieldConflict{
+			Model:    fi.model.name,
+			Field:    fi.name,
+			Property: property,
+			Modules:  modules[property],
+		})
+	}
+}
+
+// processRemovalsAndRenames removes the fields removed with Model.RemoveField,
+// changes the type of the fields given to Model.ChangeFieldType and renames the
+// fields renamed with Model.RenameField. It panics if a removed field is modified
+// by another module or if a field is renamed or retyped differently by several
+// modules.
+func processRemovalsAndRenames() {
+	Registry.removedFields = make(map[*Model]map[string]string)
+	for _, model := range Registry.registryByName {
+		var fields []*Field
+		for _, fi := range model.fields.registryByName {
+			fields = append(fields, fi)
+		}
+		for _, fi := range fields {
+			var (
+				removedBy     []string
+				modifiedBy    []string
+				newName       string
+				newField      *Field
+				newFieldOwner string
+				updates       []map[string]interface{}
+			)
+			for _, update := range fi.updates {
+				property, module := updateProperty(update)
+				switch property {
+				case "remove":
+					removedBy = append(removedBy, module)
+					continue
+				case "rename":
+					if newName != "" && newName != update[property].(string) {
+						log.Panic("Field renamed differently by several modules", "model", model.name, "field", fi.name,
+							"name1", newName, "name2", update[property], "module", module)
+					}
+					newName = update[property].(string)
+					modifiedBy = append(modifiedBy, module)
+					continue
+				case "type":
+					if newField != nil && newFieldOwner != module {
+						log.Panic("Field type changed by several modules", "model", model.name, "field", fi.name,
+							"module1", newFieldOwner, "module2", module)
+					}
+					newField, newFieldOwner = update[property].(*Field), module
+					modifiedBy = append(modifiedBy, module)
+					continue
+				}
+				modifiedBy = append(modifiedBy, module)
+				updates = append(updates, update)
+			}
+			fi.updates = updates
+			if len(removedBy) > 0 {
+				for _, module := range modifiedBy {
+					if !strutils.IsIn(module, removedBy...) {
+						log.Panic("Field removed by a module is modified by another", "model", model.name, "field", fi.name,
+							"removedBy", removedBy, "modifiedBy", module)
+					}
+				}
+				model.fields.remove(fi)
+				recordRemovedField(fi, "removed")
+				continue
+			}
+			if newField != nil {
+				fi = changeFieldType(fi, newField)
+			}
+			if newName != "" && newName != fi.name {
+				recordRemovedField(fi, "renamed to "+newName)
+				renameField(fi, newName)
+			}
+		}
+	}
+}
+
+// recordRemovedField records that the given field has been removed or
+// renamed, so that checkRemovedFieldsUsage can find the fields that still
+// refer to it. change describes what happened to the field.
+func recordRemovedField(fi *Field, change string) {
+	if Registry.removedFields[fi.model] == nil {
+		Registry.removedFields[fi.model] = make(map[string]string)
+	}
+	Registry.removedFields[fi.model][fi.name] = change
+	Registry.removedFields[fi.model][fi.json] = change
+}
+
+// changeFieldType replaces the given field by a copy of newField with the name,
+// the module and the updates of fi and returns it. If the column of the field
+// changes, it is renamed in the database.
+func changeFieldType(fi, newField *Field) *Field {
+	nf := *newField
+	nf.model = fi.model
+	nf.name = fi.name
+	nf.module = fi.module
+	nf.updates = fi.updates
+	nf.renamedFrom = fi.renamedFrom
+	if fi.hasColumn() && nf.hasColumn() && fi.json != nf.json && nf.renamedFrom == "" {
+		nf.renamedFrom = fi.json
+	}
+	fi.model.fields.remove(fi)
+	fi.model.fields.register(&nf)
+	return &nf
+}
+
+// renameField renames the given field with the given name, and records
+// the rename so that the column of the field is renamed in the database.
+func renameField(fi *Field, newName string) {
+	if _, exists := fi.model.fields.registryByName[newName]; exists {
+		log.Panic("Field cannot be renamed with the name of another field", "model", fi.model.name, "field", fi.name, "newName", newName)
+	}
+	oldColumn := fi.json
+	if fi.renamedFrom != "" {
+		// The column has already been changed by ChangeFieldType
+		oldColumn = fi.renamedFrom
+	}
+	rename := FieldRename{
+		Model:     fi.model.name,
+		OldName:   fi.name,
+		NewName:   newName,
+		OldColumn: oldColumn,
+	}
+	fi.model.fields.remove(fi)
+	fi.name = newName
+	fi.json = SnakeCaseFieldName(newName, fi.fieldType)
+	fi.structField.Name = newName
+	fi.renamedFrom = ""
+	if fi.hasColumn() && rename.OldColumn != fi.json {
+		fi.renamedFrom = rename.OldColumn
+	}
+	fi.model.fields.register(fi)
+	rename.NewColumn = fi.json
+	Registry.fieldRenames = append(Registry.fieldRenames, rename)
+}
+
+// checkRemovedFieldsUsage panics if a field removed or renamed by Model.RemoveField
+// or Model.RenameField is still used by another field, either in its related path,
+// in the dependencies of its compute method or as its reverse field.
+func checkRemovedFieldsUsage() {
+	if len(Registry.removedFields) == 0 {
+		return
+	}
+	for _, model := range Registry.registryByName {
+		for _, fi := range model.fields.registryByName {
+			if fi.relatedPathStr != "" {
+				checkPathNotRemoved(fi, "related", model, fi.relatedPathStr)
+			}
+			for _, dep := range fi.depends {
+				checkPathNotRemoved(fi, "depends", model, dep)
+			}
+			if fi.reverseFK != "" && fi.relatedModel != nil {
+				checkPathNotRemoved(fi, "reverseFK", fi.relatedModel, fi.reverseFK)
+			}
+		}
+	}
+}
+
+// checkPathNotRemoved panics if the given path of fields, starting from the
+// given model, goes through a removed or renamed field. usage is the property
+// of fi that holds the path.
+func checkPathNotRemoved(fi *Field, usage string, model *Model, path string) {
+	for _, token := range strings.Split(path, ExprSep) {
+		next, ok := model.fields.Get(token)
+		if !ok {
+			if change, removed := Registry.removedFields[model][token]; removed {
+				log.Panic("Field removed or renamed by a module is still used by another field", "model", fi.model.name,
+					"field", fi.name, usage, path, "usedModel", model.name, "usedField", token, "change", change)
+			}
+			return
+		}
+		if next.relatedModel == nil {
+			return
+		}
+		model = next.relatedModel
+	}
+}
+
 // updateFieldDefs updates fields definitions if necessary
 func updateFieldDefs() {
 	for _, model := range Registry.registryByName {
@@ -193,6 +467,7 @@ func addMixinMethods(mixinModel, model *Model) {
 					funcValue: wrapFunctionForMethodLayer(lf.funcValue),
 					mixedIn:   true,
 					method:    emi,
+					module:    lf.module,
 				}
 				emi.nextLayer[&ml] = firstMixedLayer
 				firstMixedLayer = &ml
@@ -204,11 +479,17 @@ func addMixinMethods(mixinModel, model *Model) {
 			} else {
 				emi.nextLayer[lastImplLayer] = firstMixedLayer
 			}
+			if methInfo.private {
+				emi.private = true
+			}
+			for group := range methInfo.requiredGroups {
+				emi.RequireGroups(group)
+			}
 		} else {
 			// The method does not exist
 			newMethInfo := copyMethod(model, methInfo)
 			for i := 0; i < len(layersInv); i++ {
-				newMethInfo.addMethodLayer(layersInv[i].funcValue)
+				newMethInfo.addMethodLayer(layersInv[i].funcValue, layersInv[i].module)
 			}
 			model.methods.set(methName, newMethInfo)
 		}
@@ -0,0 +1,104 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultMaxCallDepth is the default maximum number of nested method
+// calls, including calls to Super, during a transaction.
+const DefaultMaxCallDepth = 100
+
+// maxCallDepth is the maximum number of nested method calls
+var maxCallDepth int32 = DefaultMaxCallDepth
+
+// maxReportedFrames is the number of calls listed in the diagnostic
+// of the max call depth when no loop is found
+const maxReportedFrames = 10
+
+// SetMaxCallDepth sets the maximum number of nested method calls, including
+// calls to Super, after which method calls panic. A depth lower than 1 resets
+// the limit to DefaultMaxCallDepth.
+func SetMaxCallDepth(depth int) {
+	if depth < 1 {
+		depth = DefaultMaxCallDepth
+	}
+	atomic.StoreInt32(&maxCallDepth, int32(depth))
+}
+
+// MaxCallDepth returns the maximum number of nested method calls
+func MaxCallDepth() int {
+	return int(atomic.LoadInt32(&maxCallDepth))
+}
+
+// A callFrame is a method call in the call stack of an Environment
+type callFrame struct {
+	model  string
+	method string
+	super  bool
+	parent *callFrame
+}
+
+// String returns the model and method of this call frame,
+// marking calls to Super.
+func (cf *callFrame) String() string {
+	if cf.super {
+		return fmt.Sprintf("%s.%s (super)", cf.model, cf.method)
+	}
+	return fmt.Sprintf("%s.%s", cf.model, cf.method)
+}
+
+// frames returns the calls of the stack ending with this frame,
+// the most recent first.
+func (cf *callFrame) frames() []string {
+	var res []string
+	for f := cf; f != nil; f = f.parent {
+		res = append(res, f.String())
+	}
+	return res
+}
+
+// callLoop returns the smallest sequence of calls that is repeated at the top
+// of the given frames, the most recent first, in calling order. It returns nil
+// if the top frames do not repeat.
+func callLoop(frames []string) []string {
+	for period := 1; 2*period <= len(frames); period++ {
+		repeated := true
+		for i := 0; i < period; i++ {
+			if frames[i] != frames[i+period] {
+				repeated = false
+				break
+			}
+		}
+		if !repeated {
+			continue
+		}
+		res := make([]string, period+1)
+		for i := 0; i <= period; i++ {
+			res[i] = frames[period-i]
+		}
+		return res
+	}
+	return nil
+}
+
+// checkCallDepth panics if calling the given method of the given model in
+// this Environment would exceed the maximum call depth. The panic lists the
+// loop of calls that caused it, or the latest calls if there is no loop.
+func (env Environment) checkCallDepth(model, method string) {
+	if env.recursions <= MaxCallDepth() {
+		return
+	}
+	frames := (&callFrame{model: model, method: method, super: env.super, parent: env.callStack}).frames()
+	if loop := callLoop(frames); loop != nil {
+		log.Panic("Max call depth exceeded", "depth", env.recursions, "loop", strings.Join(loop, " -> "))
+	}
+	if len(frames) > maxReportedFrames {
+		frames = frames[:maxReportedFrames]
+	}
+	log.Panic("Max call depth exceeded", "depth", env.recursions, "latestCalls", strings.Join(frames, " <- "))
+}
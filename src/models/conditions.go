@@ -442,6 +442,22 @@ func (c *Condition) substituteExprs(mi *Model, substs map[FieldName][]FieldName)
 	}
 }
 
+// withPrefix returns a copy of this condition with all its exprs
+// prefixed by the given exprs.
+func (c Condition) withPrefix(prefix []FieldName) *Condition {
+	res := Condition{predicates: make([]predicate, len(c.predicates))}
+	for i, p := range c.predicates {
+		res.predicates[i] = p
+		if len(p.exprs) > 0 {
+			res.predicates[i].exprs = append(append([]FieldName{}, prefix...), p.exprs...)
+		}
+		if p.cond != nil {
+			res.predicates[i].cond = p.cond.withPrefix(prefix)
+		}
+	}
+	return &res
+}
+
 // substituteChildOfOperator recursively replaces in the condition the
 // predicates with ChildOf operator by the predicates to actually execute.
 func (c *Condition) substituteChildOfOperator(rc *RecordCollection) {
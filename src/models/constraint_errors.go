@@ -0,0 +1,96 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/tools/strutils"
+)
+
+// A constraintKind is the kind of a database constraint
+type constraintKind string
+
+// Kinds of database constraints
+const (
+	uniqueConstraint     constraintKind = "unique"
+	checkConstraint      constraintKind = "check"
+	foreignKeyConstraint constraintKind = "foreign_key"
+	notNullConstraint    constraintKind = "not_null"
+)
+
+// A constraintViolation is a database constraint violation
+// extracted from a database error by the adapter.
+type constraintViolation struct {
+	kind       constraintKind
+	constraint string
+	table      string
+	columns    []string
+}
+
+// translateSQLError returns the given recover data translated into
+// ValidationErrors if it is a database constraint violation, and unchanged
+// otherwise. unlinking must be true if records were being deleted.
+//
+// There is one ValidationError per field concerned by the constraint, all
+// with the same message, or a single one without field if they are unknown.
+func (rc *RecordCollection) translateSQLError(r interface{}, unlinking bool) interface{} {
+	err, ok := r.(error)
+	if !ok {
+		return r
+	}
+	cv, ok := adapters[db.DriverName()].constraintViolation(err)
+	if !ok {
+		return r
+	}
+	model := rc.model
+	if m, exists := Registry.Get(cv.table); exists && cv.table != "" {
+		model = m
+	}
+	lang := rc.env.Lang()
+	translations := i18n.ForDatabase(rc.env.DBName(), lang)
+	modelDesc := translations.TranslateCode(lang, "", strutils.Title(model.name))
+	var (
+		fieldNames []string
+		labels     []string
+	)
+	for _, column := range cv.columns {
+		fi, exists := model.fields.Get(column)
+		if !exists {
+			continue
+		}
+		fieldNames = append(fieldNames, fi.name)
+		labels = append(labels, fmt.Sprintf("'%s'", translations.TranslateFieldDescription(lang, model.name, fi.name, fi.description)))
+	}
+	fields := strings.Join(labels, ", ")
+	if fields == "" {
+		fields = rc.T("The value")
+	}
+	var msg string
+	constraint, declared := model.sqlConstraints[cv.constraint]
+	switch {
+	case declared:
+		msg = translations.TranslateCode(lang, "", constraint.errorString)
+	case cv.kind == uniqueConstraint:
+		msg = rc.T("%s must be unique: another %s record has the same value", fields, modelDesc)
+	case cv.kind == foreignKeyConstraint && unlinking:
+		msg = rc.T("The record cannot be deleted because it is referenced by the %s field of a %s record", fields, modelDesc)
+	case cv.kind == foreignKeyConstraint:
+		msg = rc.T("%s must reference an existing record", fields)
+	case cv.kind == notNullConstraint:
+		msg = rc.T("%s is required", fields)
+	default:
+		msg = rc.T("The values of the %s record do not satisfy the constraint %s", modelDesc, cv.constraint)
+	}
+	if len(fieldNames) == 0 {
+		return ValidationErrors{{Model: model.name, Message: msg, Err: err}}
+	}
+	res := make(ValidationErrors, len(fieldNames))
+	for i, fName := range fieldNames {
+		res[i] = ValidationError{Model: model.name, Field: fName, Message: msg, Err: err}
+	}
+	return res
+}
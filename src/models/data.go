@@ -17,22 +17,14 @@ import (
 	"github.com/hexya-erp/hexya/src/models/security"
 )
 
-// LoadCSVDataFile loads the data of the given file into the database.
-func LoadCSVDataFile(fileName string) {
-	log.Info("Importing data file", "fileName", fileName)
-	csvFile, err := os.Open(fileName)
-	if err != nil {
-		log.Panic("Unable to open CSV data file", "error", err, "fileName", fileName)
-	}
-	defer csvFile.Close()
-
+// ParseDataFileName returns the name of the model of the records of the given
+// CSV data file, which is named [SEQ-]ModelName[_update|_VERSION].csv, e.g.
+// 010-User_update.csv. update is true if the file only updates existing
+// records and version is the version of the records of the file.
+func ParseDataFileName(fileName string) (modelName string, update bool, version int) {
 	elements := strings.Split(filepath.Base(fileName), "_")
-	modelName := strings.Split(elements[0], ".")[0]
+	modelName = strings.Split(elements[0], ".")[0]
 	modelName = strings.TrimLeft(modelName, "01234567890-")
-	var (
-		update  bool
-		version int
-	)
 	if len(elements) == 2 {
 		mod := strings.Split(elements[1], ".")[0]
 		ver, err := strconv.Atoi(mod)
@@ -43,6 +35,19 @@ func LoadCSVDataFile(fileName string) {
 			version = ver
 		}
 	}
+	return
+}
+
+// LoadCSVDataFile loads the data of the given file into the database.
+func LoadCSVDataFile(fileName string) {
+	log.Info("Importing data file", "fileName", fileName)
+	csvFile, err := os.Open(fileName)
+	if err != nil {
+		log.Panic("Unable to open CSV data file", "error", err, "fileName", fileName)
+	}
+	defer csvFile.Close()
+
+	modelName, update, version := ParseDataFileName(fileName)
 
 	r := csv.NewReader(csvFile)
 	headers, err := r.Read()
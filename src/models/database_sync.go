@@ -10,6 +10,15 @@ import (
 	"github.com/hexya-erp/hexya/src/models/security"
 )
 
+const (
+	// codeSortKeySuffix is the suffix of the name of the generated columns
+	// holding the sort keys of code ordered fields
+	codeSortKeySuffix = "_sort_key"
+	// codeSortKeyFunction is the name of the SQL function
+	// that computes the sort keys of code ordered fields
+	codeSortKeyFunction = "hexya_code_sort_key"
+)
+
 // SyncDatabase creates or updates database tables with the data in the model registry
 func SyncDatabase() {
 	log.Info("Updating database schema")
@@ -17,6 +26,7 @@ func SyncDatabase() {
 	dbTables := adapter.tables()
 	// Create or update sequences
 	updateDBSequences()
+	adapter.createCodeSortKeyFunction()
 	// Create or update existing tables
 	for tableName, model := range Registry.registryByTableName {
 		if model.IsMixin() || model.IsManual() {
@@ -83,7 +93,7 @@ func buildSQLErrorSubstitutionMap(model *Model) {
 func updateDBSequences() {
 	adapter := adapters[db.DriverName()]
 	// Create or alter boot sequences
-	for _, sequence := range Registry.sequences {
+	for _, sequence := range Registry.getSequences() {
 		if !sequence.boot {
 			continue
 		}
@@ -102,7 +112,7 @@ func updateDBSequences() {
 	// Drop unused boot sequences
 	for _, dbSeq := range adapter.sequences("%_bootseq") {
 		var sequenceExists bool
-		for _, sequence := range Registry.sequences {
+		for _, sequence := range Registry.getSequences() {
 			if sequence.JSON == dbSeq.Name {
 				sequenceExists = true
 				break
@@ -120,7 +130,7 @@ func createDBTable(m *Model) {
 	adapter := adapters[db.DriverName()]
 	var columns []string
 	for colName, fi := range m.fields.registryByJSON {
-		if colName == "id" || !fi.isStored() {
+		if colName == "id" || !fi.hasColumn() {
 			continue
 		}
 		col := fmt.Sprintf("%s %s", colName, adapter.columnSQLDefinition(fi, false))
@@ -151,10 +161,16 @@ func updateDBColumns(mi *Model) {
 	dbColumns := adapter.columns(mi.tableName)
 	// create or update columns from registry data
 	for colName, fi := range mi.fields.registryByJSON {
-		if colName == "id" || !fi.isStored() {
+		if colName == "id" || !fi.hasColumn() {
 			continue
 		}
 		dbColData, ok := dbColumns[colName]
+		if _, oldExists := dbColumns[fi.renamedFrom]; !ok && fi.renamedFrom != "" && oldExists {
+			renameDBColumn(mi.tableName, fi.renamedFrom, colName)
+			dbColData, ok = dbColumns[fi.renamedFrom], true
+			dbColumns[colName] = dbColData
+			delete(dbColumns, fi.renamedFrom)
+		}
 		if !ok {
 			createDBColumn(fi)
 			continue
@@ -167,17 +183,41 @@ func updateDBColumns(mi *Model) {
 			updateDBColumnNullable(fi)
 		}
 	}
-	// drop columns that no longer exist
+	// create the sort key columns of code ordered fields
+	for _, fi := range mi.fields.registryByJSON {
+		if !fi.codeOrder {
+			continue
+		}
+		if _, ok := dbColumns[fi.codeSortKeyColumn()]; !ok {
+			createDBSortKeyColumn(fi)
+		}
+	}
+	// drop columns that no longer exist, starting with sort
+	// key columns that depend on the column of their field
 	for colName := range dbColumns {
-		if _, ok := mi.fields.registryByJSON[colName]; !ok {
+		if _, ok := mi.fields.registryByJSON[colName]; ok || !strings.HasSuffix(colName, codeSortKeySuffix) {
+			continue
+		}
+		fi, ok := mi.fields.registryByJSON[strings.TrimSuffix(colName, codeSortKeySuffix)]
+		if !ok || !fi.codeOrder {
 			dropDBColumn(mi.tableName, colName)
+			delete(dbColumns, colName)
+		}
+	}
+	for colName := range dbColumns {
+		if fi, ok := mi.fields.registryByJSON[colName]; ok && fi.hasColumn() {
+			continue
+		}
+		if fi, ok := mi.fields.registryByJSON[strings.TrimSuffix(colName, codeSortKeySuffix)]; ok && fi.codeOrder {
+			continue
 		}
+		dropDBColumn(mi.tableName, colName)
 	}
 }
 
 // createDBColumn insert the column described by Field in the database
 func createDBColumn(fi *Field) {
-	if !fi.isStored() {
+	if !fi.hasColumn() {
 		log.Panic("createDBColumn should not be called on non stored fields", "model", fi.model.name, "field", fi.json)
 	}
 	adapter := adapters[db.DriverName()]
@@ -202,13 +242,26 @@ func createDBColumn(fi *Field) {
 	updateDBColumnNullable(fi)
 }
 
-// updateDBColumnDataType updates the data type in database for the given Field
+// createDBSortKeyColumn creates the generated column
+// holding the sort key of the given code ordered field.
+func createDBSortKeyColumn(fi *Field) {
+	adapter := adapters[db.DriverName()]
+	query := fmt.Sprintf(`
+		ALTER TABLE %s
+		ADD COLUMN %s %s
+	`, adapter.quoteTableName(fi.model.tableName), fi.codeSortKeyColumn(), adapter.codeSortKeySQL(fi))
+	dbExecuteNoTx(query)
+}
+
+// updateDBColumnDataType updates the data type in database for the given Field.
+// Existing values are cast to the new type, e.g. when the type of the field
+// has been changed with Model.ChangeFieldType.
 func updateDBColumnDataType(fi *Field) {
 	adapter := adapters[db.DriverName()]
 	query := fmt.Sprintf(`
 		ALTER TABLE %s
-		ALTER COLUMN %s SET DATA TYPE %s
-	`, adapter.quoteTableName(fi.model.tableName), fi.json, adapter.typeSQL(fi))
+		ALTER COLUMN %s SET DATA TYPE %s USING %s::%s
+	`, adapter.quoteTableName(fi.model.tableName), fi.json, adapter.typeSQL(fi), fi.json, adapter.typeSQL(fi))
 	dbExecuteNoTx(query)
 }
 
@@ -232,6 +285,16 @@ func updateDBColumnNullable(fi *Field) {
 	}
 }
 
+// renameDBColumn renames the given column of the given table to newName
+func renameDBColumn(tableName, colName, newName string) {
+	adapter := adapters[db.DriverName()]
+	query := fmt.Sprintf(`
+		ALTER TABLE %s
+		RENAME COLUMN %s TO %s
+	`, adapter.quoteTableName(tableName), colName, newName)
+	dbExecuteNoTx(query)
+}
+
 // dropDBColumn drops the column colName from table tableName in database
 func dropDBColumn(tableName, colName string) {
 	adapter := adapters[db.DriverName()]
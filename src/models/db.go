@@ -20,11 +20,13 @@ import (
 
 	"github.com/hexya-erp/hexya/src/models/operator"
 	"github.com/hexya-erp/hexya/src/tools/strutils"
+	"github.com/hexya-erp/hexya/src/tools/tracing"
 	"github.com/jmoiron/sqlx"
 )
 
 var (
 	db       *sqlx.DB
+	dbName   string
 	adapters map[string]dbAdapter
 )
 
@@ -85,6 +87,16 @@ type dbAdapter interface {
 	// setTransactionIsolation returns the SQL string to set the transaction isolation
 	// level to serializable
 	setTransactionIsolation() string
+	// setSnapshotIsolation returns the SQL string to set the transaction
+	// isolation level to repeatable read in read only mode
+	setSnapshotIsolation() string
+	// snapshotQuery returns the SQL query that returns the isolation level,
+	// the read only mode, the snapshot and the start time of the current
+	// transaction
+	snapshotQuery() string
+	// exportSnapshotQuery returns the SQL query that exports the snapshot of
+	// the current transaction and returns its identifier
+	exportSnapshotQuery() string
 	// createSequence creates a DB sequence with the given name
 	createSequence(name string, increment, start int64)
 	// dropSequence drop the DB sequence with the given name
@@ -99,8 +111,15 @@ type dbAdapter interface {
 	// a record from table including itself. The query has a placeholder for the
 	// record's ID
 	childrenIdsQuery(table string) string
-	// substituteErrorMessage substitutes the given error's message by newMsg
-	substituteErrorMessage(err error, newMsg string) error
+	// createCodeSortKeyFunction creates or replaces the SQL function that
+	// computes the sort keys of code ordered fields
+	createCodeSortKeyFunction()
+	// codeSortKeySQL returns the SQL definition of the generated column
+	// holding the sort key of the given code ordered field
+	codeSortKeySQL(fi *Field) string
+	// constraintViolation returns the constraint violation of the given error.
+	// The second returned value is false if it is not a constraint violation.
+	constraintViolation(err error) (constraintViolation, bool)
 	// isSerializationError returns true if the given error is a serialization error
 	// and that the failed transaction should be retried.
 	isSerializationError(err error) bool
@@ -114,42 +133,91 @@ func registerDBAdapter(name string, adapter dbAdapter) {
 
 // Cursor is a wrapper around a database transaction
 type Cursor struct {
-	tx *sqlx.Tx
+	tx   *sqlx.Tx
+	span *tracing.Span
+	// startSeq is the invalidation sequence number of shared caches
+	// when this cursor was opened
+	startSeq uint64
+	// modifiedTables are the tables modified in this transaction
+	modifiedTables map[string]bool
+	// snapshot is true if this cursor is a read only snapshot transaction
+	snapshot bool
+	// snapshotID is the identifier of the exported snapshot of this transaction
+	snapshotID string
 }
 
 // Execute a query without returning any rows. It panics in case of error.
 // The args are for any placeholder parameters in the query.
 func (c *Cursor) Execute(query string, args ...interface{}) sql.Result {
-	return dbExecute(c.tx, query, args...)
+	span, completed := c.startQuerySpan(query), false
+	defer endSpan(span, &completed)
+	res := dbExecute(c.tx, query, args...)
+	completed = true
+	return res
 }
 
 // Get queries a row into the database and maps the result into dest.
 // The query must return only one row. Get panics on errors
 func (c *Cursor) Get(dest interface{}, query string, args ...interface{}) {
+	span, completed := c.startQuerySpan(query), false
+	defer endSpan(span, &completed)
 	dbGet(c.tx, dest, query, args...)
+	completed = true
 }
 
 // Select queries multiple rows and map the result into dest which must be a slice.
 // Select panics on errors.
 func (c *Cursor) Select(dest interface{}, query string, args ...interface{}) {
+	span, completed := c.startQuerySpan(query), false
+	defer endSpan(span, &completed)
 	dbSelect(c.tx, dest, query, args...)
+	completed = true
 }
 
-// newCursor returns a new db cursor on the given database
-func newCursor(db *sqlx.DB) *Cursor {
+// query queries multiple rows and returns them. It panics on errors.
+func (c *Cursor) query(query string, args ...interface{}) *sqlx.Rows {
+	span, completed := c.startQuerySpan(query), false
+	defer endSpan(span, &completed)
+	rows := dbQuery(c.tx, query, args...)
+	completed = true
+	return rows
+}
+
+// newCursor returns a new db cursor on the given database.
+//
+// If snapshot is true, the transaction is read only and sees a consistent
+// snapshot of the database taken at its first query. Otherwise, it is a
+// serializable transaction.
+func newCursor(db *sqlx.DB, snapshot bool) *Cursor {
 	adapter := adapters[db.DriverName()]
 	tx := db.MustBegin()
-	dbExecute(tx, adapter.setTransactionIsolation())
+	if snapshot {
+		dbExecute(tx, adapter.setSnapshotIsolation())
+	} else {
+		dbExecute(tx, adapter.setTransactionIsolation())
+	}
 	return &Cursor{
-		tx: tx,
+		tx:       tx,
+		startSeq: invalidationSeq(),
+		snapshot: snapshot,
+	}
+}
+
+// ConnectionString returns the connection string of the given driver
+// for the given parameters. It panics if the driver is not supported.
+func ConnectionString(driver string, params ConnectionParams) string {
+	adapter, ok := adapters[driver]
+	if !ok {
+		log.Panic("Unsupported database driver", "driver", driver)
 	}
+	return adapter.connectionString(params)
 }
 
 // DBConnect connects to a database using the given driver and arguments.
 func DBConnect(driver string, params ConnectionParams) {
-	adapter := adapters[driver]
-	connData := adapter.connectionString(params)
+	connData := ConnectionString(driver, params)
 	db = sqlx.MustConnect(driver, connData)
+	dbName = params.DBName
 	log.Info("Connected to database", "driver", driver, "connData", connData)
 }
 
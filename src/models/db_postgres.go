@@ -16,6 +16,8 @@ package models
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
 	"github.com/hexya-erp/hexya/src/models/operator"
@@ -51,6 +53,7 @@ var pgTypes = map[fieldtype.Type]string{
 	fieldtype.Integer:   "integer",
 	fieldtype.Float:     "numeric",
 	fieldtype.HTML:      "text",
+	fieldtype.Markdown:  "text",
 	fieldtype.Binary:    "bytea",
 	fieldtype.Selection: "character varying",
 	fieldtype.Many2One:  "integer",
@@ -116,7 +119,8 @@ func (d *postgresAdapter) columnSQLDefinition(fi *Field, null bool) string {
 	}
 	switch fi.fieldType {
 	case fieldtype.Char:
-		if fi.size > 0 {
+		// Encrypted values are longer than the field size
+		if fi.size > 0 && !fi.encrypted {
 			res = fmt.Sprintf("%s(%d)", res, fi.size)
 		}
 	case fieldtype.Float:
@@ -251,6 +255,28 @@ func (d *postgresAdapter) setTransactionIsolation() string {
 	return "SET TRANSACTION ISOLATION LEVEL SERIALIZABLE"
 }
 
+// setSnapshotIsolation returns the SQL string to set the transaction
+// isolation level to repeatable read in read only mode
+func (d *postgresAdapter) setSnapshotIsolation() string {
+	return "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY"
+}
+
+// snapshotQuery returns the SQL query that returns the isolation level,
+// the read only mode, the snapshot and the start time of the current
+// transaction
+func (d *postgresAdapter) snapshotQuery() string {
+	return `SELECT current_setting('transaction_isolation') AS isolation,
+	current_setting('transaction_read_only') = 'on' AS read_only,
+	txid_current_snapshot()::text AS txids,
+	now() AS started_at`
+}
+
+// exportSnapshotQuery returns the SQL query that exports the snapshot of
+// the current transaction and returns its identifier
+func (d *postgresAdapter) exportSnapshotQuery() string {
+	return "SELECT pg_export_snapshot()"
+}
+
 // childrenIdsQuery returns a query that finds all descendant of the given
 // a record from table including itself. The query has a placeholder for the
 // record's ID
@@ -272,14 +298,67 @@ FROM    recursive_query_children_ids`, d.quoteTableName(table), d.quoteTableName
 	return res
 }
 
-// substituteErrorMessage substitutes the given error's message by newMsg
-func (d *postgresAdapter) substituteErrorMessage(err error, newMsg string) error {
-	pgError, ok := err.(*pq.Error)
+// createCodeSortKeyFunction creates or replaces the SQL function that
+// computes the sort keys of code ordered fields.
+//
+// Numbers are padded with zeros to 20 digits so that sort keys can be
+// compared as strings, e.g. '1.2.10' gives '00000000000000000001.
+// 00000000000000000002.00000000000000000010' (without space).
+func (d *postgresAdapter) createCodeSortKeyFunction() {
+	query := fmt.Sprintf(`
+CREATE OR REPLACE FUNCTION %s(code text) RETURNS text AS $$
+	SELECT  coalesce(string_agg(
+				CASE WHEN part[1] ~ '^[0-9]+$' THEN lpad(part[1], greatest(20, length(part[1])), '0') ELSE part[1] END,
+				'' ORDER BY num), '')
+	FROM    regexp_matches(code, '([0-9]+|[^0-9]+)', 'g') WITH ORDINALITY AS parts(part, num)
+$$ LANGUAGE SQL IMMUTABLE STRICT`, codeSortKeyFunction)
+	dbExecuteNoTx(query)
+}
+
+// codeSortKeySQL returns the SQL definition of the generated column
+// holding the sort key of the given code ordered field
+func (d *postgresAdapter) codeSortKeySQL(fi *Field) string {
+	return fmt.Sprintf(`text COLLATE "C" GENERATED ALWAYS AS (%s(%s)) STORED`, codeSortKeyFunction, fi.json)
+}
+
+// postgresConstraintKinds are the kinds of constraints of
+// the SQLSTATE codes of constraint violations
+var postgresConstraintKinds = map[pq.ErrorCode]constraintKind{
+	"23505": uniqueConstraint,
+	"23514": checkConstraint,
+	"23503": foreignKeyConstraint,
+	"23502": notNullConstraint,
+}
+
+// postgresKeyDetail matches the detail of unique and foreign key
+// violations, which starts with the columns of the key
+var postgresKeyDetail = regexp.MustCompile(`^Key \(([^)]*)\)=`)
+
+// constraintViolation returns the constraint violation of the given error.
+// The second returned value is false if it is not a constraint violation.
+func (d *postgresAdapter) constraintViolation(err error) (constraintViolation, bool) {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return constraintViolation{}, false
+	}
+	kind, ok := postgresConstraintKinds[pqErr.Code]
 	if !ok {
-		return err
+		return constraintViolation{}, false
+	}
+	res := constraintViolation{kind: kind, constraint: pqErr.Constraint, table: pqErr.Table}
+	fkColumn := strings.TrimSuffix(strings.TrimPrefix(pqErr.Constraint, pqErr.Table+"_"), "_fkey")
+	switch {
+	case kind == foreignKeyConstraint && fkColumn != pqErr.Constraint:
+		// The key of the detail is the referenced key when deleting
+		res.columns = []string{fkColumn}
+	case pqErr.Column != "":
+		res.columns = []string{pqErr.Column}
+	case postgresKeyDetail.MatchString(pqErr.Detail):
+		for _, column := range strings.Split(postgresKeyDetail.FindStringSubmatch(pqErr.Detail)[1], ",") {
+			res.columns = append(res.columns, strings.Trim(strings.TrimSpace(column), `"`))
+		}
 	}
-	pgError.Message = newMsg
-	return pgError
+	return res, true
 }
 
 // isSerializationError returns true if the given error is a serialization error
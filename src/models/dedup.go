@@ -0,0 +1,107 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+)
+
+// checkTrigramExtension panics if the pg_trgm extension is not installed in the database
+func (rc *RecordCollection) checkTrigramExtension() {
+	var count int
+	rc.env.cr.Get(&count, `SELECT COUNT(*) FROM pg_extension WHERE extname = 'pg_trgm'`)
+	if count == 0 {
+		log.Panic("The pg_trgm extension must be installed in the database to find duplicates. Run 'CREATE EXTENSION pg_trgm' as a superuser.")
+	}
+}
+
+// duplicateGroups returns the groups of ids that are linked together by the given pairs.
+// Groups are sorted by their lowest id, and ids are sorted inside each group.
+func duplicateGroups(pairs [][2]int64) [][]int64 {
+	parent := make(map[int64]int64)
+	var find func(id int64) int64
+	find = func(id int64) int64 {
+		p, ok := parent[id]
+		if !ok || p == id {
+			parent[id] = id
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	for _, pair := range pairs {
+		a, b := find(pair[0]), find(pair[1])
+		if a < b {
+			parent[b] = a
+		} else {
+			parent[a] = b
+		}
+	}
+	byRoot := make(map[int64][]int64)
+	for id := range parent {
+		root := find(id)
+		byRoot[root] = append(byRoot[root], id)
+	}
+	res := make([][]int64, 0, len(byRoot))
+	for _, ids := range byRoot {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		res = append(res, ids)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res
+}
+
+// FindDuplicates returns the groups of records of this RecordSet which are
+// probably duplicates of each other, comparing the values of the given char or
+// text fields with trigram similarity.
+//
+// Two records are candidates if the average similarity of their fields is at least
+// threshold, between 0 and 1. Candidates are grouped transitively, so that each
+// returned RecordSet holds at least two records.
+//
+// FindDuplicates requires the pg_trgm extension of PostgreSQL. Since all records
+// are compared with each other, it should be called on a restricted RecordSet for
+// large tables.
+func modelMixinFindDuplicates(rc *RecordCollection, fields FieldNames, threshold float64) []*RecordCollection {
+	if len(fields) == 0 {
+		log.Panic("FindDuplicates requires at least one field", "model", rc.model.name)
+	}
+	similarities := make([]string, len(fields))
+	for i, f := range fields {
+		fi := rc.model.fields.MustGet(f.Name())
+		if (fi.fieldType != fieldtype.Char && fi.fieldType != fieldtype.Text) || !fi.hasColumn() {
+			log.Panic("FindDuplicates only compares stored char and text fields", "model", rc.model.name, "field", fi.name)
+		}
+		similarities[i] = fmt.Sprintf("CASE WHEN a.%[1]s IS NULL OR b.%[1]s IS NULL THEN 0 ELSE similarity(a.%[1]s, b.%[1]s) END", fi.json)
+	}
+	ids := rc.Ids()
+	if len(ids) < 2 {
+		return nil
+	}
+	rc.checkTrigramExtension()
+	table := adapters[db.DriverName()].quoteTableName(rc.model.tableName)
+	query := fmt.Sprintf(`
+		SELECT a.id AS a, b.id AS b FROM %[1]s a JOIN %[1]s b ON a.id < b.id
+		WHERE a.id IN (?) AND b.id IN (?) AND (%[2]s) / %[3]d >= ?`,
+		table, strings.Join(similarities, " + "), len(fields))
+	var rows []struct {
+		A int64
+		B int64
+	}
+	rc.env.cr.Select(&rows, query, ids, ids, threshold)
+	pairs := make([][2]int64, len(rows))
+	for i, row := range rows {
+		pairs[i] = [2]int64{row.A, row.B}
+	}
+	var res []*RecordCollection
+	for _, group := range duplicateGroups(pairs) {
+		res = append(res, rc.Call("Browse", group).(RecordSet).Collection())
+	}
+	return res
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models/operator"
+)
+
+// Domain operators
+const (
+	domainAnd = "&"
+	domainOr  = "|"
+	domainNot = "!"
+)
+
+// ParseDomain returns the Condition on the given model defined by the given
+// Odoo-like domain, as sent by clients and as returned by Condition.Serialize.
+//
+// A domain is a list of [field, operator, value] terms and of the prefix
+// operators '&', '|' and '!'. Successive terms are ANDed. Field may be a
+// path of field names or JSON names, such as partner_id.Name.
+//
+// It returns an error if the domain is malformed, or if a field or an
+// operator is unknown.
+func ParseDomain(mi *Model, domain []interface{}) (*Condition, error) {
+	res := newCondition()
+	for len(domain) > 0 {
+		cond, rest, err := parseDomainTerm(mi, domain)
+		if err != nil {
+			return nil, err
+		}
+		if res.IsEmpty() {
+			res = cond
+		} else {
+			res = res.AndCond(cond)
+		}
+		domain = rest
+	}
+	return res, nil
+}
+
+// parseDomainTerm parses the first term of the given domain, including the
+// operands of a prefix operator. It returns the Condition of this term and
+// the rest of the domain.
+func parseDomainTerm(mi *Model, domain []interface{}) (*Condition, []interface{}, error) {
+	if len(domain) == 0 {
+		return nil, nil, fmt.Errorf("missing operand in domain")
+	}
+	switch term := domain[0].(type) {
+	case string:
+		switch term {
+		case domainAnd, domainOr:
+			left, rest, err := parseDomainTerm(mi, domain[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			right, rest, err := parseDomainTerm(mi, rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			if term == domainOr {
+				return left.OrCond(right), rest, nil
+			}
+			return left.AndCond(right), rest, nil
+		case domainNot:
+			cond, rest, err := parseDomainTerm(mi, domain[1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			return newCondition().AndNotCond(cond), rest, nil
+		}
+		return nil, nil, fmt.Errorf("unknown domain operator '%s'", term)
+	case []interface{}:
+		if len(term) != 3 {
+			return nil, nil, fmt.Errorf("domain term %v should have 3 elements", term)
+		}
+		path, ok := term[0].(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid field in domain term %v", term)
+		}
+		fieldName, err := domainFieldName(mi, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		opStr, _ := term[1].(string)
+		op := operator.Operator(strings.ToLower(opStr))
+		if !op.IsValid() {
+			return nil, nil, fmt.Errorf("unknown operator '%v' in domain term %v", term[1], term)
+		}
+		return mi.Field(fieldName).AddOperator(op, term[2]), domain[1:], nil
+	}
+	return nil, nil, fmt.Errorf("invalid domain term %v", domain[0])
+}
+
+// domainFieldName returns the FieldName of the given path of field
+// names or JSON names of the given model, or an error if a field of
+// the path does not exist.
+func domainFieldName(mi *Model, path string) (FieldName, error) {
+	exprs := strings.Split(path, ExprSep)
+	names := make([]string, len(exprs))
+	model := mi
+	for i, expr := range exprs {
+		if model == nil {
+			return nil, fmt.Errorf("field '%s' of path '%s' is not a relation field", exprs[i-1], path)
+		}
+		fi, ok := model.fields.Get(expr)
+		if !ok {
+			return nil, fmt.Errorf("unknown field '%s' in model %s", expr, model.name)
+		}
+		names[i] = fi.name
+		model = fi.relatedModel
+	}
+	return mi.FieldName(strings.Join(names, ExprSep)), nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+// encryptedPrefix marks the values of encrypted fields in the database.
+// Encrypted values have the form $enc$<key id>$<base64 nonce and ciphertext>
+const encryptedPrefix = "$enc$"
+
+// An encryptionKey is an AES-GCM key used to encrypt field values
+type encryptionKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+var (
+	encryptionKeys     []encryptionKey
+	encryptionKeysLock sync.RWMutex
+)
+
+// SetEncryptionKeys sets the keys used to encrypt the values of fields
+// declared with Encrypted: true. Each key must be 16, 24 or 32 bytes long
+// to select AES-128, AES-192 or AES-256.
+//
+// Values are always encrypted with the first key. The other keys are only
+// used to decrypt values that have been encrypted before a key rotation.
+// Call ReencryptFields to encrypt all values with the first key, after
+// which the old keys can be removed.
+func SetEncryptionKeys(keys ...[]byte) error {
+	newKeys := make([]encryptionKey, len(keys))
+	for i, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key #%d: %s", i, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key #%d: %s", i, err)
+		}
+		hash := sha256.Sum256(key)
+		newKeys[i] = encryptionKey{
+			id:   hex.EncodeToString(hash[:4]),
+			aead: aead,
+		}
+	}
+	encryptionKeysLock.Lock()
+	defer encryptionKeysLock.Unlock()
+	encryptionKeys = newKeys
+	return nil
+}
+
+// currentEncryptionKey returns the key with which values must be encrypted.
+// It returns false if no key has been set.
+func currentEncryptionKey() (encryptionKey, bool) {
+	encryptionKeysLock.RLock()
+	defer encryptionKeysLock.RUnlock()
+	if len(encryptionKeys) == 0 {
+		return encryptionKey{}, false
+	}
+	return encryptionKeys[0], true
+}
+
+// getEncryptionKey returns the key with the given id.
+// It returns false if there is no such key.
+func getEncryptionKey(id string) (encryptionKey, bool) {
+	encryptionKeysLock.RLock()
+	defer encryptionKeysLock.RUnlock()
+	for _, key := range encryptionKeys {
+		if key.id == id {
+			return key, true
+		}
+	}
+	return encryptionKey{}, false
+}
+
+// encrypt returns the given plain text encrypted with the current key.
+// additionalData is authenticated but not encrypted.
+func encrypt(plain, additionalData string) (string, error) {
+	key, ok := currentEncryptionKey()
+	if !ok {
+		return "", errors.New("no encryption key set")
+	}
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(plain), []byte(additionalData))
+	return encryptedPrefix + key.id + "$" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns the plain text of the given value encrypted by encrypt.
+// additionalData must be the same as the one given to encrypt.
+//
+// Values that are not encrypted are returned as is.
+func decrypt(value, additionalData string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedPrefix), "$", 2)
+	if len(parts) != 2 {
+		return "", errors.New("malformed encrypted value")
+	}
+	key, ok := getEncryptionKey(parts[0])
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key '%s'", parts[0])
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < key.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, cipherText := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	plain, err := key.aead.Open(nil, nonce, cipherText, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// isEncrypted returns true if the given value has been encrypted by encrypt
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encryptionData returns the additional data used to encrypt the values of this
+// field, so that encrypted values cannot be copied from one column to another.
+func (f *Field) encryptionData() string {
+	return f.model.tableName + "." + f.json
+}
+
+// encryptValue returns the given value to write in the database
+// encrypted if this field is encrypted. NULL and empty values are
+// not encrypted.
+func (f *Field) encryptValue(value interface{}) interface{} {
+	if !f.encrypted {
+		return value
+	}
+	var plain string
+	switch val := value.(type) {
+	case string:
+		plain = val
+	case types.NullableString:
+		if val.IsNull() {
+			return value
+		}
+		plain = val.String
+	default:
+		return value
+	}
+	if plain == "" {
+		return value
+	}
+	res, err := encrypt(plain, f.encryptionData())
+	if err != nil {
+		log.Panic("Unable to encrypt field value", "model", f.model.name, "field", f.name, "error", err)
+	}
+	return res
+}
+
+// decryptValue returns the given value read from the database
+// decrypted if this field is encrypted.
+func (f *Field) decryptValue(value interface{}) interface{} {
+	if !f.encrypted {
+		return value
+	}
+	var str string
+	switch val := value.(type) {
+	case string:
+		str = val
+	case []byte:
+		str = string(val)
+	default:
+		return value
+	}
+	res, err := decrypt(str, f.encryptionData())
+	if err != nil {
+		log.Panic("Unable to decrypt field value", "model", f.model.name, "field", f.name, "error", err)
+	}
+	return res
+}
+
+// ReencryptFields encrypts with the current key all the values of encrypted
+// fields that are either not encrypted or encrypted with an older key. It
+// returns the number of values that have been encrypted.
+//
+// It should be called after a key has been added with SetEncryptionKeys,
+// or after the Encrypted attribute has been set on a field with data.
+func ReencryptFields(env Environment) int {
+	key, ok := currentEncryptionKey()
+	if !ok {
+		log.Panic("No encryption key set")
+	}
+	adapter := adapters[db.DriverName()]
+	var count int
+	for _, model := range Registry.registryByTableName {
+		if model.IsMixin() {
+			continue
+		}
+		for _, fi := range model.fields.registryByJSON {
+			if !fi.encrypted || !fi.isStored() {
+				continue
+			}
+			var rows []struct {
+				ID    int64
+				Value string
+			}
+			table := adapter.quoteTableName(model.tableName)
+			env.cr.Select(&rows, fmt.Sprintf(`SELECT id, %[1]s AS value FROM %[2]s
+				WHERE %[1]s IS NOT NULL AND %[1]s != '' AND %[1]s NOT LIKE ?`, fi.json, table),
+				encryptedPrefix+key.id+"$%")
+			for _, row := range rows {
+				plain := fi.decryptValue(row.Value)
+				env.cr.Execute(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, table, fi.json),
+					fi.encryptValue(plain), row.ID)
+			}
+			count += len(rows)
+		}
+	}
+	return count
+}
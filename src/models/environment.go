@@ -17,6 +17,7 @@ package models
 import (
 	"fmt"
 
+	"github.com/hexya-erp/hexya/src/models/security"
 	"github.com/hexya-erp/hexya/src/models/types"
 	"github.com/hexya-erp/hexya/src/tools/logging"
 )
@@ -26,10 +27,6 @@ import (
 // be retried.
 const DBSerializationMaxRetries uint8 = 5
 
-// maxRecursionDepth is the maximum allowed number of nested calls
-// during a transaction.
-const maxRecursionDepth uint8 = 100
-
 // An Environment stores various contextual data used by the models:
 // - the database cursor (current open transaction),
 // - the current user ID (for access rights checking)
@@ -37,13 +34,16 @@ const maxRecursionDepth uint8 = 100
 // The Environment also stores caches.
 type Environment struct {
 	cr             *Cursor
+	dbName         string
 	uid            int64
+	realUID        int64
 	context        *types.Context
 	cache          *cache
 	super          bool
 	currentLayer   *methodLayer
 	previousMethod *Method
-	recursions     uint8
+	recursions     int
+	callStack      *callFrame
 	nextNegativeID int64
 }
 
@@ -52,16 +52,57 @@ func (env Environment) Cr() *Cursor {
 	return env.cr
 }
 
+// DBName returns the name of the database of the Environment
+func (env Environment) DBName() string {
+	return env.dbName
+}
+
+// Memberships returns the group memberships of the users
+// of the database of this Environment.
+func (env Environment) Memberships() *security.MembershipCollection {
+	return security.Registry.ForDatabase(tenantName(env.dbName))
+}
+
+// Lang returns the language of the Environment, given by the 'lang' key of
+// its context. It returns the empty string, i.e. the source language, if
+// the 'hexya_source_lang' key of the context is set, so that translatable
+// field values, selection labels and code terms are not translated.
+func (env Environment) Lang() string {
+	if env.context.GetBool("hexya_source_lang") {
+		return ""
+	}
+	return env.context.GetString("lang")
+}
+
 // Uid returns the user id of the Environment
 func (env Environment) Uid() int64 {
 	return env.uid
 }
 
+// RealUid returns the id of the user that is actually behind this Environment.
+// It differs from Uid when the Environment was created with
+// ExecuteInDelegatedEnvironment, for instance when an administrator
+// impersonates another user.
+func (env Environment) RealUid() int64 {
+	if env.realUID != 0 {
+		return env.realUID
+	}
+	return env.uid
+}
+
 // Context returns the Context of the Environment
 func (env Environment) Context() *types.Context {
 	return env.context
 }
 
+// WithNewContext returns a copy of this Environment with its context
+// replaced by the given one. The copy shares the transaction and the
+// cache of this Environment.
+func (env Environment) WithNewContext(context *types.Context) Environment {
+	env.context = context
+	return env
+}
+
 // commit the transaction of this environment.
 //
 // WARNING: Do NOT call Commit on Environment instances that you
@@ -69,6 +110,7 @@ func (env Environment) Context() *types.Context {
 // automatically commit the Environment.
 func (env Environment) commit() {
 	env.Cr().tx.Commit()
+	invalidateSharedCaches(env.Cr().modifiedTables)
 }
 
 // rollback the transaction of this environment.
@@ -80,11 +122,15 @@ func (env Environment) rollback() {
 	env.Cr().tx.Rollback()
 }
 
-// checkRecursion panics if the recursion depth limit is reached
-func (env Environment) checkRecursion() {
-	if env.recursions > maxRecursionDepth {
-		log.Panic("Max recursion depth exceeded")
-	}
+// InvalidateCache empties the cache of this Environment, so that records are
+// fetched again from the database. It can be used to keep memory bounded when
+// reading a large number of records in a single transaction.
+func (env Environment) InvalidateCache() {
+	env.cache.Lock()
+	defer env.cache.Unlock()
+	env.cache.data = make(map[string]map[int64]FieldMap)
+	env.cache.x2mRelated = make(map[string]map[int64]map[string]map[string]int64)
+	env.cache.m2mLinks = make(map[string]map[[2]int64]bool)
 }
 
 // DumpCache returns a human readable string of this Environment's
@@ -121,8 +167,21 @@ func (env Environment) DumpCache() string {
 // or rollback() on the returned Environment after operation to release
 // the database connection.
 func newEnvironment(uid int64) Environment {
+	return newTenantEnvironment("", uid, false)
+}
+
+// newTenantEnvironment returns a new Environment for the given user ID
+// on the given tenant database. An empty tenant means the main database.
+//
+// If snapshot is true, the Environment's transaction is a read only
+// snapshot transaction.
+//
+// The same warning as newEnvironment applies.
+func newTenantEnvironment(tenant string, uid int64, snapshot bool) Environment {
+	tenantDB, name := getTenantDB(tenant)
 	env := Environment{
-		cr:      newCursor(db),
+		cr:      newCursor(tenantDB, snapshot),
+		dbName:  name,
 		uid:     uid,
 		context: types.NewContext(),
 		cache:   newCache(),
@@ -138,11 +197,30 @@ func newEnvironment(uid int64) Environment {
 // errors are automatically retried several times before returning an
 // error if they still occur.
 func ExecuteInNewEnvironment(uid int64, fnct func(Environment)) error {
-	return doExecuteInNewEnvironment(uid, 0, fnct)
+	return doExecuteInNewEnvironment("", uid, 0, fnct)
+}
+
+// ExecuteInTenantEnvironment is the same as ExecuteInNewEnvironment but
+// the new Environment is created on the given tenant database.
+//
+// The tenant database must have been connected with DBConnectTenant.
+// An empty tenant means the main database.
+func ExecuteInTenantEnvironment(tenant string, uid int64, fnct func(Environment)) error {
+	return doExecuteInNewEnvironment(tenant, uid, 0, fnct)
+}
+
+// ExecuteInDelegatedEnvironment is the same as ExecuteInTenantEnvironment but
+// the new Environment acts as uid on behalf of realUID. The real user can be
+// retrieved with the RealUid method of the Environment.
+func ExecuteInDelegatedEnvironment(tenant string, realUID, uid int64, fnct func(Environment)) error {
+	return doExecuteInNewEnvironment(tenant, uid, 0, func(env Environment) {
+		env.realUID = realUID
+		fnct(env)
+	})
 }
 
-func doExecuteInNewEnvironment(uid int64, retries uint8, fnct func(Environment)) (rError error) {
-	env := newEnvironment(uid)
+func doExecuteInNewEnvironment(tenant string, uid int64, retries uint8, fnct func(Environment)) (rError error) {
+	env := newTenantEnvironment(tenant, uid, false)
 	defer func() {
 		if r := recover(); r != nil {
 			env.rollback()
@@ -150,7 +228,7 @@ func doExecuteInNewEnvironment(uid int64, retries uint8, fnct func(Environment))
 				// Transaction error
 				retries++
 				if retries < DBSerializationMaxRetries {
-					if doExecuteInNewEnvironment(uid, retries, fnct) == nil {
+					if doExecuteInNewEnvironment(tenant, uid, retries, fnct) == nil {
 						rError = nil
 						return
 					}
@@ -171,11 +249,20 @@ func doExecuteInNewEnvironment(uid int64, retries uint8, fnct func(Environment))
 // This function always rolls back the transaction but returns an error
 // only if fnct panicked during its execution.
 func SimulateInNewEnvironment(uid int64, fnct func(Environment)) error {
-	return doSimulateInNewEnvironment(uid, 0, fnct)
+	return doSimulateInNewEnvironment("", uid, 0, fnct)
+}
+
+// SimulateInTenantEnvironment is the same as SimulateInNewEnvironment but
+// the new Environment is created on the given tenant database.
+//
+// The tenant database must have been connected with DBConnectTenant.
+// An empty tenant means the main database.
+func SimulateInTenantEnvironment(tenant string, uid int64, fnct func(Environment)) error {
+	return doSimulateInNewEnvironment(tenant, uid, 0, fnct)
 }
 
-func doSimulateInNewEnvironment(uid int64, retries uint8, fnct func(Environment)) (rError error) {
-	env := newEnvironment(uid)
+func doSimulateInNewEnvironment(tenant string, uid int64, retries uint8, fnct func(Environment)) (rError error) {
+	env := newTenantEnvironment(tenant, uid, false)
 	defer func() {
 		env.rollback()
 		if r := recover(); r != nil {
@@ -184,7 +271,7 @@ func doSimulateInNewEnvironment(uid int64, retries uint8, fnct func(Environment)
 				// to be as close as ExecuteInNewEnvironment as possible
 				retries++
 				if retries < DBSerializationMaxRetries {
-					if doSimulateInNewEnvironment(uid, retries, fnct) == nil {
+					if doSimulateInNewEnvironment(tenant, uid, retries, fnct) == nil {
 						rError = nil
 						return
 					}
@@ -0,0 +1,160 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+)
+
+// moduleColors is the palette used to color the models of an entity
+// relationship diagram according to the module that declared them.
+var moduleColors = []string{
+	"#A6CEE3", "#B2DF8A", "#FB9A99", "#FDBF6F", "#CAB2D6", "#FFFF99",
+	"#8DD3C7", "#BEBADA", "#FB8072", "#80B1D3", "#FDB462", "#B3DE69",
+}
+
+// Filter returns a new graph with only the models of this graph that have been
+// declared by one of the given modules and whose name starts with prefix.
+// An empty modules list or prefix does not filter. Only the relations between
+// the remaining models are kept.
+func (rg *RelationGraph) Filter(modules []string, prefix string) *RelationGraph {
+	mods := make(map[string]bool)
+	for _, mod := range modules {
+		mods[mod] = true
+	}
+	res := &RelationGraph{
+		Modules: make(map[string]string),
+		Tables:  make(map[string]string),
+	}
+	for _, model := range rg.Models {
+		if len(mods) > 0 && !mods[rg.Modules[model]] {
+			continue
+		}
+		if !strings.HasPrefix(model, prefix) {
+			continue
+		}
+		res.Models = append(res.Models, model)
+		res.Modules[model] = rg.Modules[model]
+		res.Tables[model] = rg.Tables[model]
+	}
+	for _, rel := range rg.Relations {
+		_, fromOK := res.Modules[rel.From]
+		_, toOK := res.Modules[rel.To]
+		if fromOK && toOK {
+			res.Relations = append(res.Relations, rel)
+		}
+	}
+	return res
+}
+
+// tableRelations returns the relations of this graph that are stored in the
+// database, that is foreign keys and many2many link tables. Reverse relations
+// are left out since they are the other end of a foreign key, and each link
+// table is only returned once.
+func (rg *RelationGraph) tableRelations() []Relation {
+	var res []Relation
+	linkTables := make(map[string]bool)
+	for _, rel := range rg.Relations {
+		if !rel.Stored {
+			continue
+		}
+		switch {
+		case rel.Type.IsFKRelationType():
+		case rel.Type == fieldtype.Many2Many && rel.LinkTable != "":
+			if linkTables[rel.LinkTable] {
+				continue
+			}
+			linkTables[rel.LinkTable] = true
+		default:
+			continue
+		}
+		res = append(res, rel)
+	}
+	return res
+}
+
+// moduleColors returns the color of each module of this graph
+func (rg *RelationGraph) moduleColors() map[string]string {
+	var modules []string
+	res := make(map[string]string)
+	for _, mod := range rg.Modules {
+		if _, exists := res[mod]; !exists {
+			res[mod] = ""
+			modules = append(modules, mod)
+		}
+	}
+	sort.Strings(modules)
+	for i, mod := range modules {
+		res[mod] = moduleColors[i%len(moduleColors)]
+	}
+	return res
+}
+
+// EntityRelationDOT returns the entity relationship diagram of this graph in the
+// DOT format of Graphviz. Each node is a table, colored by the module of its
+// model, and each edge is a foreign key or a many2many link table.
+func (rg *RelationGraph) EntityRelationDOT() string {
+	colors := rg.moduleColors()
+	var buf strings.Builder
+	buf.WriteString("digraph tables {\n")
+	buf.WriteString("\trankdir=LR;\n")
+	buf.WriteString("\tnode [shape=record, style=filled];\n")
+	for _, model := range rg.Models {
+		label := fmt.Sprintf("{%s|%s|%s}", model, rg.Tables[model], rg.Modules[model])
+		fmt.Fprintf(&buf, "\t%q [label=%q, fillcolor=%q];\n", model, label, colors[rg.Modules[model]])
+	}
+	for _, rel := range rg.tableRelations() {
+		if rel.Type == fieldtype.Many2Many {
+			fmt.Fprintf(&buf, "\t%q -> %q [label=%q, dir=both, arrowhead=crow, arrowtail=crow];\n",
+				rel.From, rel.To, rel.LinkTable)
+			continue
+		}
+		fmt.Fprintf(&buf, "\t%q -> %q [label=%q, arrowtail=crow, dir=both];\n", rel.From, rel.To, rel.Field)
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// EntityRelationPlantUML returns the entity relationship diagram of this graph
+// in the PlantUML format. Entities are colored by the module of their model and
+// grouped in a package per module.
+func (rg *RelationGraph) EntityRelationPlantUML() string {
+	colors := rg.moduleColors()
+	byModule := make(map[string][]string)
+	var modules []string
+	for _, model := range rg.Models {
+		mod := rg.Modules[model]
+		if _, exists := byModule[mod]; !exists {
+			modules = append(modules, mod)
+		}
+		byModule[mod] = append(byModule[mod], model)
+	}
+	sort.Strings(modules)
+	var buf strings.Builder
+	buf.WriteString("@startuml\n")
+	buf.WriteString("hide circle\n")
+	for _, mod := range modules {
+		fmt.Fprintf(&buf, "package %q %s {\n", mod, colors[mod])
+		for _, model := range byModule[mod] {
+			fmt.Fprintf(&buf, "\tentity %q as %s %s {\n\t\t%s\n\t}\n", model, model, colors[mod], rg.Tables[model])
+		}
+		buf.WriteString("}\n")
+	}
+	for _, rel := range rg.tableRelations() {
+		switch {
+		case rel.Type == fieldtype.Many2Many:
+			fmt.Fprintf(&buf, "%s }o--o{ %s : %s\n", rel.From, rel.To, rel.LinkTable)
+		case rel.Type == fieldtype.One2One:
+			fmt.Fprintf(&buf, "%s |o--o| %s : %s\n", rel.From, rel.To, rel.Field)
+		default:
+			fmt.Fprintf(&buf, "%s }o--o| %s : %s\n", rel.From, rel.To, rel.Field)
+		}
+	}
+	buf.WriteString("@enduml\n")
+	return buf.String()
+}
@@ -22,6 +22,7 @@ import (
 	"sync"
 
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/operator"
 	"github.com/hexya-erp/hexya/src/models/types"
 	"github.com/hexya-erp/hexya/src/tools/nbutils"
 	"github.com/hexya-erp/hexya/src/tools/strutils"
@@ -194,6 +195,27 @@ func (fc *FieldsCollection) register(fInfo *Field) {
 	}
 }
 
+// remove removes the given fInfo from the collection
+func (fc *FieldsCollection) remove(fInfo *Field) {
+	fc.Lock()
+	defer fc.Unlock()
+
+	delete(fc.registryByName, fInfo.name)
+	delete(fc.registryByJSON, fInfo.json)
+	without := func(fields []*Field) []*Field {
+		var res []*Field
+		for _, fi := range fields {
+			if fi != fInfo {
+				res = append(res, fi)
+			}
+		}
+		return res
+	}
+	fc.computedFields = without(fc.computedFields)
+	fc.computedStoredFields = without(fc.computedStoredFields)
+	fc.relatedFields = without(fc.relatedFields)
+}
+
 // Field holds the meta information about a field
 type Field struct {
 	model            *Model
@@ -236,11 +258,21 @@ type Field struct {
 	onChangeWarning  string
 	onChangeFilters  string
 	constraint       string
+	validators       []FieldValidator
+	tracking         bool
+	encrypted        bool
+	codeOrder        bool
+	raw              bool
+	sql              string
 	inverse          string
+	searchFunc       func(Environment, operator.Operator, interface{}) Conditioner
 	filter           *Condition
 	contexts         FieldContexts
 	ctxType          ctxType
 	updates          []map[string]interface{}
+	module           string
+	// renamedFrom is the former column of a field renamed by Model.RenameField
+	renamedFrom string
 }
 
 // isComputedField returns true if this field is computed
@@ -278,13 +310,16 @@ func (f *Field) isSettable() bool {
 	if f.isComputedField() && f.inverse == "" {
 		return false
 	}
+	if f.isSQLField() {
+		return false
+	}
 	return true
 }
 
 // isReadOnly returns true if this field must not be set directly
 // by the user.
 func (f *Field) isReadOnly() bool {
-	if f.readOnly {
+	if f.readOnly || f.isSQLField() {
 		return true
 	}
 	fInfo := f
@@ -315,6 +350,71 @@ func (f *Field) Name() string {
 	return f.name
 }
 
+// Module returns the name of the module that declared this field
+func (f *Field) Module() string {
+	return f.module
+}
+
+// IsTracked returns true if the changes of this field are tracked
+func (f *Field) IsTracked() bool {
+	return f.tracking
+}
+
+// IsEncrypted returns true if the values of this field are encrypted in the database
+func (f *Field) IsEncrypted() bool {
+	return f.encrypted
+}
+
+// IsCodeOrdered returns true if the values of this field are ordered as hierarchical codes
+func (f *Field) IsCodeOrdered() bool {
+	return f.codeOrder
+}
+
+// sqlTablePlaceholder is the placeholder of the table
+// alias in the SQL expressions of fields
+const sqlTablePlaceholder = "{table}"
+
+// sqlFieldTypes are the types of the fields that can have an SQL expression
+var sqlFieldTypes = map[fieldtype.Type]bool{
+	fieldtype.Boolean:   true,
+	fieldtype.Char:      true,
+	fieldtype.Date:      true,
+	fieldtype.DateTime:  true,
+	fieldtype.Float:     true,
+	fieldtype.Integer:   true,
+	fieldtype.Selection: true,
+	fieldtype.Text:      true,
+}
+
+// isSQLField returns true if the value of this field
+// is computed by an SQL expression
+func (f *Field) isSQLField() bool {
+	return f.sql != ""
+}
+
+// hasColumn returns true if this field has a column in its model's table
+func (f *Field) hasColumn() bool {
+	return f.isStored() && !f.isSQLField()
+}
+
+// sqlExpression returns the SQL expression of this field
+// for the table with the given alias.
+func (f *Field) sqlExpression(tableAlias string) string {
+	return "(" + strings.Replace(f.sql, sqlTablePlaceholder, tableAlias, -1) + ")"
+}
+
+// isSanitized returns true if the values given to this field
+// must be sanitized as HTML before being stored.
+func (f *Field) isSanitized() bool {
+	return f.fieldType == fieldtype.HTML && !f.raw
+}
+
+// codeSortKeyColumn returns the name of the generated column
+// holding the sort key of this code ordered field.
+func (f *Field) codeSortKeyColumn() string {
+	return f.json + codeSortKeySuffix
+}
+
 var _ FieldName = new(Field)
 
 // checkFieldInfo makes sanity checks on the given Field.
@@ -336,6 +436,59 @@ func checkFieldInfo(fi *Field) {
 			"type", fi.fieldType)
 	}
 
+	if fi.encrypted {
+		switch {
+		case fi.fieldType != fieldtype.Char && fi.fieldType != fieldtype.Text:
+			log.Warn("'encrypted' should be set only on char and text fields", "model", fi.model.name, "field", fi.name,
+				"type", fi.fieldType)
+			fi.encrypted = false
+		case fi.unique || fi.index:
+			log.Warn("encrypted fields cannot be unique or indexed", "model", fi.model.name, "field", fi.name)
+			fi.unique = false
+			fi.index = false
+		}
+	}
+
+	if fi.isSQLField() {
+		switch {
+		case !sqlFieldTypes[fi.fieldType]:
+			log.Panic("SQL expressions cannot be set on fields of this type", "model", fi.model.name, "field", fi.name,
+				"type", fi.fieldType)
+		case fi.isComputedField() || fi.relatedPathStr != "":
+			log.Panic("SQL expressions cannot be set on computed or related fields", "model", fi.model.name, "field", fi.name)
+		case fi.required || fi.unique || fi.index || fi.encrypted || fi.isContextedField():
+			log.Warn("SQL fields cannot be required, unique, indexed, encrypted or contexted", "model", fi.model.name,
+				"field", fi.name)
+			fi.required, fi.unique, fi.index, fi.encrypted, fi.contexts = false, false, false, false, nil
+		}
+	}
+
+	if fi.searchFunc != nil && (!fi.isComputedField() || fi.isStored()) {
+		log.Warn("'searchFunc' should be set only on non stored computed fields", "model", fi.model.name, "field", fi.name)
+		fi.searchFunc = nil
+	}
+
+	if fi.raw && fi.fieldType != fieldtype.HTML {
+		log.Warn("'raw' should be set only on html fields", "model", fi.model.name, "field", fi.name,
+			"type", fi.fieldType)
+		fi.raw = false
+	}
+
+	if fi.codeOrder {
+		switch {
+		case fi.fieldType != fieldtype.Char:
+			log.Warn("'codeOrder' should be set only on char fields", "model", fi.model.name, "field", fi.name,
+				"type", fi.fieldType)
+			fi.codeOrder = false
+		case fi.encrypted:
+			log.Warn("encrypted fields cannot be code ordered", "model", fi.model.name, "field", fi.name)
+			fi.codeOrder = false
+		case !fi.isStored():
+			log.Warn("'codeOrder' should be set only on stored fields", "model", fi.model.name, "field", fi.name)
+			fi.codeOrder = false
+		}
+	}
+
 	if fi.stored && !fi.isComputedField() {
 		log.Warn("'stored' should be set only on computed fields", "model", fi.model.name, "field", fi.name,
 			"type", fi.fieldType)
@@ -508,15 +661,37 @@ func createContextsModel(fi *Field, contexts FieldContexts) *Model {
 
 // processDepends populates the dependencies of each Field from the depends strings of
 // each Field instances.
+//
+// It is called once all modules have declared and extended their models, so that
+// dependencies on fields added by other modules trigger recomputations. Triggers are
+// rebuilt from scratch at each call. Dependencies that cannot be resolved are skipped
+// and reported in the logs and by Registry.UnreachableDependencies.
 func processDepends() {
+	Registry.unreachableDependencies = nil
+	for _, mi := range Registry.registryByTableName {
+		for _, fInfo := range mi.fields.registryByJSON {
+			fInfo.dependencies = nil
+		}
+	}
 	for _, mi := range Registry.registryByTableName {
+		if mi.IsMixin() {
+			// Mixin fields are processed in the models that inherit them
+			continue
+		}
 		for _, fInfo := range mi.fields.registryByJSON {
+			if fInfo.stored && len(fInfo.depends) == 0 {
+				reportUnreachableDependency(fInfo, "", "stored computed field has no dependencies and is never recomputed")
+			}
 			var refName string
 			for _, depString := range fInfo.depends {
 				if depString == "" {
 					continue
 				}
-				tokens := jsonizeExpr(mi, strings.Split(depString, ExprSep))
+				tokens, err := resolveDependency(mi, strings.Split(depString, ExprSep))
+				if err != nil {
+					reportUnreachableDependency(fInfo, depString, err.Error())
+					continue
+				}
 				refName = tokens[len(tokens)-1]
 				path := strings.Join(tokens[:len(tokens)-1], ExprSep)
 				targetComputeData := computeData{
@@ -534,6 +709,39 @@ func processDepends() {
 	}
 }
 
+// resolveDependency returns the given dependency path of the given model
+// with field JSON names, or an error if the path cannot be followed.
+func resolveDependency(mi *Model, exprs []string) ([]string, error) {
+	fi, ok := mi.fields.Get(exprs[0])
+	if !ok {
+		return nil, fmt.Errorf("field %s does not exist in model %s", exprs[0], mi.name)
+	}
+	if len(exprs) == 1 {
+		return []string{fi.json}, nil
+	}
+	if fi.relatedModel == nil {
+		return nil, fmt.Errorf("field %s of model %s is not a relation", exprs[0], mi.name)
+	}
+	res, err := resolveDependency(fi.relatedModel, exprs[1:])
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{fi.json}, res...), nil
+}
+
+// reportUnreachableDependency logs and records that the given dependency
+// of the given field will not trigger its recomputation for the given reason.
+func reportUnreachableDependency(fi *Field, depends, reason string) {
+	log.Warn("Unreachable dependency of computed field", "model", fi.model.name, "field", fi.name,
+		"depends", depends, "reason", reason)
+	Registry.unreachableDependencies = append(Registry.unreachableDependencies, UnreachableDependency{
+		Model:   fi.model.name,
+		Field:   fi.name,
+		Depends: depends,
+		Reason:  reason,
+	})
+}
+
 // checkComputeMethodsSignature check the signature of all methods used
 // in computed fields and for OnChange methods.
 // It panics if it is not the case.
@@ -590,10 +798,44 @@ func checkComputeMethodsSignature() {
 			if methType.NumOut() != 0 {
 				log.Panic("Inverse methods should not return any value", "model", model.name, "field", fi.name, "method", method.name)
 			}
+			if fi.isRelationField() {
+				// Relation fields values are given as record sets of the related model
+				if !isRecordSetArgOf(methType.In(1), fi.relatedModelName) {
+					log.Panic("Inverse methods argument of relation fields should be a record set of the related model",
+						"model", model.name, "field", fi.name, "method", method.name, "relatedModel", fi.relatedModelName,
+						"argType", methType.In(1))
+				}
+				continue
+			}
+			if !fi.structField.Type.ConvertibleTo(methType.In(1)) {
+				log.Panic("Inverse methods argument should be of the type of the field", "model", model.name, "field", fi.name,
+					"method", method.name, "fieldType", fi.structField.Type, "argType", methType.In(1))
+			}
 		}
 	}
 }
 
+// isRecordSetArgOf returns true if a record set of the given model can be
+// given as an argument of type argType, that is if argType is RecordSet,
+// *RecordCollection, or the RecordSet type of the model or one of its interfaces.
+func isRecordSetArgOf(argType reflect.Type, modelName string) bool {
+	if argType == reflect.TypeOf(new(RecordCollection)) {
+		return true
+	}
+	if argType.Kind() == reflect.Interface && !argType.Implements(reflect.TypeOf((*RecordSet)(nil)).Elem()) {
+		return false
+	}
+	wrapper, ok := recordSetWrappers[modelName]
+	if !ok {
+		// Without a registered type, we can only accept generic record sets
+		return argType == reflect.TypeOf((*RecordSet)(nil)).Elem()
+	}
+	if argType.Kind() == reflect.Interface {
+		return wrapper.Implements(argType)
+	}
+	return argType == wrapper
+}
+
 // checkMethType panics if the given method does not have
 // the correct number and type of arguments and returns for a compute/onChange method
 func checkMethType(method *Method, label string) error {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/hexya-erp/hexya/src/models"
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/operator"
 	"github.com/hexya-erp/hexya/src/models/types"
 	"github.com/hexya-erp/hexya/src/models/types/dates"
 	"github.com/hexya-erp/hexya/src/tools/nbutils"
@@ -17,6 +18,12 @@ import (
 )
 
 // A FieldDefinition is a struct that declares a new field in a fields collection;
+//
+// Boolean, Char, Date, DateTime, Float, Integer, Selection and Text fields can
+// be given an SQL expression instead of a database column (see models.Field.SetSQL).
+//
+// Non stored computed fields can be given a SearchFunc so that they can be
+// searched on (see models.Field.SetSearchFunc).
 type FieldDefinition interface {
 	// DeclareField creates a field for the given FieldsCollection with the given name and returns the created field.
 	DeclareField(*models.FieldsCollection, string) *models.Field
@@ -50,6 +57,7 @@ type Binary struct {
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
 }
@@ -77,6 +85,7 @@ type Boolean struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	NoCopy          bool
 	GoType          interface{}
 	OnChange        models.Methoder
@@ -84,8 +93,10 @@ type Boolean struct {
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a boolean field for the given models.FieldsCollection with the given name.
@@ -99,6 +110,20 @@ func (bf Boolean) DeclareField(fc *models.FieldsCollection, name string) *models
 // A Char is a field for storing short text. There is no
 // default max size, but it can be forced by setting the Size value.
 //
+// Validators check and normalize the values given to Create and Write,
+// e.g. models.EmailValidator or models.PhoneValidator.
+//
+// If Nullable is set, the Go type of the field is types.NullableString
+// so that NULL values can be distinguished from empty strings.
+//
+// If Encrypted is set, values are encrypted in the database with the keys
+// set by models.SetEncryptionKeys. Encrypted fields cannot be searched,
+// sorted or grouped by on the database side.
+//
+// If CodeOrder is set, values are ordered as hierarchical codes, comparing
+// numbers by value, so that "1.2.10" comes after "1.2.9". This applies to
+// the default order of the model and to OrderBy.
+//
 // Clients are expected to handle TypeChar fields as single line inputs.
 type Char struct {
 	JSON            string
@@ -115,21 +140,31 @@ type Char struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	NoCopy          bool
 	Size            int
 	GoType          interface{}
+	Nullable        bool
+	Encrypted       bool
+	CodeOrder       bool
 	Translate       bool
 	OnChange        models.Methoder
 	OnChangeWarning models.Methoder
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Validators      []models.FieldValidator
+	Tracking        bool
 }
 
 // DeclareField creates a char field for the given models.FieldsCollection with the given name.
 func (cf Char) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
+	if cf.Nullable && cf.GoType == nil {
+		cf.GoType = new(types.NullableString)
+	}
 	fInfo := models.CreateFieldFromStruct(fc, &cf, name, fieldtype.Char, new(string))
 	fInfo.SetProperty("size", cf.Size)
 	return fInfo
@@ -153,6 +188,7 @@ type Date struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	GroupOperator   string
 	NoCopy          bool
 	GoType          interface{}
@@ -161,8 +197,10 @@ type Date struct {
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a date field for the given models.FieldsCollection with the given name.
@@ -190,6 +228,7 @@ type DateTime struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	GroupOperator   string
 	NoCopy          bool
 	GoType          interface{}
@@ -198,8 +237,10 @@ type DateTime struct {
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a datetime field for the given models.FieldsCollection with the given name.
@@ -210,6 +251,10 @@ func (df DateTime) DeclareField(fc *models.FieldsCollection, name string) *model
 }
 
 // A Float is a field for storing decimal numbers.
+//
+// If Nullable is set, the Go type of the field is types.NullableFloat
+// so that NULL values can be distinguished from zero. Nullable fields
+// have no default value.
 type Float struct {
 	JSON            string
 	String          string
@@ -225,22 +270,29 @@ type Float struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	GroupOperator   string
 	NoCopy          bool
 	Digits          nbutils.Digits
 	GoType          interface{}
+	Nullable        bool
 	OnChange        models.Methoder
 	OnChangeWarning models.Methoder
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField adds this datetime field for the given models.FieldsCollection with the given name.
 func (ff Float) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
-	if ff.Default == nil {
+	if ff.Nullable && ff.GoType == nil {
+		ff.GoType = new(types.NullableFloat)
+	}
+	if ff.Default == nil && !ff.Nullable {
 		ff.Default = models.DefaultValue(0)
 	}
 	fInfo := models.CreateFieldFromStruct(fc, &ff, name, fieldtype.Float, new(float64))
@@ -251,6 +303,12 @@ func (ff Float) DeclareField(fc *models.FieldsCollection, name string) *models.F
 
 // An HTML is a field for storing HTML formatted strings.
 //
+// Values given to Create and Write are sanitized with htmlutils.DefaultPolicy
+// to prevent cross-site scripting: tags and attributes that are not allowed,
+// scripts, styles and unsafe URLs are removed. If Raw is set, values are
+// stored as given. Raw must only be set on fields that hold trusted
+// content, such as templates that only administrators can modify.
+//
 // Clients are expected to handle HTML fields with multi-line HTML editors.
 type HTML struct {
 	JSON            string
@@ -271,11 +329,13 @@ type HTML struct {
 	Size            int
 	GoType          interface{}
 	Translate       bool
+	Raw             bool
 	OnChange        models.Methoder
 	OnChangeWarning models.Methoder
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
 }
@@ -287,6 +347,48 @@ func (tf HTML) DeclareField(fc *models.FieldsCollection, name string) *models.Fi
 	return fInfo
 }
 
+// A Markdown is a field for storing texts formatted with Markdown.
+//
+// Values are stored as given. They are rendered to sanitized HTML by the
+// markdown widget of format.Formatter.Field, e.g. in QWeb templates with
+// <div t-field="doc.Content" t-options-widget="markdown"/>.
+//
+// Clients are expected to handle Markdown fields as multi-line inputs.
+type Markdown struct {
+	JSON            string
+	String          string
+	Help            string
+	Stored          bool
+	Required        bool
+	ReadOnly        bool
+	RequiredFunc    func(models.Environment) (bool, models.Conditioner)
+	ReadOnlyFunc    func(models.Environment) (bool, models.Conditioner)
+	InvisibleFunc   func(models.Environment) (bool, models.Conditioner)
+	Index           bool
+	Compute         models.Methoder
+	Depends         []string
+	Related         string
+	NoCopy          bool
+	Size            int
+	GoType          interface{}
+	Translate       bool
+	OnChange        models.Methoder
+	OnChangeWarning models.Methoder
+	OnChangeFilters models.Methoder
+	Constraint      models.Methoder
+	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
+	Contexts        models.FieldContexts
+	Default         func(models.Environment) interface{}
+}
+
+// DeclareField creates a markdown field for the given models.FieldsCollection with the given name.
+func (mf Markdown) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
+	fInfo := models.CreateFieldFromStruct(fc, &mf, name, fieldtype.Markdown, new(string))
+	fInfo.SetProperty("size", mf.Size)
+	return fInfo
+}
+
 // An Integer is a field for storing non decimal numbers.
 type Integer struct {
 	JSON            string
@@ -303,6 +405,7 @@ type Integer struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	GroupOperator   string
 	NoCopy          bool
 	GoType          interface{}
@@ -311,8 +414,10 @@ type Integer struct {
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a datetime field for the given models.FieldsCollection with the given name.
@@ -353,6 +458,7 @@ type Many2Many struct {
 	Constraint       models.Methoder
 	Filter           models.Conditioner
 	Inverse          models.Methoder
+	SearchFunc       func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Default          func(models.Environment) interface{}
 }
 
@@ -418,8 +524,10 @@ type Many2One struct {
 	Constraint      models.Methoder
 	Filter          models.Conditioner
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a many2one field for the given models.FieldsCollection with the given name.
@@ -473,6 +581,7 @@ type One2Many struct {
 	Constraint      models.Methoder
 	Filter          models.Conditioner
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Default         func(models.Environment) interface{}
 }
 
@@ -518,8 +627,10 @@ type One2One struct {
 	Constraint      models.Methoder
 	Filter          models.Conditioner
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a one2one field for the given models.FieldsCollection with the given name.
@@ -574,6 +685,7 @@ type Rev2One struct {
 	Constraint      models.Methoder
 	Filter          models.Conditioner
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Default         func(models.Environment) interface{}
 }
 
@@ -609,6 +721,7 @@ type Selection struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	NoCopy          bool
 	Selection       types.Selection
 	SelectionFunc   func() types.Selection
@@ -617,8 +730,10 @@ type Selection struct {
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
+	Tracking        bool
 }
 
 // DeclareField creates a selection field for the given models.FieldsCollection with the given name.
@@ -632,6 +747,13 @@ func (sf Selection) DeclareField(fc *models.FieldsCollection, name string) *mode
 // A Text is a field for storing long text. There is no
 // default max size, but it can be forced by setting the Size value.
 //
+// If Nullable is set, the Go type of the field is types.NullableString
+// so that NULL values can be distinguished from empty strings.
+//
+// If Encrypted is set, values are encrypted in the database with the keys
+// set by models.SetEncryptionKeys. Encrypted fields cannot be searched,
+// sorted or grouped by on the database side.
+//
 // Clients are expected to handle text fields as multi-line inputs.
 type Text struct {
 	JSON            string
@@ -648,21 +770,28 @@ type Text struct {
 	Compute         models.Methoder
 	Depends         []string
 	Related         string
+	SQL             string
 	NoCopy          bool
 	Size            int
 	GoType          interface{}
+	Nullable        bool
+	Encrypted       bool
 	Translate       bool
 	OnChange        models.Methoder
 	OnChangeWarning models.Methoder
 	OnChangeFilters models.Methoder
 	Constraint      models.Methoder
 	Inverse         models.Methoder
+	SearchFunc      func(models.Environment, operator.Operator, interface{}) models.Conditioner
 	Contexts        models.FieldContexts
 	Default         func(models.Environment) interface{}
 }
 
 // DeclareField creates a text field for the given models.FieldsCollection with the given name.
 func (tf Text) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
+	if tf.Nullable && tf.GoType == nil {
+		tf.GoType = new(types.NullableString)
+	}
 	fInfo := models.CreateFieldFromStruct(fc, &tf, name, fieldtype.Text, new(string))
 	fInfo.SetProperty("size", tf.Size)
 	return fInfo
@@ -0,0 +1,131 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package fields
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+// Color indexes of the standard kanban color palette
+const (
+	ColorNone int64 = iota
+	ColorRed
+	ColorOrange
+	ColorYellow
+	ColorLightBlue
+	ColorDarkPurple
+	ColorSalmonPink
+	ColorMediumBlue
+	ColorDarkBlue
+	ColorFuchsia
+	ColorGreen
+	ColorPurple
+)
+
+// ColorNames are the names of the colors of the standard kanban color palette by index
+var ColorNames = map[int64]string{
+	ColorNone:       "No color",
+	ColorRed:        "Red",
+	ColorOrange:     "Orange",
+	ColorYellow:     "Yellow",
+	ColorLightBlue:  "Light blue",
+	ColorDarkPurple: "Dark purple",
+	ColorSalmonPink: "Salmon pink",
+	ColorMediumBlue: "Medium blue",
+	ColorDarkBlue:   "Dark blue",
+	ColorFuchsia:    "Fuchsia",
+	ColorGreen:      "Green",
+	ColorPurple:     "Purple",
+}
+
+// Standard values of Priority fields
+const (
+	PriorityNormal   = "0"
+	PriorityMedium   = "1"
+	PriorityHigh     = "2"
+	PriorityVeryHigh = "3"
+)
+
+// PrioritySelection is the standard selection of Priority fields
+var PrioritySelection = types.Selection{
+	PriorityNormal:   "Normal",
+	PriorityMedium:   "Medium",
+	PriorityHigh:     "High",
+	PriorityVeryHigh: "Very High",
+}
+
+// A ColorIndex is an integer field holding the index of the color of
+// a record in the standard kanban color palette, from ColorNone to ColorPurple.
+//
+// Clients are expected to handle color index fields with a color picker.
+type ColorIndex struct {
+	JSON     string
+	String   string
+	Help     string
+	Required bool
+	ReadOnly bool
+	Index    bool
+	NoCopy   bool
+	Default  func(models.Environment) interface{}
+}
+
+// DeclareField creates a color index field for the given models.FieldsCollection with the given name.
+func (cf ColorIndex) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
+	if cf.String == "" {
+		cf.String = "Color Index"
+	}
+	if cf.Default == nil {
+		cf.Default = models.DefaultValue(ColorNone)
+	}
+	return Integer{
+		JSON:          cf.JSON,
+		String:        cf.String,
+		Help:          cf.Help,
+		Required:      cf.Required,
+		ReadOnly:      cf.ReadOnly,
+		Index:         cf.Index,
+		NoCopy:        cf.NoCopy,
+		GroupOperator: "max",
+		Default:       cf.Default,
+	}.DeclareField(fc, name)
+}
+
+// A Priority is a selection field holding the priority of a record,
+// from PriorityNormal to PriorityVeryHigh. The selection can be overridden
+// with another one whose keys are ordered by priority.
+//
+// Clients are expected to handle priority fields with stars.
+type Priority struct {
+	JSON      string
+	String    string
+	Help      string
+	Required  bool
+	ReadOnly  bool
+	Index     bool
+	NoCopy    bool
+	Selection types.Selection
+	Default   func(models.Environment) interface{}
+}
+
+// DeclareField creates a priority field for the given models.FieldsCollection with the given name.
+func (pf Priority) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
+	if pf.Selection == nil {
+		pf.Selection = PrioritySelection
+	}
+	if pf.Default == nil {
+		pf.Default = models.DefaultValue(PriorityNormal)
+	}
+	return Selection{
+		JSON:      pf.JSON,
+		String:    pf.String,
+		Help:      pf.Help,
+		Required:  pf.Required,
+		ReadOnly:  pf.ReadOnly,
+		Index:     pf.Index,
+		NoCopy:    pf.NoCopy,
+		Selection: pf.Selection,
+		Default:   pf.Default,
+	}.DeclareField(fc, name)
+}
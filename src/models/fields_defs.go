@@ -7,6 +7,7 @@ import (
 	"reflect"
 
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/operator"
 	"github.com/hexya-erp/hexya/src/models/types"
 	"github.com/hexya-erp/hexya/src/tools/nbutils"
 	"github.com/hexya-erp/hexya/src/tools/strutils"
@@ -74,14 +75,41 @@ func CreateFieldFromStruct(fc *FieldsCollection, fStruct interface{}, name strin
 			contexts = make(FieldContexts)
 		}
 		contexts["lang"] = func(rs RecordSet) string {
-			res := rs.Env().Context().GetString("lang")
-			return res
+			return rs.Env().Lang()
 		}
 	}
 	var noCopy bool
 	if noc := val.FieldByName("NoCopy"); noc.IsValid() {
 		noCopy = noc.Bool()
 	}
+	var validators []FieldValidator
+	if vals := val.FieldByName("Validators"); vals.IsValid() {
+		validators = vals.Interface().([]FieldValidator)
+	}
+	var tracking bool
+	if tra := val.FieldByName("Tracking"); tra.IsValid() {
+		tracking = tra.Bool()
+	}
+	var encrypted bool
+	if enc := val.FieldByName("Encrypted"); enc.IsValid() {
+		encrypted = enc.Bool()
+	}
+	var sqlExpr string
+	if se := val.FieldByName("SQL"); se.IsValid() {
+		sqlExpr = se.String()
+	}
+	var searchFunc func(Environment, operator.Operator, interface{}) Conditioner
+	if sf := val.FieldByName("SearchFunc"); sf.IsValid() {
+		searchFunc = sf.Interface().(func(Environment, operator.Operator, interface{}) Conditioner)
+	}
+	var raw bool
+	if r := val.FieldByName("Raw"); r.IsValid() {
+		raw = r.Bool()
+	}
+	var codeOrder bool
+	if co := val.FieldByName("CodeOrder"); co.IsValid() {
+		codeOrder = co.Bool()
+	}
 	fInfo := &Field{
 		model:           fc.model,
 		name:            name,
@@ -108,6 +136,13 @@ func CreateFieldFromStruct(fc *FieldsCollection, fStruct interface{}, name strin
 		onChangeWarning: onchangeWarning,
 		onChangeFilters: onchangeFilters,
 		constraint:      constraint,
+		validators:      validators,
+		tracking:        tracking,
+		encrypted:       encrypted,
+		codeOrder:       codeOrder,
+		raw:             raw,
+		sql:             sqlExpr,
+		searchFunc:      searchFunc,
 		contexts:        contexts,
 	}
 	return fInfo
@@ -151,12 +186,16 @@ func getFuncNames(compute, inverse, onchange, onchangeWarning, onchangeFilters,
 	return com, inv, onc, onw, onf, con
 }
 
+// updateModuleKey is the key of the name of the module
+// that made an update in the update entries of fields
+const updateModuleKey = "_module"
+
 // addUpdate adds an update entry for for this field with the given property and the given value
 func (f *Field) addUpdate(property string, value interface{}) {
-	if Registry.bootstrapped {
+	if Registry.metadataBootstrapped {
 		log.Panic("Fields must not be modified after bootstrap", "model", f.model.name, "field", f.name, "property", property, "value", value)
 	}
-	update := map[string]interface{}{property: value}
+	update := map[string]interface{}{property: value, updateModuleKey: declaringModule()}
 	f.updates = append(f.updates, update)
 }
 
@@ -218,8 +257,22 @@ func (f *Field) SetProperty(property string, value interface{}) {
 		f.onChangeFilters = value.(string)
 	case "constraint":
 		f.constraint = value.(string)
+	case "validators":
+		f.validators = value.([]FieldValidator)
+	case "tracking":
+		f.tracking = value.(bool)
+	case "encrypted":
+		f.encrypted = value.(bool)
+	case "codeOrder":
+		f.codeOrder = value.(bool)
+	case "raw":
+		f.raw = value.(bool)
+	case "sql":
+		f.sql = value.(string)
 	case "inverse":
 		f.inverse = value.(string)
+	case "searchFunc":
+		f.searchFunc = value.(func(Environment, operator.Operator, interface{}) Conditioner)
 	case "filter":
 		f.filter = value.(*Condition)
 	case "relationModel":
@@ -239,8 +292,7 @@ func (f *Field) SetProperty(property string, value interface{}) {
 				f.contexts = make(FieldContexts)
 			}
 			f.contexts["lang"] = func(rs RecordSet) string {
-				res := rs.Env().Context().GetString("lang")
-				return res
+				return rs.Env().Lang()
 			}
 		case false:
 			if f.contexts == nil {
@@ -418,6 +470,12 @@ func (f *Field) UpdateSelection(value types.Selection) *Field {
 	return f
 }
 
+// RemoveSelection removes the given keys from the Selection parameter of this Field
+func (f *Field) RemoveSelection(keys ...string) *Field {
+	f.addUpdate("selection_remove", keys)
+	return f
+}
+
 // SetOnchange overrides the value of the Onchange parameter of this Field
 func (f *Field) SetOnchange(value Methoder) *Field {
 	var methName string
@@ -458,6 +516,57 @@ func (f *Field) SetConstraint(value Methoder) *Field {
 	return f
 }
 
+// SetValidators overrides the value of the Validators parameter of this Field
+func (f *Field) SetValidators(value ...FieldValidator) *Field {
+	f.addUpdate("validators", value)
+	return f
+}
+
+// AddValidators adds the given validators to the Validators parameter of this Field
+func (f *Field) AddValidators(value ...FieldValidator) *Field {
+	f.addUpdate("validators_add", value)
+	return f
+}
+
+// SetTracking overrides the value of the Tracking parameter of this Field
+func (f *Field) SetTracking(value bool) *Field {
+	f.addUpdate("tracking", value)
+	return f
+}
+
+// SetEncrypted overrides the value of the Encrypted parameter of this Field
+func (f *Field) SetEncrypted(value bool) *Field {
+	f.addUpdate("encrypted", value)
+	return f
+}
+
+// SetCodeOrder overrides the value of the CodeOrder parameter of this Field
+func (f *Field) SetCodeOrder(value bool) *Field {
+	f.addUpdate("codeOrder", value)
+	return f
+}
+
+// SetRaw overrides the value of the Raw parameter of this Field
+func (f *Field) SetRaw(value bool) *Field {
+	f.addUpdate("raw", value)
+	return f
+}
+
+// SetSQL overrides the value of the SQL parameter of this Field.
+//
+// The value of a field with an SQL expression is computed by the database
+// when records are loaded, instead of being read from a column. Such fields
+// are read-only but can be searched, ordered and grouped by like columns.
+//
+// Columns of the model's table must be prefixed by the {table} placeholder,
+// e.g. "{table}.quantity * {table}.price_unit". Other tables can be used in
+// subqueries correlated with {table}. Values are refreshed when records are
+// written, but not when the other tables of the expression are modified.
+func (f *Field) SetSQL(value string) *Field {
+	f.addUpdate("sql", value)
+	return f
+}
+
 // SetInverse overrides the value of the Inverse parameter of this Field
 func (f *Field) SetInverse(value Methoder) *Field {
 	var methName string
@@ -468,6 +577,16 @@ func (f *Field) SetInverse(value Methoder) *Field {
 	return f
 }
 
+// SetSearchFunc overrides the value of the SearchFunc parameter of this Field.
+//
+// The search function of a non stored computed field returns the condition on
+// stored fields that is equivalent to searching this field with the given
+// operator and value. It makes the field usable in conditions and domains.
+func (f *Field) SetSearchFunc(value func(Environment, operator.Operator, interface{}) Conditioner) *Field {
+	f.addUpdate("searchFunc", value)
+	return f
+}
+
 // SetFilter overrides the value of the Filter parameter of this Field
 func (f *Field) SetFilter(value Conditioner) *Field {
 	f.addUpdate("filter", value.Underlying())
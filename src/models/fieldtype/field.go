@@ -25,6 +25,7 @@ const (
 	Integer   Type = "integer"
 	Many2Many Type = "many2many"
 	Many2One  Type = "many2one"
+	Markdown  Type = "markdown"
 	One2Many  Type = "one2many"
 	One2One   Type = "one2one"
 	Rev2One   Type = "rev2one"
@@ -71,7 +72,7 @@ func (t Type) Is2ManyRelationType() bool {
 // IsNullInDB returns true if this type's zero value is
 // saved as null in database.
 func (t Type) IsNullInDB() bool {
-	return t.IsFKRelationType() || t == Binary || t == Char || t == Text || t == HTML || t == Selection || t == Date || t == DateTime
+	return t.IsFKRelationType() || t == Binary || t == Char || t == Text || t == HTML || t == Markdown || t == Selection || t == Date || t == DateTime
 }
 
 // DefaultGoType returns this Type's default Go type
@@ -79,7 +80,7 @@ func (t Type) DefaultGoType() reflect.Type {
 	switch t {
 	case NoType:
 		return reflect.TypeOf(nil)
-	case Binary, Char, Text, HTML, Selection:
+	case Binary, Char, Text, HTML, Markdown, Selection:
 		return reflect.TypeOf(*new(string))
 	case Boolean:
 		return reflect.TypeOf(true)
@@ -0,0 +1,424 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+)
+
+// Types of models as given in ModelDescription
+const (
+	ModelTypeModel     = "model"
+	ModelTypeMixin     = "mixin"
+	ModelTypeTransient = "transient"
+	ModelTypeManual    = "manual"
+	ModelTypeM2MLink   = "many2many_link"
+)
+
+// A ModelDescription describes a model of the registry
+// for documentation generators and admin tools.
+type ModelDescription struct {
+	Name      string `json:"name"`
+	Module    string `json:"module"`
+	Type      string `json:"type"`
+	TableName string `json:"table_name"`
+	// Mixins are the names of the mixins directly inherited by the model
+	Mixins  []string            `json:"mixins"`
+	Fields  []FieldDescription  `json:"fields"`
+	Methods []MethodDescription `json:"methods"`
+}
+
+// An UnreachableDependency is a dependency of a computed field that
+// cannot trigger its recomputation, such as a path to a field that
+// does not exist because the module declaring it is not loaded.
+type UnreachableDependency struct {
+	Model   string `json:"model"`
+	Field   string `json:"field"`
+	Depends string `json:"depends"`
+	Reason  string `json:"reason"`
+}
+
+// UnreachableDependencies returns the dependencies of computed fields
+// that were found unreachable when bootstrapping the models. Depends is
+// empty for stored computed fields without dependencies.
+func (mc *modelCollection) UnreachableDependencies() []UnreachableDependency {
+	return mc.unreachableDependencies
+}
+
+// A FieldConflict is a property of a field that is overridden by several
+// modules other than the module that declared the field. Its value depends
+// on the order in which the modules are loaded.
+type FieldConflict struct {
+	Model    string   `json:"model"`
+	Field    string   `json:"field"`
+	Property string   `json:"property"`
+	Modules  []string `json:"modules"`
+}
+
+// FieldConflicts returns the field properties overridden
+// by several modules found when bootstrapping the models.
+func (mc *modelCollection) FieldConflicts() []FieldConflict {
+	return mc.fieldConflicts
+}
+
+// A FieldRename is a field renamed by Model.RenameField. OldColumn and
+// NewColumn are the column of the field before and after the rename.
+type FieldRename struct {
+	Model     string `json:"model"`
+	OldName   string `json:"old_name"`
+	NewName   string `json:"new_name"`
+	OldColumn string `json:"old_column"`
+	NewColumn string `json:"new_column"`
+}
+
+// FieldRenames returns the fields renamed by Model.RenameField
+func (mc *modelCollection) FieldRenames() []FieldRename {
+	return mc.fieldRenames
+}
+
+// A FieldDescription holds the metadata of a field
+type FieldDescription struct {
+	Name          string          `json:"name"`
+	JSON          string          `json:"json"`
+	Module        string          `json:"module"`
+	Type          fieldtype.Type  `json:"type"`
+	GoType        string          `json:"go_type"`
+	String        string          `json:"string"`
+	Help          string          `json:"help"`
+	Required      bool            `json:"required"`
+	ReadOnly      bool            `json:"readonly"`
+	Stored        bool            `json:"stored"`
+	Index         bool            `json:"index"`
+	Unique        bool            `json:"unique"`
+	NoCopy        bool            `json:"no_copy"`
+	Translate     bool            `json:"translate"`
+	Tracking      bool            `json:"tracking"`
+	Size          int             `json:"size,omitempty"`
+	Digits        nbutils.Digits  `json:"digits"`
+	GroupOperator string          `json:"group_operator,omitempty"`
+	Selection     types.Selection `json:"selection,omitempty"`
+	Relation      string          `json:"relation,omitempty"`
+	ReverseFK     string          `json:"reverse_fk,omitempty"`
+	OnDelete      OnDeleteAction  `json:"on_delete,omitempty"`
+	Embed         bool            `json:"embed"`
+	Compute       string          `json:"compute,omitempty"`
+	Inverse       string          `json:"inverse,omitempty"`
+	Depends       []string        `json:"depends,omitempty"`
+	Related       string          `json:"related,omitempty"`
+	OnChange      string          `json:"onchange,omitempty"`
+	Constraint    string          `json:"constraint,omitempty"`
+}
+
+// A MethodDescription holds the documentation and the signature of a method.
+//
+// Params and Returns do not include the RecordCollection receiver.
+type MethodDescription struct {
+	Name      string   `json:"name"`
+	Doc       string   `json:"doc"`
+	Signature string   `json:"signature"`
+	Params    []string `json:"params"`
+	Returns   []string `json:"returns"`
+	// Groups are the names of the groups allowed to execute the method from any caller
+	Groups []string `json:"groups"`
+	// RequiredGroups are the names of the groups of which the
+	// user must be a member of one to execute the method
+	RequiredGroups []string `json:"required_groups"`
+}
+
+// methodDocs holds the registered documentation of methods by model and method name
+var methodDocs struct {
+	sync.RWMutex
+	byModel map[string]map[string]string
+}
+
+// RegisterMethodDocs registers the documentation of the methods of the given
+// model, given by method name. It is called by the generated pool with the doc
+// comments of the method functions, which are not available at runtime.
+func RegisterMethodDocs(modelName string, docs map[string]string) {
+	methodDocs.Lock()
+	defer methodDocs.Unlock()
+	if methodDocs.byModel == nil {
+		methodDocs.byModel = make(map[string]map[string]string)
+	}
+	if methodDocs.byModel[modelName] == nil {
+		methodDocs.byModel[modelName] = make(map[string]string)
+	}
+	for method, doc := range docs {
+		methodDocs.byModel[modelName][method] = doc
+	}
+}
+
+// Doc returns the documentation of this method, or an empty
+// string if none has been registered.
+func (m *Method) Doc() string {
+	methodDocs.RLock()
+	defer methodDocs.RUnlock()
+	return methodDocs.byModel[m.model.name][m.name]
+}
+
+// Describe returns the description of this field
+func (f *Field) Describe() FieldDescription {
+	selection := f.selection
+	if selection == nil && f.selectionFunc != nil {
+		selection = f.selectionFunc()
+	}
+	var goType string
+	if f.structField.Type != nil {
+		goType = f.structField.Type.String()
+	}
+	_, translate := f.contexts["lang"]
+	return FieldDescription{
+		Name:          f.name,
+		JSON:          f.json,
+		Module:        f.module,
+		Type:          f.fieldType,
+		GoType:        goType,
+		String:        f.description,
+		Help:          f.help,
+		Required:      f.required,
+		ReadOnly:      f.readOnly || (f.compute != "" && f.inverse == ""),
+		Stored:        f.isStored(),
+		Index:         f.index,
+		Unique:        f.unique,
+		NoCopy:        f.noCopy,
+		Translate:     translate,
+		Tracking:      f.tracking,
+		Size:          f.size,
+		Digits:        f.digits,
+		GroupOperator: f.groupOperator,
+		Selection:     selection,
+		Relation:      f.relatedModelName,
+		ReverseFK:     f.reverseFK,
+		OnDelete:      f.onDelete,
+		Embed:         f.embed,
+		Compute:       f.compute,
+		Inverse:       f.inverse,
+		Depends:       f.depends,
+		Related:       f.relatedPathStr,
+		OnChange:      f.onChange,
+		Constraint:    f.constraint,
+	}
+}
+
+// Describe returns the description of this method
+func (m *Method) Describe() MethodDescription {
+	res := MethodDescription{
+		Name: m.name,
+		Doc:  m.Doc(),
+	}
+	if m.methodType != nil {
+		res.Signature = methodSignature(m.name, m.methodType)
+		for i := 1; i < m.methodType.NumIn(); i++ {
+			res.Params = append(res.Params, m.methodType.In(i).String())
+		}
+		for i := 0; i < m.methodType.NumOut(); i++ {
+			res.Returns = append(res.Returns, m.methodType.Out(i).String())
+		}
+	}
+	for _, group := range m.AllowedGroups() {
+		res.Groups = append(res.Groups, group.ID)
+	}
+	for _, group := range m.RequiredGroups() {
+		res.RequiredGroups = append(res.RequiredGroups, group.ID)
+	}
+	return res
+}
+
+// methodSignature returns the Go signature of the method with the
+// given name and type, without the RecordCollection receiver.
+func methodSignature(name string, methodType reflect.Type) string {
+	params := make([]string, 0, methodType.NumIn())
+	for i := 1; i < methodType.NumIn(); i++ {
+		typ := methodType.In(i).String()
+		if methodType.IsVariadic() && i == methodType.NumIn()-1 {
+			typ = "..." + methodType.In(i).Elem().String()
+		}
+		params = append(params, typ)
+	}
+	returns := make([]string, methodType.NumOut())
+	for i := range returns {
+		returns[i] = methodType.Out(i).String()
+	}
+	res := fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+	switch len(returns) {
+	case 0:
+	case 1:
+		res += " " + returns[0]
+	default:
+		res += fmt.Sprintf(" (%s)", strings.Join(returns, ", "))
+	}
+	return res
+}
+
+// All returns all the fields of this collection sorted by name
+func (fc *FieldsCollection) All() []*Field {
+	fc.RLock()
+	defer fc.RUnlock()
+	res := make([]*Field, 0, len(fc.registryByName))
+	for _, fi := range fc.registryByName {
+		res = append(res, fi)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].name < res[j].name
+	})
+	return res
+}
+
+// All returns all the methods of this collection sorted by name,
+// including the methods inherited from mixins once the models are bootstrapped.
+func (mc *MethodsCollection) All() []*Method {
+	res := make([]*Method, 0, len(mc.registry))
+	for _, meth := range mc.registry {
+		res = append(res, meth)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].name < res[j].name
+	})
+	return res
+}
+
+// modelType returns the type of this model as given in ModelDescription
+func (m *Model) modelType() string {
+	switch {
+	case m.IsMixin():
+		return ModelTypeMixin
+	case m.IsManual():
+		return ModelTypeManual
+	case m.IsM2MLink():
+		return ModelTypeM2MLink
+	case m.IsTransient():
+		return ModelTypeTransient
+	default:
+		return ModelTypeModel
+	}
+}
+
+// Describe returns the description of this model with its fields and methods
+func (m *Model) Describe() ModelDescription {
+	res := ModelDescription{
+		Name:      m.name,
+		Module:    m.module,
+		Type:      m.modelType(),
+		TableName: m.tableName,
+	}
+	for _, mixin := range m.mixins {
+		res.Mixins = append(res.Mixins, mixin.name)
+	}
+	for _, fi := range m.fields.All() {
+		res.Fields = append(res.Fields, fi.Describe())
+	}
+	for _, meth := range m.methods.All() {
+		res.Methods = append(res.Methods, meth.Describe())
+	}
+	return res
+}
+
+// Describe returns the descriptions of all the models of the registry sorted by name
+func (mc *modelCollection) Describe() []ModelDescription {
+	all := mc.All()
+	res := make([]ModelDescription, len(all))
+	for i, model := range all {
+		res[i] = model.Describe()
+	}
+	return res
+}
+
+// A RelationGraph is the graph of the relations between models.
+// It can be marshaled to JSON or exported to the DOT format of Graphviz.
+type RelationGraph struct {
+	Models    []string   `json:"models"`
+	Relations []Relation `json:"relations"`
+	// Modules gives the module that declared each model of the graph
+	Modules map[string]string `json:"modules"`
+	// Tables gives the database table of each model of the graph
+	Tables map[string]string `json:"tables"`
+}
+
+// A Relation is an edge of a RelationGraph, that is a relation field
+// of model From pointing to model To.
+type Relation struct {
+	From  string         `json:"from"`
+	Field string         `json:"field"`
+	To    string         `json:"to"`
+	Type  fieldtype.Type `json:"type"`
+	// Stored is true if the relation is stored in the database
+	Stored bool `json:"stored"`
+	// LinkTable is the table of many2many relations
+	LinkTable string `json:"link_table,omitempty"`
+}
+
+// RelationGraph returns the graph of the relations between the models of the
+// registry. Mixins and many2many link models are not included. If modelNames
+// are given, only the relations between these models are included.
+func (mc *modelCollection) RelationGraph(modelNames ...string) *RelationGraph {
+	selected := make(map[string]bool)
+	for _, model := range mc.All() {
+		if model.IsMixin() || model.IsM2MLink() {
+			continue
+		}
+		selected[model.name] = len(modelNames) == 0
+	}
+	for _, name := range modelNames {
+		if _, ok := selected[name]; ok {
+			selected[name] = true
+		}
+	}
+	res := &RelationGraph{
+		Modules: make(map[string]string),
+		Tables:  make(map[string]string),
+	}
+	for _, model := range mc.All() {
+		if !selected[model.name] {
+			continue
+		}
+		res.Models = append(res.Models, model.name)
+		res.Modules[model.name] = model.module
+		res.Tables[model.name] = model.tableName
+		for _, fi := range model.fields.All() {
+			if !fi.fieldType.IsRelationType() || !selected[fi.relatedModelName] {
+				continue
+			}
+			rel := Relation{
+				From:   model.name,
+				Field:  fi.name,
+				To:     fi.relatedModelName,
+				Type:   fi.fieldType,
+				Stored: fi.isStored(),
+			}
+			if fi.m2mRelModel != nil {
+				rel.LinkTable = fi.m2mRelModel.tableName
+			}
+			res.Relations = append(res.Relations, rel)
+		}
+	}
+	return res
+}
+
+// DOT returns this graph in the DOT format of Graphviz.
+// Reverse relations (one2many and rev2one) are drawn with dashed lines.
+func (rg *RelationGraph) DOT() string {
+	var buf strings.Builder
+	buf.WriteString("digraph models {\n")
+	buf.WriteString("\tnode [shape=box];\n")
+	for _, model := range rg.Models {
+		fmt.Fprintf(&buf, "\t%q;\n", model)
+	}
+	for _, rel := range rg.Relations {
+		style := ""
+		if rel.Type.IsReverseRelationType() {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&buf, "\t%q -> %q [label=%q%s];\n", rel.From, rel.To, fmt.Sprintf("%s (%s)", rel.Field, rel.Type), style)
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
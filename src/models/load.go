@@ -0,0 +1,275 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// LoadMessage is an error found by LoadRecords in the given data
+type LoadMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Row is the index of the row of the error in the data
+	Row   int    `json:"record"`
+	Field string `json:"field"`
+}
+
+// LoadRecordsResult is the result of the LoadRecords method
+type LoadRecordsResult struct {
+	// IDs are the ids of the created or updated records in the order of the rows,
+	// or nil if the data has errors.
+	IDs []int64 `json:"ids"`
+	// ExternalIDs are the external IDs of the created or updated records in the order of the rows
+	ExternalIDs []string      `json:"external_ids"`
+	Messages    []LoadMessage `json:"messages"`
+}
+
+// A loadColumn is a column of the data given to LoadRecords
+type loadColumn struct {
+	header string
+	field  *Field
+	// externalID is true if the column is the external ID of the record,
+	// or the external IDs of the related records of a relation field.
+	externalID bool
+	// dbID is true if the column is the database ID of the record,
+	// or the database IDs of the related records of a relation field.
+	dbID bool
+}
+
+// parseLoadHeaders returns the columns of the given headers of LoadRecords data.
+//
+// Headers are either id (external ID of the record), .id (database ID of the
+// record), field names, or field names followed by /id or /.id for the external
+// or database IDs of the related records of relation fields.
+func parseLoadHeaders(model *Model, headers []string) ([]loadColumn, []LoadMessage) {
+	var (
+		res      []loadColumn
+		messages []LoadMessage
+	)
+	for _, header := range headers {
+		switch header {
+		case "id":
+			res = append(res, loadColumn{header: header, externalID: true})
+			continue
+		case ".id":
+			res = append(res, loadColumn{header: header, dbID: true})
+			continue
+		}
+		tokens := strings.SplitN(header, "/", 2)
+		col := loadColumn{header: header}
+		fi, ok := model.fields.Get(tokens[0])
+		switch {
+		case !ok:
+			messages = append(messages, loadError(-1, header, "Unknown field %s", tokens[0]))
+			continue
+		case fi.fieldType == fieldtype.One2Many:
+			messages = append(messages, loadError(-1, header, "One2many field %s cannot be loaded", tokens[0]))
+			continue
+		case len(tokens) == 1:
+		case fi.fieldType.IsRelationType() && tokens[1] == "id":
+			col.externalID = true
+		case fi.fieldType.IsRelationType() && tokens[1] == ".id":
+			col.dbID = true
+		default:
+			messages = append(messages, loadError(-1, header, "Unsupported column %s", header))
+			continue
+		}
+		col.field = fi
+		res = append(res, col)
+	}
+	return res, messages
+}
+
+// loadError returns a LoadMessage of type error
+func loadError(row int, field, format string, args ...interface{}) LoadMessage {
+	return LoadMessage{Type: "error", Message: fmt.Sprintf(format, args...), Row: row, Field: field}
+}
+
+// convertLoadValue returns the value of the given column for the given string value
+func (rc *RecordCollection) convertLoadValue(col loadColumn, value string) (interface{}, error) {
+	fi := col.field
+	switch {
+	case fi.fieldType.IsRelationType():
+		relRC := rc.env.Pool(fi.relatedModelName)
+		if value == "" {
+			return relRC, nil
+		}
+		refs := strings.Split(value, ",")
+		for i := range refs {
+			refs[i] = strings.TrimSpace(refs[i])
+		}
+		if fi.fieldType.Is2OneRelationType() && len(refs) > 1 {
+			return nil, fmt.Errorf("several records given for field %s", fi.name)
+		}
+		return rc.findLoadRecords(relRC, refs, col.dbID)
+	case value == "":
+		switch fi.fieldType {
+		case fieldtype.Integer, fieldtype.Float, fieldtype.Boolean:
+			return zeroLoadValue(fi), nil
+		case fieldtype.Date:
+			return dates.Date{}, nil
+		case fieldtype.DateTime:
+			return dates.DateTime{}, nil
+		}
+		return value, nil
+	case fi.fieldType == fieldtype.Integer:
+		return strconv.ParseInt(value, 10, 64)
+	case fi.fieldType == fieldtype.Float:
+		return strconv.ParseFloat(value, 64)
+	case fi.fieldType == fieldtype.Boolean:
+		switch strings.ToLower(value) {
+		case "1", "true", "yes":
+			return true, nil
+		case "0", "false", "no":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q", value)
+	case fi.fieldType == fieldtype.Date:
+		return dates.ParseDateWithLayout(dates.DefaultServerDateFormat, value)
+	case fi.fieldType == fieldtype.DateTime:
+		return dates.ParseDateTimeWithLayout(dates.DefaultServerDateTimeFormat, value)
+	}
+	return value, nil
+}
+
+// zeroLoadValue returns the value of the given numeric or boolean field for an empty string
+func zeroLoadValue(fi *Field) interface{} {
+	switch fi.fieldType {
+	case fieldtype.Float:
+		return float64(0)
+	case fieldtype.Boolean:
+		return false
+	}
+	return int64(0)
+}
+
+// findLoadRecords returns the records of relRC with the given references,
+// which are either external IDs or database IDs if dbID is true.
+func (rc *RecordCollection) findLoadRecords(relRC *RecordCollection, refs []string, dbID bool) (*RecordCollection, error) {
+	mi := relRC.model
+	var cond *Condition
+	if dbID {
+		ids := make([]int64, len(refs))
+		for i, ref := range refs {
+			id, err := strconv.ParseInt(ref, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid database ID %q", ref)
+			}
+			ids[i] = id
+		}
+		cond = mi.Field(ID).In(ids)
+	} else {
+		cond = mi.Field(mi.FieldName("HexyaExternalID")).In(refs)
+	}
+	res := relRC.Search(cond).Fetch()
+	unique := make(map[string]bool)
+	for _, ref := range refs {
+		unique[ref] = true
+	}
+	if res.Len() != len(unique) {
+		return nil, fmt.Errorf("unable to find all records of %s with references %s", mi.name, strings.Join(refs, ","))
+	}
+	return res, nil
+}
+
+// LoadRecords creates or updates records from the given rows of data, in which
+// the values of each row are given in the order of the given field headers.
+//
+// Headers are either field names, or:
+//   - id for the external ID of the record. Records with an existing external
+//     ID are updated, and other records are created with this external ID,
+//     so that loading the same data again does not create new records.
+//   - .id for the database ID of an existing record to update.
+//   - field/id for the comma separated external IDs of the related records
+//     of a relation field (e.g. User/id or Tags/id).
+//   - field/.id for their comma separated database IDs.
+//
+// Values are converted from their string representation to the type of their field.
+// If the data has errors, no record is created or updated and the errors are
+// returned in the Messages of the result. Otherwise, the result holds the ids and
+// the external IDs of the records in the order of the rows.
+func modelMixinLoadRecords(rc *RecordCollection, headers []string, data [][]string) *LoadRecordsResult {
+	res := new(LoadRecordsResult)
+	cols, messages := parseLoadHeaders(rc.model, headers)
+	res.Messages = messages
+	if len(messages) > 0 {
+		return res
+	}
+	pool := rc.env.Pool(rc.model.name)
+	rows := make([]FieldMap, len(data))
+	records := make([]*RecordCollection, len(data))
+	for r, row := range data {
+		if len(row) != len(cols) {
+			res.Messages = append(res.Messages, loadError(r, "", "Row has %d values instead of %d", len(row), len(cols)))
+			continue
+		}
+		values := make(FieldMap)
+		for c, col := range cols {
+			switch {
+			case col.field == nil && col.externalID:
+				if row[c] == "" {
+					continue
+				}
+				values["hexya_external_id"] = row[c]
+				// We deliberately call Search directly without Call so as not to be polluted
+				// by Search overrides such as "Active test".
+				rec := pool.Search(rc.model.Field(rc.model.FieldName("HexyaExternalID")).Equals(row[c])).Limit(1)
+				if !rec.IsEmpty() {
+					records[r] = rec
+				}
+			case col.field == nil && col.dbID:
+				if row[c] == "" {
+					continue
+				}
+				id, err := strconv.ParseInt(row[c], 10, 64)
+				if err == nil {
+					records[r] = pool.Search(rc.model.Field(ID).Equals(id))
+				}
+				if err != nil || records[r].IsEmpty() {
+					res.Messages = append(res.Messages, loadError(r, col.header, "Unknown database ID %q", row[c]))
+				}
+			default:
+				val, err := rc.convertLoadValue(col, row[c])
+				if err != nil {
+					res.Messages = append(res.Messages, loadError(r, col.header, "%s", err))
+					continue
+				}
+				values[col.field.json] = val
+			}
+		}
+		rows[r] = values
+	}
+	if len(res.Messages) > 0 {
+		return res
+	}
+	res.IDs = make([]int64, len(rows))
+	res.ExternalIDs = make([]string, len(rows))
+	// created are the records created by previous rows by external ID,
+	// so that rows with the same external ID update the same record.
+	created := make(map[interface{}]*RecordCollection)
+	for r, values := range rows {
+		rec := records[r]
+		if xid, ok := values["hexya_external_id"]; ok && rec == nil {
+			rec = created[xid]
+		}
+		if rec == nil {
+			rec = pool.Call("Create", NewModelData(rc.model, values)).(RecordSet).Collection()
+			if xid, ok := values["hexya_external_id"]; ok {
+				created[xid] = rec
+			}
+		} else {
+			delete(values, "hexya_external_id")
+			rec.Call("Write", NewModelData(rc.model, values))
+		}
+		res.IDs[r] = rec.Ids()[0]
+		res.ExternalIDs[r] = rec.Get(rc.model.FieldName("HexyaExternalID")).(string)
+	}
+	return res
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+)
+
+// MergeReport is the result of the Merge method
+type MergeReport struct {
+	// Target is the ID of the record into which the other records are merged
+	Target int64 `json:"target"`
+	// Merged are the IDs of the records merged into the target, which are deleted
+	Merged []int64 `json:"merged"`
+	// References are the number of references to the merged records that are
+	// repointed to the target, by model and field (e.g. "Post.User").
+	References map[string]int64 `json:"references"`
+	// DryRun is true if nothing has been changed in the database
+	DryRun bool `json:"dry_run"`
+}
+
+// A mergeReference is a column of a table which references the records of a model
+type mergeReference struct {
+	model *Model
+	field *Field
+	// other is the other column of the table if model is a many2many link model
+	other *Field
+	// resModel is the field holding the model name if the reference is a ResModel/ResID couple
+	resModel *Field
+}
+
+// key returns the key of this reference in a MergeReport
+func (mr mergeReference) key() string {
+	return fmt.Sprintf("%s.%s", mr.model.name, mr.field.name)
+}
+
+// mergeReferences returns all the columns of the database which reference the records of
+// the given model, that is stored many2one fields of other models (including many2many link
+// models) and ResID fields of models which reference records by ResModel and ResID.
+func mergeReferences(target *Model) []mergeReference {
+	var res []mergeReference
+	for _, model := range Registry.registryByName {
+		if model.IsMixin() || model.IsManual() || model.isContext() {
+			continue
+		}
+		if resModel, ok := model.fields.Get("ResModel"); ok && resModel.fieldType == fieldtype.Char {
+			if resID, ok := model.fields.Get("ResID"); ok && resID.fieldType == fieldtype.Integer && resID.isStored() {
+				res = append(res, mergeReference{model: model, field: resID, resModel: resModel})
+			}
+		}
+		for _, fi := range model.fields.registryByName {
+			if fi.fieldType != fieldtype.Many2One || !fi.isStored() || fi.relatedModel != target {
+				continue
+			}
+			ref := mergeReference{model: model, field: fi}
+			if model.IsM2MLink() {
+				for _, other := range model.fields.registryByName {
+					if other != fi && other.fieldType == fieldtype.Many2One {
+						ref.other = other
+					}
+				}
+			}
+			res = append(res, ref)
+		}
+	}
+	return res
+}
+
+// countReferences returns the number of rows of the given reference that point to the given ids
+func (rc *RecordCollection) countReferences(ref mergeReference, ids []int64) int64 {
+	table := adapters[db.DriverName()].quoteTableName(ref.model.tableName)
+	var count int64
+	if ref.resModel != nil {
+		rc.env.cr.Get(&count, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s = ? AND %s IN (?)`,
+			table, ref.resModel.json, ref.field.json), rc.model.name, ids)
+		return count
+	}
+	rc.env.cr.Get(&count, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s IN (?)`, table, ref.field.json), ids)
+	return count
+}
+
+// repointReferences updates the rows of the given reference that point to
+// the given ids so that they point to target instead.
+func (rc *RecordCollection) repointReferences(ref mergeReference, target int64, ids []int64) {
+	table := adapters[db.DriverName()].quoteTableName(ref.model.tableName)
+	switch {
+	case ref.resModel != nil:
+		rc.env.cr.Execute(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s = ? AND %s IN (?)`,
+			table, ref.field.json, ref.resModel.json, ref.field.json), target, rc.model.name, ids)
+	case ref.other != nil:
+		// Many2many links of the merged records are added to the target unless they
+		// already exist, and removed from the merged records.
+		exclude := append([]int64{target}, ids...)
+		if ref.other.relatedModel != rc.model {
+			exclude = []int64{0}
+		}
+		rc.env.cr.Execute(fmt.Sprintf(`
+			INSERT INTO %[1]s (%[2]s, %[3]s)
+			SELECT DISTINCT ?::integer, %[3]s FROM %[1]s
+			WHERE %[2]s IN (?) AND %[3]s NOT IN (?)
+				AND %[3]s NOT IN (SELECT %[3]s FROM %[1]s WHERE %[2]s = ?)`,
+			table, ref.field.json, ref.other.json), target, ids, exclude, target)
+		rc.env.cr.Execute(fmt.Sprintf(`DELETE FROM %s WHERE %s IN (?)`, table, ref.field.json), ids)
+	case ref.model == rc.model:
+		// The target must not reference itself, e.g. as its own parent
+		rc.env.cr.Execute(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s IN (?) AND id != ?`,
+			table, ref.field.json, ref.field.json), target, ids, target)
+	default:
+		rc.env.cr.Execute(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE %s IN (?)`,
+			table, ref.field.json, ref.field.json), target, ids)
+	}
+}
+
+// Merge merges the records of this RecordSet into the given target record, in the
+// transaction of the environment: all the references to these records in other models
+// are repointed to the target, their many2many links are added to the target and these
+// records are then deleted. References are discovered from the model registry and
+// include the messages and other records linked by ResModel and ResID fields.
+//
+// The target record may be part of this RecordSet. If dryRun is true, nothing is
+// changed and the returned report holds the number of references that would be
+// repointed. Merge panics if repointing a reference violates a unique constraint,
+// so that the whole transaction is rolled back.
+func modelMixinMerge(rc *RecordCollection, target RecordSet, dryRun bool) *MergeReport {
+	targetRC := target.Collection()
+	if targetRC.ModelName() != rc.model.name || targetRC.Len() != 1 {
+		log.Panic("Merge target must be a single record of the same model", "model", rc.model.name, "target", targetRC)
+	}
+	targetID := targetRC.Ids()[0]
+	res := &MergeReport{Target: targetID, References: make(map[string]int64), DryRun: dryRun}
+	for _, id := range rc.Ids() {
+		if id != targetID {
+			res.Merged = append(res.Merged, id)
+		}
+	}
+	if len(res.Merged) == 0 {
+		return res
+	}
+	if !dryRun {
+		rc.CheckExecutionPermission(rc.model.methods.MustGet("Unlink"))
+	}
+	for _, ref := range mergeReferences(rc.model) {
+		count := rc.countReferences(ref, res.Merged)
+		if count == 0 {
+			continue
+		}
+		res.References[ref.key()] = count
+		if !dryRun {
+			rc.repointReferences(ref, targetID, res.Merged)
+		}
+	}
+	if dryRun {
+		return res
+	}
+	rc.env.InvalidateCache()
+	rc.Call("Browse", res.Merged).(RecordSet).Collection().Call("Unlink")
+	return res
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// methodProfiling is 1 if method layers profiling is enabled
+var methodProfiling int32
+
+// EnableMethodProfiling enables or disables the profiling of method layers.
+//
+// When enabled, the number of calls and the time spent in each layer of each
+// method is recorded, so that the overrides of a method that slow it down can
+// be identified with MethodProfiles.
+func EnableMethodProfiling(enable bool) {
+	var val int32
+	if enable {
+		val = 1
+	}
+	atomic.StoreInt32(&methodProfiling, val)
+}
+
+// MethodProfilingEnabled returns true if method layers profiling is enabled
+func MethodProfilingEnabled() bool {
+	return atomic.LoadInt32(&methodProfiling) == 1
+}
+
+// layerStats are the profiling statistics of a method layer
+type layerStats struct {
+	calls int64
+	total int64
+	self  int64
+}
+
+// profile records a call of this layer that took the given duration.
+// superCaller is the layer that called this layer with Super, if any.
+func (ml *methodLayer) profile(duration time.Duration, superCaller *methodLayer) {
+	atomic.AddInt64(&ml.stats.calls, 1)
+	atomic.AddInt64(&ml.stats.total, int64(duration))
+	atomic.AddInt64(&ml.stats.self, int64(duration))
+	if superCaller != nil {
+		// The time spent in this layer is not spent in its caller
+		atomic.AddInt64(&superCaller.stats.self, -int64(duration))
+	}
+}
+
+// A LayerProfile holds the profiling statistics of the layers
+// of a method declared by a module.
+type LayerProfile struct {
+	Model  string `json:"model"`
+	Method string `json:"method"`
+	// Module is the name of the module that declared the layers,
+	// or the empty string for the layers of the models package.
+	Module string `json:"module"`
+	Calls  int64  `json:"calls"`
+	// Total is the time spent in the layers, including the next layers
+	// called with Super.
+	Total time.Duration `json:"total"`
+	// Self is the time spent in the layers themselves, excluding the
+	// next layers called with Super.
+	Self time.Duration `json:"self"`
+}
+
+// MethodProfiles returns the profiling statistics of the method layers that
+// have been called since profiling has been enabled, grouped by model, method
+// and module and sorted by decreasing self time.
+func MethodProfiles() []LayerProfile {
+	var res []LayerProfile
+	for _, model := range Registry.All() {
+		for _, method := range model.methods.registry {
+			byModule := make(map[string]int)
+			for _, layer := range method.invertedLayers() {
+				calls := atomic.LoadInt64(&layer.stats.calls)
+				if calls == 0 {
+					continue
+				}
+				i, ok := byModule[layer.module]
+				if !ok {
+					i = len(res)
+					byModule[layer.module] = i
+					res = append(res, LayerProfile{Model: model.name, Method: method.name, Module: layer.module})
+				}
+				res[i].Calls += calls
+				res[i].Total += time.Duration(atomic.LoadInt64(&layer.stats.total))
+				res[i].Self += time.Duration(atomic.LoadInt64(&layer.stats.self))
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Self != res[j].Self {
+			return res[i].Self > res[j].Self
+		}
+		if res[i].Model != res[j].Model {
+			return res[i].Model < res[j].Model
+		}
+		if res[i].Method != res[j].Method {
+			return res[i].Method < res[j].Method
+		}
+		return res[i].Module < res[j].Module
+	})
+	return res
+}
+
+// ResetMethodProfiles clears the profiling statistics of all method layers
+func ResetMethodProfiles() {
+	for _, model := range Registry.All() {
+		for _, method := range model.methods.registry {
+			for _, layer := range method.invertedLayers() {
+				atomic.StoreInt64(&layer.stats.calls, 0)
+				atomic.StoreInt64(&layer.stats.total, 0)
+				atomic.StoreInt64(&layer.stats.self, 0)
+			}
+		}
+	}
+}
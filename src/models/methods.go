@@ -16,6 +16,7 @@ package models
 
 import (
 	"reflect"
+	"sort"
 	"sync"
 
 	"github.com/hexya-erp/hexya/src/models/security"
@@ -139,6 +140,16 @@ type Method struct {
 	nextLayer     map[*methodLayer]*methodLayer
 	groups        map[*security.Group]bool
 	groupsCallers map[callerGroup]bool
+	private       bool
+	// requiredGroups are the groups of which the user must be a
+	// member of one to execute the method, whatever the caller
+	requiredGroups map[*security.Group]bool
+}
+
+// IsPrivate returns true if this method has been declared with
+// NewPrivateMethod and cannot be called through RPC.
+func (m *Method) IsPrivate() bool {
+	return m.private
 }
 
 // MethodType returns the methodType of a Method
@@ -146,13 +157,14 @@ func (m *Method) MethodType() reflect.Type {
 	return m.methodType
 }
 
-// addMethodLayer adds the given layer to this Method.
-func (m *Method) addMethodLayer(val reflect.Value) {
+// addMethodLayer adds the given layer declared by the given module to this Method.
+func (m *Method) addMethodLayer(val reflect.Value, module string) {
 	m.Lock()
 	defer m.Unlock()
 	ml := methodLayer{
 		funcValue: wrapFunctionForMethodLayer(val),
 		method:    m,
+		module:    module,
 	}
 	if m.topLayer != nil {
 		m.nextLayer[&ml] = m.topLayer
@@ -178,6 +190,7 @@ func (m *Method) invertedLayers() []*methodLayer {
 // If callers are defined, then the permission is granted only when this method
 // is called from one of the callers, otherwise it is granted from any caller.
 func (m *Method) AllowGroup(group *security.Group, callers ...Methoder) *Method {
+	Registry.checkMutable("AllowGroup", "model", m.model.name, "method", m.name)
 	m.Lock()
 	defer m.Unlock()
 	if len(callers) == 0 {
@@ -194,6 +207,7 @@ func (m *Method) AllowGroup(group *security.Group, callers ...Methoder) *Method
 // if it has been given previously, otherwise does nothing.
 // Note that this methods revokes all permissions, whatever the caller.
 func (m *Method) RevokeGroup(group *security.Group) *Method {
+	Registry.checkMutable("RevokeGroup", "model", m.model.name, "method", m.name)
 	m.Lock()
 	defer m.Unlock()
 	delete(m.groups, group)
@@ -205,6 +219,57 @@ func (m *Method) RevokeGroup(group *security.Group) *Method {
 	return m
 }
 
+// RequireGroups restricts the execution of this method to the members of one
+// of the given groups, in addition to the execution permissions.
+//
+// Contrary to execution permissions, required groups are checked at each call
+// of the method, even if it is called from another method. This allows to
+// restrict dangerous operations to some users, even if they have access to the
+// other methods of the model. The admin group and the superuser (i.e. Sudo)
+// always satisfy the requirement.
+func (m *Method) RequireGroups(groups ...*security.Group) *Method {
+	Registry.checkMutable("RequireGroups", "model", m.model.name, "method", m.name)
+	m.Lock()
+	defer m.Unlock()
+	if m.requiredGroups == nil {
+		m.requiredGroups = make(map[*security.Group]bool)
+	}
+	for _, group := range groups {
+		m.requiredGroups[group] = true
+	}
+	return m
+}
+
+// RequiredGroups returns the groups required to execute this method
+// set by RequireGroups, sorted by ID.
+func (m *Method) RequiredGroups() []*security.Group {
+	m.RLock()
+	defer m.RUnlock()
+	res := make([]*security.Group, 0, len(m.requiredGroups))
+	for group := range m.requiredGroups {
+		res = append(res, group)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// AllowedGroups returns the groups which have been granted the execution
+// permission on this method from any caller, sorted by ID.
+func (m *Method) AllowedGroups() []*security.Group {
+	m.RLock()
+	defer m.RUnlock()
+	res := make([]*security.Group, 0, len(m.groups))
+	for group := range m.groups {
+		res = append(res, group)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
 // Underlying returns the underlysing method data object
 func (m *Method) Underlying() *Method {
 	return m
@@ -229,19 +294,26 @@ type methodLayer struct {
 	method    *Method
 	mixedIn   bool
 	funcValue reflect.Value
+	module    string
+	stats     layerStats
 }
 
 // copyMethod creates a new method without any method layer for
 // the given model by taking data from the given method.
 func copyMethod(m *Model, method *Method) *Method {
-	return &Method{
+	res := &Method{
 		model:         m,
 		name:          method.name,
 		methodType:    method.methodType,
+		private:       method.private,
 		nextLayer:     make(map[*methodLayer]*methodLayer),
 		groups:        make(map[*security.Group]bool),
 		groupsCallers: make(map[callerGroup]bool),
 	}
+	for group := range method.requiredGroups {
+		res.RequireGroups(group)
+	}
+	return res
 }
 
 // wrapFunctionForMethodLayer take the given fnct Value and wrap it in a
@@ -342,6 +414,7 @@ func convertFunctionArg(fnctArgType reflect.Type, arg interface{}) reflect.Value
 //
 // Do NOT factorize code with NewMethod or the code generation will fail
 func (m *Model) addMethod(methodName string, fnct interface{}) *Method {
+	Registry.checkMutable("NewMethod", "model", m.name, "method", methodName)
 	meth, exists, inModel := m.methods.get(methodName)
 	if exists && !inModel {
 		// We are trying to add an existing mixin method as a new method
@@ -357,6 +430,7 @@ func (m *Model) addMethod(methodName string, fnct interface{}) *Method {
 
 // NewMethod is used in modules to declare a new method for this model.
 func (m *Model) NewMethod(methodName string, fnct interface{}) *Method {
+	Registry.checkMutable("NewMethod", "model", m.name, "method", methodName)
 	meth, exists, inModel := m.methods.get(methodName)
 	if exists && !inModel {
 		// We are trying to add an existing mixin method as a new method
@@ -370,6 +444,16 @@ func (m *Model) NewMethod(methodName string, fnct interface{}) *Method {
 	return meth
 }
 
+// NewPrivateMethod is used in modules to declare a new private method for
+// this model. Private methods can be called from Go code as any other
+// method, but are rejected by RecordCollection.CallRPC when called
+// remotely through RPC.
+func (m *Model) NewPrivateMethod(methodName string, fnct interface{}) *Method {
+	meth := m.NewMethod(methodName, fnct)
+	meth.private = true
+	return meth
+}
+
 // AddEmptyMethod creates a new method without function layer
 // The resulting method cannot be called until finalize is called
 func (m *Model) AddEmptyMethod(methodName string) *Method {
@@ -400,7 +484,7 @@ func (m *Method) finalize(fnct interface{}) *Method {
 	}
 	m.checkMethodAndFnctType(fnct)
 	val := reflect.ValueOf(fnct)
-	m.addMethodLayer(val)
+	m.addMethodLayer(val, declaringModule())
 	m.methodType = val.Type()
 	return m
 }
@@ -408,6 +492,7 @@ func (m *Method) finalize(fnct interface{}) *Method {
 // Extend adds the given fnct function as a new layer on this method.
 // fnct must be of the same signature as the first layer of this method.
 func (m *Method) Extend(fnct interface{}) *Method {
+	Registry.checkMutable("Extend", "model", m.model.name, "method", m.name)
 	m.checkMethodAndFnctType(fnct)
 	val := reflect.ValueOf(fnct)
 	if m.methodType != nil {
@@ -416,7 +501,7 @@ func (m *Method) Extend(fnct interface{}) *Method {
 		m.checkSignaturesMatch(val)
 	}
 	m.methodType = val.Type()
-	m.addMethodLayer(val)
+	m.addMethodLayer(val, declaringModule())
 	return m
 }
 
@@ -126,6 +126,10 @@ func (q *Query) conditionSQLClause(c *Condition) (string, SQLParams) {
 		switch {
 		case first:
 			sql = vSQL
+			if p.isCond && (p.isNot || len(c.predicates) > 1 && !c.predicates[1].isCond) {
+				// Brackets are needed for NOT and the next operators to apply to the whole condition
+				sql = fmt.Sprintf("(%s)", sql)
+			}
 			if p.isNot {
 				sql = "NOT " + sql
 			}
@@ -232,8 +236,12 @@ func (q *Query) sqlLimitOffsetClause() string {
 // of this Query
 func (q *Query) sqlOrderByClause() string {
 	resSlice := make([]string, len(q.orders))
+	sortKeys := q.orderSortKeys()
 	for i, order := range q.orders {
 		_, _, resSlice[i] = q.joinedFieldExpression(splitFieldNames(order.field, ExprSep), true, i)
+		if sortKeys[i] != "" {
+			resSlice[i] = sortKeyAlias(i)
+		}
 		if order.desc {
 			resSlice[i] += " DESC"
 		}
@@ -264,10 +272,15 @@ func (q *Query) sqlCtxOrderBy() string {
 // of this Query, which should be a group by clause.
 func (q *Query) sqlOrderByClauseForGroupBy(aggFncts map[string]string) string {
 	resSlice := make([]string, len(q.orders))
+	sortKeys := q.orderSortKeys()
 	for i, order := range q.orders {
 		aggFnct := aggFncts[order.field.JSON()]
 		if aggFnct == "" {
 			_, _, jfe := q.joinedFieldExpression(splitFieldNames(order.field, ExprSep), true, i)
+			if sortKeys[i] != "" {
+				// The sort key only depends on the grouped code
+				jfe = fmt.Sprintf("min(%s)", sortKeyAlias(i))
+			}
 			if order.desc {
 				jfe += " DESC"
 			}
@@ -352,7 +365,7 @@ func (q *Query) insertQuery(data FieldMap) (string, SQLParams) {
 			}
 		}
 		cols = append(cols, fi.json)
-		vals = append(vals, v)
+		vals = append(vals, fi.encryptValue(v))
 		i++
 	}
 	tableName := adapter.quoteTableName(q.recordSet.model.tableName)
@@ -383,6 +396,7 @@ func (q *Query) selectCommonQuery(fields []FieldName) (string, SQLParams, map[st
 	// Build up the query
 	// Fields
 	fieldsSQL, fieldSubsts := q.fieldsSQL(fieldExprs)
+	fieldsSQL += q.sortKeysSQL(fieldSubsts)
 	// Tables
 	tablesSQL, joinsMap := q.tablesSQL(allExprs)
 	// Where clause and args
@@ -504,7 +518,7 @@ func (q *Query) updateQuery(data FieldMap) (string, SQLParams) {
 	for k, v := range data {
 		fi := q.recordSet.model.fields.MustGet(k)
 		cols[i] = fmt.Sprintf("%s = ?", fi.json)
-		vals[i] = v
+		vals[i] = fi.encryptValue(v)
 		i++
 	}
 	tableName := adapter.quoteTableName(q.recordSet.model.tableName)
@@ -532,6 +546,44 @@ func (q *Query) fieldsSQL(fieldExprs [][]FieldName) (string, map[string]string)
 	return strings.Join(fStr, ", "), substs
 }
 
+// orderSortKeys returns the SQL expressions of the sort key columns of the
+// order by expressions of this query. The returned slice has one item per
+// order by expression, which is empty if its field is not code ordered.
+func (q *Query) orderSortKeys() []string {
+	res := make([]string, len(q.orders))
+	for i, order := range q.orders {
+		fi := q.recordSet.model.getRelatedFieldInfo(order.field)
+		if !fi.codeOrder {
+			continue
+		}
+		joins := q.generateTableJoins(splitFieldNames(order.field, ExprSep))
+		res[i] = fmt.Sprintf("%s.%s", joins[len(joins)-1].alias, fi.codeSortKeyColumn())
+	}
+	return res
+}
+
+// sortKeysSQL returns the SQL string of the sort key columns of the order
+// by expressions of this query, to be appended to the fields of the select
+// clause. The aliases of these columns are added to substs with an empty
+// value so that they are not scanned into records.
+func (q *Query) sortKeysSQL(substs map[string]string) string {
+	var res string
+	for i, sortKey := range q.orderSortKeys() {
+		if sortKey == "" {
+			continue
+		}
+		res += fmt.Sprintf(", %s AS %s", sortKey, sortKeyAlias(i))
+		substs[sortKeyAlias(i)] = ""
+	}
+	return res
+}
+
+// sortKeyAlias returns the alias of the sort key column
+// of the i-th order by expression of a query
+func sortKeyAlias(i int) string {
+	return fmt.Sprintf("sort_key_%d", i)
+}
+
 // fieldsGroupSQL returns the SQL string for the given field expressions
 // in a select query with a GROUP BY clause.
 // Parameter must be with the following format (column names):
@@ -559,15 +611,20 @@ func (q *Query) fieldsGroupSQL(fieldExprs [][]FieldName, aggFncts map[string]str
 func (q *Query) joinedFieldExpression(exprs []FieldName, withAlias bool, aliasIndex int) (string, string, string) {
 	joins := q.generateTableJoins(exprs)
 	lastJoin := joins[len(joins)-1]
+	fieldExpr := fmt.Sprintf("%s.%s", lastJoin.alias, lastJoin.expr.JSON())
+	if fi := q.recordSet.model.getRelatedFieldInfo(joinFieldNames(exprs, ExprSep)); fi.isSQLField() {
+		// SQL fields have no column and are replaced by their expression
+		fieldExpr = fi.sqlExpression(lastJoin.alias)
+	}
 	if withAlias {
 		fAlias := joinFieldNames(exprs, sqlSep).JSON()
 		oldAlias := fAlias
 		if len(fAlias) > maxSQLidentifierLength {
 			fAlias = fmt.Sprintf("f%d", aliasIndex)
 		}
-		return fmt.Sprintf("%s.%s AS %s", lastJoin.alias, lastJoin.expr.JSON(), fAlias), oldAlias, fAlias
+		return fmt.Sprintf("%s AS %s", fieldExpr, fAlias), oldAlias, fAlias
 	}
-	return fmt.Sprintf("%s.%s", lastJoin.alias, lastJoin.expr.JSON()), "", ""
+	return fieldExpr, "", ""
 }
 
 // generateTableJoins transforms a list of fields expression into a list of tableJoins
@@ -17,7 +17,9 @@ package models
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/hexya-erp/hexya/src/models/security"
 	"github.com/hexya-erp/hexya/src/tools/strutils"
@@ -40,12 +42,18 @@ func (rc *RecordCollection) CallMulti(methName string, args ...interface{}) []in
 	if !rc.IsValid() {
 		panic(fmt.Errorf("you cannot call a method on an invalid RecordSet. Model: %s, Method: %s", rc.model.name, methName))
 	}
-	rc.env.checkRecursion()
+	rc.env.checkCallDepth(rc.model.name, methName)
 	startTime := time.Now()
 	methInfo, ok := rc.model.methods.Get(methName)
 	if !ok {
 		log.Panic("Unknown method in model", "method", methName, "model", rc.model.name)
 	}
+	if !rc.env.super {
+		rc.checkRequiredGroups(methInfo)
+	}
+	span, parentSpan := rc.env.cr.startCallSpan(rc.model.name, methName, len(rc.ids))
+	completed := false
+	defer rc.env.cr.endCallSpan(span, parentSpan, &completed)
 
 	methLayer := methInfo.topLayer
 	if rc.env.super {
@@ -60,25 +68,84 @@ func (rc *RecordCollection) CallMulti(methName string, args ...interface{}) []in
 	rSet := rc.WithEnv(newEnv)
 	rSet.env.currentLayer = methLayer
 	rSet.env.recursions += 1
+	rSet.env.callStack = &callFrame{model: rc.model.name, method: methName, super: rc.env.super, parent: rc.env.callStack}
 	if rc.env.currentLayer != nil && rc.env.currentLayer.method != methInfo {
 		rSet.env.previousMethod = rc.env.currentLayer.method
 	}
+	layerStart := time.Now()
 	res := rSet.callMulti(methLayer, args...)
+	if MethodProfilingEnabled() {
+		var superCaller *methodLayer
+		if rc.env.super && rc.env.currentLayer != nil && rc.env.currentLayer.method == methInfo {
+			superCaller = rc.env.currentLayer
+		}
+		methLayer.profile(time.Since(layerStart), superCaller)
+	}
 	for i, r := range res {
 		switch r.(type) {
 		case RecordSet:
 			// Reset the current layer and previous method to this method context and not the called one.
 			res[i].(RecordSet).Collection().env.currentLayer = rc.env.currentLayer
 			res[i].(RecordSet).Collection().env.previousMethod = rc.env.previousMethod
+			res[i].(RecordSet).Collection().env.callStack = rc.env.callStack
 			if res[i].(RecordSet).Collection().env.recursions > 0 {
 				res[i].(RecordSet).Collection().env.recursions -= 1
 			}
 		}
 	}
 	log.Debug("Called Recordset method", "model", rc.ModelName(), "method", methName, "ids", rc.ids, "duration", time.Now().Sub(startTime), "args", strutils.TrimArgs(args))
+	completed = true
 	return res
 }
 
+// RPCMethodName returns the name of the method called through RPC as name.
+// Clients may send method names in snake_case (e.g. "name_get") or with a
+// lower case first letter, which are converted to the Go method name.
+func RPCMethodName(name string) string {
+	var res strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		res.WriteRune(unicode.ToUpper(runes[0]))
+		res.WriteString(string(runes[1:]))
+	}
+	return res.String()
+}
+
+// CallRPC calls the method methName of this RecordCollection on behalf of an
+// RPC client. methName is normalized with RPCMethodName. It panics if the
+// method is private, so that private methods can only be called from Go code.
+//
+// RPC dispatchers must call model methods with CallRPC instead of CallMulti.
+// Since the JSON-RPC dispatcher of the web client is not part of this
+// repository, the server also rejects all JSON-RPC calls to private methods
+// with IsPrivateRPCMethod (see server.RejectPrivateMethods).
+func (rc *RecordCollection) CallRPC(methName string, args ...interface{}) []interface{} {
+	methName = RPCMethodName(methName)
+	methInfo, ok := rc.model.methods.Get(methName)
+	if !ok {
+		log.Panic("Unknown method in model", "method", methName, "model", rc.model.name)
+	}
+	if methInfo.private {
+		log.Panic("Private methods cannot be called through RPC", "method", methName, "model", rc.model.name)
+	}
+	return rc.CallMulti(methName, args...)
+}
+
+// IsPrivateRPCMethod returns true if methName, normalized with RPCMethodName,
+// is a private method of the model modelName. It returns false if the model
+// or the method does not exist.
+func IsPrivateRPCMethod(modelName, methName string) bool {
+	model, ok := Registry.Get(modelName)
+	if !ok {
+		return false
+	}
+	methInfo, ok := model.methods.Get(RPCMethodName(methName))
+	return ok && methInfo.private
+}
+
 // Super returns a RecordSet with a modified callstack so that call to the current
 // method will execute the next method layer.
 //
@@ -131,6 +198,25 @@ func (rc *RecordCollection) callMulti(methLayer *methodLayer, args ...interface{
 	return res
 }
 
+// checkRequiredGroups panics if the current user is not a member of one of
+// the groups required to execute the given method with Method.RequireGroups.
+func (rc *RecordCollection) checkRequiredGroups(method *Method) {
+	if len(method.requiredGroups) == 0 || rc.env.uid == security.SuperUserID {
+		return
+	}
+	userGroups := rc.env.Memberships().UserGroups(rc.env.uid)
+	if _, ok := userGroups[security.GroupAdmin]; ok {
+		return
+	}
+	for group := range method.requiredGroups {
+		if _, ok := userGroups[group]; ok {
+			return
+		}
+	}
+	log.Panic("You are not a member of the groups required to execute this method", "model", rc.ModelName(),
+		"method", fmt.Sprintf("%s.%s()", method.model.name, method.name), "uid", rc.env.uid)
+}
+
 // CheckExecutionPermission panics if the current user is not allowed to
 // execute the given method.
 //
@@ -146,7 +232,7 @@ func (rc *RecordCollection) CheckExecutionPermission(method *Method, dontPanic .
 		// We are calling Super on the same method, so it's ok
 		return true
 	}
-	userGroups := security.Registry.UserGroups(rc.env.uid)
+	userGroups := rc.env.Memberships().UserGroups(rc.env.uid)
 	for group := range userGroups {
 		if method.groups[group] {
 			return true
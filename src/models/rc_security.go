@@ -30,7 +30,7 @@ func (rc *RecordCollection) addRecordRuleConditions(uid int64, perm security.Per
 		}
 	}
 	// Add groups rules
-	userGroups := security.Registry.UserGroups(uid)
+	userGroups := rc.env.Memberships().UserGroups(uid)
 	groupCondition := newCondition()
 	for group := range userGroups {
 		for _, rule := range rSet.model.rulesRegistry.rulesByGroup[group.Name] {
@@ -150,7 +150,7 @@ func (rc *RecordCollection) new(data RecordData) *RecordCollection {
 func (rc *RecordCollection) create(data RecordData) *RecordCollection {
 	defer func() {
 		if r := recover(); r != nil {
-			panic(rc.substituteSQLErrorMessage(r))
+			panic(rc.translateSQLError(r, false))
 		}
 	}()
 	rc.CheckExecutionPermission(rc.model.methods.MustGet("Create"))
@@ -160,6 +160,7 @@ func (rc *RecordCollection) create(data RecordData) *RecordCollection {
 	newData := data.Underlying().Copy()
 	rc.applyDefaults(newData, true)
 	fMap := newData.Underlying().FieldMap
+	rc.validateFieldValues(fMap)
 	rc.applyContexts()
 	rc.addAccessFieldsCreateData(&fMap)
 	fMap = rc.addEmbeddedfields(fMap)
@@ -172,6 +173,7 @@ func (rc *RecordCollection) create(data RecordData) *RecordCollection {
 	var createdId int64
 	query, args := rc.query.insertQuery(storedFieldMap)
 	rc.env.cr.Get(&createdId, query, args...)
+	rc.env.tableModified(rc.model)
 
 	rc.env.cache.addRecord(rc.model, createdId, storedFieldMap, rc.query.ctxArgsSlug())
 	rSet := rc.withIds([]int64{createdId})
@@ -379,6 +381,7 @@ func (rc *RecordCollection) update(data RecordData) bool {
 	// process create data for FK relations if any
 	data = rc.createFKRelationRecords(data)
 	fMap := data.Underlying().Copy().FieldMap
+	rSet.validateFieldValues(fMap)
 	rSet.addAccessFieldsUpdateData(&fMap)
 	rSet.applyContexts()
 	fMap = rSet.addContextsFieldsValues(fMap)
@@ -421,7 +424,7 @@ func (rc *RecordCollection) addAccessFieldsUpdateData(fMap *FieldMap) {
 func (rc *RecordCollection) filterMapOnStoredFields(fMap FieldMap) FieldMap {
 	newFMap := make(FieldMap)
 	for field, value := range fMap {
-		if fi, ok := rc.model.fields.Get(field); ok && fi.isStored() {
+		if fi, ok := rc.model.fields.Get(field); ok && fi.hasColumn() {
 			if fi.inverse != "" && !rc.env.context.GetBool("hexya_force_compute_write") {
 				continue
 			}
@@ -441,7 +444,7 @@ func (rc *RecordCollection) doUpdate(fMap FieldMap) {
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			panic(rc.substituteSQLErrorMessage(r))
+			panic(rc.translateSQLError(r, false))
 		}
 	}()
 	// update DB
@@ -456,6 +459,7 @@ func (rc *RecordCollection) doUpdate(fMap FieldMap) {
 	if !rc.hasNegIds {
 		query, args := rc.query.updateQuery(fMap)
 		res := rc.env.cr.Execute(query, args...)
+		rc.env.tableModified(rc.model)
 		if num, _ := res.RowsAffected(); num == 0 {
 			log.Panic("Unexpected noop on update (num = 0)", "model", rc.ModelName(), "values", fMap, "query", query, "args", args)
 		}
@@ -464,6 +468,12 @@ func (rc *RecordCollection) doUpdate(fMap FieldMap) {
 		for k, v := range fMap {
 			rc.env.cache.updateEntry(rc.model, rec.Ids()[0], k, v, rc.query.ctxArgsSlug())
 		}
+		// SQL fields may depend on the updated columns and must be read again
+		for _, fi := range rc.model.fields.registryByJSON {
+			if fi.isSQLField() {
+				rc.env.cache.removeEntry(rc.model, rec.Ids()[0], fi.json, rc.query.ctxArgsSlug())
+			}
+		}
 	}
 }
 
@@ -497,6 +507,7 @@ func (rc *RecordCollection) updateRelationFields(fMap FieldMap) {
 		case fieldtype.Many2Many:
 			delQuery := fmt.Sprintf(`DELETE FROM %s WHERE %s IN (?)`, fi.m2mRelModel.tableName, fi.m2mOurField.json)
 			rc.env.cr.Execute(delQuery, rc.ids)
+			rc.env.tableModified(fi.m2mRelModel)
 			for _, id := range rc.ids {
 				rc.env.cache.removeM2MLinks(fi, id)
 				query := fmt.Sprintf(`INSERT INTO %s (%s, %s) VALUES (?, ?)`, fi.m2mRelModel.tableName,
@@ -647,27 +658,16 @@ func (rc *RecordCollection) relatedFieldMap(fMap FieldMap, field FieldName) (Fie
 	return res, prefix
 }
 
-// substituteSQLErrorMessage changes the message from the given recover data
-// if it comes from the database with the message defined in this model
-func (rc *RecordCollection) substituteSQLErrorMessage(r interface{}) interface{} {
-	err, ok := r.(error)
-	if !ok {
-		return r
-	}
-	for constraintName, constraint := range rc.model.sqlConstraints {
-		if strings.Contains(err.Error(), constraintName) {
-			res := adapters[db.DriverName()].substituteErrorMessage(err, constraint.errorString)
-			return res
-		}
-	}
-	return r
-}
-
 // unlink deletes the database record of this RecordSet and returns the number of deleted rows.
 // This function is private and low level. It should not be called directly.
 // Instead use rs.Unlink() or rs.Call("Unlink")
 func (rc *RecordCollection) unlink() int64 {
 	rc.CheckExecutionPermission(rc.model.methods.MustGet("Unlink"))
+	defer func() {
+		if r := recover(); r != nil {
+			panic(rc.translateSQLError(r, true))
+		}
+	}()
 	rSet := rc.addRecordRuleConditions(rc.env.uid, security.Unlink)
 	ids := rSet.Ids()
 	if rSet.IsEmpty() {
@@ -679,6 +679,7 @@ func (rc *RecordCollection) unlink() int64 {
 	if !rSet.hasNegIds {
 		query, args := rSet.query.deleteQuery()
 		res := rSet.env.cr.Execute(query, args...)
+		rSet.env.recordsDeleted(rSet.model)
 		num, _ = res.RowsAffected()
 	}
 	for _, id := range ids {
@@ -765,6 +766,7 @@ func (rc *RecordCollection) SearchCount() int {
 	rSet := rc.Limit(0)
 	rSet.applyDefaultOrder()
 	rSet.applyContexts()
+	addSearchFuncsToCondition(rSet.Env(), rSet.model, rSet.query.cond)
 	addNameSearchesToCondition(rSet.model, rSet.query.cond)
 	rSet = rSet.substituteRelatedInQuery()
 	query, args := rSet.query.countQuery()
@@ -823,21 +825,21 @@ func (rc *RecordCollection) ForceLoad(fieldNames ...FieldName) *RecordCollection
 	if len(fields) == 0 {
 		fields = rSet.model.fields.storedFieldNames()
 	}
+	addSearchFuncsToCondition(rSet.Env(), rSet.model, rSet.query.cond)
 	addNameSearchesToCondition(rSet.model, rSet.query.cond)
 	rSet.applyContexts()
 	subFields, _ := rSet.substituteRelatedFields(fields)
 	rSet = rSet.substituteRelatedInQuery()
 	dbFields := filterOnDBFields(rSet.model, subFields)
 	query, args, substs := rSet.query.selectQuery(dbFields)
-	rows := dbQuery(rSet.env.cr.tx, query, args...)
-	defer rows.Close()
+	scq := rSet.sharedCacheQuery(dbFields, query, args)
+	lines, cached := scq.get()
+	if !cached {
+		lines = rSet.queryLines(query, args, substs)
+		scq.put(lines)
+	}
 	var ids []int64
-	for rows.Next() {
-		line := make(FieldMap)
-		err := rSet.model.scanToFieldMap(rows, &line, substs)
-		if err != nil {
-			log.Panic(err.Error(), "model", rSet.ModelName(), "fields", fields)
-		}
+	for _, line := range lines {
 		rSet.env.cache.addRecord(rSet.model, line["id"].(int64), line, rc.query.ctxArgsSlug())
 		ids = append(ids, line["id"].(int64))
 	}
@@ -851,6 +853,22 @@ func (rc *RecordCollection) ForceLoad(fieldNames ...FieldName) *RecordCollection
 	return rSet
 }
 
+// queryLines executes the given select query and returns its rows as FieldMaps
+func (rc *RecordCollection) queryLines(query string, args SQLParams, substs map[string]string) []FieldMap {
+	rows := rc.env.cr.query(query, args...)
+	defer rows.Close()
+	var lines []FieldMap
+	for rows.Next() {
+		line := make(FieldMap)
+		err := rc.model.scanToFieldMap(rows, &line, substs)
+		if err != nil {
+			log.Panic(err.Error(), "model", rc.ModelName(), "query", query)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
 // applyDefaultOrder adds the model's default order if this query has no specific order defined
 func (rc *RecordCollection) applyDefaultOrder() {
 	if len(rc.query.orders) == 0 {
@@ -1060,6 +1078,7 @@ func (rc *RecordCollection) Aggregates(fieldNames ...FieldName) []GroupAggregate
 
 	rSet := rc.addRecordRuleConditions(rc.env.uid, security.Read)
 	rSet.applyContexts()
+	addSearchFuncsToCondition(rSet.Env(), rSet.model, rSet.query.cond)
 	fields := fieldNames
 	subFields, substMap := rSet.substituteRelatedFields(fields)
 	rSet = rSet.substituteRelatedInQuery()
@@ -1069,7 +1088,7 @@ func (rc *RecordCollection) Aggregates(fieldNames ...FieldName) []GroupAggregate
 
 	query, args := rSet.query.selectGroupQuery(rSet.fieldsGroupOperators(dbFields))
 	var res []GroupAggregateRow
-	rows := dbQuery(rSet.env.cr.tx, query, args...)
+	rows := rSet.env.cr.query(query, args...)
 	defer rows.Close()
 
 	for rows.Next() {
@@ -1272,8 +1291,8 @@ func (rc *RecordCollection) withIds(ids []int64) *RecordCollection {
 	return rc
 }
 
-// T translates the given string to the language specified by
-// the 'lang' key of rc.Env().Context(). If for any reason the
+// T translates the given string to the language of rc.Env()
+// (see Environment.Lang). If for any reason the
 // string cannot be translated, then src is returned.
 //
 // You MUST pass a string literal as src to have it extracted automatically
@@ -1281,8 +1300,8 @@ func (rc *RecordCollection) withIds(ids []int64) *RecordCollection {
 // The translated string will be passed to fmt.Sprintf with the optional args
 // before being returned.
 func (rc *RecordCollection) T(src string, args ...interface{}) string {
-	lang := rc.Env().Context().GetString("lang")
-	transCode := i18n.TranslateCode(lang, "", src)
+	lang := rc.Env().Lang()
+	transCode := i18n.ForDatabase(rc.Env().DBName(), lang).TranslateCode(lang, "", src)
 	return fmt.Sprintf(transCode, args...)
 }
 
@@ -17,10 +17,14 @@ package models
 import (
 	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hexya-erp/hexya/src/i18n"
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
 	"github.com/hexya-erp/hexya/src/models/security"
 	"github.com/hexya-erp/hexya/src/models/types/dates"
@@ -41,10 +45,30 @@ type Option int
 
 type modelCollection struct {
 	sync.RWMutex
-	bootstrapped        bool
+	bootstrapped         bool
+	metadataBootstrapped bool
+	// frozen is set to 1 at the end of BootStrap. The models, fields and
+	// methods of a frozen registry are immutable and read without locks.
+	frozen              uint32
 	registryByName      map[string]*Model
 	registryByTableName map[string]*Model
-	sequences           map[string]*Sequence
+	// all is the list of models sorted by name, set when the registry is frozen
+	all []*Model
+	// sequences holds the map[string]*Sequence of the sequences by JSON
+	// name. It is replaced by a modified copy when sequences are added or
+	// dropped, so that it is read without locks.
+	sequences atomic.Value
+	// unreachableDependencies are the dependencies of computed
+	// fields found unreachable when bootstrapping
+	unreachableDependencies []UnreachableDependency
+	// fieldConflicts are the field properties overridden
+	// by several modules found when bootstrapping
+	fieldConflicts []FieldConflict
+	// fieldRenames are the fields renamed by Model.RenameField
+	fieldRenames []FieldRename
+	// removedFields are the names and JSON names of the fields removed
+	// or renamed in each model, mapped to a description of the change
+	removedFields map[*Model]map[string]string
 }
 
 // Get the given Model by name or by table name
@@ -66,15 +90,72 @@ func (mc *modelCollection) MustGet(nameOrJSON string) *Model {
 	return mi
 }
 
+// isFrozen returns true if the registry has been frozen by BootStrap
+func (mc *modelCollection) isFrozen() bool {
+	return atomic.LoadUint32(&mc.frozen) == 1
+}
+
+// freeze makes the registry immutable. It is called at the end of BootStrap
+// with the registry locked.
+func (mc *modelCollection) freeze() {
+	mc.all = make([]*Model, 0, len(mc.registryByName))
+	for _, model := range mc.registryByName {
+		mc.all = append(mc.all, model)
+	}
+	sort.Slice(mc.all, func(i, j int) bool {
+		return mc.all[i].name < mc.all[j].name
+	})
+	atomic.StoreUint32(&mc.frozen, 1)
+}
+
+// checkMutable panics if the registry has been frozen by BootStrap.
+// action is the name of the function that tried to modify the registry.
+func (mc *modelCollection) checkMutable(action string, keyValues ...interface{}) {
+	if !mc.isFrozen() {
+		return
+	}
+	log.Panic("Models cannot be modified after bootstrap", append([]interface{}{"action", action}, keyValues...)...)
+}
+
+// UnfreezeRegistry makes the registry mutable again after BootStrap and
+// returns the function that freezes it back. It is meant for tests that
+// change method permissions at run time and must not be used while
+// environments are in use by other goroutines.
+func UnfreezeRegistry() func() {
+	atomic.StoreUint32(&Registry.frozen, 0)
+	return func() {
+		Registry.Lock()
+		defer Registry.Unlock()
+		Registry.freeze()
+	}
+}
+
+// rLock read locks the registry and returns the function that unlocks it.
+// A frozen registry is not locked since it is immutable.
+func (mc *modelCollection) rLock() func() {
+	if mc.isFrozen() {
+		return func() {}
+	}
+	mc.RLock()
+	return mc.RUnlock
+}
+
+// getSequences returns the map of the sequences by JSON name.
+// The returned map must not be modified.
+func (mc *modelCollection) getSequences() map[string]*Sequence {
+	return mc.sequences.Load().(map[string]*Sequence)
+}
+
 // GetSequence the given Sequence by name or by db name
 func (mc *modelCollection) GetSequence(nameOrJSON string) (s *Sequence, ok bool) {
-	s, ok = mc.sequences[nameOrJSON]
+	sequences := mc.getSequences()
+	s, ok = sequences[nameOrJSON]
 	if !ok {
 		jsonBoot := strutils.SnakeCase(nameOrJSON) + "_bootseq"
-		s, ok = mc.sequences[jsonBoot]
+		s, ok = sequences[jsonBoot]
 		if !ok {
 			jsonMan := strutils.SnakeCase(nameOrJSON) + "_manseq"
-			s, ok = mc.sequences[jsonMan]
+			s, ok = sequences[jsonMan]
 		}
 	}
 	return
@@ -92,6 +173,7 @@ func (mc *modelCollection) MustGetSequence(nameOrJSON string) *Sequence {
 
 // add the given Model to the modelCollection
 func (mc *modelCollection) add(mi *Model) {
+	mc.checkMutable("CreateModel", "model", mi.name)
 	if _, exists := mc.Get(mi.name); exists {
 		log.Panic("Trying to add already existing model", "model", mi.name)
 	}
@@ -101,23 +183,41 @@ func (mc *modelCollection) add(mi *Model) {
 	mi.fields.model = mi
 }
 
-// add the given Model to the modelCollection
+// addSequence adds the given Sequence to the modelCollection
 func (mc *modelCollection) addSequence(s *Sequence) {
+	mc.Lock()
+	defer mc.Unlock()
 	if _, exists := mc.GetSequence(s.JSON); exists {
 		log.Panic("Trying to add already existing sequence", "sequence", s.JSON)
 	}
-	mc.Lock()
-	defer mc.Unlock()
-	mc.sequences[s.JSON] = s
+	sequences := make(map[string]*Sequence, len(mc.getSequences())+1)
+	for json, seq := range mc.getSequences() {
+		sequences[json] = seq
+	}
+	sequences[s.JSON] = s
+	mc.sequences.Store(sequences)
+}
+
+// removeSequence removes the sequence with the given JSON name from the
+// modelCollection. The registry must be locked.
+func (mc *modelCollection) removeSequence(json string) {
+	sequences := make(map[string]*Sequence, len(mc.getSequences()))
+	for name, seq := range mc.getSequences() {
+		if name != json {
+			sequences[name] = seq
+		}
+	}
+	mc.sequences.Store(sequences)
 }
 
 // newModelCollection returns a pointer to a new modelCollection
 func newModelCollection() *modelCollection {
-	return &modelCollection{
+	mc := &modelCollection{
 		registryByName:      make(map[string]*Model),
 		registryByTableName: make(map[string]*Model),
-		sequences:           make(map[string]*Sequence),
 	}
+	mc.sequences.Store(make(map[string]*Sequence))
+	return mc
 }
 
 // A Model is the definition of a business object (e.g. a partner, a sale order, etc.)
@@ -135,6 +235,7 @@ type Model struct {
 	defaultOrderStr []string
 	defaultOrder    []orderPredicate
 	created         bool
+	module          string
 }
 
 // An sqlConstraint holds the data needed to create a table constraint in the database
@@ -149,6 +250,11 @@ func (m *Model) Name() string {
 	return m.name
 }
 
+// Module returns the name of the module that declared this model
+func (m *Model) Module() string {
+	return m.module
+}
+
 // getRelatedModelInfo returns the Model of the related model when
 // following path.
 // - If skipLast is true, getRelatedModelInfo does not follow the last part of the path
@@ -220,6 +326,10 @@ func (m *Model) scanToFieldMap(r sqlx.ColScanner, dest *FieldMap, substs map[str
 	for i, dbValue := range dbValues {
 		colName := columns[i]
 		if s, ok := substs[colName]; ok {
+			if s == "" {
+				// This column is not a field, such as a sort key
+				continue
+			}
 			colName = s
 		}
 		colName = strings.Replace(colName, sqlSep, ExprSep, -1)
@@ -227,7 +337,13 @@ func (m *Model) scanToFieldMap(r sqlx.ColScanner, dest *FieldMap, substs map[str
 		(*dest)[colName] = dbVal
 	}
 
-	// Step 3: We convert values with the type of the corresponding Field
+	// Step 3: We decrypt the values of encrypted fields
+	for colName, dbVal := range *dest {
+		fi := m.getRelatedFieldInfo(m.FieldName(colName))
+		(*dest)[colName] = fi.decryptValue(dbVal)
+	}
+
+	// Step 4: We convert values with the type of the corresponding Field
 	// if the value is not nil.
 	m.convertValuesToFieldType(dest, false)
 	return r.Err()
@@ -270,15 +386,65 @@ func (m *Model) convertValuesToFieldType(fMap *FieldMap, writeDB bool) {
 
 // AddFields adds the given fields to the model.
 func (m *Model) AddFields(fields map[string]FieldDefinition) {
+	Registry.checkMutable("AddFields", "model", m.name)
 	for name, field := range fields {
 		newField := field.DeclareField(m.fields, name)
 		if _, exists := m.fields.Get(name); exists {
 			log.Panic("models.Field already exists", "model", m.name, "field", name)
 		}
+		newField.module = declaringModule()
 		m.fields.add(newField)
 	}
 }
 
+// RemoveField removes the field with the given name from this model.
+// The field may be inherited from a mixin or declared by another module.
+//
+// The removal is applied at bootstrap, and its column is dropped from the
+// database. Bootstrap panics if another module modifies the removed field.
+func (m *Model) RemoveField(name string) {
+	Registry.checkMutable("RemoveField", "model", m.name, "field", name)
+	if name == "ID" {
+		log.Panic("ID field cannot be removed", "model", m.name)
+	}
+	m.fields.MustGet(name).addUpdate("remove", true)
+}
+
+// RenameField gives the new name newName to the field with the given name
+// of this model.
+//
+// The rename is applied at bootstrap, and the column of the field is renamed
+// in the database so that its data is kept. Renames are listed by
+// Registry.FieldRenames so that modules can migrate the data that refer to
+// the former name, such as views or domains. Bootstrap panics if several
+// modules rename the same field differently.
+func (m *Model) RenameField(name, newName string) {
+	Registry.checkMutable("RenameField", "model", m.name, "field", name)
+	if name == "ID" {
+		log.Panic("ID field cannot be renamed", "model", m.name)
+	}
+	m.fields.MustGet(name).addUpdate("rename", newName)
+}
+
+// ChangeFieldType replaces the field with the given name of this model by
+// the field of the given definition, which is usually of another type.
+// The field may be inherited from a mixin or declared by another module.
+//
+// The change is applied at bootstrap, before the modifications of the field
+// made by other modules, which are kept. The column of the field is converted
+// to the new type in the database. Bootstrap panics if several modules change
+// the type of the same field.
+func (m *Model) ChangeFieldType(name string, fieldDef FieldDefinition) {
+	Registry.checkMutable("ChangeFieldType", "model", m.name, "field", name)
+	if name == "ID" {
+		log.Panic("Type of ID field cannot be changed", "model", m.name)
+	}
+	fi := m.fields.MustGet(name)
+	newField := fieldDef.DeclareField(m.fields, name)
+	newField.module = declaringModule()
+	fi.addUpdate("type", newField)
+}
+
 // IsMixin returns true if this is a mixin model.
 func (m *Model) IsMixin() bool {
 	if m.options&MixinModel > 0 {
@@ -347,6 +513,7 @@ func (m *Model) Methods() *MethodsCollection {
 // Give the order fields in separate strings, such as
 // model.SetDefaultOrder("Name desc", "date asc", "id")
 func (m *Model) SetDefaultOrder(orders ...string) {
+	Registry.checkMutable("SetDefaultOrder", "model", m.name)
 	m.defaultOrderStr = orders
 }
 
@@ -421,9 +588,9 @@ func (m *Model) FieldsGet(fields ...FieldName) map[string]*FieldInfo {
 			Help:          fInfo.help,
 			Searchable:    true,
 			Depends:       fInfo.depends,
-			Sortable:      true,
+			Sortable:      !fInfo.isComputedField() || fInfo.isStored(),
 			Type:          fInfo.fieldType,
-			Store:         fInfo.isSettable(),
+			Store:         fInfo.isSettable() && (!fInfo.isComputedField() || fInfo.isStored()) || fInfo.isSQLField(),
 			String:        fInfo.description,
 			Relation:      relation,
 			Selection:     fInfo.selection,
@@ -438,11 +605,25 @@ func (m *Model) FieldsGet(fields ...FieldName) map[string]*FieldInfo {
 			RequiredFunc:  fInfo.requiredFunc,
 			GoType:        fInfo.structField.Type,
 			Index:         fInfo.index,
+			Digits:        fInfo.digits,
 		}
 	}
 	return res
 }
 
+// TranslateFieldsInfo translates in place the string, help and selection
+// of the given field infos of this model, as returned by FieldsGet, in the
+// language of env (see Environment.Lang).
+func (m *Model) TranslateFieldsInfo(env Environment, infos map[string]*FieldInfo) {
+	lang := env.Lang()
+	translations := i18n.ForDatabase(env.DBName(), lang)
+	for fName, fInfo := range infos {
+		fInfo.Help = translations.TranslateFieldHelp(lang, m.name, fName, fInfo.Help)
+		fInfo.String = translations.TranslateFieldDescription(lang, m.name, fName, fInfo.String)
+		fInfo.Selection = translations.TranslateFieldSelection(lang, m.name, fName, fInfo.Selection)
+	}
+}
+
 // FilteredOn adds a condition with a table join on the given field and
 // filters the result with the given condition
 func (m *Model) FilteredOn(field FieldName, condition *Condition) *Condition {
@@ -485,6 +666,7 @@ func (m *Model) BrowseOne(env Environment, id int64) *RecordCollection {
 //    - sql is constraint definition to pass to the database.
 //    - errorString is the text to display to the user when the constraint is violated
 func (m *Model) AddSQLConstraint(name, sql, errorString string) {
+	Registry.checkMutable("AddSQLConstraint", "model", m.name, "constraint", name)
 	constraintName := fmt.Sprintf("%s_%s_mancon", name, m.tableName)
 	m.sqlConstraints[constraintName] = sqlConstraint{
 		name:        constraintName,
@@ -495,6 +677,7 @@ func (m *Model) AddSQLConstraint(name, sql, errorString string) {
 
 // RemoveSQLConstraint removes the sql constraint with the given name from the database.
 func (m *Model) RemoveSQLConstraint(name string) {
+	Registry.checkMutable("RemoveSQLConstraint", "model", m.name, "constraint", name)
 	delete(m.sqlConstraints, fmt.Sprintf("%s_mancon", name))
 }
 
@@ -513,6 +696,7 @@ var _ Modeler = new(Model)
 // getOrCreateModel checks if the given model has been created
 // and creates it if it is not the case/
 func getOrCreateModel(name string, options Option) *Model {
+	Registry.checkMutable("NewModel", "model", name)
 	model, ok := Registry.Get(name)
 	if !ok {
 		model = CreateModel(name, options)
@@ -521,9 +705,57 @@ func getOrCreateModel(name string, options Option) *Model {
 		log.Panic("Trying to add already existing model", "model", name)
 	}
 	model.created = true
+	model.module = declaringModule()
 	return model
 }
 
+// declaringModule returns the name of the module that called the models
+// package, that is the name of the package of the first caller in the stack
+// that does not belong to the models package or to the generated pool.
+// It returns an empty string for the models declared by the framework.
+func declaringModule() string {
+	pc := make([]uintptr, 20)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		pkg := frame.Function
+		lastSlash := strings.LastIndex(pkg, "/")
+		if dot := strings.Index(pkg[lastSlash+1:], "."); dot >= 0 {
+			pkg = pkg[:lastSlash+1+dot]
+		}
+		switch {
+		case pkg == "github.com/hexya-erp/hexya/src/models", strings.Contains(pkg, "/pool/"):
+		case !strings.Contains(strings.SplitN(pkg, "/", 2)[0], "."):
+			// Standard library caller such as runtime for the
+			// models declared by the models package itself.
+			return ""
+		default:
+			return pkg[strings.LastIndex(pkg, "/")+1:]
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// All returns all the models of the registry sorted by name
+func (mc *modelCollection) All() []*Model {
+	if mc.isFrozen() {
+		return append([]*Model(nil), mc.all...)
+	}
+	mc.RLock()
+	defer mc.RUnlock()
+	res := make([]*Model, 0, len(mc.registryByName))
+	for _, model := range mc.registryByName {
+		res = append(res, model)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].name < res[j].name
+	})
+	return res
+}
+
 // NewModel creates a new model with the given name.
 func NewModel(name string) *Model {
 	model := getOrCreateModel(name, 0)
@@ -556,6 +788,7 @@ func NewManualModel(name string) *Model {
 // MixIn methods and fields have a lower priority than those of the model and are
 // overridden by the them when applicable.
 func (m *Model) InheritModel(mixInModel Modeler) {
+	Registry.checkMutable("InheritModel", "model", m.name, "mixin", mixInModel.Underlying().name)
 	m.mixins = append(m.mixins, mixInModel.Underlying())
 }
 
@@ -632,13 +865,13 @@ func CreateSequence(name string, increment, start int64) *Sequence {
 func (s *Sequence) Drop() {
 	Registry.Lock()
 	defer Registry.Unlock()
-	delete(Registry.sequences, s.JSON)
+	if Registry.bootstrapped && s.boot {
+		log.Panic("Boot Sequences cannot be dropped after bootstrap")
+	}
+	Registry.removeSequence(s.JSON)
 	if Registry.bootstrapped {
 		// Drop the sequence on the fly if we already bootstrapped.
 		// Otherwise, this will be done in Bootstrap
-		if s.boot {
-			log.Panic("Boot Sequences cannot be dropped after bootstrap")
-		}
 		adapters[db.DriverName()].dropSequence(s.JSON)
 	}
 }
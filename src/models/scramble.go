@@ -0,0 +1,186 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"html"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+)
+
+// scrambleBatchSize is the number of records updated by each query
+// when scrambling a field.
+const scrambleBatchSize = 1000
+
+// scrambledFieldTypes are the types of the fields that can be scrambled.
+// Fields of other types are left untouched by ScrambleFields.
+var scrambledFieldTypes = map[fieldtype.Type]bool{
+	fieldtype.Binary:   true,
+	fieldtype.Char:     true,
+	fieldtype.Date:     true,
+	fieldtype.DateTime: true,
+	fieldtype.HTML:     true,
+	fieldtype.Text:     true,
+}
+
+// scrambledDatesStart is the earliest date given to scrambled date fields.
+// Scrambled dates are spread over the fifty following years.
+var scrambledDatesStart = time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// A Scrambler overwrites some data of the database of env with fake
+// data drawn from rnd. It returns the number of overwritten values.
+type Scrambler func(env Environment, rnd *rand.Rand) int
+
+var scramblers struct {
+	sync.RWMutex
+	list []Scrambler
+}
+
+// RegisterScrambler registers the given Scrambler to be called by ScrambleDatabase.
+//
+// Modules that hold personal data outside of model fields, such as files,
+// register a Scrambler to overwrite it.
+func RegisterScrambler(scrambler Scrambler) {
+	scramblers.Lock()
+	defer scramblers.Unlock()
+	scramblers.list = append(scramblers.list, scrambler)
+}
+
+// ScrambleDatabase overwrites the personal data of the database of env with
+// fake data by calling all the registered scramblers in turn, so that a copy
+// of a production database can be handed to developers. It returns the number
+// of values that have been overwritten.
+//
+// The same seed gives the same fake data on the same database.
+func ScrambleDatabase(env Environment, seed int64) int {
+	scramblers.RLock()
+	list := append([]Scrambler(nil), scramblers.list...)
+	scramblers.RUnlock()
+	rnd := rand.New(rand.NewSource(seed))
+	var count int
+	for _, scrambler := range list {
+		count += scrambler(env, rnd)
+	}
+	return count
+}
+
+// ScrambleFields overwrites the values of the given fields in all the records
+// of the database with fake data. fields are the names of the fields to
+// scramble by model name. It returns the number of values that have been
+// overwritten.
+//
+// Only binary, char, date, datetime, html and text fields are scrambled.
+// Empty values are left empty. Texts are derived from the field label and the
+// record ID, so that they remain unique, and email and phone fields are given
+// values of the same kind. Dates are drawn from rnd, binary values are cleared.
+// The translations of the values of translatable fields are scrambled too.
+//
+// Values are written directly in the database: methods, constraints and
+// computed fields are not triggered.
+func ScrambleFields(env Environment, rnd *rand.Rand, fields map[string][]string) int {
+	modelNames := make([]string, 0, len(fields))
+	for modelName := range fields {
+		modelNames = append(modelNames, modelName)
+	}
+	sort.Strings(modelNames)
+	var count int
+	for _, modelName := range modelNames {
+		model := Registry.MustGet(modelName)
+		fNames := append([]string(nil), fields[modelName]...)
+		sort.Strings(fNames)
+		for _, fName := range fNames {
+			fi := model.fields.MustGet(fName)
+			if !scrambledFieldTypes[fi.fieldType] {
+				continue
+			}
+			if fi.hasColumn() {
+				count += scrambleColumn(env, fi, rnd)
+			}
+			if fi.isContextedField() {
+				contextsModel := model.fields.MustGet(fmt.Sprintf("%sHexyaContexts", fi.name)).relatedModel
+				count += scrambleColumn(env, contextsModel.fields.MustGet(fi.name), rnd)
+			}
+		}
+	}
+	return count
+}
+
+// scrambleColumn overwrites the non empty values of the column of the given
+// field with fake data by batches of scrambleBatchSize records. It returns
+// the number of overwritten values.
+func scrambleColumn(env Environment, fi *Field, rnd *rand.Rand) int {
+	adapter := adapters[db.DriverName()]
+	table := adapter.quoteTableName(fi.model.tableName)
+	notEmpty := fmt.Sprintf("%s IS NOT NULL", fi.json)
+	if fi.fieldType != fieldtype.Date && fi.fieldType != fieldtype.DateTime {
+		notEmpty += fmt.Sprintf(" AND %s != ''", fi.json)
+	}
+	if fi.fieldType == fieldtype.Binary {
+		value := "NULL"
+		if fi.required {
+			value = "''"
+		}
+		res := env.cr.Execute(fmt.Sprintf(`UPDATE %s SET %s = %s WHERE %s`, table, fi.json, value, notEmpty))
+		count, _ := res.RowsAffected()
+		return int(count)
+	}
+	var ids []int64
+	env.cr.Select(&ids, fmt.Sprintf(`SELECT id FROM %s WHERE %s ORDER BY id`, table, notEmpty))
+	for start := 0; start < len(ids); start += scrambleBatchSize {
+		end := start + scrambleBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		rows := make([]string, 0, end-start)
+		args := make([]interface{}, 0, 2*(end-start))
+		for _, id := range ids[start:end] {
+			rows = append(rows, "(?::bigint, ?)")
+			args = append(args, id, fi.encryptValue(scrambledValue(fi, id, rnd)))
+		}
+		env.cr.Execute(fmt.Sprintf(`UPDATE %s SET %s = v.value::%s FROM (VALUES %s) AS v(id, value) WHERE %s.id = v.id`,
+			table, fi.json, adapter.typeSQL(fi), strings.Join(rows, ", "), table), args...)
+	}
+	return len(ids)
+}
+
+// scrambledValue returns the fake value to write in the given field of the record with the given id
+func scrambledValue(fi *Field, id int64, rnd *rand.Rand) interface{} {
+	switch fi.fieldType {
+	case fieldtype.Binary:
+		if fi.required {
+			return ""
+		}
+		return nil
+	case fieldtype.Date:
+		return scrambledDatesStart.AddDate(0, 0, rnd.Intn(50*365)).Format("2006-01-02")
+	case fieldtype.DateTime:
+		return scrambledDatesStart.AddDate(0, 0, rnd.Intn(50*365)).Add(time.Duration(rnd.Intn(86400)) * time.Second)
+	case fieldtype.HTML:
+		return fmt.Sprintf("<p>%s</p>", html.EscapeString(scrambledText(fi, id)))
+	}
+	return scrambledText(fi, id)
+}
+
+// scrambledText returns the fake text to write in the given string field of the record with the given id
+func scrambledText(fi *Field, id int64) string {
+	var res string
+	switch {
+	case strings.Contains(fi.json, "email"):
+		res = fmt.Sprintf("%s%d@example.com", strings.Replace(fi.model.tableName, "_", ".", -1), id)
+	case strings.Contains(fi.json, "phone"), strings.Contains(fi.json, "mobile"), strings.Contains(fi.json, "fax"):
+		res = fmt.Sprintf("+1 555 %07d", id%10000000)
+	default:
+		res = fmt.Sprintf("%s %d", fi.description, id)
+	}
+	if fi.size > 0 && len([]rune(res)) > fi.size {
+		res = string([]rune(res)[:fi.size])
+	}
+	return res
+}
@@ -63,10 +63,23 @@ func (g Group) String() string {
 	return fmt.Sprintf("Group(%s)", g.ID)
 }
 
-// A GroupCollection keeps a list of groups
+// MainDatabase is the name under which the memberships of the
+// main database are kept (see GroupCollection.ForDatabase).
+const MainDatabase = ""
+
+// A GroupCollection keeps a list of groups and the memberships
+// of the users of each database.
 type GroupCollection struct {
 	sync.RWMutex
-	groups      map[string]*Group
+	groups    map[string]*Group
+	databases map[string]*MembershipCollection
+}
+
+// A MembershipCollection keeps the group memberships of the users
+// of a database. User IDs are only meaningful within their database,
+// so that each database has its own MembershipCollection.
+type MembershipCollection struct {
+	sync.RWMutex
 	memberships map[int64]map[*Group]InheritanceInfo
 }
 
@@ -96,10 +109,10 @@ func (gc *GroupCollection) RegisterGroup(group *Group) {
 
 // inheritedBy recursively populates the result slice for the
 // with the group's parents
-func (gc *GroupCollection) inheritedBy(group *Group, result *[]*Group) {
+func inheritedBy(group *Group, result *[]*Group) {
 	for _, parent := range group.Inherits {
 		*result = append(*result, parent)
-		gc.inheritedBy(parent, result)
+		inheritedBy(parent, result)
 	}
 }
 
@@ -118,8 +131,10 @@ func (gc *GroupCollection) UnregisterGroup(group *Group) {
 		}
 	}
 	// remove memberships
-	for uid := range gc.memberships {
-		gc.RemoveMembership(uid, group)
+	for _, mc := range gc.allDatabases() {
+		for _, uid := range mc.users() {
+			mc.RemoveMembership(uid, group)
+		}
 	}
 	// Remove the group itself
 	gc.Lock()
@@ -127,6 +142,46 @@ func (gc *GroupCollection) UnregisterGroup(group *Group) {
 	delete(gc.groups, group.ID)
 }
 
+// ForDatabase returns the memberships of the users of the given database.
+// The empty string (MainDatabase) designates the main database.
+//
+// The memberships of a database are created empty at the first call,
+// except for the SuperUser who is always a member of GroupAdmin.
+func (gc *GroupCollection) ForDatabase(db string) *MembershipCollection {
+	gc.Lock()
+	defer gc.Unlock()
+	if mc, exists := gc.databases[db]; exists {
+		return mc
+	}
+	mc := &MembershipCollection{
+		memberships: make(map[int64]map[*Group]InheritanceInfo),
+	}
+	if GroupAdmin != nil {
+		mc.AddMembership(SuperUserID, GroupAdmin)
+	}
+	gc.databases[db] = mc
+	return mc
+}
+
+// RemoveDatabase removes the memberships of the users of the given database,
+// for instance when this database is dropped.
+func (gc *GroupCollection) RemoveDatabase(db string) {
+	gc.Lock()
+	defer gc.Unlock()
+	delete(gc.databases, db)
+}
+
+// allDatabases returns the memberships of all the databases
+func (gc *GroupCollection) allDatabases() []*MembershipCollection {
+	gc.RLock()
+	defer gc.RUnlock()
+	res := make([]*MembershipCollection, 0, len(gc.databases))
+	for _, mc := range gc.databases {
+		res = append(res, mc)
+	}
+	return res
+}
+
 // GetGroup returns the group with the given groupID or nil if not found
 func (gc *GroupCollection) GetGroup(groupID string) *Group {
 	return gc.groups[groupID]
@@ -137,89 +192,132 @@ func (gc *GroupCollection) GetGroup(groupID string) *Group {
 // inherit is set to true when this method is called on an
 // inherited group recursively. You should normally leave it
 // unset.
-func (gc *GroupCollection) AddMembership(uid int64, group *Group, inherit ...bool) {
+func (mc *MembershipCollection) AddMembership(uid int64, group *Group, inherit ...bool) {
 	var inheritingGroups []*Group
-	gc.inheritedBy(group, &inheritingGroups)
+	inheritedBy(group, &inheritingGroups)
 	for _, grp := range inheritingGroups {
-		gc.AddMembership(uid, grp, true)
+		mc.AddMembership(uid, grp, true)
 	}
-	gc.Lock()
-	defer gc.Unlock()
+	mc.Lock()
+	defer mc.Unlock()
 	mode := NativeGroup
 	if len(inherit) > 0 && inherit[0] {
 		mode = InheritedGroup
 	}
-	if _, exists := gc.memberships[uid]; !exists {
-		gc.memberships[uid] = make(map[*Group]InheritanceInfo)
+	if _, exists := mc.memberships[uid]; !exists {
+		mc.memberships[uid] = make(map[*Group]InheritanceInfo)
 	}
-	gc.memberships[uid][group] = mode
+	mc.memberships[uid][group] = mode
 }
 
 // RemoveMembership removes the user with the given uid from the given group
 // and all groups that inherit from this group.
-func (gc *GroupCollection) RemoveMembership(uid int64, group *Group) {
-	if _, exists := gc.memberships[uid][group]; !exists {
+func (mc *MembershipCollection) RemoveMembership(uid int64, group *Group) {
+	if !mc.HasMembership(uid, group) {
 		return
 	}
-	gc.doRemoveMembership(uid, group)
+	mc.doRemoveMembership(uid, group)
 	// Re-Add membership for all existing groups to compute inheritance
-	for grp, ii := range gc.memberships[uid] {
-		if ii == NativeGroup {
-			gc.AddMembership(uid, grp)
+	for grp, ii := range mc.UserGroups(uid) {
+		if ii == NativeGroup && grp != GroupEveryone {
+			mc.AddMembership(uid, grp)
 		}
 	}
 }
 
 // doRemoveMembership actually removes the user with the given uid from the
 // given Group and all groups that inherit from this Group.
-func (gc *GroupCollection) doRemoveMembership(uid int64, group *Group) {
-	gc.Lock()
-	defer gc.Unlock()
+func (mc *MembershipCollection) doRemoveMembership(uid int64, group *Group) {
+	mc.Lock()
+	defer mc.Unlock()
 	// Remove our group
-	delete(gc.memberships[uid], group)
+	delete(mc.memberships[uid], group)
 	// Remove all inherited groups
 	for _, grp := range group.Inherits {
-		if gc.memberships[uid][grp] == InheritedGroup {
-			delete(gc.memberships[uid], grp)
+		if mc.memberships[uid][grp] == InheritedGroup {
+			delete(mc.memberships[uid], grp)
 		}
 	}
 }
 
 // RemoveAllMembershipsForUser removes the given uid from all groups
-func (gc *GroupCollection) RemoveAllMembershipsForUser(uid int64) {
-	gc.doRemoveAllMembershipsForUser(uid)
+func (mc *MembershipCollection) RemoveAllMembershipsForUser(uid int64) {
+	mc.doRemoveAllMembershipsForUser(uid)
 	if uid == SuperUserID {
-		gc.AddMembership(SuperUserID, GroupAdmin)
+		mc.AddMembership(SuperUserID, GroupAdmin)
 	}
 }
 
 // doRemoveAllMembershipsForUser actually removes the given uid from all groups
-func (gc *GroupCollection) doRemoveAllMembershipsForUser(uid int64) {
-	gc.Lock()
-	defer gc.Unlock()
-	delete(gc.memberships, uid)
+func (mc *MembershipCollection) doRemoveAllMembershipsForUser(uid int64) {
+	mc.Lock()
+	defer mc.Unlock()
+	delete(mc.memberships, uid)
 }
 
 // HasMembership returns true id the given uid is a member of the given group
-func (gc *GroupCollection) HasMembership(uid int64, group *Group) bool {
+func (mc *MembershipCollection) HasMembership(uid int64, group *Group) bool {
 	if group == GroupEveryone {
 		return true
 	}
-	_, ok := gc.memberships[uid][group]
+	mc.RLock()
+	defer mc.RUnlock()
+	_, ok := mc.memberships[uid][group]
 	return ok
 }
 
 // UserGroups returns the slice of groups the user with the given
 // uid belongs to, including inherited groups.
-func (gc *GroupCollection) UserGroups(uid int64) map[*Group]InheritanceInfo {
-	res := make(map[*Group]InheritanceInfo, len(gc.memberships[uid])+1)
-	for k, v := range gc.memberships[uid] {
+func (mc *MembershipCollection) UserGroups(uid int64) map[*Group]InheritanceInfo {
+	mc.RLock()
+	defer mc.RUnlock()
+	res := make(map[*Group]InheritanceInfo, len(mc.memberships[uid])+1)
+	for k, v := range mc.memberships[uid] {
 		res[k] = v
 	}
 	res[GroupEveryone] = NativeGroup
 	return res
 }
 
+// users returns the IDs of the users that are member of a group
+func (mc *MembershipCollection) users() []int64 {
+	mc.RLock()
+	defer mc.RUnlock()
+	res := make([]int64, 0, len(mc.memberships))
+	for uid := range mc.memberships {
+		res = append(res, uid)
+	}
+	return res
+}
+
+// AddMembership adds the user of the main database defined by its uid to
+// the given group (see MembershipCollection.AddMembership).
+func (gc *GroupCollection) AddMembership(uid int64, group *Group, inherit ...bool) {
+	gc.ForDatabase(MainDatabase).AddMembership(uid, group, inherit...)
+}
+
+// RemoveMembership removes the user of the main database with the given
+// uid from the given group (see MembershipCollection.RemoveMembership).
+func (gc *GroupCollection) RemoveMembership(uid int64, group *Group) {
+	gc.ForDatabase(MainDatabase).RemoveMembership(uid, group)
+}
+
+// RemoveAllMembershipsForUser removes the given uid of the main database from all groups
+func (gc *GroupCollection) RemoveAllMembershipsForUser(uid int64) {
+	gc.ForDatabase(MainDatabase).RemoveAllMembershipsForUser(uid)
+}
+
+// HasMembership returns true id the given uid of the main database is a member of the given group
+func (gc *GroupCollection) HasMembership(uid int64, group *Group) bool {
+	return gc.ForDatabase(MainDatabase).HasMembership(uid, group)
+}
+
+// UserGroups returns the groups the user of the main database with
+// the given uid belongs to, including inherited groups.
+func (gc *GroupCollection) UserGroups(uid int64) map[*Group]InheritanceInfo {
+	return gc.ForDatabase(MainDatabase).UserGroups(uid)
+}
+
 // AllGroups returns a slice with all the groups of the collection
 func (gc *GroupCollection) AllGroups() []*Group {
 	res := make([]*Group, len(gc.groups))
@@ -234,8 +332,8 @@ func (gc *GroupCollection) AllGroups() []*Group {
 // NewGroupCollection returns a pointer to a new empty GroupCollection
 func NewGroupCollection() *GroupCollection {
 	gc := GroupCollection{
-		groups:      make(map[string]*Group),
-		memberships: make(map[int64]map[*Group]InheritanceInfo),
+		groups:    make(map[string]*Group),
+		databases: make(map[string]*MembershipCollection),
 	}
 	return &gc
 }
@@ -132,6 +132,20 @@ func TestGroupRegistry(t *testing.T) {
 			So(Registry.UserGroups(6), ShouldContainKey, group5)
 			So(Registry.UserGroups(6), ShouldContainKey, GroupEveryone)
 		})
+		Convey("Memberships should be kept per database", func() {
+			group := Registry.NewGroup("tenant_group", "Tenant Group")
+			tenant := Registry.ForDatabase("tenant")
+			tenant.AddMembership(7, group)
+			So(tenant.HasMembership(7, group), ShouldBeTrue)
+			So(Registry.HasMembership(7, group), ShouldBeFalse)
+			So(Registry.ForDatabase("other").HasMembership(7, group), ShouldBeFalse)
+			So(Registry.ForDatabase(MainDatabase).HasMembership(7, group), ShouldBeFalse)
+			So(Registry.ForDatabase("other").HasMembership(SuperUserID, GroupAdmin), ShouldBeTrue)
+			Registry.UnregisterGroup(group)
+			So(tenant.HasMembership(7, group), ShouldBeFalse)
+			Registry.RemoveDatabase("tenant")
+			So(Registry.ForDatabase("tenant"), ShouldNotEqual, tenant)
+		})
 	})
 }
 
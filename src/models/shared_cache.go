@@ -0,0 +1,362 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SharedCacheStats holds the metrics of the shared cache of a model
+type SharedCacheStats struct {
+	// Entries is the number of queries currently cached
+	Entries int
+	// Hits is the number of queries served from the cache
+	Hits uint64
+	// Misses is the number of queries that were not in the cache
+	Misses uint64
+	// Evictions is the number of entries removed because
+	// the cache was full or because they expired
+	Evictions uint64
+	// Invalidations is the number of entries removed because
+	// the records of one of their tables have been modified
+	Invalidations uint64
+}
+
+// A sharedCacheEntry holds the result of a query in a sharedCache
+type sharedCacheEntry struct {
+	key    string
+	tables []string
+	lines  []FieldMap
+	expiry time.Time
+}
+
+// A sharedCache caches the results of the queries of a model across
+// environments. Entries are evicted in least recently used order.
+type sharedCache struct {
+	sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	byTable    map[string]map[string]bool
+	lru        *list.List
+	stats      SharedCacheStats
+}
+
+// newSharedCache returns a new empty sharedCache
+func newSharedCache(maxEntries int, ttl time.Duration) *sharedCache {
+	return &sharedCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		byTable:    make(map[string]map[string]bool),
+		lru:        list.New(),
+	}
+}
+
+// get returns the cached lines of the query with the given key
+func (sc *sharedCache) get(key string, now time.Time) ([]FieldMap, bool) {
+	sc.Lock()
+	defer sc.Unlock()
+	elem, ok := sc.entries[key]
+	if !ok {
+		sc.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*sharedCacheEntry)
+	if sc.ttl > 0 && now.After(entry.expiry) {
+		sc.remove(elem)
+		sc.stats.Evictions++
+		sc.stats.Misses++
+		return nil, false
+	}
+	sc.lru.MoveToFront(elem)
+	sc.stats.Hits++
+	return copyLines(entry.lines), true
+}
+
+// put caches the given lines as the result of the query with the given
+// key, which reads the given tables.
+func (sc *sharedCache) put(key string, tables []string, lines []FieldMap, now time.Time) {
+	sc.Lock()
+	defer sc.Unlock()
+	if elem, ok := sc.entries[key]; ok {
+		sc.remove(elem)
+	}
+	entry := &sharedCacheEntry{
+		key:    key,
+		tables: tables,
+		lines:  copyLines(lines),
+		expiry: now.Add(sc.ttl),
+	}
+	sc.entries[key] = sc.lru.PushFront(entry)
+	for _, table := range tables {
+		if sc.byTable[table] == nil {
+			sc.byTable[table] = make(map[string]bool)
+		}
+		sc.byTable[table][key] = true
+	}
+	for sc.maxEntries > 0 && sc.lru.Len() > sc.maxEntries {
+		sc.remove(sc.lru.Back())
+		sc.stats.Evictions++
+	}
+}
+
+// invalidate removes all the entries that read the given table
+func (sc *sharedCache) invalidate(table string) {
+	sc.Lock()
+	defer sc.Unlock()
+	for key := range sc.byTable[table] {
+		sc.remove(sc.entries[key])
+		sc.stats.Invalidations++
+	}
+}
+
+// remove removes the given element from this cache.
+// sc must be locked when calling this method.
+func (sc *sharedCache) remove(elem *list.Element) {
+	entry := sc.lru.Remove(elem).(*sharedCacheEntry)
+	delete(sc.entries, entry.key)
+	for _, table := range entry.tables {
+		delete(sc.byTable[table], entry.key)
+		if len(sc.byTable[table]) == 0 {
+			delete(sc.byTable, table)
+		}
+	}
+}
+
+// getStats returns the metrics of this cache
+func (sc *sharedCache) getStats() SharedCacheStats {
+	sc.Lock()
+	defer sc.Unlock()
+	res := sc.stats
+	res.Entries = sc.lru.Len()
+	return res
+}
+
+// copyLines returns a copy of the given lines, so that cached
+// lines cannot be modified by their users.
+func copyLines(lines []FieldMap) []FieldMap {
+	res := make([]FieldMap, len(lines))
+	for i, line := range lines {
+		res[i] = make(FieldMap, len(line))
+		for k, v := range line {
+			res[i][k] = v
+		}
+	}
+	return res
+}
+
+// sharedCaches holds the shared caches of the models that have one,
+// and the invalidation sequence number of each table.
+var sharedCaches struct {
+	sync.RWMutex
+	caches    map[*Model]*sharedCache
+	seq       uint64
+	tableSeqs map[string]uint64
+}
+
+// EnableSharedCache enables a cache of the results of the searches of this
+// model that is shared by all environments. It is meant for small models
+// that are read often and seldom modified, such as currencies or countries.
+//
+// At most maxEntries query results are kept, the least recently used being
+// evicted first, and each result is kept for ttl at most. Zero values mean
+// no limit.
+//
+// Entries are invalidated when records of their tables are created, modified
+// or deleted through the ORM. Modifications made with raw SQL queries or by
+// other processes are not detected before the entries expire, so that a ttl
+// should be set in these cases.
+func (m *Model) EnableSharedCache(maxEntries int, ttl time.Duration) {
+	sharedCaches.Lock()
+	defer sharedCaches.Unlock()
+	if sharedCaches.caches == nil {
+		sharedCaches.caches = make(map[*Model]*sharedCache)
+		sharedCaches.tableSeqs = make(map[string]uint64)
+	}
+	sharedCaches.caches[m] = newSharedCache(maxEntries, ttl)
+}
+
+// DisableSharedCache disables the shared cache of this model
+// and drops its entries.
+func (m *Model) DisableSharedCache() {
+	sharedCaches.Lock()
+	defer sharedCaches.Unlock()
+	delete(sharedCaches.caches, m)
+}
+
+// SharedCacheStats returns the metrics of the shared cache of this model.
+// The returned boolean is false if this model has no shared cache.
+func (m *Model) SharedCacheStats() (SharedCacheStats, bool) {
+	sc := m.sharedCache()
+	if sc == nil {
+		return SharedCacheStats{}, false
+	}
+	return sc.getStats(), true
+}
+
+// sharedCache returns the shared cache of this model or nil if it has none
+func (m *Model) sharedCache() *sharedCache {
+	sharedCaches.RLock()
+	defer sharedCaches.RUnlock()
+	return sharedCaches.caches[m]
+}
+
+// sharedCachesEnabled returns true if at least one model has a shared cache
+func sharedCachesEnabled() bool {
+	sharedCaches.RLock()
+	defer sharedCaches.RUnlock()
+	return len(sharedCaches.caches) > 0
+}
+
+// invalidationSeq returns the current invalidation sequence number
+func invalidationSeq() uint64 {
+	sharedCaches.RLock()
+	defer sharedCaches.RUnlock()
+	return sharedCaches.seq
+}
+
+// invalidateSharedCaches removes the entries that read the given tables
+// from all shared caches.
+func invalidateSharedCaches(tables map[string]bool) {
+	if len(tables) == 0 {
+		return
+	}
+	sharedCaches.Lock()
+	sharedCaches.seq++
+	for table := range tables {
+		sharedCaches.tableSeqs[table] = sharedCaches.seq
+	}
+	caches := make([]*sharedCache, 0, len(sharedCaches.caches))
+	for _, sc := range sharedCaches.caches {
+		caches = append(caches, sc)
+	}
+	sharedCaches.Unlock()
+	for _, sc := range caches {
+		for table := range tables {
+			sc.invalidate(table)
+		}
+	}
+}
+
+// invalidatedSince returns true if one of the given tables
+// has been invalidated after the given sequence number.
+func invalidatedSince(tables []string, seq uint64) bool {
+	sharedCaches.RLock()
+	defer sharedCaches.RUnlock()
+	for _, table := range tables {
+		if sharedCaches.tableSeqs[table] > seq {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedCacheTable returns the key of the given quoted table of the given
+// database for the invalidation of shared caches.
+func sharedCacheTable(dbName, table string) string {
+	return dbName + "." + table
+}
+
+// tableModified must be called each time records of the table of the given
+// model are created, modified or deleted in the transaction of this
+// Environment. It invalidates the entries of shared caches that read this
+// table, and prevents this transaction from using shared caches for this
+// table. Entries are invalidated again when the transaction is committed.
+func (env Environment) tableModified(model *Model) {
+	if !sharedCachesEnabled() {
+		return
+	}
+	if env.cr.modifiedTables == nil {
+		env.cr.modifiedTables = make(map[string]bool)
+	}
+	adapter := adapters[db.DriverName()]
+	key := sharedCacheTable(env.dbName, adapter.quoteTableName(model.tableName))
+	env.cr.modifiedTables[key] = true
+	invalidateSharedCaches(map[string]bool{key: true})
+}
+
+// recordsDeleted must be called each time records of the given model are
+// deleted in the transaction of this Environment. Since the database may
+// cascade the deletion to the records that reference them, the tables of the
+// models with a relation to the given model are considered modified too.
+func (env Environment) recordsDeleted(model *Model) {
+	if !sharedCachesEnabled() {
+		return
+	}
+	env.tableModified(model)
+	defer Registry.rLock()()
+	for _, mi := range Registry.registryByName {
+		for _, fi := range mi.fields.registryByJSON {
+			if fi.fieldType.IsFKRelationType() && fi.relatedModel == model {
+				env.tableModified(mi)
+				break
+			}
+		}
+	}
+}
+
+// A sharedCacheQuery is a query whose result can be stored in
+// the shared cache of its model.
+type sharedCacheQuery struct {
+	cache  *sharedCache
+	key    string
+	tables []string
+	seq    uint64
+}
+
+// sharedCacheQuery returns the sharedCacheQuery to load the given fields
+// with the given SQL query, or nil if the model of this RecordCollection
+// has no shared cache, if the current transaction is a snapshot transaction
+// or if the query reads tables that have been modified in it.
+func (rc *RecordCollection) sharedCacheQuery(fields []FieldName, query string, args SQLParams) *sharedCacheQuery {
+	sc := rc.model.sharedCache()
+	if sc == nil || rc.env.cr.snapshot {
+		// Cached results may be more recent than the snapshot
+		return nil
+	}
+	_, allExprs := rc.query.selectData(fields, true)
+	var tables []string
+	seen := make(map[string]bool)
+	for _, expr := range allExprs {
+		for _, tj := range rc.query.generateTableJoins(expr) {
+			table := sharedCacheTable(rc.env.dbName, tj.tableName)
+			if seen[table] {
+				continue
+			}
+			if rc.env.cr.modifiedTables[table] {
+				return nil
+			}
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	return &sharedCacheQuery{
+		cache:  sc,
+		key:    fmt.Sprintf("%s\x00%s\x00%#v", rc.env.dbName, query, args),
+		tables: tables,
+		seq:    rc.env.cr.startSeq,
+	}
+}
+
+// get returns the cached result of this query
+func (scq *sharedCacheQuery) get() ([]FieldMap, bool) {
+	if scq == nil {
+		return nil, false
+	}
+	return scq.cache.get(scq.key, time.Now())
+}
+
+// put stores the given result of this query in the shared cache, unless
+// one of its tables has been modified by another transaction since the
+// current transaction started, in which case it may be outdated.
+func (scq *sharedCacheQuery) put(lines []FieldMap) {
+	if scq == nil || invalidatedSince(scq.tables, scq.seq) {
+		return
+	}
+	scq.cache.put(scq.key, scq.tables, lines, time.Now())
+}
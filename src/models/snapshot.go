@@ -0,0 +1,101 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// A Snapshot describes the view of the database of a transaction
+type Snapshot struct {
+	// Isolation is the isolation level of the transaction,
+	// e.g. 'serializable' or 'repeatable read'
+	Isolation string `db:"isolation"`
+	// ReadOnly is true if the transaction cannot modify the database
+	ReadOnly bool `db:"read_only"`
+	// TxIDs is the snapshot in the 'xmin:xmax:xip_list' format of the
+	// database: transactions before xmin are visible, transactions from
+	// xmax on are not, and xip_list are transactions in progress.
+	TxIDs string `db:"txids"`
+	// StartedAt is the start time of the transaction
+	StartedAt time.Time `db:"started_at"`
+}
+
+// XMin returns the earliest transaction id that was still active
+// when the snapshot was taken.
+func (s Snapshot) XMin() int64 {
+	return s.txID(0)
+}
+
+// XMax returns the first transaction id that was not yet
+// assigned when the snapshot was taken.
+func (s Snapshot) XMax() int64 {
+	return s.txID(1)
+}
+
+// txID returns the transaction id at the given position of TxIDs
+func (s Snapshot) txID(index int) int64 {
+	parts := strings.Split(s.TxIDs, ":")
+	if len(parts) <= index {
+		return 0
+	}
+	res, _ := strconv.ParseInt(parts[index], 10, 64)
+	return res
+}
+
+// Snapshot returns the description of the view of the database of the
+// transaction of this Environment.
+//
+// In a snapshot Environment, the snapshot stays the same for the whole
+// transaction. In a default serializable Environment, it is also the case
+// except that the transaction may fail at commit if it conflicts with others.
+func (env Environment) Snapshot() Snapshot {
+	var res Snapshot
+	env.cr.Get(&res, adapters[db.DriverName()].snapshotQuery())
+	return res
+}
+
+// IsSnapshot returns true if this Environment is a read only snapshot
+// Environment created with ExecuteInSnapshotEnvironment.
+func (env Environment) IsSnapshot() bool {
+	return env.cr.snapshot
+}
+
+// ExportSnapshot exports the snapshot of the transaction of this Environment
+// and returns its identifier. Other transactions can import it to see the
+// exact same data, as long as this transaction is open.
+func (env Environment) ExportSnapshot() string {
+	if env.cr.snapshotID == "" {
+		env.cr.Get(&env.cr.snapshotID, adapters[db.DriverName()].exportSnapshotQuery())
+	}
+	return env.cr.snapshotID
+}
+
+// ExecuteInSnapshotEnvironment executes the given fnct in a new Environment
+// within a read only transaction on the given tenant database. All queries
+// of the transaction see the same consistent snapshot of the database, which
+// makes it suitable for long reads such as reports. Any attempt to modify the
+// database panics.
+//
+// The Environment acts as uid on behalf of realUID, as with
+// ExecuteInDelegatedEnvironment. realUID can be 0 if there is no delegation.
+//
+// Since the transaction does not modify the database, it is rolled back at
+// the end and is never retried.
+func ExecuteInSnapshotEnvironment(tenant string, realUID, uid int64, fnct func(Environment)) (rError error) {
+	env := newTenantEnvironment(tenant, uid, true)
+	env.realUID = realUID
+	defer func() {
+		env.rollback()
+		if r := recover(); r != nil {
+			rError = logging.LogPanicData(r)
+		}
+	}()
+	fnct(env)
+	return nil
+}
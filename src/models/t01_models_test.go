@@ -46,6 +46,8 @@ func TestModelDeclaration(t *testing.T) {
 		activeMI := NewMixinModel("ActiveMixIn")
 		viewModel := NewManualModel("UserView")
 		wizard := NewTransientModel("Wizard")
+		// FieldMigration has its fields removed, renamed and retyped in TestBootStrap
+		migration := NewModel("FieldMigration")
 
 		userModel.NewMethod("PrefixedUser", testPrefixdUser)
 
@@ -155,6 +157,11 @@ func TestModelDeclaration(t *testing.T) {
 				rc.Get(rc.Model().FieldName("Profile")).(*RecordCollection).Set(Registry.MustGet("Profile").FieldName("Age"), age)
 			})
 
+		userModel.NewMethod("InverseLastPost",
+			func(rc *RecordCollection, post RecordSet) {
+				rc.Set(rc.Model().FieldName("LastPost"), post)
+			})
+
 		userModel.NewMethod("UpdateCity",
 			func(rc *RecordCollection, value string) {
 				rc.Get(rc.Model().FieldName("Profile")).(*RecordCollection).Set(Registry.MustGet("Profile").FieldName("City"), value)
@@ -252,6 +259,11 @@ func TestModelDeclaration(t *testing.T) {
 				return "Hello !"
 			})
 
+		addressMI.NewPrivateMethod("CityLine",
+			func(rc *RecordCollection) string {
+				return fmt.Sprintf("%s %s", rc.Get(rc.Model().FieldName("Zip")), rc.Get(rc.Model().FieldName("City")))
+			})
+
 		addressMI.NewMethod("PrintAddress",
 			func(rc *RecordCollection) string {
 				return fmt.Sprintf("%s, %s %s", rc.Get(rc.Model().FieldName("Street")), rc.Get(rc.Model().FieldName("Zip")), rc.Get(rc.Model().FieldName("City")))
@@ -499,6 +511,7 @@ func TestModelDeclaration(t *testing.T) {
 			json:        "email2",
 			fieldType:   fieldtype.Char,
 			structField: reflect.StructField{Type: reflect.TypeOf("")},
+			validators:  []FieldValidator{EmailValidator},
 		})
 		userModel.fields.add(&Field{
 			model:       userModel,
@@ -611,6 +624,14 @@ func TestModelDeclaration(t *testing.T) {
 			fieldType:   fieldtype.Char,
 			structField: reflect.StructField{Type: reflect.TypeOf("")},
 		})
+		profileModel.fields.add(&Field{
+			model:       profileModel,
+			name:        "Phone",
+			json:        "phone",
+			fieldType:   fieldtype.Char,
+			structField: reflect.StructField{Type: reflect.TypeOf("")},
+			validators:  []FieldValidator{PhoneValidator("Country")},
+		})
 		profileModel.fields.add(&Field{
 			model:          profileModel,
 			name:           "UserName",
@@ -959,5 +980,15 @@ func TestModelDeclaration(t *testing.T) {
 			structField: reflect.StructField{Type: reflect.TypeOf(int64(0))},
 			defaultFunc: DefaultValue(0),
 		})
+
+		for _, name := range []string{"Name", "OldName", "Removed", "Amount"} {
+			migration.fields.add(&Field{
+				model:       migration,
+				name:        name,
+				json:        SnakeCaseFieldName(name, fieldtype.Char),
+				fieldType:   fieldtype.Char,
+				structField: reflect.StructField{Type: reflect.TypeOf("")},
+			})
+		}
 	})
 }
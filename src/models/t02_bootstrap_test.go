@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/security"
 	"github.com/hexya-erp/hexya/src/models/types"
 	"github.com/hexya-erp/hexya/src/models/types/dates"
 	"github.com/hexya-erp/hexya/src/tools/nbutils"
@@ -44,6 +46,8 @@ func (f TestFieldMap) Underlying() FieldMap {
 
 func UnBootStrap() {
 	Registry.bootstrapped = false
+	Registry.metadataBootstrapped = false
+	atomic.StoreUint32(&Registry.frozen, 0)
 	for _, mi := range Registry.registryByName {
 		if mi.options&ContextsModel > 0 {
 			delete(Registry.registryByName, mi.name)
@@ -62,13 +66,27 @@ func UnBootStrap() {
 			}
 		}
 	}
-	for seqName, seq := range Registry.sequences {
+	for seqName, seq := range Registry.getSequences() {
 		if strings.HasSuffix(seq.JSON, "_manseq") {
-			delete(Registry.sequences, seqName)
+			Registry.removeSequence(seqName)
 		}
 	}
 }
 
+// integerFieldDef is the definition of an Integer field used to test Model.ChangeFieldType
+type integerFieldDef struct{}
+
+// DeclareField creates an Integer field with the given name in the given FieldsCollection
+func (integerFieldDef) DeclareField(fc *FieldsCollection, name string) *Field {
+	return &Field{
+		model:       fc.model,
+		name:        name,
+		json:        SnakeCaseFieldName(name, fieldtype.Integer),
+		fieldType:   fieldtype.Integer,
+		structField: reflect.StructField{Name: name, Type: reflect.TypeOf(int64(0))},
+	}
+}
+
 func checkUpdates(f *Field, property string, value interface{}) {
 	So(len(f.updates), ShouldBeGreaterThan, 0)
 	So(f.updates[len(f.updates)-1], ShouldContainKey, property)
@@ -202,6 +220,64 @@ func TestFieldModification(t *testing.T) {
 	})
 }
 
+func TestFieldRemovalsAndRenames(t *testing.T) {
+	Convey("Testing field removals, renames and conflicts", t, func() {
+		userModel := Registry.MustGet("User")
+		nameField := userModel.Fields().MustGet("Name")
+		updates := nameField.updates
+		Convey("RemoveField and RenameField should add update entries", func() {
+			userModel.RemoveField("Name")
+			checkUpdates(nameField, "remove", true)
+			userModel.RenameField("Name", "FullName")
+			checkUpdates(nameField, "rename", "FullName")
+			So(nameField.updates[len(nameField.updates)-1], ShouldContainKey, updateModuleKey)
+			So(func() { userModel.RemoveField("ID") }, ShouldPanic)
+			So(func() { userModel.RenameField("NonExistentField", "Other") }, ShouldPanic)
+			userModel.ChangeFieldType("Name", integerFieldDef{})
+			So(nameField.updates[len(nameField.updates)-1], ShouldContainKey, "type")
+			So(nameField.updates[len(nameField.updates)-1]["type"].(*Field).fieldType, ShouldEqual, fieldtype.Integer)
+			So(func() { userModel.ChangeFieldType("ID", integerFieldDef{}) }, ShouldPanic)
+			nameField.updates = updates
+		})
+		Convey("Fields using removed or renamed fields should panic", func() {
+			removedFields := Registry.removedFields
+			Registry.removedFields = map[*Model]map[string]string{userModel: {"Nickname": "removed"}}
+			fi := &Field{model: userModel, name: "Test"}
+			So(func() { checkPathNotRemoved(fi, "depends", userModel, "Nickname") }, ShouldPanic)
+			So(func() { checkPathNotRemoved(fi, "related", userModel, "Name") }, ShouldNotPanic)
+			So(func() { checkPathNotRemoved(fi, "related", userModel, "Unknown.Nickname") }, ShouldNotPanic)
+			Registry.removedFields = removedFields
+		})
+		Convey("RemoveSelection should add an update entry", func() {
+			visibilityField := Registry.MustGet("Post").Fields().MustGet("Visibility")
+			visibilityUpdates := visibilityField.updates
+			visibilityField.RemoveSelection("logged_in")
+			lastUpdateShouldResemble(visibilityField, "selection_remove", []string{"logged_in"})
+			visibilityField.updates = visibilityUpdates
+		})
+		Convey("Properties overridden by several modules should be reported", func() {
+			fi := &Field{model: userModel, name: "Test", module: "base", updates: []map[string]interface{}{
+				{"description": "Own", updateModuleKey: "base"},
+				{"description": "Sale", updateModuleKey: "sale"},
+				{"help": "Help", updateModuleKey: "sale"},
+				{"selection_add": types.Selection{}, updateModuleKey: "crm"},
+				{"description": "Purchase", updateModuleKey: "purchase"},
+				{"selection_add": types.Selection{}, updateModuleKey: "stock"},
+			}}
+			conflicts := Registry.fieldConflicts
+			detectUpdateConflicts(fi)
+			So(Registry.FieldConflicts(), ShouldHaveLength, len(conflicts)+1)
+			So(Registry.FieldConflicts()[len(conflicts)], ShouldResemble, FieldConflict{
+				Model:    "User",
+				Field:    "Test",
+				Property: "description",
+				Modules:  []string{"sale", "purchase"},
+			})
+			Registry.fieldConflicts = conflicts
+		})
+	})
+}
+
 func TestMiscellaneous(t *testing.T) {
 	Convey("Check that Field instances are FieldNamers", t, func() {
 		So(Registry.MustGet("User").Fields().MustGet("Name").JSON(), ShouldEqual, "name")
@@ -329,9 +405,22 @@ func TestIllegalMethods(t *testing.T) {
 		ageField.SetInverse(userModel.Methods().MustGet("WrongInverseSetAge"))
 		processUpdates()
 		So(checkComputeMethodsSignature, ShouldPanic)
+		ageField.SetInverse(userModel.Methods().MustGet("UpdateCity"))
+		processUpdates()
+		So(checkComputeMethodsSignature, ShouldPanic)
 		ageField.SetInverse(userModel.Methods().MustGet("InverseSetAge"))
 		processUpdates()
 
+		lastPostField := userModel.Fields().MustGet("LastPost")
+		lastPostField.SetInverse(userModel.Methods().MustGet("UpdateCity"))
+		processUpdates()
+		So(checkComputeMethodsSignature, ShouldPanic)
+		lastPostField.SetInverse(userModel.Methods().MustGet("InverseLastPost"))
+		processUpdates()
+		So(checkComputeMethodsSignature, ShouldNotPanic)
+		lastPostField.SetInverse(nil)
+		processUpdates()
+
 		dnField := userModel.Fields().MustGet("DecoratedName")
 		dnField.SetCompute(userModel.Methods().MustGet("TwoReturnValues"))
 		processUpdates()
@@ -371,6 +460,12 @@ func TestBootStrap(t *testing.T) {
 		Convey("Dummy table should exist", func() {
 			So(TestAdapter.tables(), ShouldContainKey, "shouldbedeleted")
 		})
+		Convey("Metadata bootstrap should not panic", func() {
+			BootStrapMetadata()
+			So(MetadataBootStrapped(), ShouldBeTrue)
+			So(BootStrapped(), ShouldBeFalse)
+			So(BootStrapMetadata, ShouldPanic)
+		})
 		Convey("Bootstrap should not panic", func() {
 			BootStrap()
 			SyncDatabase()
@@ -384,6 +479,26 @@ func TestBootStrap(t *testing.T) {
 				Registry.MustGet("User").NewMethod("NewMethod", func(rc *RecordCollection) {})
 			}, ShouldPanic)
 		})
+		Convey("Modifying models after bootstrap should panic", func() {
+			So(Registry.isFrozen(), ShouldBeTrue)
+			So(func() { NewModel("NewModel") }, ShouldPanic)
+			So(func() { Registry.MustGet("User").AddFields(map[string]FieldDefinition{}) }, ShouldPanic)
+			So(func() { Registry.MustGet("User").InheritModel(Registry.MustGet("ModelMixin")) }, ShouldPanic)
+			So(func() { Registry.MustGet("User").AddSQLConstraint("new", "unique(name)", "") }, ShouldPanic)
+		})
+		Convey("Modifying methods after bootstrap should panic", func() {
+			updateCity := Registry.MustGet("User").methods.MustGet("UpdateCity")
+			So(func() { Registry.MustGet("User").NewMethod("UpdateCity", func(rc *RecordCollection, value string) {}) }, ShouldPanic)
+			So(func() { updateCity.Extend(func(rc *RecordCollection, value string) {}) }, ShouldPanic)
+			So(func() { updateCity.AllowGroup(security.GroupEveryone) }, ShouldPanic)
+			So(func() { updateCity.RevokeGroup(security.GroupEveryone) }, ShouldPanic)
+			So(func() { updateCity.RequireGroups(security.GroupAdmin) }, ShouldPanic)
+			restore := UnfreezeRegistry()
+			So(func() { updateCity.RequireGroups() }, ShouldNotPanic)
+			restore()
+			So(Registry.isFrozen(), ShouldBeTrue)
+			So(Registry.All(), ShouldHaveLength, len(Registry.registryByName))
+		})
 		Convey("Creating SQL view should run fine", func() {
 			So(func() {
 				dbExecuteNoTx(`DROP VIEW IF EXISTS user_view;
@@ -445,11 +560,39 @@ func TestBootStrap(t *testing.T) {
 			})
 			textField := Registry.MustGet("Comment").Fields().MustGet("Text")
 			textField.SetFieldType(fieldtype.Text)
+			migration := Registry.MustGet("FieldMigration")
+			dbExecuteNoTx(`INSERT INTO field_migration (name, old_name, removed, amount) VALUES ('Migrated', 'Kept', 'Lost', '42')`)
+			migration.RenameField("OldName", "NewName")
+			migration.RemoveField("Removed")
+			migration.ChangeFieldType("Amount", integerFieldDef{})
 			So(BootStrap, ShouldNotPanic)
 			So(contentField.required, ShouldBeFalse)
 			So(profileField.required, ShouldBeFalse)
 			So(numsField.index, ShouldBeFalse)
+			So(migration.fields.registryByName, ShouldContainKey, "NewName")
+			So(migration.fields.registryByName, ShouldNotContainKey, "OldName")
+			So(migration.fields.registryByName, ShouldNotContainKey, "Removed")
+			So(migration.fields.MustGet("Amount").fieldType, ShouldEqual, fieldtype.Integer)
+			So(Registry.FieldRenames(), ShouldContain, FieldRename{
+				Model:     "FieldMigration",
+				OldName:   "OldName",
+				NewName:   "NewName",
+				OldColumn: "old_name",
+				NewColumn: "new_name",
+			})
 			So(SyncDatabase, ShouldNotPanic)
+			columns := TestAdapter.columns("field_migration")
+			So(columns, ShouldContainKey, "new_name")
+			So(columns, ShouldNotContainKey, "old_name")
+			So(columns, ShouldNotContainKey, "removed")
+			So(columns["amount"].DataType, ShouldEqual, "integer")
+			var migrated struct {
+				NewName string `db:"new_name"`
+				Amount  int64  `db:"amount"`
+			}
+			dbGetNoTx(&migrated, `SELECT new_name, amount FROM field_migration WHERE name = 'Migrated'`)
+			So(migrated.NewName, ShouldEqual, "Kept")
+			So(migrated.Amount, ShouldEqual, 42)
 		})
 	})
 
@@ -465,6 +608,32 @@ func TestBootStrap(t *testing.T) {
 		So(genderField.selection, ShouldContainKey, "f")
 	})
 
+	Convey("Unreachable dependencies should be reported", t, func() {
+		ageField := Registry.MustGet("User").fields.MustGet("Age")
+		profileAgeField := Registry.MustGet("Profile").fields.MustGet("Age")
+		depends := ageField.depends
+		nDeps := len(profileAgeField.dependencies)
+		userAgeDepends := func() []string {
+			var res []string
+			for _, ud := range Registry.UnreachableDependencies() {
+				if ud.Model == "User" && ud.Field == "Age" {
+					res = append(res, ud.Depends)
+				}
+			}
+			return res
+		}
+		ageField.depends = append([]string{"Unknown", "Name.Age"}, depends...)
+		So(processDepends, ShouldNotPanic)
+		So(profileAgeField.dependencies, ShouldHaveLength, nDeps)
+		So(userAgeDepends(), ShouldHaveLength, 2)
+		So(userAgeDepends(), ShouldContain, "Unknown")
+		So(userAgeDepends(), ShouldContain, "Name.Age")
+		ageField.depends = depends
+		processDepends()
+		So(profileAgeField.dependencies, ShouldHaveLength, nDeps)
+		So(userAgeDepends(), ShouldBeEmpty)
+	})
+
 	Convey("Truncating all tables...", t, func() {
 		for tn, mi := range Registry.registryByTableName {
 			if mi.IsMixin() || mi.IsManual() {
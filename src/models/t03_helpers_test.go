@@ -5,8 +5,11 @@ package models
 
 import (
 	"encoding/json"
+	"math/rand"
 	"testing"
+	"time"
 
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
 	"github.com/hexya-erp/hexya/src/models/security"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -254,5 +257,55 @@ func TestTypes(t *testing.T) {
 			So(jsons[0], ShouldEqual, "name")
 			So(jsons[1], ShouldEqual, "user_id")
 		})
+		Convey("Testing field encryption", func() {
+			oldKey, newKey := make([]byte, 32), make([]byte, 32)
+			newKey[0] = 1
+			So(SetEncryptionKeys([]byte("too short")), ShouldNotBeNil)
+			So(SetEncryptionKeys(oldKey), ShouldBeNil)
+			encOld, err := encrypt("1234567890", "user.nid")
+			So(err, ShouldBeNil)
+			So(isEncrypted(encOld), ShouldBeTrue)
+			So(encOld, ShouldNotContainSubstring, "1234567890")
+			So(SetEncryptionKeys(newKey, oldKey), ShouldBeNil)
+			encNew, err := encrypt("1234567890", "user.nid")
+			So(err, ShouldBeNil)
+			So(encNew, ShouldNotEqual, encOld)
+			plain, err := decrypt(encOld, "user.nid")
+			So(err, ShouldBeNil)
+			So(plain, ShouldEqual, "1234567890")
+			plain, err = decrypt(encNew, "user.nid")
+			So(err, ShouldBeNil)
+			So(plain, ShouldEqual, "1234567890")
+			_, err = decrypt(encNew, "user.name")
+			So(err, ShouldNotBeNil)
+			plain, err = decrypt("not encrypted", "user.nid")
+			So(err, ShouldBeNil)
+			So(plain, ShouldEqual, "not encrypted")
+			So(SetEncryptionKeys(newKey), ShouldBeNil)
+			_, err = decrypt(encOld, "user.nid")
+			So(err, ShouldNotBeNil)
+			So(SetEncryptionKeys(), ShouldBeNil)
+			_, err = encrypt("1234567890", "user.nid")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Testing scrambled values", func() {
+			model := &Model{name: "User", tableName: "user"}
+			email := &Field{model: model, json: "email", description: "Email", fieldType: fieldtype.Char}
+			mobile := &Field{model: model, json: "mobile_phone", description: "Mobile", fieldType: fieldtype.Char}
+			name := &Field{model: model, json: "name", description: "Name", fieldType: fieldtype.Char, size: 6}
+			bio := &Field{model: model, json: "bio", description: "Biography", fieldType: fieldtype.HTML}
+			avatar := &Field{model: model, json: "avatar", description: "Avatar", fieldType: fieldtype.Binary}
+			birthday := &Field{model: model, json: "birthday", description: "Birthday", fieldType: fieldtype.DateTime}
+			rnd := rand.New(rand.NewSource(1))
+			So(scrambledValue(email, 12, rnd), ShouldEqual, "user12@example.com")
+			So(scrambledValue(mobile, 12, rnd), ShouldEqual, "+1 555 0000012")
+			So(scrambledValue(name, 12, rnd), ShouldEqual, "Name 1")
+			So(scrambledValue(bio, 12, rnd), ShouldEqual, "<p>Biography 12</p>")
+			So(scrambledValue(avatar, 12, rnd), ShouldBeNil)
+			date := scrambledValue(birthday, 12, rnd).(time.Time)
+			So(date.Before(scrambledDatesStart), ShouldBeFalse)
+			So(date.Before(scrambledDatesStart.AddDate(50, 0, 0)), ShouldBeTrue)
+			So(scrambledValue(birthday, 12, rand.New(rand.NewSource(1))), ShouldEqual, date)
+		})
 	})
 }
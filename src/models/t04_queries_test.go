@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/hexya-erp/hexya/src/models/operator"
 	"github.com/hexya-erp/hexya/src/models/security"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -28,6 +29,7 @@ var (
 	age                      = fieldName{name: "Age", json: "age"}
 	email                    = fieldName{name: "Email", json: "email"}
 	email2                   = fieldName{name: "Email2", json: "email2"}
+	phone                    = fieldName{name: "Phone", json: "phone"}
 	bestPost                 = fieldName{name: "BestPost", json: "best_post_id"}
 	title                    = fieldName{name: "Title", json: "title"}
 	isStaff                  = fieldName{name: "IsStaff", json: "is_staff"}
@@ -185,6 +187,42 @@ func TestConditions(t *testing.T) {
 					sql, _, _ := rs.query.selectQuery(fields)
 					So(sql, ShouldEqual, `SELECT * FROM (SELECT DISTINCT ON ("user".id) "user".name AS name, "user".email AS email, "user".id AS id FROM "user" "user"  WHERE "user".email ILIKE ? ORDER BY "user".id ) foo ORDER BY email, id `)
 				})
+				Convey("Testing query with ORDER BY on a code ordered field", func() {
+					emailField := Registry.MustGet("User").fields.MustGet("Email")
+					emailField.codeOrder = true
+					defer func() { emailField.codeOrder = false }()
+					rs = env.Pool("User").Search(rs.Model().Field(email).IContains("jane.smith@example.com")).Call("OrderBy", []string{"Email", "ID"}).(RecordSet).Collection()
+					fields = []FieldName{Name}
+					sql, _, substs := rs.query.selectQuery(fields)
+					So(sql, ShouldEqual, `SELECT * FROM (SELECT DISTINCT ON ("user".id) "user".name AS name, "user".email AS email, "user".id AS id, "user".email_sort_key AS sort_key_0 FROM "user" "user"  WHERE "user".email ILIKE ? ORDER BY "user".id ) foo ORDER BY sort_key_0, id `)
+					So(substs, ShouldContainKey, "sort_key_0")
+					So(substs["sort_key_0"], ShouldBeEmpty)
+				})
+				Convey("Testing query with an SQL field", func() {
+					emailField := Registry.MustGet("User").fields.MustGet("Email")
+					emailField.sql = "lower({table}.name) || '@example.com'"
+					defer func() { emailField.sql = "" }()
+					rs = env.Pool("User").Search(rs.Model().Field(email).IContains("jane")).Call("OrderBy", []string{"Email", "ID"}).(RecordSet).Collection()
+					fields = []FieldName{Name, email}
+					sql, _, _ := rs.query.selectQuery(fields)
+					So(sql, ShouldEqual, `SELECT * FROM (SELECT DISTINCT ON ("user".id) "user".name AS name, (lower("user".name) || '@example.com') AS email, "user".id AS id FROM "user" "user"  WHERE (lower("user".name) || '@example.com') ILIKE ? ORDER BY "user".id ) foo ORDER BY email, id `)
+				})
+				Convey("Testing query on a computed field with a search function", func() {
+					dnField := Registry.MustGet("User").fields.MustGet("DecoratedName")
+					dnField.searchFunc = func(env Environment, op operator.Operator, value interface{}) Conditioner {
+						return env.Pool("User").Model().Field(Name).AddOperator(op, value).
+							Or().Field(email).AddOperator(op, value)
+					}
+					defer func() { dnField.searchFunc = nil }()
+					rs = env.Pool("User").Search(rs.Model().Field(decoratedName).IContains("Jane").
+						And().Field(isStaff).Equals(true))
+					addSearchFuncsToCondition(env, rs.model, rs.query.cond)
+					sql, args := rs.query.sqlWhereClause(true)
+					So(sql, ShouldEqual, `WHERE ("user".name ILIKE ? OR "user".email ILIKE ?) AND "user".is_staff = ?`)
+					So(args, ShouldHaveLength, 3)
+					So(args[0], ShouldEqual, "%Jane%")
+					So(args[2], ShouldEqual, true)
+				})
 				Convey("Testing complex conditions", func() {
 					rs = env.Pool("User").Search(rs.Model().Field(profileAge).GreaterOrEqual(12).
 						AndNot().Field(Name).IContains("Jane").
@@ -396,3 +434,39 @@ func TestConditionSerialization(t *testing.T) {
 		})
 	})
 }
+
+func TestParseDomain(t *testing.T) {
+	Convey("Testing domain parsing", t, func() {
+		userModel := Registry.MustGet("User")
+		Convey("Parsing a domain with prefix operators and paths", func() {
+			cond, err := ParseDomain(userModel, []interface{}{
+				"|", []interface{}{"Name", "ilike", "John"},
+				"&", []interface{}{"profile_id.Age", ">", 18}, []interface{}{"IsStaff", "=", true},
+			})
+			So(err, ShouldBeNil)
+			So(fmt.Sprint(cond.Serialize()), ShouldEqual, "[| & [profile_id.age > 18] [is_staff = true] [name ilike John]]")
+		})
+		Convey("Successive terms should be ANDed", func() {
+			cond, err := ParseDomain(userModel, []interface{}{
+				[]interface{}{"name", "=", "John"}, []interface{}{"is_staff", "=", false},
+			})
+			So(err, ShouldBeNil)
+			So(fmt.Sprint(cond.Serialize()), ShouldEqual, "[& [name = John] [is_staff = false]]")
+			cond, err = ParseDomain(userModel, []interface{}{})
+			So(err, ShouldBeNil)
+			So(cond.IsEmpty(), ShouldBeTrue)
+		})
+		Convey("Invalid domains should be rejected", func() {
+			_, err := ParseDomain(userModel, []interface{}{[]interface{}{"Unknown", "=", 1}})
+			So(err, ShouldNotBeNil)
+			_, err = ParseDomain(userModel, []interface{}{[]interface{}{"Name", "~", "John"}})
+			So(err, ShouldNotBeNil)
+			_, err = ParseDomain(userModel, []interface{}{"|", []interface{}{"Name", "=", "John"}})
+			So(err, ShouldNotBeNil)
+			_, err = ParseDomain(userModel, []interface{}{[]interface{}{"Name.Age", "=", 1}})
+			So(err, ShouldNotBeNil)
+			_, err = ParseDomain(userModel, []interface{}{[]interface{}{"Name", "="}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -15,13 +15,17 @@
 package models
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/hexya-erp/hexya/src/models/security"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func TestCreateRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer UnfreezeRegistry()()
 	Convey("Test record creation", t, func() {
 		So(ExecuteInNewEnvironment(security.SuperUserID, func(env Environment) {
 			userModel := Registry.MustGet("User")
@@ -163,6 +167,25 @@ func TestCreateRecordSet(t *testing.T) {
 				So(func() { env.Pool("User").Call("Create", user1Data).(RecordSet).Collection() }, ShouldNotPanic)
 				So(func() { env.Pool("User").Call("Create", user1Data).(RecordSet).Collection() }, ShouldPanic)
 			})
+			Convey("Checking that unique violations are translated with the field label", func() {
+				user1Data := NewModelData(userModel, FieldMap{
+					"Name": "Rob Smith",
+				})
+				env.Pool("User").Call("Create", user1Data)
+				var errs ValidationErrors
+				func() {
+					defer func() {
+						errs, _ = recover().(ValidationErrors)
+					}()
+					env.Pool("User").Call("Create", user1Data)
+				}()
+				So(errs, ShouldHaveLength, 1)
+				So(errs[0].Model, ShouldEqual, "User")
+				So(errs[0].Field, ShouldEqual, "Name")
+				So(errs[0].Message, ShouldEqual, "'Name' must be unique: another User record has the same value")
+				So(errs.Error(), ShouldEqual, errs[0].Message)
+				So(errs[0].Unwrap(), ShouldNotBeNil)
+			})
 			Convey("Checking that we can create as many users with a NULL name", func() {
 				user2Data := NewModelData(userModel, FieldMap{
 					"Email": "user2@example.com",
@@ -183,7 +206,7 @@ func TestCreateRecordSet(t *testing.T) {
 			env.Pool("User").Call("Create", userRobData)
 		})
 		So(err, ShouldNotBeNil)
-		So(err.Error(), ShouldStartWith, "pq: Premium users must have positive nums")
+		So(err.Error(), ShouldStartWith, "Premium users must have positive nums")
 	})
 	group1 := security.Registry.NewGroup("group1", "Group 1")
 	Convey("Testing access control list on creation (create only)", t, func() {
@@ -263,6 +286,8 @@ func TestCreateRecordSet(t *testing.T) {
 }
 
 func TestSearchRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer UnfreezeRegistry()()
 	Convey("Testing search through RecordSets", t, func() {
 		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
 			Convey("Searching User Jane", func() {
@@ -596,6 +621,8 @@ func TestGroupedQueries(t *testing.T) {
 }
 
 func TestUpdateRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer UnfreezeRegistry()()
 	Convey("Testing updates through RecordSets", t, func() {
 		So(ExecuteInNewEnvironment(security.SuperUserID, func(env Environment) {
 			userModel := Registry.MustGet("User")
@@ -752,7 +779,7 @@ func TestUpdateRecordSet(t *testing.T) {
 			userModel := Registry.MustGet("User")
 			userWill := env.Pool("User").Search(env.Pool("User").Model().Field(email).Equals("will.smith@example.com"))
 			userWill.Call("Write", NewModelData(userModel).Set(nums, 0).Set(isPremium, true))
-		}).Error(), ShouldStartWith, "pq: Premium users must have positive nums")
+		}).Error(), ShouldStartWith, "Premium users must have positive nums")
 	})
 
 	group1 := security.Registry.NewGroup("group1", "Group 1")
@@ -799,6 +826,21 @@ func TestUpdateRecordSet(t *testing.T) {
 				So(jane.Len(), ShouldEqual, 1)
 				So(func() { jane.Call("UpdateCity", "London") }, ShouldNotPanic)
 			})
+			Convey("Checking that user 2 cannot run UpdateCity without the required groups", func() {
+				groupRequired := security.Registry.NewGroup("group_required", "Required Group")
+				userModel.methods.MustGet("Load").AllowGroup(group1)
+				profileModel.methods.MustGet("Write").AllowGroup(group1, userModel.methods.MustGet("UpdateCity"))
+				updateCity := userModel.methods.MustGet("UpdateCity").RequireGroups(groupRequired)
+				So(updateCity.RequiredGroups(), ShouldResemble, []*security.Group{groupRequired})
+				jane := env.Pool("User").Search(env.Pool("User").Model().Field(Name).Equals("Jane A. Smith"))
+				So(func() { jane.Call("UpdateCity", "London") }, ShouldPanic)
+				So(func() { jane.Sudo().Call("UpdateCity", "London") }, ShouldNotPanic)
+				security.Registry.AddMembership(2, groupRequired)
+				So(func() { jane.Call("UpdateCity", "London") }, ShouldNotPanic)
+				security.Registry.RemoveMembership(2, groupRequired)
+				delete(updateCity.requiredGroups, groupRequired)
+				security.Registry.UnregisterGroup(groupRequired)
+			})
 			Convey("Checking record rules", func() {
 				userJane := env.Pool("User").SearchAll()
 				So(userJane.Len(), ShouldEqual, 3)
@@ -836,7 +878,120 @@ func TestUpdateRecordSet(t *testing.T) {
 	security.Registry.UnregisterGroup(group1)
 }
 
+func TestToggleActive(t *testing.T) {
+	Convey("Testing archiving and unarchiving records", t, func() {
+		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+			users := env.Pool("User").SearchAll()
+			So(users.Len(), ShouldBeGreaterThan, 1)
+			userJane := env.Pool("User").Search(env.Pool("User").Model().Field(email).Equals("jane.smith@example.com"))
+			userJane.Set(active, false)
+			before := make(map[int64]bool)
+			for _, user := range users.Records() {
+				before[user.Ids()[0]] = user.Get(active).(bool)
+			}
+			So(users.Call("ToggleActive"), ShouldBeTrue)
+			So(userJane.Get(active), ShouldBeTrue)
+			for _, user := range users.Records() {
+				So(user.Get(active), ShouldEqual, !before[user.Ids()[0]])
+			}
+			So(users.Call("ToggleActive"), ShouldBeTrue)
+			So(userJane.Get(active), ShouldBeFalse)
+		}), ShouldBeNil)
+	})
+}
+
+func TestMergeRecords(t *testing.T) {
+	Convey("Testing records merging", t, func() {
+		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+			tagModel := Registry.MustGet("Tag")
+			mergedTags := env.Pool("Tag").Search(tagModel.Field(Name).In([]string{"Trending", "Books"}))
+			So(mergedTags.Len(), ShouldEqual, 2)
+			trending := env.Pool("Tag").Search(tagModel.Field(Name).Equals("Trending"))
+			books := env.Pool("Tag").Search(tagModel.Field(Name).Equals("Books"))
+			taggedPosts := env.Pool("Post").Search(env.Pool("Post").Model().Field(tags).In(mergedTags.Ids()))
+			So(taggedPosts.IsEmpty(), ShouldBeFalse)
+			Convey("A dry run should report references without changing anything", func() {
+				report := mergedTags.Call("Merge", trending, true).(*MergeReport)
+				So(report.DryRun, ShouldBeTrue)
+				So(report.Target, ShouldEqual, trending.Ids()[0])
+				So(report.Merged, ShouldResemble, books.Ids())
+				So(report.References, ShouldNotBeEmpty)
+				So(env.Pool("Tag").Search(tagModel.Field(Name).Equals("Books")).IsEmpty(), ShouldBeFalse)
+			})
+			Convey("Merging should repoint links and delete the duplicates", func() {
+				report := mergedTags.Call("Merge", trending, false).(*MergeReport)
+				So(report.DryRun, ShouldBeFalse)
+				So(env.Pool("Tag").Search(tagModel.Field(Name).Equals("Books")).IsEmpty(), ShouldBeTrue)
+				for _, post := range taggedPosts.Records() {
+					So(post.Get(tags).(RecordSet).Collection().Ids(), ShouldContain, trending.Ids()[0])
+				}
+			})
+			Convey("The target must be a single record of the model", func() {
+				So(func() { mergedTags.Call("Merge", mergedTags, true) }, ShouldPanic)
+			})
+		}), ShouldBeNil)
+	})
+}
+
+func TestFindDuplicates(t *testing.T) {
+	Convey("Testing duplicates search", t, func() {
+		Convey("Pairs of duplicates should be grouped transitively", func() {
+			So(duplicateGroups(nil), ShouldBeEmpty)
+			So(duplicateGroups([][2]int64{{4, 7}, {1, 3}, {7, 9}, {3, 12}}), ShouldResemble,
+				[][]int64{{1, 3, 12}, {4, 7, 9}})
+		})
+		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+			users := env.Pool("User").SearchAll()
+			Convey("Only char and text fields can be compared", func() {
+				So(func() { users.Call("FindDuplicates", FieldNames{age}, 0.5) }, ShouldPanic)
+				So(func() { users.Call("FindDuplicates", FieldNames{}, 0.5) }, ShouldPanic)
+			})
+		}), ShouldBeNil)
+	})
+}
+
+func TestFieldValidators(t *testing.T) {
+	Convey("Testing field validators", t, func() {
+		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+			profileModel := Registry.MustGet("Profile")
+			Convey("Phone numbers should be normalized with the country of the record", func() {
+				prof := env.Pool("Profile").Call("Create", NewModelData(profileModel).
+					Set(country, "FR").
+					Set(phone, "01 23 45 67 89")).(RecordSet).Collection()
+				So(prof.Get(phone), ShouldEqual, "+33123456789")
+				prof.Set(phone, "06.98.76.54.32")
+				So(prof.Get(phone), ShouldEqual, "+33698765432")
+				prof.Call("Write", NewModelData(profileModel).Set(country, "US").Set(phone, "(415) 555-0100"))
+				So(prof.Get(phone), ShouldEqual, "+14155550100")
+			})
+			Convey("Invalid values should be rejected with all the offending values", func() {
+				prof := env.Pool("Profile").Call("Create", NewModelData(profileModel)).(RecordSet).Collection()
+				var errs ValidationErrors
+				func() {
+					defer func() { errs, _ = recover().(ValidationErrors) }()
+					prof.Set(phone, "01 23 45 67 89")
+				}()
+				So(errs, ShouldHaveLength, 1)
+				So(errs[0].Field, ShouldEqual, "Phone")
+				So(errs[0].Value, ShouldEqual, "01 23 45 67 89")
+				So(prof.Get(phone), ShouldBeEmpty)
+				user := env.Pool("User").Call("Create", NewModelData(env.Pool("User").Model()).
+					Set(Name, "Validated User").
+					Set(email2, "  Valid.User@Example.COM")).(RecordSet).Collection()
+				So(user.Get(email2), ShouldEqual, "Valid.User@example.com")
+				So(func() { user.Set(email2, "not an email") }, ShouldPanic)
+				So(ValidationErrors{
+					{Model: "User", Field: "Email2", Value: "a@b", Err: errors.New("bad")},
+					{Model: "Profile", Field: "Phone", Value: "x", Err: errors.New("worse")},
+				}.Error(), ShouldEqual, "User.Email2: invalid value \"a@b\": bad\nProfile.Phone: invalid value \"x\": worse")
+			})
+		}), ShouldBeNil)
+	})
+}
+
 func TestDeleteRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer UnfreezeRegistry()()
 	Convey("Checking unlink method", t, func() {
 		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
 			Convey("Deleting user John: number of deleted record should be 1", func() {
@@ -935,3 +1090,72 @@ func TestDeleteRecordSet(t *testing.T) {
 	})
 	security.Registry.UnregisterGroup(group1)
 }
+
+func TestSharedCache(t *testing.T) {
+	Convey("Testing shared caches", t, func() {
+		Convey("Least recently used entries should be evicted", func() {
+			sc := newSharedCache(2, time.Minute)
+			now := time.Now()
+			sc.put("q1", []string{"t1"}, []FieldMap{{"id": int64(1)}}, now)
+			sc.put("q2", []string{"t1", "t2"}, []FieldMap{{"id": int64(2)}}, now)
+			_, ok := sc.get("q1", now)
+			So(ok, ShouldBeTrue)
+			sc.put("q3", []string{"t2"}, []FieldMap{{"id": int64(3)}}, now)
+			_, ok = sc.get("q2", now)
+			So(ok, ShouldBeFalse)
+			lines, ok := sc.get("q1", now)
+			So(ok, ShouldBeTrue)
+			So(lines, ShouldResemble, []FieldMap{{"id": int64(1)}})
+			lines[0]["id"] = int64(10)
+			lines, _ = sc.get("q1", now)
+			So(lines[0]["id"], ShouldEqual, 1)
+			So(sc.getStats(), ShouldResemble, SharedCacheStats{Entries: 2, Hits: 3, Misses: 1, Evictions: 1})
+		})
+		Convey("Expired entries should not be returned", func() {
+			sc := newSharedCache(0, time.Minute)
+			now := time.Now()
+			sc.put("q1", []string{"t1"}, []FieldMap{{"id": int64(1)}}, now)
+			_, ok := sc.get("q1", now.Add(2*time.Minute))
+			So(ok, ShouldBeFalse)
+			So(sc.getStats().Entries, ShouldEqual, 0)
+		})
+		Convey("Entries should be invalidated by table", func() {
+			sc := newSharedCache(0, 0)
+			now := time.Now()
+			sc.put("q1", []string{"t1"}, []FieldMap{{"id": int64(1)}}, now)
+			sc.put("q2", []string{"t1", "t2"}, []FieldMap{{"id": int64(2)}}, now)
+			sc.put("q3", []string{"t3"}, []FieldMap{{"id": int64(3)}}, now)
+			sc.invalidate("t1")
+			So(sc.getStats().Entries, ShouldEqual, 1)
+			So(sc.getStats().Invalidations, ShouldEqual, 2)
+			_, ok := sc.get("q3", now)
+			So(ok, ShouldBeTrue)
+		})
+		Convey("Searches should be served from the shared cache until records are modified", func() {
+			tagModel := Registry.MustGet("Tag")
+			tagModel.EnableSharedCache(100, time.Minute)
+			defer tagModel.DisableSharedCache()
+			readTags := func() {
+				So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+					tags := env.Pool("Tag").Search(tagModel.Field(Name).Equals("Books"))
+					So(tags.Get(Name), ShouldEqual, "Books")
+				}), ShouldBeNil)
+			}
+			readTags()
+			stats, ok := tagModel.SharedCacheStats()
+			So(ok, ShouldBeTrue)
+			So(stats.Entries, ShouldBeGreaterThan, 0)
+			hits := stats.Hits
+			readTags()
+			stats, _ = tagModel.SharedCacheStats()
+			So(stats.Hits, ShouldBeGreaterThan, hits)
+			So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+				tags := env.Pool("Tag").Search(tagModel.Field(Name).Equals("Books"))
+				tags.Set(Name, "Novels")
+				stats, _ := tagModel.SharedCacheStats()
+				So(stats.Entries, ShouldEqual, 0)
+				So(env.Pool("Tag").Search(tagModel.Field(Name).Equals("Novels")).Len(), ShouldEqual, 1)
+			}), ShouldBeNil)
+		})
+	})
+}
@@ -277,6 +277,19 @@ func TestMixedInModels(t *testing.T) {
 				So(janeProfile.Call("PrintAddress"), ShouldEqual, "[<165 5th Avenue, 0305 New York>, USA]")
 				So(janeProfile.Call("SayHello"), ShouldEqual, "Hello !")
 			})
+			Convey("Checking that private methods cannot be called through RPC", func() {
+				janeProfile := users.Search(users.Model().Field(email).Equals("jane.smith@example.com")).Get(profile).(RecordSet).Collection()
+				So(RPCMethodName("print_address"), ShouldEqual, "PrintAddress")
+				So(RPCMethodName("PrintAddress"), ShouldEqual, "PrintAddress")
+				So(janeProfile.CallRPC("print_address"), ShouldResemble, []interface{}{"[<165 5th Avenue, 0305 New York>, USA]"})
+				So(janeProfile.Call("CityLine"), ShouldEqual, "0305 New York")
+				So(func() { janeProfile.CallRPC("CityLine") }, ShouldPanic)
+				So(func() { janeProfile.CallRPC("city_line") }, ShouldPanic)
+				So(func() { janeProfile.CallRPC("cityLine") }, ShouldPanic)
+				So(IsPrivateRPCMethod("Profile", "city_line"), ShouldBeTrue)
+				So(IsPrivateRPCMethod("Profile", "print_address"), ShouldBeFalse)
+				So(IsPrivateRPCMethod("UnknownModel", "city_line"), ShouldBeFalse)
+			})
 			Convey("Checking mixing in all models", func() {
 				userJane := users.Search(users.Model().Field(email).Equals("jane.smith@example.com"))
 				userJane.Set(active, true)
@@ -333,6 +346,9 @@ func TestContextedFields(t *testing.T) {
 				So(tagc.WithContext("lang", "de_DE").Get(description), ShouldEqual, "übersetzte Beschreibung")
 				So(tagc.WithContext("lang", "es_ES").Get(description), ShouldEqual, "descripción traducida")
 				So(tagc.WithContext("lang", "it_IT").Get(description), ShouldEqual, "Translated description")
+				sourceTag := tagc.WithContext("lang", "fr_FR").WithContext("hexya_source_lang", true)
+				So(sourceTag.Env().Lang(), ShouldEqual, "")
+				So(sourceTag.Get(description), ShouldEqual, "Translated description")
 			})
 			Convey("Creating a record with a contexted field should also create for default context", func() {
 				mTags.WithContext("lang", "fr_FR").Call("Create", NewModelData(mTags.model).
@@ -454,14 +470,58 @@ func TestRecursionProtection(t *testing.T) {
 			})
 			Convey("Loop calls should not trigger recursion protection", func() {
 				So(func() {
-					for i := 0; i < int(maxRecursionDepth)+10; i++ {
+					for i := 0; i < MaxCallDepth()+10; i++ {
 						env.Pool("Profile").Call("SayHello")
 					}
 				}, ShouldNotPanic)
 			})
 			Convey("Recursion should be triggered exactly at the max recursion depth", func() {
-				So(func() { env.Pool("User").Call("RecursiveMethod", int(maxRecursionDepth)/2-1, "Hi!") }, ShouldNotPanic)
-				So(func() { env.Pool("User").Call("RecursiveMethod", int(maxRecursionDepth)/2, "Hi!") }, ShouldPanic)
+				So(func() { env.Pool("User").Call("RecursiveMethod", MaxCallDepth()/2-1, "Hi!") }, ShouldNotPanic)
+				So(func() { env.Pool("User").Call("RecursiveMethod", MaxCallDepth()/2, "Hi!") }, ShouldPanic)
+			})
+			Convey("Max call depth should be configurable", func() {
+				SetMaxCallDepth(20)
+				So(MaxCallDepth(), ShouldEqual, 20)
+				So(func() { env.Pool("User").Call("RecursiveMethod", 9, "Hi!") }, ShouldNotPanic)
+				So(func() { env.Pool("User").Call("RecursiveMethod", 10, "Hi!") }, ShouldPanic)
+				SetMaxCallDepth(0)
+				So(MaxCallDepth(), ShouldEqual, DefaultMaxCallDepth)
+			})
+			Convey("Call loops should be found in call stacks", func() {
+				So(callLoop([]string{"User.A", "User.A", "User.A"}), ShouldResemble, []string{"User.A", "User.A"})
+				So(callLoop([]string{"User.B", "Post.A (super)", "User.B", "Post.A (super)", "User.C"}), ShouldResemble,
+					[]string{"User.B", "Post.A (super)", "User.B"})
+				So(callLoop([]string{"User.A", "User.B", "User.C"}), ShouldBeNil)
+				frames := (&callFrame{model: "User", method: "B", super: true,
+					parent: &callFrame{model: "User", method: "A"}}).frames()
+				So(frames, ShouldResemble, []string{"User.B (super)", "User.A"})
+			})
+		}), ShouldBeNil)
+	})
+}
+
+func TestMethodProfiling(t *testing.T) {
+	Convey("Testing method layers profiling", t, func() {
+		So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+			Convey("Calls of method layers should be profiled only when enabled", func() {
+				ResetMethodProfiles()
+				env.Pool("User").Call("RecursiveMethod", 2, "Hi!")
+				So(MethodProfiles(), ShouldBeEmpty)
+				EnableMethodProfiling(true)
+				env.Pool("User").Call("RecursiveMethod", 2, "Hi!")
+				EnableMethodProfiling(false)
+				var profiles []LayerProfile
+				for _, profile := range MethodProfiles() {
+					if profile.Model == "User" && profile.Method == "RecursiveMethod" {
+						profiles = append(profiles, profile)
+					}
+				}
+				So(profiles, ShouldHaveLength, 1)
+				So(profiles[0].Calls, ShouldEqual, 6)
+				So(profiles[0].Self, ShouldBeGreaterThan, 0)
+				So(profiles[0].Self, ShouldBeLessThanOrEqualTo, profiles[0].Total)
+				ResetMethodProfiles()
+				So(MethodProfiles(), ShouldBeEmpty)
 			})
 		}), ShouldBeNil)
 	})
@@ -500,6 +560,12 @@ func TestInternalMethodFunctions(t *testing.T) {
 				meth := users.model.methods.MustGet("OnChangeMana")
 				So(meth.MethodType(), ShouldEqual, reflect.TypeOf(func(*RecordCollection) *ModelData { return &ModelData{} }))
 			})
+			Convey("IsPrivate", func() {
+				So(users.model.methods.MustGet("OnChangeMana").IsPrivate(), ShouldBeFalse)
+				So(Registry.MustGet("Profile").methods.MustGet("PrintAddress").IsPrivate(), ShouldBeFalse)
+				So(Registry.MustGet("AddressMixIn").methods.MustGet("CityLine").IsPrivate(), ShouldBeTrue)
+				So(Registry.MustGet("Profile").methods.MustGet("CityLine").IsPrivate(), ShouldBeTrue)
+			})
 			Convey("Name", func() {
 				meth := users.model.methods.MustGet("ComputeCoolType")
 				So(meth.Name(), ShouldEqual, "ComputeCoolType")
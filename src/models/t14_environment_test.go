@@ -267,7 +267,7 @@ X2M Links
 	Convey("Testing db error retries", t, func() {
 		Convey("ExecuteInNewEnvironment should retry db errors up to max retries", func() {
 			var retries uint8
-			So(doExecuteInNewEnvironment(security.SuperUserID, 0, func(env Environment) {
+			So(doExecuteInNewEnvironment("", security.SuperUserID, 0, func(env Environment) {
 				retries++
 				panic(&pq.Error{Code: "40001"})
 			}), ShouldNotBeNil)
@@ -275,7 +275,7 @@ X2M Links
 		})
 		Convey("ExecuteInNewEnvironment should retry db errors and stop when ok", func() {
 			var retries uint8
-			So(doExecuteInNewEnvironment(security.SuperUserID, 0, func(env Environment) {
+			So(doExecuteInNewEnvironment("", security.SuperUserID, 0, func(env Environment) {
 				retries++
 				if retries < 3 {
 					panic(&pq.Error{Code: "40001"})
@@ -285,7 +285,7 @@ X2M Links
 		})
 		Convey("SimulateInNewEnvironment should retry db errors up to max retries", func() {
 			var retries uint8
-			So(doSimulateInNewEnvironment(security.SuperUserID, 0, func(env Environment) {
+			So(doSimulateInNewEnvironment("", security.SuperUserID, 0, func(env Environment) {
 				retries++
 				panic(&pq.Error{Code: "40001"})
 			}), ShouldNotBeNil)
@@ -293,7 +293,7 @@ X2M Links
 		})
 		Convey("SimulateInNewEnvironment should retry db errors and stop when ok", func() {
 			var retries uint8
-			So(doSimulateInNewEnvironment(security.SuperUserID, 0, func(env Environment) {
+			So(doSimulateInNewEnvironment("", security.SuperUserID, 0, func(env Environment) {
 				retries++
 				if retries < 3 {
 					panic(&pq.Error{Code: "40001"})
@@ -303,3 +303,50 @@ X2M Links
 		})
 	})
 }
+
+func TestSnapshotEnvironment(t *testing.T) {
+	Convey("Testing snapshot environments", t, func() {
+		Convey("Transaction ids should be parsed from the snapshot", func() {
+			snapshot := Snapshot{TxIDs: "10:20:10,14,15"}
+			So(snapshot.XMin(), ShouldEqual, 10)
+			So(snapshot.XMax(), ShouldEqual, 20)
+			So(Snapshot{}.XMin(), ShouldEqual, 0)
+		})
+		Convey("Snapshot environments should be read only and repeatable", func() {
+			So(ExecuteInSnapshotEnvironment("", 0, security.SuperUserID, func(env Environment) {
+				So(env.IsSnapshot(), ShouldBeTrue)
+				snapshot := env.Snapshot()
+				So(snapshot.Isolation, ShouldEqual, "repeatable read")
+				So(snapshot.ReadOnly, ShouldBeTrue)
+				So(snapshot.XMax(), ShouldBeGreaterThanOrEqualTo, snapshot.XMin())
+				So(env.ExportSnapshot(), ShouldNotBeBlank)
+				So(env.Snapshot().TxIDs, ShouldEqual, snapshot.TxIDs)
+				users := env.Pool("User").SearchAll()
+				So(users.Len(), ShouldBeGreaterThan, 0)
+			}), ShouldBeNil)
+			So(ExecuteInSnapshotEnvironment("", 0, security.SuperUserID, func(env Environment) {
+				users := env.Pool("User")
+				users.Search(users.Model().Field(email).Equals("jane.smith@example.com")).Set(email, "jane@example.com")
+			}), ShouldNotBeNil)
+		})
+		Convey("Default environments should be serializable", func() {
+			So(SimulateInNewEnvironment(security.SuperUserID, func(env Environment) {
+				So(env.IsSnapshot(), ShouldBeFalse)
+				So(env.Snapshot().Isolation, ShouldEqual, "serializable")
+			}), ShouldBeNil)
+		})
+	})
+}
+
+func TestTenantSchema(t *testing.T) {
+	Convey("Testing tenant schema check", t, func() {
+		Convey("A synchronised database should be accepted", func() {
+			So(checkTenantSchema(db), ShouldBeNil)
+		})
+		Convey("A database with other custom fields should be refused", func() {
+			dbExecuteNoTx(`ALTER TABLE post ADD COLUMN custom_field varchar`)
+			defer dbExecuteNoTx(`ALTER TABLE post DROP COLUMN custom_field`)
+			So(checkTenantSchema(db), ShouldNotBeNil)
+		})
+	})
+}
@@ -96,6 +96,8 @@ func TestBaseModelMethods(t *testing.T) {
 				So(fInfo.Type, ShouldEqual, fieldtype.Char)
 				fInfos := userJane.Call("FieldsGet", FieldsGetArgs{}).(map[string]*FieldInfo)
 				So(fInfos, ShouldHaveLength, 35)
+				So(fInfos["name"].Sortable, ShouldBeTrue)
+				So(fInfos["decorated_name"].Sortable, ShouldBeFalse)
 			})
 			Convey("NameGet", func() {
 				So(userJane.Get(displayName), ShouldEqual, "Jane A. Smith")
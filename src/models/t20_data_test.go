@@ -129,3 +129,48 @@ func TestDataLoading(t *testing.T) {
 		}), ShouldBeNil)
 	})
 }
+
+func TestLoadRecords(t *testing.T) {
+	Convey("Testing records loading with external IDs", t, func() {
+		So(ExecuteInNewEnvironment(security.SuperUserID, func(env Environment) {
+			postObj := env.Pool("Post")
+			headers := []string{"id", "User/id", "Title", "Content", "Tags/id"}
+			Convey("Records should be created and then updated by external ID", func() {
+				res := postObj.Call("LoadRecords", headers, [][]string{
+					{"load_post_1", "external_id_1", "Loaded Post", "Loaded content", "tag_book,tag_app"},
+					{"", "external_id_2", "Post without external ID", "Content", ""},
+				}).(*LoadRecordsResult)
+				So(res.Messages, ShouldBeEmpty)
+				So(res.IDs, ShouldHaveLength, 2)
+				So(res.ExternalIDs[0], ShouldEqual, "load_post_1")
+				So(res.ExternalIDs[1], ShouldNotBeBlank)
+				post := postObj.Search(postObj.Model().Field(ID).Equals(res.IDs[0]))
+				So(post.Get(title), ShouldEqual, "Loaded Post")
+				So(post.Get(tags).(RecordSet).Collection().Len(), ShouldEqual, 2)
+				res2 := postObj.Call("LoadRecords", headers, [][]string{
+					{"load_post_1", "external_id_1", "Loaded Post Modified", "Loaded content", "tag_film"},
+				}).(*LoadRecordsResult)
+				So(res2.Messages, ShouldBeEmpty)
+				So(res2.IDs, ShouldResemble, []int64{res.IDs[0]})
+				So(post.Get(title), ShouldEqual, "Loaded Post Modified")
+				So(post.Get(tags).(RecordSet).Collection().Len(), ShouldEqual, 1)
+			})
+			Convey("Invalid data should not be loaded", func() {
+				count := postObj.SearchAll().SearchCount()
+				res := postObj.Call("LoadRecords", headers, [][]string{
+					{"load_post_2", "external_id_1", "Post", "Content", ""},
+					{"load_post_3", "unknown_user", "Post", "Content", ""},
+					{"load_post_4", "external_id_1", "Post"},
+				}).(*LoadRecordsResult)
+				So(res.IDs, ShouldBeNil)
+				So(res.Messages, ShouldHaveLength, 2)
+				So(res.Messages[0].Row, ShouldEqual, 1)
+				So(res.Messages[0].Field, ShouldEqual, "User/id")
+				So(res.Messages[1].Row, ShouldEqual, 2)
+				So(postObj.SearchAll().SearchCount(), ShouldEqual, count)
+				res = postObj.Call("LoadRecords", []string{"id", "Unknown", "Title/id"}, [][]string{}).(*LoadRecordsResult)
+				So(res.Messages, ShouldHaveLength, 2)
+			})
+		}), ShouldBeNil)
+	})
+}
@@ -392,6 +392,7 @@ func TestExtModelDeclaration(t *testing.T) {
 			"BestPost": fields.Many2One{RelationModel: models.Registry.MustGet("ExtPost")},
 			"City":     fields.Char{},
 			"Country":  fields.Char{},
+			"Phone":    fields.Char{Validators: []models.FieldValidator{models.PhoneValidator("Country")}},
 			"UserName": fields.Char{Related: "User.Name"},
 		})
 
@@ -415,6 +416,8 @@ func TestExtModelDeclaration(t *testing.T) {
 			"WriterAge": fields.Integer{Compute: post.Methods().MustGet("ComputeWriterAge"),
 				Depends: []string{"User.Age"}, Stored: true, GoType: new(int16)},
 			"WriterMoney": fields.Float{Related: "User.PMoney"},
+			"Color":       fields.ColorIndex{},
+			"Priority":    fields.Priority{},
 		})
 		post.SetDefaultOrder("Title")
 
@@ -73,5 +73,61 @@ func TestExtBootStrap(t *testing.T) {
 		So(fInfos[genderField.JSON()].Selection, ShouldHaveLength, 2)
 		So(fInfos[genderField.JSON()].Selection, ShouldContainKey, "m")
 		So(fInfos[genderField.JSON()].Selection, ShouldContainKey, "f")
+		colorField := models.Registry.MustGet("ExtPost").Fields().MustGet("Color")
+		priorityField := models.Registry.MustGet("ExtPost").Fields().MustGet("Priority")
+		fInfos = models.Registry.MustGet("ExtPost").FieldsGet(colorField, priorityField)
+		So(fInfos[colorField.JSON()].Type, ShouldEqual, fieldtype.Integer)
+		So(fInfos[colorField.JSON()].String, ShouldEqual, "Color Index")
+		So(fInfos[priorityField.JSON()].Type, ShouldEqual, fieldtype.Selection)
+		So(fInfos[priorityField.JSON()].Selection, ShouldResemble, fields.PrioritySelection)
+	})
+}
+
+func TestExtIntrospection(t *testing.T) {
+	Convey("Testing the introspection of the registry", t, func() {
+		Convey("Models should be described with their fields and methods", func() {
+			desc := models.Registry.MustGet("ExtUser").Describe()
+			So(desc.Type, ShouldEqual, models.ModelTypeModel)
+			So(desc.Mixins, ShouldContain, "ModelMixin")
+			var profile models.FieldDescription
+			for _, fd := range desc.Fields {
+				if fd.Name == "Profile" {
+					profile = fd
+				}
+			}
+			So(profile.Type, ShouldEqual, fieldtype.One2One)
+			So(profile.Relation, ShouldEqual, "ExtProfile")
+			So(profile.Required, ShouldBeTrue)
+			var prefixed models.MethodDescription
+			for _, md := range desc.Methods {
+				if md.Name == "PrefixedUser" {
+					prefixed = md
+				}
+			}
+			So(prefixed.Signature, ShouldEqual, "PrefixedUser(string) []string")
+			So(prefixed.Params, ShouldResemble, []string{"string"})
+		})
+		Convey("The relation graph should link related models", func() {
+			graph := models.Registry.RelationGraph("ExtUser", "ExtPost")
+			So(graph.Models, ShouldResemble, []string{"ExtPost", "ExtUser"})
+			So(graph.Relations, ShouldContain, models.Relation{From: "ExtPost", Field: "User", To: "ExtUser", Type: fieldtype.Many2One, Stored: true})
+			So(graph.Relations, ShouldContain, models.Relation{From: "ExtUser", Field: "Posts", To: "ExtPost", Type: fieldtype.One2Many})
+			So(graph.DOT(), ShouldContainSubstring, `"ExtPost" -> "ExtUser" [label="User (many2one)"];`)
+		})
+		Convey("Entity relationship diagrams should only show foreign keys and link tables", func() {
+			graph := models.Registry.RelationGraph().Filter(nil, "Ext")
+			So(graph.Models, ShouldContain, "ExtPost")
+			So(graph.Models, ShouldNotContain, "User")
+			So(graph.Tables["ExtPost"], ShouldEqual, "ext_post")
+			dot := graph.EntityRelationDOT()
+			So(dot, ShouldContainSubstring, `"ExtPost" -> "ExtUser" [label="User", arrowtail=crow, dir=both];`)
+			So(dot, ShouldContainSubstring, `"ExtUser" -> "ExtPost" [label="LastPost", arrowtail=crow, dir=both];`)
+			So(dot, ShouldNotContainSubstring, `label="Posts"`)
+			So(dot, ShouldNotContainSubstring, `label="BestProfilePost"`)
+			uml := graph.EntityRelationPlantUML()
+			So(uml, ShouldStartWith, "@startuml\n")
+			So(uml, ShouldContainSubstring, "ExtPost }o--o| ExtUser : User\n")
+			So(graph.Filter([]string{"unknown"}, "").Models, ShouldBeEmpty)
+		})
 	})
 }
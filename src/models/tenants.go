@@ -0,0 +1,189 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/jmoiron/sqlx"
+)
+
+// tenants holds the connections to the databases that are served
+// by this process in addition to the main database.
+//
+// All tenant databases share the models Registry of the main database:
+// there are no per database registries, since models are built once at
+// bootstrap and cannot be modified afterwards. Tenant databases must therefore
+// have been synchronised with the same modules (e.g. with 'hexya updatedb
+// --db-name <tenant>') and DBConnectTenant refuses databases whose schema
+// differs from the Registry, such as databases with other custom fields.
+// These must be served by a separate process. Each Environment having its
+// own cache, data of different tenants is never mixed. Process-wide data
+// that depends on the database, such as group memberships or shared caches,
+// is kept per database.
+var tenants = struct {
+	sync.RWMutex
+	dbs map[string]*sqlx.DB
+}{
+	dbs: make(map[string]*sqlx.DB),
+}
+
+// DBConnectTenant connects to the tenant database given by params.DBName
+// so that environments can be created on it with ExecuteInTenantEnvironment.
+//
+// The driver must be the same as the one of the main database.
+// It does nothing if the tenant database is already connected and returns
+// an error if the tables of the tenant database do not match the Registry
+// (see checkTenantSchema).
+func DBConnectTenant(driver string, params ConnectionParams) error {
+	tenants.Lock()
+	defer tenants.Unlock()
+	if _, exists := tenants.dbs[params.DBName]; exists || params.DBName == dbName {
+		return nil
+	}
+	if db != nil && driver != db.DriverName() {
+		log.Panic("Tenant database driver must be the same as the main database driver",
+			"driver", driver, "mainDriver", db.DriverName())
+	}
+	tenantDB, err := sqlx.Connect(driver, ConnectionString(driver, params))
+	if err != nil {
+		return err
+	}
+	if err = checkTenantSchema(tenantDB); err != nil {
+		tenantDB.Close()
+		return fmt.Errorf("database %s cannot be served by this process: %s", params.DBName, err)
+	}
+	tenants.dbs[params.DBName] = tenantDB
+	log.Info("Connected to tenant database", "driver", driver, "database", params.DBName)
+	return nil
+}
+
+// checkTenantSchema returns an error if the tables of the given database do
+// not have exactly the columns of the models of the Registry. It does nothing
+// if the Registry has not been bootstrapped yet.
+func checkTenantSchema(tenantDB *sqlx.DB) error {
+	if !Registry.isFrozen() {
+		return nil
+	}
+	var colData []struct {
+		TableName  string `db:"table_name"`
+		ColumnName string `db:"column_name"`
+	}
+	if err := tenantDB.Select(&colData, `
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`); err != nil {
+		return err
+	}
+	dbColumns := make(map[string]map[string]bool)
+	for _, col := range colData {
+		if dbColumns[col.TableName] == nil {
+			dbColumns[col.TableName] = make(map[string]bool)
+		}
+		dbColumns[col.TableName][col.ColumnName] = true
+	}
+	for _, model := range Registry.All() {
+		if model.IsMixin() || model.IsManual() {
+			continue
+		}
+		columns, ok := dbColumns[model.tableName]
+		if !ok {
+			return fmt.Errorf("table %s does not exist", model.tableName)
+		}
+		expected := map[string]bool{"id": true}
+		for colName, fi := range model.fields.registryByJSON {
+			if fi.hasColumn() {
+				expected[colName] = true
+			}
+			if fi.codeOrder {
+				expected[fi.codeSortKeyColumn()] = true
+			}
+		}
+		for colName := range expected {
+			if !columns[colName] {
+				return fmt.Errorf("column %s of table %s does not exist", colName, model.tableName)
+			}
+		}
+		for colName := range columns {
+			if !expected[colName] {
+				return fmt.Errorf("column %s of table %s is not a field of model %s", colName, model.tableName, model.name)
+			}
+		}
+	}
+	return nil
+}
+
+// DBCloseTenant closes the connection to the given tenant database.
+// It does nothing if this tenant is not connected.
+func DBCloseTenant(name string) {
+	tenants.Lock()
+	defer tenants.Unlock()
+	tenantDB, exists := tenants.dbs[name]
+	if !exists {
+		return
+	}
+	err := tenantDB.Close()
+	delete(tenants.dbs, name)
+	security.Registry.RemoveDatabase(name)
+	log.Info("Closed tenant database", "database", name, "error", err)
+}
+
+// TenantConnected returns true if the given name is the main database
+// or a connected tenant database.
+func TenantConnected(name string) bool {
+	if name == "" || name == dbName {
+		return true
+	}
+	tenants.RLock()
+	defer tenants.RUnlock()
+	_, exists := tenants.dbs[name]
+	return exists
+}
+
+// ConnectedDBNames returns the names of the main database
+// and of all the connected tenant databases.
+func ConnectedDBNames() []string {
+	tenants.RLock()
+	defer tenants.RUnlock()
+	res := []string{dbName}
+	for name := range tenants.dbs {
+		res = append(res, name)
+	}
+	sort.Strings(res[1:])
+	return res
+}
+
+// tenantName returns the name under which the data of the given database is
+// kept in the process-wide registries, such as security.Registry memberships.
+// This is the empty string for the main database.
+func tenantName(name string) string {
+	if name == dbName {
+		return ""
+	}
+	return name
+}
+
+// MainDBName returns the name of the main database
+func MainDBName() string {
+	return dbName
+}
+
+// getTenantDB returns the connection and the name of the given tenant database.
+// An empty tenant or the name of the main database returns the main database.
+// It panics if the tenant is not connected.
+func getTenantDB(tenant string) (*sqlx.DB, string) {
+	if tenant == "" || tenant == dbName {
+		return db, dbName
+	}
+	tenants.RLock()
+	defer tenants.RUnlock()
+	tenantDB, exists := tenants.dbs[tenant]
+	if !exists {
+		log.Panic("Tenant database is not connected", "database", tenant)
+	}
+	return tenantDB, tenant
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"github.com/hexya-erp/hexya/src/tools/tracing"
+)
+
+// SetTraceSpan sets the span under which the method calls and the queries
+// of this cursor are traced, typically the span of the HTTP request.
+func (c *Cursor) SetTraceSpan(span *tracing.Span) {
+	c.span = span
+}
+
+// TraceSpan returns the current span of this cursor, that is the span of
+// the method being executed, or nil if this cursor is not traced.
+func (c *Cursor) TraceSpan() *tracing.Span {
+	return c.span
+}
+
+// startCallSpan starts a span for the call of the given method on the
+// given number of records and makes it the current span of this cursor.
+// It returns the new span and the previous current span.
+func (c *Cursor) startCallSpan(model, method string, records int) (*tracing.Span, *tracing.Span) {
+	parent := c.span
+	span := tracing.StartSpan(parent, model+"."+method, tracing.KindInternal)
+	if span == nil {
+		return nil, parent
+	}
+	span.SetAttribute("hexya.model", model)
+	span.SetAttribute("hexya.method", method)
+	span.SetAttribute("hexya.records", records)
+	c.span = span
+	return span, parent
+}
+
+// endCallSpan ends the given span started by startCallSpan and restores
+// parent as the current span of this cursor.
+func (c *Cursor) endCallSpan(span, parent *tracing.Span, completed *bool) {
+	if span == nil {
+		return
+	}
+	c.span = parent
+	endSpan(span, completed)
+}
+
+// startQuerySpan starts a span for the given SQL query if this cursor is
+// traced. Queries are not traced outside of a trace, so that they do not
+// start traces of their own.
+func (c *Cursor) startQuerySpan(query string) *tracing.Span {
+	if c.span == nil {
+		return nil
+	}
+	span := tracing.StartSpan(c.span, "SQL", tracing.KindClient)
+	span.SetAttribute("db.system", db.DriverName())
+	span.SetAttribute("db.statement", query)
+	return span
+}
+
+// endSpan ends the given span, marking it as failed
+// if the traced operation panicked.
+func endSpan(span *tracing.Span, completed *bool) {
+	if span == nil {
+		return
+	}
+	if !*completed {
+		span.SetError("operation panicked")
+	}
+	span.End()
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// A NullableFloat is a float64 that can be NULL in the database.
+//
+// It is the Go type of Float fields declared with Nullable: true,
+// so that NULL values are distinguished from zero.
+type NullableFloat struct {
+	Float float64
+	Valid bool
+}
+
+// NewNullableFloat returns a non NULL NullableFloat with the given value
+func NewNullableFloat(value float64) NullableFloat {
+	return NullableFloat{Float: value, Valid: true}
+}
+
+// IsNull returns true if this NullableFloat is NULL
+func (nf NullableFloat) IsNull() bool {
+	return !nf.Valid
+}
+
+// String returns the value of this NullableFloat as a string,
+// or an empty string if it is NULL.
+func (nf NullableFloat) String() string {
+	if !nf.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(nf.Float, 'f', -1, 64)
+}
+
+// Value returns the value of this NullableFloat for the database
+func (nf NullableFloat) Value() (driver.Value, error) {
+	if !nf.Valid {
+		return nil, nil
+	}
+	return nf.Float, nil
+}
+
+// Scan sets the value of this NullableFloat from the given database value
+func (nf *NullableFloat) Scan(src interface{}) error {
+	if s, ok := src.(NullableFloat); ok {
+		*nf = s
+		return nil
+	}
+	var val sql.NullFloat64
+	if err := val.Scan(src); err != nil {
+		return fmt.Errorf("invalid value for NullableFloat: %v", err)
+	}
+	*nf = NullableFloat{Float: val.Float64, Valid: val.Valid}
+	return nil
+}
+
+// MarshalJSON returns the value of this NullableFloat as JSON, that is
+// null if it is NULL.
+func (nf NullableFloat) MarshalJSON() ([]byte, error) {
+	if !nf.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(nf.Float)
+}
+
+// UnmarshalJSON sets the value of this NullableFloat from JSON.
+// Both null and false are unmarshalled as NULL, since clients
+// send false for empty values.
+func (nf *NullableFloat) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || string(data) == "false" {
+		*nf = NullableFloat{}
+		return nil
+	}
+	var val float64
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	*nf = NewNullableFloat(val)
+	return nil
+}
+
+var _ driver.Valuer = NullableFloat{}
+var _ sql.Scanner = &NullableFloat{}
+var _ json.Marshaler = NullableFloat{}
+var _ json.Unmarshaler = &NullableFloat{}
+
+// A NullableString is a string that can be NULL in the database.
+//
+// It is the Go type of Char and Text fields declared with Nullable: true,
+// so that NULL values are distinguished from empty strings.
+type NullableString struct {
+	String string
+	Valid  bool
+}
+
+// NewNullableString returns a non NULL NullableString with the given value
+func NewNullableString(value string) NullableString {
+	return NullableString{String: value, Valid: true}
+}
+
+// IsNull returns true if this NullableString is NULL
+func (ns NullableString) IsNull() bool {
+	return !ns.Valid
+}
+
+// Value returns the value of this NullableString for the database
+func (ns NullableString) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return ns.String, nil
+}
+
+// Scan sets the value of this NullableString from the given database value
+func (ns *NullableString) Scan(src interface{}) error {
+	if s, ok := src.(NullableString); ok {
+		*ns = s
+		return nil
+	}
+	var val sql.NullString
+	if err := val.Scan(src); err != nil {
+		return fmt.Errorf("invalid value for NullableString: %v", err)
+	}
+	*ns = NullableString{String: val.String, Valid: val.Valid}
+	return nil
+}
+
+// MarshalJSON returns the value of this NullableString as JSON, that is
+// null if it is NULL.
+func (ns NullableString) MarshalJSON() ([]byte, error) {
+	if !ns.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(ns.String)
+}
+
+// UnmarshalJSON sets the value of this NullableString from JSON.
+// Both null and false are unmarshalled as NULL, since clients
+// send false for empty values.
+func (ns *NullableString) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || string(data) == "false" {
+		*ns = NullableString{}
+		return nil
+	}
+	var val string
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+	*ns = NewNullableString(val)
+	return nil
+}
+
+var _ driver.Valuer = NullableString{}
+var _ sql.Scanner = &NullableString{}
+var _ json.Marshaler = NullableString{}
+var _ json.Unmarshaler = &NullableString{}
@@ -48,12 +48,15 @@ type RecordSet interface {
 var log logging.Logger
 
 // A Context is a map of objects that is passed along from function to function
-// during a transaction. A Context is read only.
+// during a transaction. A Context is read only: methods that modify it, such as
+// WithKey or Delete, return a modified copy and leave the original untouched,
+// so that a Context can be safely shared between environments.
 type Context struct {
 	values map[string]interface{}
 }
 
-// Copy returns a shallow copy of the Context
+// Copy returns a shallow copy of the Context.
+// The values themselves are not copied.
 func (c Context) Copy() *Context {
 	newCtx := NewContext()
 	for k, v := range c.values {
@@ -220,6 +223,56 @@ func (c *Context) GetBool(key string) bool {
 	return res == 1
 }
 
+// GetStringDefault returns the value of the given key in
+// this Context as a string, or defaultValue if there is no such key.
+// It panics if the value is not of type string
+func (c *Context) GetStringDefault(key string, defaultValue string) string {
+	if !c.HasKey(key) {
+		return defaultValue
+	}
+	return c.GetString(key)
+}
+
+// GetIntegerDefault returns the value of the given key in
+// this Context as an int64, or defaultValue if there is no such key.
+// It panics if the value cannot be casted to int64
+func (c *Context) GetIntegerDefault(key string, defaultValue int64) int64 {
+	if !c.HasKey(key) {
+		return defaultValue
+	}
+	return c.GetInteger(key)
+}
+
+// GetFloatDefault returns the value of the given key in
+// this Context as a float64, or defaultValue if there is no such key.
+// It panics if the value cannot be casted to float64
+func (c *Context) GetFloatDefault(key string, defaultValue float64) float64 {
+	if !c.HasKey(key) {
+		return defaultValue
+	}
+	return c.GetFloat(key)
+}
+
+// GetBoolDefault returns the value of the given key in
+// this Context as a bool, or defaultValue if there is no such key.
+func (c *Context) GetBoolDefault(key string, defaultValue bool) bool {
+	if !c.HasKey(key) {
+		return defaultValue
+	}
+	return c.GetBool(key)
+}
+
+// GetStringSliceDefault returns the value of the given key in
+// this Context as a []string, or defaultValue if there is no such key.
+// It panics if the value is not a slice or if any value
+// is not a string
+func (c *Context) GetStringSliceDefault(key string, defaultValue []string) []string {
+	if !c.HasKey(key) {
+		return defaultValue
+	}
+	return c.GetStringSlice(key)
+}
+
 // HasKey returns true if this Context has the given key
 func (c *Context) HasKey(key string) bool {
 	_, exists := c.values[key]
@@ -227,27 +280,32 @@ func (c *Context) HasKey(key string) bool {
 }
 
 // WithKey returns a copy of this context with the given key/value.
-// If key already exists, it is overwritten.
+// If key already exists, it is overwritten. This context is not modified,
+// so that calls can be chained to build a new context:
+//
+//	ctx := types.NewContext().WithKey("lang", "fr_FR").WithKey("tz", "Europe/Paris")
 func (c Context) WithKey(key string, value interface{}) *Context {
 	if _, ok := value.(RecordSet); ok {
 		log.Panic("Recordset passed in Context. Pass ID instead", "key", key, "value", value)
 	}
-	c.values[key] = value
-	return &c
+	res := c.Copy()
+	res.values[key] = value
+	return res
 }
 
-// Delete removes the content pointed by the given key from the context.
-func (c *Context) Delete(key string) *Context {
-	delete(c.values, key)
-	return c
+// Delete returns a copy of this context without the given key.
+// This context is not modified.
+func (c Context) Delete(key string) *Context {
+	res := c.Copy()
+	delete(res.values, key)
+	return res
 }
 
-// Pop removes the content pointed by the given key from the context.
-// and returns the value
-func (c *Context) Pop(key string) interface{} {
-	val := c.Get(key)
-	c.Delete(key)
-	return val
+// Pop returns the value pointed by the given key and
+// a copy of this context without this key.
+// This context is not modified.
+func (c Context) Pop(key string) (interface{}, *Context) {
+	return c.values[key], c.Delete(key)
 }
 
 // IsEmpty returns true if this Context has no entries.
@@ -269,23 +327,28 @@ func (c Context) ToMap() map[string]interface{} {
 
 // UnmarshalXMLAttr is the XML unmarshalling method of Context.
 func (c *Context) UnmarshalXMLAttr(attr xml.Attr) error {
-	var cm map[string]interface{}
-	err := json.Unmarshal([]byte(attr.Value), &cm)
-	(*c).values = cm
-	return err
+	return c.UnmarshalJSON([]byte(attr.Value))
 }
 
 // MarshalJSON method for Context
-func (c *Context) MarshalJSON() ([]byte, error) {
+func (c Context) MarshalJSON() ([]byte, error) {
+	if c.values == nil {
+		return []byte("{}"), nil
+	}
 	return json.Marshal(c.values)
 }
 
 // UnmarshalJSON method for Context
 func (c *Context) UnmarshalJSON(data []byte) error {
 	var cm map[string]interface{}
-	err := json.Unmarshal(data, &cm)
+	if err := json.Unmarshal(data, &cm); err != nil {
+		return err
+	}
+	if cm == nil {
+		cm = make(map[string]interface{})
+	}
 	(*c).values = cm
-	return err
+	return nil
 }
 
 // String function for Context type
@@ -303,6 +366,9 @@ func (c Context) Value() (driver.Value, error) {
 func (c *Context) Scan(src interface{}) error {
 	var data []byte
 	switch s := src.(type) {
+	case nil:
+		*c = *NewContext()
+		return nil
 	case string:
 		data = []byte(s)
 	case []byte:
@@ -320,13 +386,12 @@ func (c *Context) Scan(src interface{}) error {
 	}
 	*c = ctx
 	return nil
-
 }
 
 var _ driver.Valuer = Context{}
 var _ sql.Scanner = &Context{}
 var _ xml.UnmarshalerAttr = &Context{}
-var _ json.Marshaler = &Context{}
+var _ json.Marshaler = Context{}
 var _ json.Unmarshaler = &Context{}
 
 // NewContext returns a new Context instance
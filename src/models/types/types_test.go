@@ -0,0 +1,127 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+import . "github.com/smartystreets/goconvey/convey"
+
+func TestContext(t *testing.T) {
+	Convey("Testing Context objects", t, func() {
+		ctx := NewContext().WithKey("lang", "fr_FR").WithKey("active_id", 12).WithKey("debug", true)
+		Convey("Typed getters should work", func() {
+			So(ctx.GetString("lang"), ShouldEqual, "fr_FR")
+			So(ctx.GetInteger("active_id"), ShouldEqual, 12)
+			So(ctx.GetFloat("active_id"), ShouldEqual, 12)
+			So(ctx.GetBool("debug"), ShouldBeTrue)
+			So(ctx.GetString("tz"), ShouldEqual, "")
+			So(func() { ctx.GetInteger("lang") }, ShouldPanic)
+		})
+		Convey("Getters with defaults should work", func() {
+			So(ctx.GetStringDefault("lang", "en_US"), ShouldEqual, "fr_FR")
+			So(ctx.GetStringDefault("tz", "UTC"), ShouldEqual, "UTC")
+			So(ctx.GetIntegerDefault("active_id", 1), ShouldEqual, 12)
+			So(ctx.GetIntegerDefault("limit", 80), ShouldEqual, 80)
+			So(ctx.GetFloatDefault("ratio", 0.5), ShouldEqual, 0.5)
+			So(ctx.GetBoolDefault("debug", false), ShouldBeTrue)
+			So(ctx.GetBoolDefault("active_test", true), ShouldBeTrue)
+			So(ctx.GetStringSliceDefault("groups", []string{"base"}), ShouldResemble, []string{"base"})
+		})
+		Convey("Modifying a context should not modify the original", func() {
+			newCtx := ctx.WithKey("lang", "en_US")
+			So(newCtx.GetString("lang"), ShouldEqual, "en_US")
+			So(ctx.GetString("lang"), ShouldEqual, "fr_FR")
+			delCtx := ctx.Delete("lang")
+			So(delCtx.HasKey("lang"), ShouldBeFalse)
+			So(ctx.HasKey("lang"), ShouldBeTrue)
+			val, popCtx := ctx.Pop("active_id")
+			So(val, ShouldEqual, 12)
+			So(popCtx.HasKey("active_id"), ShouldBeFalse)
+			So(ctx.HasKey("active_id"), ShouldBeTrue)
+		})
+		Convey("JSON round-tripping should work", func() {
+			data, err := json.Marshal(ctx)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"active_id":12,"debug":true,"lang":"fr_FR"}`)
+			var newCtx Context
+			So(json.Unmarshal(data, &newCtx), ShouldBeNil)
+			So(newCtx.GetString("lang"), ShouldEqual, "fr_FR")
+			So(newCtx.GetInteger("active_id"), ShouldEqual, 12)
+			So(newCtx.GetBool("debug"), ShouldBeTrue)
+			So(json.Unmarshal([]byte("null"), &newCtx), ShouldBeNil)
+			So(newCtx.IsEmpty(), ShouldBeTrue)
+			So(newCtx.WithKey("lang", "en_US").GetString("lang"), ShouldEqual, "en_US")
+		})
+		Convey("DB round-tripping should work", func() {
+			val, err := ctx.Value()
+			So(err, ShouldBeNil)
+			So(string(val.([]byte)), ShouldEqual, `{"active_id":12,"debug":true,"lang":"fr_FR"}`)
+			var newCtx Context
+			So(newCtx.Scan(val), ShouldBeNil)
+			So(newCtx.GetString("lang"), ShouldEqual, "fr_FR")
+			So(newCtx.GetInteger("active_id"), ShouldEqual, 12)
+			So(newCtx.Scan(nil), ShouldBeNil)
+			So(newCtx.IsEmpty(), ShouldBeTrue)
+			So(newCtx.Scan(12), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestNullableTypes(t *testing.T) {
+	Convey("Testing nullable types", t, func() {
+		Convey("NullableFloat should distinguish NULL from zero", func() {
+			var nf NullableFloat
+			So(nf.IsNull(), ShouldBeTrue)
+			val, err := nf.Value()
+			So(err, ShouldBeNil)
+			So(val, ShouldBeNil)
+			So(nf.Scan(float64(0)), ShouldBeNil)
+			So(nf.IsNull(), ShouldBeFalse)
+			So(nf.Float, ShouldEqual, 0)
+			So(nf.Scan([]byte("12.5")), ShouldBeNil)
+			So(nf, ShouldResemble, NewNullableFloat(12.5))
+			So(nf.String(), ShouldEqual, "12.5")
+			So(nf.Scan(int64(3)), ShouldBeNil)
+			So(nf.Float, ShouldEqual, 3)
+			So(nf.Scan(nil), ShouldBeNil)
+			So(nf.IsNull(), ShouldBeTrue)
+			So(nf.Scan("abc"), ShouldNotBeNil)
+		})
+		Convey("NullableFloat JSON marshalling should work", func() {
+			data, _ := json.Marshal(NullableFloat{})
+			So(string(data), ShouldEqual, "null")
+			data, _ = json.Marshal(NewNullableFloat(0))
+			So(string(data), ShouldEqual, "0")
+			var nf NullableFloat
+			So(json.Unmarshal([]byte("1.5"), &nf), ShouldBeNil)
+			So(nf, ShouldResemble, NewNullableFloat(1.5))
+			So(json.Unmarshal([]byte("false"), &nf), ShouldBeNil)
+			So(nf.IsNull(), ShouldBeTrue)
+		})
+		Convey("NullableString should distinguish NULL from empty string", func() {
+			var ns NullableString
+			So(ns.IsNull(), ShouldBeTrue)
+			val, _ := ns.Value()
+			So(val, ShouldBeNil)
+			So(ns.Scan(""), ShouldBeNil)
+			So(ns, ShouldResemble, NewNullableString(""))
+			val, _ = ns.Value()
+			So(val, ShouldEqual, "")
+			So(ns.Scan([]byte("hello")), ShouldBeNil)
+			So(ns.String, ShouldEqual, "hello")
+			So(ns.Scan(nil), ShouldBeNil)
+			So(ns.IsNull(), ShouldBeTrue)
+		})
+		Convey("NullableString JSON marshalling should work", func() {
+			data, _ := json.Marshal(NullableString{})
+			So(string(data), ShouldEqual, "null")
+			data, _ = json.Marshal(NewNullableString(""))
+			So(string(data), ShouldEqual, `""`)
+			var ns NullableString
+			So(json.Unmarshal([]byte(`"hello"`), &ns), ShouldBeNil)
+			So(ns, ShouldResemble, NewNullableString("hello"))
+			So(json.Unmarshal([]byte("null"), &ns), ShouldBeNil)
+			So(ns.IsNull(), ShouldBeTrue)
+		})
+	})
+}
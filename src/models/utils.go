@@ -70,6 +70,38 @@ func addNameSearchesToCondition(mi *Model, cond *Condition) {
 	}
 }
 
+// addSearchFuncsToCondition recursively modifies the given condition to replace
+// the predicates on non stored computed fields by the conditions returned by
+// the search functions of these fields.
+func addSearchFuncsToCondition(env Environment, mi *Model, cond *Condition) {
+	for i, p := range cond.predicates {
+		if p.cond != nil {
+			addSearchFuncsToCondition(env, mi, p.cond)
+		}
+		if len(p.exprs) == 0 {
+			continue
+		}
+		fi := mi.getRelatedFieldInfo(joinFieldNames(p.exprs, ExprSep))
+		if fi.isStored() || fi.searchFunc == nil {
+			continue
+		}
+		fCond := fi.searchFunc(env, p.operator, p.arg)
+		if fCond == nil || fCond.Underlying().IsEmpty() {
+			log.Panic("Search function returned an empty condition", "model", fi.model.name, "field", fi.name,
+				"operator", p.operator, "value", p.arg)
+		}
+		subCond := fCond.Underlying().withPrefix(p.exprs[:len(p.exprs)-1])
+		// The returned condition may itself use fields with a search function
+		addSearchFuncsToCondition(env, mi, subCond)
+		cond.predicates[i] = predicate{
+			cond:   subCond,
+			isCond: true,
+			isOr:   p.isOr,
+			isNot:  p.isNot,
+		}
+	}
+}
+
 // addNameSearchToExprs modifies the given exprs to search on the name of the related record
 // if it points to a relation field.
 func addNameSearchToExprs(fi *Field, exprs []FieldName) []FieldName {
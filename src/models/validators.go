@@ -0,0 +1,179 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/tools/emailutils"
+	"github.com/hexya-erp/hexya/src/tools/htmlutils"
+	"github.com/hexya-erp/hexya/src/tools/phoneutils"
+)
+
+// A FieldValidator checks and normalizes the value of a char field given to Create
+// or Write. rc holds the records being updated, or is empty when creating a record,
+// and values are all the values given to Create or Write.
+//
+// It returns the normalized value to store, or an error if the value is invalid.
+type FieldValidator func(rc *RecordCollection, values FieldMap, value string) (string, error)
+
+// A ValidationError is the error of a value rejected by a FieldValidator
+// or by a database constraint.
+type ValidationError struct {
+	Model string
+	Field string
+	Value string
+	// Message is the translated message for users of a database
+	// constraint violation. It is empty for FieldValidator errors.
+	Message string
+	Err     error
+}
+
+// Error returns the error message of this ValidationError
+func (ve ValidationError) Error() string {
+	if ve.Message != "" {
+		return ve.Message
+	}
+	return fmt.Sprintf("%s.%s: invalid value %q: %s", ve.Model, ve.Field, ve.Value, ve.Err)
+}
+
+// Unwrap returns the error of the validator or of the database
+func (ve ValidationError) Unwrap() error {
+	return ve.Err
+}
+
+// ValidationErrors are the errors of all the values rejected by the
+// validators of their field or by a database constraint in a call to
+// Create, Write or Unlink.
+type ValidationErrors []ValidationError
+
+// Error returns the messages of all the errors, one per line.
+// A message shared by several fields is only returned once.
+func (ves ValidationErrors) Error() string {
+	var msgs []string
+	seen := make(map[string]bool)
+	for _, ve := range ves {
+		msg := ve.Error()
+		if seen[msg] {
+			continue
+		}
+		seen[msg] = true
+		msgs = append(msgs, msg)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// validateFieldValues runs the validators of the fields of the given FieldMap on
+// their string values, and replaces them by their normalized values. Values of
+// HTML fields that are not raw are sanitized before being validated.
+// It panics with ValidationErrors if some values are invalid.
+func (rc *RecordCollection) validateFieldValues(fMap FieldMap) {
+	var errs ValidationErrors
+	for _, key := range fMap.OrderedKeys() {
+		fi, ok := rc.model.fields.Get(key)
+		if !ok || (len(fi.validators) == 0 && !fi.isSanitized()) {
+			continue
+		}
+		value, ok := fMap[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		normalized := value
+		if fi.isSanitized() {
+			normalized = htmlutils.Sanitize(value, htmlutils.DefaultPolicy)
+		}
+		for _, validator := range fi.validators {
+			var err error
+			normalized, err = validator(rc, fMap, normalized)
+			if err != nil {
+				errs = append(errs, ValidationError{Model: rc.model.name, Field: fi.name, Value: value, Err: err})
+				break
+			}
+		}
+		fMap[key] = normalized
+	}
+	if len(errs) > 0 {
+		panic(errs)
+	}
+}
+
+// EmailValidator is a FieldValidator that checks that the value is a single
+// email address, and normalizes it with a lower case domain.
+func EmailValidator(_ *RecordCollection, _ FieldMap, value string) (string, error) {
+	return emailutils.Normalize(value)
+}
+
+// PhoneValidator returns a FieldValidator that normalizes phone numbers to the E.164
+// format. National numbers are given the calling code of the country of the record,
+// which is taken from countryField. This field is either a many2one to a model with
+// a Code field holding ISO 3166-1 alpha-2 codes, or a char field holding the code
+// itself. Its value is read from the values being written, or from the records.
+//
+// If countryField is empty, only international numbers are accepted.
+func PhoneValidator(countryField string) FieldValidator {
+	return func(rc *RecordCollection, values FieldMap, value string) (string, error) {
+		if countryField == "" {
+			return phoneutils.Normalize(value, "")
+		}
+		codes := phoneCountryCodes(rc, values, countryField)
+		if len(codes) > 1 {
+			return "", fmt.Errorf("records have different countries %s", strings.Join(codes, ", "))
+		}
+		var code string
+		if len(codes) == 1 {
+			code = codes[0]
+		}
+		return phoneutils.Normalize(value, code)
+	}
+}
+
+// phoneCountryCodes returns the distinct non empty country codes given by the given
+// country field, either in the given values or in the records of rc.
+func phoneCountryCodes(rc *RecordCollection, values FieldMap, countryField string) []string {
+	fi := rc.model.fields.MustGet(countryField)
+	var codes []string
+	if value, ok := values.Get(rc.model.FieldName(fi.name)); ok {
+		codes = []string{phoneCountryCode(rc, fi, value)}
+	} else {
+		for _, rec := range rc.Records() {
+			codes = append(codes, phoneCountryCode(rc, fi, rec.Get(rc.model.FieldName(fi.name))))
+		}
+	}
+	unique := make(map[string]bool)
+	var res []string
+	for _, code := range codes {
+		if code == "" || unique[code] {
+			continue
+		}
+		unique[code] = true
+		res = append(res, code)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// phoneCountryCode returns the country code of the given value of the given country field
+func phoneCountryCode(rc *RecordCollection, fi *Field, value interface{}) string {
+	if fi.fieldType != fieldtype.Many2One {
+		code, _ := value.(string)
+		return code
+	}
+	country := rc.env.Pool(fi.relatedModelName)
+	switch val := value.(type) {
+	case RecordSet:
+		country = val.Collection()
+	case int64:
+		country = country.withIds([]int64{val})
+	default:
+		return ""
+	}
+	if country.IsEmpty() {
+		return ""
+	}
+	code, _ := country.Get(country.model.FieldName("Code")).(string)
+	return code
+}
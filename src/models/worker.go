@@ -40,6 +40,43 @@ func NewWorkerFunction(fnct func(), period time.Duration) WorkerFunction {
 	}
 }
 
+// A Schedule gives the times at which a scheduled worker function is run.
+//
+// Recurrence rules of the tools/recurrence package implement Schedule,
+// so that worker functions can be run at times given by an RRULE.
+type Schedule interface {
+	// Next returns the first run time strictly after t,
+	// or a zero time if the worker function must not run anymore.
+	Next(t time.Time) time.Time
+}
+
+// A ScheduledWorkerFunction is a WorkerFunction that is executed at the
+// times given by its Schedule instead of every LoopPeriod.
+type ScheduledWorkerFunction interface {
+	WorkerFunction
+	// Schedule returns the Schedule of the worker function
+	Schedule() Schedule
+}
+
+// A scheduledWorkerFunction implements ScheduledWorkerFunction
+type scheduledWorkerFunction struct {
+	workerFunction
+	schedule Schedule
+}
+
+// Schedule returns the Schedule of the worker function
+func (w *scheduledWorkerFunction) Schedule() Schedule {
+	return w.schedule
+}
+
+// NewScheduledWorkerFunction returns a ScheduledWorkerFunction from the given fnct and schedule
+func NewScheduledWorkerFunction(fnct func(), schedule Schedule) ScheduledWorkerFunction {
+	return &scheduledWorkerFunction{
+		workerFunction: workerFunction{fnct: fnct},
+		schedule:       schedule,
+	}
+}
+
 var (
 	workerFunctions []WorkerFunction
 	workerStop      chan struct{}
@@ -61,6 +98,10 @@ func RunWorkerLoop() {
 	workerStop = make(chan struct{})
 	for _, workerFunc := range workerFunctions {
 		workerGroup.Add(1)
+		if swf, ok := workerFunc.(ScheduledWorkerFunction); ok {
+			go runScheduledWorker(swf)
+			continue
+		}
 		go func(wf WorkerFunction) {
 			ticker := time.NewTicker(wf.LoopPeriod())
 			defer ticker.Stop()
@@ -77,6 +118,27 @@ func RunWorkerLoop() {
 	}
 }
 
+// runScheduledWorker executes the given ScheduledWorkerFunction
+// at the times of its Schedule until the worker loop is stopped.
+func runScheduledWorker(swf ScheduledWorkerFunction) {
+	defer workerGroup.Done()
+	for {
+		next := swf.Schedule().Next(time.Now())
+		if next.IsZero() {
+			<-workerStop
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			swf.Run()
+		case <-workerStop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
 // StopWorkerLoop stops the hexya core worker loop.
 //
 // Calling this method if the core worker loop is not running will cause panic.
@@ -0,0 +1,45 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package onboarding is a Hexya module that tracks the progress of each
+// company in the onboarding steps declared by modules.
+//
+// Steps are grouped in panels, which are displayed as banners by the views
+// of the modules. Modules declare their steps with RegisterStep in their
+// init() function, and mark them as done with MarkDone or with the IsDone
+// hook of the step:
+//
+//	onboarding.RegisterStep(onboarding.Step{
+//		Name:     "sale_quotation",
+//		Panel:    "sale",
+//		Sequence: 10,
+//		Title:    "Send your first quotation",
+//		Action:   "sale_action_quotations",
+//		IsDone: func(env models.Environment, companyID int64) bool {
+//			return h.SaleOrder().NewSet(env).SearchCount() > 0
+//		},
+//	})
+//
+// Views query the state of a panel with the GetPanel method of the
+// OnboardingStep model, and hide it once it has been closed with ClosePanel.
+package onboarding
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "onboarding"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("onboarding")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
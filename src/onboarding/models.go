@@ -0,0 +1,54 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package onboarding
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+func declareModels() {
+	onboardingStep := models.NewModel("OnboardingStep")
+	onboardingStep.SetDefaultOrder("CompanyID", "Step")
+	onboardingStep.NewMethod("GetPanel", onboardingStep_GetPanel)
+	onboardingStep.NewMethod("MarkDone", onboardingStep_MarkDone)
+	onboardingStep.NewMethod("ClosePanel", onboardingStep_ClosePanel)
+	onboardingStep.AddFields(map[string]models.FieldDefinition{
+		"Step":      fields.Char{Required: true, Index: true},
+		"CompanyID": fields.Integer{String: "Company ID", Index: true},
+		"State": fields.Selection{Required: true,
+			Selection: types.Selection{StateNotDone: "Not Done", StateJustDone: "Just Done", StateDone: "Done"},
+			Default:   models.DefaultValue(StateNotDone)},
+		"DoneDate": fields.DateTime{ReadOnly: true, NoCopy: true},
+	})
+	onboardingStep.AddSQLConstraint("step_company_uniq", "unique(step, company_id)",
+		"The state of a step must be unique per company")
+
+	onboardingPanel := models.NewModel("OnboardingPanel")
+	onboardingPanel.SetDefaultOrder("CompanyID", "Panel")
+	onboardingPanel.AddFields(map[string]models.FieldDefinition{
+		"Panel":     fields.Char{Required: true, Index: true},
+		"CompanyID": fields.Integer{String: "Company ID", Index: true},
+		"Closed":    fields.Boolean{},
+	})
+	onboardingPanel.AddSQLConstraint("panel_company_uniq", "unique(panel, company_id)",
+		"The state of a panel must be unique per company")
+}
+
+// onboardingStep_GetPanel returns the state of the steps of the given panel for the given company.
+func onboardingStep_GetPanel(rs *models.RecordCollection, panel string, companyID int64) Panel {
+	return GetPanel(rs.Env(), panel, companyID)
+}
+
+// onboardingStep_MarkDone marks the step with the given name as done for the given company.
+func onboardingStep_MarkDone(rs *models.RecordCollection, step string, companyID int64) {
+	MarkDone(rs.Env(), step, companyID)
+}
+
+// onboardingStep_ClosePanel closes the given panel for the given company,
+// so that it is not displayed anymore.
+func onboardingStep_ClosePanel(rs *models.RecordCollection, panel string, companyID int64) {
+	ClosePanel(rs.Env(), panel, companyID)
+}
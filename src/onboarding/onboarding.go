@@ -0,0 +1,212 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package onboarding
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// States of onboarding steps
+const (
+	StateNotDone = "not_done"
+	// StateJustDone is the state of a step that has been done since the
+	// last time its panel was displayed, so that it can be congratulated.
+	StateJustDone = "just_done"
+	StateDone     = "done"
+)
+
+// A Step is an onboarding step declared by a module
+type Step struct {
+	// Name is the unique name of the step
+	Name string
+	// Panel is the name of the panel in which the step is displayed
+	Panel    string
+	Sequence int
+	Title    string
+	// Description is an explanation of the step displayed under its title
+	Description string
+	// Action is the ID of the action that starts the step
+	Action string
+	// IsDone is an optional completion hook that returns true if the step has been
+	// done by the given company. It is called when the panel is displayed and the
+	// step is marked as done if it returns true.
+	IsDone func(env models.Environment, companyID int64) bool
+}
+
+// A StepState is a step with its state for a company
+type StepState struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Action      string `json:"action"`
+	State       string `json:"state"`
+}
+
+// A Panel is the state of the steps of a panel for a company
+type Panel struct {
+	Name string `json:"name"`
+	// Closed is true if the panel has been closed and must not be displayed anymore
+	Closed bool `json:"closed"`
+	// Done is true if all the steps of the panel are done
+	Done  bool        `json:"done"`
+	Steps []StepState `json:"steps"`
+}
+
+// steps are the registered steps by name
+var steps struct {
+	sync.RWMutex
+	registry map[string]Step
+}
+
+// RegisterStep declares the given onboarding step.
+// It panics if a step with the same name is already registered.
+func RegisterStep(step Step) {
+	if step.Name == "" || step.Panel == "" {
+		log.Panic("Onboarding steps must have a name and a panel", "step", step.Name, "panel", step.Panel)
+	}
+	steps.Lock()
+	defer steps.Unlock()
+	if _, exists := steps.registry[step.Name]; exists {
+		log.Panic("Onboarding step already registered", "step", step.Name)
+	}
+	if steps.registry == nil {
+		steps.registry = make(map[string]Step)
+	}
+	steps.registry[step.Name] = step
+}
+
+// PanelSteps returns the registered steps of the given panel ordered by sequence
+func PanelSteps(panel string) []Step {
+	steps.RLock()
+	defer steps.RUnlock()
+	var res []Step
+	for _, step := range steps.registry {
+		if step.Panel == panel {
+			res = append(res, step)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Sequence != res[j].Sequence {
+			return res[i].Sequence < res[j].Sequence
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// stepStates returns the OnboardingStep records of the given company
+// for the given step names, indexed by step name.
+func stepStates(env models.Environment, companyID int64, names []string) map[string]*models.RecordCollection {
+	rs := env.Pool("OnboardingStep").Sudo()
+	mi := rs.Model()
+	res := make(map[string]*models.RecordCollection)
+	for _, rec := range rs.Search(mi.Field(mi.FieldName("CompanyID")).Equals(companyID).
+		And().Field(mi.FieldName("Step")).In(names)).Records() {
+		res[rec.Get(mi.FieldName("Step")).(string)] = rec
+	}
+	return res
+}
+
+// MarkDone marks the step with the given name as done for the given company.
+// It does nothing if the step is already done.
+func MarkDone(env models.Environment, step string, companyID int64) {
+	rs := env.Pool("OnboardingStep").Sudo()
+	mi := rs.Model()
+	rec, ok := stepStates(env, companyID, []string{step})[step]
+	switch {
+	case !ok:
+		rs.Call("Create", models.NewModelData(mi).
+			Set(mi.FieldName("Step"), step).
+			Set(mi.FieldName("CompanyID"), companyID).
+			Set(mi.FieldName("State"), StateJustDone).
+			Set(mi.FieldName("DoneDate"), dates.Now()))
+	case rec.Get(mi.FieldName("State")).(string) == StateNotDone:
+		rec.Call("Write", models.NewModelData(mi).
+			Set(mi.FieldName("State"), StateJustDone).
+			Set(mi.FieldName("DoneDate"), dates.Now()))
+	}
+}
+
+// GetPanel returns the state of the steps of the given panel for the given company.
+//
+// Steps whose IsDone hook returns true are marked as done. Steps that have been
+// done since the last call are returned with StateJustDone once, and are then
+// set to StateDone.
+func GetPanel(env models.Environment, panel string, companyID int64) Panel {
+	panelSteps := PanelSteps(panel)
+	names := make([]string, len(panelSteps))
+	for i, step := range panelSteps {
+		names[i] = step.Name
+	}
+	states := stepStates(env, companyID, names)
+	for _, step := range panelSteps {
+		if step.IsDone == nil {
+			continue
+		}
+		if rec, ok := states[step.Name]; ok && rec.Get(rec.Model().FieldName("State")).(string) != StateNotDone {
+			continue
+		}
+		if step.IsDone(env, companyID) {
+			MarkDone(env, step.Name, companyID)
+			states = stepStates(env, companyID, names)
+		}
+	}
+	res := Panel{Name: panel, Closed: panelClosed(env, panel, companyID), Done: true}
+	for _, step := range panelSteps {
+		state := StateNotDone
+		if rec, ok := states[step.Name]; ok {
+			mi := rec.Model()
+			state = rec.Get(mi.FieldName("State")).(string)
+			if state == StateJustDone {
+				rec.Set(mi.FieldName("State"), StateDone)
+			}
+		}
+		if state == StateNotDone {
+			res.Done = false
+		}
+		res.Steps = append(res.Steps, StepState{
+			Name:        step.Name,
+			Title:       step.Title,
+			Description: step.Description,
+			Action:      step.Action,
+			State:       state,
+		})
+	}
+	return res
+}
+
+// panelRecord returns the OnboardingPanel record of the given panel and company
+func panelRecord(env models.Environment, panel string, companyID int64) *models.RecordCollection {
+	rs := env.Pool("OnboardingPanel").Sudo()
+	mi := rs.Model()
+	return rs.Search(mi.Field(mi.FieldName("Panel")).Equals(panel).
+		And().Field(mi.FieldName("CompanyID")).Equals(companyID))
+}
+
+// panelClosed returns true if the given panel has been closed for the given company
+func panelClosed(env models.Environment, panel string, companyID int64) bool {
+	rec := panelRecord(env, panel, companyID)
+	if rec.IsEmpty() {
+		return false
+	}
+	return rec.Get(rec.Model().FieldName("Closed")).(bool)
+}
+
+// ClosePanel closes the given panel for the given company, so that it is not displayed anymore.
+func ClosePanel(env models.Environment, panel string, companyID int64) {
+	rec := panelRecord(env, panel, companyID)
+	mi := rec.Model()
+	if rec.IsEmpty() {
+		rec.Call("Create", models.NewModelData(mi).
+			Set(mi.FieldName("Panel"), panel).
+			Set(mi.FieldName("CompanyID"), companyID).
+			Set(mi.FieldName("Closed"), true))
+		return
+	}
+	rec.Set(mi.FieldName("Closed"), true)
+}
@@ -0,0 +1,27 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package onboarding
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegisterStep(t *testing.T) {
+	RegisterStep(Step{Name: "test_invoice", Panel: "test", Sequence: 20})
+	RegisterStep(Step{Name: "test_company", Panel: "test", Sequence: 10})
+	RegisterStep(Step{Name: "test_bank", Panel: "test", Sequence: 20})
+	RegisterStep(Step{Name: "test_other", Panel: "other"})
+	Convey("Testing the registration of onboarding steps", t, func() {
+		var names []string
+		for _, step := range PanelSteps("test") {
+			names = append(names, step.Name)
+		}
+		So(names, ShouldResemble, []string{"test_company", "test_bank", "test_invoice"})
+		So(PanelSteps("unknown"), ShouldBeEmpty)
+		So(func() { RegisterStep(Step{Name: "test_company", Panel: "other"}) }, ShouldPanic)
+		So(func() { RegisterStep(Step{Name: "test_no_panel"}) }, ShouldPanic)
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package paperformats
+
+import (
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// ResetRegistry replaces the Registry by a collection with only
+// the default paper format, so that paper formats can be loaded again.
+func ResetRegistry() {
+	Registry = NewCollection()
+	Registry.Add(&PaperFormat{
+		XMLID:         DefaultPaperFormatID,
+		Name:          "A4",
+		Format:        "A4",
+		Orientation:   OrientationPortrait,
+		MarginTop:     40,
+		MarginBottom:  32,
+		MarginLeft:    7,
+		MarginRight:   7,
+		HeaderSpacing: 35,
+		DPI:           90,
+	})
+}
+
+func init() {
+	log = logging.GetLogger("paperformats")
+	ResetRegistry()
+}
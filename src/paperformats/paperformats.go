@@ -0,0 +1,160 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package paperformats holds the paper formats with which PDF reports are printed.
+//
+// Paper formats are defined in XML resource files with the 'paperformat' tag:
+//
+//     <paperformat id="paperformat_us" name="US Letter" format="Letter" orientation="Portrait"
+//                  margin_top="40" margin_bottom="25" margin_left="7" margin_right="7"
+//                  header_spacing="35" dpi="90"/>
+//
+// Margins and spacing are in millimeters. Custom page sizes are
+// defined with format="custom" and the page_width and page_height attributes.
+package paperformats
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/beevik/etree"
+	"github.com/hexya-erp/hexya/src/tools/xmlutils"
+)
+
+// DefaultPaperFormatID is the ID of the paper format used when none is specified
+const DefaultPaperFormatID = "paperformat_a4"
+
+// Orientation of the pages of a PaperFormat
+type Orientation string
+
+// Available orientations
+const (
+	OrientationPortrait  Orientation = "Portrait"
+	OrientationLandscape Orientation = "Landscape"
+)
+
+// Registry is the paper format collection of the application
+var Registry *Collection
+
+// A PaperFormat defines the page setup of PDF reports
+type PaperFormat struct {
+	XMLID         string      `xml:"id,attr"`
+	Name          string      `xml:"name,attr"`
+	Format        string      `xml:"format,attr"`
+	PageWidth     float64     `xml:"page_width,attr"`
+	PageHeight    float64     `xml:"page_height,attr"`
+	Orientation   Orientation `xml:"orientation,attr"`
+	MarginTop     float64     `xml:"margin_top,attr"`
+	MarginBottom  float64     `xml:"margin_bottom,attr"`
+	MarginLeft    float64     `xml:"margin_left,attr"`
+	MarginRight   float64     `xml:"margin_right,attr"`
+	HeaderLine    bool        `xml:"header_line,attr"`
+	HeaderSpacing float64     `xml:"header_spacing,attr"`
+	DPI           int         `xml:"dpi,attr"`
+}
+
+// formatFloat formats a float for the command line
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// WkhtmltopdfArgs returns the wkhtmltopdf command line arguments
+// that implement this paper format.
+func (pf *PaperFormat) WkhtmltopdfArgs() []string {
+	var res []string
+	if pf.Format == "custom" || pf.Format == "" {
+		if pf.PageWidth > 0 && pf.PageHeight > 0 {
+			res = append(res, "--page-width", formatFloat(pf.PageWidth)+"mm",
+				"--page-height", formatFloat(pf.PageHeight)+"mm")
+		}
+	} else {
+		res = append(res, "--page-size", pf.Format)
+	}
+	if pf.Orientation != "" {
+		res = append(res, "--orientation", string(pf.Orientation))
+	}
+	res = append(res,
+		"--margin-top", formatFloat(pf.MarginTop)+"mm",
+		"--margin-bottom", formatFloat(pf.MarginBottom)+"mm",
+		"--margin-left", formatFloat(pf.MarginLeft)+"mm",
+		"--margin-right", formatFloat(pf.MarginRight)+"mm")
+	if pf.HeaderLine {
+		res = append(res, "--header-line")
+	}
+	if pf.HeaderSpacing > 0 {
+		res = append(res, "--header-spacing", formatFloat(pf.HeaderSpacing))
+	}
+	if pf.DPI > 0 {
+		res = append(res, "--dpi", strconv.Itoa(pf.DPI))
+	}
+	return res
+}
+
+// A Collection is a collection of paper formats
+type Collection struct {
+	sync.RWMutex
+	formats map[string]*PaperFormat
+}
+
+// NewCollection returns a pointer to a new Collection instance
+func NewCollection() *Collection {
+	return &Collection{
+		formats: make(map[string]*PaperFormat),
+	}
+}
+
+// Add adds the given paper format to this Collection,
+// replacing any paper format with the same XMLID.
+func (c *Collection) Add(pf *PaperFormat) {
+	c.Lock()
+	defer c.Unlock()
+	c.formats[pf.XMLID] = pf
+}
+
+// GetByXMLID returns the PaperFormat with the given xmlid or nil if it does not exist
+func (c *Collection) GetByXMLID(id string) *PaperFormat {
+	c.RLock()
+	defer c.RUnlock()
+	return c.formats[id]
+}
+
+// LoadFromEtree reads the paper format given as etree.Element
+// and adds it to this Collection.
+func (c *Collection) LoadFromEtree(element *etree.Element) {
+	xmlBytes, err := xmlutils.ElementToXML(element)
+	if err != nil {
+		log.Panic("Unable to convert element to XML", "error", err)
+	}
+	var pf PaperFormat
+	if err = xml.Unmarshal(xmlBytes, &pf); err != nil {
+		log.Panic("Unable to unmarshal element", "error", err, "bytes", string(xmlBytes))
+	}
+	if err = pf.check(); err != nil {
+		log.Panic("Invalid paper format", "id", pf.XMLID, "error", err)
+	}
+	c.Add(&pf)
+}
+
+// check returns an error if this paper format is not valid
+func (pf *PaperFormat) check() error {
+	if pf.XMLID == "" {
+		return fmt.Errorf("paper format has no id")
+	}
+	if pf.Format == "custom" && (pf.PageWidth <= 0 || pf.PageHeight <= 0) {
+		return fmt.Errorf("custom paper format must have a page width and height")
+	}
+	switch pf.Orientation {
+	case "", OrientationPortrait, OrientationLandscape:
+	default:
+		return fmt.Errorf("unknown orientation %s", pf.Orientation)
+	}
+	return nil
+}
+
+// LoadFromEtree reads the paper format given as etree.Element
+// and adds it to the paper formats registry.
+func LoadFromEtree(element *etree.Element) {
+	Registry.LoadFromEtree(element)
+}
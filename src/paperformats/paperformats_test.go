@@ -0,0 +1,44 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package paperformats
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/tools/xmlutils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var paperFormatDef = `
+<paperformat id="paperformat_label" name="Label" format="custom" page_width="100" page_height="62.5"
+             orientation="Landscape" margin_top="2" margin_bottom="2" margin_left="1.5" margin_right="1.5"
+             header_line="true" dpi="96"/>
+`
+
+func TestPaperFormats(t *testing.T) {
+	Convey("Testing paper formats", t, func() {
+		Convey("The default paper format should be A4", func() {
+			pf := Registry.GetByXMLID(DefaultPaperFormatID)
+			So(pf, ShouldNotBeNil)
+			So(pf.WkhtmltopdfArgs(), ShouldResemble, []string{"--page-size", "A4", "--orientation", "Portrait",
+				"--margin-top", "40mm", "--margin-bottom", "32mm", "--margin-left", "7mm", "--margin-right", "7mm",
+				"--header-spacing", "35", "--dpi", "90"})
+		})
+		Convey("Paper formats should be loaded from XML", func() {
+			element, _ := xmlutils.XMLToElement(paperFormatDef)
+			LoadFromEtree(element)
+			pf := Registry.GetByXMLID("paperformat_label")
+			So(pf, ShouldNotBeNil)
+			So(pf.Name, ShouldEqual, "Label")
+			So(pf.Orientation, ShouldEqual, OrientationLandscape)
+			So(pf.WkhtmltopdfArgs(), ShouldResemble, []string{"--page-width", "100mm", "--page-height", "62.5mm",
+				"--orientation", "Landscape", "--margin-top", "2mm", "--margin-bottom", "2mm",
+				"--margin-left", "1.5mm", "--margin-right", "1.5mm", "--header-line", "--dpi", "96"})
+		})
+		Convey("Invalid paper formats should panic", func() {
+			element, _ := xmlutils.XMLToElement(`<paperformat id="wrong" format="custom"/>`)
+			So(func() { LoadFromEtree(element) }, ShouldPanic)
+		})
+	})
+}
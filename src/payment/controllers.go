@@ -0,0 +1,226 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package payment
+
+import (
+	"crypto/subtle"
+	"errors"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// maxNotificationSize is the maximum size of the body of notifications
+const maxNotificationSize = 1 << 20
+
+// ErrInvalidNotification is returned by Notify if the
+// notification is rejected by the provider
+var ErrInvalidNotification = errors.New("invalid payment notification")
+
+// findTransaction returns the transaction with the given reference
+// if its access token is the given token, or an empty RecordSet.
+func findTransaction(env models.Environment, reference, token string) *models.RecordCollection {
+	txs := env.Pool("PaymentTransaction")
+	mi := txs.Model()
+	tx := txs.Search(mi.Field(mi.FieldName("Reference")).Equals(reference)).Limit(1)
+	if tx.IsEmpty() {
+		return tx
+	}
+	accessToken := tx.Get(mi.FieldName("AccessToken")).(string)
+	if token == "" || subtle.ConstantTimeCompare([]byte(accessToken), []byte(token)) != 1 {
+		return txs.Call("Browse", []int64{}).(models.RecordSet).Collection()
+	}
+	return tx
+}
+
+// Pay starts the payment of the transaction with the given reference and
+// access token in the given database. It returns the URL to which the
+// customer must be redirected, and false if there is no such transaction.
+//
+// Transactions that are not in the draft state are not authorized again: the
+// customer is redirected to their status page.
+func Pay(dbName, reference, token string) (string, bool, error) {
+	var (
+		redirectURL string
+		found       bool
+	)
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		tx := findTransaction(env, reference, token)
+		if tx.IsEmpty() {
+			return
+		}
+		found = true
+		if tx.Get(tx.Model().FieldName("State")).(string) == StateDraft {
+			redirectURL = tx.Call("Authorize").(string)
+		}
+		if redirectURL == "" {
+			redirectURL = transactionData(tx).ReturnURL
+		}
+	})
+	return redirectURL, found, err
+}
+
+// Notify applies the notification of the given request with the given body to
+// the provider with the given ID in the given database. It returns false if
+// the provider or the notified transaction does not exist, and
+// ErrInvalidNotification if the provider rejects the notification.
+func Notify(dbName string, providerID int64, req *http.Request, body []byte) (bool, error) {
+	var (
+		found   bool
+		invalid error
+	)
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		providers := env.Pool("PaymentProvider")
+		pmi := providers.Model()
+		provider := providers.Search(pmi.Field(models.ID).Equals(providerID).
+			And().Field(pmi.FieldName("State")).NotEquals(ProviderDisabled)).Limit(1)
+		if provider.IsEmpty() {
+			return
+		}
+		prov, ok := GetProvider(provider.Get(pmi.FieldName("Code")).(string))
+		if !ok {
+			return
+		}
+		update, err := prov.ParseNotification(req, body, provider.Call("Config").(Config))
+		if err != nil {
+			invalid = err
+			return
+		}
+		txs := env.Pool("PaymentTransaction")
+		mi := txs.Model()
+		tx := txs.Search(mi.Field(mi.FieldName("Reference")).Equals(update.Reference).
+			And().Field(mi.FieldName("Provider")).Equals(providerID)).Limit(1)
+		if tx.IsEmpty() {
+			return
+		}
+		found = true
+		tx.Call("ApplyUpdate", update)
+	})
+	if invalid != nil {
+		log.Warn("Payment notification rejected", "provider", providerID, "error", invalid)
+		return false, ErrInvalidNotification
+	}
+	return found, err
+}
+
+// pay is the controller of payment links. It redirects
+// the customer to the payment page of the provider.
+func pay(ctx *server.Context) {
+	reference := ctx.Param("reference")
+	token := ctx.Query("token")
+	if reference == "" || token == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	redirectURL, found, err := Pay(ctx.DBName(), reference, token)
+	if err != nil {
+		log.Warn("Unable to start payment", "reference", reference, "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		ctx.String(http.StatusNotFound, "This link is not valid anymore.")
+		return
+	}
+	ctx.Redirect(http.StatusSeeOther, redirectURL)
+}
+
+// statusPageTemplate is the template of the page to
+// which customers are redirected after their payment
+var statusPageTemplate = template.Must(template.New("payment").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Payment {{ .Reference }}</title></head>
+<body>
+<h1>Payment {{ .Reference }}</h1>
+<p>Status: {{ .State }}</p>
+{{ if .Message }}<p class="message">{{ .Message }}</p>{{ end }}
+{{ if .ReturnURL }}<p><a href="{{ .ReturnURL }}">Continue</a></p>{{ end }}
+</body></html>
+`))
+
+// statusPage holds the data of the status page template
+type statusPage struct {
+	Reference string
+	State     string
+	Message   string
+	ReturnURL string
+}
+
+// status is the controller of the page to which customers
+// are redirected after their payment.
+func status(ctx *server.Context) {
+	reference := ctx.Param("reference")
+	var (
+		page  statusPage
+		found bool
+	)
+	err := models.ExecuteInTenantEnvironment(ctx.DBName(), security.SuperUserID, func(env models.Environment) {
+		tx := findTransaction(env, reference, ctx.Query("token"))
+		if tx.IsEmpty() {
+			return
+		}
+		found = true
+		mi := tx.Model()
+		state := tx.Get(mi.FieldName("State")).(string)
+		page = statusPage{
+			Reference: reference,
+			State:     transactionStates[state],
+			Message:   tx.Get(mi.FieldName("StateMessage")).(string),
+			ReturnURL: tx.Get(mi.FieldName("ReturnURL")).(string),
+		}
+	})
+	if err != nil {
+		log.Warn("Unable to read transaction", "reference", reference, "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		ctx.String(http.StatusNotFound, "This link is not valid anymore.")
+		return
+	}
+	ctx.Status(http.StatusOK)
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	if err := statusPageTemplate.Execute(ctx.Writer, page); err != nil {
+		log.Warn("Unable to render payment status page", "error", err)
+	}
+}
+
+// notify is the controller of the webhooks of the providers
+func notify(ctx *server.Context) {
+	providerID, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, maxNotificationSize+1))
+	if err != nil || len(body) > maxNotificationSize {
+		ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+	found, err := Notify(ctx.DBName(), providerID, ctx.Request, body)
+	switch {
+	case err == ErrInvalidNotification:
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	case err != nil:
+		log.Warn("Unable to process payment notification", "provider", providerID, "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	case !found:
+		ctx.AbortWithStatus(http.StatusNotFound)
+	default:
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/payment")
+	grp.AddController(http.MethodGet, "/pay/:reference", pay)
+	grp.AddController(http.MethodGet, "/status/:reference", status)
+	grp.AddController(http.MethodPost, "/webhook/:id", notify)
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// TestProviderCode is the code of the test provider
+const TestProviderCode = "test"
+
+// TestSignatureHeader is the header holding the signature
+// of the notifications of the test provider
+const TestSignatureHeader = "X-Hexya-Test-Signature"
+
+// A TestNotification is the JSON body of the notifications of the test provider
+type TestNotification struct {
+	Reference string `json:"reference"`
+	State     string `json:"state"`
+	Message   string `json:"message,omitempty"`
+}
+
+// testProvider is a Provider that calls no gateway. It can only be used in
+// test mode: payments are authorized at once, without redirection, and its
+// notifications are signed with the HMAC-SHA256 of their body with the
+// webhook secret, in hexadecimal in the TestSignatureHeader header.
+type testProvider struct{}
+
+var _ Provider = testProvider{}
+
+// errTestOnly is returned by the test provider if it is not in test mode
+var errTestOnly = errors.New("the test payment provider can only be used in test mode")
+
+// Authorize authorizes the transaction at once
+func (testProvider) Authorize(tx Transaction, config Config) (Update, error) {
+	if !config.Test {
+		return Update{}, errTestOnly
+	}
+	return Update{State: StateAuthorized, ProviderReference: "TEST-" + tx.Reference}, nil
+}
+
+// Capture marks the transaction as done
+func (testProvider) Capture(tx Transaction, config Config) (Update, error) {
+	if !config.Test {
+		return Update{}, errTestOnly
+	}
+	return Update{State: StateDone}, nil
+}
+
+// Refund accepts all refunds
+func (testProvider) Refund(tx Transaction, amount float64, config Config) (Update, error) {
+	if !config.Test {
+		return Update{}, errTestOnly
+	}
+	return Update{}, nil
+}
+
+// ParseNotification checks the signature of the given TestNotification
+func (testProvider) ParseNotification(req *http.Request, body []byte, config Config) (Update, error) {
+	if !config.Test {
+		return Update{}, errTestOnly
+	}
+	signature, err := hex.DecodeString(req.Header.Get(TestSignatureHeader))
+	if err != nil || config.WebhookSecret == "" || !hmac.Equal(signature, TestSignature(config.WebhookSecret, body)) {
+		return Update{}, errors.New("invalid signature")
+	}
+	var notification TestNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return Update{}, err
+	}
+	return Update{Reference: notification.Reference, State: notification.State, Message: notification.Message}, nil
+}
+
+// TestSignature returns the signature of the given notification
+// body of the test provider with the given webhook secret
+func TestSignature(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
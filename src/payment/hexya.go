@@ -0,0 +1,45 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package payment is a Hexya module that collects online payments through
+// payment gateways, for e-commerce or invoicing modules.
+//
+// Each gateway is called by a Provider registered with RegisterProvider. A
+// PaymentProvider record holds the keys of an account of a gateway and
+// whether it is disabled, in test mode or enabled. The test provider, with the
+// TestProviderCode code, calls no gateway and can be used in test mode.
+//
+// A PaymentTransaction is an amount to be paid through a provider, possibly
+// for a document given by ResModel and ResID:
+//
+//   - PaymentURL returns the public link that redirects the customer to the
+//     payment page of the gateway, and then to the status page of the
+//     transaction, or to its ReturnURL.
+//   - The gateway notifies the new states of the transaction on the webhook of
+//     the provider, whose notifications are validated by ParseNotification.
+//   - Capture and Refund call the gateway for authorized and done transactions.
+//
+// PostProcess is called when a transaction is done. Modules that create
+// transactions extend it to confirm the documents they pay.
+package payment
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "payment"
+)
+
+func init() {
+	log = logging.GetLogger("payment")
+	declareModels()
+	RegisterProvider(TestProviderCode, testProvider{})
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package payment
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+// transactionStates is the selection of the states of transactions
+var transactionStates = types.Selection{
+	StateDraft:      "Draft",
+	StatePending:    "Pending",
+	StateAuthorized: "Authorized",
+	StateDone:       "Done",
+	StateCanceled:   "Canceled",
+	StateError:      "Error",
+	StateRefunded:   "Refunded",
+}
+
+func declareModels() {
+	provider := models.NewModel("PaymentProvider")
+	provider.SetDefaultOrder("Sequence", "Name")
+	provider.NewMethod("CheckCode", paymentProvider_CheckCode)
+	provider.NewMethod("Config", paymentProvider_Config)
+	provider.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true, Translate: true},
+		"Code": fields.Char{Required: true, Constraint: provider.Methods().MustGet("CheckCode"),
+			Help: "Code of the registered provider that calls the gateway (e.g. test)"},
+		"State": fields.Selection{Required: true,
+			Selection: types.Selection{ProviderDisabled: "Disabled", ProviderTest: "Test Mode", ProviderEnabled: "Enabled"},
+			Default:   models.DefaultValue(ProviderDisabled),
+			Help:      "In test mode, the sandbox of the gateway is called"},
+		"Sequence":  fields.Integer{Default: models.DefaultValue(10)},
+		"PublicKey": fields.Char{Help: "Public key or account identifier given by the gateway"},
+		"SecretKey": fields.Char{NoCopy: true, Help: "Secret key with which the gateway is called"},
+		"WebhookSecret": fields.Char{NoCopy: true,
+			Help: "Secret with which the gateway signs its notifications"},
+	})
+
+	tx := models.NewModel("PaymentTransaction")
+	tx.SetDefaultOrder("ID desc")
+	tx.NewMethod("PaymentURL", paymentTransaction_PaymentURL)
+	tx.NewMethod("Authorize", paymentTransaction_Authorize)
+	tx.NewMethod("Capture", paymentTransaction_Capture)
+	tx.NewMethod("Refund", paymentTransaction_Refund)
+	tx.NewMethod("Cancel", paymentTransaction_Cancel)
+	tx.NewMethod("ApplyUpdate", paymentTransaction_ApplyUpdate)
+	tx.NewMethod("PostProcess", paymentTransaction_PostProcess)
+	tx.AddFields(map[string]models.FieldDefinition{
+		"Reference": fields.Char{Required: true, Unique: true, NoCopy: true,
+			Default: func(env models.Environment) interface{} {
+				return newReference()
+			}},
+		"Provider": fields.Many2One{RelationModel: provider, Required: true, Index: true, OnDelete: models.Restrict},
+		"Amount":   fields.Float{Required: true},
+		"CurrencyCode": fields.Char{String: "Currency", Required: true,
+			Help: "ISO 4217 code of the currency of the amount (e.g. EUR)"},
+		"AmountRefunded": fields.Float{NoCopy: true, ReadOnly: true},
+		"State": fields.Selection{Required: true, Index: true, NoCopy: true, ReadOnly: true,
+			Selection: transactionStates,
+			Default:   models.DefaultValue(StateDraft)},
+		"StateMessage": fields.Text{NoCopy: true, ReadOnly: true,
+			Help: "Last message of the gateway about the transaction"},
+		"ProviderReference": fields.Char{NoCopy: true, ReadOnly: true, Index: true,
+			Help: "Reference of the transaction for the gateway"},
+		"ResModel": fields.Char{String: "Related Document Model", Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Index: true},
+		"ReturnURL": fields.Char{String: "Return URL",
+			Help: "URL to which the customer is redirected after the payment"},
+		"AccessToken": fields.Char{NoCopy: true,
+			Help: "Secret of the payment link of the transaction",
+			Default: func(env models.Environment) interface{} {
+				return newToken()
+			}},
+	})
+	provider.AddFields(map[string]models.FieldDefinition{
+		"Transactions": fields.One2Many{RelationModel: tx, ReverseFK: "Provider"},
+	})
+}
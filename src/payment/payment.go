@@ -0,0 +1,230 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package payment
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/settings"
+)
+
+// defaultBaseURL is the base URL of payment links
+// if the web.base.url parameter is not set
+const defaultBaseURL = "http://localhost:8080"
+
+// amountPrecision is the precision below which amounts are considered equal
+const amountPrecision = 1e-6
+
+// newToken returns a new random access token
+func newToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate access token", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newReference returns a new random transaction reference
+func newReference() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate transaction reference", "error", err)
+	}
+	return "TX-" + strings.ToUpper(hex.EncodeToString(b))
+}
+
+// validStates are the states that can be set by an Update
+var validStates = map[string]bool{
+	StatePending:    true,
+	StateAuthorized: true,
+	StateDone:       true,
+	StateCanceled:   true,
+	StateError:      true,
+	StateRefunded:   true,
+}
+
+// baseURL returns the base URL of the links of the given environment
+func baseURL(env models.Environment) string {
+	return strings.TrimSuffix(settings.GetParam(env, "web.base.url", defaultBaseURL), "/")
+}
+
+// transactionData returns the Transaction given to providers for the given record
+func transactionData(rc *models.RecordCollection) Transaction {
+	mi := rc.Model()
+	base := baseURL(rc.Env())
+	db := url.QueryEscape(rc.Env().DBName())
+	provider := rc.Get(mi.FieldName("Provider")).(models.RecordSet)
+	reference := rc.Get(mi.FieldName("Reference")).(string)
+	return Transaction{
+		ID:                rc.Ids()[0],
+		Reference:         reference,
+		Amount:            rc.Get(mi.FieldName("Amount")).(float64),
+		AmountRefunded:    rc.Get(mi.FieldName("AmountRefunded")).(float64),
+		CurrencyCode:      rc.Get(mi.FieldName("CurrencyCode")).(string),
+		ProviderReference: rc.Get(mi.FieldName("ProviderReference")).(string),
+		State:             rc.Get(mi.FieldName("State")).(string),
+		ReturnURL: fmt.Sprintf("%s/payment/status/%s?token=%s&db=%s", base, url.PathEscape(reference),
+			url.QueryEscape(rc.Get(mi.FieldName("AccessToken")).(string)), db),
+		NotifyURL: fmt.Sprintf("%s/payment/webhook/%d?db=%s", base, provider.Ids()[0], db),
+	}
+}
+
+// providerOf returns the Provider and the Config of the provider
+// of the given transaction. It panics if the provider is disabled.
+func providerOf(rc *models.RecordCollection) (Provider, Config) {
+	provider := rc.Get(rc.Model().FieldName("Provider")).(models.RecordSet).Collection().Sudo()
+	pmi := provider.Model()
+	code := provider.Get(pmi.FieldName("Code")).(string)
+	if provider.Get(pmi.FieldName("State")).(string) == ProviderDisabled {
+		log.Panic("Payment provider is disabled", "provider", provider.Ids()[0], "code", code)
+	}
+	prov, ok := GetProvider(code)
+	if !ok {
+		log.Panic("Unknown payment provider", "code", code)
+	}
+	return prov, provider.Call("Config").(Config)
+}
+
+// CheckCode checks that the codes of the providers are
+// codes of registered providers
+func paymentProvider_CheckCode(rc *models.RecordCollection) {
+	for _, rec := range rc.Records() {
+		code := rec.Get(rc.Model().FieldName("Code")).(string)
+		if _, ok := GetProvider(code); !ok {
+			log.Panic("Unknown payment provider", "code", code, "known", ProviderCodes())
+		}
+	}
+}
+
+// Config returns the settings with which the gateway of this provider is called
+func paymentProvider_Config(rc *models.RecordCollection) Config {
+	rc.EnsureOne()
+	mi := rc.Model()
+	return Config{
+		ProviderID:    rc.Ids()[0],
+		Test:          rc.Get(mi.FieldName("State")).(string) == ProviderTest,
+		PublicKey:     rc.Get(mi.FieldName("PublicKey")).(string),
+		SecretKey:     rc.Get(mi.FieldName("SecretKey")).(string),
+		WebhookSecret: rc.Get(mi.FieldName("WebhookSecret")).(string),
+	}
+}
+
+// PaymentURL returns the public link with which the customer pays this transaction
+func paymentTransaction_PaymentURL(rc *models.RecordCollection) string {
+	rc.EnsureOne()
+	mi := rc.Model()
+	return fmt.Sprintf("%s/payment/pay/%s?token=%s&db=%s", baseURL(rc.Env()),
+		url.PathEscape(rc.Get(mi.FieldName("Reference")).(string)),
+		url.QueryEscape(rc.Get(mi.FieldName("AccessToken")).(string)),
+		url.QueryEscape(rc.Env().DBName()))
+}
+
+// Authorize starts the payment of this draft transaction with its provider.
+// It returns the URL to which the customer must be redirected to pay, or an
+// empty string if the payment does not need the customer.
+func paymentTransaction_Authorize(rc *models.RecordCollection) string {
+	rc.EnsureOne()
+	if state := rc.Get(rc.Model().FieldName("State")).(string); state != StateDraft {
+		log.Panic("Only draft transactions can be authorized", "transaction", rc.Ids()[0], "state", state)
+	}
+	provider, config := providerOf(rc)
+	update, err := provider.Authorize(transactionData(rc), config)
+	if err != nil {
+		log.Panic("Unable to authorize transaction", "transaction", rc.Ids()[0], "error", err)
+	}
+	if update.State == "" && update.RedirectURL != "" {
+		update.State = StatePending
+	}
+	rc.Call("ApplyUpdate", update)
+	return update.RedirectURL
+}
+
+// Capture captures the authorized amount of this transaction
+func paymentTransaction_Capture(rc *models.RecordCollection) {
+	for _, rec := range rc.Records() {
+		if state := rec.Get(rc.Model().FieldName("State")).(string); state != StateAuthorized {
+			log.Panic("Only authorized transactions can be captured", "transaction", rec.Ids()[0], "state", state)
+		}
+		provider, config := providerOf(rec)
+		update, err := provider.Capture(transactionData(rec), config)
+		if err != nil {
+			log.Panic("Unable to capture transaction", "transaction", rec.Ids()[0], "error", err)
+		}
+		rec.Call("ApplyUpdate", update)
+	}
+}
+
+// Refund refunds the given amount of this done transaction. The
+// transaction is in the refunded state once it is fully refunded.
+func paymentTransaction_Refund(rc *models.RecordCollection, amount float64) {
+	rc.EnsureOne()
+	mi := rc.Model()
+	if state := rc.Get(mi.FieldName("State")).(string); state != StateDone {
+		log.Panic("Only done transactions can be refunded", "transaction", rc.Ids()[0], "state", state)
+	}
+	refundable := rc.Get(mi.FieldName("Amount")).(float64) - rc.Get(mi.FieldName("AmountRefunded")).(float64)
+	if amount <= 0 || amount > refundable+amountPrecision {
+		log.Panic("Invalid refund amount", "transaction", rc.Ids()[0], "amount", amount, "refundable", refundable)
+	}
+	provider, config := providerOf(rc)
+	update, err := provider.Refund(transactionData(rc), amount, config)
+	if err != nil {
+		log.Panic("Unable to refund transaction", "transaction", rc.Ids()[0], "error", err)
+	}
+	if update.State == StateError {
+		rc.Call("ApplyUpdate", Update{Message: update.Message})
+		return
+	}
+	update.State = StateDone
+	if math.Abs(refundable-amount) < amountPrecision {
+		update.State = StateRefunded
+	}
+	rc.Sudo().Set(mi.FieldName("AmountRefunded"), rc.Get(mi.FieldName("AmountRefunded")).(float64)+amount)
+	rc.Call("ApplyUpdate", update)
+}
+
+// Cancel cancels the transactions of this RecordSet that are not done yet
+func paymentTransaction_Cancel(rc *models.RecordCollection) {
+	for _, rec := range rc.Records() {
+		switch state := rec.Get(rc.Model().FieldName("State")).(string); state {
+		case StateDone, StateRefunded:
+			log.Panic("Done transactions cannot be canceled", "transaction", rec.Ids()[0], "state", state)
+		}
+		rec.Call("ApplyUpdate", Update{State: StateCanceled})
+	}
+}
+
+// ApplyUpdate sets the state of this transaction given by its provider.
+// PostProcess is called when the transaction becomes done.
+func paymentTransaction_ApplyUpdate(rc *models.RecordCollection, update Update) {
+	rc.EnsureOne()
+	mi := rc.Model()
+	if update.State != "" && !validStates[update.State] {
+		log.Panic("Invalid transaction state", "transaction", rc.Ids()[0], "state", update.State)
+	}
+	oldState := rc.Get(mi.FieldName("State")).(string)
+	data := models.NewModelData(mi).Set(mi.FieldName("StateMessage"), update.Message)
+	if update.State != "" {
+		data.Set(mi.FieldName("State"), update.State)
+	}
+	if update.ProviderReference != "" {
+		data.Set(mi.FieldName("ProviderReference"), update.ProviderReference)
+	}
+	rc.Sudo().Call("Write", data)
+	if update.State == StateDone && oldState != StateDone {
+		rc.Call("PostProcess")
+	}
+}
+
+// PostProcess is called when this transaction is done, to confirm the
+// document it pays. It does nothing by default and is meant to be extended
+// by the modules that create transactions.
+func paymentTransaction_PostProcess(_ *models.RecordCollection) {}
@@ -0,0 +1,142 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package payment
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/tests"
+	_ "github.com/lib/pq"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// postProcessed are the references of the transactions given to PostProcess
+var postProcessed []string
+
+func TestMain(m *testing.M) {
+	models.Registry.MustGet("PaymentTransaction").Methods().MustGet("PostProcess").Extend(
+		func(rc *models.RecordCollection) {
+			postProcessed = append(postProcessed, rc.Get(rc.Model().FieldName("Reference")).(string))
+			rc.Super().Call("PostProcess")
+		})
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+
+func TestPayment(t *testing.T) {
+	Convey("Testing payment providers", t, func() {
+		Convey("The test provider should be registered", func() {
+			prov, ok := GetProvider(TestProviderCode)
+			So(ok, ShouldBeTrue)
+			So(prov, ShouldHaveSameTypeAs, testProvider{})
+			So(ProviderCodes(), ShouldContain, TestProviderCode)
+			So(func() { RegisterProvider(TestProviderCode, testProvider{}) }, ShouldPanic)
+		})
+		Convey("The test provider should only work in test mode", func() {
+			tx := Transaction{Reference: "TX-1", Amount: 10, CurrencyCode: "EUR"}
+			_, err := testProvider{}.Authorize(tx, Config{})
+			So(err, ShouldEqual, errTestOnly)
+			update, err := testProvider{}.Authorize(tx, Config{Test: true})
+			So(err, ShouldBeNil)
+			So(update.State, ShouldEqual, StateAuthorized)
+			So(update.ProviderReference, ShouldEqual, "TEST-TX-1")
+			update, err = testProvider{}.Capture(tx, Config{Test: true})
+			So(err, ShouldBeNil)
+			So(update.State, ShouldEqual, StateDone)
+		})
+		Convey("Notifications of the test provider should be signed", func() {
+			config := Config{Test: true, WebhookSecret: "secret"}
+			body := []byte(`{"reference":"TX-1","state":"done","message":"Paid"}`)
+			req := httptest.NewRequest(http.MethodPost, "/payment/webhook/1", strings.NewReader(string(body)))
+			_, err := testProvider{}.ParseNotification(req, body, config)
+			So(err, ShouldNotBeNil)
+			req.Header.Set(TestSignatureHeader, hex.EncodeToString(TestSignature("other", body)))
+			_, err = testProvider{}.ParseNotification(req, body, config)
+			So(err, ShouldNotBeNil)
+			req.Header.Set(TestSignatureHeader, hex.EncodeToString(TestSignature("secret", body)))
+			update, err := testProvider{}.ParseNotification(req, body, config)
+			So(err, ShouldBeNil)
+			So(update, ShouldResemble, Update{Reference: "TX-1", State: StateDone, Message: "Paid"})
+		})
+		Convey("Transaction references and tokens should be random", func() {
+			So(newReference(), ShouldStartWith, "TX-")
+			So(newReference(), ShouldNotEqual, newReference())
+			So(newToken(), ShouldNotEqual, newToken())
+		})
+	})
+}
+
+func TestPaymentTransactions(t *testing.T) {
+	Convey("Testing payment transactions", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			postProcessed = nil
+			providers := env.Pool("PaymentProvider")
+			pmi := providers.Model()
+			provider := providers.Call("Create", models.NewModelData(pmi).
+				Set(pmi.FieldName("Name"), "Test").
+				Set(pmi.FieldName("Code"), TestProviderCode).
+				Set(pmi.FieldName("State"), ProviderTest)).(models.RecordSet).Collection()
+			transactions := env.Pool("PaymentTransaction")
+			mi := transactions.Model()
+			tx := transactions.Call("Create", models.NewModelData(mi).
+				Set(mi.FieldName("Provider"), provider).
+				Set(mi.FieldName("Amount"), 100.0).
+				Set(mi.FieldName("CurrencyCode"), "EUR")).(models.RecordSet).Collection()
+			reference := tx.Get(mi.FieldName("Reference")).(string)
+			Convey("New transactions should be drafts with a payment link", func() {
+				So(reference, ShouldStartWith, "TX-")
+				So(tx.Get(mi.FieldName("State")), ShouldEqual, StateDraft)
+				So(tx.Call("PaymentURL"), ShouldStartWith, defaultBaseURL+"/payment/pay/"+reference+"?token=")
+			})
+			Convey("Providers with unknown codes should be refused", func() {
+				So(func() {
+					providers.Call("Create", models.NewModelData(pmi).
+						Set(pmi.FieldName("Name"), "Unknown").
+						Set(pmi.FieldName("Code"), "unknown"))
+				}, ShouldPanic)
+			})
+			Convey("Transactions of disabled providers should not be authorized", func() {
+				provider.Set(pmi.FieldName("State"), ProviderDisabled)
+				So(func() { tx.Call("Authorize") }, ShouldPanic)
+			})
+			Convey("Authorized transactions should be done once captured", func() {
+				So(tx.Call("Authorize"), ShouldEqual, "")
+				So(tx.Get(mi.FieldName("State")), ShouldEqual, StateAuthorized)
+				So(tx.Get(mi.FieldName("ProviderReference")), ShouldEqual, "TEST-"+reference)
+				So(postProcessed, ShouldBeEmpty)
+				So(func() { tx.Call("Authorize") }, ShouldPanic)
+				tx.Call("Capture")
+				So(tx.Get(mi.FieldName("State")), ShouldEqual, StateDone)
+				So(postProcessed, ShouldResemble, []string{reference})
+				So(func() { tx.Call("Cancel") }, ShouldPanic)
+				Convey("Done transactions should be refunded up to their amount", func() {
+					So(func() { tx.Call("Refund", 150.0) }, ShouldPanic)
+					tx.Call("Refund", 40.0)
+					So(tx.Get(mi.FieldName("State")), ShouldEqual, StateDone)
+					So(tx.Get(mi.FieldName("AmountRefunded")), ShouldEqual, 40)
+					tx.Call("Refund", 60.0)
+					So(tx.Get(mi.FieldName("State")), ShouldEqual, StateRefunded)
+					So(tx.Get(mi.FieldName("AmountRefunded")), ShouldEqual, 100)
+					So(postProcessed, ShouldHaveLength, 1)
+				})
+			})
+			Convey("Draft transactions should be canceled", func() {
+				tx.Call("Cancel")
+				So(tx.Get(mi.FieldName("State")), ShouldEqual, StateCanceled)
+				So(func() { tx.Call("Capture") }, ShouldPanic)
+			})
+			Convey("Updates with unknown states should be refused", func() {
+				So(func() { tx.Call("ApplyUpdate", Update{State: "paid"}) }, ShouldPanic)
+				tx.Call("ApplyUpdate", Update{State: StatePending, Message: "Waiting for the bank"})
+				So(tx.Get(mi.FieldName("State")), ShouldEqual, StatePending)
+				So(tx.Get(mi.FieldName("StateMessage")), ShouldEqual, "Waiting for the bank")
+			})
+		}), ShouldBeNil)
+	})
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package payment
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// States of transactions
+const (
+	StateDraft      = "draft"
+	StatePending    = "pending"
+	StateAuthorized = "authorized"
+	StateDone       = "done"
+	StateCanceled   = "canceled"
+	StateError      = "error"
+	StateRefunded   = "refunded"
+)
+
+// States of providers
+const (
+	ProviderDisabled = "disabled"
+	ProviderTest     = "test"
+	ProviderEnabled  = "enabled"
+)
+
+// A Config holds the settings of a PaymentProvider record
+// with which a Provider calls its gateway.
+type Config struct {
+	ProviderID int64
+	// Test is true if the provider is in test mode and must
+	// call the sandbox of its gateway.
+	Test          bool
+	PublicKey     string
+	SecretKey     string
+	WebhookSecret string
+}
+
+// A Transaction holds the data of a PaymentTransaction
+// record that is given to a Provider.
+type Transaction struct {
+	ID                int64
+	Reference         string
+	Amount            float64
+	AmountRefunded    float64
+	CurrencyCode      string
+	ProviderReference string
+	State             string
+	// ReturnURL is the URL to which the gateway must redirect the customer
+	// after the payment.
+	ReturnURL string
+	// NotifyURL is the URL to which the gateway must send its notifications
+	NotifyURL string
+}
+
+// An Update is the new state of a transaction returned
+// by a Provider or notified by its gateway.
+type Update struct {
+	// Reference is the reference of the updated transaction.
+	// It must only be set in notifications.
+	Reference         string
+	ProviderReference string
+	// State is the new state of the transaction. If empty, the state is
+	// not changed.
+	State   string
+	Message string
+	// RedirectURL is the URL to which the customer must be redirected to pay.
+	// It is only used by Authorize.
+	RedirectURL string
+}
+
+// A Provider is the client of a payment gateway.
+//
+// Operations return an error if the gateway cannot be called. Refused payments
+// are not errors: they are reported by an Update with the StateError state.
+type Provider interface {
+	// Authorize starts the payment of the given transaction. If the customer
+	// must pay on the site of the gateway, the RedirectURL of the result is
+	// the address of the payment page.
+	Authorize(tx Transaction, config Config) (Update, error)
+	// Capture captures the authorized amount of the given transaction
+	Capture(tx Transaction, config Config) (Update, error)
+	// Refund refunds the given amount of the given transaction
+	Refund(tx Transaction, amount float64, config Config) (Update, error)
+	// ParseNotification checks that the given request of the webhook of the
+	// provider is sent by its gateway and returns the Update it notifies.
+	ParseNotification(req *http.Request, body []byte, config Config) (Update, error)
+}
+
+// providers are the registered providers by code
+var providers struct {
+	sync.RWMutex
+	byCode map[string]Provider
+}
+
+// RegisterProvider registers the given Provider under the given code. The
+// Code of PaymentProvider records must be the code of a registered Provider.
+// It panics if a provider is already registered with this code.
+func RegisterProvider(code string, provider Provider) {
+	providers.Lock()
+	defer providers.Unlock()
+	if _, exists := providers.byCode[code]; exists {
+		log.Panic("Payment provider already registered", "code", code)
+	}
+	if providers.byCode == nil {
+		providers.byCode = make(map[string]Provider)
+	}
+	providers.byCode[code] = provider
+}
+
+// GetProvider returns the Provider registered with the given code
+func GetProvider(code string) (Provider, bool) {
+	providers.RLock()
+	defer providers.RUnlock()
+	provider, ok := providers.byCode[code]
+	return provider, ok
+}
+
+// ProviderCodes returns the codes of the registered providers, sorted
+func ProviderCodes() []string {
+	providers.RLock()
+	defer providers.RUnlock()
+	res := make([]string, 0, len(providers.byCode))
+	for code := range providers.byCode {
+		res = append(res, code)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package portal
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// SharedRecord is the read-only view of a
+// record sent to the holders of a share link
+type SharedRecord struct {
+	Model  string                 `json:"model"`
+	ID     int64                  `json:"id"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// portalValue returns the value of the given field of the given record as
+// displayed on the portal. Relations are rendered by the display name of the
+// related records, so that the holders of a link see no other record.
+func portalValue(rec *models.RecordCollection, field *models.FieldInfo) interface{} {
+	value := rec.Get(rec.Model().FieldName(field.Name))
+	related, ok := value.(models.RecordSet)
+	if !ok {
+		return value
+	}
+	names := make([]string, 0, related.Len())
+	for _, rel := range related.Collection().Records() {
+		names = append(names, rel.Call("NameGet").(string))
+	}
+	if field.Type.Is2OneRelationType() {
+		if len(names) == 0 {
+			return nil
+		}
+		return names[0]
+	}
+	return names
+}
+
+// GetSharedRecord returns the read-only view of the record designated by the
+// given share token. The returned boolean is false if the token is invalid,
+// expired or revoked.
+func GetSharedRecord(token string) (SharedRecord, bool, error) {
+	share, ok := ParseShareToken(token)
+	if !ok {
+		return SharedRecord{}, false, nil
+	}
+	mi, ok := models.Registry.Get(share.Model)
+	if !ok {
+		return SharedRecord{}, false, nil
+	}
+	if _, ok := mi.Methods().Get("PortalFields"); !ok {
+		return SharedRecord{}, false, nil
+	}
+	var res SharedRecord
+	var found bool
+	err := models.ExecuteInTenantEnvironment(share.DBName, security.SuperUserID, func(env models.Environment) {
+		rec := env.Pool(mi.Name()).Search(mi.Field(models.ID).Equals(share.ID))
+		if rec.IsEmpty() {
+			return
+		}
+		accessToken := rec.Get(mi.FieldName("AccessToken")).(string)
+		if accessToken == "" || subtle.ConstantTimeCompare([]byte(accessToken), []byte(share.AccessToken)) != 1 {
+			return
+		}
+		found = true
+		res = SharedRecord{Model: mi.Name(), ID: share.ID, Values: make(map[string]interface{})}
+		var fieldNames []models.FieldName
+		for _, name := range rec.Call("PortalFields").([]string) {
+			if _, ok := mi.Fields().Get(name); !ok {
+				log.Warn("Unknown portal field", "model", mi.Name(), "field", name)
+				continue
+			}
+			fieldNames = append(fieldNames, mi.FieldName(name))
+		}
+		if len(fieldNames) == 0 {
+			return
+		}
+		for json, field := range mi.FieldsGet(fieldNames...) {
+			res.Values[json] = portalValue(rec.Collection(), field)
+		}
+	})
+	return res, found, err
+}
+
+// share is the controller of share links. It returns the
+// read-only view of the record designated by the token.
+func share(ctx *server.Context) {
+	token := ctx.Param("token")
+	if token == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	res, found, err := GetSharedRecord(token)
+	if err != nil {
+		log.Warn("Unable to read shared record", "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		ctx.String(http.StatusNotFound, "This link is not valid anymore.")
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/portal")
+	grp.AddController(http.MethodGet, "/share/:token", share)
+}
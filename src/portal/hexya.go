@@ -0,0 +1,61 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package portal is a Hexya module that gives customers access to
+// their records, such as orders or invoices.
+//
+// Models whose records can be shared inherit the PortalMixin model:
+//
+//	h.Invoice().InheritModel(h.PortalMixin())
+//
+// Records can be shared in two ways:
+//
+//   - PortalShareURL returns a public link holding a signed share token. Anyone
+//     with the link can see a read-only view of the fields of the record returned
+//     by PortalFields. Shares are revoked with PortalRevokeShares.
+//   - PortalGrantAccess gives users of the GroupPortal group read access to the
+//     record through record rules.
+package portal
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "portal"
+	// GroupPortalID is the ID of the group of portal users
+	GroupPortalID = "portal"
+)
+
+// GroupPortal is the group of the users who access
+// the records shared with them on the portal
+var GroupPortal *security.Group
+
+// addUserField adds the user with whom a PortalAccess shares its record
+func addUserField() {
+	user := models.Registry.MustGet("User")
+	access := models.Registry.MustGet("PortalAccess")
+	access.AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{RelationModel: user, Required: true, Index: true, OnDelete: models.Cascade},
+	})
+	access.AddSQLConstraint("access_uniq", "unique(res_model, res_id, user_id)",
+		"A record can be shared with a user only once")
+}
+
+func init() {
+	log = logging.GetLogger("portal")
+	declareModels()
+	GroupPortal = security.Registry.NewGroup(GroupPortalID, "Portal")
+	server.RegisterModule(&server.Module{
+		Name:     MODULE_NAME,
+		PreInit:  addUserField,
+		PostInit: addPortalRules,
+	})
+}
@@ -0,0 +1,32 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package portal
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	access := models.NewModel("PortalAccess")
+	access.AddFields(map[string]models.FieldDefinition{
+		"ResModel": fields.Char{String: "Related Document Model", Required: true, Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Required: true, Index: true},
+	})
+
+	portalMixin := models.NewMixinModel("PortalMixin")
+	portalMixin.NewMethod("PortalFields", portalMixin_PortalFields)
+	portalMixin.NewMethod("PortalShareToken", portalMixin_PortalShareToken)
+	portalMixin.NewMethod("PortalShareURL", portalMixin_PortalShareURL)
+	portalMixin.NewMethod("PortalRevokeShares", portalMixin_PortalRevokeShares)
+	portalMixin.NewMethod("PortalGrantAccess", portalMixin_PortalGrantAccess)
+	portalMixin.NewMethod("PortalRevokeAccess", portalMixin_PortalRevokeAccess)
+	portalMixin.AddFields(map[string]models.FieldDefinition{
+		"AccessToken": fields.Char{String: "Security Token", NoCopy: true,
+			Help: "Secret of the share links of the record. Changing it revokes all its links.",
+			Default: func(env models.Environment) interface{} {
+				return newAccessToken()
+			}},
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package portal
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/auth"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/settings"
+)
+
+// ShareValidity is the duration during which share links are valid
+var ShareValidity = 30 * 24 * time.Hour
+
+// sharePurpose is the purpose field of share tokens
+const sharePurpose = "portal_share"
+
+// portalRuleName is the name of the record rule that gives portal
+// users read access to the records shared with them
+const portalRuleName = "portal_access"
+
+// defaultBaseURL is the base URL of share links
+// if the web.base.url parameter is not set
+const defaultBaseURL = "http://localhost:8080"
+
+// newAccessToken returns a new random access token
+func newAccessToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate access token", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// A Share is the record designated by a share token
+type Share struct {
+	DBName      string
+	Model       string
+	ID          int64
+	AccessToken string
+}
+
+// shareToken returns the signed token of the given share, valid until expiry
+func shareToken(share Share, expiry time.Time) string {
+	return auth.SignedToken(expiry, sharePurpose, share.DBName, share.Model, strconv.FormatInt(share.ID, 10), share.AccessToken)
+}
+
+// ParseShareToken returns the share designated by the given token.
+// The returned boolean is false if the token is invalid or expired.
+//
+// The token is only checked against its signature: the access token of the
+// share must still be compared to the AccessToken of the record.
+func ParseShareToken(token string) (Share, bool) {
+	fields, ok := auth.ParseSignedToken(token)
+	if !ok || len(fields) != 5 || fields[0] != sharePurpose {
+		return Share{}, false
+	}
+	id, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return Share{}, false
+	}
+	return Share{DBName: fields[1], Model: fields[2], ID: id, AccessToken: fields[4]}, true
+}
+
+// SharedRecordIDs returns the IDs of the records of the given model that
+// are shared with the user with the given ID through PortalGrantAccess.
+func SharedRecordIDs(env models.Environment, modelName string, uid int64) []int64 {
+	accesses := env.Pool("PortalAccess").Sudo()
+	mi := accesses.Model()
+	accesses = accesses.Search(mi.Field(mi.FieldName("ResModel")).Equals(modelName).
+		And().Field(mi.FieldName("User")).Equals(uid))
+	var res []int64
+	for _, access := range accesses.Records() {
+		res = append(res, access.Get(mi.FieldName("ResID")).(int64))
+	}
+	return res
+}
+
+// addPortalRules gives the users of GroupPortal read access to the records of
+// the models inheriting PortalMixin that are shared with them.
+func addPortalRules() {
+	for _, model := range models.Registry.All() {
+		if model.IsMixin() {
+			continue
+		}
+		if _, ok := model.Methods().Get("PortalShareToken"); !ok {
+			continue
+		}
+		modelName := model.Name()
+		model.AddRecordRule(&models.RecordRule{
+			Name:  portalRuleName,
+			Group: GroupPortal,
+			Condition: model.Field(models.ID).In(func(rs models.RecordSet) []int64 {
+				ids := SharedRecordIDs(rs.Env(), modelName, rs.Env().Uid())
+				if len(ids) == 0 {
+					// ID in [] => ID = -1
+					return []int64{-1}
+				}
+				return ids
+			}),
+			Perms: security.Read,
+		})
+		model.Methods().MustGet("Load").AllowGroup(GroupPortal)
+	}
+}
+
+// PortalFields returns the names of the fields of the record that are
+// displayed on the public page of its share links. It returns only the
+// display name by default and is meant to be overridden.
+func portalMixin_PortalFields(_ *models.RecordCollection) []string {
+	return []string{"DisplayName"}
+}
+
+// PortalShareToken returns a new share token of this record,
+// valid for ShareValidity.
+func portalMixin_PortalShareToken(rc *models.RecordCollection) string {
+	rc.EnsureOne()
+	fieldName := rc.Model().FieldName("AccessToken")
+	accessToken := rc.Get(fieldName).(string)
+	if accessToken == "" {
+		// Records created before the module was installed have no token
+		accessToken = newAccessToken()
+		rc.Sudo().Set(fieldName, accessToken)
+	}
+	return shareToken(Share{
+		DBName:      rc.Env().DBName(),
+		Model:       rc.ModelName(),
+		ID:          rc.Ids()[0],
+		AccessToken: accessToken,
+	}, time.Now().Add(ShareValidity))
+}
+
+// PortalShareURL returns a new public link to the read-only
+// view of this record, valid for ShareValidity.
+func portalMixin_PortalShareURL(rc *models.RecordCollection) string {
+	token := rc.Call("PortalShareToken").(string)
+	baseURL := settings.GetParam(rc.Env(), "web.base.url", defaultBaseURL)
+	return fmt.Sprintf("%s/portal/share/%s?db=%s", baseURL, url.PathEscape(token), url.QueryEscape(rc.Env().DBName()))
+}
+
+// PortalRevokeShares invalidates all the share links of the records of this RecordSet
+func portalMixin_PortalRevokeShares(rc *models.RecordCollection) {
+	for _, rec := range rc.Records() {
+		rec.Sudo().Set(rc.Model().FieldName("AccessToken"), newAccessToken())
+	}
+}
+
+// PortalGrantAccess gives read access to the records of this RecordSet to the
+// portal users with the given IDs. Users who already have access are ignored.
+func portalMixin_PortalGrantAccess(rc *models.RecordCollection, userIDs []int64) {
+	accesses := rc.Env().Pool("PortalAccess").Sudo()
+	mi := accesses.Model()
+	for _, rec := range rc.Records() {
+		existing := make(map[int64]bool)
+		for _, access := range accesses.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+			And().Field(mi.FieldName("ResID")).Equals(rec.Ids()[0])).Records() {
+			existing[access.Get(mi.FieldName("User")).(models.RecordSet).Ids()[0]] = true
+		}
+		for _, uid := range userIDs {
+			if existing[uid] {
+				continue
+			}
+			accesses.Call("Create", models.NewModelData(mi).
+				Set(mi.FieldName("ResModel"), rc.ModelName()).
+				Set(mi.FieldName("ResID"), rec.Ids()[0]).
+				Set(mi.FieldName("User"), rc.Env().Pool("User").Call("BrowseOne", uid)))
+		}
+	}
+}
+
+// PortalRevokeAccess removes the read access to the records of
+// this RecordSet of the portal users with the given IDs.
+func portalMixin_PortalRevokeAccess(rc *models.RecordCollection, userIDs []int64) {
+	accesses := rc.Env().Pool("PortalAccess").Sudo()
+	mi := accesses.Model()
+	accesses.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("ResID")).In(rc.Ids()).
+		And().Field(mi.FieldName("User")).In(userIDs)).Call("Unlink")
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package portal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/auth"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/tests"
+	_ "github.com/lib/pq"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMain(m *testing.M) {
+	// PortalAccess references the User model
+	models.NewModel("User").AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	invoice := models.NewModel("PortalTestInvoice")
+	invoice.InheritModel(models.Registry.MustGet("PortalMixin"))
+	invoice.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+
+func TestPortal(t *testing.T) {
+	Convey("Testing portal shares", t, func() {
+		share := Share{DBName: "hexya", Model: "Invoice", ID: 12, AccessToken: newAccessToken()}
+		Convey("Share tokens should designate their record", func() {
+			res, ok := ParseShareToken(shareToken(share, time.Now().Add(time.Hour)))
+			So(ok, ShouldBeTrue)
+			So(res, ShouldResemble, share)
+		})
+		Convey("Expired or tampered share tokens should be rejected", func() {
+			_, ok := ParseShareToken(shareToken(share, time.Now().Add(-time.Second)))
+			So(ok, ShouldBeFalse)
+			_, ok = ParseShareToken(shareToken(share, time.Now().Add(time.Hour))[1:])
+			So(ok, ShouldBeFalse)
+		})
+		Convey("Signed tokens of other features should be rejected", func() {
+			token := auth.SignedToken(time.Now().Add(time.Hour), "other", "hexya", "Invoice", "12", share.AccessToken)
+			_, ok := ParseShareToken(token)
+			So(ok, ShouldBeFalse)
+		})
+		Convey("The portal group should be registered", func() {
+			So(security.Registry.GetGroup(GroupPortalID), ShouldEqual, GroupPortal)
+		})
+	})
+}
+
+func TestPortalMixin(t *testing.T) {
+	Convey("Testing PortalMixin", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			invoices := env.Pool("PortalTestInvoice")
+			mi := invoices.Model()
+			invoice := invoices.Call("Create", models.NewModelData(mi).Set(mi.FieldName("Name"), "INV/001")).(models.RecordSet).Collection()
+			accessToken := invoice.Get(mi.FieldName("AccessToken")).(string)
+			Convey("Share tokens should designate the record with its access token", func() {
+				So(accessToken, ShouldNotBeEmpty)
+				share, ok := ParseShareToken(invoice.Call("PortalShareToken").(string))
+				So(ok, ShouldBeTrue)
+				So(share, ShouldResemble, Share{DBName: env.DBName(), Model: "PortalTestInvoice", ID: invoice.Ids()[0], AccessToken: accessToken})
+				So(invoice.Call("PortalShareURL"), ShouldStartWith, defaultBaseURL+"/portal/share/")
+				So(invoice.Call("PortalFields"), ShouldResemble, []string{"DisplayName"})
+			})
+			Convey("Revoking shares should change the access token of the record", func() {
+				invoice.Call("PortalRevokeShares")
+				newToken := invoice.Get(mi.FieldName("AccessToken")).(string)
+				So(newToken, ShouldNotBeEmpty)
+				So(newToken, ShouldNotEqual, accessToken)
+				share, _ := ParseShareToken(invoice.Call("PortalShareToken").(string))
+				So(share.AccessToken, ShouldEqual, newToken)
+			})
+			Convey("Granting access should share the record with the given users", func() {
+				users := env.Pool("User")
+				umi := users.Model()
+				alice := users.Call("Create", models.NewModelData(umi).Set(umi.FieldName("Name"), "Alice")).(models.RecordSet).Ids()[0]
+				bob := users.Call("Create", models.NewModelData(umi).Set(umi.FieldName("Name"), "Bob")).(models.RecordSet).Ids()[0]
+				invoice.Call("PortalGrantAccess", []int64{alice, bob})
+				invoice.Call("PortalGrantAccess", []int64{alice})
+				So(env.Pool("PortalAccess").SearchAll().Len(), ShouldEqual, 2)
+				So(SharedRecordIDs(env, "PortalTestInvoice", alice), ShouldResemble, invoice.Ids())
+				So(SharedRecordIDs(env, "PortalTestInvoice", bob), ShouldResemble, invoice.Ids())
+				Convey("Revoking access should only unshare the record with the given users", func() {
+					invoice.Call("PortalRevokeAccess", []int64{alice})
+					So(SharedRecordIDs(env, "PortalTestInvoice", alice), ShouldBeEmpty)
+					So(SharedRecordIDs(env, "PortalTestInvoice", bob), ShouldResemble, invoice.Ids())
+				})
+			})
+		}), ShouldBeNil)
+	})
+}
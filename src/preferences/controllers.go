@@ -0,0 +1,57 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package preferences
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// getPreferences is the controller that returns the preferences of the logged in user
+func getPreferences(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var res Preferences
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		res = Get(env, uid)
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// updatePreferences is the controller that updates the preferences of the
+// logged in user. Preferences that are not given in the parameters are kept.
+// It returns the updated preferences.
+func updatePreferences(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params json.RawMessage
+	ctx.BindRPCParams(&params)
+	var res Preferences
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		res = Get(env, uid)
+		if err := json.Unmarshal(params, &res); err != nil {
+			log.Panic("Invalid preferences parameters", "uid", uid, "error", err)
+		}
+		if err := Set(env, uid, res); err != nil {
+			log.Panic("Invalid preferences", "uid", uid, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/preferences")
+	grp.AddController(http.MethodPost, "/get", getPreferences)
+	grp.AddController(http.MethodPost, "/update", updatePreferences)
+}
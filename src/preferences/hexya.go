@@ -0,0 +1,33 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package preferences is a Hexya module that stores the preferences of each
+// user, such as their language, their timezone, the state of the sidebar of
+// the web client and how they want to be notified.
+//
+// The language and the timezone of the user are set by default in the
+// context of the Environment of each request, as returned by ContextGet.
+// The web client reads and updates the preferences of the logged in user
+// with the /web/preferences/get and /web/preferences/update endpoints.
+package preferences
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "preferences"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("preferences")
+	declareModels()
+	server.RegisterContextDefaults(ContextGet)
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package preferences
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+func declareModels() {
+	userPreferences := models.NewModel("UserPreferences")
+	userPreferences.SetDefaultOrder("UserID")
+	userPreferences.NewMethod("ContextGet", userPreferences_ContextGet)
+	userPreferences.NewMethod("GetPreferences", userPreferences_GetPreferences)
+	userPreferences.NewMethod("SetPreferences", userPreferences_SetPreferences)
+	userPreferences.AddFields(map[string]models.FieldDefinition{
+		"UserID":           fields.Integer{String: "User ID", Required: true, Index: true},
+		"Lang":             fields.Char{String: "Language"},
+		"TZ":               fields.Char{String: "Timezone"},
+		"SidebarCollapsed": fields.Boolean{String: "Collapsed Sidebar"},
+		"NotificationType": fields.Selection{String: "Notification", Required: true,
+			Selection: types.Selection{NotificationEmail: "By email", NotificationInbox: "In the inbox"},
+			Default:   models.DefaultValue(NotificationEmail)},
+		"DesktopNotifications": fields.Boolean{String: "Desktop Notifications"},
+	})
+	userPreferences.AddSQLConstraint("user_uniq", "unique(user_id)",
+		"The preferences of a user must be unique")
+}
+
+// userPreferences_ContextGet returns the default context of the current user
+func userPreferences_ContextGet(rs *models.RecordCollection) *types.Context {
+	return ContextGet(rs.Env())
+}
+
+// userPreferences_GetPreferences returns the preferences of the current user
+func userPreferences_GetPreferences(rs *models.RecordCollection) Preferences {
+	return Get(rs.Env(), rs.Env().Uid())
+}
+
+// userPreferences_SetPreferences updates the preferences of the current user
+func userPreferences_SetPreferences(rs *models.RecordCollection, prefs Preferences) {
+	if err := Set(rs.Env(), rs.Env().Uid(), prefs); err != nil {
+		log.Panic("Invalid preferences", "uid", rs.Env().Uid(), "error", err)
+	}
+}
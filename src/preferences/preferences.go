@@ -0,0 +1,131 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package preferences
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+// Notification types of users
+const (
+	// NotificationEmail notifies users by email
+	NotificationEmail = "email"
+	// NotificationInbox notifies users in their inbox of the web client
+	NotificationInbox = "inbox"
+)
+
+// Preferences are the preferences of a user
+type Preferences struct {
+	// Lang is the language code of the user, e.g. fr_FR
+	Lang string `json:"lang"`
+	// TZ is the name of the timezone of the user in the IANA database, e.g. Europe/Paris
+	TZ                   string `json:"tz"`
+	SidebarCollapsed     bool   `json:"sidebar_collapsed"`
+	NotificationType     string `json:"notification_type"`
+	DesktopNotifications bool   `json:"desktop_notifications"`
+}
+
+// defaultPreferences are the preferences of users who have not set any
+var defaultPreferences = Preferences{
+	NotificationType: NotificationEmail,
+}
+
+// Validate returns an error if these preferences are not valid
+func (p Preferences) Validate() error {
+	if p.Lang != "" && !knownLanguage(p.Lang) {
+		return fmt.Errorf("unknown language %q", p.Lang)
+	}
+	if p.TZ != "" {
+		if _, err := time.LoadLocation(p.TZ); err != nil {
+			return fmt.Errorf("unknown timezone %q", p.TZ)
+		}
+	}
+	switch p.NotificationType {
+	case NotificationEmail, NotificationInbox:
+	default:
+		return fmt.Errorf("unknown notification type %q", p.NotificationType)
+	}
+	return nil
+}
+
+// knownLanguage returns true if the given language code, or its base
+// language code (e.g. fr for fr_FR), is a known language
+func knownLanguage(lang string) bool {
+	base := strings.Split(lang, "_")[0]
+	for _, l := range i18n.GetAllLanguageList() {
+		if l == lang || l == base {
+			return true
+		}
+	}
+	return false
+}
+
+// userPreferences returns the UserPreferences record of the given user, which may be empty
+func userPreferences(env models.Environment, uid int64) *models.RecordCollection {
+	rs := env.Pool("UserPreferences").Sudo()
+	mi := rs.Model()
+	return rs.Search(mi.Field(mi.FieldName("UserID")).Equals(uid)).Limit(1)
+}
+
+// Get returns the preferences of the given user, or the
+// default preferences if the user has not set any.
+func Get(env models.Environment, uid int64) Preferences {
+	rec := userPreferences(env, uid)
+	if rec.IsEmpty() {
+		return defaultPreferences
+	}
+	mi := rec.Model()
+	return Preferences{
+		Lang:                 rec.Get(mi.FieldName("Lang")).(string),
+		TZ:                   rec.Get(mi.FieldName("TZ")).(string),
+		SidebarCollapsed:     rec.Get(mi.FieldName("SidebarCollapsed")).(bool),
+		NotificationType:     rec.Get(mi.FieldName("NotificationType")).(string),
+		DesktopNotifications: rec.Get(mi.FieldName("DesktopNotifications")).(bool),
+	}
+}
+
+// Set saves the given preferences of the given user.
+// It returns an error if the preferences are not valid.
+func Set(env models.Environment, uid int64, prefs Preferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+	rec := userPreferences(env, uid)
+	mi := rec.Model()
+	data := models.NewModelData(mi).
+		Set(mi.FieldName("Lang"), prefs.Lang).
+		Set(mi.FieldName("TZ"), prefs.TZ).
+		Set(mi.FieldName("SidebarCollapsed"), prefs.SidebarCollapsed).
+		Set(mi.FieldName("NotificationType"), prefs.NotificationType).
+		Set(mi.FieldName("DesktopNotifications"), prefs.DesktopNotifications)
+	if rec.IsEmpty() {
+		rec.Call("Create", data.Set(mi.FieldName("UserID"), uid))
+		return nil
+	}
+	rec.Call("Write", data)
+	return nil
+}
+
+// ContextGet returns the default context of the user of the given Environment,
+// with the lang and tz keys of the user's preferences if they are set.
+func ContextGet(env models.Environment) *types.Context {
+	res := types.NewContext()
+	if env.Uid() == 0 {
+		return res
+	}
+	prefs := Get(env, env.Uid())
+	if prefs.Lang != "" {
+		res = res.WithKey("lang", prefs.Lang)
+	}
+	if prefs.TZ != "" {
+		res = res.WithKey("tz", prefs.TZ)
+	}
+	return res
+}
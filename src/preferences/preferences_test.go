@@ -0,0 +1,30 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package preferences
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("Testing the validation of preferences", t, func() {
+		So(defaultPreferences.Validate(), ShouldBeNil)
+		prefs := Preferences{Lang: "fr_FR", TZ: "Europe/Paris", NotificationType: NotificationInbox}
+		So(prefs.Validate(), ShouldBeNil)
+		Convey("Unknown languages should be rejected", func() {
+			prefs.Lang = "xx_YY"
+			So(prefs.Validate(), ShouldNotBeNil)
+		})
+		Convey("Unknown timezones should be rejected", func() {
+			prefs.TZ = "Europe/Atlantis"
+			So(prefs.Validate(), ShouldNotBeNil)
+		})
+		Convey("Unknown notification types should be rejected", func() {
+			prefs.NotificationType = "pigeon"
+			So(prefs.Validate(), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package rating
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Apply gives the given score and feedback to the rating with the given token
+// in the given database. feedback may be empty to keep the current feedback.
+// It returns false if there is no rating with this token.
+func Apply(dbName, token string, score int, feedback string) (bool, error) {
+	var found bool
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		ratings := env.Pool("Rating")
+		mi := ratings.Model()
+		rating := ratings.Search(mi.Field(mi.FieldName("Token")).Equals(token)).Limit(1)
+		if rating.IsEmpty() {
+			return
+		}
+		found = true
+		apply(rating, score, feedback)
+	})
+	return found, err
+}
+
+// ratingPageTemplate is the template of the public page of rating links
+var ratingPageTemplate = template.Must(template.New("rating").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Thank you for your feedback</title></head>
+<body>
+<h1>Thank you for your feedback</h1>
+<p>You rated: {{ .Label }}</p>
+{{ if .Feedback }}<p class="feedback">{{ .Feedback }}</p>{{ else }}<form method="post" action="{{ .Action }}">
+<input type="hidden" name="csrf_token" value="{{ .CSRFToken }}">
+<label>Would you like to tell us more? <textarea name="feedback" required></textarea></label><br>
+<button type="submit">Send</button>
+</form>{{ end }}
+</body></html>
+`))
+
+// ratingPage holds the data of the rating page template
+type ratingPage struct {
+	Label     string
+	Feedback  string
+	Action    string
+	CSRFToken string
+}
+
+// rate is the controller of the public rating links. It gives the score of
+// the link to the rating of the token, and on POST requests the feedback of
+// the form.
+func rate(ctx *server.Context) {
+	token := ctx.Param("token")
+	score, err := strconv.Atoi(ctx.Param("score"))
+	if token == "" || err != nil || !ValidScore(score) {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	feedback := ctx.PostForm("feedback")
+	found, err := Apply(ctx.DBName(), token, score, feedback)
+	if err != nil {
+		log.Warn("Unable to apply rating", "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		ctx.String(http.StatusNotFound, "This link is not valid anymore.")
+		return
+	}
+	ctx.Status(http.StatusOK)
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	err = ratingPageTemplate.Execute(ctx.Writer, ratingPage{
+		Label:     Labels[score],
+		Feedback:  feedback,
+		Action:    ctx.Request.URL.String(),
+		CSRFToken: ctx.CSRFToken(),
+	})
+	if err != nil {
+		log.Warn("Unable to render rating page", "error", err)
+	}
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/rate")
+	grp.AddController(http.MethodGet, "/:token/:score", rate)
+	grp.AddController(http.MethodPost, "/:token/:score", rate)
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package rating is a Hexya module that collects the satisfaction ratings
+// of customers on records, such as tickets or tasks.
+//
+// Models whose records can be rated inherit the RatingMixin model:
+//
+//	h.Ticket().InheritModel(h.RatingMixin())
+//
+// RatingGetAccessToken returns the secret token of a pending rating of a
+// record. URL returns the public link that rates the record with a score in
+// one click, so that it can be sent by email. The customer can then add a
+// written feedback on the page of the link.
+//
+// When the record also inherits the MailThread model, each rating is posted
+// as a notification in the chatter of the record.
+package rating
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "rating"
+)
+
+// addPartnerField links ratings to the customer who gives them, if the
+// Partner model exists, so that each customer has a separate pending rating.
+func addPartnerField() {
+	partner, ok := models.Registry.Get("Partner")
+	if !ok {
+		return
+	}
+	models.Registry.MustGet("Rating").AddFields(map[string]models.FieldDefinition{
+		"Partner": fields.Many2One{String: "Customer", RelationModel: partner, Index: true, OnDelete: models.SetNull,
+			Help: "Customer who gives the rating"},
+	})
+}
+
+func init() {
+	log = logging.GetLogger("rating")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: addPartnerField,
+	})
+}
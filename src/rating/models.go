@@ -0,0 +1,43 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package rating
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	rating := models.NewModel("Rating")
+	rating.SetDefaultOrder("RatedDate DESC", "ID DESC")
+	rating.AddFields(map[string]models.FieldDefinition{
+		"ResModel": fields.Char{String: "Related Document Model", Required: true, Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Required: true, Index: true},
+		"Rating": fields.Float{String: "Score", NoCopy: true,
+			Help: "Score given by the customer, from 1 (unhappy) to 5 (very happy), or 0 if not rated yet"},
+		"Feedback": fields.Text{NoCopy: true, Help: "Written feedback of the customer"},
+		"Token": fields.Char{Required: true, Unique: true, NoCopy: true,
+			Help: "Secret token of the public rating links of the customer",
+			Default: func(env models.Environment) interface{} {
+				return newToken()
+			}},
+		"Consumed": fields.Boolean{String: "Filled Rating", Index: true, NoCopy: true,
+			Help: "Whether the customer has given the rating"},
+		"RatedDate": fields.DateTime{String: "Rated On", NoCopy: true},
+	})
+
+	ratingMixin := models.NewMixinModel("RatingMixin")
+	ratingMixin.NewMethod("ComputeRatings", ratingMixin_ComputeRatings)
+	ratingMixin.NewMethod("RatingGetAccessToken", ratingMixin_RatingGetAccessToken)
+	ratingMixin.NewMethod("RatingApply", ratingMixin_RatingApply)
+	ratingMixin.NewMethod("RatingStatistics", ratingMixin_RatingStatistics)
+	ratingMixin.AddFields(map[string]models.FieldDefinition{
+		"RatingLastValue": fields.Float{String: "Last Rating",
+			Compute: ratingMixin.Methods().MustGet("ComputeRatings")},
+		"RatingCount": fields.Integer{String: "Number of Ratings",
+			Compute: ratingMixin.Methods().MustGet("ComputeRatings")},
+		"RatingAvg": fields.Float{String: "Average Rating",
+			Compute: ratingMixin.Methods().MustGet("ComputeRatings")},
+	})
+}
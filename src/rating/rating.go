@@ -0,0 +1,222 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package rating
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/url"
+
+	"github.com/hexya-erp/hexya/src/messaging"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/settings"
+)
+
+// Bounds of the scores of ratings
+const (
+	MinScore = 1
+	MaxScore = 5
+)
+
+// Labels are the labels of the scores of ratings
+var Labels = map[int]string{
+	1: "Highly Dissatisfied",
+	2: "Dissatisfied",
+	3: "Okay",
+	4: "Satisfied",
+	5: "Highly Satisfied",
+}
+
+// defaultBaseURL is the base URL of rating links
+// if the web.base.url parameter is not set
+const defaultBaseURL = "http://localhost:8080"
+
+// newToken returns a new random rating token
+func newToken() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate rating token", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ValidScore returns true if the given score is between MinScore and MaxScore
+func ValidScore(score int) bool {
+	return score >= MinScore && score <= MaxScore
+}
+
+// URL returns the public link that gives the given score to the
+// rating with the given token. It is meant to be sent by email.
+func URL(env models.Environment, token string, score int) string {
+	baseURL := settings.GetParam(env, "web.base.url", defaultBaseURL)
+	return fmt.Sprintf("%s/rate/%s/%d?db=%s", baseURL, url.PathEscape(token), score, url.QueryEscape(env.DBName()))
+}
+
+// Statistics are aggregated statistics over ratings
+type Statistics struct {
+	Count   int     `json:"count"`
+	Average float64 `json:"average"`
+	// Repartition is the number of ratings of each score
+	Repartition map[int]int `json:"repartition"`
+	// Percentages is the percentage of ratings of each score
+	Percentages map[int]float64 `json:"percentages"`
+}
+
+// ComputeStatistics returns the statistics of the given scores.
+// Scores are rounded to the nearest integer in the repartition.
+func ComputeStatistics(scores []float64) Statistics {
+	res := Statistics{
+		Count:       len(scores),
+		Repartition: make(map[int]int),
+		Percentages: make(map[int]float64),
+	}
+	for score := MinScore; score <= MaxScore; score++ {
+		res.Repartition[score] = 0
+		res.Percentages[score] = 0
+	}
+	if len(scores) == 0 {
+		return res
+	}
+	var sum float64
+	for _, score := range scores {
+		sum += score
+		res.Repartition[int(math.Round(score))]++
+	}
+	res.Average = sum / float64(len(scores))
+	for score, count := range res.Repartition {
+		res.Percentages[score] = float64(count) * 100 / float64(len(scores))
+	}
+	return res
+}
+
+// Ratings returns the filled ratings of the records of the
+// given RecordSet, the most recent first.
+func Ratings(rs models.RecordSet) models.RecordSet {
+	rc := rs.Collection()
+	ratings := rc.Env().Pool("Rating").Sudo()
+	mi := ratings.Model()
+	return ratings.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("ResID")).In(rc.Ids()).
+		And().Field(mi.FieldName("Consumed")).Equals(true))
+}
+
+// messageBody returns the body of the chatter message of a rating
+func messageBody(score int, feedback string) string {
+	res := fmt.Sprintf("Rating: %d/%d (%s)", score, MaxScore, Labels[score])
+	if feedback != "" {
+		res += "\n" + feedback
+	}
+	return res
+}
+
+// apply sets the given score and feedback on the given rating and posts
+// it in the chatter of the rated record if its model inherits MailThread.
+// feedback may be empty to keep the current feedback.
+func apply(rating *models.RecordCollection, score int, feedback string) {
+	if !ValidScore(score) {
+		log.Panic("Invalid rating score", "score", score)
+	}
+	mi := rating.Model()
+	data := models.NewModelData(mi).
+		Set(mi.FieldName("Rating"), float64(score)).
+		Set(mi.FieldName("Consumed"), true).
+		Set(mi.FieldName("RatedDate"), dates.Now())
+	if feedback != "" {
+		data.Set(mi.FieldName("Feedback"), feedback)
+	}
+	rating.Call("Write", data)
+	resModel := rating.Get(mi.FieldName("ResModel")).(string)
+	recordModel, ok := models.Registry.Get(resModel)
+	if !ok {
+		return
+	}
+	if _, ok := recordModel.Methods().Get("MessagePost"); !ok {
+		return
+	}
+	messaging.Post(rating.Env(), resModel, rating.Get(mi.FieldName("ResID")).(int64), messaging.MessageValues{
+		Subject:     "Rating",
+		Body:        messageBody(score, rating.Get(mi.FieldName("Feedback")).(string)),
+		MessageType: messaging.TypeNotification,
+	})
+}
+
+// ComputeRatings computes the last rating, the number of
+// ratings and the average rating of the record.
+func ratingMixin_ComputeRatings(rc *models.RecordCollection) *models.ModelData {
+	mi := rc.Model()
+	ratings := Ratings(rc).Collection()
+	ratingMI := ratings.Model()
+	var scores []float64
+	for _, rating := range ratings.Records() {
+		scores = append(scores, rating.Get(ratingMI.FieldName("Rating")).(float64))
+	}
+	res := models.NewModelData(mi).
+		Set(mi.FieldName("RatingLastValue"), 0.0).
+		Set(mi.FieldName("RatingCount"), int64(len(scores))).
+		Set(mi.FieldName("RatingAvg"), ComputeStatistics(scores).Average)
+	if len(scores) > 0 {
+		res.Set(mi.FieldName("RatingLastValue"), scores[0])
+	}
+	return res
+}
+
+// RatingGetAccessToken returns the token of the pending rating of this record
+// by the partner with the given ID, creating the rating if needed. partnerID
+// may be 0 if the customer is not a known partner.
+func ratingMixin_RatingGetAccessToken(rc *models.RecordCollection, partnerID int64) string {
+	rc.EnsureOne()
+	ratings := rc.Env().Pool("Rating").Sudo()
+	mi := ratings.Model()
+	cond := mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("ResID")).Equals(rc.Ids()[0]).
+		And().Field(mi.FieldName("Consumed")).Equals(false)
+	data := models.NewModelData(mi).
+		Set(mi.FieldName("ResModel"), rc.ModelName()).
+		Set(mi.FieldName("ResID"), rc.Ids()[0])
+	if _, ok := mi.Fields().Get("Partner"); ok {
+		if partnerID == 0 {
+			cond = cond.And().Field(mi.FieldName("Partner")).IsNull()
+		} else {
+			cond = cond.And().Field(mi.FieldName("Partner")).Equals(partnerID)
+			data.Set(mi.FieldName("Partner"), rc.Env().Pool("Partner").Call("BrowseOne", partnerID))
+		}
+	}
+	rating := ratings.Search(cond).Limit(1)
+	if rating.IsEmpty() {
+		rating = ratings.Call("Create", data).(models.RecordSet).Collection()
+	}
+	return rating.Get(mi.FieldName("Token")).(string)
+}
+
+// RatingApply gives the given score, from MinScore to MaxScore, and the given
+// feedback, which may be empty, to the rating with the given token of this
+// record. The rating is posted in the chatter of the record if its model
+// inherits MailThread.
+func ratingMixin_RatingApply(rc *models.RecordCollection, score int, token, feedback string) {
+	rc.EnsureOne()
+	ratings := rc.Env().Pool("Rating").Sudo()
+	mi := ratings.Model()
+	rating := ratings.Search(mi.Field(mi.FieldName("ResModel")).Equals(rc.ModelName()).
+		And().Field(mi.FieldName("ResID")).Equals(rc.Ids()[0]).
+		And().Field(mi.FieldName("Token")).Equals(token)).Limit(1)
+	if rating.IsEmpty() {
+		log.Panic("Unknown rating token", "model", rc.ModelName(), "id", rc.Ids()[0])
+	}
+	apply(rating, score, feedback)
+}
+
+// RatingStatistics returns the statistics of the
+// ratings of the records of this RecordSet.
+func ratingMixin_RatingStatistics(rc *models.RecordCollection) Statistics {
+	ratings := Ratings(rc).Collection()
+	mi := ratings.Model()
+	scores := make([]float64, 0, ratings.Len())
+	for _, rating := range ratings.Records() {
+		scores = append(scores, rating.Get(mi.FieldName("Rating")).(float64))
+	}
+	return ComputeStatistics(scores)
+}
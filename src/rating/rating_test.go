@@ -0,0 +1,100 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package rating
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/tests"
+	_ "github.com/lib/pq"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMain(m *testing.M) {
+	// The messaging module adds its fields to the User model
+	models.NewModel("User").AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	ticket := models.NewModel("RatingTestTicket")
+	ticket.InheritModel(models.Registry.MustGet("RatingMixin"))
+	ticket.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+
+func TestRatings(t *testing.T) {
+	Convey("Testing ratings", t, func() {
+		Convey("Scores should be bounded", func() {
+			So(ValidScore(0), ShouldBeFalse)
+			So(ValidScore(MinScore), ShouldBeTrue)
+			So(ValidScore(MaxScore), ShouldBeTrue)
+			So(ValidScore(6), ShouldBeFalse)
+		})
+		Convey("Statistics should aggregate the scores", func() {
+			stats := ComputeStatistics([]float64{5, 5, 3, 1})
+			So(stats.Count, ShouldEqual, 4)
+			So(stats.Average, ShouldEqual, 3.5)
+			So(stats.Repartition, ShouldResemble, map[int]int{1: 1, 2: 0, 3: 1, 4: 0, 5: 2})
+			So(stats.Percentages[5], ShouldEqual, 50)
+			So(stats.Percentages[4], ShouldEqual, 0)
+			empty := ComputeStatistics(nil)
+			So(empty.Count, ShouldEqual, 0)
+			So(empty.Average, ShouldEqual, 0)
+			So(empty.Repartition, ShouldHaveLength, MaxScore)
+		})
+		Convey("Chatter messages should show the score and the feedback", func() {
+			So(messageBody(4, ""), ShouldEqual, "Rating: 4/5 (Satisfied)")
+			So(messageBody(1, "Too slow"), ShouldEqual, "Rating: 1/5 (Highly Dissatisfied)\nToo slow")
+		})
+		Convey("Tokens should be random", func() {
+			So(newToken(), ShouldNotEqual, newToken())
+		})
+	})
+}
+
+func TestRatingMixin(t *testing.T) {
+	Convey("Testing RatingMixin", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			tickets := env.Pool("RatingTestTicket")
+			mi := tickets.Model()
+			ticket := tickets.Call("Create", models.NewModelData(mi).Set(mi.FieldName("Name"), "Printer is broken")).(models.RecordSet).Collection()
+			token := ticket.Call("RatingGetAccessToken", int64(0)).(string)
+			Convey("The token of a pending rating should be stable", func() {
+				So(token, ShouldNotBeEmpty)
+				So(ticket.Call("RatingGetAccessToken", int64(0)), ShouldEqual, token)
+				So(Ratings(ticket).IsEmpty(), ShouldBeTrue)
+				So(ticket.Get(mi.FieldName("RatingCount")), ShouldEqual, 0)
+			})
+			Convey("Applying a rating should fill it and update the record", func() {
+				ticket.Call("RatingApply", 4, token, "Nice")
+				ratings := Ratings(ticket).Collection()
+				So(ratings.Len(), ShouldEqual, 1)
+				rmi := ratings.Model()
+				So(ratings.Get(rmi.FieldName("Rating")), ShouldEqual, 4)
+				So(ratings.Get(rmi.FieldName("Feedback")), ShouldEqual, "Nice")
+				So(ratings.Get(rmi.FieldName("Consumed")), ShouldBeTrue)
+				So(ticket.Get(mi.FieldName("RatingCount")), ShouldEqual, 1)
+				So(ticket.Get(mi.FieldName("RatingAvg")), ShouldEqual, 4)
+				So(ticket.Get(mi.FieldName("RatingLastValue")), ShouldEqual, 4)
+				Convey("A new token should be given for the next rating", func() {
+					next := ticket.Call("RatingGetAccessToken", int64(0)).(string)
+					So(next, ShouldNotEqual, token)
+					ticket.Call("RatingApply", 2, next, "")
+					stats := ticket.Call("RatingStatistics").(Statistics)
+					So(stats.Count, ShouldEqual, 2)
+					So(stats.Average, ShouldEqual, 3)
+					So(ticket.Get(mi.FieldName("RatingLastValue")), ShouldEqual, 2)
+				})
+			})
+			Convey("Unknown tokens and invalid scores should be refused", func() {
+				So(func() { ticket.Call("RatingApply", 4, "unknown", "") }, ShouldPanic)
+				So(func() { ticket.Call("RatingApply", 6, token, "") }, ShouldPanic)
+			})
+		}), ShouldBeNil)
+	})
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package reports
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/barcode"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+const (
+	// defaultBarcodeWidth is the default width of barcode images
+	defaultBarcodeWidth = 600
+	// defaultBarcodeHeight is the default height of barcode images
+	defaultBarcodeHeight = 100
+)
+
+// converters maps URL converters to report types
+var converters = map[string]actions.ReportType{
+	"pdf":  actions.ReportTypePDF,
+	"html": actions.ReportTypeHTML,
+	"csv":  actions.ReportTypeCSV,
+	"xlsx": actions.ReportTypeXLSX,
+}
+
+// fileExtensions maps report types to the extension of downloaded files
+var fileExtensions = map[actions.ReportType]string{
+	actions.ReportTypePDF:  ".pdf",
+	actions.ReportTypeHTML: ".html",
+	actions.ReportTypeCSV:  ".csv",
+	actions.ReportTypeXLSX: ".xlsx",
+}
+
+// FileExtension returns the extension of the files of the given report type
+func FileExtension(reportType actions.ReportType) string {
+	return fileExtensions[reportType]
+}
+
+// parseIDs parses a comma separated list of ids
+func parseIDs(idsStr string) ([]int64, error) {
+	var res []int64
+	for _, idStr := range strings.Split(idsStr, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, nil
+}
+
+// report renders a report for the given records.
+//
+// The URL is /report/<converter>/<report_name>/<ids> where converter is one of
+// pdf, html, csv or xlsx and ids is a comma separated list of record ids. Optional
+// report parameters can be given as JSON in the 'options' query parameter.
+// The document is sent as an attachment if the 'download' query parameter is 'true'.
+//
+// The 'barcode' converter is reserved for barcode images (see barcodeImage).
+func report(ctx *server.Context) {
+	if ctx.Param("converter") == "barcode" {
+		barcodeImage(ctx)
+		return
+	}
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	reportType, ok := converters[ctx.Param("converter")]
+	action := GetByReportName(ctx.Param("reportname"))
+	ids, err := parseIDs(ctx.Param("docids"))
+	if !ok || action == nil || err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if !CanRender(action, ctx.DBName(), uid) {
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	var data map[string]interface{}
+	if options := ctx.Query("options"); options != "" {
+		if err = json.Unmarshal([]byte(options), &data); err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	if ctx.Query("download") == "true" {
+		fileName := action.Name + fileExtensions[reportType]
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	}
+	if reportType == actions.ReportTypeXLSX {
+		streamXLSX(ctx, uid, action, ids)
+		return
+	}
+	var (
+		doc         []byte
+		contentType string
+		rErr        error
+	)
+	err = ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		doc, contentType, rErr = Render(env, action, reportType, ids, data)
+	})
+	if err != nil {
+		log.Warn("Report rendering refused", "report", action.ReportName, "uid", uid, "error", err)
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	if rErr != nil {
+		log.Panic("Unable to render report", "report", action.ReportName, "ids", ids, "error", rErr)
+	}
+	ctx.Data(http.StatusOK, contentType, doc)
+}
+
+// streamXLSX renders the given spreadsheet report directly into the response
+// so that large exports are not held in memory. Since the response has already
+// started, rendering errors can only be logged.
+func streamXLSX(ctx *server.Context, uid int64, action *actions.Action, ids []int64) {
+	if action.Type != actions.ActionReport {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	ctx.Header("Content-Type", xlsxContentType)
+	ctx.Status(http.StatusOK)
+	var rErr error
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		rErr = renderXLSX(env, action, ids, ctx.Writer)
+	})
+	if err != nil || rErr != nil {
+		log.Warn("Unable to render spreadsheet report", "report", action.ReportName, "ids", ids, "error", err, "renderError", rErr)
+	}
+}
+
+// barcodeImage returns a PNG barcode image.
+//
+// The URL is /report/barcode/<type>/<value> where type is one of Code128,
+// EAN13 or QR. Values that contain slashes can be given in the 'value' query
+// parameter instead. The size of the image is given by the 'width' and
+// 'height' query parameters.
+func barcodeImage(ctx *server.Context) {
+	value := ctx.Query("value")
+	if value == "" {
+		value = ctx.Param("docids")
+	}
+	width, err := strconv.Atoi(ctx.DefaultQuery("width", strconv.Itoa(defaultBarcodeWidth)))
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	height, err := strconv.Atoi(ctx.DefaultQuery("height", strconv.Itoa(defaultBarcodeHeight)))
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	img, err := barcode.Generate(barcode.Type(ctx.Param("reportname")), value, width, height)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	ctx.Header("Cache-Control", "public, max-age=86400")
+	ctx.Data(http.StatusOK, "image/png", img)
+}
+
+func init() {
+	log = logging.GetLogger("reports")
+	grp := controllers.Registry.AddGroup("/report")
+	grp.AddController(http.MethodGet, "/:converter/:reportname/:docids", report)
+}
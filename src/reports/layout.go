@@ -0,0 +1,52 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package reports
+
+import (
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/paperformats"
+	"github.com/spf13/viper"
+)
+
+// A Layout defines how the PDF reports of a company are printed
+type Layout struct {
+	// PaperFormat is the ID of the paper format of the company's reports.
+	// It is used for reports that do not define their own paper format.
+	PaperFormat string
+	// HeaderTemplate is the ID of the template printed at the top of each page
+	HeaderTemplate string
+	// FooterTemplate is the ID of the template printed at the bottom of each page
+	FooterTemplate string
+}
+
+// CompanyLayout returns the Layout of the current company of the user of env.
+//
+// If it is not set, reports are printed without header and footer.
+var CompanyLayout func(env models.Environment) Layout
+
+// getLayout returns the Layout to use in the given Environment
+func getLayout(env models.Environment) Layout {
+	if CompanyLayout == nil {
+		return Layout{}
+	}
+	return CompanyLayout(env)
+}
+
+// getPaperFormat returns the paper format with which the given report is printed
+// with the given layout. This is in order of precedence the paper format of the
+// report, the paper format of the layout, the paper format of the
+// Reports.PaperFormat configuration and the default A4 paper format.
+func getPaperFormat(action *actions.Action, layout Layout) *paperformats.PaperFormat {
+	for _, id := range []string{action.PaperFormat, layout.PaperFormat, viper.GetString("Reports.PaperFormat")} {
+		if id == "" {
+			continue
+		}
+		if pf := paperformats.Registry.GetByXMLID(id); pf != nil {
+			return pf
+		}
+		log.Warn("Unknown paper format", "id", id, "report", action.ReportName)
+	}
+	return paperformats.Registry.GetByXMLID(paperformats.DefaultPaperFormatID)
+}
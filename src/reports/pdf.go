@@ -0,0 +1,85 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/hexya-erp/hexya/src/paperformats"
+	"github.com/spf13/viper"
+)
+
+// wkhtmltopdfBin returns the path to the wkhtmltopdf executable
+// from the Reports.Wkhtmltopdf configuration.
+func wkhtmltopdfBin() string {
+	if bin := viper.GetString("Reports.Wkhtmltopdf"); bin != "" {
+		return bin
+	}
+	return "wkhtmltopdf"
+}
+
+// wkhtmltopdfArgs returns the command line arguments of wkhtmltopdf to print
+// with the given paper format and the header and footer files if not empty.
+func wkhtmltopdfArgs(pf *paperformats.PaperFormat, headerFile, footerFile string) []string {
+	res := []string{"--quiet", "--encoding", "utf-8"}
+	res = append(res, pf.WkhtmltopdfArgs()...)
+	if headerFile != "" {
+		res = append(res, "--header-html", headerFile)
+	}
+	if footerFile != "" {
+		res = append(res, "--footer-html", footerFile)
+	}
+	return res
+}
+
+// writeTempHTML writes the given HTML into a temporary file and returns
+// its name. It returns an empty string if html is empty.
+func writeTempHTML(html []byte) (string, error) {
+	if len(html) == 0 {
+		return "", nil
+	}
+	f, err := ioutil.TempFile("", "hexya-report-*.html")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err = f.Write(html); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// htmlToPDF converts the given HTML document to PDF with the given paper format.
+// header and footer are the HTML documents printed on each page, if not empty.
+func htmlToPDF(html, header, footer []byte, pf *paperformats.PaperFormat) ([]byte, error) {
+	headerFile, err := writeTempHTML(header)
+	if err != nil {
+		return nil, err
+	}
+	if headerFile != "" {
+		defer os.Remove(headerFile)
+	}
+	footerFile, err := writeTempHTML(footer)
+	if err != nil {
+		return nil, err
+	}
+	if footerFile != "" {
+		defer os.Remove(footerFile)
+	}
+	args := append(wkhtmltopdfArgs(pf, headerFile, footerFile), "-", "-")
+	cmd := exec.Command(wkhtmltopdfBin(), args...)
+	cmd.Stdin = bytes.NewReader(html)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("wkhtmltopdf failed: %v: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
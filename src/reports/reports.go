@@ -0,0 +1,224 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package reports renders report actions into documents.
+//
+// HTML reports are rendered from the template named after the report name of
+// the action. PDF reports are HTML reports converted with wkhtmltopdf. CSV
+// reports are rendered by a function registered with RegisterCSVRenderer.
+// XLSX reports are spreadsheets whose sheets and columns are declared in the
+// report action and which are streamed to the client while records are read.
+//
+// Values of record fields are displayed in report templates with the t-field
+// directive, which formats them in the language and timezone of the user.
+//
+// Report templates can embed barcodes and QR codes with the 'barcode'
+// function, and barcode images are served at /report/barcode/<type>/<value>.
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/i18n/format"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/templates"
+	"github.com/hexya-erp/hexya/src/tools/barcode"
+	"github.com/hexya-erp/hexya/src/tools/hweb"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// A CSVRenderer returns the rows of a CSV report for the given records.
+// data holds the optional parameters of the report.
+//
+// Values should be formatted with a format.Formatter of the Environment of
+// docs so that selection labels are translated in the language of the report.
+type CSVRenderer func(docs *models.RecordCollection, data map[string]interface{}) ([][]string, error)
+
+var csvRenderers = struct {
+	sync.RWMutex
+	renderers map[string]CSVRenderer
+}{
+	renderers: make(map[string]CSVRenderer),
+}
+
+// RegisterCSVRenderer registers the function that renders
+// the CSV report with the given report name.
+func RegisterCSVRenderer(reportName string, fnct CSVRenderer) {
+	csvRenderers.Lock()
+	defer csvRenderers.Unlock()
+	csvRenderers.renderers[reportName] = fnct
+}
+
+// getCSVRenderer returns the CSVRenderer of the given report name
+func getCSVRenderer(reportName string) (CSVRenderer, bool) {
+	csvRenderers.RLock()
+	defer csvRenderers.RUnlock()
+	fnct, ok := csvRenderers.renderers[reportName]
+	return fnct, ok
+}
+
+// GetByReportName returns the report action with the given report name
+// or nil if there is no such report.
+func GetByReportName(reportName string) *actions.Action {
+	for _, action := range actions.Registry.GetAll() {
+		if action.Type == actions.ActionReport && action.ReportName == reportName {
+			return action
+		}
+	}
+	return nil
+}
+
+// URL returns the URL at which the given report can be downloaded
+// for the records with the given ids.
+func URL(action *actions.Action, ids []int64) string {
+	idsStr := make([]string, len(ids))
+	for i, id := range ids {
+		idsStr[i] = strconv.FormatInt(id, 10)
+	}
+	return fmt.Sprintf("/report/%s/%s/%s", converter(action.ReportType), action.ReportName, strings.Join(idsStr, ","))
+}
+
+// converter returns the name of the URL converter of the given report type
+func converter(reportType actions.ReportType) string {
+	return strings.TrimPrefix(string(reportType), "qweb-")
+}
+
+// CanRender returns true if the given user of the given database is allowed to
+// render the given report, that is if the report has no groups or the user
+// belongs to one of them.
+func CanRender(action *actions.Action, db string, uid int64) bool {
+	if uid == security.SuperUserID || len(action.Groups) == 0 {
+		return true
+	}
+	for _, groupID := range action.Groups {
+		group := security.Registry.GetGroup(groupID)
+		if group != nil && security.Registry.ForDatabase(db).HasMembership(uid, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render renders the given report for the records with the given ids in the
+// given report type. Access rights and record rules of the user of env apply.
+//
+// It returns the document and its content type.
+func Render(env models.Environment, action *actions.Action, reportType actions.ReportType, ids []int64, data map[string]interface{}) ([]byte, string, error) {
+	if action.Type != actions.ActionReport {
+		return nil, "", fmt.Errorf("action %s is not a report", action.XMLID)
+	}
+	if reportType == actions.ReportTypeXLSX {
+		var buf bytes.Buffer
+		err := renderXLSX(env, action, ids, &buf)
+		return buf.Bytes(), xlsxContentType, err
+	}
+	mi, ok := models.Registry.Get(action.Model)
+	if !ok {
+		return nil, "", fmt.Errorf("unknown model %s for report %s", action.Model, action.ReportName)
+	}
+	docs := env.Pool(action.Model).Call("Search", mi.Field(models.ID).In(ids)).(models.RecordSet).Collection()
+	switch reportType {
+	case actions.ReportTypeHTML:
+		res, err := renderHTML(env, action, docs, data)
+		return res, "text/html; charset=utf-8", err
+	case actions.ReportTypePDF:
+		res, err := renderPDF(env, action, docs, data)
+		return res, "application/pdf", err
+	case actions.ReportTypeCSV:
+		res, err := renderCSV(action, docs, data)
+		return res, "text/csv; charset=utf-8", err
+	default:
+		return nil, "", fmt.Errorf("unknown report type %s", reportType)
+	}
+}
+
+// renderHTML renders the template of the given report for docs
+func renderHTML(env models.Environment, action *actions.Action, docs *models.RecordCollection, data map[string]interface{}) ([]byte, error) {
+	return renderTemplate(env, action.ReportName, action, docs, data)
+}
+
+// renderPDF renders the given report for docs as PDF, with the
+// paper format, header and footer of the current company layout.
+func renderPDF(env models.Environment, action *actions.Action, docs *models.RecordCollection, data map[string]interface{}) ([]byte, error) {
+	html, err := renderHTML(env, action, docs, data)
+	if err != nil {
+		return nil, err
+	}
+	layout := getLayout(env)
+	var header, footer []byte
+	if layout.HeaderTemplate != "" {
+		if header, err = renderTemplate(env, layout.HeaderTemplate, action, docs, data); err != nil {
+			return nil, err
+		}
+	}
+	if layout.FooterTemplate != "" {
+		if footer, err = renderTemplate(env, layout.FooterTemplate, action, docs, data); err != nil {
+			return nil, err
+		}
+	}
+	return htmlToPDF(html, header, footer, getPaperFormat(action, layout))
+}
+
+// renderTemplate renders the template with the given ID for the given report and docs
+func renderTemplate(env models.Environment, templateID string, action *actions.Action, docs *models.RecordCollection, data map[string]interface{}) ([]byte, error) {
+	lang := env.Lang()
+	tmpl, err := templates.Registry.FromCache(path.Join(lang, templateID))
+	if err != nil {
+		return nil, err
+	}
+	formatter := format.NewFormatter(env)
+	return tmpl.ExecuteBytes(hweb.Context{
+		"docs":      docs.Records(),
+		"doc_ids":   docs.Ids(),
+		"doc_model": action.Model,
+		"data":      data,
+		"lang":      lang,
+		"barcode":   barcodeDataURI,
+		"format":    formatter,
+		"t_field":   formatter.Field,
+	})
+}
+
+// barcodeDataURI returns a barcode image of the given type and size as data URI.
+// It is available in report templates as 'barcode', for instance
+// in <img t-att-src="barcode('EAN13', doc.Barcode, 600, 100)"/>.
+func barcodeDataURI(typ, value string, width, height int) string {
+	uri, err := barcode.DataURI(barcode.Type(typ), value, width, height)
+	if err != nil {
+		log.Warn("Unable to generate barcode", "type", typ, "value", value, "error", err)
+	}
+	return uri
+}
+
+// renderCSV renders the given CSV report for docs
+func renderCSV(action *actions.Action, docs *models.RecordCollection, data map[string]interface{}) ([]byte, error) {
+	fnct, ok := getCSVRenderer(action.ReportName)
+	if !ok {
+		return nil, fmt.Errorf("no CSV renderer for report %s", action.ReportName)
+	}
+	rows, err := fnct(docs, data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCSV(rows)
+}
+
+// encodeCSV returns the given rows encoded as CSV
+func encodeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
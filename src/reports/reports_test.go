@@ -0,0 +1,105 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package reports
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/paperformats"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReports(t *testing.T) {
+	Convey("Testing reports", t, func() {
+		action := &actions.Action{
+			XMLID:      "test_report",
+			Type:       actions.ActionReport,
+			ReportName: "test_report",
+			ReportType: actions.ReportTypePDF,
+			Model:      "Partner",
+		}
+		actions.Registry.Add(action)
+		Convey("Reports should be found by report name", func() {
+			So(GetByReportName("test_report"), ShouldEqual, action)
+			So(GetByReportName("unknown"), ShouldBeNil)
+		})
+		Convey("Report URLs should use the converter of the report type", func() {
+			So(URL(action, []int64{1, 2}), ShouldEqual, "/report/pdf/test_report/1,2")
+			ids, err := parseIDs("1, 2")
+			So(err, ShouldBeNil)
+			So(ids, ShouldResemble, []int64{1, 2})
+			_, err = parseIDs("1,a")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Reports with groups should only be rendered by members", func() {
+			group := security.Registry.NewGroup("reports_test_group", "Reports Test Group")
+			restricted := &actions.Action{Type: actions.ActionReport, Groups: []string{group.ID}}
+			So(CanRender(action, security.MainDatabase, 2), ShouldBeTrue)
+			So(CanRender(restricted, security.MainDatabase, 2), ShouldBeFalse)
+			So(CanRender(restricted, security.MainDatabase, security.SuperUserID), ShouldBeTrue)
+			security.Registry.AddMembership(2, group)
+			So(CanRender(restricted, security.MainDatabase, 2), ShouldBeTrue)
+			security.Registry.RemoveMembership(2, group)
+		})
+		Convey("Paper format of the report should take precedence over the company's", func() {
+			paperformats.Registry.Add(&paperformats.PaperFormat{XMLID: "test_letter", Format: "Letter"})
+			paperformats.Registry.Add(&paperformats.PaperFormat{XMLID: "test_a5", Format: "A5"})
+			So(getPaperFormat(action, Layout{}).XMLID, ShouldEqual, paperformats.DefaultPaperFormatID)
+			So(getPaperFormat(action, Layout{PaperFormat: "test_letter"}).XMLID, ShouldEqual, "test_letter")
+			withFormat := &actions.Action{ReportName: "test_report", PaperFormat: "test_a5"}
+			So(getPaperFormat(withFormat, Layout{PaperFormat: "test_letter"}).XMLID, ShouldEqual, "test_a5")
+			So(wkhtmltopdfArgs(getPaperFormat(withFormat, Layout{}), "header.html", ""), ShouldResemble,
+				[]string{"--quiet", "--encoding", "utf-8", "--page-size", "A5", "--margin-top", "0mm",
+					"--margin-bottom", "0mm", "--margin-left", "0mm", "--margin-right", "0mm", "--header-html", "header.html"})
+		})
+		Convey("CSV rows should be encoded", func() {
+			data, err := encodeCSV([][]string{{"Name", "City"}, {"John, Jr", "Paris"}})
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "Name,City\n\"John, Jr\",Paris\n")
+		})
+	})
+}
+
+func TestBarcodes(t *testing.T) {
+	Convey("Testing barcodes in reports", t, func() {
+		So(barcodeDataURI("QR", "https://www.hexya.io", 100, 100), ShouldStartWith, "data:image/png;base64,")
+		So(barcodeDataURI("EAN13", "1234", 100, 100), ShouldBeEmpty)
+	})
+}
+
+func TestXLSXReports(t *testing.T) {
+	Convey("Testing spreadsheet reports", t, func() {
+		Convey("Aggregates should ignore empty values", func() {
+			values := []interface{}{3, nil, 1.5, "", 4.5}
+			results := make(map[string]interface{})
+			for _, function := range []string{"sum", "avg", "min", "max", "count"} {
+				agg, err := newAggregator(function)
+				So(err, ShouldBeNil)
+				for _, v := range values {
+					agg.add(v)
+				}
+				results[function] = agg.result()
+			}
+			So(results, ShouldResemble, map[string]interface{}{
+				"sum": 9.0, "avg": 3.0, "min": 1.5, "max": 4.5, "count": 3,
+			})
+			_, err := newAggregator("median")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Aggregates of empty columns should be empty", func() {
+			agg, _ := newAggregator("sum")
+			So(agg.result(), ShouldBeNil)
+			agg, _ = newAggregator("count")
+			So(agg.result(), ShouldEqual, 0)
+		})
+		Convey("Dates should be converted to times", func() {
+			date := dates.ParseDate("2019-03-15")
+			So(cellValue(date), ShouldResemble, date.Time)
+			So(cellValue(12.5), ShouldEqual, 12.5)
+		})
+	})
+}
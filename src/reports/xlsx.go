@@ -0,0 +1,196 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package reports
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+	"github.com/hexya-erp/hexya/src/tools/xlsx"
+)
+
+// xlsxContentType is the content type of spreadsheet reports
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// xlsxBatchSize is the number of records that are loaded at
+// once when rendering a spreadsheet report.
+const xlsxBatchSize = 500
+
+// renderXLSX writes the given spreadsheet report for the records with the given ids into w.
+//
+// Records are loaded by batches and the Environment cache is emptied after each
+// batch, so that memory usage does not depend on the number of records.
+func renderXLSX(env models.Environment, action *actions.Action, ids []int64, w io.Writer) error {
+	mi, ok := models.Registry.Get(action.Model)
+	if !ok {
+		return fmt.Errorf("unknown model %s for report %s", action.Model, action.ReportName)
+	}
+	xw := xlsx.NewWriter(w)
+	for _, sheetDef := range action.Sheets {
+		if err := renderXLSXSheet(env, mi, xw, sheetDef, ids); err != nil {
+			return err
+		}
+	}
+	return xw.Close()
+}
+
+// renderXLSXSheet adds the given sheet to xw for the records with the given ids
+func renderXLSXSheet(env models.Environment, mi *models.Model, xw *xlsx.Writer, sheetDef actions.ReportSheet, ids []int64) error {
+	fieldNames := make([]models.FieldName, len(sheetDef.Columns))
+	selections := make([]types.Selection, len(sheetDef.Columns))
+	titles := make([]string, len(sheetDef.Columns))
+	aggregates := make([]*aggregator, len(sheetDef.Columns))
+	var hasAggregates bool
+	for i, col := range sheetDef.Columns {
+		// FieldName panics on invalid paths, so that nothing is written
+		fieldNames[i] = mi.FieldName(col.Field)
+		selections[i] = translatedSelection(env, mi, fieldNames[i])
+		titles[i] = col.String
+		if titles[i] == "" {
+			titles[i] = col.Field
+		}
+		if col.Aggregate != "" {
+			agg, err := newAggregator(col.Aggregate)
+			if err != nil {
+				return err
+			}
+			aggregates[i] = agg
+			hasAggregates = true
+		}
+	}
+	sheet, err := xw.AddSheet(sheetDef.Name)
+	if err != nil {
+		return err
+	}
+	if err = sheet.WriteHeader(titles...); err != nil {
+		return err
+	}
+	for start := 0; start < len(ids); start += xlsxBatchSize {
+		end := start + xlsxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		records := env.Pool(mi.Name()).Call("Search", mi.Field(models.ID).In(ids[start:end])).(models.RecordSet).Collection()
+		for _, record := range records.Records() {
+			values := make([]interface{}, len(fieldNames))
+			for i, fName := range fieldNames {
+				values[i] = cellValue(record.Get(fName))
+				if label, ok := selections[i][fmt.Sprint(values[i])]; ok {
+					values[i] = label
+				}
+				if aggregates[i] != nil {
+					aggregates[i].add(values[i])
+				}
+			}
+			if err = sheet.WriteRow(values...); err != nil {
+				return err
+			}
+		}
+		env.InvalidateCache()
+	}
+	if !hasAggregates {
+		return nil
+	}
+	values := make([]interface{}, len(aggregates))
+	for i, agg := range aggregates {
+		if agg != nil {
+			values[i] = agg.result()
+		}
+	}
+	return sheet.WriteRow(values...)
+}
+
+// translatedSelection returns the selection of the given field of mi
+// translated in the language of env, or nil if it is not a selection
+// field of mi. Fields of related models given as paths are not translated.
+func translatedSelection(env models.Environment, mi *models.Model, fName models.FieldName) types.Selection {
+	if strings.Contains(fName.Name(), models.ExprSep) {
+		return nil
+	}
+	infos := mi.FieldsGet(fName)
+	if fi, ok := infos[fName.JSON()]; !ok || fi.Type != fieldtype.Selection {
+		return nil
+	}
+	mi.TranslateFieldsInfo(env, infos)
+	return infos[fName.JSON()].Selection
+}
+
+// cellValue converts a field value into a value for a spreadsheet cell
+func cellValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case dates.Date:
+		return v.Time
+	case dates.DateTime:
+		return v.Time
+	case models.RecordSet:
+		var names []string
+		for _, rec := range v.Collection().Records() {
+			names = append(names, rec.Call("NameGet").(string))
+		}
+		return strings.Join(names, ", ")
+	default:
+		return value
+	}
+}
+
+// An aggregator computes an aggregate of the values of a column
+type aggregator struct {
+	function string
+	count    int
+	sum      float64
+	min      float64
+	max      float64
+}
+
+// newAggregator returns an aggregator for the given function
+func newAggregator(function string) (*aggregator, error) {
+	switch function {
+	case "sum", "avg", "min", "max", "count":
+		return &aggregator{function: function, min: math.Inf(1), max: math.Inf(-1)}, nil
+	default:
+		return nil, fmt.Errorf("unknown aggregate function %s", function)
+	}
+}
+
+// add the given value to the aggregate. Empty values are ignored
+// and values that are not numbers only count for 'count'.
+func (a *aggregator) add(value interface{}) {
+	if value == nil || value == "" {
+		return
+	}
+	a.count++
+	f, err := nbutils.CastToFloat(value)
+	if err != nil {
+		return
+	}
+	a.sum += f
+	a.min = math.Min(a.min, f)
+	a.max = math.Max(a.max, f)
+}
+
+// result returns the aggregated value
+func (a *aggregator) result() interface{} {
+	switch {
+	case a.function == "count":
+		return a.count
+	case a.count == 0:
+		return nil
+	case a.function == "sum":
+		return a.sum
+	case a.function == "avg":
+		return a.sum / float64(a.count)
+	case a.function == "min":
+		return a.min
+	default:
+		return a.max
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package resource
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	tdates "github.com/hexya-erp/hexya/src/tools/dates"
+)
+
+// weekday returns the time.Weekday of the given DayOfWeek selection key
+func weekday(dayOfWeek string) time.Weekday {
+	day, err := strconv.Atoi(dayOfWeek)
+	if err != nil || day < 0 || day > 6 {
+		log.Panic("Invalid day of week", "day", dayOfWeek)
+	}
+	return time.Weekday((day + 1) % 7)
+}
+
+// A recordCalendar is the tools/dates Calendar of a ResourceCalendar record.
+// Its leaves are read from the database when they are needed.
+type recordCalendar struct {
+	tdates.WeekCalendar
+	record *models.RecordCollection
+}
+
+// Leaves returns the CalendarLeaves of the calendar that overlap the given interval
+func (cal *recordCalendar) Leaves(start, stop time.Time) []tdates.Interval {
+	leaves := cal.record.Env().Pool("CalendarLeaves")
+	mi := leaves.Model()
+	leaves = leaves.Search(mi.Field(mi.FieldName("Calendar")).Equals(cal.record).
+		And().Field(mi.FieldName("DateFrom")).Lower(dates.DateTime{Time: stop.UTC()}).
+		And().Field(mi.FieldName("DateTo")).Greater(dates.DateTime{Time: start.UTC()}))
+	var res []tdates.Interval
+	for _, leave := range leaves.Records() {
+		res = append(res, tdates.Interval{
+			Start: leave.Get(mi.FieldName("DateFrom")).(dates.DateTime).Time,
+			Stop:  leave.Get(mi.FieldName("DateTo")).(dates.DateTime).Time,
+		})
+	}
+	return res
+}
+
+// Calendar returns the working time of the given ResourceCalendar record
+// as a tools/dates Calendar, so that scheduling helpers can be used on it.
+func Calendar(calendar *models.RecordCollection) tdates.Calendar {
+	calendar.EnsureOne()
+	mi := calendar.Model()
+	loc, err := time.LoadLocation(calendar.Get(mi.FieldName("TZ")).(string))
+	if err != nil {
+		log.Panic("Invalid timezone of calendar", "calendar", calendar.Ids()[0], "error", err)
+	}
+	res := &recordCalendar{
+		WeekCalendar: tdates.WeekCalendar{Loc: loc},
+		record:       calendar,
+	}
+	attendances := calendar.Get(mi.FieldName("Attendances")).(models.RecordSet).Collection()
+	ami := attendances.Model()
+	for _, att := range attendances.Records() {
+		res.Attendances = append(res.Attendances, tdates.Attendance{
+			Weekday:  weekday(att.Get(ami.FieldName("DayOfWeek")).(string)),
+			HourFrom: att.Get(ami.FieldName("HourFrom")).(float64),
+			HourTo:   att.Get(ami.FieldName("HourTo")).(float64),
+		})
+	}
+	return res
+}
+
+// resourceCalendar_WorkingIntervals returns the working intervals of this
+// calendar between start and stop, without the leaves.
+func resourceCalendar_WorkingIntervals(rs *models.RecordCollection, start, stop dates.DateTime) []tdates.Interval {
+	return tdates.WorkingIntervals(Calendar(rs), start.UTC().Time, stop.UTC().Time)
+}
+
+// resourceCalendar_WorkHours returns the number of working hours
+// of this calendar between start and stop, without the leaves.
+func resourceCalendar_WorkHours(rs *models.RecordCollection, start, stop dates.DateTime) float64 {
+	return tdates.WorkingDuration(Calendar(rs), start.UTC().Time, stop.UTC().Time).Hours()
+}
+
+// resourceCalendar_PlanHours returns the datetime at which the given number of
+// working hours of this calendar is elapsed from start. Hours may be negative
+// to plan backward from start.
+func resourceCalendar_PlanHours(rs *models.RecordCollection, start dates.DateTime, hours float64) dates.DateTime {
+	res, err := tdates.AddWorkingTime(Calendar(rs), start.UTC().Time, time.Duration(hours*float64(time.Hour)))
+	if err != nil {
+		log.Panic("Unable to plan hours in calendar", "calendar", rs.Ids()[0], "hours", hours, "error", err)
+	}
+	return dates.DateTime{Time: res}
+}
+
+// resourceCalendar_PlanDays returns start moved by the given number of working
+// days of this calendar. Days may be negative to plan backward from start.
+func resourceCalendar_PlanDays(rs *models.RecordCollection, start dates.DateTime, days int) dates.DateTime {
+	res, err := tdates.AddBusinessDays(Calendar(rs), start.UTC().Time, days)
+	if err != nil {
+		log.Panic("Unable to plan days in calendar", "calendar", rs.Ids()[0], "days", days, "error", err)
+	}
+	return dates.DateTime{Time: res}
+}
+
+// resourceCalendar_IsWorkingDay returns true if the given day has working hours in this calendar
+func resourceCalendar_IsWorkingDay(rs *models.RecordCollection, day dates.Date) bool {
+	cal := Calendar(rs)
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, cal.Location())
+	return tdates.IsBusinessDay(cal, noon)
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package resource is a Hexya module that defines the working time of
+// resources, as shared infrastructure for planning modules.
+//
+// A ResourceCalendar holds the weekly working hours of resources as
+// CalendarAttendance records, expressed in the timezone of the calendar,
+// and their leaves as CalendarLeaves records. Its methods compute the
+// working time between two datetimes and plan tasks of a given duration:
+//
+//	calendar := h.ResourceCalendar().Browse(env, []int64{calID})
+//	hours := calendar.WorkHours(start, stop)
+//	end := calendar.PlanHours(start, 6)
+package resource
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "resource"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("resource")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package resource
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// dayOfWeekSelection is the selection of the days of attendances,
+// whose keys start from Monday as in ISO 8601.
+var dayOfWeekSelection = types.Selection{
+	"0": "Monday",
+	"1": "Tuesday",
+	"2": "Wednesday",
+	"3": "Thursday",
+	"4": "Friday",
+	"5": "Saturday",
+	"6": "Sunday",
+}
+
+// timezoneSelection returns the selection of the timezones of calendars
+func timezoneSelection() types.Selection {
+	res := make(types.Selection)
+	for _, tz := range dates.TimeZones() {
+		res[tz] = tz
+	}
+	return res
+}
+
+func declareModels() {
+	calendar := models.NewModel("ResourceCalendar")
+	calendar.SetDefaultOrder("Name")
+	calendar.NewMethod("WorkingIntervals", resourceCalendar_WorkingIntervals)
+	calendar.NewMethod("WorkHours", resourceCalendar_WorkHours)
+	calendar.NewMethod("PlanHours", resourceCalendar_PlanHours)
+	calendar.NewMethod("PlanDays", resourceCalendar_PlanDays)
+	calendar.NewMethod("IsWorkingDay", resourceCalendar_IsWorkingDay)
+	calendar.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true},
+		"TZ": fields.Selection{String: "Timezone", Required: true, SelectionFunc: timezoneSelection,
+			Default: models.DefaultValue("UTC"),
+			Help:    "Timezone in which the working hours of the calendar are given"},
+		"HoursPerDay": fields.Float{String: "Average Hours per Day", Default: models.DefaultValue(8.0),
+			Help: "Average number of working hours per day, used to convert days into hours"},
+		"Active": fields.Boolean{Default: models.DefaultValue(true)},
+	})
+
+	attendance := models.NewModel("CalendarAttendance")
+	attendance.SetDefaultOrder("Calendar", "DayOfWeek", "HourFrom")
+	attendance.NewMethod("CheckHours", calendarAttendance_CheckHours)
+	attendance.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+		"Calendar": fields.Many2One{RelationModel: calendar, Required: true, Index: true,
+			OnDelete: models.Cascade},
+		"DayOfWeek": fields.Selection{String: "Day of Week", Required: true, Index: true,
+			Selection: dayOfWeekSelection, Default: models.DefaultValue("0")},
+		"HourFrom": fields.Float{String: "Work From", Required: true,
+			Constraint: attendance.Methods().MustGet("CheckHours"),
+			Help:       "Start of the working period, as a decimal hour (e.g. 13.5 for 1:30 PM)"},
+		"HourTo": fields.Float{String: "Work To", Required: true,
+			Constraint: attendance.Methods().MustGet("CheckHours"),
+			Help:       "End of the working period, as a decimal hour (e.g. 17.5 for 5:30 PM)"},
+	})
+
+	leaves := models.NewModel("CalendarLeaves")
+	leaves.SetDefaultOrder("DateFrom")
+	leaves.NewMethod("CheckDates", calendarLeaves_CheckDates)
+	leaves.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Reason"},
+		"Calendar": fields.Many2One{RelationModel: calendar, Required: true, Index: true,
+			OnDelete: models.Cascade},
+		"DateFrom": fields.DateTime{String: "Start Date", Required: true, Index: true,
+			Constraint: leaves.Methods().MustGet("CheckDates")},
+		"DateTo": fields.DateTime{String: "End Date", Required: true, Index: true,
+			Constraint: leaves.Methods().MustGet("CheckDates")},
+	})
+
+	calendar.AddFields(map[string]models.FieldDefinition{
+		"Attendances": fields.One2Many{String: "Working Hours", RelationModel: attendance, ReverseFK: "Calendar"},
+		"Leaves":      fields.One2Many{RelationModel: leaves, ReverseFK: "Calendar"},
+	})
+}
+
+// calendarAttendance_CheckHours checks that attendances are periods of a single day
+func calendarAttendance_CheckHours(rs *models.RecordCollection) {
+	mi := rs.Model()
+	for _, rec := range rs.Records() {
+		from := rec.Get(mi.FieldName("HourFrom")).(float64)
+		to := rec.Get(mi.FieldName("HourTo")).(float64)
+		if from < 0 || to > 24 || from >= to {
+			log.Panic("Working hours must be between 0 and 24 and end after they start",
+				"attendance", rec.Ids()[0], "from", from, "to", to)
+		}
+	}
+}
+
+// calendarLeaves_CheckDates checks that leaves end after they start
+func calendarLeaves_CheckDates(rs *models.RecordCollection) {
+	mi := rs.Model()
+	for _, rec := range rs.Records() {
+		from := rec.Get(mi.FieldName("DateFrom")).(dates.DateTime)
+		to := rec.Get(mi.FieldName("DateTo")).(dates.DateTime)
+		if !to.Greater(from) {
+			log.Panic("Leaves must end after they start", "leave", rec.Ids()[0], "from", from, "to", to)
+		}
+	}
+}
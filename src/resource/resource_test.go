@@ -0,0 +1,25 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package resource
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWeekday(t *testing.T) {
+	Convey("Testing the conversion of days of attendances", t, func() {
+		So(weekday("0"), ShouldEqual, time.Monday)
+		So(weekday("5"), ShouldEqual, time.Saturday)
+		So(weekday("6"), ShouldEqual, time.Sunday)
+		So(dayOfWeekSelection, ShouldHaveLength, 7)
+		for key, name := range dayOfWeekSelection {
+			So(weekday(key).String(), ShouldEqual, name)
+		}
+		So(func() { weekday("7") }, ShouldPanic)
+		So(timezoneSelection(), ShouldContainKey, "Europe/Paris")
+	})
+}
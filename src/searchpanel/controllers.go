@@ -0,0 +1,58 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package searchpanel
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// selectRange is the controller that returns the values
+// of a category of the search panel.
+func selectRange(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params RangeParams
+	ctx.BindRPCParams(&params)
+	var res RangeResult
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		var err error
+		if res, err = SelectRange(env, params); err != nil {
+			log.Panic("Invalid search panel category", "model", params.Model, "field", params.Field, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+// selectMultiRange is the controller that returns the values
+// of a filter of the search panel.
+func selectMultiRange(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params MultiRangeParams
+	ctx.BindRPCParams(&params)
+	var res MultiRangeResult
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		var err error
+		if res, err = SelectMultiRange(env, params); err != nil {
+			log.Panic("Invalid search panel filter", "model", params.Model, "field", params.Field, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/search_panel")
+	grp.AddController(http.MethodPost, "/select_range", selectRange)
+	grp.AddController(http.MethodPost, "/select_multi_range", selectMultiRange)
+}
@@ -0,0 +1,410 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package searchpanel computes the values of the search panel of the list
+// and kanban views, that is the sidebar that filters the records of a view
+// by the values of a field.
+//
+// A category field (see SelectRange) lets the user select one value. If the
+// field is a many2one to a model with a Parent field, its values are given
+// with their parent so that the client displays them as a tree, and the
+// count of a value includes the records of its descendants.
+//
+// A filter field (see SelectMultiRange) lets the user select several values,
+// which may be grouped by a field of the related model.
+//
+// Values are served at /web/search_panel/select_range and
+// /web/search_panel/select_multi_range.
+package searchpanel
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+)
+
+// ParentField is the name of the field that makes a model hierarchical
+const ParentField = "Parent"
+
+var log logging.Logger
+
+// A Value is a value of a field in the search panel
+type Value struct {
+	// ID is the ID of the record for relation fields
+	// and the key of the item for selection fields
+	ID          interface{} `json:"id"`
+	DisplayName string      `json:"display_name"`
+	// ParentID is the ID of the parent of the value in hierarchical
+	// categories, and nil for root values and other fields.
+	ParentID interface{} `json:"parent_id,omitempty"`
+	// Count is the number of records with this value. It is always 0 if
+	// counters are not enabled.
+	Count int `json:"__count"`
+	// GroupID and GroupName are the value and the label of the field of
+	// the related record by which the values of a filter are grouped.
+	GroupID   interface{} `json:"group_id,omitempty"`
+	GroupName string      `json:"group_name,omitempty"`
+}
+
+// RangeParams are the parameters of SelectRange
+type RangeParams struct {
+	Model string `json:"model"`
+	Field string `json:"field_name"`
+	// CategoryDomain is the domain of the other categories of the panel
+	CategoryDomain []interface{} `json:"category_domain"`
+	// ComodelDomain restricts the records of the related model
+	ComodelDomain []interface{} `json:"comodel_domain"`
+	// SearchDomain is the domain of the search view
+	SearchDomain   []interface{} `json:"search_domain"`
+	EnableCounters bool          `json:"enable_counters"`
+	// Expand returns also the values that no record has
+	Expand bool `json:"expand"`
+}
+
+// RangeResult is the result of SelectRange
+type RangeResult struct {
+	// ParentField is the JSON name of the parent field of the related
+	// model for hierarchical categories, and empty otherwise.
+	ParentField string  `json:"parent_field"`
+	Values      []Value `json:"values"`
+}
+
+// MultiRangeParams are the parameters of SelectMultiRange
+type MultiRangeParams struct {
+	RangeParams
+	// FilterDomain is the domain of the other filters of the panel
+	FilterDomain []interface{} `json:"filter_domain"`
+	// GroupBy is the field of the related model by which values are grouped
+	GroupBy string `json:"group_by"`
+}
+
+// MultiRangeResult is the result of SelectMultiRange
+type MultiRangeResult struct {
+	Values []Value `json:"values"`
+}
+
+// A panelField is a field of the search panel with its model
+type panelField struct {
+	model *models.Model
+	name  models.FieldName
+	info  *models.FieldInfo
+}
+
+// getPanelField returns the panelField of the given field of the given
+// model. It returns an error if the field does not have one of the given
+// types.
+func getPanelField(env models.Environment, model, field string, types ...fieldtype.Type) (panelField, error) {
+	mi, ok := models.Registry.Get(model)
+	if !ok {
+		return panelField{}, fmt.Errorf("unknown model '%s'", model)
+	}
+	fi, ok := mi.Fields().Get(field)
+	if !ok {
+		return panelField{}, fmt.Errorf("unknown field '%s' in model %s", field, model)
+	}
+	fName := mi.FieldName(fi.Name())
+	info := env.Pool(mi.Name()).Call("FieldsGet", models.FieldsGetArgs{Fields: models.FieldNames{fName}}).(map[string]*models.FieldInfo)[fi.JSON()]
+	for _, t := range types {
+		if info.Type == t {
+			return panelField{model: mi, name: fName, info: info}, nil
+		}
+	}
+	return panelField{}, fmt.Errorf("field '%s' of type %s cannot be used in the search panel", field, info.Type)
+}
+
+// domainsCondition returns the condition on the given model of all the given domains
+func domainsCondition(mi *models.Model, domains ...[]interface{}) (*models.Condition, error) {
+	res, err := models.ParseDomain(mi, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, domain := range domains {
+		cond, err := models.ParseDomain(mi, domain)
+		if err != nil {
+			return nil, err
+		}
+		res = res.AndCond(cond)
+	}
+	return res, nil
+}
+
+// search returns the records of the given model matching cond,
+// or all its records if cond is empty.
+func search(env models.Environment, mi *models.Model, cond *models.Condition) *models.RecordCollection {
+	if cond.IsEmpty() {
+		return env.Pool(mi.Name()).SearchAll()
+	}
+	return env.Pool(mi.Name()).Search(cond)
+}
+
+// valueKey returns the ID of the given value of a field in the
+// search panel: the ID of a record or the key of a selection.
+// It returns nil for empty values.
+func valueKey(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case models.RecordSet:
+		if v.IsEmpty() {
+			return nil
+		}
+		return v.Ids()[0]
+	case string:
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+	if id, err := nbutils.CastToInteger(value); err == nil {
+		return id
+	}
+	return value
+}
+
+// counts returns the number of records matching cond by value of the given
+// many2one or selection field.
+func counts(env models.Environment, field panelField, cond *models.Condition) map[interface{}]int {
+	res := make(map[interface{}]int)
+	rows := search(env, field.model, cond).GroupBy(field.name).Aggregates(field.name)
+	for _, row := range rows {
+		if key := valueKey(row.Values.Get(field.name)); key != nil {
+			res[key] += row.Count
+		}
+	}
+	return res
+}
+
+// selectionValues returns the values of the given selection
+// field, ordered by label, with their counts.
+func selectionValues(field panelField, counts map[interface{}]int, expand bool) []Value {
+	res := make([]Value, 0, len(field.info.Selection))
+	for key, label := range field.info.Selection {
+		if !expand && counts[key] == 0 {
+			continue
+		}
+		res = append(res, Value{ID: key, DisplayName: label, Count: counts[key]})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].DisplayName < res[j].DisplayName
+	})
+	return res
+}
+
+// comodelRecords returns the records of the related model of
+// the given relation field that match the given domain.
+func comodelRecords(env models.Environment, field panelField, domain []interface{}) (*models.RecordCollection, error) {
+	comodel := models.Registry.MustGet(field.info.Relation)
+	cond, err := domainsCondition(comodel, domain)
+	if err != nil {
+		return nil, err
+	}
+	return search(env, comodel, cond), nil
+}
+
+// SelectRange returns the values of the given many2one or selection field
+// of a category of the search panel.
+//
+// Values are the records of the related model matching the comodel domain
+// or the items of the selection. Unless Expand is true, only values of
+// records matching the category and the search domains are returned, with
+// their ancestors in hierarchical categories.
+func SelectRange(env models.Environment, params RangeParams) (RangeResult, error) {
+	var res RangeResult
+	field, err := getPanelField(env, params.Model, params.Field, fieldtype.Many2One, fieldtype.Selection)
+	if err != nil {
+		return res, err
+	}
+	cond, err := domainsCondition(field.model, params.CategoryDomain, params.SearchDomain)
+	if err != nil {
+		return res, err
+	}
+	cnts := make(map[interface{}]int)
+	if params.EnableCounters || !params.Expand {
+		cnts = counts(env, field, cond)
+	}
+	if field.info.Type == fieldtype.Selection {
+		res.Values = selectionValues(field, cnts, params.Expand)
+		if !params.EnableCounters {
+			clearCounts(res.Values)
+		}
+		return res, nil
+	}
+	records, err := comodelRecords(env, field, params.ComodelDomain)
+	if err != nil {
+		return res, err
+	}
+	parentFName := records.Model().FieldName(ParentField)
+	_, hierarchical := records.Model().Fields().Get(ParentField)
+	if hierarchical {
+		res.ParentField = parentFName.JSON()
+	}
+	for _, rec := range records.Records() {
+		value := Value{
+			ID:          rec.Ids()[0],
+			DisplayName: rec.Call("NameGet").(string),
+			Count:       cnts[rec.Ids()[0]],
+		}
+		if hierarchical {
+			value.ParentID = valueKey(rec.Get(parentFName))
+		}
+		res.Values = append(res.Values, value)
+	}
+	if !params.Expand {
+		res.Values = withAncestors(res.Values)
+	}
+	if hierarchical {
+		res.Values = globalCounts(res.Values)
+	}
+	if !params.EnableCounters {
+		clearCounts(res.Values)
+	}
+	return res, nil
+}
+
+// SelectMultiRange returns the values of the given many2one, many2many or
+// selection field of a filter of the search panel.
+//
+// Values are the records of the related model matching the comodel domain
+// or the items of the selection. Unless Expand is true, only values of
+// records matching the category, the filter and the search domains are
+// returned.
+//
+// If GroupBy is set, values are grouped by this many2one or selection field
+// of the related model.
+func SelectMultiRange(env models.Environment, params MultiRangeParams) (MultiRangeResult, error) {
+	var res MultiRangeResult
+	field, err := getPanelField(env, params.Model, params.Field, fieldtype.Many2One, fieldtype.Many2Many, fieldtype.Selection)
+	if err != nil {
+		return res, err
+	}
+	cond, err := domainsCondition(field.model, params.CategoryDomain, params.FilterDomain, params.SearchDomain)
+	if err != nil {
+		return res, err
+	}
+	countValues := params.EnableCounters || !params.Expand
+	if field.info.Type == fieldtype.Selection {
+		cnts := make(map[interface{}]int)
+		if countValues {
+			cnts = counts(env, field, cond)
+		}
+		res.Values = selectionValues(field, cnts, params.Expand)
+		if !params.EnableCounters {
+			clearCounts(res.Values)
+		}
+		return res, nil
+	}
+	records, err := comodelRecords(env, field, params.ComodelDomain)
+	if err != nil {
+		return res, err
+	}
+	var group panelField
+	if params.GroupBy != "" {
+		group, err = getPanelField(env, field.info.Relation, params.GroupBy, fieldtype.Many2One, fieldtype.Selection)
+		if err != nil {
+			return res, err
+		}
+	}
+	cnts := make(map[interface{}]int)
+	if countValues && field.info.Type == fieldtype.Many2One {
+		cnts = counts(env, field, cond)
+	}
+	for _, rec := range records.Records() {
+		id := rec.Ids()[0]
+		count := cnts[id]
+		if countValues && field.info.Type == fieldtype.Many2Many {
+			count = search(env, field.model, cond.AndCond(field.model.Field(field.name).In([]int64{id}))).SearchCount()
+		}
+		if !params.Expand && count == 0 {
+			continue
+		}
+		value := Value{
+			ID:          id,
+			DisplayName: rec.Call("NameGet").(string),
+			Count:       count,
+		}
+		if group.name != nil {
+			groupValue := rec.Get(group.name)
+			value.GroupID = valueKey(groupValue)
+			switch gv := groupValue.(type) {
+			case models.RecordSet:
+				if !gv.IsEmpty() {
+					value.GroupName = gv.Collection().Call("NameGet").(string)
+				}
+			default:
+				value.GroupName = group.info.Selection[fmt.Sprint(gv)]
+			}
+			if value.GroupID == nil {
+				value.GroupID = false
+			}
+		}
+		res.Values = append(res.Values, value)
+	}
+	if !params.EnableCounters {
+		clearCounts(res.Values)
+	}
+	return res, nil
+}
+
+// withAncestors returns the values with a count, and their ancestors,
+// in their original order.
+func withAncestors(values []Value) []Value {
+	byID := make(map[interface{}]Value)
+	for _, v := range values {
+		byID[v.ID] = v
+	}
+	keep := make(map[interface{}]bool)
+	for _, v := range values {
+		if v.Count == 0 {
+			continue
+		}
+		for cur, ok := v, true; ok && !keep[cur.ID]; cur, ok = byID[cur.ParentID] {
+			keep[cur.ID] = true
+		}
+	}
+	var res []Value
+	for _, v := range values {
+		if keep[v.ID] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// globalCounts returns the given hierarchical values with the count of
+// each value including the counts of its descendants.
+func globalCounts(values []Value) []Value {
+	index := make(map[interface{}]int)
+	for i, v := range values {
+		index[v.ID] = i
+	}
+	res := make([]Value, len(values))
+	copy(res, values)
+	for _, v := range values {
+		visited := make(map[interface{}]bool)
+		for parent := v.ParentID; parent != nil && !visited[parent]; {
+			visited[parent] = true
+			i, ok := index[parent]
+			if !ok {
+				break
+			}
+			res[i].Count += v.Count
+			parent = values[i].ParentID
+		}
+	}
+	return res
+}
+
+// clearCounts sets the counts of the given values to 0
+func clearCounts(values []Value) {
+	for i := range values {
+		values[i].Count = 0
+	}
+}
+
+func init() {
+	log = logging.GetLogger("searchpanel")
+}
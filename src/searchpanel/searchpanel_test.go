@@ -0,0 +1,55 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package searchpanel
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHierarchicalValues(t *testing.T) {
+	Convey("Testing hierarchical values of categories", t, func() {
+		values := []Value{
+			{ID: int64(1), DisplayName: "All"},
+			{ID: int64(2), DisplayName: "Saleable", ParentID: int64(1), Count: 3},
+			{ID: int64(3), DisplayName: "Office", ParentID: int64(2), Count: 2},
+			{ID: int64(4), DisplayName: "Internal", ParentID: int64(1)},
+			{ID: int64(5), DisplayName: "Services"},
+		}
+		Convey("Values without records are removed unless they are ancestors", func() {
+			res := withAncestors(values)
+			So(res, ShouldHaveLength, 3)
+			So(res[0].ID, ShouldEqual, int64(1))
+			So(res[1].ID, ShouldEqual, int64(2))
+			So(res[2].ID, ShouldEqual, int64(3))
+		})
+		Convey("Counts include the counts of descendants", func() {
+			res := globalCounts(values)
+			So(res[0].Count, ShouldEqual, 5)
+			So(res[1].Count, ShouldEqual, 5)
+			So(res[2].Count, ShouldEqual, 2)
+			So(res[3].Count, ShouldEqual, 0)
+			So(res[4].Count, ShouldEqual, 0)
+			So(values[0].Count, ShouldEqual, 0)
+		})
+		Convey("Cycles in the hierarchy do not loop forever", func() {
+			cycle := []Value{
+				{ID: int64(1), ParentID: int64(2), Count: 1},
+				{ID: int64(2), ParentID: int64(1), Count: 1},
+			}
+			So(withAncestors(cycle), ShouldHaveLength, 2)
+			res := globalCounts(cycle)
+			So(res[0].Count, ShouldEqual, 3)
+			So(res[1].Count, ShouldEqual, 3)
+		})
+	})
+	Convey("Testing value keys", t, func() {
+		So(valueKey(nil), ShouldBeNil)
+		So(valueKey(""), ShouldBeNil)
+		So(valueKey("draft"), ShouldEqual, "draft")
+		So(valueKey(int64(4)), ShouldEqual, int64(4))
+		So(valueKey(int32(4)), ShouldEqual, int64(4))
+	})
+}
@@ -4,14 +4,24 @@
 package server
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types"
 	"github.com/hexya-erp/hexya/src/tools/exceptions"
 	"github.com/hexya-erp/hexya/src/tools/hweb"
 )
@@ -77,11 +87,240 @@ func (c *Context) BindRPCParams(data interface{}) {
 	}
 }
 
+// An RPCCall describes a call to a model method made through JSON-RPC
+type RPCCall struct {
+	Model  string
+	Method string
+	IDs    []int64
+}
+
+// maxRPCCallBodySize is the maximum size of the request bodies
+// that are parsed to extract the RPC call.
+const maxRPCCallBodySize = 1 << 20
+
+// rpcCallKey is the key under which the parsed RPC call is stored in the context
+const rpcCallKey = "rpc_call"
+
+// RPCCall returns the model method call of the request of c if it is a
+// JSON-RPC call with params of the form
+// {"model": "...", "method": "...", "args": [ids, ...]}.
+// The returned boolean is false otherwise.
+//
+// The body of the request is restored so that it can be read again.
+func (c *Context) RPCCall() (RPCCall, bool) {
+	if call, ok := c.Get(rpcCallKey); ok {
+		return call.(RPCCall), call.(RPCCall).Model != ""
+	}
+	call := parseRPCCall(c.Request)
+	c.Set(rpcCallKey, call)
+	return call, call.Model != ""
+}
+
+// parseRPCCall returns the model method call of the given request,
+// or an empty RPCCall if it is not a JSON-RPC call to a model method.
+func parseRPCCall(req *http.Request) RPCCall {
+	if req.Method != http.MethodPost || req.Body == nil || !strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return RPCCall{}
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(req.Body, maxRPCCallBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+	if err != nil || len(buf) > maxRPCCallBodySize {
+		return RPCCall{}
+	}
+	var rpc struct {
+		Params struct {
+			Model  string            `json:"model"`
+			Method string            `json:"method"`
+			Args   []json.RawMessage `json:"args"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(buf, &rpc); err != nil || rpc.Params.Model == "" {
+		return RPCCall{}
+	}
+	call := RPCCall{Model: rpc.Params.Model, Method: rpc.Params.Method}
+	if len(rpc.Params.Args) > 0 {
+		if err := json.Unmarshal(rpc.Params.Args[0], &call.IDs); err != nil {
+			var id int64
+			if json.Unmarshal(rpc.Params.Args[0], &id) == nil && id != 0 {
+				call.IDs = []int64{id}
+			}
+		}
+	}
+	return call
+}
+
 // Session returns the current Session instance
 func (c *Context) Session() sessions.Session {
 	return sessions.Default(c.Context)
 }
 
+// CSRFSessionKey is the session key under which the CSRF token is stored
+const CSRFSessionKey = "csrf_token"
+
+// CSRFToken returns the CSRF token of the current session.
+// A new token is generated and saved in the session if none exists yet.
+//
+// This token must be sent back by the client with each unsafe request
+// (e.g. POST) of an authenticated session, either in the X-CSRF-Token
+// header or in the csrf_token form value.
+func (c *Context) CSRFToken() string {
+	if token, ok := c.Session().Get(CSRFSessionKey).(string); ok && token != "" {
+		return token
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Panic("Unable to generate CSRF token", "error", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	c.Session().Set(CSRFSessionKey, token)
+	if err := c.Session().Save(); err != nil {
+		log.Warn("Unable to save CSRF token in session", "error", err)
+	}
+	return token
+}
+
+// DBNameKey is the key under which the database selected for the request
+// is stored in the context and in the session.
+const DBNameKey = "db"
+
+// DBName returns the name of the database selected for this request.
+// An empty string means the main database.
+func (c *Context) DBName() string {
+	return c.GetString(DBNameKey)
+}
+
+// RealUIDSessionKey is the session key of the administrator who
+// is impersonating the user of the session.
+const RealUIDSessionKey = "real_uid"
+
+// ImpersonatingSessionKey is the session key of the flag that
+// is set when the user of the session is impersonated.
+const ImpersonatingSessionKey = "impersonating"
+
+// RealUID returns the ID of the administrator who is impersonating
+// the user of the session, or 0 if there is no impersonation.
+func (c *Context) RealUID() int64 {
+	realUID, _ := c.Session().Get(RealUIDSessionKey).(int64)
+	return realUID
+}
+
+// contextDefaults are the functions that return the default
+// context values of the Environments of requests
+var contextDefaults []func(env models.Environment) *types.Context
+
+// RegisterContextDefaults registers a function that returns context values
+// to set by default in the Environment of each request, such as the language
+// of the user. It must be called in the init() function of modules.
+func RegisterContextDefaults(fnct func(env models.Environment) *types.Context) {
+	contextDefaults = append(contextDefaults, fnct)
+}
+
+// requestContextDefaults are the functions that return the default
+// context values of the Environments of requests from the requests
+var requestContextDefaults []func(c *Context) *types.Context
+
+// RegisterRequestContextDefaults registers a function that returns context
+// values to set by default in the Environment of each request from the request
+// itself, such as the values of its cookies or query parameters. These values
+// take precedence over those of RegisterContextDefaults. It must be called in
+// the init() function of modules.
+func RegisterRequestContextDefaults(fnct func(c *Context) *types.Context) {
+	requestContextDefaults = append(requestContextDefaults, fnct)
+}
+
+// withContextDefaults returns a copy of the given Environment with the
+// default context values given by the registered functions.
+func (c *Context) withContextDefaults(env models.Environment) models.Environment {
+	if len(contextDefaults) == 0 && len(requestContextDefaults) == 0 {
+		return env
+	}
+	ctx := types.NewContext()
+	addDefaults := func(defaults *types.Context) {
+		if defaults == nil {
+			return
+		}
+		for key, value := range defaults.ToMap() {
+			ctx = ctx.WithKey(key, value)
+		}
+	}
+	for _, fnct := range contextDefaults {
+		addDefaults(fnct(env))
+	}
+	for _, fnct := range requestContextDefaults {
+		addDefaults(fnct(c))
+	}
+	for key, value := range env.Context().ToMap() {
+		ctx = ctx.WithKey(key, value)
+	}
+	return env.WithNewContext(ctx)
+}
+
+// ExecuteInNewEnvironment executes the given fnct in a new Environment on the
+// database selected for this request. See models.ExecuteInNewEnvironment.
+//
+// The context of the Environment holds the values of the functions registered
+// with RegisterContextDefaults and RegisterRequestContextDefaults. If the user
+// of the session is impersonated, the Environment tracks the impersonating
+// administrator as its real user. If the request is traced, the method calls
+// and queries of the Environment are traced in child spans of the span of the
+// request.
+//
+// If the request opted into a snapshot transaction with the SnapshotHeader,
+// fnct is executed with ExecuteInSnapshotEnvironment instead.
+func (c *Context) ExecuteInNewEnvironment(uid int64, fnct func(models.Environment)) error {
+	if c.SnapshotRequested() {
+		return c.ExecuteInSnapshotEnvironment(uid, fnct)
+	}
+	withDefaults := func(env models.Environment) {
+		env.Cr().SetTraceSpan(c.TraceSpan())
+		fnct(c.withContextDefaults(env))
+	}
+	if realUID := c.RealUID(); realUID != 0 {
+		return models.ExecuteInDelegatedEnvironment(c.DBName(), realUID, uid, withDefaults)
+	}
+	return models.ExecuteInTenantEnvironment(c.DBName(), uid, withDefaults)
+}
+
+// SnapshotHeader is the HTTP header with which a request opts into read only
+// snapshot transactions by setting it to a true value (e.g. '1' or 'true').
+// The snapshot metadata of the transaction is then returned in the response
+// headers SnapshotHeader (in the 'xmin:xmax:xip_list' format) and
+// SnapshotTimeHeader.
+const SnapshotHeader = "X-Hexya-Snapshot"
+
+// SnapshotTimeHeader is the response header holding the start time of
+// the snapshot transaction of a request in RFC 3339 format.
+const SnapshotTimeHeader = "X-Hexya-Snapshot-Time"
+
+// SnapshotRequested returns true if this request opted into
+// read only snapshot transactions with the SnapshotHeader.
+func (c *Context) SnapshotRequested() bool {
+	res, _ := strconv.ParseBool(c.GetHeader(SnapshotHeader))
+	return res
+}
+
+// ExecuteInSnapshotEnvironment executes the given fnct in a new read only
+// Environment on the database selected for this request, in which all queries
+// see the same consistent snapshot of the database. It is meant for long reads
+// such as report renderings. See models.ExecuteInSnapshotEnvironment.
+//
+// The Environment is set up as in ExecuteInNewEnvironment. If the response has
+// not been written yet, the snapshot metadata is added to its headers.
+func (c *Context) ExecuteInSnapshotEnvironment(uid int64, fnct func(models.Environment)) error {
+	return models.ExecuteInSnapshotEnvironment(c.DBName(), c.RealUID(), uid, func(env models.Environment) {
+		env.Cr().SetTraceSpan(c.TraceSpan())
+		if !c.Writer.Written() {
+			snapshot := env.Snapshot()
+			c.Header(SnapshotHeader, snapshot.TxIDs)
+			c.Header(SnapshotTimeHeader, snapshot.StartedAt.Format(time.RFC3339Nano))
+		}
+		fnct(c.withContextDefaults(env))
+	})
+}
+
 // Super calls the next middleware / handler layer
 // It is an alias for Next
 func (c *Context) Super() {
@@ -0,0 +1,182 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/beevik/etree"
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// A DataRecord is a record of the database identified by its external ID
+type DataRecord struct {
+	Model      string
+	ExternalID string
+}
+
+// Contributions lists the external IDs of the objects contributed
+// by a module to the registries from its XML resource files.
+type Contributions struct {
+	Views []string
+	// ViewExtensions are the IDs of the views extended by the module
+	// with views having an inherit_id attribute
+	ViewExtensions []string
+	Actions        []string
+	Menus          []string
+	Templates      []string
+	PaperFormats   []string
+}
+
+// contributions holds the contributions of each module loaded by
+// LoadInternalResources
+var contributions struct {
+	sync.RWMutex
+	byModule map[string]*Contributions
+}
+
+// resetContributions forgets the contributions of all modules
+func resetContributions() {
+	contributions.Lock()
+	defer contributions.Unlock()
+	contributions.byModule = make(map[string]*Contributions)
+}
+
+// addContribution records that the given XML object of a resource file
+// has been contributed by the given module.
+func addContribution(module string, object *etree.Element) {
+	contributions.Lock()
+	defer contributions.Unlock()
+	if contributions.byModule == nil {
+		contributions.byModule = make(map[string]*Contributions)
+	}
+	contrib, ok := contributions.byModule[module]
+	if !ok {
+		contrib = new(Contributions)
+		contributions.byModule[module] = contrib
+	}
+	id := object.SelectAttrValue("id", "")
+	switch object.Tag {
+	case "view":
+		if inheritID := object.SelectAttrValue("inherit_id", ""); inheritID != "" {
+			contrib.ViewExtensions = append(contrib.ViewExtensions, inheritID)
+			return
+		}
+		contrib.Views = append(contrib.Views, id)
+	case "action":
+		contrib.Actions = append(contrib.Actions, id)
+	case "menuitem":
+		contrib.Menus = append(contrib.Menus, id)
+	case "template":
+		contrib.Templates = append(contrib.Templates, id)
+	case "paperformat":
+		contrib.PaperFormats = append(contrib.PaperFormats, id)
+	}
+}
+
+// ModuleContributions returns the objects contributed by the given module
+// to the registries when the internal resources were last loaded.
+// It returns an empty Contributions if the module is not loaded.
+func ModuleContributions(module string) Contributions {
+	contributions.RLock()
+	defer contributions.RUnlock()
+	contrib, ok := contributions.byModule[module]
+	if !ok {
+		return Contributions{}
+	}
+	return *contrib
+}
+
+// UnloadModule removes the given module from the activated modules and
+// reloads the internal resources without it, so that the views, actions,
+// menus, templates and paper formats it contributed are removed from the
+// registries and that its extensions of the views of other modules are
+// reverted. The module is added to UninstalledModules.
+//
+// The data records of the module are not removed from the database, this is
+// done by the lifecycle module when the database is updated without this
+// module. Models and methods cannot be unloaded and are left as is.
+func UnloadModule(resourceDir, module string) error {
+	for i, mod := range Modules {
+		if mod.Name != module {
+			continue
+		}
+		for _, other := range Modules {
+			if other.Manifest == nil {
+				continue
+			}
+			for _, dep := range other.Manifest.Depends {
+				if dep == module {
+					return fmt.Errorf("module %s cannot be unloaded since module %s depends on it", module, other.Name)
+				}
+			}
+		}
+		Modules = append(Modules[:i:i], Modules[i+1:]...)
+		UninstalledModules = append(UninstalledModules, mod)
+		ReloadInternalResources(resourceDir)
+		log.Info("Module unloaded", "module", module)
+		return nil
+	}
+	return fmt.Errorf("module %s is not loaded", module)
+}
+
+// ModuleDataRecords returns the records defined by the data and demo CSV
+// files of the given module in the given resource directory, in the order
+// they are loaded. Records of the files which update existing records
+// (i.e. named Model_update.csv) are not included, since they belong to the
+// module which created them.
+func ModuleDataRecords(resourceDir string, mod *Module) ([]DataRecord, error) {
+	var res []DataRecord
+	for _, dir := range []string{"data", "demo"} {
+		for _, fileName := range moduleDataFiles(resourceDir, mod, dir, "csv") {
+			modelName, update, _ := models.ParseDataFileName(fileName)
+			if update {
+				continue
+			}
+			ids, err := csvExternalIDs(fileName)
+			if err != nil {
+				return nil, fmt.Errorf("error while reading %s: %s", fileName, err)
+			}
+			for _, id := range ids {
+				res = append(res, DataRecord{Model: modelName, ExternalID: id})
+			}
+		}
+	}
+	return res, nil
+}
+
+// csvExternalIDs returns the values of the 'id' column of the given CSV file
+func csvExternalIDs(fileName string) ([]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	col := -1
+	for i, header := range records[0] {
+		if header == "id" {
+			col = i
+		}
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("no 'id' column")
+	}
+	var res []string
+	for _, record := range records[1:] {
+		if record[col] != "" {
+			res = append(res, record[col])
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestContributions(t *testing.T) {
+	Convey("Testing module contributions", t, func() {
+		Convey("Objects of XML resources should be recorded by module", func() {
+			resetContributions()
+			defer resetContributions()
+			res := parseXMLResource("contrib/views.xml", []byte(`<hexya><data>
+	<view id="contrib_view_form" model="Partner"><form/></view>
+	<view id="contrib_view_ext" inherit_id="base_view_form"><form position="inside"/></view>
+	<action id="contrib_action" type="ir.actions.act_window" model="Partner"/>
+	<menuitem id="contrib_menu" name="Contrib"/>
+</data></hexya>`))
+			for _, object := range res.doc.FindElements("hexya/data/*") {
+				addContribution("contrib", object)
+			}
+			So(ModuleContributions("contrib"), ShouldResemble, Contributions{
+				Views:          []string{"contrib_view_form"},
+				ViewExtensions: []string{"base_view_form"},
+				Actions:        []string{"contrib_action"},
+				Menus:          []string{"contrib_menu"},
+			})
+			So(ModuleContributions("other"), ShouldResemble, Contributions{})
+		})
+		Convey("Data records of a module should be read from its CSV files", func() {
+			resDir, err := ioutil.TempDir("", "hexya-contrib")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(resDir)
+			dataDir := filepath.Join(resDir, "data", "contrib")
+			demoDir := filepath.Join(resDir, "demo", "contrib")
+			So(os.MkdirAll(dataDir, 0755), ShouldBeNil)
+			So(os.MkdirAll(demoDir, 0755), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(dataDir, "010-Partner.csv"), []byte("Name,id\nP1,partner_1\nP2,partner_2\n"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(dataDir, "020-User_update.csv"), []byte("id,Name\nbase_user_admin,Admin\n"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(demoDir, "Tag.csv"), []byte("id,Name\ntag_1,Tag\n"), 0644), ShouldBeNil)
+			records, err := ModuleDataRecords(resDir, &Module{Name: "contrib"})
+			So(err, ShouldBeNil)
+			So(records, ShouldResemble, []DataRecord{
+				{Model: "Partner", ExternalID: "partner_1"},
+				{Model: "Partner", ExternalID: "partner_2"},
+				{Model: "Tag", ExternalID: "tag_1"},
+			})
+			So(ioutil.WriteFile(filepath.Join(demoDir, "Tag.csv"), []byte("Name\nTag\n"), 0644), ShouldBeNil)
+			_, err = ModuleDataRecords(resDir, &Module{Name: "contrib"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Modules required by other modules should not be unloaded", func() {
+			base := &Module{Name: "base"}
+			sale := &Module{Name: "sale", Manifest: &Manifest{Name: "sale", Version: "1.0", Depends: []string{"base"}}}
+			oldModules := Modules
+			Modules = ModulesList{base, sale}
+			defer func() { Modules = oldModules }()
+			So(UnloadModule("", "base"), ShouldNotBeNil)
+			So(UnloadModule("", "unknown"), ShouldNotBeNil)
+			So(Modules, ShouldResemble, ModulesList{base, sale})
+		})
+	})
+}
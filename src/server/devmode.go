@@ -0,0 +1,140 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/menus"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/paperformats"
+	"github.com/hexya-erp/hexya/src/templates"
+	"github.com/hexya-erp/hexya/src/views"
+)
+
+// devWatchPeriod is the period at which resource files are checked for changes in dev mode
+const devWatchPeriod = time.Second
+
+// reloadLock is held while internal resources are reloaded in dev mode
+var reloadLock sync.RWMutex
+
+// fileStamps gives the modification time of files by path
+type fileStamps map[string]time.Time
+
+// stampFiles returns the modification time of the given files.
+// Files that cannot be read are left out.
+func stampFiles(fileNames []string) fileStamps {
+	res := make(fileStamps)
+	for _, fileName := range fileNames {
+		info, err := os.Stat(fileName)
+		if err != nil {
+			continue
+		}
+		res[fileName] = info.ModTime()
+	}
+	return res
+}
+
+// changedFiles returns the sorted paths of the files that have been created,
+// modified or removed between the old and the new stamps.
+func changedFiles(old, new fileStamps) []string {
+	var res []string
+	for fileName, modTime := range new {
+		if oldTime, ok := old[fileName]; !ok || !oldTime.Equal(modTime) {
+			res = append(res, fileName)
+		}
+	}
+	for fileName := range old {
+		if _, ok := new[fileName]; !ok {
+			res = append(res, fileName)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// ReloadInternalResources empties the views, actions, menus, templates and
+// paper formats registries, loads all the XML files of the 'resources'
+// directory again and bootstraps the registries.
+//
+// Requests received during the reload wait for it to complete. Errors are
+// logged and do not stop the server, so that they can be fixed in the
+// resource files which are then reloaded.
+func ReloadInternalResources(resourceDir string) {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Unable to reload resources", "error", r)
+		}
+	}()
+	views.ResetRegistry()
+	actions.ResetRegistry()
+	menus.ResetRegistry()
+	templates.ResetRegistry()
+	paperformats.ResetRegistry()
+	LoadInternalResources(resourceDir)
+	views.BootStrap()
+	templates.BootStrap()
+	actions.BootStrap()
+	menus.BootStrap()
+	log.Info("Resources reloaded")
+}
+
+// reloadDataFile loads the given CSV data file again into the database
+func reloadDataFile(fileName string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Unable to reload data file", "file", fileName, "error", r)
+		}
+	}()
+	models.LoadCSVDataFile(fileName)
+}
+
+// StartDevMode sets up the server for iterating quickly on modules:
+//
+// - templates are compiled again at each rendering instead of being cached,
+// - XML files of the 'resources' directory are watched and internal
+// resources are reloaded when one of them changes,
+// - CSV files of the 'data' directory are watched and loaded again
+// into the database when they change.
+//
+// It must be called before the controllers are bootstrapped.
+func StartDevMode(resourceDir string) {
+	log.Info("Starting server in dev mode")
+	templates.Registry.Debug = true
+	hexyaServer.AddMiddleWare(func(ctx *Context) {
+		// Wait for the resources to be reloaded
+		reloadLock.RLock()
+		reloadLock.RUnlock()
+	})
+	go watchResources(resourceDir)
+}
+
+// watchResources checks periodically the resources and data files of the
+// given resource directory and reloads the files that have changed.
+func watchResources(resourceDir string) {
+	resources := stampFiles(dataFiles(resourceDir, "resources", "xml"))
+	data := stampFiles(dataFiles(resourceDir, "data", "csv"))
+	for range time.Tick(devWatchPeriod) {
+		newResources := stampFiles(dataFiles(resourceDir, "resources", "xml"))
+		if changed := changedFiles(resources, newResources); len(changed) > 0 {
+			log.Info("Resource files changed", "files", changed)
+			ReloadInternalResources(resourceDir)
+		}
+		resources = newResources
+		newData := stampFiles(dataFiles(resourceDir, "data", "csv"))
+		for _, fileName := range changedFiles(data, newData) {
+			if _, exists := newData[fileName]; !exists {
+				continue
+			}
+			reloadDataFile(fileName)
+		}
+		data = newData
+	}
+}
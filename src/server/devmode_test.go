@@ -0,0 +1,33 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDevMode(t *testing.T) {
+	Convey("Testing dev mode file watching", t, func() {
+		now := time.Now()
+		old := fileStamps{
+			"a.xml": now,
+			"b.xml": now,
+			"c.xml": now,
+		}
+		Convey("Unchanged files should not be reported", func() {
+			So(changedFiles(old, old), ShouldBeEmpty)
+		})
+		Convey("Modified, created and removed files should be reported", func() {
+			new := fileStamps{
+				"a.xml": now,
+				"b.xml": now.Add(time.Second),
+				"d.xml": now,
+			}
+			So(changedFiles(old, new), ShouldResemble, []string{"b.xml", "c.xml", "d.xml"})
+		})
+	})
+}
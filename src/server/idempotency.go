@@ -0,0 +1,335 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// IdempotencyKeyHeader is the request header holding the idempotency key of
+// an RPC call. Requests with the same key are only executed once.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is the response header that is set to true
+// when the response is the stored response of a previous request.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength is the maximum length of idempotency keys
+const maxIdempotencyKeyLength = 255
+
+// defaultIdempotencyKeyTTL is the duration during which the responses of
+// requests with an idempotency key are kept if Server.IdempotencyKeyTTL
+// is not set.
+const defaultIdempotencyKeyTTL = time.Hour
+
+// defaultIdempotencyMaxKeys is the maximum number of idempotency keys whose
+// responses are kept if Server.IdempotencyMaxKeys is not set.
+const defaultIdempotencyMaxKeys = 10000
+
+// maxIdempotentResponseSize is the maximum size in bytes of the stored
+// responses. Larger responses are not stored and their requests are executed
+// again when retried.
+const maxIdempotentResponseSize = 1 << 20
+
+// An idempotentResponse is the response of a request with an idempotency key
+type idempotentResponse struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiry      time.Time
+}
+
+// An IdempotencyStore keeps the responses of the requests
+// with an idempotency key for some time.
+//
+// Responses are kept in the order of their last update, which is also the
+// order of their expiry, so that expired responses are removed from the
+// front and the oldest responses are evicted first when the store is full.
+type IdempotencyStore struct {
+	sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	responses  map[string]*list.Element
+	order      *list.List
+}
+
+// NewIdempotencyStore returns a new IdempotencyStore that keeps responses
+// for the given duration, and keeps the responses of maxEntries keys at
+// most. A zero maxEntries means no limit.
+func NewIdempotencyStore(ttl time.Duration, maxEntries int) *IdempotencyStore {
+	return &IdempotencyStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		responses:  make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// begin returns the response stored for the given key. If there is none, a
+// pending response with the given fingerprint is stored and nil is returned.
+func (is *IdempotencyStore) begin(key string, fingerprint [sha256.Size]byte) *idempotentResponse {
+	is.Lock()
+	defer is.Unlock()
+	now := time.Now()
+	is.cleanExpiredResponses(now)
+	if elem, exists := is.responses[key]; exists {
+		res := *elem.Value.(*idempotentResponse)
+		return &res
+	}
+	for is.maxEntries > 0 && is.order.Len() >= is.maxEntries {
+		is.remove(is.order.Front())
+	}
+	is.responses[key] = is.order.PushBack(&idempotentResponse{key: key, fingerprint: fingerprint, expiry: now.Add(is.ttl)})
+	return nil
+}
+
+// finish stores the given response for the given key. If the response
+// is nil, the pending response of the key is removed so that the request
+// can be retried.
+//
+// The response is dropped if the pending response of the key has been
+// evicted in the meantime.
+func (is *IdempotencyStore) finish(key string, resp *idempotentResponse) {
+	is.Lock()
+	defer is.Unlock()
+	elem, exists := is.responses[key]
+	if !exists {
+		return
+	}
+	if resp == nil {
+		is.remove(elem)
+		return
+	}
+	resp.key = key
+	resp.done = true
+	resp.expiry = time.Now().Add(is.ttl)
+	elem.Value = resp
+	is.order.MoveToBack(elem)
+}
+
+// cleanExpiredResponses removes the expired responses.
+// is must be locked when calling this method.
+func (is *IdempotencyStore) cleanExpiredResponses(now time.Time) {
+	for elem := is.order.Front(); elem != nil; elem = is.order.Front() {
+		if now.Before(elem.Value.(*idempotentResponse).expiry) {
+			return
+		}
+		is.remove(elem)
+	}
+}
+
+// remove removes the given element from this store.
+// is must be locked when calling this method.
+func (is *IdempotencyStore) remove(elem *list.Element) {
+	delete(is.responses, is.order.Remove(elem).(*idempotentResponse).key)
+}
+
+// A recordingWriter is a gin.ResponseWriter that records the
+// written body up to maxIdempotentResponseSize bytes
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+// record records the given data unless the recorded body would exceed
+// maxIdempotentResponseSize, in which case recording stops.
+func (rw *recordingWriter) record(data []byte) {
+	if rw.overflow {
+		return
+	}
+	if rw.body.Len()+len(data) > maxIdempotentResponseSize {
+		rw.overflow = true
+		rw.body.Reset()
+		return
+	}
+	rw.body.Write(data)
+}
+
+// Write writes the given data to the response and records it
+func (rw *recordingWriter) Write(data []byte) (int, error) {
+	rw.record(data)
+	return rw.ResponseWriter.Write(data)
+}
+
+// WriteString writes the given string to the response and records it
+func (rw *recordingWriter) WriteString(s string) (int, error) {
+	rw.record([]byte(s))
+	return rw.ResponseWriter.WriteString(s)
+}
+
+// requestFingerprint returns the hash of the path and of the JSON body of the
+// given request, without its JSON-RPC ID which changes between retries. The
+// body of the request is restored so that it can be read again.
+func requestFingerprint(req *http.Request) ([sha256.Size]byte, error) {
+	buf, err := ioutil.ReadAll(req.Body)
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(buf), req.Body}
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &body); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	delete(body, "id")
+	canonical, err := json.Marshal(body)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(append([]byte(req.URL.Path+"\n"), canonical...)), nil
+}
+
+// requestRPCID returns the JSON-RPC ID of the given request body
+func requestRPCID(req *http.Request) json.RawMessage {
+	buf, err := ioutil.ReadAll(req.Body)
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(buf), req.Body}
+	if err != nil {
+		return nil
+	}
+	var body struct {
+		ID json.RawMessage `json:"id"`
+	}
+	json.Unmarshal(buf, &body)
+	return body.ID
+}
+
+// replayBody returns the given stored JSON-RPC response body
+// with the given JSON-RPC ID of the retried request.
+func replayBody(body []byte, id json.RawMessage) []byte {
+	var resp map[string]json.RawMessage
+	if len(id) == 0 || json.Unmarshal(body, &resp) != nil {
+		return body
+	}
+	if _, ok := resp["id"]; !ok {
+		return body
+	}
+	resp["id"] = id
+	res, err := json.Marshal(resp)
+	if err != nil {
+		return body
+	}
+	return res
+}
+
+// storableResponse returns true if the given response can be replayed, that
+// is if it is successful and is not a JSON-RPC error. Other responses are not
+// stored so that the request can be retried.
+func storableResponse(status int, body []byte) bool {
+	if status < 200 || status >= 300 {
+		return false
+	}
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) != nil {
+		return true
+	}
+	return len(resp.Error) == 0 || string(resp.Error) == "null"
+}
+
+// IdempotencyKeys returns a middleware that executes only once the RPC
+// calls to model methods bearing the same IdempotencyKeyHeader, so that
+// client retries or double clicks do not create duplicate records.
+//
+// The successful response of the first request is stored in the given
+// IdempotencyStore and replayed for the next requests with the same key
+// from the same user on the same database. A request reusing a key with
+// different parameters is rejected with a 422 Unprocessable Entity status,
+// and a request whose key is still being processed with a 409 Conflict
+// status. Failed requests are not stored so that they can be retried, and
+// neither are responses larger than 1 MiB.
+func IdempotencyKeys(is *IdempotencyStore) HandlerFunc {
+	return func(c *Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if _, ok := c.RPCCall(); !ok {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		fingerprint, err := requestFingerprint(c.Request)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		// Requests authenticated by a bearer token have no session user yet
+		credentials := sha256.Sum256([]byte(c.GetHeader("Authorization")))
+		storeKey := fmt.Sprintf("%s\x00%v\x00%x\x00%s", c.DBName(), c.Session().Get("uid"), credentials, key)
+		stored := is.begin(storeKey, fingerprint)
+		switch {
+		case stored == nil:
+		case stored.fingerprint != fingerprint:
+			log.Warn("Idempotency key reused with different parameters", "key", key, "path", c.Request.URL.Path)
+			c.AbortWithStatus(http.StatusUnprocessableEntity)
+			return
+		case !stored.done:
+			c.AbortWithStatus(http.StatusConflict)
+			return
+		default:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.status, stored.contentType, replayBody(stored.body, requestRPCID(c.Request)))
+			c.Abort()
+			return
+		}
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			if !completed || writer.overflow || !storableResponse(writer.Status(), writer.body.Bytes()) {
+				is.finish(storeKey, nil)
+				return
+			}
+			is.finish(storeKey, &idempotentResponse{
+				fingerprint: fingerprint,
+				status:      writer.Status(),
+				contentType: writer.Header().Get("Content-Type"),
+				body:        writer.body.Bytes(),
+			})
+		}()
+		c.Next()
+		completed = true
+	}
+}
+
+// setupIdempotencyKeys adds the idempotency keys middleware to the server.
+// Responses are kept for Server.IdempotencyKeyTTL, which defaults to one hour,
+// and for Server.IdempotencyMaxKeys keys at most, which defaults to 10000.
+func setupIdempotencyKeys() {
+	ttl := viper.GetDuration("Server.IdempotencyKeyTTL")
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+	maxKeys := viper.GetInt("Server.IdempotencyMaxKeys")
+	if maxKeys <= 0 {
+		maxKeys = defaultIdempotencyMaxKeys
+	}
+	hexyaServer.AddMiddleWare(IdempotencyKeys(NewIdempotencyStore(ttl, maxKeys)))
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	Convey("Testing idempotency keys", t, func() {
+		var calls int
+		srv := &Server{Engine: gin.New()}
+		srv.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+		srv.AddMiddleWare(IdempotencyKeys(NewIdempotencyStore(time.Hour, 10)))
+		srv.Group("/").POST("/call", func(c *Context) {
+			calls++
+			if c.Query("fail") != "" {
+				c.JSON(http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "error": map[string]interface{}{"code": 500}})
+				return
+			}
+			c.JSON(http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": calls})
+		})
+		call := func(path, key string, id int, name string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(fmt.Sprintf(
+				`{"jsonrpc":"2.0","id":%d,"params":{"model":"User","method":"Create","args":[{"name":"%s"}]}}`, id, name)))
+			req.Header.Set("Content-Type", "application/json")
+			if key != "" {
+				req.Header.Set(IdempotencyKeyHeader, key)
+			}
+			srv.ServeHTTP(w, req)
+			return w
+		}
+		Convey("Requests without key should always be executed", func() {
+			call("/call", "", 1, "John")
+			call("/call", "", 1, "John")
+			So(calls, ShouldEqual, 2)
+		})
+		Convey("Retried requests should return the original result", func() {
+			w := call("/call", "key1", 1, "John")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldContainSubstring, `"result":1`)
+			w = call("/call", "key1", 2, "John")
+			So(calls, ShouldEqual, 1)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get(IdempotentReplayedHeader), ShouldEqual, "true")
+			So(w.Body.String(), ShouldContainSubstring, `"result":1`)
+			So(w.Body.String(), ShouldContainSubstring, `"id":2`)
+			w = call("/call", "key2", 3, "John")
+			So(calls, ShouldEqual, 2)
+			So(w.Body.String(), ShouldContainSubstring, `"result":2`)
+		})
+		Convey("Reusing a key with other parameters should be rejected", func() {
+			call("/call", "key1", 1, "John")
+			w := call("/call", "key1", 1, "Jane")
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(calls, ShouldEqual, 1)
+		})
+		Convey("Failed requests should be retried", func() {
+			call("/call?fail=1", "key1", 1, "John")
+			call("/call?fail=1", "key1", 1, "John")
+			So(calls, ShouldEqual, 2)
+		})
+		Convey("Pending requests should be rejected", func() {
+			is := NewIdempotencyStore(time.Hour, 0)
+			So(is.begin("key", [32]byte{1}), ShouldBeNil)
+			pending := is.begin("key", [32]byte{1})
+			So(pending, ShouldNotBeNil)
+			So(pending.done, ShouldBeFalse)
+			is.finish("key", nil)
+			So(is.begin("key", [32]byte{1}), ShouldBeNil)
+		})
+		Convey("Expired responses should be removed", func() {
+			is := NewIdempotencyStore(time.Millisecond, 0)
+			is.begin("key", [32]byte{1})
+			is.finish("key", &idempotentResponse{fingerprint: [32]byte{1}, status: http.StatusOK})
+			time.Sleep(2 * time.Millisecond)
+			So(is.begin("other", [32]byte{2}), ShouldBeNil)
+			So(is.responses, ShouldHaveLength, 1)
+			So(is.begin("key", [32]byte{1}), ShouldBeNil)
+		})
+		Convey("The oldest responses should be evicted when the store is full", func() {
+			is := NewIdempotencyStore(time.Hour, 2)
+			is.begin("key1", [32]byte{1})
+			is.begin("key2", [32]byte{2})
+			is.finish("key1", &idempotentResponse{fingerprint: [32]byte{1}, status: http.StatusOK})
+			is.begin("key3", [32]byte{3})
+			So(is.responses, ShouldHaveLength, 2)
+			So(is.responses, ShouldNotContainKey, "key2")
+			So(is.begin("key1", [32]byte{1}).done, ShouldBeTrue)
+			is.finish("key2", &idempotentResponse{fingerprint: [32]byte{2}, status: http.StatusOK})
+			So(is.responses, ShouldNotContainKey, "key2")
+		})
+		Convey("Large responses should not be stored", func() {
+			srv.Group("/").POST("/large", func(c *Context) {
+				calls++
+				c.JSON(http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": strings.Repeat("a", maxIdempotentResponseSize)})
+			})
+			w := call("/large", "key1", 1, "John")
+			So(w.Body.Len(), ShouldBeGreaterThan, maxIdempotentResponseSize)
+			call("/large", "key1", 1, "John")
+			So(calls, ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// staleBucketTimeout is the duration after which an unused bucket
+// is removed from a RateLimiter.
+const staleBucketTimeout = 10 * time.Minute
+
+// A bucket is a token bucket holding the remaining allowance of a single key.
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// A RateLimiter limits the number of requests that can be made
+// for a given key (e.g. a client IP or a user ID) within a period.
+//
+// It is implemented as a token bucket for each key: each key is
+// allowed 'burst' requests at once and its allowance is refilled
+// at the rate of 'requests' per 'period'.
+type RateLimiter struct {
+	sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastClean time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter that allows the given number of
+// requests per period with the given burst. If burst is lower than 1, it
+// defaults to the number of requests.
+func NewRateLimiter(requests int, period time.Duration, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = requests
+	}
+	return &RateLimiter{
+		rate:      float64(requests) / period.Seconds(),
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastClean: time.Now(),
+	}
+}
+
+// Allow returns true if a new request can be made for the given key
+// and consumes one token of the key's allowance.
+func (rl *RateLimiter) Allow(key string) bool {
+	rl.Lock()
+	defer rl.Unlock()
+	now := time.Now()
+	rl.cleanStaleBuckets(now)
+	b, exists := rl.buckets[key]
+	if !exists {
+		b = &bucket{tokens: rl.burst, lastSeen: now}
+		rl.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.lastSeen).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.lastSeen = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// cleanStaleBuckets removes the buckets that have not been used for some time.
+// rl must be locked when calling this method.
+func (rl *RateLimiter) cleanStaleBuckets(now time.Time) {
+	if now.Sub(rl.lastClean) < staleBucketTimeout {
+		return
+	}
+	for key, b := range rl.buckets {
+		if now.Sub(b.lastSeen) > staleBucketTimeout {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastClean = now
+}
+
+// RateLimitByIP returns a middleware that aborts the request with a
+// 429 Too Many Requests status if the client IP exceeds the allowance
+// of the given RateLimiter.
+//
+// The client IP is given by Context.RemoteIP, so that forwarding headers
+// are only taken into account behind a trusted proxy.
+func RateLimitByIP(rl *RateLimiter) HandlerFunc {
+	return func(c *Context) {
+		if !rl.Allow(c.RemoteIP()) {
+			log.Warn("Rate limit exceeded", "ip", c.RemoteIP(), "path", c.Request.URL.Path)
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitByUser returns a middleware that aborts the request with a
+// 429 Too Many Requests status if the user logged in the session exceeds
+// the allowance of the given RateLimiter.
+//
+// Users are counted per database, so that users of different tenants with
+// the same ID do not share their allowance. Requests without a logged in
+// user are not limited by this middleware.
+func RateLimitByUser(rl *RateLimiter) HandlerFunc {
+	return func(c *Context) {
+		uid := c.Session().Get("uid")
+		if uid == nil {
+			c.Next()
+			return
+		}
+		if !rl.Allow(fmt.Sprintf("%s/%v", c.DBName(), uid)) {
+			log.Warn("Rate limit exceeded", "database", c.DBName(), "uid", uid, "path", c.Request.URL.Path)
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+// MaxBodySize returns a middleware that limits the size of request bodies
+// to the given number of bytes.
+//
+// Requests that announce a larger Content-Length are rejected immediately
+// with a 413 Request Entity Too Large status. Other requests have their body
+// wrapped so that reading beyond the limit fails.
+func MaxBodySize(size int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Request.ContentLength > size {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, size)
+		}
+		c.Next()
+	}
+}
+
+// setupLimits adds the rate limiting and request size middlewares
+// to the server according to the configuration.
+func setupLimits() {
+	period := viper.GetDuration("Server.RateLimitPeriod")
+	if period == 0 {
+		period = time.Minute
+	}
+	burst := viper.GetInt("Server.RateLimitBurst")
+	if ipLimit := viper.GetInt("Server.RateLimit"); ipLimit > 0 {
+		hexyaServer.AddMiddleWare(RateLimitByIP(NewRateLimiter(ipLimit, period, burst)))
+	}
+	if userLimit := viper.GetInt("Server.UserRateLimit"); userLimit > 0 {
+		hexyaServer.AddMiddleWare(RateLimitByUser(NewRateLimiter(userLimit, period, burst)))
+	}
+	if size := viper.GetInt64("Server.MaxBodySize"); size > 0 {
+		hexyaServer.AddMiddleWare(MaxBodySize(size))
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLimits(t *testing.T) {
+	Convey("Testing request limits", t, func() {
+		Convey("Rate limiter should allow burst then reject", func() {
+			rl := NewRateLimiter(2, time.Hour, 0)
+			So(rl.Allow("a"), ShouldBeTrue)
+			So(rl.Allow("a"), ShouldBeTrue)
+			So(rl.Allow("a"), ShouldBeFalse)
+			So(rl.Allow("b"), ShouldBeTrue)
+		})
+		Convey("Rate limiter should refill tokens over time", func() {
+			rl := NewRateLimiter(1000, time.Second, 1)
+			So(rl.Allow("a"), ShouldBeTrue)
+			So(rl.Allow("a"), ShouldBeFalse)
+			time.Sleep(5 * time.Millisecond)
+			So(rl.Allow("a"), ShouldBeTrue)
+		})
+		Convey("Rate limit by IP middleware should return 429", func() {
+			srv := &Server{Engine: gin.New()}
+			grp := srv.Group("/", RateLimitByIP(NewRateLimiter(1, time.Hour, 0)))
+			grp.GET("/ping", func(c *Context) {
+				c.String(http.StatusOK, "pong")
+			})
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+			So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+		})
+		Convey("Rate limit by IP middleware should ignore forwarding headers of untrusted peers", func() {
+			srv := &Server{Engine: gin.New()}
+			grp := srv.Group("/", RateLimitByIP(NewRateLimiter(1, time.Hour, 0)))
+			grp.GET("/ping", func(c *Context) {
+				c.String(http.StatusOK, "pong")
+			})
+			for i, ip := range []string{"1.2.3.4", "5.6.7.8"} {
+				req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+				req.Header.Set("X-Forwarded-For", ip)
+				req.Header.Set("X-Real-Ip", ip)
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, req)
+				if i == 0 {
+					So(w.Code, ShouldEqual, http.StatusOK)
+					continue
+				}
+				So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+			}
+		})
+		Convey("Remote IP should only be read from headers of trusted proxies", func() {
+			networks, err := ParseNetworks([]string{"10.0.0.0/8"})
+			So(err, ShouldBeNil)
+			SetTrustedProxies(networks)
+			defer SetTrustedProxies(nil)
+			remoteIP := func(remoteAddr, forwarded, realIP string) string {
+				req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+				req.RemoteAddr = remoteAddr
+				if forwarded != "" {
+					req.Header.Set("X-Forwarded-For", forwarded)
+				}
+				if realIP != "" {
+					req.Header.Set("X-Real-Ip", realIP)
+				}
+				return (&Context{Context: &gin.Context{Request: req}}).RemoteIP()
+			}
+			So(remoteIP("192.0.2.1:1234", "1.2.3.4", ""), ShouldEqual, "192.0.2.1")
+			So(remoteIP("10.0.0.1:1234", "1.2.3.4", ""), ShouldEqual, "1.2.3.4")
+			So(remoteIP("10.0.0.1:1234", "6.6.6.6, 1.2.3.4, 10.0.0.2", ""), ShouldEqual, "1.2.3.4")
+			So(remoteIP("10.0.0.1:1234", "", "1.2.3.4"), ShouldEqual, "1.2.3.4")
+			So(remoteIP("10.0.0.1:1234", "", ""), ShouldEqual, "10.0.0.1")
+		})
+		Convey("Max body size middleware should reject large bodies", func() {
+			srv := &Server{Engine: gin.New()}
+			grp := srv.Group("/", MaxBodySize(10))
+			grp.POST("/ping", func(c *Context) {
+				c.String(http.StatusOK, "pong")
+			})
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ping", strings.NewReader("small")))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ping", strings.NewReader("this body is too large")))
+			So(w.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+		})
+	})
+}
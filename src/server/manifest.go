@@ -0,0 +1,310 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/hexya-erp/hexya/src/tools/assetfs"
+	"github.com/hexya-erp/hexya/src/tools/po"
+)
+
+const (
+	// ManifestFileName is the name of the manifest file in a module directory
+	ManifestFileName = "manifest.json"
+	// ManifestsDir is the directory of the resource directory in which the
+	// manifests of the modules are installed as <module>.json
+	ManifestsDir = "manifests"
+)
+
+var (
+	moduleNameRegexp    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	moduleVersionRegexp = regexp.MustCompile(`^\d+\.\d+(\.\d+)?$`)
+)
+
+// A Manifest describes a module. It is read from the manifest.json
+// file at the root of the module directory, e.g.
+//
+//	{
+//	    "name": "sale",
+//	    "version": "1.2.0",
+//	    "depends": ["base", "product"],
+//	    "data": ["data/Sequence.csv"],
+//	    "demo": ["demo/SaleOrder.csv"],
+//	    "auto_install": false
+//	}
+//
+// Data and demo files are given relative to the module directory and are
+// loaded in the given order. If they are not set, all the CSV files of the
+// module's data and demo directories are loaded in alphabetical order.
+type Manifest struct {
+	// Name of the module, which must be the name registered with RegisterModule
+	Name string `json:"name"`
+	// Version of the module, as MAJOR.MINOR or MAJOR.MINOR.PATCH
+	Version string `json:"version"`
+	// Summary is a short description of the module
+	Summary string `json:"summary,omitempty"`
+	// Depends are the names of the modules this module depends on
+	Depends []string `json:"depends,omitempty"`
+	// Data are the CSV files of the data directory to load
+	Data []string `json:"data,omitempty"`
+	// Demo are the CSV files of the demo directory to load in demo mode
+	Demo []string `json:"demo,omitempty"`
+	// AutoInstall is true if this module must be installed automatically
+	// when all its dependencies are installed
+	AutoInstall bool `json:"auto_install,omitempty"`
+}
+
+// ParseManifest parses the given JSON content of a manifest and validates it
+func ParseManifest(content []byte) (*Manifest, error) {
+	var res Manifest
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&res); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %s", err)
+	}
+	if err := res.Validate(); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ReadManifestFile reads, parses and validates the given manifest file
+func ReadManifestFile(fileName string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(content)
+}
+
+// Validate checks the fields of this manifest.
+// It does not check that files exist.
+func (m *Manifest) Validate() error {
+	if !moduleNameRegexp.MatchString(m.Name) {
+		return fmt.Errorf("invalid module name '%s' in manifest", m.Name)
+	}
+	if !moduleVersionRegexp.MatchString(m.Version) {
+		return fmt.Errorf("invalid version '%s' in manifest of module %s: must be MAJOR.MINOR or MAJOR.MINOR.PATCH", m.Version, m.Name)
+	}
+	seen := make(map[string]bool)
+	for _, dep := range m.Depends {
+		switch {
+		case dep == m.Name:
+			return fmt.Errorf("module %s depends on itself", m.Name)
+		case seen[dep]:
+			return fmt.Errorf("module %s depends twice on %s", m.Name, dep)
+		}
+		seen[dep] = true
+	}
+	for _, files := range []struct {
+		dir   string
+		names []string
+	}{{dir: "data", names: m.Data}, {dir: "demo", names: m.Demo}} {
+		for _, fileName := range files.names {
+			if _, err := manifestFilePath(files.dir, fileName); err != nil {
+				return fmt.Errorf("invalid file in manifest of module %s: %s", m.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// manifestFilePath returns the path of the given data or demo file of a
+// manifest relative to the module's subdirectory dir. It returns an error
+// if the file is not a CSV file of this directory.
+func manifestFilePath(dir, fileName string) (string, error) {
+	clean := path.Clean(fileName)
+	if !strings.HasPrefix(clean, dir+"/") || path.Ext(clean) != ".csv" {
+		return "", fmt.Errorf("%s must be a CSV file of the %s directory", fileName, dir)
+	}
+	return strings.TrimPrefix(clean, dir+"/"), nil
+}
+
+// manifestFiles returns the paths in the resource directory of the files of
+// the given dir ("data" or "demo") declared in the manifest of the given
+// module. The returned boolean is false if the module has no manifest or if
+// its manifest does not declare the files of this directory.
+func manifestFiles(resourceDir string, mod *Module, dir string) ([]string, bool) {
+	if mod.Manifest == nil {
+		return nil, false
+	}
+	var fileNames []string
+	switch dir {
+	case "data":
+		fileNames = mod.Manifest.Data
+	case "demo":
+		fileNames = mod.Manifest.Demo
+	}
+	if fileNames == nil {
+		return nil, false
+	}
+	res := make([]string, len(fileNames))
+	for i, fileName := range fileNames {
+		rel, _ := manifestFilePath(dir, fileName)
+		res[i] = filepath.Join(resourceDir, dir, mod.Name, filepath.FromSlash(rel))
+	}
+	return res, true
+}
+
+// LoadManifests reads the manifests of the modules from the manifests
+// directory of the given resource directory (or of the embedded assets) and
+// sets the Manifest of each module. Modules without manifest are left as is.
+//
+// Auto install modules whose dependencies are not all installed are then
+// removed from Modules and moved to UninstalledModules (see resolveModules).
+//
+// It panics if a manifest is invalid, if it does not match its module, if a
+// dependency is not registered before the module or if a declared data file
+// does not exist.
+func LoadManifests(resourceDir string) {
+	fsys := assetfs.Resources(resourceDir)
+	for _, mod := range Modules {
+		fileName := path.Join(ManifestsDir, mod.Name+".json")
+		content, err := assetfs.ReadFile(fsys, fileName)
+		if err != nil {
+			continue
+		}
+		manifest, err := ParseManifest(content)
+		if err != nil {
+			log.Panic("Error while reading module manifest", "module", mod.Name, "file", fileName, "error", err)
+		}
+		if manifest.Name != mod.Name {
+			log.Panic("Module manifest name does not match module name", "module", mod.Name, "manifestName", manifest.Name)
+		}
+		mod.Manifest = manifest
+	}
+	installed, uninstalled, err := resolveModules(Modules)
+	if err != nil {
+		log.Panic("Unable to resolve module dependencies", "error", err)
+	}
+	for _, mod := range uninstalled {
+		log.Info("Auto install module not installed since its dependencies are not all installed", "module", mod.Name, "depends", mod.Manifest.Depends)
+	}
+	Modules, UninstalledModules = installed, uninstalled
+	for _, mod := range Modules {
+		for _, dir := range []string{"data", "demo"} {
+			files, _ := manifestFiles(resourceDir, mod, dir)
+			for _, file := range files {
+				if _, err := os.Stat(file); err != nil {
+					log.Panic("Data file declared in manifest not found", "module", mod.Name, "file", file, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// resolveModules returns the given modules split between the modules to
+// install and the modules not to install, keeping their order.
+//
+// Modules that are not auto install modules are always installed and all
+// their dependencies must be installed modules registered before them.
+// Auto install modules are installed if and only if all their dependencies
+// are installed. An auto install module may depend on other auto install
+// modules, which must be registered before it.
+func resolveModules(mods ModulesList) (ModulesList, ModulesList, error) {
+	var installed, uninstalled ModulesList
+	registered := make(map[string]bool)
+	for _, mod := range mods {
+		registered[mod.Name] = true
+	}
+	installedNames := make(map[string]bool)
+	skippedNames := make(map[string]bool)
+modulesLoop:
+	for _, mod := range mods {
+		if mod.Manifest != nil {
+			for _, dep := range mod.Manifest.Depends {
+				if installedNames[dep] {
+					continue
+				}
+				if mod.Manifest.AutoInstall && (!registered[dep] || skippedNames[dep]) {
+					uninstalled = append(uninstalled, mod)
+					skippedNames[mod.Name] = true
+					continue modulesLoop
+				}
+				if !registered[dep] {
+					return nil, nil, fmt.Errorf("module %s depends on module %s which is not registered", mod.Name, dep)
+				}
+				return nil, nil, fmt.Errorf("module %s depends on module %s which must be registered before it", mod.Name, dep)
+			}
+		}
+		installed = append(installed, mod)
+		installedNames[mod.Name] = true
+	}
+	return installed, uninstalled, nil
+}
+
+// LintModule checks the module in the given directory and returns the
+// problems found. It checks that:
+//
+// - the manifest exists and is valid,
+// - the data and demo files declared in the manifest exist and are valid CSV
+// files with an 'id' column,
+// - the XML files of the resources directory parse,
+// - the PO files of the i18n directory parse.
+func LintModule(dir string) []error {
+	var res []error
+	manifest, err := ReadManifestFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		res = append(res, fmt.Errorf("%s: %s", ManifestFileName, err))
+	}
+	if manifest != nil {
+		for _, fileName := range append(append([]string{}, manifest.Data...), manifest.Demo...) {
+			if err := lintCSVFile(filepath.Join(dir, filepath.FromSlash(fileName))); err != nil {
+				res = append(res, fmt.Errorf("%s: %s", fileName, err))
+			}
+		}
+	}
+	xmlFiles, _ := filepath.Glob(filepath.Join(dir, "resources", "*.xml"))
+	for _, fileName := range xmlFiles {
+		doc := etree.NewDocument()
+		if err := doc.ReadFromFile(fileName); err != nil {
+			res = append(res, fmt.Errorf("%s: %s", fileName, err))
+			continue
+		}
+		if doc.FindElement("hexya/data") == nil {
+			res = append(res, fmt.Errorf("%s: no hexya/data element", fileName))
+		}
+	}
+	poFiles, _ := filepath.Glob(filepath.Join(dir, "i18n", "*.po"))
+	for _, fileName := range poFiles {
+		if _, err := po.Load(fileName); err != nil {
+			res = append(res, fmt.Errorf("%s: %s", fileName, err))
+		}
+	}
+	return res
+}
+
+// lintCSVFile checks that the given file is a valid CSV data
+// file with an 'id' column.
+func lintCSVFile(fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("empty file")
+	}
+	for _, header := range records[0] {
+		if header == "id" {
+			return nil
+		}
+	}
+	return fmt.Errorf("no 'id' column")
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestManifest(t *testing.T) {
+	Convey("Testing module manifests", t, func() {
+		Convey("Valid manifests should be parsed", func() {
+			m, err := ParseManifest([]byte(`{"name": "sale", "version": "1.2.0", "depends": ["base"],
+				"data": ["data/Sequence.csv"], "auto_install": true}`))
+			So(err, ShouldBeNil)
+			So(m.Name, ShouldEqual, "sale")
+			So(m.Depends, ShouldResemble, []string{"base"})
+			So(m.AutoInstall, ShouldBeTrue)
+		})
+		Convey("Invalid manifests should be rejected", func() {
+			for _, content := range []string{
+				`{"name": "sale"`,
+				`{"name": "sale", "version": "1.0", "unknown": true}`,
+				`{"name": "Sale", "version": "1.0"}`,
+				`{"name": "sale", "version": "v1"}`,
+				`{"name": "sale", "version": "1.0", "depends": ["sale"]}`,
+				`{"name": "sale", "version": "1.0", "depends": ["base", "base"]}`,
+				`{"name": "sale", "version": "1.0", "data": ["../base/data/Partner.csv"]}`,
+				`{"name": "sale", "version": "1.0", "demo": ["data/Partner.csv"]}`,
+			} {
+				_, err := ParseManifest([]byte(content))
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("Data files should follow the manifest order", func() {
+			resDir, err := ioutil.TempDir("", "hexya-manifest")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(resDir)
+			dataDir := filepath.Join(resDir, "data", "manifest_test")
+			So(os.MkdirAll(dataDir, 0755), ShouldBeNil)
+			for _, name := range []string{"A.csv", "B.csv", "C.csv"} {
+				So(ioutil.WriteFile(filepath.Join(dataDir, name), []byte("id\n"), 0644), ShouldBeNil)
+			}
+			mod := &Module{Name: "manifest_test"}
+			oldModules := Modules
+			Modules = ModulesList{mod}
+			defer func() { Modules = oldModules }()
+			So(dataFiles(resDir, "data", "csv"), ShouldResemble, []string{
+				filepath.Join(dataDir, "A.csv"), filepath.Join(dataDir, "B.csv"), filepath.Join(dataDir, "C.csv")})
+			mod.Manifest = &Manifest{Name: "manifest_test", Version: "1.0", Data: []string{"data/C.csv", "data/A.csv"}}
+			So(dataFiles(resDir, "data", "csv"), ShouldResemble, []string{
+				filepath.Join(dataDir, "C.csv"), filepath.Join(dataDir, "A.csv")})
+		})
+		Convey("Auto install modules should be installed only if their dependencies are", func() {
+			mod := func(name string, autoInstall bool, depends ...string) *Module {
+				return &Module{Name: name, Manifest: &Manifest{Name: name, Version: "1.0", Depends: depends, AutoInstall: autoInstall}}
+			}
+			base, sale, stock := mod("base", false), mod("sale", false, "base"), mod("stock", false, "base")
+			saleStock := mod("sale_stock", true, "sale", "stock")
+			saleMrp := mod("sale_mrp", true, "sale", "mrp")
+			saleMrpStock := mod("sale_mrp_stock", true, "sale_mrp", "stock")
+			installed, uninstalled, err := resolveModules(ModulesList{base, sale, stock, saleStock, saleMrp, saleMrpStock, {Name: "legacy"}})
+			So(err, ShouldBeNil)
+			So(installed.Names(), ShouldResemble, []string{"base", "sale", "stock", "sale_stock", "legacy"})
+			So(uninstalled, ShouldResemble, ModulesList{saleMrp, saleMrpStock})
+			_, _, err = resolveModules(ModulesList{sale, base})
+			So(err, ShouldNotBeNil)
+			_, _, err = resolveModules(ModulesList{base, mod("mrp", false, "stock")})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Linting should report missing and invalid files", func() {
+			modDir, err := ioutil.TempDir("", "hexya-lint")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(modDir)
+			So(LintModule(modDir), ShouldHaveLength, 1)
+			So(os.MkdirAll(filepath.Join(modDir, "data"), 0755), ShouldBeNil)
+			So(os.MkdirAll(filepath.Join(modDir, "resources"), 0755), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(modDir, ManifestFileName), []byte(`{"name": "lint", "version": "1.0",
+				"data": ["data/Partner.csv", "data/Missing.csv", "data/NoID.csv"]}`), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(modDir, "data", "Partner.csv"), []byte("id,Name\np1,Partner 1\n"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(modDir, "data", "NoID.csv"), []byte("Name\nPartner 1\n"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(modDir, "resources", "views.xml"), []byte("<hexya><data></data></hexya>"), 0644), ShouldBeNil)
+			So(ioutil.WriteFile(filepath.Join(modDir, "resources", "bad.xml"), []byte("<hexya><data attr=></data></hexya>"), 0644), ShouldBeNil)
+			problems := LintModule(modDir)
+			So(problems, ShouldHaveLength, 3)
+		})
+	})
+}
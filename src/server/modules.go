@@ -16,7 +16,9 @@ package server
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -26,7 +28,11 @@ import (
 	"github.com/hexya-erp/hexya/src/i18n"
 	"github.com/hexya-erp/hexya/src/menus"
 	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/paperformats"
 	"github.com/hexya-erp/hexya/src/templates"
+	"github.com/hexya-erp/hexya/src/tools/assetfs"
+	"github.com/hexya-erp/hexya/src/tools/startup"
+	"github.com/hexya-erp/hexya/src/tools/xmlutils"
 	"github.com/hexya-erp/hexya/src/views"
 )
 
@@ -40,6 +46,8 @@ type Module struct {
 	Name     string
 	PreInit  func() // Function to be run before bootstrap but after all calls to init
 	PostInit func() // Function to be run after initialisation is complete and before server starts
+	// Manifest of the module, if it has one. It is set by LoadManifests.
+	Manifest *Manifest
 }
 
 // A ModulesList is a list of Module objects
@@ -57,6 +65,11 @@ func (ml *ModulesList) Names() []string {
 // Modules is the list of activated modules in the application
 var Modules ModulesList
 
+// UninstalledModules is the list of the modules of the application that are
+// not activated. These are the auto install modules whose dependencies are not
+// all installed. It is set by LoadManifests.
+var UninstalledModules ModulesList
+
 // RegisterModule registers the given module in the server
 // This function should be called in the init() function of
 // all Hexya Addons.
@@ -68,9 +81,28 @@ func RegisterModule(mod *Module) {
 // - views,
 // - actions,
 // - menu items
+// - templates
+// - paper formats
 // Internal resources are defined in XML files.
+//
+// Files are parsed concurrently, then loaded in the order of the modules.
+// The objects loaded from the files of each module are recorded and
+// can be retrieved with ModuleContributions.
 func LoadInternalResources(resourceDir string) {
-	loadData(resourceDir, "resources", "xml", loadXMLResourceFile)
+	fsys := assetfs.Resources(resourceDir)
+	files := resourceFiles(fsys, "resources", "xml")
+	resources := make([]*xmlResource, len(files))
+	startup.Each(len(files), func(i int) {
+		content, err := assetfs.ReadFile(fsys, files[i])
+		if err != nil {
+			log.Panic("Error reading XML data file", "file", files[i], "error", err)
+		}
+		resources[i] = parseXMLResource(filepath.Join(resourceDir, filepath.FromSlash(files[i])), content)
+	})
+	resetContributions()
+	for _, resource := range resources {
+		resource.load()
+	}
 }
 
 // LoadDataRecords loads all the data records in the 'data' directory into the database.
@@ -93,18 +125,28 @@ func LoadTranslations(resourceDir string, langs []string) {
 			langs = append(langs[:i], append(i18n.GetAllLanguageList(), langs[i+1:]...)...)
 		}
 	}
+	fsys := assetfs.Resources(resourceDir)
+	var poFiles []string
 	for _, mod := range Modules {
-		dataDir := filepath.Join(resourceDir, "i18n", mod.Name)
-		if _, err := os.Stat(dataDir); err != nil {
-			// No resources dir in this module
-			continue
+		dataDir := path.Join("i18n", mod.Name)
+		for _, lang := range langs {
+			fileName := path.Join(dataDir, lang+".po")
+			if assetfs.Exists(fsys, fileName) {
+				poFiles = append(poFiles, fileName)
+			}
 		}
-		LoadModuleTranslations(dataDir, langs)
 	}
+	i18n.LoadPOFilesFS(fsys, poFiles)
 }
 
 // LoadModuleTranslations loads the PO files in the given directory for the given languages
 func LoadModuleTranslations(i18nDir string, langs []string) {
+	i18n.LoadPOFiles(moduleTranslationFiles(i18nDir, langs))
+}
+
+// moduleTranslationFiles returns the sorted list of the PO files
+// in the given directory for the given languages.
+func moduleTranslationFiles(i18nDir string, langs []string) []string {
 	var poFiles []string
 	for _, lang := range langs {
 		abs, _ := filepath.Abs(i18nDir)
@@ -116,49 +158,123 @@ func LoadModuleTranslations(i18nDir string, langs []string) {
 	}
 	dataFilesSorted := sort.StringSlice(poFiles)
 	dataFilesSorted.Sort()
-	for _, dataFile := range dataFilesSorted {
-		i18n.LoadPOFile(dataFile)
-	}
+	return dataFilesSorted
 }
 
 // loadData loads the files in the given dir with the given extension (without .)
 // using the loader function.
 func loadData(resourceDir, dir, ext string, loader func(string)) {
+	for _, dataFile := range dataFiles(resourceDir, dir, ext) {
+		loader(dataFile)
+	}
+}
+
+// resourceFiles returns the files in the given dir of fsys with the given
+// extension (without .) in the order they must be loaded, that is sorted by
+// module, then by name. Returned file names are relative to the root of fsys.
+func resourceFiles(fsys http.FileSystem, dir, ext string) []string {
+	var res []string
 	for _, mod := range Modules {
-		dataDir := filepath.Join(resourceDir, dir, mod.Name)
-		if _, err := os.Stat(dataDir); err != nil {
+		dataDir := path.Join(dir, mod.Name)
+		entries, err := assetfs.ReadDir(fsys, dataDir)
+		if err != nil {
 			// No resources dir in this module
 			continue
 		}
-		dataFiles, err := filepath.Glob(fmt.Sprintf("%s/*.%s", dataDir, ext))
-		if err != nil {
-			log.Panic("Unable to scan directory for data files", "dir", dataDir, "type", ext, "error", err)
-		}
-		dataFilesSorted := sort.StringSlice(dataFiles)
-		dataFilesSorted.Sort()
-		for _, dataFile := range dataFilesSorted {
-			loader(dataFile)
+		for _, entry := range entries {
+			if entry.IsDir() || path.Ext(entry.Name()) != "."+ext {
+				continue
+			}
+			res = append(res, path.Join(dataDir, entry.Name()))
 		}
 	}
+	return res
+}
+
+// dataFiles returns the files in the given dir with the given extension (without .)
+// in the order they must be loaded, that is sorted by module, then by name.
+//
+// If the manifest of a module declares the files of this dir, they are
+// returned in the order of the manifest instead.
+func dataFiles(resourceDir, dir, ext string) []string {
+	var res []string
+	for _, mod := range Modules {
+		res = append(res, moduleDataFiles(resourceDir, mod, dir, ext)...)
+	}
+	return res
+}
+
+// moduleDataFiles returns the files of the given module in the given dir with
+// the given extension (without .) in the order they must be loaded.
+func moduleDataFiles(resourceDir string, mod *Module, dir, ext string) []string {
+	if files, ok := manifestFiles(resourceDir, mod, dir); ok {
+		return files
+	}
+	dataDir := filepath.Join(resourceDir, dir, mod.Name)
+	if _, err := os.Stat(dataDir); err != nil {
+		// No resources dir in this module
+		return nil
+	}
+	dataFiles, err := filepath.Glob(fmt.Sprintf("%s/*.%s", dataDir, ext))
+	if err != nil {
+		log.Panic("Unable to scan directory for data files", "dir", dataDir, "type", ext, "error", err)
+	}
+	dataFilesSorted := sort.StringSlice(dataFiles)
+	dataFilesSorted.Sort()
+	return dataFilesSorted
 }
 
-// loadXMLResourceFile loads the data from an XML data file into memory.
-func loadXMLResourceFile(fileName string) {
+// An xmlResource is a parsed XML data file
+type xmlResource struct {
+	fileName string
+	doc      *etree.Document
+	lines    []int
+}
+
+// parseXMLResource parses the given content of the given XML data file.
+// It does not modify any registry and can be called concurrently.
+func parseXMLResource(fileName string, content []byte) *xmlResource {
 	doc := etree.NewDocument()
-	if err := doc.ReadFromFile(fileName); err != nil {
+	if err := doc.ReadFromBytes(content); err != nil {
 		log.Panic("Error loading XML data file", "file", fileName, "error", err)
 	}
-	for _, dataTag := range doc.FindElements("hexya/data") {
+	lines, err := xmlutils.ChildLines(content, "hexya", "data")
+	if err != nil {
+		log.Warn("Unable to find line numbers in XML data file", "file", fileName, "error", err)
+	}
+	return &xmlResource{
+		fileName: fileName,
+		doc:      doc,
+		lines:    lines,
+	}
+}
+
+// load loads the data of this xmlResource into the registries
+func (xr *xmlResource) load() {
+	fileName, lines := xr.fileName, xr.lines
+	source := views.Source{
+		Module: filepath.Base(filepath.Dir(fileName)),
+		File:   fileName,
+	}
+	var index int
+	for _, dataTag := range xr.doc.FindElements("hexya/data") {
 		for _, object := range dataTag.ChildElements() {
+			if index < len(lines) {
+				source.Line = lines[index]
+			}
+			index++
+			addContribution(source.Module, object)
 			switch object.Tag {
 			case "view":
-				views.LoadFromEtree(object)
+				views.LoadFromEtreeWithSource(object, source)
 			case "action":
 				actions.LoadFromEtree(object)
 			case "menuitem":
 				menus.LoadFromEtree(object)
 			case "template":
 				templates.LoadFromEtree(object)
+			case "paperformat":
+				paperformats.LoadFromEtree(object)
 			default:
 				log.Panic("Unknown XML tag", "filename", fileName, "tag", object.Tag)
 			}
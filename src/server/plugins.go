@@ -0,0 +1,86 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// PluginExt is the file extension of the plugin modules
+const PluginExt = ".so"
+
+// LoadPlugins loads the Go plugins found in the given directory, in the
+// order of their file names. Loading a plugin runs its init functions, so
+// that a plugin module registers itself with RegisterModule and declares
+// its models exactly like a compiled in module.
+//
+// A plugin module is built with
+//
+//	go build -buildmode=plugin -o mymodule.so github.com/myorg/mymodule
+//
+// with the same Go version and the same versions of all the packages it
+// shares with the server binary, including hexya and the generated pool.
+// Since the pool of the binary does not know the models declared by plugins,
+// plugins must access them through the models package API (e.g.
+// models.Registry.MustGet) rather than through the pool. The resources of a
+// plugin module (static files, XML resources, translations, etc.) must be
+// installed in the resource directory of the server, like those of other
+// modules.
+//
+// Plugins must be loaded before the models are bootstrapped. Go plugins
+// cannot be unloaded, so that removing a plugin requires a restart. Plugins
+// are only supported on the platforms supported by the Go plugin package.
+//
+// It returns the names of the modules registered by the plugins.
+func LoadPlugins(dir string) ([]string, error) {
+	fileNames, err := filepath.Glob(filepath.Join(dir, "*"+PluginExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(fileNames)
+	existing := make(map[string]bool)
+	for _, mod := range Modules {
+		existing[mod.Name] = true
+	}
+	var res []string
+	for _, fileName := range fileNames {
+		nbModules := len(Modules)
+		if _, err := plugin.Open(fileName); err != nil {
+			return res, fmt.Errorf("unable to load plugin %s: %s", fileName, err)
+		}
+		if len(Modules) == nbModules {
+			log.Warn("Plugin did not register any module", "plugin", fileName)
+		}
+		for _, mod := range Modules[nbModules:] {
+			if existing[mod.Name] {
+				return res, fmt.Errorf("plugin %s registers module %s which is already registered", fileName, mod.Name)
+			}
+			existing[mod.Name] = true
+			res = append(res, mod.Name)
+			log.Info("Loaded plugin module", "plugin", fileName, "module", mod.Name)
+		}
+	}
+	return res, nil
+}
+
+// loadPlugins loads the plugins of the Server.PluginDir directory
+// if it is set in the configuration.
+func loadPlugins() {
+	dir := viper.GetString("Server.PluginDir")
+	if dir == "" {
+		return
+	}
+	if _, err := os.Stat(dir); err != nil {
+		log.Panic("Unable to access plugin directory", "dir", dir, "error", err)
+	}
+	if _, err := LoadPlugins(dir); err != nil {
+		log.Panic("Error while loading plugins", "dir", dir, "error", err)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadPlugins(t *testing.T) {
+	Convey("Testing plugin loading", t, func() {
+		dir, err := ioutil.TempDir("", "hexya-plugins")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		Convey("A directory without plugins should load nothing", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, "README"), []byte("no plugin"), 0644), ShouldBeNil)
+			mods, err := LoadPlugins(dir)
+			So(err, ShouldBeNil)
+			So(mods, ShouldBeEmpty)
+		})
+		Convey("An invalid plugin file should return an error", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, "invalid.so"), []byte("not a plugin"), 0644), ShouldBeNil)
+			nbModules := len(Modules)
+			_, err := LoadPlugins(dir)
+			So(err, ShouldNotBeNil)
+			So(Modules, ShouldHaveLength, nbModules)
+		})
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/pprof"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/spf13/viper"
+)
+
+// ProfilingPath is the path prefix of the pprof profiling endpoints
+const ProfilingPath = "/debug/pprof"
+
+// defaultProfilingAllowedIPs are the IP addresses allowed to access the
+// profiling endpoints if Server.ProfilingAllowedIPs is not set.
+var defaultProfilingAllowedIPs = []string{"127.0.0.1", "::1"}
+
+// RestrictPath returns a middleware that aborts with a 403 Forbidden status
+// the requests to paths starting with the given prefix that do not come
+// from one of the given networks.
+func RestrictPath(prefix string, networks []*net.IPNet) HandlerFunc {
+	return func(c *Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, prefix) || networksContain(networks, c.RemoteIP()) {
+			c.Next()
+			return
+		}
+		log.Warn("Access to restricted path refused", "path", c.Request.URL.Path, "ip", c.RemoteIP())
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+// methodProfiles is the controller of the method layers profiling endpoint.
+// It returns the profiling statistics of the method layers, and clears them
+// afterwards if the 'reset' query parameter is set.
+func methodProfiles(c *Context) {
+	res := models.MethodProfiles()
+	if c.Query("reset") != "" {
+		models.ResetMethodProfiles()
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// setupProfiling registers the pprof profiling endpoints under ProfilingPath
+// if Server.Profiling or Debug is set in the configuration.
+//
+// The method layers profiling endpoint is also registered as methods under
+// ProfilingPath. Method layers are only profiled if Server.MethodProfiling
+// is set, since profiling slows down method calls.
+//
+// The endpoints can only be accessed from the addresses or networks listed
+// in Server.ProfilingAllowedIPs, which defaults to the loopback addresses.
+func setupProfiling() {
+	if !viper.GetBool("Server.Profiling") && !viper.GetBool("Debug") {
+		return
+	}
+	allowed := viper.GetStringSlice("Server.ProfilingAllowedIPs")
+	if len(allowed) == 0 {
+		allowed = defaultProfilingAllowedIPs
+	}
+	networks, err := ParseNetworks(allowed)
+	if err != nil {
+		log.Panic("Invalid profiling allowed IPs", "error", err)
+	}
+	hexyaServer.AddMiddleWare(RestrictPath(ProfilingPath, networks))
+	pprof.Register(hexyaServer.Engine, ProfilingPath)
+	hexyaServer.Group(ProfilingPath).GET("/methods", methodProfiles)
+	models.EnableMethodProfiling(viper.GetBool("Server.MethodProfiling"))
+}
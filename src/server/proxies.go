@@ -0,0 +1,70 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// trustedProxies are the networks of the reverse proxies whose
+// X-Forwarded-For and X-Real-Ip headers are trusted by RemoteIP.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the networks of the reverse proxies whose
+// X-Forwarded-For and X-Real-Ip headers are trusted by RemoteIP.
+func SetTrustedProxies(networks []*net.IPNet) {
+	trustedProxies = networks
+}
+
+// RemoteIP returns the IP address of the client of this request.
+//
+// Contrary to ClientIP, the X-Forwarded-For and X-Real-Ip headers are only
+// taken into account if the request comes from one of the trusted proxies.
+// In this case, the client is the first address of X-Forwarded-For that is
+// not itself a trusted proxy, starting from the right. Otherwise, this is
+// the address of the peer of the connection.
+//
+// RemoteIP must be used instead of ClientIP whenever the address is used for
+// a security decision, since the headers can be set by any client.
+func (c *Context) RemoteIP() string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		ip = strings.TrimSpace(c.Request.RemoteAddr)
+	}
+	if !networksContain(trustedProxies, ip) {
+		return ip
+	}
+	if forwarded := c.Request.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				// Malformed header, we stop at the last hop we could trust
+				return ip
+			}
+			ip = hop
+			if !networksContain(trustedProxies, hop) {
+				return hop
+			}
+		}
+		return ip
+	}
+	if realIP := strings.TrimSpace(c.Request.Header.Get("X-Real-Ip")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return ip
+}
+
+// setupTrustedProxies reads the trusted proxies from the Server.TrustedProxies
+// configuration key.
+func setupTrustedProxies() {
+	networks, err := ParseNetworks(viper.GetStringSlice("Server.TrustedProxies"))
+	if err != nil {
+		log.Panic("Invalid trusted proxies", "error", err)
+	}
+	SetTrustedProxies(networks)
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+// RejectPrivateMethods returns a middleware that aborts with a 403 Forbidden
+// status the JSON-RPC calls to private model methods (see RPCCall and
+// models.Model.NewPrivateMethod).
+//
+// Private methods are also rejected by models.RecordCollection.CallRPC.
+// This middleware makes sure that they cannot be called remotely even by
+// dispatchers that call model methods with CallMulti.
+func RejectPrivateMethods() HandlerFunc {
+	return func(c *Context) {
+		call, ok := c.RPCCall()
+		if ok && models.IsPrivateRPCMethod(call.Model, call.Method) {
+			log.Warn("RPC call to a private method rejected", "model", call.Model, "method", call.Method,
+				"path", c.Request.URL.Path, "ip", c.RemoteIP())
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// setupPrivateMethods adds the RejectPrivateMethods middleware to the server
+func setupPrivateMethods() {
+	hexyaServer.AddMiddleWare(RejectPrivateMethods())
+}
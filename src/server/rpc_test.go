@@ -0,0 +1,48 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPrivateMethods(t *testing.T) {
+	model := models.NewModel("RPCTestModel")
+	model.NewMethod("PublicAction", func(rc *models.RecordCollection) {})
+	model.NewPrivateMethod("PrivateAction", func(rc *models.RecordCollection) {})
+	Convey("Testing RPC calls to private methods", t, func() {
+		srv := &Server{Engine: gin.New()}
+		srv.AddMiddleWare(RejectPrivateMethods())
+		srv.Group("/").POST("/call_kw", func(c *Context) {
+			c.String(http.StatusOK, "called")
+		})
+		call := func(model, method string) *httptest.ResponseRecorder {
+			payload := `{"jsonrpc":"2.0","id":1,"params":{"model":"` + model + `","method":"` + method + `","args":[[1]]}}`
+			req := httptest.NewRequest(http.MethodPost, "/call_kw", strings.NewReader(payload))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			return w
+		}
+		Convey("Private methods should be rejected whatever their name case", func() {
+			So(models.IsPrivateRPCMethod("RPCTestModel", "private_action"), ShouldBeTrue)
+			So(call("RPCTestModel", "private_action").Code, ShouldEqual, http.StatusForbidden)
+			So(call("RPCTestModel", "PrivateAction").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Public methods and other calls should be dispatched", func() {
+			So(models.IsPrivateRPCMethod("RPCTestModel", "public_action"), ShouldBeFalse)
+			w := call("RPCTestModel", "public_action")
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Body.String(), ShouldEqual, "called")
+			So(call("UnknownModel", "private_action").Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
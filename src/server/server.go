@@ -43,6 +43,13 @@ func (s *Server) Group(relativePath string, handlers ...HandlerFunc) *RouterGrou
 	}
 }
 
+// AddMiddleWare adds the given fnct as a global middleware of this server.
+// Global middlewares are executed for every request, including requests that
+// do not match any route.
+func (s *Server) AddMiddleWare(fnct HandlerFunc) {
+	s.Use(wrapContextFuncs(fnct)...)
+}
+
 // Run attaches the router to a http.Server and starts listening and serving HTTP requests.
 // It is a shortcut for http.ListenAndServe(addr, router)
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
@@ -128,6 +135,13 @@ type JSONRPCError struct {
 	Data    interface{} `json:"data"`
 }
 
+// Version is the version of the Hexya server
+const Version = "0.1"
+
+// VersionInfo is the version of the Hexya server in the
+// (major, minor, micro, release level, serial) format.
+var VersionInfo = []interface{}{0, 1, 0, "final", 0}
+
 var hexyaServer *Server
 var log logging.Logger
 
@@ -152,8 +166,23 @@ func init() {
 // PreInit runs all actions that need to be done after we get the configuration,
 // but before bootstrap.
 //
-// This function runs successively all PreInit() func of modules
+// This function:
+// - sets up tracing and the profiling endpoints according to the configuration,
+// - sets up the request limits middlewares according to the configuration,
+// - sets up the session security middleware according to the configuration,
+// - sets up the idempotency keys middleware,
+// - rejects the RPC calls to private model methods,
+// - loads the module plugins of the plugin directory if it is configured,
+// - runs successively all PreInit() func of modules.
 func PreInit() {
+	setupTrustedProxies()
+	setupTracing()
+	setupProfiling()
+	setupLimits()
+	setupSessionPolicies()
+	setupIdempotencyKeys()
+	setupPrivateMethods()
+	loadPlugins()
 	PreInitModules()
 }
 
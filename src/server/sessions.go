@@ -0,0 +1,454 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/spf13/viper"
+)
+
+// Session keys of the data used to enforce session policies
+const (
+	sessionIDKey        = "session_id"
+	sessionUIDKey       = "session_uid"
+	sessionLoginTimeKey = "login_time"
+	sessionLastSeenKey  = "last_seen"
+	sessionLoginIPKey   = "login_ip"
+)
+
+// lastSeenResolution is the minimum duration between two updates
+// of the last seen time stored in a session.
+const lastSeenResolution = time.Minute
+
+// staleSessionTimeout is the duration after which a session that has
+// not been seen is removed from the registry of active sessions.
+const staleSessionTimeout = 24 * time.Hour
+
+// A SessionPolicy defines the security rules of the sessions of a user.
+// Zero values disable the corresponding rule.
+type SessionPolicy struct {
+	// IdleTimeout is the duration after which a session without
+	// any request is closed.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout is the duration after login at which a
+	// session is closed, whatever its activity.
+	AbsoluteTimeout time.Duration
+	// BindIP binds sessions to the IP address from which the user
+	// logged in. Requests from other addresses are rejected.
+	//
+	// As for AllowedNetworks, the IP address is given by Context.RemoteIP
+	// so that forwarding headers are only trusted from trusted proxies.
+	BindIP bool
+	// AllowedNetworks is the list of networks from which the user
+	// is allowed to connect.
+	AllowedNetworks []*net.IPNet
+	// MaxSessions is the maximum number of concurrent sessions of the
+	// user. The oldest sessions are closed when the user logs in again.
+	MaxSessions int
+}
+
+// isZero returns true if this policy does not enforce any rule
+func (sp SessionPolicy) isZero() bool {
+	return sp.IdleTimeout == 0 && sp.AbsoluteTimeout == 0 && !sp.BindIP && len(sp.AllowedNetworks) == 0 && sp.MaxSessions == 0
+}
+
+// allowsIP returns true if the given IP address is in the allowed networks
+// of this policy, or if this policy has no allowed networks.
+func (sp SessionPolicy) allowsIP(ip string) bool {
+	if len(sp.AllowedNetworks) == 0 {
+		return true
+	}
+	return networksContain(sp.AllowedNetworks, ip)
+}
+
+// networksContain returns true if the given IP address
+// is in one of the given networks.
+func networksContain(networks []*net.IPNet, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks parses the given list of CIDR networks (e.g. "10.0.0.0/8")
+// or single IP addresses.
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	res := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address '%s'", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			res = append(res, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, network)
+	}
+	return res, nil
+}
+
+// SessionPolicies holds the session policies of all users.
+//
+// A user is subject to the default policy, to the policies of all its groups
+// and to its own policy. A session must comply with all of them, so that the
+// most restrictive rule applies.
+type SessionPolicies struct {
+	// Default is the policy applied to all users
+	Default SessionPolicy
+	// Groups are the policies of the members of groups, by group ID.
+	// Group IDs are case insensitive.
+	Groups map[string]SessionPolicy
+	// Users are the policies of single users, by user ID
+	Users    map[int64]SessionPolicy
+	registry *sessionRegistry
+}
+
+// NewSessionPolicies returns a new SessionPolicies instance with the given default policy
+func NewSessionPolicies(def SessionPolicy) *SessionPolicies {
+	return &SessionPolicies{
+		Default:  def,
+		Groups:   make(map[string]SessionPolicy),
+		Users:    make(map[int64]SessionPolicy),
+		registry: newSessionRegistry(),
+	}
+}
+
+// policiesFor returns all the policies the given user of the given database is subject to
+func (sp *SessionPolicies) policiesFor(db string, uid int64) []SessionPolicy {
+	res := []SessionPolicy{sp.Default}
+	if len(sp.Groups) > 0 {
+		for group := range security.Registry.ForDatabase(db).UserGroups(uid) {
+			if policy, ok := sp.Groups[strings.ToLower(group.ID)]; ok {
+				res = append(res, policy)
+			}
+		}
+	}
+	if policy, ok := sp.Users[uid]; ok {
+		res = append(res, policy)
+	}
+	return res
+}
+
+// maxSessions returns the smallest non zero MaxSessions of the given
+// policies or 0 if the number of sessions is not limited.
+func maxSessions(policies []SessionPolicy) int {
+	var res int
+	for _, policy := range policies {
+		if policy.MaxSessions > 0 && (res == 0 || policy.MaxSessions < res) {
+			res = policy.MaxSessions
+		}
+	}
+	return res
+}
+
+// errSessionExpired is returned when a session has timed out or has been
+// closed by a newer session of the same user.
+var errSessionExpired = errors.New("session expired")
+
+// check checks that the session of c complies with the policies of the given
+// user and updates its security data. It returns an error if it does not,
+// with the HTTP status with which the request must be aborted, or 0 if the
+// request can go on without the user being logged in.
+func (sp *SessionPolicies) check(c *Context, uid int64, now time.Time) (int, error) {
+	policies := sp.policiesFor(c.DBName(), uid)
+	ip := c.RemoteIP()
+	for _, policy := range policies {
+		if !policy.allowsIP(ip) {
+			return http.StatusForbidden, fmt.Errorf("IP address %s is not allowed", ip)
+		}
+	}
+	session := c.Session()
+	max := maxSessions(policies)
+	sessionID, _ := session.Get(sessionIDKey).(string)
+	sessionUID, _ := session.Get(sessionUIDKey).(int64)
+	if sessionID == "" || sessionUID != uid {
+		// The user has just logged in
+		if sessionID != "" {
+			sp.registry.remove(sessionKey(c, sessionUID), sessionID)
+		}
+		sessionID = newSessionID()
+		session.Set(sessionIDKey, sessionID)
+		session.Set(sessionUIDKey, uid)
+		session.Set(sessionLoginTimeKey, now.UnixNano())
+		session.Set(sessionLastSeenKey, now.UnixNano())
+		session.Set(sessionLoginIPKey, ip)
+		if err := session.Save(); err != nil {
+			log.Warn("Unable to save session", "error", err)
+		}
+		if max > 0 {
+			sp.registry.touch(sessionKey(c, uid), sessionID, now, now, max)
+		}
+		return 0, nil
+	}
+	loginTime, _ := session.Get(sessionLoginTimeKey).(int64)
+	lastSeen, _ := session.Get(sessionLastSeenKey).(int64)
+	for _, policy := range policies {
+		if policy.AbsoluteTimeout > 0 && now.Sub(time.Unix(0, loginTime)) > policy.AbsoluteTimeout {
+			return 0, errSessionExpired
+		}
+		if policy.IdleTimeout > 0 && now.Sub(time.Unix(0, lastSeen)) > policy.IdleTimeout {
+			return 0, errSessionExpired
+		}
+		if policy.BindIP && session.Get(sessionLoginIPKey) != ip {
+			return http.StatusForbidden, fmt.Errorf("IP address %s is not the login IP address", ip)
+		}
+	}
+	if max > 0 && !sp.registry.touch(sessionKey(c, uid), sessionID, time.Unix(0, loginTime), now, max) {
+		return 0, errSessionExpired
+	}
+	if now.Sub(time.Unix(0, lastSeen)) >= lastSeenResolution {
+		session.Set(sessionLastSeenKey, now.UnixNano())
+		if err := session.Save(); err != nil {
+			log.Warn("Unable to save session", "error", err)
+		}
+	}
+	return 0, nil
+}
+
+// sessionKey returns the key of the sessions of the given user
+// in the database of the session of c in the session registry.
+//
+// The database is read from the session, since this middleware
+// may run before the database of the request is selected.
+func sessionKey(c *Context, uid int64) string {
+	dbName, _ := c.Session().Get(DBNameKey).(string)
+	return fmt.Sprintf("%s:%d", dbName, uid)
+}
+
+// newSessionID returns a new random session ID
+func newSessionID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		log.Panic("Unable to generate session ID", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// SessionSecurity returns a middleware that enforces the given policies
+// on the sessions of logged in users.
+//
+// Sessions that have timed out or that have been closed by a newer session
+// are cleared, so that the request is served as if the user was logged out.
+// Requests from IP addresses that are not allowed are aborted with a
+// 403 Forbidden status and their session is cleared.
+//
+// When impersonating, the policies of the administrator apply.
+func SessionSecurity(sp *SessionPolicies) HandlerFunc {
+	return func(c *Context) {
+		if _, ok := c.Get(sessions.DefaultKey); !ok {
+			c.Next()
+			return
+		}
+		uid, ok := c.Session().Get("uid").(int64)
+		if !ok {
+			c.Next()
+			return
+		}
+		if realUID := c.RealUID(); realUID != 0 {
+			uid = realUID
+		}
+		status, err := sp.check(c, uid, time.Now())
+		if err != nil {
+			log.Info("Session closed by security policy", "uid", uid, "ip", c.RemoteIP(), "reason", err)
+			c.Session().Clear()
+			if err := c.Session().Save(); err != nil {
+				log.Warn("Unable to save session", "error", err)
+			}
+			if status != 0 {
+				c.AbortWithStatus(status)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// sessionPolicies are the policies set in the configuration, if any
+var sessionPolicies *SessionPolicies
+
+// EndSession clears the session of c and removes it from the active
+// sessions of its user. Logout controllers should call this method
+// so that the session does not count in the concurrent sessions limit.
+func (c *Context) EndSession() {
+	if sessionPolicies != nil {
+		uid, _ := c.Session().Get(sessionUIDKey).(int64)
+		sessionID, _ := c.Session().Get(sessionIDKey).(string)
+		sessionPolicies.registry.remove(sessionKey(c, uid), sessionID)
+	}
+	c.Session().Clear()
+	if err := c.Session().Save(); err != nil {
+		log.Warn("Unable to save session", "error", err)
+	}
+}
+
+// An activeSession is a session registered in a sessionRegistry
+type activeSession struct {
+	id        string
+	loginTime time.Time
+	lastSeen  time.Time
+}
+
+// A sessionRegistry keeps track of the active sessions of users to limit
+// their number. Sessions are kept in memory only, so that sessions opened
+// before a restart are registered again at their next request.
+type sessionRegistry struct {
+	sync.Mutex
+	sessions  map[string][]*activeSession
+	revoked   map[string]time.Time
+	lastClean time.Time
+}
+
+// newSessionRegistry returns a new empty sessionRegistry
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{
+		sessions:  make(map[string][]*activeSession),
+		revoked:   make(map[string]time.Time),
+		lastClean: time.Now(),
+	}
+}
+
+// touch registers the session with the given id as an active session of the
+// user with the given key. The oldest sessions of the user are revoked so
+// that there are no more than max sessions.
+//
+// It returns false if the session has been revoked.
+func (sr *sessionRegistry) touch(key, id string, loginTime, now time.Time, max int) bool {
+	sr.Lock()
+	defer sr.Unlock()
+	sr.cleanStaleSessions(now)
+	if _, ok := sr.revoked[id]; ok {
+		sr.revoked[id] = now
+		return false
+	}
+	for _, s := range sr.sessions[key] {
+		if s.id == id {
+			s.lastSeen = now
+			return true
+		}
+	}
+	active := append(sr.sessions[key], &activeSession{id: id, loginTime: loginTime, lastSeen: now})
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].loginTime.Before(active[j].loginTime)
+	})
+	for len(active) > max {
+		sr.revoked[active[0].id] = now
+		active = active[1:]
+	}
+	sr.sessions[key] = active
+	_, revoked := sr.revoked[id]
+	return !revoked
+}
+
+// remove removes the session with the given id from the active sessions
+// of the user with the given key.
+func (sr *sessionRegistry) remove(key, id string) {
+	sr.Lock()
+	defer sr.Unlock()
+	active := sr.sessions[key]
+	for i, s := range active {
+		if s.id == id {
+			sr.sessions[key] = append(active[:i:i], active[i+1:]...)
+			break
+		}
+	}
+	if len(sr.sessions[key]) == 0 {
+		delete(sr.sessions, key)
+	}
+}
+
+// cleanStaleSessions removes the sessions that have not been seen for some time.
+// sr must be locked when calling this method.
+func (sr *sessionRegistry) cleanStaleSessions(now time.Time) {
+	if now.Sub(sr.lastClean) < staleBucketTimeout {
+		return
+	}
+	for key, active := range sr.sessions {
+		var res []*activeSession
+		for _, s := range active {
+			if now.Sub(s.lastSeen) <= staleSessionTimeout {
+				res = append(res, s)
+			}
+		}
+		if len(res) == 0 {
+			delete(sr.sessions, key)
+			continue
+		}
+		sr.sessions[key] = res
+	}
+	for id, lastSeen := range sr.revoked {
+		if now.Sub(lastSeen) > staleSessionTimeout {
+			delete(sr.revoked, id)
+		}
+	}
+	sr.lastClean = now
+}
+
+// sessionPolicyFromConfig returns the session policy defined
+// in the given section of the configuration.
+func sessionPolicyFromConfig(section string) SessionPolicy {
+	networks, err := ParseNetworks(viper.GetStringSlice(section + ".AllowedIPs"))
+	if err != nil {
+		log.Panic("Invalid allowed IPs in session policy", "section", section, "error", err)
+	}
+	return SessionPolicy{
+		IdleTimeout:     viper.GetDuration(section + ".IdleTimeout"),
+		AbsoluteTimeout: viper.GetDuration(section + ".AbsoluteTimeout"),
+		BindIP:          viper.GetBool(section + ".BindIP"),
+		AllowedNetworks: networks,
+		MaxSessions:     viper.GetInt(section + ".MaxSessions"),
+	}
+}
+
+// setupSessionPolicies adds the session security middleware to the server
+// if session policies are set in the configuration. The default policy is
+// set in the 'Server.Session' section of the configuration. Policies of groups and users are set in the
+// 'Server.Session.Groups.<groupID>' and 'Server.Session.Users.<uid>'
+// sections respectively.
+func setupSessionPolicies() {
+	sp := NewSessionPolicies(sessionPolicyFromConfig("Server.Session"))
+	for groupID := range viper.GetStringMap("Server.Session.Groups") {
+		sp.Groups[strings.ToLower(groupID)] = sessionPolicyFromConfig("Server.Session.Groups." + groupID)
+	}
+	for user := range viper.GetStringMap("Server.Session.Users") {
+		uid, err := strconv.ParseInt(user, 10, 64)
+		if err != nil {
+			log.Panic("Invalid user ID in session policy", "user", user, "error", err)
+		}
+		sp.Users[uid] = sessionPolicyFromConfig("Server.Session.Users." + user)
+	}
+	if sp.Default.isZero() && len(sp.Groups) == 0 && len(sp.Users) == 0 {
+		return
+	}
+	sessionPolicies = sp
+	hexyaServer.AddMiddleWare(SessionSecurity(sp))
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newSessionTestServer returns a server with the given session policies
+// and routes to log in and get the logged in user.
+func newSessionTestServer(sp *SessionPolicies) *Server {
+	srv := &Server{Engine: gin.New()}
+	srv.Use(sessions.Sessions("test-session", cookie.NewStore([]byte("secret"))))
+	srv.AddMiddleWare(SessionSecurity(sp))
+	srv.Group("/").GET("/login", func(c *Context) {
+		c.Session().Set("uid", int64(2))
+		c.Session().Save()
+		c.String(http.StatusOK, "ok")
+	})
+	srv.Group("/").GET("/uid", func(c *Context) {
+		uid, _ := c.Session().Get("uid").(int64)
+		c.String(http.StatusOK, strconv.FormatInt(uid, 10))
+	})
+	return srv
+}
+
+// A sessionClient sends requests to a server keeping the session cookie
+type sessionClient struct {
+	srv       *Server
+	ip        string
+	forwarded string
+	cookies   []*http.Cookie
+}
+
+// get sends a GET request to the given path and returns the response
+func (sc *sessionClient) get(path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = sc.ip + ":1234"
+	if sc.forwarded != "" {
+		req.Header.Set("X-Forwarded-For", sc.forwarded)
+		req.Header.Set("X-Real-Ip", sc.forwarded)
+	}
+	for _, c := range sc.cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	sc.srv.ServeHTTP(w, req)
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		sc.cookies = cookies
+	}
+	return w
+}
+
+func TestSessionPolicies(t *testing.T) {
+	Convey("Testing session policies", t, func() {
+		Convey("Networks should be parsed from CIDR and IP addresses", func() {
+			networks, err := ParseNetworks([]string{"10.0.0.0/8", "192.168.1.5", "::1"})
+			So(err, ShouldBeNil)
+			So(networks, ShouldHaveLength, 3)
+			policy := SessionPolicy{AllowedNetworks: networks}
+			So(policy.allowsIP("10.1.2.3"), ShouldBeTrue)
+			So(policy.allowsIP("192.168.1.5"), ShouldBeTrue)
+			So(policy.allowsIP("192.168.1.6"), ShouldBeFalse)
+			So(policy.allowsIP("::1"), ShouldBeTrue)
+			So(SessionPolicy{}.allowsIP("192.168.1.6"), ShouldBeTrue)
+			_, err = ParseNetworks([]string{"10.0.0.300"})
+			So(err, ShouldNotBeNil)
+			_, err = ParseNetworks([]string{"10.0.0.0/33"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Idle sessions should be closed", func() {
+			srv := newSessionTestServer(NewSessionPolicies(SessionPolicy{IdleTimeout: 50 * time.Millisecond}))
+			client := &sessionClient{srv: srv, ip: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			time.Sleep(100 * time.Millisecond)
+			So(client.get("/uid").Body.String(), ShouldEqual, "0")
+		})
+		Convey("Sessions should be closed after the absolute timeout", func() {
+			srv := newSessionTestServer(NewSessionPolicies(SessionPolicy{AbsoluteTimeout: 50 * time.Millisecond}))
+			client := &sessionClient{srv: srv, ip: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			time.Sleep(100 * time.Millisecond)
+			So(client.get("/uid").Body.String(), ShouldEqual, "0")
+		})
+		Convey("Requests from IP addresses that are not allowed should be forbidden", func() {
+			_, network, _ := net.ParseCIDR("10.0.0.0/8")
+			srv := newSessionTestServer(NewSessionPolicies(SessionPolicy{AllowedNetworks: []*net.IPNet{network}}))
+			client := &sessionClient{srv: srv, ip: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			client.ip = "192.168.1.1"
+			So(client.get("/uid").Code, ShouldEqual, http.StatusForbidden)
+			client.ip = "10.0.0.1"
+			So(client.get("/uid").Body.String(), ShouldEqual, "0")
+		})
+		Convey("Sessions bound to their IP address should be closed when it changes", func() {
+			sp := NewSessionPolicies(SessionPolicy{})
+			sp.Users[2] = SessionPolicy{BindIP: true}
+			srv := newSessionTestServer(sp)
+			client := &sessionClient{srv: srv, ip: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			client.ip = "10.0.0.2"
+			So(client.get("/uid").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Forwarding headers should not bypass IP restrictions", func() {
+			_, network, _ := net.ParseCIDR("10.0.0.0/8")
+			srv := newSessionTestServer(NewSessionPolicies(SessionPolicy{AllowedNetworks: []*net.IPNet{network}, BindIP: true}))
+			client := &sessionClient{srv: srv, ip: "192.168.1.1", forwarded: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Code, ShouldEqual, http.StatusForbidden)
+			client = &sessionClient{srv: srv, ip: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			client.ip = "192.168.1.1"
+			client.forwarded = "10.0.0.1"
+			So(client.get("/uid").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Forwarding headers of trusted proxies should be used", func() {
+			_, network, _ := net.ParseCIDR("10.0.0.0/8")
+			_, proxies, _ := net.ParseCIDR("172.16.0.0/12")
+			SetTrustedProxies([]*net.IPNet{proxies})
+			defer SetTrustedProxies(nil)
+			srv := newSessionTestServer(NewSessionPolicies(SessionPolicy{AllowedNetworks: []*net.IPNet{network}}))
+			client := &sessionClient{srv: srv, ip: "172.16.0.1", forwarded: "10.0.0.1"}
+			client.get("/login")
+			So(client.get("/uid").Body.String(), ShouldEqual, "2")
+			client.forwarded = "192.168.1.1"
+			So(client.get("/uid").Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Oldest sessions should be closed beyond the concurrent sessions limit", func() {
+			srv := newSessionTestServer(NewSessionPolicies(SessionPolicy{MaxSessions: 1}))
+			client1 := &sessionClient{srv: srv, ip: "10.0.0.1"}
+			client1.get("/login")
+			So(client1.get("/uid").Body.String(), ShouldEqual, "2")
+			client2 := &sessionClient{srv: srv, ip: "10.0.0.2"}
+			client2.get("/login")
+			So(client2.get("/uid").Body.String(), ShouldEqual, "2")
+			So(client1.get("/uid").Body.String(), ShouldEqual, "0")
+			So(client2.get("/uid").Body.String(), ShouldEqual, "2")
+		})
+	})
+}
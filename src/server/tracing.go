@@ -0,0 +1,80 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/hexya-erp/hexya/src/tools/tracing"
+	"github.com/spf13/viper"
+)
+
+// traceSpanKey is the key of the span of the request in the context
+const traceSpanKey = "trace_span"
+
+// TraceSpan returns the span of the request of c,
+// or nil if the request is not traced.
+func (c *Context) TraceSpan() *tracing.Span {
+	span, _ := c.Get(traceSpanKey)
+	res, _ := span.(*tracing.Span)
+	return res
+}
+
+// Tracing returns a middleware that traces each request in a span,
+// child of the span given by the W3C traceparent header of the request
+// if any. The method calls and the queries of the Environments created by
+// Context.ExecuteInNewEnvironment are traced in child spans.
+func Tracing() HandlerFunc {
+	return func(c *Context) {
+		span := tracing.StartRemoteSpan(c.GetHeader("traceparent"), c.Request.Method+" "+c.Request.URL.Path, tracing.KindServer)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		span.SetAttribute("http.client_ip", c.ClientIP())
+		c.Set(traceSpanKey, span)
+		completed := false
+		defer func() {
+			if !completed {
+				span.SetError("request panicked")
+				span.End()
+			}
+		}()
+		c.Next()
+		completed = true
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= http.StatusInternalServerError {
+			span.SetError("HTTP status " + strconv.Itoa(status))
+		}
+		if len(c.Errors) > 0 {
+			span.SetError(c.Errors.String())
+		}
+		span.End()
+	}
+}
+
+// setupTracing enables tracing if a tracing endpoint is set in the
+// Tracing.Endpoint configuration and adds the tracing middleware.
+//
+// Spans are exported to this endpoint with the OpenTelemetry protocol
+// (e.g. http://localhost:4318/v1/traces for Jaeger). Tracing.ServiceName
+// sets the name of this server in traces and Tracing.SampleRatio sets the
+// ratio of requests that are traced (1 by default).
+func setupTracing() {
+	endpoint := viper.GetString("Tracing.Endpoint")
+	if endpoint == "" {
+		return
+	}
+	serviceName := viper.GetString("Tracing.ServiceName")
+	if serviceName == "" {
+		serviceName = "hexya"
+	}
+	ratio := 1.0
+	if viper.IsSet("Tracing.SampleRatio") {
+		ratio = viper.GetFloat64("Tracing.SampleRatio")
+	}
+	tracing.Enable(tracing.NewOTLPExporter(endpoint, serviceName), ratio)
+	hexyaServer.AddMiddleWare(Tracing())
+	log.Info("Tracing enabled", "endpoint", endpoint, "sampleRatio", ratio)
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/tools/tracing"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type memoryExporter struct {
+	spans []*tracing.Span
+}
+
+func (me *memoryExporter) ExportSpans(spans []*tracing.Span) error {
+	me.spans = append(me.spans, spans...)
+	return nil
+}
+
+func TestDiagnostics(t *testing.T) {
+	Convey("Testing tracing and profiling", t, func() {
+		Convey("Requests should be traced in the trace of their caller", func() {
+			exporter := new(memoryExporter)
+			tracing.Enable(exporter, 1)
+			srv := &Server{Engine: gin.New()}
+			srv.AddMiddleWare(Tracing())
+			var child *tracing.Span
+			srv.Group("/").GET("/ping", func(c *Context) {
+				child = tracing.StartSpan(c.TraceSpan(), "child", tracing.KindInternal)
+				child.End()
+				c.String(http.StatusInternalServerError, "fail")
+			})
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			srv.ServeHTTP(httptest.NewRecorder(), req)
+			tracing.Disable()
+			So(exporter.spans, ShouldHaveLength, 2)
+			span := exporter.spans[1]
+			So(span.Name, ShouldEqual, "GET /ping")
+			So(span.TraceID.String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(span.ParentID.String(), ShouldEqual, "00f067aa0ba902b7")
+			So(span.Attributes["http.status_code"], ShouldEqual, http.StatusInternalServerError)
+			So(span.Error, ShouldNotBeEmpty)
+			So(child.ParentID, ShouldEqual, span.SpanID)
+		})
+		Convey("Restricted paths should only be accessible from allowed networks", func() {
+			networks, _ := ParseNetworks([]string{"127.0.0.1"})
+			srv := &Server{Engine: gin.New()}
+			srv.AddMiddleWare(RestrictPath(ProfilingPath, networks))
+			srv.Group("/").GET(ProfilingPath+"/heap", func(c *Context) {
+				c.String(http.StatusOK, "ok")
+			})
+			req := httptest.NewRequest(http.MethodGet, ProfilingPath+"/heap", nil)
+			req.RemoteAddr = "127.0.0.1:1234"
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			req.RemoteAddr = "10.0.0.1:1234"
+			w = httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package settings is a Hexya module that stores the runtime settings of
+// the application in the database.
+//
+// Settings are key/value ConfigParameter records that are read with the typed
+// getters of this package, such as GetInt or GetDuration, and changed with
+// SetParam. Parameters are cached for each database, and the cache is
+// invalidated when parameters are changed.
+//
+// Modules can set the default values of their parameters in their data files,
+// e.g. data/010-ConfigParameter.csv:
+//
+//	ID,Key,Value
+//	mymodule_param_timeout,mymodule.timeout,30s
+//
+// Since data files do not update existing records, values changed by
+// administrators are kept when the module is updated.
+//
+// Modules add their settings to the Settings wizard with RegisterSetting in their
+// init() function. Each Boolean or Selection field of the wizard is mapped either to
+// a parameter or to a group given to all users, and all the settings are saved by
+// the Execute method of the wizard.
+package settings
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "settings"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("settings")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package settings
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+)
+
+func declareModels() {
+	configParameter := models.NewModel("ConfigParameter")
+	configParameter.SetDefaultOrder("Key")
+	// Parameters may hold secrets, so that they can only be read and written by administrators
+	configParameter.NewMethod("GetParam", configParameter_GetParam).
+		RevokeGroup(security.GroupEveryone).AllowGroup(security.GroupAdmin)
+	configParameter.NewMethod("SetParam", configParameter_SetParam).
+		RevokeGroup(security.GroupEveryone).AllowGroup(security.GroupAdmin)
+	configParameter.AddEmptyMethod("Create").Extend(configParameter_Create)
+	configParameter.AddEmptyMethod("Write").Extend(configParameter_Write)
+	configParameter.AddEmptyMethod("Unlink").Extend(configParameter_Unlink)
+	configParameter.AddFields(map[string]models.FieldDefinition{
+		"Key":   fields.Char{Required: true, Unique: true, Index: true},
+		"Value": fields.Text{},
+	})
+
+	declareSettingsModel()
+}
+
+// configParameter_GetParam returns the value of the parameter with the
+// given key, or the given default value if the parameter does not exist.
+func configParameter_GetParam(rs *models.RecordCollection, key, defaultValue string) string {
+	return GetParam(rs.Env(), key, defaultValue)
+}
+
+// configParameter_SetParam sets the value of the parameter with the given key.
+// The parameter is created if it does not exist.
+func configParameter_SetParam(rs *models.RecordCollection, key, value string) {
+	SetParam(rs.Env(), key, value)
+}
+
+// configParameter_Create invalidates the cache of parameters
+func configParameter_Create(rc *models.RecordCollection, data models.RecordData) *models.RecordCollection {
+	res := rc.Super().Call("Create", data).(models.RecordSet).Collection()
+	invalidate(rc.Env().DBName())
+	return res
+}
+
+// configParameter_Write invalidates the cache of parameters
+func configParameter_Write(rc *models.RecordCollection, data models.RecordData) bool {
+	res := rc.Super().Call("Write", data).(bool)
+	invalidate(rc.Env().DBName())
+	return res
+}
+
+// configParameter_Unlink invalidates the cache of parameters
+func configParameter_Unlink(rc *models.RecordCollection) int64 {
+	res := rc.Super().Call("Unlink").(int64)
+	invalidate(rc.Env().DBName())
+	return res
+}
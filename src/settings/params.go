@@ -0,0 +1,135 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package settings
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+)
+
+const (
+	paramModel = "ConfigParameter"
+	// cacheTTL is the time after which the parameters of a database are reloaded,
+	// so that changes made by other processes are eventually taken into account.
+	cacheTTL = time.Minute
+)
+
+// cache holds the parameters of each database
+var cache struct {
+	sync.Mutex
+	byDB map[string]*dbParams
+}
+
+// dbParams are the cached parameters of a database
+type dbParams struct {
+	loaded time.Time
+	values map[string]string
+}
+
+// invalidate clears the cached parameters of the given database
+func invalidate(dbName string) {
+	cache.Lock()
+	defer cache.Unlock()
+	delete(cache.byDB, dbName)
+}
+
+// params returns the parameters of the database of env,
+// which are loaded if they are not cached yet.
+func params(env models.Environment) map[string]string {
+	dbName := env.DBName()
+	cache.Lock()
+	p, ok := cache.byDB[dbName]
+	cache.Unlock()
+	if ok && time.Since(p.loaded) < cacheTTL {
+		return p.values
+	}
+	p = &dbParams{loaded: time.Now(), values: make(map[string]string)}
+	rs := env.Pool(paramModel).Sudo()
+	mi := rs.Model()
+	for _, rec := range rs.SearchAll().Records() {
+		p.values[rec.Get(mi.FieldName("Key")).(string)] = rec.Get(mi.FieldName("Value")).(string)
+	}
+	cache.Lock()
+	if cache.byDB == nil {
+		cache.byDB = make(map[string]*dbParams)
+	}
+	cache.byDB[dbName] = p
+	cache.Unlock()
+	return p.values
+}
+
+// GetParam returns the value of the parameter with the given key,
+// or defaultValue if the parameter does not exist.
+//
+// Parameters are read with superuser rights, so that modules can
+// read their settings whatever the user of the environment.
+func GetParam(env models.Environment, key, defaultValue string) string {
+	if value, ok := params(env)[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetInt returns the value of the parameter with the given key as an integer,
+// or defaultValue if the parameter does not exist or is not an integer.
+func GetInt(env models.Environment, key string, defaultValue int64) int64 {
+	value, ok := params(env)[key]
+	if !ok {
+		return defaultValue
+	}
+	res, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Warn("Invalid integer parameter", "key", key, "value", value)
+		return defaultValue
+	}
+	return res
+}
+
+// GetBool returns the value of the parameter with the given key as a boolean,
+// or defaultValue if the parameter does not exist or is not a boolean.
+func GetBool(env models.Environment, key string, defaultValue bool) bool {
+	value, ok := params(env)[key]
+	if !ok {
+		return defaultValue
+	}
+	res, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Warn("Invalid boolean parameter", "key", key, "value", value)
+		return defaultValue
+	}
+	return res
+}
+
+// GetDuration returns the value of the parameter with the given key as a duration
+// (e.g. 1h30m), or defaultValue if the parameter does not exist or is not a duration.
+func GetDuration(env models.Environment, key string, defaultValue time.Duration) time.Duration {
+	value, ok := params(env)[key]
+	if !ok {
+		return defaultValue
+	}
+	res, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn("Invalid duration parameter", "key", key, "value", value)
+		return defaultValue
+	}
+	return res
+}
+
+// SetParam sets the value of the parameter with the given key.
+// The parameter is created if it does not exist.
+func SetParam(env models.Environment, key, value string) {
+	rs := env.Pool(paramModel).Sudo()
+	mi := rs.Model()
+	rec := rs.Search(mi.Field(mi.FieldName("Key")).Equals(key))
+	if rec.IsEmpty() {
+		rs.Call("Create", models.NewModelData(mi).
+			Set(mi.FieldName("Key"), key).
+			Set(mi.FieldName("Value"), value))
+		return
+	}
+	rec.Set(mi.FieldName("Value"), value)
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTypedGetters(t *testing.T) {
+	Convey("Testing the typed getters of parameters", t, func() {
+		var env models.Environment
+		cache.byDB = map[string]*dbParams{
+			env.DBName(): {loaded: time.Now(), values: map[string]string{
+				"mail.from":      "noreply@example.com",
+				"mail.retries":   "3",
+				"mail.enabled":   "true",
+				"mail.timeout":   "1m30s",
+				"invalid.number": "three",
+			}},
+		}
+		defer invalidate(env.DBName())
+		So(GetParam(env, "mail.from", ""), ShouldEqual, "noreply@example.com")
+		So(GetParam(env, "mail.host", "localhost"), ShouldEqual, "localhost")
+		So(GetInt(env, "mail.retries", 5), ShouldEqual, 3)
+		So(GetInt(env, "invalid.number", 5), ShouldEqual, 5)
+		So(GetInt(env, "mail.port", 25), ShouldEqual, 25)
+		So(GetBool(env, "mail.enabled", false), ShouldBeTrue)
+		So(GetBool(env, "invalid.number", true), ShouldBeTrue)
+		So(GetDuration(env, "mail.timeout", time.Second), ShouldEqual, 90*time.Second)
+		So(GetDuration(env, "mail.from", time.Second), ShouldEqual, time.Second)
+		Convey("Invalidating the cache should remove the parameters of the database", func() {
+			invalidate(env.DBName())
+			_, ok := cache.byDB[env.DBName()]
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestRegisterSetting(t *testing.T) {
+	RegisterSetting("TestDigestEnabled", fields.Boolean{}, Setting{Section: "Mail", Param: "test.digest"})
+	RegisterSetting("TestDigestPeriod", fields.Selection{Selection: types.Selection{"day": "Daily", "week": "Weekly"}},
+		Setting{Section: "Mail", Param: "test.digest_period"})
+	RegisterSetting("TestGroupManager", fields.Boolean{}, Setting{Section: "Users", Group: security.GroupAdmin})
+	Convey("Testing the registration of settings", t, func() {
+		_, ok := models.Registry.MustGet(settingsModel).Fields().Get("TestDigestPeriod")
+		So(ok, ShouldBeTrue)
+		var mail, users Section
+		for _, s := range Sections() {
+			switch s.Name {
+			case "Mail":
+				mail = s
+			case "Users":
+				users = s
+			}
+		}
+		So(mail.Fields, ShouldResemble, []string{"TestDigestEnabled", "TestDigestPeriod"})
+		So(users.Fields, ShouldResemble, []string{"TestGroupManager"})
+		Convey("Invalid settings should panic", func() {
+			So(func() { RegisterSetting("TestDigestEnabled", fields.Boolean{}, Setting{Param: "test.other"}) }, ShouldPanic)
+			So(func() { RegisterSetting("TestNoTarget", fields.Boolean{}, Setting{}) }, ShouldPanic)
+			So(func() {
+				RegisterSetting("TestTwoTargets", fields.Boolean{}, Setting{Param: "test.two", Group: security.GroupAdmin})
+			}, ShouldPanic)
+			So(func() { RegisterSetting("TestChar", fields.Char{}, Setting{Param: "test.char"}) }, ShouldPanic)
+			So(func() {
+				RegisterSetting("TestGroupSelection", fields.Selection{}, Setting{Group: security.GroupAdmin})
+			}, ShouldPanic)
+		})
+		Convey("Users given a group by a setting should be read back from its parameter", func() {
+			So(parseIDs("2, 5,x,7"), ShouldResemble, []int64{2, 5, 7})
+			So(parseIDs(""), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package settings
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+)
+
+// settingsModel is the name of the transient model of the settings wizard
+const settingsModel = "Settings"
+
+// groupParamPrefix is the prefix of the parameters that store whether a group setting is enabled
+const groupParamPrefix = "settings.group."
+
+// groupUsersParamSuffix is the suffix of the parameters that store the IDs of
+// the users to whom a group setting has given its group.
+const groupUsersParamSuffix = ".users"
+
+// SetUserGroup gives the given group to the user with the given id if member
+// is true, or removes it from this user otherwise.
+//
+// The change must be written in the user record with env, so that it is
+// saved or rolled back with the transaction of the settings wizard. The
+// module that defines the User model sets this function and is in charge
+// of updating the memberships of the database afterwards.
+var SetUserGroup func(env models.Environment, uid int64, group *security.Group, member bool)
+
+// A Setting defines what a field of the settings wizard is mapped to.
+// Exactly one of Param or Group must be set.
+type Setting struct {
+	// Section is the name of the section of the wizard in which the field is displayed
+	Section string
+	// Param is the key of the ConfigParameter which holds the value of the field.
+	// The field must be a Boolean or a Selection.
+	Param string
+	// Group is the group given to all users when the Boolean field is checked,
+	// and removed when it is unchecked from the users to whom it was given this way.
+	Group *security.Group
+}
+
+// A Section is a group of settings as displayed in the wizard
+type Section struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+}
+
+// registry holds the registered settings
+var registry struct {
+	sync.RWMutex
+	settings map[string]Setting
+	// booleans are the names of the Boolean settings
+	booleans map[string]bool
+	sections []*Section
+}
+
+// RegisterSetting adds a field with the given name and definition to the
+// settings wizard, which is mapped to the given Setting.
+//
+// It must be called in the init() function of modules and panics if the
+// setting is invalid or if a setting with the same name already exists.
+func RegisterSetting(name string, def models.FieldDefinition, setting Setting) {
+	if (setting.Param == "") == (setting.Group == nil) {
+		log.Panic("A setting must be mapped to exactly one parameter or group", "setting", name)
+	}
+	var boolean bool
+	switch def.(type) {
+	case fields.Boolean:
+		boolean = true
+	case fields.Selection:
+		if setting.Param == "" {
+			log.Panic("Selection settings must be mapped to a parameter", "setting", name)
+		}
+	default:
+		log.Panic("Settings must be Boolean or Selection fields", "setting", name)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, exists := registry.settings[name]; exists {
+		log.Panic("Setting already registered", "setting", name)
+	}
+	if registry.settings == nil {
+		registry.settings = make(map[string]Setting)
+		registry.booleans = make(map[string]bool)
+	}
+	registry.settings[name] = setting
+	registry.booleans[name] = boolean
+	section := findSection(setting.Section)
+	section.Fields = append(section.Fields, name)
+	models.Registry.MustGet(settingsModel).AddFields(map[string]models.FieldDefinition{name: def})
+}
+
+// findSection returns the section with the given name, which is created if it does not exist.
+// The registry must be locked by the caller.
+func findSection(name string) *Section {
+	for _, s := range registry.sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	section := &Section{Name: name}
+	registry.sections = append(registry.sections, section)
+	return section
+}
+
+// Sections returns the sections of the settings wizard in their registration order
+func Sections() []Section {
+	registry.RLock()
+	defer registry.RUnlock()
+	res := make([]Section, len(registry.sections))
+	for i, s := range registry.sections {
+		res[i] = Section{Name: s.Name, Fields: append([]string(nil), s.Fields...)}
+	}
+	return res
+}
+
+// currentValue returns the current value of the setting with the given name.
+// The registry must be locked by the caller.
+func currentValue(env models.Environment, name string) interface{} {
+	setting := registry.settings[name]
+	switch {
+	case setting.Group != nil:
+		return GetBool(env, groupParamPrefix+setting.Group.ID, false)
+	case registry.booleans[name]:
+		return GetBool(env, setting.Param, false)
+	}
+	return GetParam(env, setting.Param, "")
+}
+
+// applyGroup gives the given group to all the users of the database of env
+// if enabled is true, or removes it from the users to whom it was given by a
+// previous call otherwise.
+//
+// Users who were already members of the group when it was enabled are not
+// recorded, so that groups given explicitly by an administrator are kept
+// when the setting is disabled.
+func applyGroup(env models.Environment, group *security.Group, enabled bool) {
+	if SetUserGroup == nil {
+		return
+	}
+	usersParam := groupParamPrefix + group.ID + groupUsersParamSuffix
+	if !enabled {
+		for _, uid := range parseIDs(GetParam(env, usersParam, "")) {
+			SetUserGroup(env, uid, group, false)
+		}
+		SetParam(env, usersParam, "")
+		return
+	}
+	var given []string
+	for _, uid := range env.Pool("User").Sudo().SearchAll().Ids() {
+		if env.Memberships().HasMembership(uid, group) {
+			continue
+		}
+		SetUserGroup(env, uid, group, true)
+		given = append(given, strconv.FormatInt(uid, 10))
+	}
+	SetParam(env, usersParam, strings.Join(given, ","))
+}
+
+// parseIDs returns the IDs of the given comma separated list, ignoring invalid values
+func parseIDs(list string) []int64 {
+	var res []int64
+	for _, val := range strings.Split(list, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		if err != nil {
+			continue
+		}
+		res = append(res, id)
+	}
+	return res
+}
+
+// DefaultGroups returns the groups of the enabled group settings of the database
+// of env. They must be given to the users created after the setting has been
+// enabled by the module that defines the User model.
+func DefaultGroups(env models.Environment) []*security.Group {
+	registry.RLock()
+	defer registry.RUnlock()
+	var res []*security.Group
+	for _, setting := range registry.settings {
+		if setting.Group != nil && GetBool(env, groupParamPrefix+setting.Group.ID, false) {
+			res = append(res, setting.Group)
+		}
+	}
+	return res
+}
+
+func declareSettingsModel() {
+	settings := models.NewTransientModel(settingsModel)
+	settings.AddEmptyMethod("DefaultGet").Extend(settings_DefaultGet)
+	settings.NewMethod("Execute", settings_Execute).
+		RevokeGroup(security.GroupEveryone).AllowGroup(security.GroupAdmin)
+	settings.NewMethod("GetSections", settings_GetSections)
+}
+
+// settings_DefaultGet returns the current values of the settings
+func settings_DefaultGet(rc *models.RecordCollection) *models.ModelData {
+	res := rc.Super().Call("DefaultGet").(*models.ModelData)
+	registry.RLock()
+	defer registry.RUnlock()
+	for name := range registry.settings {
+		res.Set(rc.Model().FieldName(name), currentValue(rc.Env(), name))
+	}
+	return res
+}
+
+// settings_Execute saves the changed values of the wizard: parameters are set
+// and groups are given to or removed from users. It is the single path by which
+// settings are saved.
+func settings_Execute(rc *models.RecordCollection) *actions.Action {
+	env := rc.Env()
+	registry.RLock()
+	defer registry.RUnlock()
+	for name, setting := range registry.settings {
+		value := rc.Get(rc.Model().FieldName(name))
+		if value == currentValue(env, name) {
+			continue
+		}
+		switch {
+		case setting.Group != nil:
+			enabled := value.(bool)
+			SetParam(env, groupParamPrefix+setting.Group.ID, strconv.FormatBool(enabled))
+			applyGroup(env, setting.Group, enabled)
+		case registry.booleans[name]:
+			SetParam(env, setting.Param, strconv.FormatBool(value.(bool)))
+		default:
+			SetParam(env, setting.Param, value.(string))
+		}
+	}
+	return &actions.Action{Type: actions.ActionClient, Tag: "reload"}
+}
+
+// settings_GetSections returns the sections of the settings wizard
+func settings_GetSections(_ *models.RecordCollection) []Section {
+	return Sections()
+}
@@ -0,0 +1,37 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package stages
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// columns is the controller that returns the columns
+// of a kanban view grouped by stage.
+func columns(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var params ColumnsParams
+	ctx.BindRPCParams(&params)
+	var res []Column
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		var err error
+		if res, err = Columns(env, params); err != nil {
+			log.Panic("Invalid kanban stage columns", "model", params.Model, "error", err)
+		}
+	})
+	ctx.RPC(http.StatusOK, res, err)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/web/stages")
+	grp.AddController(http.MethodPost, "/columns", columns)
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package stages is a Hexya module that provides the stages through
+// which the records of a model go, such as the stages of leads or tasks.
+//
+// Models whose records go through stages inherit the StageMixin model:
+//
+//	h.Task().InheritModel(h.StageMixin())
+//
+// Stage records are defined for a model (ResModel) and ordered by Sequence.
+// New records are put in the first stage of their model. Models whose stages
+// depend on a team or a project override StageScopeField to return the name
+// of the many2one field of the team: stages with a ScopeID are then only
+// available to the records of this team.
+//
+// Columns returns the columns of the kanban view of a model grouped by stage,
+// including the stages without records, and is served at /web/stages/columns.
+package stages
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "stages"
+)
+
+func init() {
+	log = logging.GetLogger("stages")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package stages
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	stage := models.NewModel("Stage")
+	stage.SetDefaultOrder("Sequence", "ID")
+	stage.AddFields(map[string]models.FieldDefinition{
+		"Name":     fields.Char{String: "Stage Name", Required: true, Translate: true},
+		"Sequence": fields.Integer{Default: models.DefaultValue(10), Help: "Order of the stage in the kanban view"},
+		"Fold": fields.Boolean{String: "Folded in Kanban",
+			Help: "Whether the column of the stage is folded in the kanban view"},
+		"ResModel": fields.Char{String: "Model", Required: true, Index: true,
+			Help: "Name of the model whose records go through this stage (e.g. Task)"},
+		"ScopeID": fields.Integer{String: "Scope ID", Index: true,
+			Help: "ID of the team or project to which the stage is restricted. The stage is available to all records of the model if it is 0."},
+		"Description": fields.Text{Translate: true},
+		"Active":      fields.Boolean{Default: models.DefaultValue(true)},
+	})
+
+	stageMixin := models.NewMixinModel("StageMixin")
+	stageMixin.NewMethod("StageScopeField", stageMixin_StageScopeField)
+	stageMixin.NewMethod("StageScope", stageMixin_StageScope)
+	stageMixin.NewMethod("AvailableStages", stageMixin_AvailableStages)
+	stageMixin.NewMethod("DefaultStage", stageMixin_DefaultStage)
+	stageMixin.NewMethod("CheckStage", stageMixin_CheckStage)
+	stageMixin.AddEmptyMethod("Create").Extend(stageMixin_Create)
+	stageMixin.AddEmptyMethod("Write").Extend(stageMixin_Write)
+	stageMixin.AddFields(map[string]models.FieldDefinition{
+		"Stage": fields.Many2One{RelationModel: stage, Index: true, OnDelete: models.Restrict,
+			Constraint: stageMixin.Methods().MustGet("CheckStage")},
+		"DateLastStageUpdate": fields.DateTime{String: "Last Stage Update", NoCopy: true, ReadOnly: true},
+	})
+}
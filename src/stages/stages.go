@@ -0,0 +1,205 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package stages
+
+import (
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// A Column is a column of a kanban view grouped by stage
+type Column struct {
+	// ID is the ID of the stage of the column, or 0 for
+	// the column of the records without stage.
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Fold  bool   `json:"fold"`
+	Count int    `json:"__count"`
+}
+
+// ColumnsParams are the parameters of Columns
+type ColumnsParams struct {
+	Model string `json:"model"`
+	// Domain is the domain of the records displayed in the view
+	Domain []interface{} `json:"domain"`
+	// ScopeID is the ID of the team or project displayed in the view,
+	// or 0 to display the stages that are available to all records.
+	ScopeID int64 `json:"scope_id"`
+}
+
+// StagesOf returns the active stages of the given model that are available in
+// the given scope, that is the stages of the scope and the stages of no scope.
+func StagesOf(env models.Environment, modelName string, scopeID int64) *models.RecordCollection {
+	stages := env.Pool("Stage")
+	mi := stages.Model()
+	scopeCond := mi.Field(mi.FieldName("ScopeID")).IsNull().
+		Or().Field(mi.FieldName("ScopeID")).In([]int64{0, scopeID})
+	return stages.Search(mi.Field(mi.FieldName("ResModel")).Equals(modelName).
+		And().Field(mi.FieldName("Active")).Equals(true).
+		AndCond(scopeCond))
+}
+
+// buildColumns returns the columns of the given stages with the given counts
+// of records by stage ID. Stages without records are kept so that records can
+// be moved to them. A column of the records without stage (with the 0 key)
+// is prepended if there are any.
+func buildColumns(stages []Column, counts map[int64]int) []Column {
+	res := make([]Column, 0, len(stages)+1)
+	if counts[0] > 0 {
+		res = append(res, Column{Name: "Undefined", Count: counts[0]})
+	}
+	for _, col := range stages {
+		col.Count = counts[col.ID]
+		res = append(res, col)
+	}
+	return res
+}
+
+// Columns returns the columns of the kanban view of the given model grouped
+// by stage. The columns are the stages of the model available in the scope of
+// the view, whether or not they have records, followed by the other stages of
+// the records matching the domain, such as archived stages. Stages are
+// ordered by sequence.
+func Columns(env models.Environment, params ColumnsParams) ([]Column, error) {
+	mi, ok := models.Registry.Get(params.Model)
+	if !ok {
+		return nil, fmt.Errorf("unknown model '%s'", params.Model)
+	}
+	if _, ok := mi.Methods().Get("AvailableStages"); !ok {
+		return nil, fmt.Errorf("model %s does not inherit StageMixin", params.Model)
+	}
+	cond, err := models.ParseDomain(mi, params.Domain)
+	if err != nil {
+		return nil, err
+	}
+	records := env.Pool(mi.Name())
+	if cond.IsEmpty() {
+		records = records.SearchAll()
+	} else {
+		records = records.Search(cond)
+	}
+	stageFName := mi.FieldName("Stage")
+	counts := make(map[int64]int)
+	for _, row := range records.GroupBy(stageFName).Aggregates(stageFName) {
+		var stageID int64
+		if stage, ok := row.Values.Get(stageFName).(models.RecordSet); ok && !stage.IsEmpty() {
+			stageID = stage.Ids()[0]
+		}
+		counts[stageID] += row.Count
+	}
+	stages := StagesOf(env, mi.Name(), params.ScopeID)
+	listed := make(map[int64]bool)
+	for _, id := range stages.Ids() {
+		listed[id] = true
+	}
+	var others []int64
+	for id := range counts {
+		if id != 0 && !listed[id] {
+			others = append(others, id)
+		}
+	}
+	smi := stages.Model()
+	if len(others) > 0 {
+		stages = env.Pool("Stage").Search(smi.Field(models.ID).In(append(stages.Ids(), others...)))
+	}
+	cols := make([]Column, 0, stages.Len())
+	for _, stage := range stages.Records() {
+		cols = append(cols, Column{
+			ID:   stage.Ids()[0],
+			Name: stage.Get(smi.FieldName("Name")).(string),
+			Fold: stage.Get(smi.FieldName("Fold")).(bool),
+		})
+	}
+	return buildColumns(cols, counts), nil
+}
+
+// stageMixin_Create puts the new records in their default stage
+// if they are not given one
+func stageMixin_Create(rc *models.RecordCollection, data models.RecordData) *models.RecordCollection {
+	res := rc.Super().Call("Create", data).(models.RecordSet).Collection()
+	mi := rc.Model()
+	for _, rec := range res.Records() {
+		values := models.NewModelData(mi).Set(mi.FieldName("DateLastStageUpdate"), dates.Now())
+		if rec.Get(mi.FieldName("Stage")).(models.RecordSet).IsEmpty() {
+			values.Set(mi.FieldName("Stage"), rec.Call("DefaultStage"))
+		}
+		rec.Call("Write", values)
+	}
+	return res
+}
+
+// stageMixin_Write updates the date of the last stage update
+// when the stage of the records is changed
+func stageMixin_Write(rc *models.RecordCollection, data models.RecordData) bool {
+	mi := rc.Model()
+	if data.Underlying().Has(mi.FieldName("Stage")) && !data.Underlying().Has(mi.FieldName("DateLastStageUpdate")) {
+		values := data.Underlying().Copy()
+		values.Set(mi.FieldName("DateLastStageUpdate"), dates.Now())
+		data = values
+	}
+	return rc.Super().Call("Write", data).(bool)
+}
+
+// StageScopeField returns the name of the many2one field of the team or
+// project whose stages are available to the records. It returns an empty
+// string by default, so that only the stages of no scope are available, and
+// is meant to be overridden.
+func stageMixin_StageScopeField(_ *models.RecordCollection) string {
+	return ""
+}
+
+// StageScope returns the ID of the team or project whose
+// stages are available to this record, or 0 if there is none.
+func stageMixin_StageScope(rc *models.RecordCollection) int64 {
+	rc.EnsureOne()
+	field := rc.Call("StageScopeField").(string)
+	if field == "" {
+		return 0
+	}
+	scope, ok := rc.Get(rc.Model().FieldName(field)).(models.RecordSet)
+	if !ok {
+		log.Panic("Stage scope field is not a many2one field", "model", rc.ModelName(), "field", field)
+	}
+	if scope.IsEmpty() {
+		return 0
+	}
+	return scope.Ids()[0]
+}
+
+// AvailableStages returns the stages to which this record can be moved
+func stageMixin_AvailableStages(rc *models.RecordCollection) *models.RecordCollection {
+	rc.EnsureOne()
+	return StagesOf(rc.Env(), rc.ModelName(), rc.Call("StageScope").(int64))
+}
+
+// DefaultStage returns the stage in which this record is put when it is
+// created, that is its first available stage that is not folded, or its
+// first available stage if they are all folded.
+func stageMixin_DefaultStage(rc *models.RecordCollection) *models.RecordCollection {
+	stages := rc.Call("AvailableStages").(models.RecordSet).Collection()
+	for _, stage := range stages.Records() {
+		if !stage.Get(stage.Model().FieldName("Fold")).(bool) {
+			return stage
+		}
+	}
+	if stages.IsEmpty() {
+		return stages
+	}
+	return stages.Records()[0]
+}
+
+// CheckStage checks that the stages of the records are stages of their model
+func stageMixin_CheckStage(rc *models.RecordCollection) {
+	for _, rec := range rc.Records() {
+		stage := rec.Get(rc.Model().FieldName("Stage")).(models.RecordSet).Collection()
+		if stage.IsEmpty() {
+			continue
+		}
+		if resModel := stage.Get(stage.Model().FieldName("ResModel")).(string); resModel != rc.ModelName() {
+			log.Panic("Stage of another model", "model", rc.ModelName(), "record", rec.Ids()[0], "stage", stage.Ids()[0], "stageModel", resModel)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package stages
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/tests"
+	_ "github.com/lib/pq"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMain(m *testing.M) {
+	team := models.NewModel("StageTestTeam")
+	team.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	task := models.NewModel("StageTestTask")
+	task.InheritModel(models.Registry.MustGet("StageMixin"))
+	task.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+		"Team": fields.Many2One{RelationModel: team},
+	})
+	task.Methods().MustGet("StageScopeField").Extend(
+		func(_ *models.RecordCollection) string {
+			return "Team"
+		})
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+
+func TestStages(t *testing.T) {
+	Convey("Testing kanban stages", t, func() {
+		stages := []Column{
+			{ID: 3, Name: "New"},
+			{ID: 1, Name: "In Progress"},
+			{ID: 2, Name: "Done", Fold: true},
+		}
+		Convey("Stages without records should be kept in order", func() {
+			res := buildColumns(stages, map[int64]int{1: 4})
+			So(res, ShouldResemble, []Column{
+				{ID: 3, Name: "New"},
+				{ID: 1, Name: "In Progress", Count: 4},
+				{ID: 2, Name: "Done", Fold: true},
+			})
+		})
+		Convey("Records without stage should be in a first column", func() {
+			res := buildColumns(stages, map[int64]int{0: 2, 2: 1})
+			So(res, ShouldHaveLength, 4)
+			So(res[0], ShouldResemble, Column{Name: "Undefined", Count: 2})
+			So(res[3].Count, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestStageMixin(t *testing.T) {
+	Convey("Testing StageMixin", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			stages := env.Pool("Stage")
+			smi := stages.Model()
+			createStage := func(name string, sequence int64, fold bool, resModel string, scopeID int64) *models.RecordCollection {
+				return stages.Call("Create", models.NewModelData(smi).
+					Set(smi.FieldName("Name"), name).
+					Set(smi.FieldName("Sequence"), sequence).
+					Set(smi.FieldName("Fold"), fold).
+					Set(smi.FieldName("ResModel"), resModel).
+					Set(smi.FieldName("ScopeID"), scopeID)).(models.RecordSet).Collection()
+			}
+			teams := env.Pool("StageTestTeam")
+			team := teams.Call("Create", models.NewModelData(teams.Model()).
+				Set(teams.Model().FieldName("Name"), "Support")).(models.RecordSet).Collection()
+			backlog := createStage("Backlog", 1, true, "StageTestTask", 0)
+			review := createStage("Review", 2, false, "StageTestTask", team.Ids()[0])
+			newStage := createStage("New", 5, false, "StageTestTask", 0)
+			done := createStage("Done", 20, true, "StageTestTask", 0)
+			other := createStage("Other", 1, false, "StageTestTeam", 0)
+			tasks := env.Pool("StageTestTask")
+			mi := tasks.Model()
+			task := tasks.Call("Create", models.NewModelData(mi).Set(mi.FieldName("Name"), "Fix the printer")).(models.RecordSet).Collection()
+			Convey("New records should be put in their first stage that is not folded", func() {
+				So(task.Get(mi.FieldName("Stage")).(models.RecordSet).Ids(), ShouldResemble, newStage.Ids())
+				So(task.Get(mi.FieldName("DateLastStageUpdate")).(dates.DateTime).IsZero(), ShouldBeFalse)
+				So(task.Call("AvailableStages").(models.RecordSet).Ids(), ShouldResemble,
+					[]int64{backlog.Ids()[0], newStage.Ids()[0], done.Ids()[0]})
+			})
+			Convey("Records of a team should get the stages of the team", func() {
+				teamTask := tasks.Call("Create", models.NewModelData(mi).
+					Set(mi.FieldName("Name"), "Answer the customer").
+					Set(mi.FieldName("Team"), team)).(models.RecordSet).Collection()
+				So(teamTask.Call("StageScope"), ShouldEqual, team.Ids()[0])
+				So(teamTask.Get(mi.FieldName("Stage")).(models.RecordSet).Ids(), ShouldResemble, review.Ids())
+				So(teamTask.Call("AvailableStages").(models.RecordSet).Ids(), ShouldResemble,
+					[]int64{backlog.Ids()[0], review.Ids()[0], newStage.Ids()[0], done.Ids()[0]})
+			})
+			Convey("Changing the stage should update the date of the last stage update", func() {
+				past := dates.Now().AddDate(0, 0, -7)
+				task.Set(mi.FieldName("DateLastStageUpdate"), past)
+				task.Set(mi.FieldName("Name"), "Fix the printer again")
+				So(task.Get(mi.FieldName("DateLastStageUpdate")).(dates.DateTime).Greater(past.AddDate(0, 0, 1)), ShouldBeFalse)
+				task.Set(mi.FieldName("Stage"), done)
+				So(task.Get(mi.FieldName("DateLastStageUpdate")).(dates.DateTime).Greater(past.AddDate(0, 0, 1)), ShouldBeTrue)
+			})
+			Convey("Stages of other models should be refused", func() {
+				So(func() { task.Set(mi.FieldName("Stage"), other) }, ShouldPanic)
+			})
+			Convey("Columns should list the available stages with their counts", func() {
+				cols, err := Columns(env, ColumnsParams{Model: "StageTestTask"})
+				So(err, ShouldBeNil)
+				So(cols, ShouldResemble, []Column{
+					{ID: backlog.Ids()[0], Name: "Backlog", Fold: true},
+					{ID: newStage.Ids()[0], Name: "New", Count: 1},
+					{ID: done.Ids()[0], Name: "Done", Fold: true},
+				})
+				_, err = Columns(env, ColumnsParams{Model: "StageTestTeam"})
+				So(err, ShouldNotBeNil)
+			})
+		}), ShouldBeNil)
+	})
+}
@@ -0,0 +1,31 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package tags is a Hexya module that provides tags to classify records.
+//
+// The TagMixin model holds the fields of all tag models (name, color index and
+// active flag) and the Tag model is a generic tag model inheriting it. Modules
+// that need their own tags declare a model inheriting TagMixin.
+//
+// Tags are added to any model with a single field declaration:
+//
+//	h.Post().AddFields(map[string]models.FieldDefinition{
+//		"Tags": tags.Many2Many{RelationModel: h.Tag()},
+//	})
+package tags
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "tags"
+)
+
+func init() {
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,29 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package tags
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+// MaxColor is the greatest color index of tags.
+// Color indexes are interpreted by the client.
+const MaxColor = 11
+
+func declareModels() {
+	tagMixin := models.NewMixinModel("TagMixin")
+	tagMixin.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Tag Name", Required: true, Translate: true},
+		"Color": fields.Integer{String: "Color Index",
+			Help: "Index of the color of the tag in the client's palette (from 0 to 11)"},
+		"Active": fields.Boolean{Default: models.DefaultValue(true),
+			Help: "Archived tags cannot be added to records anymore"},
+	})
+
+	tag := models.NewModel("Tag")
+	tag.InheritModel(tagMixin)
+	tag.SetDefaultOrder("Name")
+	tag.AddSQLConstraint("name_uniq", "unique(name)", "Tag names must be unique")
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package tags
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+// activeFieldName is the name of the active field of tag models
+var activeFieldName = models.NewFieldName("Active", "active")
+
+// A Many2Many is a field for storing the tags of a record.
+//
+// RelationModel must be a model that inherits TagMixin. The field is a
+// many2many field whose default description is 'Tags' and that only allows
+// active tags to be selected.
+type Many2Many struct {
+	JSON             string
+	String           string
+	Help             string
+	Required         bool
+	ReadOnly         bool
+	InvisibleFunc    func(models.Environment) (bool, models.Conditioner)
+	NoCopy           bool
+	RelationModel    models.Modeler
+	M2MLinkModelName string
+	OnChange         models.Methoder
+	Default          func(models.Environment) interface{}
+}
+
+var _ models.FieldDefinition = Many2Many{}
+
+// DeclareField creates a tags field for the given models.FieldsCollection with the given name.
+func (tf Many2Many) DeclareField(fc *models.FieldsCollection, name string) *models.Field {
+	if tf.String == "" {
+		tf.String = "Tags"
+	}
+	relModel := tf.RelationModel.Underlying()
+	return fields.Many2Many{
+		JSON:             tf.JSON,
+		String:           tf.String,
+		Help:             tf.Help,
+		Required:         tf.Required,
+		ReadOnly:         tf.ReadOnly,
+		InvisibleFunc:    tf.InvisibleFunc,
+		NoCopy:           tf.NoCopy,
+		RelationModel:    relModel,
+		M2MLinkModelName: tf.M2MLinkModelName,
+		OnChange:         tf.OnChange,
+		Default:          tf.Default,
+		Filter:           relModel.Field(activeFieldName).Equals(true),
+	}.DeclareField(fc, name)
+}
+
+// HasTags returns a condition on the given model that selects the records
+// whose tags field with the given name has at least one of the given tags.
+func HasTags(model *models.Model, field string, tagNames ...string) *models.Condition {
+	return model.Field(model.FieldName(field + ".Name")).In(tagNames)
+}
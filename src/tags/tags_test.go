@@ -0,0 +1,29 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package tags
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTagsField(t *testing.T) {
+	post := models.NewModel("TagsTestPost")
+	post.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+		"Tags": Many2Many{RelationModel: models.Registry.MustGet("Tag")},
+	})
+	Convey("Testing tags fields", t, func() {
+		Convey("Tags fields should be many2many fields", func() {
+			fi := post.FieldsGet(post.FieldName("Tags"))["tags_ids"]
+			So(fi, ShouldNotBeNil)
+			So(fi.Type, ShouldEqual, fieldtype.Many2Many)
+			So(fi.String, ShouldEqual, "Tags")
+		})
+	})
+}
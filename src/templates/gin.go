@@ -5,6 +5,7 @@ package templates
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin/render"
 	"github.com/hexya-erp/hexya/src/tools/hweb"
@@ -62,3 +63,18 @@ func (tr TemplateRenderer) WriteContentType(w http.ResponseWriter) {
 }
 
 var _ render.Render = TemplateRenderer{}
+
+// FromQWebString returns a Template from the given QWeb source, which may be
+// a fragment with several root elements or text, such as the body of an email
+// or of a web page stored in the database.
+func (ts *TemplateSet) FromQWebString(src string) (*hweb.Template, error) {
+	p2Content, err := hweb.ToPongo([]byte("<t>" + src + "</t>"))
+	if err != nil {
+		return nil, err
+	}
+	// Remove the wrapping <t> element that we added
+	p2Str := string(p2Content)
+	start := strings.Index(p2Str, "<t>")
+	p2Str = strings.TrimSuffix(p2Str[:start]+p2Str[start+len("<t>"):], "</t>")
+	return ts.FromString(p2Str)
+}
@@ -82,6 +82,18 @@ func BootStrap() {
 	}
 }
 
+// ResetRegistry removes all the templates of the Registry and clears
+// its cache, so that templates can be loaded again.
+//
+// The Registry itself is kept since it is the HTML renderer of the server.
+func ResetRegistry() {
+	Registry.collection.Lock()
+	Registry.collection.templates = make(map[string]*Template)
+	Registry.collection.rawInheritedTemplates = nil
+	Registry.collection.Unlock()
+	Registry.CleanCache()
+}
+
 func init() {
 	log = logging.GetLogger("templates")
 	Registry = NewTemplateSet()
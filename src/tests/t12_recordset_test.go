@@ -25,6 +25,8 @@ import (
 )
 
 func TestCreateRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer models.UnfreezeRegistry()()
 	Convey("Test record creation", t, func() {
 		So(models.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
 			Convey("Creating simple user John with no relations and checking ID", func() {
@@ -152,6 +154,8 @@ func TestCreateRecordSet(t *testing.T) {
 }
 
 func TestSearchRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer models.UnfreezeRegistry()()
 	Convey("Testing search through RecordSets", t, func() {
 		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
 			Convey("Searching User Jane", func() {
@@ -372,6 +376,8 @@ func TestAdvancedQueries(t *testing.T) {
 }
 
 func TestUpdateRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer models.UnfreezeRegistry()()
 	Convey("Testing updates through RecordSets", t, func() {
 		So(models.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
 			Convey("Checking ModelData methods", func() {
@@ -608,6 +614,8 @@ func TestUpdateRecordSet(t *testing.T) {
 }
 
 func TestDeleteRecordSet(t *testing.T) {
+	// Method permissions are changed at run time in these tests
+	defer models.UnfreezeRegistry()()
 	Convey("Delete user John Smith", t, func() {
 		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
 			Convey("Number of deleted record should be 1", func() {
@@ -0,0 +1,244 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package assetfs gives access to the resources of the modules (static
+// files of the web client, XML resources, translations, etc.) whether they
+// are on the disk in the resource directory or embedded in the binary.
+//
+// Embedded assets are laid out like the resource directory, i.e. with the
+// files of each module in static/<module>, resources/<module>, etc. They
+// are registered with Embed, usually in the init function of a file
+// created by 'hexya generate --embed-assets'.
+//
+// Files found on the disk in the resource directory take precedence over
+// embedded files, so that assets can be overridden during development
+// without rebuilding the binary.
+package assetfs
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	embedded     http.FileSystem
+	embeddedLock sync.RWMutex
+)
+
+// Embed registers the given file system as the embedded assets of
+// this binary. It must be laid out like the resource directory.
+func Embed(fsys http.FileSystem) {
+	embeddedLock.Lock()
+	defer embeddedLock.Unlock()
+	embedded = fsys
+}
+
+// Embedded returns the embedded assets of this binary,
+// or nil if there are none.
+func Embedded() http.FileSystem {
+	embeddedLock.RLock()
+	defer embeddedLock.RUnlock()
+	return embedded
+}
+
+// Resources returns a file system with the files of the given resource
+// directory on the disk, overlaid on the embedded assets.
+//
+// If resourceDir is empty or does not exist, only embedded assets are
+// available.
+func Resources(resourceDir string) http.FileSystem {
+	var layers []http.FileSystem
+	if resourceDir != "" {
+		if _, err := os.Stat(resourceDir); err == nil {
+			layers = append(layers, http.Dir(resourceDir))
+		}
+	}
+	if emb := Embedded(); emb != nil {
+		layers = append(layers, emb)
+	}
+	switch len(layers) {
+	case 0:
+		return http.Dir(resourceDir)
+	case 1:
+		return layers[0]
+	}
+	return Overlay(layers...)
+}
+
+// Dir returns a file system with the files of the given directory on the
+// disk. If dir is inside resourceDir, the embedded assets of the same
+// directory are available too, with a lower precedence.
+func Dir(resourceDir, dir string) http.FileSystem {
+	emb := Embedded()
+	if emb == nil || resourceDir == "" {
+		return http.Dir(dir)
+	}
+	rel, err := filepath.Rel(resourceDir, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return http.Dir(dir)
+	}
+	return Overlay(http.Dir(dir), Sub(emb, filepath.ToSlash(rel)))
+}
+
+// ReadFile returns the content of the file with the given name in fsys
+func ReadFile(fsys http.FileSystem, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// ReadDir returns the entries of the directory with the given
+// name in fsys, sorted by name.
+func ReadDir(fsys http.FileSystem, name string) ([]os.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res, err := f.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name() < res[j].Name()
+	})
+	return res, nil
+}
+
+// Exists returns true if a file or directory with
+// the given name exists in fsys.
+func Exists(fsys http.FileSystem, name string) bool {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// A subFS is a file system restricted to a directory of another file system
+type subFS struct {
+	fsys http.FileSystem
+	dir  string
+}
+
+// Open the file with the given name in the directory of this subFS
+func (s subFS) Open(name string) (http.File, error) {
+	return s.fsys.Open(path.Join(s.dir, path.Clean("/"+name)))
+}
+
+// Sub returns a file system with the files of
+// the given directory of the given file system.
+func Sub(fsys http.FileSystem, dir string) http.FileSystem {
+	return subFS{
+		fsys: fsys,
+		dir:  path.Clean("/" + dir),
+	}
+}
+
+// An overlayFS is a file system made of several layers.
+// Files of the first layers hide files of the next ones.
+type overlayFS []http.FileSystem
+
+// Overlay returns a file system made of the given layers. Files are looked
+// up in each layer in turn. The listing of a directory holds the entries of
+// this directory in all layers.
+func Overlay(layers ...http.FileSystem) http.FileSystem {
+	return overlayFS(layers)
+}
+
+// Open the file with the given name in the first layer that has it
+func (o overlayFS) Open(name string) (http.File, error) {
+	var firstErr error
+	for i, layer := range o {
+		f, err := layer.Open(name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fi, err := f.Stat()
+		if err != nil || !fi.IsDir() {
+			return f, err
+		}
+		return &overlayDir{File: f, name: name, layers: o[i+1:]}, nil
+	}
+	return nil, firstErr
+}
+
+// An overlayDir is a directory of an overlayFS
+type overlayDir struct {
+	http.File
+	name    string
+	layers  []http.FileSystem
+	entries []os.FileInfo
+	read    bool
+}
+
+// Readdir returns the entries of this directory in all layers.
+// Entries of the first layers hide those with the same name in
+// the next ones.
+func (od *overlayDir) Readdir(count int) ([]os.FileInfo, error) {
+	if !od.read {
+		entries, err := od.File.Readdir(-1)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool)
+		for _, entry := range entries {
+			seen[entry.Name()] = true
+		}
+		for _, layer := range od.layers {
+			lEntries, err := ReadDir(layer, od.name)
+			if err != nil {
+				continue
+			}
+			for _, entry := range lEntries {
+				if seen[entry.Name()] {
+					continue
+				}
+				seen[entry.Name()] = true
+				entries = append(entries, entry)
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+		od.entries = entries
+		od.read = true
+	}
+	return readEntries(&od.entries, count)
+}
+
+// readEntries returns the count first entries of the given list and
+// removes them from it, following the semantics of os.File.Readdir.
+func readEntries(entries *[]os.FileInfo, count int) ([]os.FileInfo, error) {
+	if count <= 0 {
+		res := *entries
+		*entries = nil
+		return res, nil
+	}
+	if len(*entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(*entries) {
+		count = len(*entries)
+	}
+	res := (*entries)[:count]
+	*entries = (*entries)[count:]
+	return res, nil
+}
+
+var _ http.FileSystem = overlayFS{}
+var _ http.FileSystem = subFS{}
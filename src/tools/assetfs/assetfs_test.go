@@ -0,0 +1,96 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package assetfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func compress(content string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(content))
+	w.Close()
+	return buf.String()
+}
+
+func entryNames(entries []os.FileInfo) []string {
+	res := make([]string, len(entries))
+	for i, entry := range entries {
+		res[i] = entry.Name()
+	}
+	return res
+}
+
+func TestAssetFS(t *testing.T) {
+	Convey("Testing asset file systems", t, func() {
+		emb := NewMapFS(map[string]string{
+			"static/web/src/js/boot.js": compress("embedded boot"),
+			"static/web/src/js/main.js": compress("embedded main"),
+			"i18n/web/fr.po":            compress("embedded po"),
+		})
+		resDir, err := ioutil.TempDir("", "hexya-assetfs")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(resDir)
+		jsDir := filepath.Join(resDir, "static", "web", "src", "js")
+		So(os.MkdirAll(jsDir, 0755), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(jsDir, "main.js"), []byte("disk main"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(jsDir, "dev.js"), []byte("disk dev"), 0644), ShouldBeNil)
+		Convey("Map file systems should serve their files and directories", func() {
+			content, err := ReadFile(emb, "/static/web/src/js/boot.js")
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "embedded boot")
+			entries, err := ReadDir(emb, "/")
+			So(err, ShouldBeNil)
+			So(entryNames(entries), ShouldResemble, []string{"i18n", "static"})
+			So(entries[0].IsDir(), ShouldBeTrue)
+			So(Exists(emb, "/static/web/src/js/unknown.js"), ShouldBeFalse)
+		})
+		Convey("Without embedded assets, resources should be read from the disk", func() {
+			Embed(nil)
+			fsys := Resources(resDir)
+			So(Exists(fsys, "/static/web/src/js/main.js"), ShouldBeTrue)
+			So(Exists(fsys, "/static/web/src/js/boot.js"), ShouldBeFalse)
+		})
+		Convey("Files on the disk should override embedded assets", func() {
+			Embed(emb)
+			defer Embed(nil)
+			fsys := Resources(resDir)
+			content, err := ReadFile(fsys, "/static/web/src/js/main.js")
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "disk main")
+			content, err = ReadFile(fsys, "/static/web/src/js/boot.js")
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "embedded boot")
+			entries, err := ReadDir(fsys, "/static/web/src/js")
+			So(err, ShouldBeNil)
+			So(entryNames(entries), ShouldResemble, []string{"boot.js", "dev.js", "main.js"})
+			So(Exists(fsys, "/i18n/web/fr.po"), ShouldBeTrue)
+		})
+		Convey("Embedded assets should be used when there is no resource directory", func() {
+			Embed(emb)
+			defer Embed(nil)
+			fsys := Resources(filepath.Join(resDir, "unknown"))
+			content, err := ReadFile(fsys, "/static/web/src/js/main.js")
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "embedded main")
+		})
+		Convey("Dir should overlay the matching embedded directory", func() {
+			Embed(emb)
+			defer Embed(nil)
+			fsys := Dir(resDir, filepath.Join(resDir, "static"))
+			content, err := ReadFile(fsys, "/web/src/js/boot.js")
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "embedded boot")
+			So(Exists(Dir(resDir, jsDir+"-other"), "/boot.js"), ShouldBeFalse)
+		})
+	})
+}
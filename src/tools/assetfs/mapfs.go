@@ -0,0 +1,176 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package assetfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A mapFS is a read only in memory file system
+type mapFS struct {
+	files map[string]*mapFileData
+	dirs  map[string][]os.FileInfo
+}
+
+// mapFileData holds the data of a file of a mapFS.
+// Its content is decompressed on first access.
+type mapFileData struct {
+	once       sync.Once
+	compressed string
+	content    []byte
+	err        error
+}
+
+// NewMapFS returns a read only file system with the given files. Keys of
+// the map are file paths with slash separators, and values are the content
+// of the files compressed with gzip. Directories are deduced from the file
+// paths.
+//
+// It is meant to be used by generated code embedding assets, e.g.
+//
+//	assetfs.Embed(assetfs.NewMapFS(map[string]string{
+//		"static/web/src/js/boot.js": "\x1f\x8b\b\x00...",
+//	}))
+func NewMapFS(files map[string]string) http.FileSystem {
+	res := mapFS{
+		files: make(map[string]*mapFileData),
+		dirs:  map[string][]os.FileInfo{"/": nil},
+	}
+	for name, compressed := range files {
+		name = path.Clean("/" + name)
+		res.files[name] = &mapFileData{compressed: compressed}
+		res.addToDir(name, false)
+	}
+	for dir := range res.dirs {
+		entries := res.dirs[dir]
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name() < entries[j].Name()
+		})
+	}
+	return res
+}
+
+// addToDir adds the file or directory with the given
+// name to its parent directory, recursively.
+func (m mapFS) addToDir(name string, isDir bool) {
+	if name == "/" {
+		return
+	}
+	dir := path.Dir(name)
+	_, dirExists := m.dirs[dir]
+	m.dirs[dir] = append(m.dirs[dir], mapFileInfo{name: path.Base(name), isDir: isDir})
+	if !dirExists {
+		m.addToDir(dir, true)
+	}
+}
+
+// Open the file with the given name
+func (m mapFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	if entries, ok := m.dirs[name]; ok {
+		return &mapFile{
+			Reader:  bytes.NewReader(nil),
+			info:    mapFileInfo{name: path.Base(name), isDir: true},
+			entries: append([]os.FileInfo(nil), entries...),
+		}, nil
+	}
+	data, ok := m.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	data.once.Do(func() {
+		var r *gzip.Reader
+		r, data.err = gzip.NewReader(strings.NewReader(data.compressed))
+		if data.err != nil {
+			return
+		}
+		data.content, data.err = ioutil.ReadAll(r)
+	})
+	if data.err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: data.err}
+	}
+	return &mapFile{
+		Reader: bytes.NewReader(data.content),
+		info:   mapFileInfo{name: path.Base(name), size: int64(len(data.content))},
+	}, nil
+}
+
+// A mapFile is an opened file of a mapFS
+type mapFile struct {
+	*bytes.Reader
+	info    mapFileInfo
+	entries []os.FileInfo
+}
+
+// Close this file
+func (mf *mapFile) Close() error {
+	return nil
+}
+
+// Readdir returns the entries of this directory
+func (mf *mapFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !mf.info.isDir {
+		return nil, &os.PathError{Op: "readdir", Path: mf.info.name, Err: os.ErrInvalid}
+	}
+	return readEntries(&mf.entries, count)
+}
+
+// Stat returns the FileInfo of this file
+func (mf *mapFile) Stat() (os.FileInfo, error) {
+	return mf.info, nil
+}
+
+// mapFileInfo is the os.FileInfo of the files of a mapFS
+type mapFileInfo struct {
+	name  string
+	size  int64
+	isDir bool
+}
+
+// Name of the file
+func (mfi mapFileInfo) Name() string {
+	return mfi.name
+}
+
+// Size of the file
+func (mfi mapFileInfo) Size() int64 {
+	return mfi.size
+}
+
+// Mode of the file
+func (mfi mapFileInfo) Mode() os.FileMode {
+	if mfi.isDir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ModTime returns the zero time, so that embedded
+// files are not given a Last-Modified header
+func (mfi mapFileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+// IsDir returns true if this file is a directory
+func (mfi mapFileInfo) IsDir() bool {
+	return mfi.isDir
+}
+
+// Sys returns nil
+func (mfi mapFileInfo) Sys() interface{} {
+	return nil
+}
+
+var _ http.FileSystem = mapFS{}
+var _ http.File = new(mapFile)
+var _ os.FileInfo = mapFileInfo{}
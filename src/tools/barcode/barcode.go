@@ -0,0 +1,81 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package barcode generates barcode and QR code images,
+// for instance to be printed in reports.
+package barcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+)
+
+// A Type of barcode
+type Type string
+
+// Available barcode types
+const (
+	Code128 Type = "Code128"
+	EAN13   Type = "EAN13"
+	QR      Type = "QR"
+)
+
+// MaxSize is the maximum width or height of generated images
+const MaxSize = 4096
+
+// Encode returns the given value encoded as a barcode of the given type.
+// The type is case insensitive.
+func Encode(typ Type, value string) (barcode.Barcode, error) {
+	switch strings.ToUpper(string(typ)) {
+	case strings.ToUpper(string(Code128)):
+		return code128.Encode(value)
+	case string(EAN13):
+		if len(value) != 12 && len(value) != 13 {
+			return nil, fmt.Errorf("EAN13 barcodes must have 12 or 13 digits, got %s", value)
+		}
+		return ean.Encode(value)
+	case string(QR):
+		return qr.Encode(value, qr.M, qr.Auto)
+	default:
+		return nil, fmt.Errorf("unknown barcode type %s", typ)
+	}
+}
+
+// Generate returns a PNG image of the given value encoded as a barcode of the
+// given type and scaled to the given size in pixels.
+func Generate(typ Type, value string, width, height int) ([]byte, error) {
+	if width <= 0 || height <= 0 || width > MaxSize || height > MaxSize {
+		return nil, fmt.Errorf("invalid barcode size %dx%d", width, height)
+	}
+	code, err := Encode(typ, value)
+	if err != nil {
+		return nil, err
+	}
+	code, err = barcode.Scale(code, width, height)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, code); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DataURI returns the image generated by Generate as a data URI that
+// can be used directly as the source of an HTML image.
+func DataURI(typ Type, value string, width, height int) (string, error) {
+	img, err := Generate(typ, value, width, height)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(img), nil
+}
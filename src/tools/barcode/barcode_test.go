@@ -0,0 +1,43 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package barcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBarcode(t *testing.T) {
+	Convey("Testing barcode generation", t, func() {
+		Convey("Barcodes should be generated at the requested size", func() {
+			for _, typ := range []Type{Code128, EAN13, QR} {
+				data, err := Generate(typ, "5901234123457", 300, 150)
+				So(err, ShouldBeNil)
+				img, err := png.Decode(bytes.NewReader(data))
+				So(err, ShouldBeNil)
+				So(img.Bounds().Dx(), ShouldEqual, 300)
+				So(img.Bounds().Dy(), ShouldEqual, 150)
+			}
+		})
+		Convey("Invalid values should be rejected", func() {
+			_, err := Generate(EAN13, "1234", 300, 150)
+			So(err, ShouldNotBeNil)
+			_, err = Generate(EAN13, "5901234123458", 300, 150)
+			So(err, ShouldNotBeNil)
+			_, err = Generate("Unknown", "1234", 300, 150)
+			So(err, ShouldNotBeNil)
+			_, err = Generate(QR, "1234", 0, 150)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Data URIs should embed PNG images", func() {
+			uri, err := DataURI(QR, "https://www.hexya.io", 100, 100)
+			So(err, ShouldBeNil)
+			So(strings.HasPrefix(uri, "data:image/png;base64,"), ShouldBeTrue)
+		})
+	})
+}
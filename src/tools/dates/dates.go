@@ -0,0 +1,287 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package dates computes scheduled dates from the working time of a resource,
+// such as the business days between two dates or the date at which a task
+// of a given working duration ends.
+//
+// Working time is given by a Calendar, whose working hours are expressed in
+// its own timezone, so that they keep the same local time across DST
+// transitions. WeekCalendar is a Calendar with the same working hours each
+// week, which is typically built from a ResourceCalendar model.
+//
+//	cal := &dates.WeekCalendar{
+//		Loc: paris,
+//		Attendances: []dates.Attendance{
+//			{Weekday: time.Monday, HourFrom: 8, HourTo: 12},
+//			{Weekday: time.Monday, HourFrom: 13, HourTo: 17},
+//		},
+//	}
+//	end, err := dates.AddWorkingTime(cal, start, 6*time.Hour)
+//
+// Returned times are in the location of the given times.
+package dates
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// maxSearchDays is the number of days after which AddWorkingTime and
+// AddBusinessDays give up looking for working time
+const maxSearchDays = 5 * 366
+
+// ErrNoWorkingTime is returned when a calendar has no working time
+// in the period needed for a computation.
+var ErrNoWorkingTime = errors.New("no working time in calendar")
+
+// An Interval is a period of time between Start (included) and Stop (excluded)
+type Interval struct {
+	Start time.Time
+	Stop  time.Time
+}
+
+// Duration returns the duration of this interval
+func (i Interval) Duration() time.Duration {
+	return i.Stop.Sub(i.Start)
+}
+
+// A Calendar gives the working time of a resource
+type Calendar interface {
+	// Location returns the timezone in which the working hours of the calendar are given
+	Location() *time.Location
+	// DayIntervals returns the working hours of the given day, without leaves.
+	DayIntervals(year int, month time.Month, day int) []Interval
+	// Leaves returns the leaves that overlap the given interval
+	Leaves(start, stop time.Time) []Interval
+}
+
+// An Attendance is a working period of a day of the week, with
+// hours given as decimal numbers (e.g. 13.5 for 1:30 PM).
+type Attendance struct {
+	Weekday  time.Weekday
+	HourFrom float64
+	HourTo   float64
+}
+
+// A WeekCalendar is a Calendar with the same attendances each week
+type WeekCalendar struct {
+	// Loc is the timezone of the attendances. UTC is used if nil.
+	Loc         *time.Location
+	Attendances []Attendance
+	// LeaveIntervals are the periods during which the resource does not work
+	LeaveIntervals []Interval
+}
+
+// Location returns the timezone of the attendances of this calendar
+func (wc *WeekCalendar) Location() *time.Location {
+	if wc.Loc == nil {
+		return time.UTC
+	}
+	return wc.Loc
+}
+
+// DayIntervals returns the attendances of the given day
+func (wc *WeekCalendar) DayIntervals(year int, month time.Month, day int) []Interval {
+	loc := wc.Location()
+	weekday := time.Date(year, month, day, 12, 0, 0, 0, loc).Weekday()
+	var res []Interval
+	for _, att := range wc.Attendances {
+		if att.Weekday != weekday || att.HourTo <= att.HourFrom {
+			continue
+		}
+		res = append(res, Interval{
+			Start: hourTime(year, month, day, att.HourFrom, loc),
+			Stop:  hourTime(year, month, day, att.HourTo, loc),
+		})
+	}
+	return merge(res)
+}
+
+// Leaves returns the leaves of this calendar that overlap the given interval
+func (wc *WeekCalendar) Leaves(start, stop time.Time) []Interval {
+	var res []Interval
+	for _, leave := range wc.LeaveIntervals {
+		if leave.Start.Before(stop) && leave.Stop.After(start) {
+			res = append(res, leave)
+		}
+	}
+	return res
+}
+
+// hourTime returns the time of the given day at the given decimal hour
+func hourTime(year int, month time.Month, day int, hour float64, loc *time.Location) time.Time {
+	seconds := int(hour*3600 + 0.5)
+	return time.Date(year, month, day, seconds/3600, seconds%3600/60, seconds%60, 0, loc)
+}
+
+// merge returns the given intervals sorted, with overlapping intervals merged
+func merge(intervals []Interval) []Interval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Start.Before(intervals[j].Start) })
+	var res []Interval
+	for _, interval := range intervals {
+		if !interval.Start.Before(interval.Stop) {
+			continue
+		}
+		if n := len(res); n > 0 && !interval.Start.After(res[n-1].Stop) {
+			if interval.Stop.After(res[n-1].Stop) {
+				res[n-1].Stop = interval.Stop
+			}
+			continue
+		}
+		res = append(res, interval)
+	}
+	return res
+}
+
+// subtract returns the parts of the given sorted intervals that are not in the given leaves
+func subtract(intervals, leaves []Interval) []Interval {
+	res := intervals
+	for _, leave := range leaves {
+		var next []Interval
+		for _, interval := range res {
+			if !leave.Start.Before(interval.Stop) || !leave.Stop.After(interval.Start) {
+				next = append(next, interval)
+				continue
+			}
+			if leave.Start.After(interval.Start) {
+				next = append(next, Interval{Start: interval.Start, Stop: leave.Start})
+			}
+			if leave.Stop.Before(interval.Stop) {
+				next = append(next, Interval{Start: leave.Stop, Stop: interval.Stop})
+			}
+		}
+		res = next
+	}
+	return res
+}
+
+// WorkingIntervals returns the working intervals of the given calendar between
+// start and stop, that is the working hours of each day without the leaves.
+func WorkingIntervals(cal Calendar, start, stop time.Time) []Interval {
+	if !start.Before(stop) {
+		return nil
+	}
+	loc := cal.Location()
+	first, last := start.In(loc), stop.In(loc)
+	var res []Interval
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); day.Before(last); day = day.AddDate(0, 0, 1) {
+		for _, interval := range cal.DayIntervals(day.Year(), day.Month(), day.Day()) {
+			if interval.Start.Before(start) {
+				interval.Start = start
+			}
+			if interval.Stop.After(stop) {
+				interval.Stop = stop
+			}
+			res = append(res, interval)
+		}
+	}
+	res = subtract(merge(res), cal.Leaves(start, stop))
+	for i := range res {
+		res[i] = Interval{Start: res[i].Start.In(start.Location()), Stop: res[i].Stop.In(start.Location())}
+	}
+	return res
+}
+
+// WorkingDuration returns the working time of the given calendar between start and stop
+func WorkingDuration(cal Calendar, start, stop time.Time) time.Duration {
+	var res time.Duration
+	for _, interval := range WorkingIntervals(cal, start, stop) {
+		res += interval.Duration()
+	}
+	return res
+}
+
+// dayBounds returns the start of the day of t and the start of the next day, in the given location
+func dayBounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	t = t.In(loc)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// IsBusinessDay returns true if the given calendar has working time
+// on the day of t, in the timezone of the calendar.
+func IsBusinessDay(cal Calendar, t time.Time) bool {
+	start, stop := dayBounds(t, cal.Location())
+	return len(WorkingIntervals(cal, start, stop)) > 0
+}
+
+// BusinessDays returns the number of business days of the given calendar
+// from the day of start to the day of stop, both included.
+func BusinessDays(cal Calendar, start, stop time.Time) int {
+	day, _ := dayBounds(start, cal.Location())
+	last, _ := dayBounds(stop, cal.Location())
+	var res int
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if IsBusinessDay(cal, day) {
+			res++
+		}
+	}
+	return res
+}
+
+// AddBusinessDays returns t moved by the given number of business days of the
+// given calendar, at the same local time. n may be negative to move backward.
+// It returns ErrNoWorkingTime if the calendar has no business day in a long period.
+func AddBusinessDays(cal Calendar, t time.Time, n int) (time.Time, error) {
+	loc := cal.Location()
+	local := t.In(loc)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	day := local
+	for searched := 0; n > 0; searched++ {
+		if searched > maxSearchDays {
+			return time.Time{}, ErrNoWorkingTime
+		}
+		day = day.AddDate(0, 0, step)
+		if IsBusinessDay(cal, day) {
+			n--
+			searched = 0
+		}
+	}
+	res := time.Date(day.Year(), day.Month(), day.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), loc)
+	return res.In(t.Location()), nil
+}
+
+// AddWorkingTime returns the time at which the given working duration of the
+// given calendar is elapsed from t. If d is negative, it returns the time from
+// which d of working time elapses until t.
+//
+// The result is always inside or at the bound of a working interval, so that
+// adding no time to t outside working hours returns t. It returns
+// ErrNoWorkingTime if the calendar has no working time in a long period.
+func AddWorkingTime(cal Calendar, t time.Time, d time.Duration) (time.Time, error) {
+	const window = 7 * 24 * time.Hour
+	if d == 0 {
+		return t, nil
+	}
+	remaining := d
+	cursor := t
+	for searched := 0; searched*7 <= maxSearchDays; searched++ {
+		if remaining > 0 {
+			for _, interval := range WorkingIntervals(cal, cursor, cursor.Add(window)) {
+				if interval.Duration() >= remaining {
+					return interval.Start.Add(remaining), nil
+				}
+				remaining -= interval.Duration()
+				searched = 0
+			}
+			cursor = cursor.Add(window)
+			continue
+		}
+		intervals := WorkingIntervals(cal, cursor.Add(-window), cursor)
+		for i := len(intervals) - 1; i >= 0; i-- {
+			interval := intervals[i]
+			if interval.Duration() >= -remaining {
+				return interval.Stop.Add(remaining), nil
+			}
+			remaining += interval.Duration()
+			searched = 0
+		}
+		cursor = cursor.Add(-window)
+	}
+	return time.Time{}, ErrNoWorkingTime
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package dates
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkingTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+	cal := &WeekCalendar{Loc: paris}
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday} {
+		cal.Attendances = append(cal.Attendances,
+			Attendance{Weekday: day, HourFrom: 8, HourTo: 12},
+			Attendance{Weekday: day, HourFrom: 13.5, HourTo: 17.5})
+	}
+	// Wednesday 2019-03-20 is a day off
+	cal.LeaveIntervals = []Interval{{
+		Start: time.Date(2019, 3, 20, 0, 0, 0, 0, paris),
+		Stop:  time.Date(2019, 3, 21, 0, 0, 0, 0, paris),
+	}}
+	monday := time.Date(2019, 3, 18, 9, 0, 0, 0, paris)
+	Convey("Testing working time computations", t, func() {
+		Convey("Working intervals should exclude leaves and non working hours", func() {
+			intervals := WorkingIntervals(cal, monday, monday.AddDate(0, 0, 3))
+			So(intervals, ShouldHaveLength, 5)
+			So(intervals[0].Start, ShouldEqual, monday)
+			So(intervals[4].Stop, ShouldEqual, time.Date(2019, 3, 21, 9, 0, 0, 0, paris))
+			So(WorkingDuration(cal, monday, monday.AddDate(0, 0, 3)), ShouldEqual, 16*time.Hour)
+		})
+		Convey("Business days should skip week-ends and leaves", func() {
+			So(IsBusinessDay(cal, monday), ShouldBeTrue)
+			So(IsBusinessDay(cal, monday.AddDate(0, 0, 2)), ShouldBeFalse)
+			So(IsBusinessDay(cal, monday.AddDate(0, 0, 5)), ShouldBeFalse)
+			So(BusinessDays(cal, monday, monday.AddDate(0, 0, 7)), ShouldEqual, 5)
+			res, err := AddBusinessDays(cal, monday, 3)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, time.Date(2019, 3, 22, 9, 0, 0, 0, paris))
+			res, err = AddBusinessDays(cal, res, -3)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, monday)
+		})
+		Convey("Working time should be added in working intervals", func() {
+			res, err := AddWorkingTime(cal, monday, 4*time.Hour)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, time.Date(2019, 3, 18, 14, 30, 0, 0, paris))
+			res, err = AddWorkingTime(cal, monday, 16*time.Hour)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, time.Date(2019, 3, 21, 9, 0, 0, 0, paris))
+			res, err = AddWorkingTime(cal, time.Date(2019, 3, 21, 9, 0, 0, 0, paris), -16*time.Hour)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, monday)
+		})
+		Convey("Working hours should keep their local time across DST transitions", func() {
+			// DST starts on Sunday 2019-03-31 in Paris
+			res, err := AddWorkingTime(cal, time.Date(2019, 3, 29, 17, 0, 0, 0, paris).UTC(), time.Hour)
+			So(err, ShouldBeNil)
+			So(res.Location(), ShouldEqual, time.UTC)
+			So(res.In(paris), ShouldEqual, time.Date(2019, 4, 1, 8, 30, 0, 0, paris))
+		})
+		Convey("Calendars without working time should be detected", func() {
+			_, err := AddWorkingTime(&WeekCalendar{}, monday, time.Hour)
+			So(err, ShouldEqual, ErrNoWorkingTime)
+			_, err = AddBusinessDays(&WeekCalendar{}, monday, 1)
+			So(err, ShouldEqual, ErrNoWorkingTime)
+		})
+	})
+}
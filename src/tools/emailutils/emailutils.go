@@ -3,7 +3,11 @@
 
 package emailutils
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
 
 // SingleEmailRE is the regular expression for a single email address
 const SingleEmailRE string = `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,63}$`
@@ -17,3 +21,14 @@ func IsValidAddress(address string) bool {
 	}
 	return true
 }
+
+// Normalize returns the given address without surrounding spaces and with its
+// domain in lower case. It returns an error if the address is not valid.
+func Normalize(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if !IsValidAddress(address) {
+		return "", fmt.Errorf("invalid email address %q", address)
+	}
+	at := strings.LastIndex(address, "@")
+	return address[:at] + strings.ToLower(address[at:]), nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package generate
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// AssetFSPath is the go import path of the hexya/tools/assetfs package
+const AssetFSPath = HexyaPath + "/src/tools/assetfs"
+
+// CreateAssetsFile creates a go source file of package main with the given
+// fileName that embeds the files of the given subdirectories of resDir in the
+// binary. Symlinks are followed, so that the resource directory created by
+// 'hexya generate' can be embedded.
+//
+// File contents are compressed with gzip and registered with assetfs.Embed.
+func CreateAssetsFile(resDir string, dirs []string, fileName string) {
+	files := make(map[string]string)
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(resDir, dir)); err != nil {
+			continue
+		}
+		collectAssets(resDir, dir, files)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		log.Panic("Error while creating assets file", "error", err, "fileName", fileName)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, assetsFileHeader, AssetFSPath, len(names))
+	for _, name := range names {
+		fmt.Fprintf(w, "\tfiles[%s] = %s\n", strconv.Quote(name), strconv.Quote(files[name]))
+	}
+	fmt.Fprint(w, "\tassetfs.Embed(assetfs.NewMapFS(files))\n}\n")
+	if err := w.Flush(); err != nil {
+		log.Panic("Error while saving assets file", "error", err, "fileName", fileName)
+	}
+}
+
+// collectAssets adds the gzip compressed content of all the files
+// of the given directory (relative to resDir) to files, recursively.
+func collectAssets(resDir, dir string, files map[string]string) {
+	entries, err := ioutil.ReadDir(filepath.Join(resDir, dir))
+	if err != nil {
+		log.Panic("Unable to read assets directory", "dir", dir, "error", err)
+	}
+	for _, entry := range entries {
+		rel := path.Join(dir, entry.Name())
+		fullPath := filepath.Join(resDir, filepath.FromSlash(rel))
+		// Stat follows symlinks, contrary to ReadDir
+		fi, err := os.Stat(fullPath)
+		if err != nil {
+			log.Warn("Skipping unreadable asset", "file", fullPath, "error", err)
+			continue
+		}
+		if fi.IsDir() {
+			collectAssets(resDir, rel, files)
+			continue
+		}
+		content, err := ioutil.ReadFile(fullPath)
+		if err != nil {
+			log.Panic("Unable to read asset file", "file", fullPath, "error", err)
+		}
+		var buf bytes.Buffer
+		gw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		gw.Write(content)
+		gw.Close()
+		files[rel] = buf.String()
+	}
+}
+
+const assetsFileHeader = `// This file is autogenerated by hexya-server
+// DO NOT MODIFY THIS FILE - ANY CHANGES WILL BE OVERWRITTEN
+
+package main
+
+import "%s"
+
+func init() {
+	files := make(map[string]string, %d)
+`
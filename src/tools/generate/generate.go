@@ -81,6 +81,7 @@ type modelData struct {
 	ConditionFuncs        []string
 	Types                 []fieldType
 	TypesDeps             []string
+	MethodDocs            map[string]string
 }
 
 // sort sorts all slices fields of this modelData so that the generated code is always the same.
@@ -150,6 +151,7 @@ func CreatePool(modules []*ModuleInfo, dir string) {
 			addFieldTypesToModelData(&mData)
 			// Add methods
 			addMethodsToModelData(modelsASTData, &mData, &depsMap)
+			mData.MethodDocs = methodDocs(modelsASTData[modelName])
 			// Setting imports
 			var deps []string
 			for dep := range depsMap {
@@ -249,6 +251,23 @@ func addMethodsToModelData(modelsASTData map[string]ModelASTData, modelData *mod
 	}
 }
 
+// methodDocs returns the documentation of the methods of the given model
+// by method name, as plain text without comment markers.
+func methodDocs(modelASTData ModelASTData) map[string]string {
+	res := make(map[string]string)
+	for methodName, methodASTData := range modelASTData.Methods {
+		if methodASTData.Doc == "" {
+			continue
+		}
+		lines := strings.Split(methodASTData.Doc, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(strings.TrimPrefix(line, "//"), " ")
+		}
+		res[methodName] = strings.Join(lines, "\n")
+	}
+	return res
+}
+
 // addFieldsToModelData extracts data from modelASTData to populate fields in modelData
 func addFieldsToModelData(modelASTData ModelASTData, modelData *modelData, depsMap *map[string]bool) {
 	relModels := make(map[string]bool)
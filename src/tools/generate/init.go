@@ -12,6 +12,8 @@ const (
 	HexyaPath = "github.com/hexya-erp/hexya"
 	// ModelsPath is the go import path of the hexya/models package
 	ModelsPath = HexyaPath + "/src/models"
+	// TypesPath is the go import path of the hexya/models/types package
+	TypesPath = HexyaPath + "/src/models/types"
 	// DatesPath is the go import path of the hexya/models/types/dates package
 	DatesPath = HexyaPath + "/src/models/types/dates"
 	// PoolPath is the go import path of the autogenerated pool package
@@ -0,0 +1,253 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/hexya-erp/hexya/src/tools/strutils"
+)
+
+// ModuleDirs are the standard resource directories of a module
+var ModuleDirs = []string{"static", "data", "demo", "resources", "i18n"}
+
+var moduleNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// A moduleScaffoldData holds the data of the templates of a module skeleton
+type moduleScaffoldData struct {
+	ModuleName  string
+	ModuleTitle string
+	ModelName   string
+	ModelTitle  string
+	SnakeModel  string
+	LowerModel  string
+}
+
+// ScaffoldModule creates the skeleton of a new module with the given name in
+// dir/<moduleName>. The skeleton declares the module and a first model with
+// the given name (derived from the module name if empty) with:
+//
+// - manifest.json which describes the module,
+// - 000hexya.go which registers the module and declares its user group,
+// - <model>.go which declares the model and its fields,
+// - security.go which grants access to the model to the user group,
+// - resources/<module>.xml with the views, action and menus of the model,
+// - demo/<Model>.csv with demo records,
+// - the other standard module directories.
+//
+// It returns an error if the module name is invalid or if the module
+// directory already exists.
+func ScaffoldModule(dir, moduleName, modelName string) error {
+	if !moduleNameRegexp.MatchString(moduleName) {
+		return fmt.Errorf("invalid module name '%s': must be lower case letters, digits and underscores", moduleName)
+	}
+	if modelName == "" {
+		modelName = camelCase(moduleName)
+	}
+	if !unicode.IsUpper([]rune(modelName)[0]) {
+		return fmt.Errorf("invalid model name '%s': must start with an upper case letter", modelName)
+	}
+	modDir := filepath.Join(dir, moduleName)
+	if _, err := os.Stat(modDir); err == nil {
+		return fmt.Errorf("directory %s already exists", modDir)
+	}
+	data := moduleScaffoldData{
+		ModuleName:  moduleName,
+		ModuleTitle: strutils.Title(camelCase(moduleName)),
+		ModelName:   modelName,
+		ModelTitle:  strutils.Title(modelName),
+		SnakeModel:  strutils.SnakeCase(modelName),
+		LowerModel:  strings.ToLower(modelName[:1]) + modelName[1:],
+	}
+	for _, d := range ModuleDirs {
+		if err := os.MkdirAll(filepath.Join(modDir, d), 0755); err != nil {
+			return err
+		}
+	}
+	files := []struct {
+		name   string
+		tmpl   *template.Template
+		goFile bool
+	}{
+		{name: "manifest.json", tmpl: moduleManifestTemplate},
+		{name: "000hexya.go", tmpl: moduleHexyaTemplate, goFile: true},
+		{name: data.SnakeModel + ".go", tmpl: moduleModelTemplate, goFile: true},
+		{name: "security.go", tmpl: moduleSecurityTemplate, goFile: true},
+		{name: filepath.Join("resources", moduleName+".xml"), tmpl: moduleResourcesTemplate},
+		{name: filepath.Join("demo", modelName+".csv"), tmpl: moduleDemoTemplate},
+	}
+	for _, f := range files {
+		var buf bytes.Buffer
+		if err := f.tmpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		content := buf.Bytes()
+		if f.goFile {
+			var err error
+			if content, err = format.Source(content); err != nil {
+				return fmt.Errorf("error while formatting %s: %s", f.name, err)
+			}
+		}
+		if err := ioutil.WriteFile(filepath.Join(modDir, f.name), content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// camelCase returns the given snake case string in camel case
+// eg. my_module => MyModule
+func camelCase(in string) string {
+	var res strings.Builder
+	for _, part := range strings.Split(in, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		res.WriteString(string(runes))
+	}
+	return res.String()
+}
+
+var moduleManifestTemplate = template.Must(template.New("").Parse(`{
+	"name": "{{ .ModuleName }}",
+	"version": "0.1.0",
+	"summary": "{{ .ModuleTitle }}",
+	"depends": [],
+	"data": [],
+	"demo": ["demo/{{ .ModelName }}.csv"],
+	"auto_install": false
+}
+`))
+
+var moduleHexyaTemplate = template.Must(template.New("").Parse(`// Package {{ .ModuleName }} is a Hexya module.
+package {{ .ModuleName }}
+
+import (
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	// Add blank imports of the modules this module depends on here
+)
+
+const (
+	// MODULE_NAME is the name of this module
+	MODULE_NAME string = "{{ .ModuleName }}"
+	// GroupUserID is the ID of the users group of this module
+	GroupUserID = "{{ .ModuleName }}_group_user"
+)
+
+// GroupUser is the group of the users of this module
+var GroupUser *security.Group
+
+func init() {
+	server.RegisterModule(&server.Module{
+		Name:     MODULE_NAME,
+		PreInit:  func() {},
+		PostInit: func() {},
+	})
+	GroupUser = security.Registry.NewGroup(GroupUserID, "{{ .ModuleTitle }} / User")
+}
+`))
+
+var moduleModelTemplate = template.Must(template.New("").Parse(`package {{ .ModuleName }}
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/pool/h"
+	"github.com/hexya-erp/pool/m"
+)
+
+var fields_{{ .ModelName }} = map[string]models.FieldDefinition{
+	"Name":        fields.Char{String: "Name", Required: true},
+	"Description": fields.Text{String: "Description"},
+	"Sequence":    fields.Integer{String: "Sequence", Default: models.DefaultValue(10)},
+	"Active":      fields.Boolean{String: "Active", Default: models.DefaultValue(true)},
+}
+
+// {{ .LowerModel }}_Archive sets the Active field of the given records to false
+func {{ .LowerModel }}_Archive(rs m.{{ .ModelName }}Set) bool {
+	rs.SetActive(false)
+	return true
+}
+
+func init() {
+	models.NewModel("{{ .ModelName }}")
+	h.{{ .ModelName }}().SetDefaultOrder("Sequence", "Name")
+	h.{{ .ModelName }}().AddFields(fields_{{ .ModelName }})
+
+	h.{{ .ModelName }}().NewMethod("Archive", {{ .LowerModel }}_Archive)
+}
+`))
+
+var moduleSecurityTemplate = template.Must(template.New("").Parse(`package {{ .ModuleName }}
+
+import (
+	"github.com/hexya-erp/pool/h"
+)
+
+func init() {
+	h.{{ .ModelName }}().Methods().AllowAllToGroup(GroupUser)
+	h.{{ .ModelName }}().Methods().Archive().AllowGroup(GroupUser)
+}
+`))
+
+var moduleResourcesTemplate = template.Must(template.New("").Parse(`<?xml version="1.0" encoding="utf-8"?>
+<hexya>
+	<data>
+		<view id="{{ .ModuleName }}_view_{{ .SnakeModel }}_tree" model="{{ .ModelName }}">
+			<tree>
+				<field name="Sequence" widget="handle"/>
+				<field name="Name"/>
+			</tree>
+		</view>
+
+		<view id="{{ .ModuleName }}_view_{{ .SnakeModel }}_form" model="{{ .ModelName }}">
+			<form>
+				<header>
+					<button name="Archive" type="object" string="Archive" attrs="{'invisible': [('Active', '=', False)]}"/>
+				</header>
+				<sheet>
+					<group>
+						<field name="Name"/>
+						<field name="Sequence"/>
+						<field name="Active" invisible="1"/>
+					</group>
+					<field name="Description"/>
+				</sheet>
+			</form>
+		</view>
+
+		<view id="{{ .ModuleName }}_view_{{ .SnakeModel }}_search" model="{{ .ModelName }}">
+			<search>
+				<field name="Name"/>
+				<filter name="archived" string="Archived" domain="[('Active', '=', False)]"/>
+			</search>
+		</view>
+
+		<action id="{{ .ModuleName }}_action_{{ .SnakeModel }}" name="{{ .ModelTitle }}" type="ir.actions.act_window"
+				model="{{ .ModelName }}" view_mode="tree,form" view_id="{{ .ModuleName }}_view_{{ .SnakeModel }}_tree"
+				search_view_id="{{ .ModuleName }}_view_{{ .SnakeModel }}_search"/>
+
+		<menuitem id="{{ .ModuleName }}_menu_root" name="{{ .ModuleTitle }}" sequence="50"/>
+		<menuitem id="{{ .ModuleName }}_menu_{{ .SnakeModel }}" name="{{ .ModelTitle }}" parent="{{ .ModuleName }}_menu_root"
+				  action="{{ .ModuleName }}_action_{{ .SnakeModel }}" sequence="10"/>
+	</data>
+</hexya>
+`))
+
+var moduleDemoTemplate = template.Must(template.New("").Parse(`id,Name,Sequence
+{{ .ModuleName }}_{{ .SnakeModel }}_demo_1,First {{ .ModelTitle }},10
+{{ .ModuleName }}_{{ .SnakeModel }}_demo_2,Second {{ .ModelTitle }},20
+`))
@@ -106,6 +106,8 @@ type FieldASTData struct {
 	IsRS        bool
 	MixinField  bool
 	EmbedField  bool
+	Required    bool
+	Computed    bool
 	embed       bool
 }
 
@@ -125,6 +127,7 @@ type MethodASTData struct {
 	Params    []ParamData
 	Returns   []TypeData
 	ToDeclare bool
+	Private   bool
 }
 
 // A ModelASTData holds fields and methods data of a Model
@@ -137,6 +140,15 @@ type ModelASTData struct {
 	Mixins       map[string]bool
 	Embeds       map[string]bool
 	Validated    bool
+	fieldChanges map[string]fieldChange
+}
+
+// A fieldChange holds the changes made to a field by the RemoveField,
+// RenameField and ChangeFieldType methods of its model.
+type fieldChange struct {
+	removed bool
+	newName string
+	newType *FieldASTData
 }
 
 // newModelASTData returns an initialized ModelASTData instance
@@ -149,6 +161,7 @@ func newModelASTData(name string) ModelASTData {
 		Mixins:       make(map[string]bool),
 		Embeds:       make(map[string]bool),
 		ModelType:    "",
+		fieldChanges: make(map[string]fieldChange),
 	}
 }
 
@@ -258,13 +271,17 @@ func GetModelsASTDataForModules(modInfos []*ModuleInfo, validate bool) map[strin
 					}
 					switch {
 					case fnctName == "addMethod":
-						parseAddMethod(node, modInfo, &modelsData, false)
+						parseAddMethod(node, modInfo, &modelsData, false, false)
 					case fnctName == "NewMethod":
-						parseAddMethod(node, modInfo, &modelsData, true)
+						parseAddMethod(node, modInfo, &modelsData, true, false)
+					case fnctName == "NewPrivateMethod":
+						parseAddMethod(node, modInfo, &modelsData, true, true)
 					case fnctName == "InheritModel":
 						parseMixInModel(node, modInfo, &modelsData)
 					case fnctName == "AddFields":
 						parseAddFields(node, modInfo, &modelsData)
+					case fnctName == "RemoveField", fnctName == "RenameField", fnctName == "ChangeFieldType":
+						parseFieldChange(node, modInfo, &modelsData)
 					case strutils.StartsAndEndsWith(fnctName, "New", "Model"):
 						parseNewModel(node, &modelsData)
 					}
@@ -275,6 +292,9 @@ func GetModelsASTDataForModules(modInfos []*ModuleInfo, validate bool) map[strin
 	}
 	if !validate {
 		// We don't want validation, so we exit early
+		for modelName := range modelsData {
+			applyFieldChanges(modelName, &modelsData)
+		}
 		return modelsData
 	}
 	for modelName, md := range modelsData {
@@ -293,6 +313,7 @@ func GetModelsASTDataForModules(modInfos []*ModuleInfo, validate bool) map[strin
 func inflateEmbeds(modelName string, modelsData *map[string]ModelASTData) {
 	for emb := range (*modelsData)[modelName].Embeds {
 		relModel := (*modelsData)[modelName].Fields[emb].RelModel
+		applyFieldChanges(relModel, modelsData)
 		inflateEmbeds(relModel, modelsData)
 		for fieldName, field := range (*modelsData)[relModel].Fields {
 			if _, exists := (*modelsData)[modelName].Fields[fieldName]; exists {
@@ -322,6 +343,43 @@ func inflateMixins(modelName string, modelsData *map[string]ModelASTData) {
 			(*modelsData)[modelName].Methods[methodName] = method
 		}
 	}
+	applyFieldChanges(modelName, modelsData)
+}
+
+// applyFieldChanges removes, renames and changes the type of the fields of the
+// given model as requested by the RemoveField, RenameField and ChangeFieldType
+// calls, so that no accessor is generated for removed fields or former names.
+//
+// It is called after the fields of the mixins have been added to the model,
+// since these methods can be called on fields inherited from mixins.
+func applyFieldChanges(modelName string, modelsData *map[string]ModelASTData) {
+	model, exists := (*modelsData)[modelName]
+	if !exists {
+		return
+	}
+	for fieldName, change := range model.fieldChanges {
+		field, ok := model.Fields[fieldName]
+		if !ok {
+			continue
+		}
+		delete(model.Fields, fieldName)
+		if change.removed {
+			delete(model.Embeds, fieldName)
+			continue
+		}
+		if change.newType != nil {
+			newField := *change.newType
+			newField.MixinField = field.MixinField
+			newField.EmbedField = field.EmbedField
+			field = newField
+		}
+		if change.newName != "" {
+			field.Name = change.newName
+			// The JSON name of a renamed field is always computed from its new name
+			field.JSON = ""
+		}
+		model.Fields[field.Name] = field
+	}
 }
 
 // parseMixInModel updates the mixin tree with the given node which is a InheritModel function
@@ -389,6 +447,32 @@ func ExtractFunctionName(node *ast.CallExpr) (string, error) {
 	return fName, nil
 }
 
+// parseFieldChange parses the given node which is a RemoveField, RenameField
+// or ChangeFieldType function. Changes are applied by applyFieldChanges once
+// all the fields of the model are known.
+func parseFieldChange(node *ast.CallExpr, modInfo *ModuleInfo, modelsData *map[string]ModelASTData) {
+	fNode := node.Fun.(*ast.SelectorExpr)
+	modelName, err := extractModel(fNode.X, modInfo)
+	if err != nil {
+		log.Panic("Unable to extract model while visiting AST", "error", err, "node", modInfo.FSet.Position(node.Pos()))
+	}
+	if _, exists := (*modelsData)[modelName]; !exists {
+		(*modelsData)[modelName] = newModelASTData(modelName)
+	}
+	fieldName := parseStringValue(node.Args[0])
+	change := (*modelsData)[modelName].fieldChanges[fieldName]
+	switch fNode.Sel.Name {
+	case "RemoveField":
+		change.removed = true
+	case "RenameField":
+		change.newName = parseStringValue(node.Args[1])
+	case "ChangeFieldType":
+		fData := parseFieldDefinition(fieldName, node.Args[1], modInfo)
+		change.newType = &fData
+	}
+	(*modelsData)[modelName].fieldChanges[fieldName] = change
+}
+
 // parseAddFields parses the given node which is an AddFields function
 func parseAddFields(node *ast.CallExpr, modInfo *ModuleInfo, modelsData *map[string]ModelASTData) {
 	fNode := node.Fun.(*ast.SelectorExpr)
@@ -409,46 +493,59 @@ func parseAddFields(node *ast.CallExpr, modInfo *ModuleInfo, modelsData *map[str
 	for _, f := range fields.Elts {
 		fDef := f.(*ast.KeyValueExpr)
 		fieldName := strings.Trim(fDef.Key.(*ast.BasicLit).Value, "\"`")
-		var typeStr string
-
-		switch ft := fDef.Value.(*ast.CompositeLit).Type.(type) {
-		case *ast.Ident:
-			typeStr = strings.TrimSuffix(ft.Name, "Field")
-		case *ast.SelectorExpr:
-			typeStr = strings.TrimSuffix(ft.Sel.Name, "Field")
-		}
-		var importPath string
-		if typeStr == "Date" || typeStr == "DateTime" {
-			importPath = DatesPath
-		}
-
-		var fieldParams []ast.Expr
-		switch fd := fDef.Value.(type) {
-		case *ast.Ident:
-			fieldParams = fd.Obj.Decl.(*ast.CompositeLit).Elts
-		case *ast.CompositeLit:
-			fieldParams = fd.Elts
-		}
-		fType := fieldtype.Type(strings.ToLower(typeStr))
-		fData := FieldASTData{
-			Name:  fieldName,
-			FType: fType,
-			Type: TypeData{
-				Type:       fType.DefaultGoType().String(),
-				ImportPath: importPath,
-			},
-		}
-		for _, elem := range fieldParams {
-			fElem := elem.(*ast.KeyValueExpr)
-			fData = parseFieldAttribute(fElem, fData, modInfo)
-			if fData.embed {
-				(*modelsData)[modelName].Embeds[fieldName] = true
-			}
+		fData := parseFieldDefinition(fieldName, fDef.Value, modInfo)
+		if fData.embed {
+			(*modelsData)[modelName].Embeds[fieldName] = true
 		}
 		(*modelsData)[modelName].Fields[fieldName] = fData
 	}
 }
 
+// parseFieldDefinition returns the data of the field with the given name
+// from the given expression, which is a field definition such as fields.Char{}.
+func parseFieldDefinition(fieldName string, def ast.Expr, modInfo *ModuleInfo) FieldASTData {
+	var typeStr string
+	switch ft := def.(*ast.CompositeLit).Type.(type) {
+	case *ast.Ident:
+		typeStr = strings.TrimSuffix(ft.Name, "Field")
+	case *ast.SelectorExpr:
+		typeStr = strings.TrimSuffix(ft.Sel.Name, "Field")
+	}
+	// Widget fields are declared with the type of their underlying field
+	switch typeStr {
+	case "ColorIndex":
+		typeStr = "Integer"
+	case "Priority":
+		typeStr = "Selection"
+	}
+	var importPath string
+	if typeStr == "Date" || typeStr == "DateTime" {
+		importPath = DatesPath
+	}
+
+	var fieldParams []ast.Expr
+	switch fd := def.(type) {
+	case *ast.Ident:
+		fieldParams = fd.Obj.Decl.(*ast.CompositeLit).Elts
+	case *ast.CompositeLit:
+		fieldParams = fd.Elts
+	}
+	fType := fieldtype.Type(strings.ToLower(typeStr))
+	fData := FieldASTData{
+		Name:  fieldName,
+		FType: fType,
+		Type: TypeData{
+			Type:       fType.DefaultGoType().String(),
+			ImportPath: importPath,
+		},
+	}
+	for _, elem := range fieldParams {
+		fElem := elem.(*ast.KeyValueExpr)
+		fData = parseFieldAttribute(fElem, fData, modInfo)
+	}
+	return fData
+}
+
 // parseFieldAttribute parses the given KeyValueExpr of a field definition
 func parseFieldAttribute(fElem *ast.KeyValueExpr, fData FieldASTData, modInfo *ModuleInfo) FieldASTData {
 	switch fElem.Key.(*ast.Ident).Name {
@@ -473,10 +570,36 @@ func parseFieldAttribute(fElem *ast.KeyValueExpr, fData FieldASTData, modInfo *M
 		if fElem.Value.(*ast.Ident).Name == "true" {
 			fData.embed = true
 		}
+	case "Required":
+		if ident, ok := fElem.Value.(*ast.Ident); ok && ident.Name == "true" {
+			fData.Required = true
+		}
+	case "Nullable":
+		if ident, ok := fElem.Value.(*ast.Ident); ok && ident.Name == "true" {
+			fData.Type = nullableTypeData(fData)
+		}
+	case "Compute", "Related":
+		fData.Computed = true
 	}
 	return fData
 }
 
+// nullableTypeData returns the type of the given field when it is declared
+// with Nullable: true. The type is unchanged if it has been set with GoType
+// or if the field type has no nullable type.
+func nullableTypeData(fData FieldASTData) TypeData {
+	if fData.Type.Type != fData.FType.DefaultGoType().String() {
+		return fData.Type
+	}
+	switch fData.FType {
+	case fieldtype.Float:
+		return TypeData{Type: "types.NullableFloat", ImportPath: TypesPath}
+	case fieldtype.Char, fieldtype.Text:
+		return TypeData{Type: "types.NullableString", ImportPath: TypesPath}
+	}
+	return fData.Type
+}
+
 // parseStringValue returns the value of a string expr which can be a literal
 // or an identifier for a string.
 func parseStringValue(expr ast.Expr) string {
@@ -506,8 +629,9 @@ func extractSelection(expr ast.Expr) map[string]string {
 	return res
 }
 
-// parseAddMethod parses the given node which is an addMethod function
-func parseAddMethod(node *ast.CallExpr, modInfo *ModuleInfo, modelsData *map[string]ModelASTData, toDeclare bool) {
+// parseAddMethod parses the given node which is an addMethod function.
+// If private is true, the method is marked as private in its documentation.
+func parseAddMethod(node *ast.CallExpr, modInfo *ModuleInfo, modelsData *map[string]ModelASTData, toDeclare, private bool) {
 	fNode := node.Fun.(*ast.SelectorExpr)
 	modelName, err := extractModel(fNode.X, modInfo)
 	if err != nil {
@@ -530,17 +654,32 @@ func parseAddMethod(node *ast.CallExpr, modInfo *ModuleInfo, modelsData *map[str
 	if _, exists := (*modelsData)[modelName]; !exists {
 		(*modelsData)[modelName] = newModelASTData(modelName)
 	}
+	doc = formatDocString(doc)
+	if private {
+		doc = privateMethodDoc(methodName, doc)
+	}
 	methData := MethodASTData{
 		Name:      methodName,
-		Doc:       formatDocString(doc),
+		Doc:       doc,
 		PkgPath:   modInfo.PkgPath,
 		Params:    extractParams(funcType, modInfo),
 		Returns:   extractReturnType(funcType, modInfo),
 		ToDeclare: toDeclare,
+		Private:   private,
 	}
 	(*modelsData)[modelName].Methods[methodName] = methData
 }
 
+// privateMethodDoc returns the given formatted documentation of the method
+// with the given name, completed with a notice that the method is private.
+func privateMethodDoc(methodName, doc string) string {
+	notice := fmt.Sprintf("// %s is private: it cannot be called through RPC.", methodName)
+	if doc == "" {
+		return notice
+	}
+	return doc + "\n//\n" + notice
+}
+
 // A generalMixinError is returned if the mixin is
 // a general mixin set in NewXXXXModel function.
 type generalMixinError struct{}
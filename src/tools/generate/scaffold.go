@@ -0,0 +1,173 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package generate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/tools/strutils"
+)
+
+// scaffoldSkippedFields are the fields that are not tested by the CRUD
+// test scaffolds since they are set by the framework.
+var scaffoldSkippedFields = map[string]bool{
+	"ID":              true,
+	"CreateDate":      true,
+	"CreateUID":       true,
+	"WriteDate":       true,
+	"WriteUID":        true,
+	"LastUpdate":      true,
+	"DisplayName":     true,
+	"HexyaExternalID": true,
+	"HexyaVersion":    true,
+}
+
+// A scaffoldField is a field tested by a CRUD test scaffold,
+// with the Go literals of the values set at creation and update.
+type scaffoldField struct {
+	Name    string
+	Value   string
+	Updated string
+}
+
+// A testScaffoldData holds the data of the CRUD test scaffold of a model
+type testScaffoldData struct {
+	PackageName     string
+	ModelName       string
+	Required        []scaffoldField
+	MissingRequired []string
+	Fields          []scaffoldField
+	NeedsDates      bool
+}
+
+// scaffoldValues returns the Go literals of two different sample values for the
+// given field. The last returned value is false if the field cannot be tested
+// with literal values, such as relation or binary fields.
+func scaffoldValues(fData FieldASTData) (string, string, bool) {
+	switch fData.FType {
+	case fieldtype.Char, fieldtype.Text, fieldtype.HTML, fieldtype.Markdown:
+		if fData.Type.Type != "string" {
+			return "", "", false
+		}
+		return fmt.Sprintf("%q", "Test "+fData.Name), fmt.Sprintf("%q", "Updated "+fData.Name), true
+	case fieldtype.Boolean:
+		return "true", "false", true
+	case fieldtype.Integer:
+		return fmt.Sprintf("%s(1)", fData.Type.Type), fmt.Sprintf("%s(2)", fData.Type.Type), true
+	case fieldtype.Float:
+		return fmt.Sprintf("%s(1.5)", fData.Type.Type), fmt.Sprintf("%s(2.5)", fData.Type.Type), true
+	case fieldtype.Date:
+		return `dates.ParseDate("2019-01-02")`, `dates.ParseDate("2019-03-04")`, true
+	case fieldtype.DateTime:
+		return `dates.ParseDateTime("2019-01-02 03:04:05")`, `dates.ParseDateTime("2019-03-04 05:06:07")`, true
+	case fieldtype.Selection:
+		keys := make([]string, 0, len(fData.Selection))
+		for key := range fData.Selection {
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			return "", "", false
+		}
+		sort.Strings(keys)
+		return keys[0], keys[len(keys)-1], true
+	}
+	return "", "", false
+}
+
+// newTestScaffoldData returns the data of the CRUD test scaffold of the given model
+func newTestScaffoldData(packageName string, modelASTData ModelASTData) *testScaffoldData {
+	res := testScaffoldData{
+		PackageName: packageName,
+		ModelName:   modelASTData.Name,
+	}
+	for _, fData := range modelASTData.Fields {
+		if scaffoldSkippedFields[fData.Name] || fData.Computed {
+			continue
+		}
+		value, updated, ok := scaffoldValues(fData)
+		switch {
+		case !ok && fData.Required:
+			res.MissingRequired = append(res.MissingRequired, fData.Name)
+			continue
+		case !ok:
+			continue
+		case fData.Required:
+			res.Required = append(res.Required, scaffoldField{Name: fData.Name, Value: value, Updated: updated})
+		}
+		res.Fields = append(res.Fields, scaffoldField{Name: fData.Name, Value: value, Updated: updated})
+		if fData.FType == fieldtype.Date || fData.FType == fieldtype.DateTime {
+			res.NeedsDates = true
+		}
+	}
+	sort.Slice(res.Required, func(i, j int) bool { return res.Required[i].Name < res.Required[j].Name })
+	sort.Strings(res.MissingRequired)
+	sort.Slice(res.Fields, func(i, j int) bool { return res.Fields[i].Name < res.Fields[j].Name })
+	return &res
+}
+
+// CreateTestScaffolds generates a test file with table-driven CRUD round-trip
+// tests for each model declared in the module of the given source directory.
+//
+// Test files are created in the module directory as <model>_crud_test.go.
+// They are meant to be edited, so existing files are never overwritten. If
+// the module has no TestMain function yet, one is created in main_test.go
+// that runs the tests on a test database with tests.RunTests.
+func CreateTestScaffolds(modules []*ModuleInfo, dir string) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		log.Panic("Unable to find module directory", "dir", dir, "error", err)
+	}
+	var modInfo *ModuleInfo
+	for _, mod := range modules {
+		if mod.ModType == Base && len(mod.GoFiles) > 0 && filepath.Dir(mod.GoFiles[0]) == absDir {
+			modInfo = mod
+		}
+	}
+	if modInfo == nil {
+		log.Panic("No module found in directory", "dir", absDir)
+	}
+	modelsASTData := GetModelsASTData(modules)
+	var modelNames []string
+	for modelName, mData := range GetModelsASTDataForModules([]*ModuleInfo{modInfo}, false) {
+		if !mData.Validated || (mData.ModelType != "" && mData.ModelType != "Transient") {
+			continue
+		}
+		modelNames = append(modelNames, modelName)
+	}
+	sort.Strings(modelNames)
+	for _, modelName := range modelNames {
+		fileName := filepath.Join(absDir, fmt.Sprintf("%s_crud_test.go", strutils.SnakeCase(modelName)))
+		if _, err := os.Stat(fileName); err == nil {
+			continue
+		}
+		CreateFileFromTemplate(fileName, testScaffoldTemplate, newTestScaffoldData(modInfo.Name, modelsASTData[modelName]))
+	}
+	if len(modelNames) > 0 && !hasTestMain(absDir) {
+		CreateFileFromTemplate(filepath.Join(absDir, "main_test.go"), testMainTemplate, modInfo.Name)
+	}
+}
+
+// hasTestMain returns true if a test file of the given directory declares a TestMain function
+func hasTestMain(dir string) bool {
+	testFiles, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return false
+	}
+	for _, testFile := range testFiles {
+		content, err := ioutil.ReadFile(testFile)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(string(content), "func TestMain(") {
+			return true
+		}
+	}
+	return false
+}
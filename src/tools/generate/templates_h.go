@@ -529,5 +529,10 @@ func init() {
 {{- end }}
 	models.RegisterRecordSetWrapper("{{ .Name }}", {{ .Name }}Set{})
 	models.RegisterModelDataWrapper("{{ .Name }}", {{ .Name }}Data{})
+	models.RegisterMethodDocs("{{ .Name }}", map[string]string{
+{{- range $method, $doc := .MethodDocs }}
+		"{{ $method }}": {{ printf "%q" $doc }},
+{{- end }}
+	})
 }
 `))
@@ -0,0 +1,81 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package generate
+
+import "text/template"
+
+var testScaffoldTemplate = template.Must(template.New("").Parse(`
+// This file has been generated by hexya-generate as a scaffold of the tests
+// of the {{ .ModelName }} model. It is not overwritten by later generations,
+// so that it can be completed freely.
+
+package {{ .PackageName }}
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+{{- if .NeedsDates }}
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+{{- end }}
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func Test{{ .ModelName }}CRUD(t *testing.T) {
+	Convey("Testing CRUD round-trips of {{ .ModelName }} records", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			rs := env.Pool("{{ .ModelName }}")
+			mi := rs.Model()
+			// requiredData returns the values of the required fields of new records
+			requiredData := func() *models.ModelData {
+				// TODO: set the required fields that are not set below{{ range .MissingRequired }}
+				// - {{ . }}{{ end }}
+				return models.NewModelData(mi){{ range .Required }}.
+					Set(mi.FieldName("{{ .Name }}"), {{ .Value }}){{ end }}
+			}
+			cases := []struct {
+				field   string
+				value   interface{}
+				updated interface{}
+			}{
+{{- range .Fields }}
+				{"{{ .Name }}", {{ .Value }}, {{ .Updated }}},
+{{- end }}
+			}
+			for _, c := range cases {
+				Convey(fmt.Sprintf("Field %s should be stored and updated", c.field), func() {
+					fName := mi.FieldName(c.field)
+					rec := rs.Call("Create", requiredData().Set(fName, c.value)).(models.RecordSet).Collection()
+					env.InvalidateCache()
+					So(fmt.Sprint(rec.Get(fName)), ShouldEqual, fmt.Sprint(c.value))
+					rec.Call("Write", models.NewModelData(mi).Set(fName, c.updated))
+					env.InvalidateCache()
+					So(fmt.Sprint(rec.Get(fName)), ShouldEqual, fmt.Sprint(c.updated))
+					id := rec.Ids()[0]
+					rec.Call("Unlink")
+					So(rs.Search(mi.Field(mi.FieldName("ID")).Equals(id)).IsEmpty(), ShouldBeTrue)
+				})
+			}
+		}), ShouldBeNil)
+	})
+}
+`))
+
+var testMainTemplate = template.Must(template.New("").Parse(`
+// This file has been generated by hexya-generate with the test scaffolds.
+
+package {{ . }}
+
+import (
+	"testing"
+
+	"github.com/hexya-erp/hexya/src/tests"
+)
+
+func TestMain(m *testing.M) {
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+`))
@@ -0,0 +1,301 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package htmlutils sanitizes HTML fragments given by users so that they
+// can be displayed safely, that is without scripts nor external content
+// that would run or load in the browser of other users.
+package htmlutils
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// A Policy defines what Sanitize keeps of an HTML fragment
+type Policy struct {
+	// Tags maps the allowed tags to their allowed attributes, in addition
+	// to Attributes. Other tags are removed but their content is kept.
+	Tags map[string][]string
+	// Attributes are the attributes allowed on all tags
+	Attributes []string
+	// MaxImageSize is the maximum width and height in pixels of images.
+	// Larger width and height attributes are reduced to this value, and
+	// max-width is set on images without width. 0 means no limit.
+	MaxImageSize int
+	// MaxDataImageLength is the maximum length of the data URIs of images
+	// that are embedded in the fragment. Larger images are removed.
+	// 0 means that embedded images are not allowed.
+	MaxDataImageLength int
+}
+
+// DefaultPolicy is the policy applied to HTML fields
+var DefaultPolicy = Policy{
+	Tags: map[string][]string{
+		"a":          {"href", "target"},
+		"abbr":       nil,
+		"b":          nil,
+		"blockquote": {"cite"},
+		"br":         nil,
+		"caption":    nil,
+		"code":       nil,
+		"col":        {"span"},
+		"colgroup":   {"span"},
+		"dd":         nil,
+		"del":        nil,
+		"div":        nil,
+		"dl":         nil,
+		"dt":         nil,
+		"em":         nil,
+		"font":       {"color"},
+		"h1":         nil,
+		"h2":         nil,
+		"h3":         nil,
+		"h4":         nil,
+		"h5":         nil,
+		"h6":         nil,
+		"hr":         nil,
+		"i":          nil,
+		"img":        {"src", "alt", "width", "height"},
+		"ins":        nil,
+		"li":         nil,
+		"ol":         {"start", "type"},
+		"p":          nil,
+		"pre":        nil,
+		"s":          nil,
+		"small":      nil,
+		"span":       nil,
+		"strike":     nil,
+		"strong":     nil,
+		"sub":        nil,
+		"sup":        nil,
+		"table":      {"border", "cellpadding", "cellspacing"},
+		"tbody":      nil,
+		"td":         {"colspan", "rowspan"},
+		"tfoot":      nil,
+		"th":         {"colspan", "rowspan", "scope"},
+		"thead":      nil,
+		"tr":         nil,
+		"u":          nil,
+		"ul":         nil,
+	},
+	Attributes:         []string{"class", "title", "align", "dir", "lang"},
+	MaxImageSize:       1920,
+	MaxDataImageLength: 1 << 20,
+}
+
+// droppedTags are the tags that are removed with their content
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Applet:   true,
+	atom.Head:     true,
+	atom.Title:    true,
+	atom.Template: true,
+	atom.Noscript: true,
+	atom.Textarea: true,
+	atom.Select:   true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+// urlAttributes are the attributes whose value is a URL
+var urlAttributes = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+// safeSchemes are the schemes allowed in URLs. URLs without scheme are relative.
+var safeSchemes = []string{"http:", "https:", "mailto:", "tel:"}
+
+// Sanitize returns the given HTML fragment with only the tags and attributes
+// allowed by the given policy. Scripts, styles and comments are removed, as
+// well as URLs with a scheme that can run code such as 'javascript:'. Links
+// that open in a new window are given rel="noopener noreferrer".
+func Sanitize(src string, policy Policy) string {
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(src), context)
+	if err != nil {
+		// The parser is lenient and only fails on read errors
+		return html.EscapeString(src)
+	}
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		for _, n := range policy.sanitizeNode(node) {
+			html.Render(&buf, n)
+		}
+	}
+	return buf.String()
+}
+
+// sanitizeNode returns the nodes that replace the given node once sanitized
+func (p Policy) sanitizeNode(node *html.Node) []*html.Node {
+	switch node.Type {
+	case html.TextNode:
+		return []*html.Node{node}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+	if droppedTags[node.DataAtom] {
+		return nil
+	}
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, p.sanitizeNode(child)...)
+	}
+	for _, child := range children {
+		if child.Parent != nil {
+			child.Parent.RemoveChild(child)
+		}
+	}
+	tagAttrs, ok := p.Tags[node.Data]
+	if !ok || node.Namespace != "" {
+		// Unknown tags are replaced by their content
+		return children
+	}
+	res := &html.Node{Type: html.ElementNode, Data: node.Data, DataAtom: node.DataAtom}
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !p.allowedAttr(tagAttrs, attr.Key) {
+			continue
+		}
+		if urlAttributes[attr.Key] && !p.safeURL(node.DataAtom, attr.Val) {
+			continue
+		}
+		res.Attr = append(res.Attr, attr)
+	}
+	switch node.DataAtom {
+	case atom.Img:
+		if getAttr(res, "src") == "" {
+			return nil
+		}
+		p.limitImageSize(res)
+	case atom.A:
+		if getAttr(res, "target") != "" {
+			setAttr(res, "rel", "noopener noreferrer")
+		}
+	}
+	for _, child := range children {
+		res.AppendChild(child)
+	}
+	return []*html.Node{res}
+}
+
+// allowedAttr returns true if the attribute with the given key is allowed
+// by this policy on a tag that allows the given attributes.
+func (p Policy) allowedAttr(tagAttrs []string, key string) bool {
+	for _, allowed := range tagAttrs {
+		if key == allowed {
+			return true
+		}
+	}
+	for _, allowed := range p.Attributes {
+		if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// safeURL returns true if the given URL of an attribute of a tag cannot run
+// code. Data URIs are only allowed for images within the policy's limits.
+func (p Policy) safeURL(tag atom.Atom, value string) bool {
+	// Browsers ignore control characters and spaces in schemes
+	url := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(value))
+	if strings.HasPrefix(url, "data:") {
+		return tag == atom.Img && strings.HasPrefix(url, "data:image/") && !strings.HasPrefix(url, "data:image/svg") &&
+			len(value) <= p.MaxDataImageLength
+	}
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		// Relative URL
+		return true
+	}
+	for _, scheme := range safeSchemes {
+		if strings.HasPrefix(url, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitImageSize reduces the width and height of the given image to
+// the maximum size of this policy, keeping its aspect ratio.
+func (p Policy) limitImageSize(img *html.Node) {
+	if p.MaxImageSize == 0 {
+		return
+	}
+	width, wErr := strconv.Atoi(strings.TrimSuffix(getAttr(img, "width"), "px"))
+	height, hErr := strconv.Atoi(strings.TrimSuffix(getAttr(img, "height"), "px"))
+	if wErr != nil || width <= 0 {
+		removeAttr(img, "width")
+		width = 0
+	}
+	if hErr != nil || height <= 0 {
+		removeAttr(img, "height")
+		height = 0
+	}
+	if width > p.MaxImageSize {
+		if height > 0 {
+			height = height * p.MaxImageSize / width
+			setAttr(img, "height", strconv.Itoa(height))
+		}
+		width = p.MaxImageSize
+		setAttr(img, "width", strconv.Itoa(width))
+	}
+	if height > p.MaxImageSize {
+		if width > 0 {
+			setAttr(img, "width", strconv.Itoa(width*p.MaxImageSize/height))
+		}
+		setAttr(img, "height", strconv.Itoa(p.MaxImageSize))
+	}
+	if width == 0 {
+		setAttr(img, "style", "max-width: "+strconv.Itoa(p.MaxImageSize)+"px")
+	}
+}
+
+// getAttr returns the value of the attribute with the given key of
+// the given node, or an empty string if it does not have one.
+func getAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// setAttr sets the value of the attribute with the given key of the given node
+func setAttr(node *html.Node, key, value string) {
+	for i, attr := range node.Attr {
+		if attr.Key == key {
+			node.Attr[i].Val = value
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: key, Val: value})
+}
+
+// removeAttr removes the attribute with the given key of the given node
+func removeAttr(node *html.Node, key string) {
+	for i, attr := range node.Attr {
+		if attr.Key == key {
+			node.Attr = append(node.Attr[:i], node.Attr[i+1:]...)
+			return
+		}
+	}
+}
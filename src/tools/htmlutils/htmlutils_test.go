@@ -0,0 +1,59 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package htmlutils
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSanitize(t *testing.T) {
+	Convey("Testing HTML sanitization", t, func() {
+		Convey("Allowed tags and attributes should be kept", func() {
+			src := `<p class="lead">Hello <b>World</b><br/><a href="https://example.com/a?b=c">link</a></p>`
+			So(Sanitize(src, DefaultPolicy), ShouldEqual,
+				`<p class="lead">Hello <b>World</b><br/><a href="https://example.com/a?b=c">link</a></p>`)
+		})
+		Convey("Scripts and styles should be removed with their content", func() {
+			src := `<p>Hi<script>alert(1)</script><style>p {color: red}</style></p><!-- comment -->`
+			So(Sanitize(src, DefaultPolicy), ShouldEqual, `<p>Hi</p>`)
+		})
+		Convey("Unknown tags should be replaced by their content", func() {
+			So(Sanitize(`<form action="/x"><p>Name <input name="n"/></p></form>`, DefaultPolicy), ShouldEqual, `<p>Name </p>`)
+			So(Sanitize(`<custom>text</custom>`, DefaultPolicy), ShouldEqual, `text`)
+		})
+		Convey("Event handlers and styles should be stripped", func() {
+			src := `<div onclick="alert(1)" style="background: url(javascript:alert(1))">x</div>`
+			So(Sanitize(src, DefaultPolicy), ShouldEqual, `<div>x</div>`)
+		})
+		Convey("Unsafe URLs should be removed", func() {
+			So(Sanitize(`<a href="javascript:alert(1)">x</a>`, DefaultPolicy), ShouldEqual, `<a>x</a>`)
+			So(Sanitize(`<a href=" JaVa&#10;script:alert(1)">x</a>`, DefaultPolicy), ShouldEqual, `<a>x</a>`)
+			So(Sanitize(`<a href="/web#id=3">x</a>`, DefaultPolicy), ShouldEqual, `<a href="/web#id=3">x</a>`)
+			So(Sanitize(`<a href="mailto:a@example.com">x</a>`, DefaultPolicy), ShouldEqual, `<a href="mailto:a@example.com">x</a>`)
+			So(Sanitize(`<a href="data:text/html,x">x</a>`, DefaultPolicy), ShouldEqual, `<a>x</a>`)
+		})
+		Convey("Links opening new windows should not give access to their opener", func() {
+			So(Sanitize(`<a href="/x" target="_blank">x</a>`, DefaultPolicy), ShouldEqual,
+				`<a href="/x" target="_blank" rel="noopener noreferrer">x</a>`)
+		})
+		Convey("Text should be escaped", func() {
+			So(Sanitize(`1 < 2 & "3"`, DefaultPolicy), ShouldEqual, `1 &lt; 2 &amp; &#34;3&#34;`)
+		})
+		Convey("Images should be limited in size", func() {
+			So(Sanitize(`<img src="/a.png" width="3840" height="1080"/>`, DefaultPolicy), ShouldEqual,
+				`<img src="/a.png" width="1920" height="540"/>`)
+			So(Sanitize(`<img src="/a.png" height="4000"/>`, DefaultPolicy), ShouldEqual,
+				`<img src="/a.png" height="1920" style="max-width: 1920px"/>`)
+			So(Sanitize(`<img alt="no source"/>`, DefaultPolicy), ShouldBeEmpty)
+			So(Sanitize(`<img src="data:image/png;base64,AAAA"/>`, DefaultPolicy), ShouldEqual,
+				`<img src="data:image/png;base64,AAAA" style="max-width: 1920px"/>`)
+			large := `<img src="data:image/png;base64,` + strings.Repeat("A", 1<<20) + `"/>`
+			So(Sanitize(large, DefaultPolicy), ShouldBeEmpty)
+			So(Sanitize(`<img src="data:image/svg+xml;base64,AAAA"/>`, DefaultPolicy), ShouldBeEmpty)
+		})
+	})
+}
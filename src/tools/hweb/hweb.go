@@ -66,7 +66,9 @@ func ToPongo(src []byte) ([]byte, error) {
 	if err = transpileVariables(doc.ChildElements()); err != nil {
 		return nil, err
 	}
-	transpileSmartFields(doc.ChildElements())
+	if err = transpileSmartFields(doc.ChildElements()); err != nil {
+		return nil, err
+	}
 	doc.WriteSettings.CanonicalText = true
 	res, err := doc.WriteToBytes()
 	if err != nil {
@@ -369,7 +371,55 @@ func transpileCalls(elts []*etree.Element) error {
 	return nil
 }
 
-// transpileSmartFields handles t-field attributes
-func transpileSmartFields(elts []*etree.Element) {
+// htmlWidgets are the t-field widgets that output
+// sanitized HTML, which must not be escaped.
+var htmlWidgets = map[string]bool{
+	"markdown": true,
+}
 
+// transpileSmartFields handles t-field attributes.
+//
+// t-field="doc.Partner.Name" is transpiled to a call to the 't_field' function
+// of the template context with the record 'doc.Partner' and the field name
+// 'Name'. The widget to use can be given with the t-options-widget attribute
+// and the currency of monetary fields with the t-options-display_currency one.
+// The output of HTML widgets such as 'markdown' is not escaped.
+func transpileSmartFields(elts []*etree.Element) error {
+	for _, elt := range elts {
+		if err := transpileSmartFields(elt.ChildElements()); err != nil {
+			return err
+		}
+		fieldAttr := elt.SelectAttr("t-field")
+		if fieldAttr == nil {
+			continue
+		}
+		sepIndex := strings.LastIndex(fieldAttr.Value, ".")
+		if sepIndex <= 0 || sepIndex == len(fieldAttr.Value)-1 {
+			return fmt.Errorf("t-field value must be in the form 'record.Field' (got '%s')", fieldAttr.Value)
+		}
+		widget := elt.SelectAttrValue("t-options-widget", "")
+		args := []string{
+			fieldAttr.Value[:sepIndex],
+			fmt.Sprintf("%q", fieldAttr.Value[sepIndex+1:]),
+			fmt.Sprintf("%q", widget),
+		}
+		if currency := elt.SelectAttrValue("t-options-display_currency", ""); currency != "" {
+			args = append(args, currency)
+		}
+		format := "{{ t_field(%s) }}"
+		if htmlWidgets[widget] {
+			format = "{{ t_field(%s)|safe }}"
+		}
+		text := fmt.Sprintf(format, strings.Join(args, ", "))
+		elt.RemoveAttr("t-field")
+		elt.RemoveAttr("t-options-widget")
+		elt.RemoveAttr("t-options-display_currency")
+		if elt.Tag == "t" {
+			elt.Parent().InsertChild(elt, &etree.CharData{Data: text})
+			elt.Parent().RemoveChild(elt)
+			continue
+		}
+		elt.SetText(text)
+	}
+	return nil
 }
@@ -406,3 +406,40 @@ func TestToPongo(t *testing.T) {
 		So(err.Error(), ShouldEqual, "t-call attribute set on non 't' XML tag")
 	})
 }
+
+var (
+	template8 = `
+<div>
+	<span t-field="doc.Name"/>
+	<t t-field="doc.Partner.Birthday" t-options-widget="date"/>
+	<p class="amount" t-field="doc.Amount" t-options-widget="monetary" t-options-display_currency="doc.Currency"/>
+	<div t-field="doc.Notes" t-options-widget="markdown"/>
+</div>`
+	template81 = `
+<span t-field="doc"/>`
+)
+
+func TestTranspileSmartFields(t *testing.T) {
+	Convey("Testing t-field transpilation", t, func() {
+		doc, err := xmlutils.XMLToDocument(template8)
+		if err != nil {
+			panic(err)
+		}
+		So(transpileSmartFields(doc.ChildElements()), ShouldBeNil)
+		doc.WriteSettings.CanonicalText = true
+		resXML, err := doc.WriteToString()
+		So(err, ShouldBeNil)
+		So(string(resXML), ShouldEqual, `
+<div>
+	<span>{{ t_field(doc, "Name", "") }}</span>
+	{{ t_field(doc.Partner, "Birthday", "date") }}
+	<p class="amount">{{ t_field(doc, "Amount", "monetary", doc.Currency) }}</p>
+	<div>{{ t_field(doc, "Notes", "markdown")|safe }}</div>
+</div>`)
+	})
+	Convey("t-field without record should fail", t, func() {
+		_, err := ToPongo([]byte(template81))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "t-field value must be in the form 'record.Field' (got 'doc')")
+	})
+}
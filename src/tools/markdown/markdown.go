@@ -0,0 +1,525 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package markdown renders Markdown texts to HTML.
+//
+// It supports the common subset of CommonMark: ATX headings, paragraphs,
+// hard line breaks, block quotes, ordered and bullet lists, fenced and
+// indented code blocks, thematic breaks, emphasis, strong emphasis, code
+// spans, links, images and autolinks, as well as the ~~strikethrough~~
+// extension. Raw HTML is escaped.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/tools/htmlutils"
+)
+
+var (
+	headingRE       = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))??(?:[ \t]+#+)?[ \t]*$`)
+	ruleRE          = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRE         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`]*)$")
+	bulletRE        = regexp.MustCompile(`^( {0,3})([-*+])( {1,4}|$)`)
+	orderedRE       = regexp.MustCompile(`^( {0,3})([0-9]{1,9})([.)])( {1,4}|$)`)
+	autolinkRE      = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailAutolinkRE = regexp.MustCompile(`^<([^\s@<>]+@[^\s@<>]+\.[^\s@<>]+)>`)
+)
+
+// ToHTML returns the HTML rendering of the given Markdown text,
+// sanitized with htmlutils.DefaultPolicy.
+func ToHTML(src string) string {
+	return htmlutils.Sanitize(render(src), htmlutils.DefaultPolicy)
+}
+
+// render returns the HTML rendering of the given Markdown text
+func render(src string) string {
+	src = strings.Replace(src, "\r\n", "\n", -1)
+	src = strings.Replace(src, "\t", "    ", -1)
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), false)
+	return b.String()
+}
+
+// renderBlocks writes the HTML of the blocks of the given lines to b.
+// If tight is true, paragraphs are not wrapped in <p> tags, as in the
+// items of tight lists.
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case indentation(line) >= 4:
+			i = renderIndentedCode(b, lines, i)
+		case fenceRE.MatchString(line):
+			i = renderFencedCode(b, lines, i)
+		case headingRE.MatchString(line):
+			m := headingRE.FindStringSubmatch(line)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", len(m[1]), renderInline(m[2]), len(m[1]))
+			i++
+		case ruleRE.MatchString(line):
+			b.WriteString("<hr/>\n")
+			i++
+		case isBlockQuote(line):
+			i = renderBlockQuote(b, lines, i)
+		case isListItem(line):
+			i = renderList(b, lines, i)
+		default:
+			i = renderParagraph(b, lines, i, tight)
+		}
+	}
+}
+
+// renderIndentedCode writes the indented code block starting at
+// lines[start] to b and returns the index of the following line.
+func renderIndentedCode(b *strings.Builder, lines []string, start int) int {
+	end := start
+	var code []string
+	for i := start; i < len(lines) && (isBlank(lines[i]) || indentation(lines[i]) >= 4); i++ {
+		if isBlank(lines[i]) {
+			code = append(code, "")
+			continue
+		}
+		code = append(code, lines[i][4:])
+		end = i + 1
+	}
+	code = code[:end-start]
+	fmt.Fprintf(b, "<pre><code>%s\n</code></pre>\n", html.EscapeString(strings.Join(code, "\n")))
+	return end
+}
+
+// renderFencedCode writes the fenced code block starting at lines[start]
+// to b and returns the index of the line following its closing fence. The
+// first word of the info string gives the language of the code.
+func renderFencedCode(b *strings.Builder, lines []string, start int) int {
+	m := fenceRE.FindStringSubmatch(lines[start])
+	fence, info := m[1], strings.Fields(m[2])
+	indent := indentation(lines[start])
+	var code []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if indentation(lines[i]) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, strings.TrimPrefix(lines[i], strings.Repeat(" ", min(indent, indentation(lines[i])))))
+	}
+	b.WriteString("<pre><code")
+	if len(info) > 0 {
+		fmt.Fprintf(b, ` class="language-%s"`, html.EscapeString(info[0]))
+	}
+	b.WriteString(">")
+	if len(code) > 0 {
+		b.WriteString(html.EscapeString(strings.Join(code, "\n")) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderBlockQuote writes the block quote starting at lines[start]
+// to b and returns the index of the following line.
+func renderBlockQuote(b *strings.Builder, lines []string, start int) int {
+	var content []string
+	i := start
+	for ; i < len(lines) && isBlockQuote(lines[i]); i++ {
+		line := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+		content = append(content, strings.TrimPrefix(line, " "))
+	}
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, content, false)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+// renderList writes the list starting at lines[start] to b and returns the
+// index of the following line. The list is loose, and its items wrapped in
+// paragraphs, if blank lines separate its items or their blocks.
+func renderList(b *strings.Builder, lines []string, start int) int {
+	ordered, first, _ := listMarker(lines[start])
+	var (
+		items [][]string
+		loose bool
+	)
+	i := start
+	for i < len(lines) {
+		o, _, offset := listMarker(lines[i])
+		if offset == 0 || o != ordered {
+			break
+		}
+		item := []string{strings.TrimLeft(lines[i][min(offset, len(lines[i])):], " ")}
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				next := i
+				for next < len(lines) && isBlank(lines[next]) {
+					next++
+				}
+				if next == len(lines) || indentation(lines[next]) < offset {
+					break
+				}
+				for ; i < next; i++ {
+					item = append(item, "")
+				}
+				loose = true
+				continue
+			}
+			if indentation(line) >= offset {
+				item = append(item, line[offset:])
+				i++
+				continue
+			}
+			if isListItem(line) || startsBlock(line) || isBlank(item[len(item)-1]) {
+				break
+			}
+			// Lazy continuation of a paragraph
+			item = append(item, strings.TrimSpace(line))
+			i++
+		}
+		items = append(items, item)
+		next := i
+		for next < len(lines) && isBlank(lines[next]) {
+			next++
+		}
+		if next > i && next < len(lines) {
+			if o, _, offset := listMarker(lines[next]); offset > 0 && o == ordered {
+				loose = true
+				i = next
+			}
+		}
+	}
+	switch {
+	case !ordered:
+		b.WriteString("<ul>\n")
+	case first != 1:
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", first)
+	default:
+		b.WriteString("<ol>\n")
+	}
+	for _, item := range items {
+		b.WriteString("<li>")
+		if loose {
+			b.WriteString("\n")
+		}
+		renderBlocks(b, item, !loose)
+		b.WriteString("</li>\n")
+	}
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// renderParagraph writes the paragraph starting at lines[start] to b and
+// returns the index of the following line. If tight is true, the paragraph
+// is not wrapped in a <p> tag.
+func renderParagraph(b *strings.Builder, lines []string, start int, tight bool) int {
+	var content []string
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) || (i > start && startsBlock(line)) {
+			break
+		}
+		if strings.HasSuffix(line, "  ") {
+			// Hard line break
+			line = strings.TrimRight(line, " ") + `\`
+		}
+		content = append(content, strings.TrimSpace(line))
+	}
+	text := strings.TrimSuffix(strings.Join(content, "\n"), `\`)
+	if tight {
+		b.WriteString(renderInline(text))
+		return i
+	}
+	fmt.Fprintf(b, "<p>%s</p>\n", renderInline(text))
+	return i
+}
+
+// renderInline returns the HTML of the given inline Markdown text
+func renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br/>\n")
+			i += 2
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+		case c == '`':
+			n := runLength(s, i)
+			end := strings.Index(s[i+n:], s[i:i+n])
+			if end < 0 {
+				b.WriteString(s[i : i+n])
+				i += n
+				break
+			}
+			code := strings.Replace(s[i+n:i+n+end], "\n", " ", -1)
+			fmt.Fprintf(&b, "<code>%s</code>", html.EscapeString(strings.TrimSpace(code)))
+			i += 2*n + end
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			text, dest, title, n := parseLink(s[i+1:])
+			if n == 0 {
+				b.WriteByte('!')
+				i++
+				break
+			}
+			fmt.Fprintf(&b, `<img src="%s" alt="%s"%s/>`, html.EscapeString(dest), html.EscapeString(plainText(text)), titleAttr(title))
+			i += n + 1
+		case c == '[':
+			text, dest, title, n := parseLink(s[i:])
+			if n == 0 {
+				b.WriteByte('[')
+				i++
+				break
+			}
+			fmt.Fprintf(&b, `<a href="%s"%s>%s</a>`, html.EscapeString(dest), titleAttr(title), renderInline(text))
+			i += n
+		case c == '<':
+			if m := autolinkRE.FindStringSubmatch(s[i:]); m != nil {
+				fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(m[1]), html.EscapeString(m[1]))
+				i += len(m[0])
+				break
+			}
+			if m := emailAutolinkRE.FindStringSubmatch(s[i:]); m != nil {
+				fmt.Fprintf(&b, `<a href="mailto:%s">%s</a>`, html.EscapeString(m[1]), html.EscapeString(m[1]))
+				i += len(m[0])
+				break
+			}
+			b.WriteString("&lt;")
+			i++
+		case c == '*' || c == '_' || c == '~':
+			res, n := renderEmphasis(s, i)
+			b.WriteString(res)
+			i += n
+		default:
+			next := strings.IndexAny(s[i+1:], "\\`![<*_~")
+			if next < 0 {
+				next = len(s) - i - 1
+			}
+			b.WriteString(html.EscapeString(s[i : i+1+next]))
+			i += 1 + next
+		}
+	}
+	return b.String()
+}
+
+// punctuation are the characters that can be escaped with a backslash
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// renderEmphasis returns the HTML of the emphasis, strong emphasis or
+// strikethrough starting at s[i], and the length of its Markdown text.
+// If the delimiters at s[i] do not open an emphasis, they are returned
+// as is.
+func renderEmphasis(s string, i int) (string, int) {
+	delim := s[i]
+	n := runLength(s, i)
+	literal := s[i : i+n]
+	switch {
+	case delim == '~' && n != 2:
+		return literal, n
+	case n > 3:
+		n = 3
+	}
+	opens := i+n < len(s) && s[i+n] != ' ' && s[i+n] != '\n'
+	if delim == '_' && i > 0 && isAlphaNum(s[i-1]) {
+		opens = false
+	}
+	if !opens {
+		return literal, len(literal)
+	}
+	for j := i + n + 1; j+n <= len(s); j++ {
+		if s[j] == '`' {
+			// Skip code spans
+			if end := strings.IndexByte(s[j+1:], '`'); end >= 0 {
+				j += end + 1
+			}
+			continue
+		}
+		if s[j] != delim {
+			continue
+		}
+		run := runLength(s, j)
+		closes := s[j-1] != ' ' && s[j-1] != '\n' && run >= n && (n != 1 || run != 2)
+		if delim == '_' && j+run < len(s) && isAlphaNum(s[j+run]) {
+			closes = false
+		}
+		if !closes {
+			j += run - 1
+			continue
+		}
+		inner := renderInline(s[i+n : j])
+		switch {
+		case delim == '~':
+			inner = "<del>" + inner + "</del>"
+		case n == 1:
+			inner = "<em>" + inner + "</em>"
+		case n == 2:
+			inner = "<strong>" + inner + "</strong>"
+		default:
+			inner = "<strong><em>" + inner + "</em></strong>"
+		}
+		return inner, j + n - i
+	}
+	return literal, len(literal)
+}
+
+// parseLink parses the link at the beginning of s, in the form
+// [text](destination "title"). It returns the text, the destination and
+// the title of the link and the length of its Markdown text, which is 0
+// if s does not begin with a link.
+func parseLink(s string) (string, string, string, int) {
+	depth, closing := 0, -1
+loop:
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = j
+				break loop
+			}
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return "", "", "", 0
+	}
+	end := closingParenthesis(s[closing+2:])
+	if end < 0 {
+		return "", "", "", 0
+	}
+	inside := strings.TrimSpace(s[closing+2 : closing+2+end])
+	dest, title := inside, ""
+	if sep := strings.IndexAny(inside, " \n"); sep >= 0 {
+		dest, title = inside[:sep], strings.TrimSpace(inside[sep+1:])
+		if len(title) < 2 || title[0] != title[len(title)-1] || strings.IndexByte(`"'`, title[0]) < 0 {
+			return "", "", "", 0
+		}
+		title = title[1 : len(title)-1]
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	return s[1:closing], dest, title, closing + 3 + end
+}
+
+// closingParenthesis returns the index in s of the parenthesis that closes
+// an opened one, skipping balanced parentheses, or -1 if there is none.
+func closingParenthesis(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// titleAttr returns the title attribute of a link or an
+// image with the given title, if it is not empty.
+func titleAttr(title string) string {
+	if title == "" {
+		return ""
+	}
+	return fmt.Sprintf(` title="%s"`, html.EscapeString(title))
+}
+
+// plainText returns the given inline Markdown text without markup,
+// as used for the alternative text of images.
+func plainText(s string) string {
+	return strings.NewReplacer("*", "", "_", "", "`", "", "~~", "").Replace(s)
+}
+
+// runLength returns the number of consecutive s[i] characters starting at i
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// isAlphaNum returns true if the given byte is an ASCII letter or digit,
+// or a part of a multibyte character.
+func isAlphaNum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// startsBlock returns true if the given line starts a block that
+// interrupts a paragraph.
+func startsBlock(line string) bool {
+	return fenceRE.MatchString(line) || headingRE.MatchString(line) || ruleRE.MatchString(line) ||
+		isBlockQuote(line) || isListItem(line)
+}
+
+// listMarker returns whether the given line is an item of an ordered list,
+// the number of this item and the offset of its content. The offset is 0
+// if the line is not a list item.
+func listMarker(line string) (bool, int, int) {
+	if m := bulletRE.FindStringSubmatch(line); m != nil && !ruleRE.MatchString(line) {
+		return false, 0, listOffset(len(m[1])+len(m[2]), m[3])
+	}
+	if m := orderedRE.FindStringSubmatch(line); m != nil {
+		num, _ := strconv.Atoi(m[2])
+		return true, num, listOffset(len(m[1])+len(m[2])+len(m[3]), m[3])
+	}
+	return false, 0, 0
+}
+
+// listOffset returns the offset of the content of a list item
+// whose marker ends at markerEnd and is followed by spaces.
+func listOffset(markerEnd int, spaces string) int {
+	if spaces == "" {
+		return markerEnd + 1
+	}
+	return markerEnd + len(spaces)
+}
+
+// isListItem returns true if the given line starts a list item
+func isListItem(line string) bool {
+	_, _, offset := listMarker(line)
+	return offset > 0
+}
+
+// isBlockQuote returns true if the given line is a line of a block quote
+func isBlockQuote(line string) bool {
+	return indentation(line) < 4 && strings.HasPrefix(strings.TrimLeft(line, " "), ">")
+}
+
+// isBlank returns true if the given line only contains spaces
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indentation returns the number of leading spaces of the given line
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// min returns the smallest of a and b
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package markdown
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRender(t *testing.T) {
+	Convey("Testing Markdown rendering", t, func() {
+		Convey("Headings, paragraphs and rules", func() {
+			So(render("# Title #\n\nSome *text*\non two lines.  \nBreak\n\n---\n## Sub"), ShouldEqual,
+				"<h1>Title</h1>\n<p>Some <em>text</em>\non two lines.<br/>\nBreak</p>\n<hr/>\n<h2>Sub</h2>\n")
+		})
+		Convey("Inline markup", func() {
+			So(renderInline("**bold** and _it_ and ~~del~~ and `a < b`"), ShouldEqual,
+				"<strong>bold</strong> and <em>it</em> and <del>del</del> and <code>a &lt; b</code>")
+			So(renderInline("snake_case_name and 2 * 3 * 4"), ShouldEqual, "snake_case_name and 2 * 3 * 4")
+			So(renderInline(`\*not em\* & <b>`), ShouldEqual, "*not em* &amp; &lt;b&gt;")
+			So(renderInline("***both***"), ShouldEqual, "<strong><em>both</em></strong>")
+		})
+		Convey("Links and images", func() {
+			So(renderInline(`[the *site*](https://example.com "Example") <https://hexya.io> <a@example.com>`), ShouldEqual,
+				`<a href="https://example.com" title="Example">the <em>site</em></a> `+
+					`<a href="https://hexya.io">https://hexya.io</a> <a href="mailto:a@example.com">a@example.com</a>`)
+			So(renderInline(`![a *logo*](/logo.png)`), ShouldEqual, `<img src="/logo.png" alt="a logo"/>`)
+			So(renderInline(`[not a link] (here)`), ShouldEqual, `[not a link] (here)`)
+		})
+		Convey("Lists", func() {
+			So(render("- one\n- two\n  - nested\n- three"), ShouldEqual,
+				"<ul>\n<li>one</li>\n<li>two<ul>\n<li>nested</li>\n</ul>\n</li>\n<li>three</li>\n</ul>\n")
+			So(render("3. three\n4. four\n\n5. five"), ShouldEqual,
+				"<ol start=\"3\">\n<li>\n<p>three</p>\n</li>\n<li>\n<p>four</p>\n</li>\n<li>\n<p>five</p>\n</li>\n</ol>\n")
+			So(render("* item\nlazy line\n\nParagraph"), ShouldEqual,
+				"<ul>\n<li>item\nlazy line</li>\n</ul>\n<p>Paragraph</p>\n")
+		})
+		Convey("Code blocks and quotes", func() {
+			So(render("```go\nif a < b {\n}\n```\n\n    indented\n\n    code\n\n> quoted\n> *text*"), ShouldEqual,
+				"<pre><code class=\"language-go\">if a &lt; b {\n}\n</code></pre>\n"+
+					"<pre><code>indented\n\ncode\n</code></pre>\n"+
+					"<blockquote>\n<p>quoted\n<em>text</em></p>\n</blockquote>\n")
+		})
+	})
+	Convey("Testing Markdown to sanitized HTML", t, func() {
+		So(ToHTML("[click](javascript:alert(1)) <script>alert(1)</script>"), ShouldEqual,
+			"<p><a>click</a> &lt;script&gt;alert(1)&lt;/script&gt;</p>\n")
+	})
+}
@@ -15,20 +15,25 @@
 package tools
 
 import (
-	"io/ioutil"
+	"path"
 	"path/filepath"
+
+	"github.com/hexya-erp/hexya/src/tools/assetfs"
 )
 
 // ListStaticFiles get all file names of the static files that are in
-// the "server/static/*/<subDir>" directories.
+// the "server/static/*/<subDir>" directories, either on the disk or in
+// the embedded assets.
 // Returned
 // If diskPath is true, returned file names have their path on the disk
 // otherwise file names are relative to the http root (e.g. /static/src/js/foo.js)
+// Note that files that are only embedded do not exist at their disk path.
 func ListStaticFiles(resourceDir, subDir string, modules []string, diskPath bool) []string {
 	var res []string
+	fsys := assetfs.Resources(resourceDir)
 	for _, module := range modules {
-		dirName := filepath.Join(resourceDir, "static", module, subDir)
-		fileInfos, _ := ioutil.ReadDir(dirName)
+		dirName := path.Join("static", module, subDir)
+		fileInfos, _ := assetfs.ReadDir(fsys, dirName)
 		for _, fi := range fileInfos {
 			if !fi.IsDir() {
 				fPath := filepath.Join("static", module, subDir, fi.Name())
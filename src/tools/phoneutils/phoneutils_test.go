@@ -0,0 +1,48 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package phoneutils
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNormalize(t *testing.T) {
+	Convey("Testing phone number normalization", t, func() {
+		Convey("International numbers should keep their country code", func() {
+			for _, number := range []string{"+33 1 23 45 67 89", "0033 (0)1-23-45-67-89", "+33.1.23.45.67.89"} {
+				res, err := Normalize(number, "")
+				So(err, ShouldBeNil)
+				So(res, ShouldEqual, "+33123456789")
+			}
+			res, err := Normalize("+1 (415) 555-0100", "FR")
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, "+14155550100")
+		})
+		Convey("National numbers should get the code of the given country", func() {
+			res, err := Normalize("01 23 45 67 89", "fr")
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, "+33123456789")
+			res, err = Normalize("1-415-555-0100", "US")
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, "+14155550100")
+			res, err = Normalize("06 1234 5678", "IT")
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, "+390612345678")
+		})
+		Convey("Invalid numbers should be rejected", func() {
+			_, err := Normalize("01 23 45 67 89", "")
+			So(err, ShouldNotBeNil)
+			_, err = Normalize("01 23 45 67 89", "ZZ")
+			So(err, ShouldNotBeNil)
+			_, err = Normalize("+33 1 23 45 67 89 ext. 12", "FR")
+			So(err, ShouldNotBeNil)
+			_, err = Normalize("+33", "")
+			So(err, ShouldNotBeNil)
+			_, err = Normalize("+1234567890123456", "")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
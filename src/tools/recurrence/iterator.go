@@ -0,0 +1,430 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package recurrence
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// maxYears is the maximum number of years between two occurrences. Since
+	// the Gregorian calendar repeats itself every 400 years, a rule without
+	// occurrence during this time has no more occurrences.
+	maxYears = 400
+	// maxYear is the last year in which occurrences are computed
+	maxYear = 9999
+)
+
+// An Iterator returns the occurrences of a Rule in chronological order
+type Iterator struct {
+	rule    Rule
+	loc     *time.Location
+	start   time.Time
+	until   time.Time
+	hours   []int
+	minutes []int
+	seconds []int
+	// period is the index of the next period for daily or less frequent rules
+	period int
+	// cursor is the start of the next period for more frequent rules
+	cursor time.Time
+	// last is the start of the last period with occurrences
+	last   time.Time
+	buffer []time.Time
+	count  int
+	done   bool
+}
+
+// Iterator returns a new Iterator on the occurrences of the rule
+func (r *Rule) Iterator() *Iterator {
+	it := Iterator{
+		rule:  *r,
+		loc:   r.DTStart.Location(),
+		start: r.DTStart.Truncate(time.Second),
+		until: r.Until,
+		last:  r.DTStart,
+	}
+	if it.rule.Interval < 1 {
+		it.rule.Interval = 1
+	}
+	if r.floatingUntil {
+		y, m, d := r.Until.Date()
+		it.until = localTime(time.Date(y, m, d, 0, 0, 0, 0, time.UTC), r.Until.Hour(), r.Until.Minute(), r.Until.Second(), it.loc)
+	}
+	if len(r.ByWeekNo)+len(r.ByYearDay)+len(r.ByMonthDay)+len(r.ByDay) == 0 {
+		// Days default to those of DTStart
+		switch r.Freq {
+		case Yearly:
+			if len(r.ByMonth) == 0 {
+				it.rule.ByMonth = []int{int(r.DTStart.Month())}
+			}
+			it.rule.ByMonthDay = []int{r.DTStart.Day()}
+		case Monthly:
+			it.rule.ByMonthDay = []int{r.DTStart.Day()}
+		case Weekly:
+			it.rule.ByDay = []Weekday{{Day: r.DTStart.Weekday()}}
+		}
+	}
+	it.hours, it.minutes, it.seconds = sortedInts(r.ByHour), sortedInts(r.ByMinute), sortedInts(r.BySecond)
+	if len(it.hours) == 0 {
+		it.hours = []int{r.DTStart.Hour()}
+	}
+	if len(it.minutes) == 0 {
+		it.minutes = []int{r.DTStart.Minute()}
+	}
+	if len(it.seconds) == 0 {
+		it.seconds = []int{r.DTStart.Second()}
+	}
+	switch r.Freq {
+	case Hourly:
+		it.cursor = r.DTStart.Add(-time.Duration(r.DTStart.Minute())*time.Minute - time.Duration(r.DTStart.Second())*time.Second)
+	case Minutely:
+		it.cursor = r.DTStart.Add(-time.Duration(r.DTStart.Second()) * time.Second)
+	case Secondly:
+		it.cursor = r.DTStart
+	}
+	it.cursor = it.cursor.Truncate(time.Second)
+	return &it
+}
+
+// Next returns the next occurrence of the rule. The second returned
+// value is false if the rule has no more occurrences.
+func (it *Iterator) Next() (time.Time, bool) {
+	for len(it.buffer) == 0 {
+		if it.done {
+			return time.Time{}, false
+		}
+		it.nextPeriod()
+	}
+	t := it.buffer[0]
+	it.buffer = it.buffer[1:]
+	it.count++
+	if !it.until.IsZero() && t.After(it.until) || it.rule.Count > 0 && it.count > it.rule.Count {
+		it.done = true
+		it.buffer = nil
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// nextPeriod computes the occurrences of the next period of the rule into the buffer
+func (it *Iterator) nextPeriod() {
+	var (
+		periodStart time.Time
+		occurrences []time.Time
+	)
+	if it.rule.Freq >= Hourly {
+		periodStart, occurrences = it.timePeriod()
+	} else {
+		periodStart, occurrences = it.dayPeriod()
+	}
+	if periodStart.Year() > maxYear || periodStart.Year()-it.last.Year() > maxYears ||
+		!it.until.IsZero() && periodStart.After(it.until) {
+		it.done = true
+		return
+	}
+	for _, t := range occurrences {
+		if t.Before(it.start) {
+			continue
+		}
+		it.buffer = append(it.buffer, t)
+	}
+	if len(occurrences) > 0 {
+		it.last = periodStart
+	}
+}
+
+// dayPeriod returns the start and the occurrences of the next
+// period of a rule whose frequency is daily or less frequent.
+func (it *Iterator) dayPeriod() (time.Time, []time.Time) {
+	r := &it.rule
+	n := it.period * r.Interval
+	it.period++
+	y0, m0, d0 := r.DTStart.Date()
+	var first, end time.Time
+	switch r.Freq {
+	case Yearly:
+		first = time.Date(y0+n, 1, 1, 0, 0, 0, 0, time.UTC)
+		end = first.AddDate(1, 0, 0)
+	case Monthly:
+		first = time.Date(y0, m0+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		end = first.AddDate(0, 1, 0)
+	case Weekly:
+		first = time.Date(y0, m0, d0-(int(r.DTStart.Weekday()-r.WeekStart)+7)%7+7*n, 0, 0, 0, 0, time.UTC)
+		end = first.AddDate(0, 0, 7)
+	default:
+		first = time.Date(y0, m0, d0+n, 0, 0, 0, 0, time.UTC)
+		end = first.AddDate(0, 0, 1)
+	}
+	var candidates []time.Time
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !it.dayMatches(day) {
+			continue
+		}
+		for _, h := range it.hours {
+			for _, m := range it.minutes {
+				for _, s := range it.seconds {
+					candidates = append(candidates, day.Add(time.Duration(h)*time.Hour+time.Duration(m)*time.Minute+time.Duration(s)*time.Second))
+				}
+			}
+		}
+	}
+	candidates = it.setPos(candidates)
+	res := make([]time.Time, len(candidates))
+	for i, c := range candidates {
+		res[i] = localTime(c, c.Hour(), c.Minute(), c.Second(), it.loc)
+	}
+	return localTime(first, 0, 0, 0, it.loc), sortUnique(res)
+}
+
+// timePeriod returns the start and the occurrences of the next
+// period of a rule whose frequency is hourly or more frequent.
+func (it *Iterator) timePeriod() (time.Time, []time.Time) {
+	r := &it.rule
+	step := time.Duration(r.Interval) * time.Second
+	switch r.Freq {
+	case Hourly:
+		step = time.Duration(r.Interval) * time.Hour
+	case Minutely:
+		step = time.Duration(r.Interval) * time.Minute
+	}
+	periodStart := it.cursor
+	it.cursor = it.cursor.Add(step)
+	local := periodStart.In(it.loc)
+	y, m, d := local.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if !it.dayMatches(day) {
+		// Skip the periods of this day
+		nextDay := localTime(day.AddDate(0, 0, 1), 0, 0, 0, it.loc)
+		if skip := nextDay.Sub(it.cursor) / step; skip > 0 {
+			it.cursor = it.cursor.Add(skip * step)
+		}
+		return periodStart, nil
+	}
+	if len(r.ByHour) > 0 && !containsInt(r.ByHour, local.Hour()) ||
+		r.Freq >= Minutely && len(r.ByMinute) > 0 && !containsInt(r.ByMinute, local.Minute()) ||
+		r.Freq == Secondly && len(r.BySecond) > 0 && !containsInt(r.BySecond, local.Second()) {
+		return periodStart, nil
+	}
+	var candidates []time.Time
+	switch r.Freq {
+	case Hourly:
+		for _, mn := range it.minutes {
+			for _, s := range it.seconds {
+				candidates = append(candidates, periodStart.Add(time.Duration(mn)*time.Minute+time.Duration(s)*time.Second))
+			}
+		}
+	case Minutely:
+		for _, s := range it.seconds {
+			candidates = append(candidates, periodStart.Add(time.Duration(s)*time.Second))
+		}
+	default:
+		candidates = []time.Time{periodStart}
+	}
+	return periodStart, it.setPos(candidates)
+}
+
+// dayMatches returns true if the given day (at midnight UTC)
+// matches the day parts of the rule.
+func (it *Iterator) dayMatches(day time.Time) bool {
+	r := &it.rule
+	y, m, d := day.Date()
+	yearLen := daysIn(y)
+	monthLen := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(m)) {
+		return false
+	}
+	if len(r.ByWeekNo) > 0 {
+		week, weeks := weekNumber(day, r.WeekStart)
+		if !matchesOrdinal(r.ByWeekNo, week, weeks) {
+			return false
+		}
+	}
+	if len(r.ByYearDay) > 0 && !matchesOrdinal(r.ByYearDay, day.YearDay(), yearLen) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !matchesOrdinal(r.ByMonthDay, d, monthLen) {
+		return false
+	}
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		switch {
+		case wd.N == 0:
+			return true
+		case r.Freq == Monthly || len(r.ByMonth) > 0:
+			// Nth weekday of the month
+			if wd.N > 0 && (d-1)/7+1 == wd.N || wd.N < 0 && (monthLen-d)/7+1 == -wd.N {
+				return true
+			}
+		default:
+			// Nth weekday of the year
+			yd := day.YearDay()
+			if wd.N > 0 && (yd-1)/7+1 == wd.N || wd.N < 0 && (yearLen-yd)/7+1 == -wd.N {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setPos returns the candidates of a period selected by the BYSETPOS part of the rule
+func (it *Iterator) setPos(candidates []time.Time) []time.Time {
+	if len(it.rule.BySetPos) == 0 {
+		return candidates
+	}
+	var res []time.Time
+	for _, pos := range it.rule.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(candidates) + pos
+		}
+		if i >= 0 && i < len(candidates) {
+			res = append(res, candidates[i])
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Before(res[j])
+	})
+	return res
+}
+
+// Between returns the occurrences of the rule after after and before before.
+// If inc is true, occurrences equal to after or before are included.
+func (r *Rule) Between(after, before time.Time, inc bool) []time.Time {
+	var res []time.Time
+	it := r.Iterator()
+	for t, ok := it.Next(); ok; t, ok = it.Next() {
+		if t.After(before) || !inc && t.Equal(before) {
+			break
+		}
+		if t.After(after) || inc && t.Equal(after) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// After returns the first occurrence of the rule after t, or a zero time
+// if there is none. If inc is true, an occurrence equal to t is returned.
+func (r *Rule) After(t time.Time, inc bool) time.Time {
+	it := r.Iterator()
+	for occ, ok := it.Next(); ok; occ, ok = it.Next() {
+		if occ.After(t) || inc && occ.Equal(t) {
+			return occ
+		}
+	}
+	return time.Time{}
+}
+
+// Next returns the first occurrence of the rule strictly after t,
+// or a zero time if there is none. It allows a Rule to be used as a
+// schedule of worker functions.
+func (r *Rule) Next(t time.Time) time.Time {
+	return r.After(t, false)
+}
+
+// localTime returns the time at the given hour, minute and second of the
+// given day (at midnight UTC) in loc, following RFC 5545 for times that do not
+// exist or are ambiguous because of a DST transition:
+//
+// - times in a gap are interpreted with the offset before the gap,
+// that is shifted forward by the length of the gap.
+// - ambiguous times are resolved to their first occurrence.
+func localTime(day time.Time, hour, min, sec int, loc *time.Location) time.Time {
+	y, m, d := day.Date()
+	wall := time.Date(y, m, d, hour, min, sec, 0, time.UTC)
+	_, offsetBefore := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, offsetAfter := wall.Add(24 * time.Hour).In(loc).Zone()
+	var res time.Time
+	for _, offset := range []int{offsetBefore, offsetAfter} {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if ty, tm, td := t.Date(); ty != y || tm != m || td != d || t.Hour() != hour || t.Minute() != min || t.Second() != sec {
+			continue
+		}
+		if res.IsZero() || t.Before(res) {
+			res = t
+		}
+	}
+	if res.IsZero() {
+		// The time is in a gap
+		res = wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	}
+	return res
+}
+
+// week1Start returns the first day of the first week of the given year,
+// that is the first week with at least 4 days in the year.
+func week1Start(year int, weekStart time.Weekday) time.Time {
+	jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan1.Weekday()-weekStart) + 7) % 7
+	if offset <= 3 {
+		return jan1.AddDate(0, 0, -offset)
+	}
+	return jan1.AddDate(0, 0, 7-offset)
+}
+
+// weekNumber returns the week number of the given day and the number
+// of weeks of its year. Days at the start or the end of a year may
+// belong to the last week of the previous year or to the first week
+// of the next year.
+func weekNumber(day time.Time, weekStart time.Weekday) (int, int) {
+	year := day.Year()
+	start := week1Start(year, weekStart)
+	if day.Before(start) {
+		year--
+		start = week1Start(year, weekStart)
+	} else if next := week1Start(year+1, weekStart); !day.Before(next) {
+		year++
+		start = next
+	}
+	weeks := int(week1Start(year+1, weekStart).Sub(start).Hours()) / (7 * 24)
+	return int(day.Sub(start).Hours())/(7*24) + 1, weeks
+}
+
+// matchesOrdinal returns true if value is one of ordinals,
+// negative ordinals being counted from max.
+func matchesOrdinal(ordinals []int, value, max int) bool {
+	for _, o := range ordinals {
+		if o == value || o < 0 && max+o+1 == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt returns true if values contains value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// daysIn returns the number of days of the given year
+func daysIn(year int) int {
+	return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+// sortUnique sorts the given times and removes duplicates
+func sortUnique(times []time.Time) []time.Time {
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	res := times[:0]
+	for _, t := range times {
+		if len(res) > 0 && t.Equal(res[len(res)-1]) {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
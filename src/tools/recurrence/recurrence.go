@@ -0,0 +1,416 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package recurrence implements the recurrence rules (RRULE) of RFC 5545.
+//
+// A Rule is parsed from its RFC 5545 representation, and the start of its
+// occurrences are computed from its DTStart:
+//
+//	rule, err := recurrence.Parse("DTSTART;TZID=Europe/Paris:20190315T100000\nRRULE:FREQ=WEEKLY;BYDAY=MO,FR")
+//	next := rule.After(time.Now(), false)
+//
+// Occurrences are computed in the location of DTStart, so that daily and
+// less frequent occurrences keep the same local time across DST transitions.
+// As specified by RFC 5545, local times that do not exist because of a
+// transition are shifted by the length of the gap, and ambiguous local times
+// are resolved to their first occurrence. Hourly, minutely and secondly
+// occurrences are separated by an absolute duration.
+//
+// As for other implementations, a DTStart that does not match the rule
+// is not an occurrence.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Frequency of a recurrence rule
+type Frequency int
+
+// Frequencies of recurrence rules
+const (
+	Yearly Frequency = iota
+	Monthly
+	Weekly
+	Daily
+	Hourly
+	Minutely
+	Secondly
+)
+
+// frequencyNames are the RFC 5545 names of the frequencies
+var frequencyNames = []string{"YEARLY", "MONTHLY", "WEEKLY", "DAILY", "HOURLY", "MINUTELY", "SECONDLY"}
+
+// String returns the RFC 5545 name of the frequency
+func (f Frequency) String() string {
+	if f < Yearly || f > Secondly {
+		return fmt.Sprintf("Frequency(%d)", int(f))
+	}
+	return frequencyNames[f]
+}
+
+// weekdayNames are the RFC 5545 names of the weekdays
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// A Weekday of the BYDAY part of a rule, with an optional ordinal N.
+//
+// With a monthly or yearly rule, a non zero N selects the Nth weekday of the
+// month or year (e.g. 2nd monday), or the Nth from the end if N is negative.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+// String returns the RFC 5545 representation of the weekday (e.g. -1FR)
+func (w Weekday) String() string {
+	if w.N == 0 {
+		return weekdayNames[w.Day]
+	}
+	return strconv.Itoa(w.N) + weekdayNames[w.Day]
+}
+
+// A Rule is a recurrence rule.
+//
+// Zero values of the fields are those of a rule without the
+// corresponding part, except Interval for which 0 means 1.
+type Rule struct {
+	Freq Frequency
+	// DTStart is the start of the recurrence. Its location
+	// is the timezone in which occurrences are computed.
+	DTStart    time.Time
+	Interval   int
+	Count      int
+	Until      time.Time
+	WeekStart  time.Weekday
+	BySecond   []int
+	ByMinute   []int
+	ByHour     []int
+	ByDay      []Weekday
+	ByMonthDay []int
+	ByYearDay  []int
+	ByWeekNo   []int
+	ByMonth    []int
+	BySetPos   []int
+	// floatingUntil is true if Until has no timezone and must be
+	// interpreted as a local time in the location of DTStart.
+	floatingUntil bool
+}
+
+// Parse parses a recurrence in RFC 5545 format, that is an optional DTSTART
+// line and a RRULE line. DTSTART may be given with a TZID parameter, in which
+// case it is interpreted in the location with that name. Without DTSTART, the
+// DTStart of the returned rule must be set before computing occurrences.
+func Parse(text string) (*Rule, error) {
+	var (
+		dtStart time.Time
+		rule    *Rule
+		err     error
+	)
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		sep := strings.IndexAny(line, ":;")
+		if sep < 0 {
+			return nil, fmt.Errorf("invalid recurrence line %q", line)
+		}
+		switch strings.ToUpper(line[:sep]) {
+		case "DTSTART":
+			dtStart, err = parseDTStart(line[sep:])
+		case "RRULE":
+			rule, err = ParseRule(line[sep+1:])
+		default:
+			err = fmt.Errorf("unsupported recurrence property %q", line[:sep])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if rule == nil {
+		return nil, errors.New("recurrence without RRULE")
+	}
+	rule.DTStart = dtStart
+	return rule, nil
+}
+
+// parseDTStart parses the value of a DTSTART property, with its parameters
+func parseDTStart(value string) (time.Time, error) {
+	colon := strings.LastIndex(value, ":")
+	if colon < 0 {
+		return time.Time{}, fmt.Errorf("invalid DTSTART %q", value)
+	}
+	loc := time.UTC
+	for _, param := range strings.Split(value[:colon], ";") {
+		tokens := strings.SplitN(param, "=", 2)
+		if len(tokens) != 2 || strings.ToUpper(tokens[0]) != "TZID" {
+			continue
+		}
+		var err error
+		loc, err = time.LoadLocation(tokens[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown timezone %q in DTSTART", tokens[1])
+		}
+	}
+	t, floating, err := parseTime(value[colon+1:], loc)
+	if err == nil && !floating && loc != time.UTC {
+		err = fmt.Errorf("UTC DTSTART %q cannot have a TZID", value)
+	}
+	return t, err
+}
+
+// parseTime parses the given RFC 5545 date or date-time in loc.
+// Date-times ending with Z are UTC times, in which case floating is false.
+func parseTime(value string, loc *time.Location) (t time.Time, floating bool, err error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	case len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, loc)
+		floating = true
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+		floating = true
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	return t, floating, nil
+}
+
+// ParseRule parses the value of a RRULE property (e.g. FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10).
+// The DTStart of the returned rule must be set before computing occurrences.
+func ParseRule(value string) (*Rule, error) {
+	r := Rule{Freq: -1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "RRULE:"), ";") {
+		if part == "" {
+			continue
+		}
+		tokens := strings.SplitN(part, "=", 2)
+		if len(tokens) != 2 || tokens[1] == "" {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		name, val := strings.ToUpper(tokens[0]), strings.ToUpper(tokens[1])
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate recurrence rule part %s", name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			err = fmt.Errorf("unknown frequency")
+			for i, fn := range frequencyNames {
+				if fn == val {
+					r.Freq, err = Frequency(i), nil
+				}
+			}
+		case "INTERVAL":
+			r.Interval, err = parsePositive(val)
+		case "COUNT":
+			r.Count, err = parsePositive(val)
+		case "UNTIL":
+			r.Until, r.floatingUntil, err = parseTime(val, time.UTC)
+		case "WKST":
+			var wd Weekday
+			wd, err = parseWeekday(val)
+			r.WeekStart = wd.Day
+			if err == nil && wd.N != 0 {
+				err = fmt.Errorf("ordinal not allowed")
+			}
+		case "BYSECOND":
+			r.BySecond, err = parseInts(val, 0, 59, false)
+		case "BYMINUTE":
+			r.ByMinute, err = parseInts(val, 0, 59, false)
+		case "BYHOUR":
+			r.ByHour, err = parseInts(val, 0, 23, false)
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				var wd Weekday
+				if wd, err = parseWeekday(day); err != nil {
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(val, 1, 31, true)
+		case "BYYEARDAY":
+			r.ByYearDay, err = parseInts(val, 1, 366, true)
+		case "BYWEEKNO":
+			r.ByWeekNo, err = parseInts(val, 1, 53, true)
+		case "BYMONTH":
+			r.ByMonth, err = parseInts(val, 1, 12, false)
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(val, 1, 366, true)
+		default:
+			err = fmt.Errorf("unknown part")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recurrence rule part %q: %s", part, err)
+		}
+	}
+	if r.Freq < 0 {
+		return nil, fmt.Errorf("recurrence rule %q has no frequency", value)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// parsePositive parses the given strictly positive integer
+func parsePositive(value string) (int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("%q is not a positive integer", value)
+	}
+	return i, nil
+}
+
+// parseInts parses the given list of integers separated by commas, whose
+// absolute value must be between min and max. Negative values are only
+// allowed if signed is true.
+func parseInts(value string, min, max int, signed bool) ([]int, error) {
+	var res []int
+	for _, v := range strings.Split(value, ",") {
+		i, err := strconv.Atoi(v)
+		abs := i
+		if i < 0 && signed {
+			abs = -i
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, fmt.Errorf("invalid value %q", v)
+		}
+		res = append(res, i)
+	}
+	return res, nil
+}
+
+// parseWeekday parses the given weekday, with an optional ordinal (e.g. -1FR)
+func parseWeekday(value string) (Weekday, error) {
+	if len(value) < 2 {
+		return Weekday{}, fmt.Errorf("invalid weekday %q", value)
+	}
+	var res Weekday
+	day := value[len(value)-2:]
+	for i, name := range weekdayNames {
+		if name == day {
+			res.Day = time.Weekday(i)
+			break
+		}
+		if i == len(weekdayNames)-1 {
+			return Weekday{}, fmt.Errorf("invalid weekday %q", value)
+		}
+	}
+	if len(value) > 2 {
+		n, err := strconv.Atoi(value[:len(value)-2])
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return Weekday{}, fmt.Errorf("invalid weekday %q", value)
+		}
+		res.N = n
+	}
+	return res, nil
+}
+
+// Validate checks that the parts of the rule can be used together
+func (r *Rule) Validate() error {
+	switch {
+	case r.Freq < Yearly || r.Freq > Secondly:
+		return fmt.Errorf("unknown frequency %d", r.Freq)
+	case r.Interval < 0 || r.Count < 0:
+		return errors.New("INTERVAL and COUNT cannot be negative")
+	case r.Count > 0 && !r.Until.IsZero():
+		return errors.New("COUNT and UNTIL cannot be used together")
+	case len(r.ByWeekNo) > 0 && r.Freq != Yearly:
+		return errors.New("BYWEEKNO can only be used with a yearly frequency")
+	case len(r.ByYearDay) > 0 && (r.Freq == Monthly || r.Freq == Weekly || r.Freq == Daily):
+		return fmt.Errorf("BYYEARDAY cannot be used with a %s frequency", r.Freq)
+	case len(r.ByMonthDay) > 0 && r.Freq == Weekly:
+		return errors.New("BYMONTHDAY cannot be used with a weekly frequency")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N == 0 {
+			continue
+		}
+		if r.Freq != Monthly && r.Freq != Yearly || r.Freq == Yearly && len(r.ByWeekNo) > 0 {
+			return fmt.Errorf("BYDAY ordinals cannot be used with a %s frequency or with BYWEEKNO", r.Freq)
+		}
+	}
+	if len(r.BySetPos) > 0 && len(r.BySecond)+len(r.ByMinute)+len(r.ByHour)+len(r.ByDay)+len(r.ByMonthDay)+
+		len(r.ByYearDay)+len(r.ByWeekNo)+len(r.ByMonth) == 0 {
+		return errors.New("BYSETPOS must be used with another BYxxx part")
+	}
+	return nil
+}
+
+// String returns the value of the RRULE property of the rule
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	switch {
+	case r.Until.IsZero():
+	case r.floatingUntil && r.Until.Hour() == 0 && r.Until.Minute() == 0 && r.Until.Second() == 0:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	case r.floatingUntil:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+	default:
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	for _, p := range []struct {
+		name   string
+		values []int
+	}{
+		{"BYMONTH", r.ByMonth}, {"BYWEEKNO", r.ByWeekNo}, {"BYYEARDAY", r.ByYearDay}, {"BYMONTHDAY", r.ByMonthDay},
+	} {
+		if len(p.values) > 0 {
+			parts = append(parts, p.name+"="+joinInts(p.values))
+		}
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	for _, p := range []struct {
+		name   string
+		values []int
+	}{
+		{"BYHOUR", r.ByHour}, {"BYMINUTE", r.ByMinute}, {"BYSECOND", r.BySecond}, {"BYSETPOS", r.BySetPos},
+	} {
+		if len(p.values) > 0 {
+			parts = append(parts, p.name+"="+joinInts(p.values))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// joinInts returns the given integers separated by commas
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ",")
+}
+
+// sortedInts returns a sorted copy of values
+func sortedInts(values []int) []int {
+	res := append([]int(nil), values...)
+	sort.Ints(res)
+	return res
+}
@@ -0,0 +1,256 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// occurrences returns the n first occurrences of the given recurrence, formatted
+func occurrences(text string, n int, layout string) []string {
+	rule, err := Parse(text)
+	So(err, ShouldBeNil)
+	res := make([]string, 0)
+	it := rule.Iterator()
+	for t, ok := it.Next(); ok && len(res) < n; t, ok = it.Next() {
+		res = append(res, t.Format(layout))
+	}
+	return res
+}
+
+// rfc returns the n first occurrences of the given rule starting
+// at the given date, as in the examples of RFC 5545.
+func rfc(dtStart, rule string, n int) []string {
+	return occurrences("DTSTART;TZID=America/New_York:"+dtStart+"\nRRULE:"+rule, n, "2006-01-02")
+}
+
+func TestParse(t *testing.T) {
+	Convey("Testing recurrence rules parsing", t, func() {
+		Convey("Rules should be formatted back to RFC 5545", func() {
+			for _, value := range []string{
+				"FREQ=DAILY",
+				"FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR",
+				"FREQ=MONTHLY;COUNT=10;BYDAY=1FR",
+				"FREQ=YEARLY;BYMONTH=1;BYDAY=SU,-1MO",
+				"FREQ=MONTHLY;UNTIL=20190501;BYMONTHDAY=-3",
+				"FREQ=DAILY;BYHOUR=9,10;BYMINUTE=0,20,40;BYSETPOS=-1",
+			} {
+				rule, err := ParseRule(value)
+				So(err, ShouldBeNil)
+				So(rule.String(), ShouldEqual, value)
+			}
+		})
+		Convey("DTSTART should be parsed with its timezone", func() {
+			rule, err := Parse("DTSTART;TZID=Europe/Paris:20190315T100000\r\nRRULE:FREQ=DAILY")
+			So(err, ShouldBeNil)
+			So(rule.DTStart.Location().String(), ShouldEqual, "Europe/Paris")
+			So(rule.DTStart.UTC().Format(time.RFC3339), ShouldEqual, "2019-03-15T09:00:00Z")
+			rule, err = Parse("DTSTART:20190315T100000Z\nRRULE:FREQ=DAILY")
+			So(err, ShouldBeNil)
+			So(rule.DTStart.Location(), ShouldEqual, time.UTC)
+		})
+		Convey("Invalid rules should be rejected", func() {
+			for _, value := range []string{
+				"", "INTERVAL=2", "FREQ=FORTNIGHTLY", "FREQ=DAILY;COUNT=0", "FREQ=DAILY;INTERVAL=-1",
+				"FREQ=DAILY;FREQ=WEEKLY", "FREQ=DAILY;COUNT=2;UNTIL=20190501", "FREQ=DAILY;UNTIL=tomorrow",
+				"FREQ=WEEKLY;BYDAY=XX", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=MONTHLY;BYDAY=0MO", "FREQ=YEARLY;BYWEEKNO=1;BYDAY=1MO",
+				"FREQ=DAILY;BYHOUR=24", "FREQ=MONTHLY;BYMONTHDAY=32", "FREQ=MONTHLY;BYMONTH=0", "FREQ=WEEKLY;BYMONTHDAY=1",
+				"FREQ=MONTHLY;BYYEARDAY=1", "FREQ=MONTHLY;BYWEEKNO=1", "FREQ=DAILY;BYSETPOS=1", "FREQ=DAILY;FOO=1",
+			} {
+				_, err := ParseRule(value)
+				So(err, ShouldNotBeNil)
+			}
+			for _, text := range []string{
+				"DTSTART:20190315T100000", "DTSTART;TZID=Mars/Olympus:20190315T100000\nRRULE:FREQ=DAILY",
+				"DTSTART;TZID=Europe/Paris:20190315T100000Z\nRRULE:FREQ=DAILY", "EXDATE:20190315T100000\nRRULE:FREQ=DAILY",
+			} {
+				_, err := Parse(text)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestRFCExamples(t *testing.T) {
+	Convey("Testing the examples of RFC 5545", t, func() {
+		Convey("Daily rules", func() {
+			So(rfc("19970902T090000", "FREQ=DAILY;COUNT=10", 100), ShouldResemble, []string{
+				"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05", "1997-09-06",
+				"1997-09-07", "1997-09-08", "1997-09-09", "1997-09-10", "1997-09-11"})
+			So(rfc("19970902T090000", "FREQ=DAILY;UNTIL=19971224T000000Z", 200), ShouldHaveLength, 113)
+			So(rfc("19970902T090000", "FREQ=DAILY;INTERVAL=10;COUNT=5", 100), ShouldResemble, []string{
+				"1997-09-02", "1997-09-12", "1997-09-22", "1997-10-02", "1997-10-12"})
+			So(rfc("19980101T090000", "FREQ=DAILY;UNTIL=20000131T140000Z;BYMONTH=1", 100), ShouldHaveLength, 93)
+		})
+		Convey("Weekly rules", func() {
+			So(rfc("19970902T090000", "FREQ=WEEKLY;COUNT=3", 100), ShouldResemble, []string{
+				"1997-09-02", "1997-09-09", "1997-09-16"})
+			occ := rfc("19970901T090000", "FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR", 100)
+			So(occ, ShouldHaveLength, 25)
+			So(occ[:6], ShouldResemble, []string{"1997-09-01", "1997-09-03", "1997-09-05", "1997-09-15", "1997-09-17", "1997-09-19"})
+			So(occ[24], ShouldEqual, "1997-12-22")
+		})
+		Convey("The week start should change the weeks of the rule", func() {
+			So(rfc("19970805T090000", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", 100), ShouldResemble, []string{
+				"1997-08-05", "1997-08-10", "1997-08-19", "1997-08-24"})
+			So(rfc("19970805T090000", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", 100), ShouldResemble, []string{
+				"1997-08-05", "1997-08-17", "1997-08-19", "1997-08-31"})
+		})
+		Convey("Monthly rules", func() {
+			So(rfc("19970905T090000", "FREQ=MONTHLY;COUNT=10;BYDAY=1FR", 100), ShouldResemble, []string{
+				"1997-09-05", "1997-10-03", "1997-11-07", "1997-12-05", "1998-01-02",
+				"1998-02-06", "1998-03-06", "1998-04-03", "1998-05-01", "1998-06-05"})
+			So(rfc("19970907T090000", "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU", 100), ShouldResemble, []string{
+				"1997-09-07", "1997-09-28", "1997-11-02", "1997-11-30", "1998-01-04",
+				"1998-01-25", "1998-03-01", "1998-03-29", "1998-05-03", "1998-05-31"})
+			So(rfc("19970922T090000", "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", 100), ShouldResemble, []string{
+				"1997-09-22", "1997-10-20", "1997-11-17", "1997-12-22", "1998-01-19", "1998-02-16"})
+			So(rfc("19970928T090000", "FREQ=MONTHLY;BYMONTHDAY=-3", 6), ShouldResemble, []string{
+				"1997-09-28", "1997-10-29", "1997-11-28", "1997-12-29", "1998-01-29", "1998-02-26"})
+			So(rfc("19970902T090000", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1", 100), ShouldResemble, []string{
+				"1997-09-30", "1997-10-01", "1997-10-31", "1997-11-01", "1997-11-30",
+				"1997-12-01", "1997-12-31", "1998-01-01", "1998-01-31", "1998-02-01"})
+			So(rfc("19970902T090000", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", 5), ShouldResemble, []string{
+				"1998-02-13", "1998-03-13", "1998-11-13", "1999-08-13", "2000-10-13"})
+			So(rfc("19970913T090000", "FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13", 4), ShouldResemble, []string{
+				"1997-09-13", "1997-10-11", "1997-11-08", "1997-12-13"})
+		})
+		Convey("Months without the day of the rule should be skipped", func() {
+			So(rfc("20070115T090000", "FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5", 100), ShouldResemble, []string{
+				"2007-01-15", "2007-01-30", "2007-02-15", "2007-03-15", "2007-03-30"})
+			So(rfc("20070131T090000", "FREQ=MONTHLY;COUNT=3", 100), ShouldResemble, []string{
+				"2007-01-31", "2007-03-31", "2007-05-31"})
+		})
+		Convey("Yearly rules", func() {
+			So(rfc("19970610T090000", "FREQ=YEARLY;COUNT=10;BYMONTH=6,7", 100), ShouldResemble, []string{
+				"1997-06-10", "1997-07-10", "1998-06-10", "1998-07-10", "1999-06-10",
+				"1999-07-10", "2000-06-10", "2000-07-10", "2001-06-10", "2001-07-10"})
+			So(rfc("19970101T090000", "FREQ=YEARLY;INTERVAL=3;COUNT=10;BYYEARDAY=1,100,200", 100), ShouldResemble, []string{
+				"1997-01-01", "1997-04-10", "1997-07-19", "2000-01-01", "2000-04-09",
+				"2000-07-18", "2003-01-01", "2003-04-10", "2003-07-19", "2006-01-01"})
+			So(rfc("19970519T090000", "FREQ=YEARLY;BYDAY=20MO", 3), ShouldResemble, []string{
+				"1997-05-19", "1998-05-18", "1999-05-17"})
+			So(rfc("19970512T090000", "FREQ=YEARLY;BYWEEKNO=20;BYDAY=MO", 3), ShouldResemble, []string{
+				"1997-05-12", "1998-05-11", "1999-05-17"})
+			So(rfc("19970313T090000", "FREQ=YEARLY;BYMONTH=3;BYDAY=TH", 3), ShouldResemble, []string{
+				"1997-03-13", "1997-03-20", "1997-03-27"})
+			So(rfc("19961105T090000", "FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8", 3), ShouldResemble, []string{
+				"1996-11-05", "2000-11-07", "2004-11-02"})
+			So(rfc("20160229T090000", "FREQ=YEARLY;COUNT=2", 100), ShouldResemble, []string{"2016-02-29", "2020-02-29"})
+		})
+		Convey("Week numbers should follow ISO 8601 at year boundaries", func() {
+			So(rfc("20140101T090000", "FREQ=YEARLY;BYWEEKNO=1;BYDAY=MO", 3), ShouldResemble, []string{
+				"2014-12-29", "2016-01-04", "2017-01-02"})
+			So(rfc("20150101T090000", "FREQ=YEARLY;BYWEEKNO=-1;BYDAY=SU", 3), ShouldResemble, []string{
+				"2016-01-03", "2017-01-01", "2017-12-31"})
+			So(rfc("20150101T090000", "FREQ=YEARLY;BYWEEKNO=53;BYDAY=MO", 2), ShouldResemble, []string{
+				"2015-12-28", "2020-12-28"})
+		})
+		Convey("Set positions should select occurrences of each period", func() {
+			So(rfc("19970904T090000", "FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3", 100), ShouldResemble, []string{
+				"1997-09-04", "1997-10-07", "1997-11-06"})
+			So(rfc("19970929T090000", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2", 7), ShouldResemble, []string{
+				"1997-09-29", "1997-10-30", "1997-11-27", "1997-12-30", "1998-01-29", "1998-02-26", "1998-03-30"})
+		})
+		Convey("Times of the day", func() {
+			layout := "2006-01-02 15:04"
+			So(occurrences("DTSTART;TZID=America/New_York:19970902T090000\nRRULE:FREQ=HOURLY;INTERVAL=3;UNTIL=19970902T210000Z",
+				100, layout), ShouldResemble, []string{"1997-09-02 09:00", "1997-09-02 12:00", "1997-09-02 15:00"})
+			So(occurrences("DTSTART;TZID=America/New_York:19970902T090000\nRRULE:FREQ=MINUTELY;INTERVAL=15;COUNT=6",
+				100, layout), ShouldResemble, []string{"1997-09-02 09:00", "1997-09-02 09:15", "1997-09-02 09:30",
+				"1997-09-02 09:45", "1997-09-02 10:00", "1997-09-02 10:15"})
+			daily := occurrences("DTSTART;TZID=America/New_York:19970902T090000\nRRULE:FREQ=DAILY;BYHOUR=9,10,11,12,13,14,15,16;BYMINUTE=0,20,40",
+				50, layout)
+			minutely := occurrences("DTSTART;TZID=America/New_York:19970902T090000\nRRULE:FREQ=MINUTELY;INTERVAL=20;BYHOUR=9,10,11,12,13,14,15,16",
+				50, layout)
+			So(daily[:4], ShouldResemble, []string{"1997-09-02 09:00", "1997-09-02 09:20", "1997-09-02 09:40", "1997-09-02 10:00"})
+			So(daily[23:25], ShouldResemble, []string{"1997-09-02 16:40", "1997-09-03 09:00"})
+			So(minutely, ShouldResemble, daily)
+			So(occurrences("DTSTART;TZID=America/New_York:19970902T090000\nRRULE:FREQ=SECONDLY;INTERVAL=30;BYMONTHDAY=3;COUNT=2",
+				100, "2006-01-02 15:04:05"), ShouldResemble, []string{"1997-09-03 00:00:00", "1997-09-03 00:00:30"})
+		})
+	})
+}
+
+func TestDST(t *testing.T) {
+	Convey("Testing recurrences across DST transitions", t, func() {
+		layout := "2006-01-02 15:04 MST"
+		Convey("Daily occurrences should keep their local time", func() {
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190330T100000\nRRULE:FREQ=DAILY;COUNT=2", 100, time.RFC3339), ShouldResemble,
+				[]string{"2019-03-30T10:00:00+01:00", "2019-03-31T10:00:00+02:00"})
+			So(occurrences("DTSTART;TZID=Europe/Paris:20191026T100000\nRRULE:FREQ=DAILY;COUNT=2", 100, time.RFC3339), ShouldResemble,
+				[]string{"2019-10-26T10:00:00+02:00", "2019-10-27T10:00:00+01:00"})
+			So(occurrences("DTSTART;TZID=America/New_York:20191027T090000\nRRULE:FREQ=WEEKLY;COUNT=3", 100, layout), ShouldResemble,
+				[]string{"2019-10-27 09:00 EDT", "2019-11-03 09:00 EST", "2019-11-10 09:00 EST"})
+		})
+		Convey("Local times in a gap should be shifted by the length of the gap", func() {
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190330T023000\nRRULE:FREQ=DAILY;COUNT=3", 100, layout), ShouldResemble,
+				[]string{"2019-03-30 02:30 CET", "2019-03-31 03:30 CEST", "2019-04-01 02:30 CEST"})
+			So(occurrences("DTSTART;TZID=America/New_York:20190210T023000\nRRULE:FREQ=MONTHLY;BYDAY=2SU;COUNT=3", 100, layout), ShouldResemble,
+				[]string{"2019-02-10 02:30 EST", "2019-03-10 03:30 EDT", "2019-04-14 02:30 EDT"})
+		})
+		Convey("Ambiguous local times should be resolved to their first occurrence", func() {
+			occ := occurrences("DTSTART;TZID=Europe/Paris:20191026T023000\nRRULE:FREQ=DAILY;COUNT=3", 100, time.RFC3339)
+			So(occ, ShouldResemble, []string{"2019-10-26T02:30:00+02:00", "2019-10-27T02:30:00+02:00", "2019-10-28T02:30:00+01:00"})
+			So(occurrences("DTSTART;TZID=Australia/Sydney:20190406T023000\nRRULE:FREQ=DAILY;COUNT=2", 100, time.RFC3339), ShouldResemble,
+				[]string{"2019-04-06T02:30:00+11:00", "2019-04-07T02:30:00+11:00"})
+		})
+		Convey("Gaps should not duplicate occurrences", func() {
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190331T020000\nRRULE:FREQ=DAILY;BYHOUR=2,3;BYMINUTE=0;COUNT=3", 100, layout), ShouldResemble,
+				[]string{"2019-03-31 03:00 CEST", "2019-04-01 02:00 CEST", "2019-04-01 03:00 CEST"})
+		})
+		Convey("Hourly occurrences should be separated by an hour", func() {
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190331T000000\nRRULE:FREQ=HOURLY;COUNT=4", 100, layout), ShouldResemble,
+				[]string{"2019-03-31 00:00 CET", "2019-03-31 01:00 CET", "2019-03-31 03:00 CEST", "2019-03-31 04:00 CEST"})
+			So(occurrences("DTSTART;TZID=Europe/Paris:20191027T000000\nRRULE:FREQ=HOURLY;COUNT=5", 100, layout), ShouldResemble,
+				[]string{"2019-10-27 00:00 CEST", "2019-10-27 01:00 CEST", "2019-10-27 02:00 CEST", "2019-10-27 02:00 CET", "2019-10-27 03:00 CET"})
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190330T020000\nRRULE:FREQ=HOURLY;INTERVAL=24;COUNT=2", 100, layout), ShouldResemble,
+				[]string{"2019-03-30 02:00 CET", "2019-03-31 03:00 CEST"})
+		})
+		Convey("Hourly occurrences should be limited by local hours", func() {
+			So(occurrences("DTSTART;TZID=Europe/Paris:20191026T020000\nRRULE:FREQ=HOURLY;BYHOUR=2;COUNT=4", 100, layout), ShouldResemble,
+				[]string{"2019-10-26 02:00 CEST", "2019-10-27 02:00 CEST", "2019-10-27 02:00 CET", "2019-10-28 02:00 CET"})
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190330T020000\nRRULE:FREQ=HOURLY;BYHOUR=2;COUNT=2", 100, layout), ShouldResemble,
+				[]string{"2019-03-30 02:00 CET", "2019-04-01 02:00 CEST"})
+		})
+		Convey("Floating UNTIL should be a local time", func() {
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190329T100000\nRRULE:FREQ=DAILY;UNTIL=20190331T100000", 100, layout), ShouldHaveLength, 3)
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190329T100000\nRRULE:FREQ=DAILY;UNTIL=20190331T080000Z", 100, layout), ShouldHaveLength, 3)
+			So(occurrences("DTSTART;TZID=Europe/Paris:20190329T100000\nRRULE:FREQ=DAILY;UNTIL=20190331T075959Z", 100, layout), ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestSearch(t *testing.T) {
+	Convey("Testing occurrence search", t, func() {
+		paris, err := time.LoadLocation("Europe/Paris")
+		So(err, ShouldBeNil)
+		rule, err := ParseRule("FREQ=WEEKLY;BYDAY=MO,FR")
+		So(err, ShouldBeNil)
+		rule.DTStart = time.Date(2019, 3, 15, 10, 0, 0, 0, paris)
+		Convey("Between should return the occurrences of a period", func() {
+			from, to := time.Date(2019, 3, 18, 10, 0, 0, 0, paris), time.Date(2019, 3, 25, 10, 0, 0, 0, paris)
+			So(rule.Between(from, to, false), ShouldHaveLength, 1)
+			So(rule.Between(from, to, true), ShouldHaveLength, 3)
+		})
+		Convey("After and Next should return the next occurrence", func() {
+			So(rule.After(rule.DTStart, true).Equal(rule.DTStart), ShouldBeTrue)
+			So(rule.Next(rule.DTStart).Equal(time.Date(2019, 3, 18, 10, 0, 0, 0, paris)), ShouldBeTrue)
+			So(rule.Next(time.Date(2019, 3, 31, 12, 0, 0, 0, time.UTC)).Format(time.RFC3339), ShouldEqual, "2019-04-01T10:00:00+02:00")
+		})
+		Convey("Rules without occurrences should end", func() {
+			rule, err = ParseRule("FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30")
+			So(err, ShouldBeNil)
+			rule.DTStart = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+			So(rule.Next(rule.DTStart).IsZero(), ShouldBeTrue)
+			rule, err = ParseRule("FREQ=MINUTELY;BYMONTH=2;BYMONTHDAY=30")
+			So(err, ShouldBeNil)
+			rule.DTStart = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+			So(rule.Next(rule.DTStart).IsZero(), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package startup runs the independent steps of the server startup
+// concurrently to reduce the start time of large installations.
+package startup
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// A Stage is a step of the startup
+type Stage struct {
+	// Name identifies the stage in the dependencies of other stages
+	Name string
+	// Depends are the names of the stages that must
+	// be finished before this stage starts
+	Depends []string
+	// Run is the function executing the stage
+	Run func()
+}
+
+// Run runs the given stages, each in its own goroutine as soon as all the
+// stages it depends on are finished. It returns when all stages are finished.
+//
+// It panics if a dependency is unknown or circular, or if a stage panics. In
+// the latter case, the stages that are not started yet are skipped and the
+// panic is raised again in the calling goroutine once running stages finish.
+func Run(stages ...Stage) {
+	checkStages(stages)
+	done := make(map[string]chan struct{}, len(stages))
+	for _, stage := range stages {
+		done[stage.Name] = make(chan struct{})
+	}
+	var (
+		wg         sync.WaitGroup
+		failure    panicHolder
+		failedCh   = make(chan struct{})
+		failedOnce sync.Once
+	)
+	for _, stage := range stages {
+		wg.Add(1)
+		go func(stage Stage) {
+			defer wg.Done()
+			defer close(done[stage.Name])
+			for _, dep := range stage.Depends {
+				select {
+				case <-done[dep]:
+				case <-failedCh:
+				}
+			}
+			if failure.failed() {
+				return
+			}
+			start := time.Now()
+			if failure.run(stage.Run) {
+				failedOnce.Do(func() { close(failedCh) })
+				return
+			}
+			log.Debug("Startup stage done", "stage", stage.Name, "duration", time.Since(start))
+		}(stage)
+	}
+	wg.Wait()
+	failure.repanic()
+}
+
+// checkStages panics if stages have duplicate names
+// or unknown or circular dependencies.
+func checkStages(stages []Stage) {
+	byName := make(map[string]Stage, len(stages))
+	for _, stage := range stages {
+		if _, exists := byName[stage.Name]; exists {
+			log.Panic("Duplicate startup stage", "stage", stage.Name)
+		}
+		byName[stage.Name] = stage
+	}
+	// 0: not visited, 1: being visited, 2: visited
+	state := make(map[string]int)
+	var visit func(name string, path []string)
+	visit = func(name string, path []string) {
+		switch state[name] {
+		case 1:
+			log.Panic("Circular startup stage dependencies", "stages", strings.Join(append(path, name), " -> "))
+		case 2:
+			return
+		}
+		state[name] = 1
+		for _, dep := range byName[name].Depends {
+			if _, ok := byName[dep]; !ok {
+				log.Panic("Unknown startup stage dependency", "stage", name, "dependency", dep)
+			}
+			visit(dep, append(path, name))
+		}
+		state[name] = 2
+	}
+	for _, stage := range stages {
+		visit(stage.Name, nil)
+	}
+}
+
+// Each calls fnct for each integer from 0 to n-1 with at most GOMAXPROCS
+// concurrent goroutines and returns when all calls are finished.
+//
+// If a call panics, the remaining calls are skipped and the panic is
+// raised again in the calling goroutine.
+func Each(n int, fnct func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	var (
+		wg      sync.WaitGroup
+		failure panicHolder
+	)
+	indexes := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if failure.failed() {
+					continue
+				}
+				failure.run(func() { fnct(i) })
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	failure.repanic()
+}
+
+// A panicHolder keeps the first panic of concurrent functions
+type panicHolder struct {
+	sync.Mutex
+	value interface{}
+	set   bool
+}
+
+// run calls fnct and returns true if it panicked,
+// keeping the panic value if it is the first one.
+func (ph *panicHolder) run(fnct func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ph.Lock()
+			defer ph.Unlock()
+			if !ph.set {
+				ph.value, ph.set = r, true
+			}
+			panicked = true
+		}
+	}()
+	fnct()
+	return false
+}
+
+// failed returns true if a function has panicked
+func (ph *panicHolder) failed() bool {
+	ph.Lock()
+	defer ph.Unlock()
+	return ph.set
+}
+
+// repanic panics with the kept panic value, if any
+func (ph *panicHolder) repanic() {
+	if ph.failed() {
+		panic(ph.value)
+	}
+}
+
+func init() {
+	log = logging.GetLogger("startup")
+}
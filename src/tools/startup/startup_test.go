@@ -0,0 +1,70 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package startup
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStartup(t *testing.T) {
+	Convey("Testing startup stages", t, func() {
+		Convey("Stages should start after their dependencies", func() {
+			var (
+				mu    sync.Mutex
+				order []string
+			)
+			record := func(name string, delay time.Duration) func() {
+				return func() {
+					time.Sleep(delay)
+					mu.Lock()
+					defer mu.Unlock()
+					order = append(order, name)
+				}
+			}
+			Run(
+				Stage{Name: "views", Depends: []string{"resources", "translations"}, Run: record("views", 0)},
+				Stage{Name: "resources", Run: record("resources", 20*time.Millisecond)},
+				Stage{Name: "translations", Run: record("translations", 0)},
+				Stage{Name: "actions", Depends: []string{"views"}, Run: record("actions", 0)},
+			)
+			So(order, ShouldResemble, []string{"translations", "resources", "views", "actions"})
+		})
+		Convey("Invalid dependencies should panic", func() {
+			So(func() { Run(Stage{Name: "a", Depends: []string{"b"}, Run: func() {}}) }, ShouldPanic)
+			So(func() {
+				Run(Stage{Name: "a", Depends: []string{"b"}, Run: func() {}},
+					Stage{Name: "b", Depends: []string{"a"}, Run: func() {}})
+			}, ShouldPanic)
+			So(func() { Run(Stage{Name: "a", Run: func() {}}, Stage{Name: "a", Run: func() {}}) }, ShouldPanic)
+		})
+		Convey("A panicking stage should skip its dependents and panic", func() {
+			var dependentRun bool
+			So(func() {
+				Run(Stage{Name: "a", Run: func() { panic("stage failed") }},
+					Stage{Name: "b", Depends: []string{"a"}, Run: func() { dependentRun = true }})
+			}, ShouldPanicWith, "stage failed")
+			So(dependentRun, ShouldBeFalse)
+		})
+		Convey("Each should call the function for all indexes", func() {
+			res := make([]int, 100)
+			Each(len(res), func(i int) {
+				res[i] = i * 2
+			})
+			for i, v := range res {
+				So(v, ShouldEqual, i*2)
+			}
+			So(func() {
+				Each(10, func(i int) {
+					if i == 5 {
+						panic("failed")
+					}
+				})
+			}, ShouldPanicWith, "failed")
+		})
+	})
+}
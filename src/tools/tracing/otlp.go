@@ -0,0 +1,164 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// An OTLPExporter exports spans with the OpenTelemetry protocol over HTTP
+// in JSON encoding. This protocol is supported by the OpenTelemetry
+// collector and by Jaeger (on port 4318 with path /v1/traces).
+type OTLPExporter struct {
+	// Endpoint is the URL to which spans are posted,
+	// e.g. http://localhost:4318/v1/traces
+	Endpoint string
+	// ServiceName is the name of this service in traces
+	ServiceName string
+	// Client is the HTTP client used to post spans
+	Client *http.Client
+}
+
+// NewOTLPExporter returns a new OTLPExporter posting spans
+// of the given service to the given endpoint.
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	return &OTLPExporter{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ExportSpans posts the given spans to the endpoint of this exporter
+func (oe *OTLPExporter) ExportSpans(spans []*Span) error {
+	body, err := json.Marshal(oe.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := oe.Client.Post(oe.Endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status from tracing endpoint: %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON messages
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              SpanKind        `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+// OTLP status codes
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// request returns the OTLP request exporting the given spans
+func (oe *OTLPExporter) request(spans []*Span) otlpRequest {
+	res := otlpScopeSpans{
+		Scope: otlpScope{Name: "hexya"},
+		Spans: make([]otlpSpan, len(spans)),
+	}
+	for i, span := range spans {
+		os := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if !span.ParentID.IsZero() {
+			os.ParentSpanID = span.ParentID.String()
+		}
+		if span.Error != "" {
+			os.Status = otlpStatus{Code: otlpStatusError, Message: span.Error}
+		}
+		res.Spans[i] = os
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes(map[string]interface{}{"service.name": oe.ServiceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{res},
+		}},
+	}
+}
+
+// otlpAttributes returns the given attributes as OTLP attributes sorted by key
+func otlpAttributes(attrs map[string]interface{}) []otlpAttribute {
+	res := make([]otlpAttribute, 0, len(attrs))
+	for key, value := range attrs {
+		var val map[string]interface{}
+		switch v := value.(type) {
+		case string:
+			val = map[string]interface{}{"stringValue": v}
+		case bool:
+			val = map[string]interface{}{"boolValue": v}
+		case int:
+			val = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			val = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			val = map[string]interface{}{"doubleValue": v}
+		default:
+			val = map[string]interface{}{"stringValue": fmt.Sprintf("%v", v)}
+		}
+		res = append(res, otlpAttribute{Key: key, Value: val})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+var _ Exporter = new(OTLPExporter)
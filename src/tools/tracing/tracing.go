@@ -0,0 +1,347 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package tracing records the spans of distributed traces, i.e. timed
+// operations such as HTTP requests, method calls or SQL queries, and
+// exports them to a tracing backend such as Jaeger.
+//
+// Tracing is disabled until Enable is called. When it is disabled,
+// StartSpan returns nil and all Span methods are no-ops on nil spans,
+// so that instrumented code does not need to check.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// A SpanKind describes the relationship of a span with its parent
+type SpanKind int
+
+// Available span kinds
+const (
+	KindInternal SpanKind = iota + 1
+	KindServer
+	KindClient
+)
+
+// A TraceID identifies a trace
+type TraceID [16]byte
+
+// String returns the hex representation of this TraceID
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// A SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the hex representation of this SpanID
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsZero returns true if this SpanID is not set
+func (s SpanID) IsZero() bool {
+	return s == SpanID{}
+}
+
+// A Span is a timed operation of a trace.
+//
+// A Span must only be modified by the goroutine that started it.
+type Span struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID
+	Name       string
+	Kind       SpanKind
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	// Error is the error message of a failed operation
+	Error   string
+	sampled bool
+	ended   bool
+}
+
+// StartSpan starts a new span with the given name as a child of parent.
+// If parent is nil, the span starts a new trace.
+//
+// It returns nil if tracing is disabled.
+func StartSpan(parent *Span, name string, kind SpanKind) *Span {
+	p := currentProcessor()
+	if p == nil {
+		return nil
+	}
+	span := &Span{
+		SpanID:    newSpanID(),
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+	}
+	if parent == nil {
+		span.TraceID = newTraceID()
+		span.sampled = p.sample()
+		return span
+	}
+	span.TraceID = parent.TraceID
+	span.ParentID = parent.SpanID
+	span.sampled = parent.sampled
+	return span
+}
+
+// StartRemoteSpan starts a new span with the given name as a child of the
+// span of another service given by its W3C traceparent header value. If
+// traceParent is empty or invalid, the span starts a new trace.
+//
+// It returns nil if tracing is disabled.
+func StartRemoteSpan(traceParent, name string, kind SpanKind) *Span {
+	parent, ok := ParseTraceParent(traceParent)
+	if !ok {
+		return StartSpan(nil, name, kind)
+	}
+	return StartSpan(parent, name, kind)
+}
+
+// ParseTraceParent returns a span holding the ids and the sampling decision
+// of the given W3C traceparent header value. It returns false if the value
+// is not a valid traceparent.
+func ParseTraceParent(value string) (*Span, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return nil, false
+	}
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return nil, false
+	}
+	span := &Span{sampled: flags[0]&1 == 1}
+	copy(span.TraceID[:], traceID)
+	copy(span.SpanID[:], spanID)
+	if span.TraceID == (TraceID{}) || span.SpanID.IsZero() {
+		return nil, false
+	}
+	return span, true
+}
+
+// TraceParent returns the W3C traceparent header value with which this
+// span can be propagated to other services, or an empty string if this
+// span is nil.
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, flags)
+}
+
+// SetAttribute sets the given attribute on this span
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.sampled {
+		return
+	}
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// SetError marks this span as failed with the given message
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+	s.Error = msg
+}
+
+// End ends this span and queues it for export if it is sampled.
+// Calling End several times has no effect.
+func (s *Span) End() {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	if !s.sampled {
+		return
+	}
+	if p := currentProcessor(); p != nil {
+		p.enqueue(s)
+	}
+}
+
+// Duration returns the duration of this span, which must have ended
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// newTraceID returns a new random TraceID
+func newTraceID() TraceID {
+	var res TraceID
+	rand.Read(res[:])
+	return res
+}
+
+// newSpanID returns a new random SpanID
+func newSpanID() SpanID {
+	var res SpanID
+	rand.Read(res[:])
+	return res
+}
+
+// An Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	// ExportSpans exports the given spans. It is called from a
+	// single goroutine.
+	ExportSpans(spans []*Span) error
+}
+
+// Settings of the span processor
+const (
+	queueSize     = 2048
+	batchSize     = 512
+	flushInterval = 5 * time.Second
+)
+
+// A processor samples traces and exports finished spans by batches
+type processor struct {
+	exporter    Exporter
+	sampleRatio float64
+	queue       chan *Span
+	flush       chan chan struct{}
+	done        chan struct{}
+	stopped     chan struct{}
+}
+
+var (
+	currentProc     *processor
+	currentProcLock sync.RWMutex
+)
+
+// currentProcessor returns the current processor or nil if tracing is disabled
+func currentProcessor() *processor {
+	currentProcLock.RLock()
+	defer currentProcLock.RUnlock()
+	return currentProc
+}
+
+// Enable enables tracing. Traces are sampled with the given ratio, between
+// 0 (no trace) and 1 (all traces), and finished spans are sent to the given
+// exporter by batches.
+//
+// The sampling decision of traces propagated from other services is kept.
+func Enable(exporter Exporter, sampleRatio float64) {
+	Disable()
+	p := &processor{
+		exporter:    exporter,
+		sampleRatio: sampleRatio,
+		queue:       make(chan *Span, queueSize),
+		flush:       make(chan chan struct{}),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go p.run()
+	currentProcLock.Lock()
+	defer currentProcLock.Unlock()
+	currentProc = p
+}
+
+// Disable disables tracing after exporting the pending spans
+func Disable() {
+	currentProcLock.Lock()
+	p := currentProc
+	currentProc = nil
+	currentProcLock.Unlock()
+	if p == nil {
+		return
+	}
+	close(p.done)
+	<-p.stopped
+}
+
+// Flush exports all the finished spans that have not been exported yet
+func Flush() {
+	p := currentProcessor()
+	if p == nil {
+		return
+	}
+	flushed := make(chan struct{})
+	select {
+	case p.flush <- flushed:
+		<-flushed
+	case <-p.done:
+	}
+}
+
+// sample returns true if a new trace must be sampled
+func (p *processor) sample() bool {
+	return p.sampleRatio >= 1 || mrand.Float64() < p.sampleRatio
+}
+
+// enqueue queues the given span for export.
+// Spans are dropped if the queue is full.
+func (p *processor) enqueue(span *Span) {
+	select {
+	case p.queue <- span:
+	default:
+	}
+}
+
+// run exports the queued spans by batches until the processor is done
+func (p *processor) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			p.exportQueue()
+			close(p.stopped)
+			return
+		case <-ticker.C:
+			p.exportQueue()
+		case flushed := <-p.flush:
+			p.exportQueue()
+			close(flushed)
+		}
+	}
+}
+
+// exportQueue exports all the spans of the queue
+func (p *processor) exportQueue() {
+	for {
+		batch := make([]*Span, 0, batchSize)
+	loop:
+		for len(batch) < batchSize {
+			select {
+			case span := <-p.queue:
+				batch = append(batch, span)
+			default:
+				break loop
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.ExportSpans(batch); err != nil {
+			log.Warn("Unable to export spans", "count", len(batch), "error", err)
+		}
+	}
+}
+
+func init() {
+	log = logging.GetLogger("tracing")
+}
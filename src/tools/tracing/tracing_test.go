@@ -0,0 +1,108 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type memoryExporter struct {
+	sync.Mutex
+	spans []*Span
+}
+
+func (me *memoryExporter) ExportSpans(spans []*Span) error {
+	me.Lock()
+	defer me.Unlock()
+	me.spans = append(me.spans, spans...)
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	Convey("Testing tracing", t, func() {
+		Convey("Spans should be nil when tracing is disabled", func() {
+			span := StartSpan(nil, "test", KindInternal)
+			So(span, ShouldBeNil)
+			span.SetAttribute("key", "value")
+			span.End()
+			So(span.TraceParent(), ShouldBeEmpty)
+		})
+		Convey("Children spans should belong to the trace of their parent", func() {
+			exporter := new(memoryExporter)
+			Enable(exporter, 1)
+			defer Disable()
+			root := StartSpan(nil, "root", KindServer)
+			child := StartSpan(root, "child", KindInternal)
+			child.SetAttribute("hexya.model", "Partner")
+			child.SetError("failed")
+			child.End()
+			root.End()
+			root.End()
+			Flush()
+			So(exporter.spans, ShouldHaveLength, 2)
+			So(exporter.spans[0].Name, ShouldEqual, "child")
+			So(exporter.spans[0].TraceID, ShouldEqual, root.TraceID)
+			So(exporter.spans[0].ParentID, ShouldEqual, root.SpanID)
+			So(exporter.spans[0].Attributes["hexya.model"], ShouldEqual, "Partner")
+			So(exporter.spans[0].Error, ShouldEqual, "failed")
+			So(exporter.spans[1].ParentID.IsZero(), ShouldBeTrue)
+		})
+		Convey("Traces should not be exported when they are not sampled", func() {
+			exporter := new(memoryExporter)
+			Enable(exporter, 0)
+			root := StartSpan(nil, "root", KindServer)
+			StartSpan(root, "child", KindInternal).End()
+			root.End()
+			Disable()
+			So(exporter.spans, ShouldBeEmpty)
+		})
+		Convey("Trace parent headers should be parsed and generated", func() {
+			header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+			parent, ok := ParseTraceParent(header)
+			So(ok, ShouldBeTrue)
+			So(parent.TraceID.String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(parent.TraceParent(), ShouldEqual, header)
+			_, ok = ParseTraceParent("00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+			So(ok, ShouldBeFalse)
+			_, ok = ParseTraceParent("garbage")
+			So(ok, ShouldBeFalse)
+			exporter := new(memoryExporter)
+			Enable(exporter, 0)
+			span := StartRemoteSpan(header, "remote", KindServer)
+			span.End()
+			Disable()
+			So(exporter.spans, ShouldHaveLength, 1)
+			So(exporter.spans[0].ParentID.String(), ShouldEqual, "00f067aa0ba902b7")
+		})
+		Convey("OTLP exporter should post spans as JSON", func() {
+			var body map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := ioutil.ReadAll(r.Body)
+				json.Unmarshal(data, &body)
+			}))
+			defer srv.Close()
+			Enable(NewOTLPExporter(srv.URL, "test"), 1)
+			span := StartSpan(nil, "Partner.Write", KindInternal)
+			span.SetAttribute("hexya.records", 3)
+			span.End()
+			Disable()
+			resourceSpans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+			spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+			So(spans, ShouldHaveLength, 1)
+			otlpSpan := spans[0].(map[string]interface{})
+			So(otlpSpan["name"], ShouldEqual, "Partner.Write")
+			So(otlpSpan["traceId"], ShouldEqual, span.TraceID.String())
+			So(otlpSpan["attributes"], ShouldResemble, []interface{}{
+				map[string]interface{}{"key": "hexya.records", "value": map[string]interface{}{"intValue": "3"}},
+			})
+		})
+	})
+}
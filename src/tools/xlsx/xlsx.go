@@ -0,0 +1,310 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package xlsx writes Office Open XML spreadsheets (.xlsx files).
+//
+// Rows are streamed to the underlying writer as they are added so that
+// memory usage does not depend on the size of the spreadsheet. Therefore,
+// sheets must be written one after the other and strings are stored inline
+// instead of in a shared strings table.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cell styles defined in the styles part
+const (
+	styleDefault = iota
+	styleBold
+	styleDate
+	styleDateTime
+)
+
+// maxSheetNameLength is the maximum length of sheet names allowed by spreadsheet applications
+const maxSheetNameLength = 31
+
+// excelEpoch is the origin of spreadsheet serial dates
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// ErrClosed is returned when writing to a closed Writer or Sheet
+var ErrClosed = errors.New("xlsx: write to closed writer")
+
+// A Writer writes a spreadsheet into an io.Writer
+type Writer struct {
+	zw     *zip.Writer
+	sheets []string
+	sheet  *Sheet
+	closed bool
+}
+
+// NewWriter returns a new Writer writing a spreadsheet to w.
+// The Close method must be called to finish the spreadsheet.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		zw: zip.NewWriter(w),
+	}
+}
+
+// AddSheet finishes the current sheet if any and starts a new sheet with the given name.
+func (w *Writer) AddSheet(name string) (*Sheet, error) {
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if err := w.finishSheet(); err != nil {
+		return nil, err
+	}
+	name = sanitizeSheetName(name, len(w.sheets)+1)
+	w.sheets = append(w.sheets, name)
+	fw, err := w.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)))
+	if err != nil {
+		return nil, err
+	}
+	w.sheet = &Sheet{w: bufio.NewWriter(fw)}
+	w.sheet.w.WriteString(xml.Header)
+	w.sheet.w.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return w.sheet, nil
+}
+
+// finishSheet writes the end of the current sheet
+func (w *Writer) finishSheet() error {
+	if w.sheet == nil {
+		return nil
+	}
+	w.sheet.w.WriteString(`</sheetData></worksheet>`)
+	err := w.sheet.w.Flush()
+	w.sheet.closed = true
+	w.sheet = nil
+	return err
+}
+
+// Close finishes the spreadsheet. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if len(w.sheets) == 0 {
+		if _, err := w.AddSheet(""); err != nil {
+			return err
+		}
+	}
+	if err := w.finishSheet(); err != nil {
+		return err
+	}
+	w.closed = true
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", w.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", w.workbook()},
+		{"xl/_rels/workbook.xml.rels", w.workbookRels()},
+		{"xl/styles.xml", styles},
+	}
+	for _, part := range parts {
+		fw, err := w.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(fw, xml.Header+part.content); err != nil {
+			return err
+		}
+	}
+	return w.zw.Close()
+}
+
+// contentTypes returns the content of the [Content_Types].xml part
+func (w *Writer) contentTypes() string {
+	var sb strings.Builder
+	sb.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range w.sheets {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	sb.WriteString(`</Types>`)
+	return sb.String()
+}
+
+// workbook returns the content of the workbook part
+func (w *Writer) workbook() string {
+	var sb strings.Builder
+	sb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, name := range w.sheets {
+		fmt.Fprintf(&sb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i+1, i+1)
+	}
+	sb.WriteString(`</sheets></workbook>`)
+	return sb.String()
+}
+
+// workbookRels returns the content of the relationships part of the workbook
+func (w *Writer) workbookRels() string {
+	var sb strings.Builder
+	sb.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range w.sheets {
+		fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.sheets)+1)
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+// A Sheet is a sheet of a spreadsheet being written
+type Sheet struct {
+	w      *bufio.Writer
+	rows   int
+	closed bool
+}
+
+// WriteRow writes a row with the given values.
+//
+// Values can be strings, booleans, integers, floats or time.Time. Times without
+// a time part are formatted as dates. Nil values give empty cells and other
+// values are written with their default string format.
+func (s *Sheet) WriteRow(values ...interface{}) error {
+	return s.writeRow(false, values)
+}
+
+// WriteHeader writes a row of the given titles in bold
+func (s *Sheet) WriteHeader(titles ...string) error {
+	values := make([]interface{}, len(titles))
+	for i, title := range titles {
+		values[i] = title
+	}
+	return s.writeRow(true, values)
+}
+
+// writeRow writes a row with the given values
+func (s *Sheet) writeRow(bold bool, values []interface{}) error {
+	if s.closed {
+		return ErrClosed
+	}
+	s.rows++
+	fmt.Fprintf(s.w, `<row r="%d">`, s.rows)
+	for i, value := range values {
+		s.writeCell(cellRef(i, s.rows), value, bold)
+	}
+	_, err := s.w.WriteString(`</row>`)
+	return err
+}
+
+// writeCell writes a cell with the given reference and value
+func (s *Sheet) writeCell(ref string, value interface{}, bold bool) {
+	style := styleDefault
+	if bold {
+		style = styleBold
+	}
+	switch v := value.(type) {
+	case nil:
+		return
+	case bool:
+		val := "0"
+		if v {
+			val = "1"
+		}
+		fmt.Fprintf(s.w, `<c r="%s" s="%d" t="b"><v>%s</v></c>`, ref, style, val)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(s.w, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+	case float32:
+		s.writeNumber(ref, style, float64(v))
+	case float64:
+		s.writeNumber(ref, style, v)
+	case time.Time:
+		if v.IsZero() {
+			return
+		}
+		style = styleDateTime
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 {
+			style = styleDate
+		}
+		s.writeNumber(ref, style, serialDate(v))
+	case string:
+		fmt.Fprintf(s.w, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(v))
+	default:
+		s.writeCell(ref, fmt.Sprint(v), bold)
+	}
+}
+
+// writeNumber writes a numeric cell
+func (s *Sheet) writeNumber(ref string, style int, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	fmt.Fprintf(s.w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// serialDate returns the given time as a spreadsheet serial date
+func serialDate(t time.Time) float64 {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return t.Sub(excelEpoch).Hours() / 24
+}
+
+// ColumnName returns the name of the column with the given zero based index (e.g. 27 => AB)
+func ColumnName(index int) string {
+	var res string
+	for index++; index > 0; index = (index - 1) / 26 {
+		res = string(rune('A'+(index-1)%26)) + res
+	}
+	return res
+}
+
+// cellRef returns the reference of the cell at the given zero based column and one based row
+func cellRef(col, row int) string {
+	return ColumnName(col) + strconv.Itoa(row)
+}
+
+// escape returns s escaped for XML text
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// sanitizeSheetName returns a valid sheet name from name
+func sanitizeSheetName(name string, index int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if len([]rune(name)) > maxSheetNameLength {
+		name = string([]rune(name)[:maxSheetNameLength])
+	}
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", index)
+	}
+	return name
+}
+
+// rootRels is the content of the package relationships part
+const rootRels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// styles is the content of the styles part. The cellXfs are in the order of the style constants.
+const styles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
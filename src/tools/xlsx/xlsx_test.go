@@ -0,0 +1,74 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// readParts returns the content of the parts of the given spreadsheet
+func readParts(data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	So(err, ShouldBeNil)
+	res := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		So(err, ShouldBeNil)
+		content, err := ioutil.ReadAll(r)
+		So(err, ShouldBeNil)
+		res[f.Name] = string(content)
+	}
+	return res
+}
+
+func TestXLSX(t *testing.T) {
+	Convey("Testing XLSX writer", t, func() {
+		Convey("Column names should be computed", func() {
+			So(ColumnName(0), ShouldEqual, "A")
+			So(ColumnName(25), ShouldEqual, "Z")
+			So(ColumnName(26), ShouldEqual, "AA")
+			So(ColumnName(27), ShouldEqual, "AB")
+			So(ColumnName(702), ShouldEqual, "AAA")
+		})
+		Convey("Spreadsheets should be written sheet by sheet", func() {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			sheet, err := w.AddSheet("Partners: <all>")
+			So(err, ShouldBeNil)
+			So(sheet.WriteHeader("Name", "Active", "Credit", "Date"), ShouldBeNil)
+			So(sheet.WriteRow("John & Co", true, 12.5, time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)), ShouldBeNil)
+			So(sheet.WriteRow(nil, false, 3), ShouldBeNil)
+			sheet2, err := w.AddSheet("")
+			So(err, ShouldBeNil)
+			So(sheet.WriteRow("closed"), ShouldEqual, ErrClosed)
+			So(sheet2.WriteRow("second"), ShouldBeNil)
+			So(w.Close(), ShouldBeNil)
+			parts := readParts(buf.Bytes())
+			So(parts, ShouldContainKey, "[Content_Types].xml")
+			So(parts, ShouldContainKey, "_rels/.rels")
+			So(parts, ShouldContainKey, "xl/styles.xml")
+			So(parts["xl/workbook.xml"], ShouldContainSubstring, `<sheet name="Partners_ &lt;all&gt;" sheetId="1" r:id="rId1"/>`)
+			So(parts["xl/workbook.xml"], ShouldContainSubstring, `<sheet name="Sheet2" sheetId="2" r:id="rId2"/>`)
+			sheet1 := parts["xl/worksheets/sheet1.xml"]
+			So(sheet1, ShouldContainSubstring, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c>`)
+			So(sheet1, ShouldContainSubstring, `<c r="A2" s="0" t="inlineStr"><is><t xml:space="preserve">John &amp; Co</t></is></c>`)
+			So(sheet1, ShouldContainSubstring, `<c r="B2" s="0" t="b"><v>1</v></c>`)
+			So(sheet1, ShouldContainSubstring, `<c r="C2" s="0"><v>12.5</v></c>`)
+			So(sheet1, ShouldContainSubstring, `<c r="D2" s="2"><v>43467</v></c>`)
+			So(sheet1, ShouldContainSubstring, `<row r="3"><c r="B3" s="0" t="b"><v>0</v></c><c r="C3" s="0"><v>3</v></c></row>`)
+			So(parts["xl/worksheets/sheet2.xml"], ShouldContainSubstring, "second")
+		})
+		Convey("Empty spreadsheets should have one sheet", func() {
+			var buf bytes.Buffer
+			So(NewWriter(&buf).Close(), ShouldBeNil)
+			So(readParts(buf.Bytes()), ShouldContainKey, "xl/worksheets/sheet1.xml")
+		})
+	})
+}
@@ -17,10 +17,12 @@
 package xmlutils
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 
 	"github.com/beevik/etree"
@@ -69,7 +71,10 @@ func ApplyExtensions(base *etree.Element, specs *etree.Document) (*etree.Element
 			specBytes, _ := ElementToXML(spec)
 			return nil, fmt.Errorf("error in spec %s: %s", string(specBytes), err)
 		}
-		nodeToModify := baseElem.Parent().FindElement(xpath)
+		nodeToModify, err := FindOne(baseElem.Parent(), xpath)
+		if err != nil {
+			return nil, err
+		}
 		if nodeToModify == nil {
 			return nil, fmt.Errorf("node not found in parent view: %s", xpath)
 		}
@@ -99,15 +104,38 @@ func ApplyExtensions(base *etree.Element, specs *etree.Document) (*etree.Element
 			}
 		case "attributes":
 			for _, node := range spec.FindElements("./attribute") {
-				attrName := node.SelectAttr("name").Value
-				nodeToModify.RemoveAttr(attrName)
-				nodeToModify.CreateAttr(attrName, node.Text())
+				applyAttributeSpec(nodeToModify, node)
 			}
 		}
 	}
 	return baseElem, nil
 }
 
+// applyAttributeSpec modifies an attribute of the given node according to
+// the given <attribute> spec element. If the spec has 'add' or 'remove'
+// attributes, the given values are added to or removed from the list of
+// values of the attribute, separated by the 'separator' attribute of the
+// spec (',' by default). Otherwise, the attribute value is replaced by the
+// text of the spec, and removed if this text is empty.
+func applyAttributeSpec(node, spec *etree.Element) {
+	attrName := spec.SelectAttrValue("name", "")
+	add, remove := spec.SelectAttr("add"), spec.SelectAttr("remove")
+	if add == nil && remove == nil {
+		node.RemoveAttr(attrName)
+		if spec.Text() != "" {
+			node.CreateAttr(attrName, spec.Text())
+		}
+		return
+	}
+	sep := spec.SelectAttrValue("separator", ",")
+	if add != nil {
+		AddToAttribute(node, attrName, sep, splitAttribute(add.Value, sep)...)
+	}
+	if remove != nil {
+		RemoveFromAttribute(node, attrName, sep, splitAttribute(remove.Value, sep)...)
+	}
+}
+
 // getInheritXPathFromSpec returns an XPath string that is suitable for
 // searching the base view and find the node to modify.
 func getInheritXPathFromSpec(spec *etree.Element) (string, error) {
@@ -127,3 +155,51 @@ func getInheritXPathFromSpec(spec *etree.Element) (string, error) {
 	}
 	return fmt.Sprintf("//%s%s", spec.Tag, attrStr), nil
 }
+
+// ChildLines returns the line numbers at which the child elements of the
+// elements at the given path of tags start in the given XML document, in
+// document order. For instance, ChildLines(data, "hexya", "data") returns
+// the lines of the records of a Hexya data file.
+func ChildLines(data []byte, path ...string) ([]int, error) {
+	var (
+		res    []int
+		stack  []string
+		line   = 1
+		offset int64
+	)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		start := decoder.InputOffset()
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if matchPath(stack, path) {
+				line += bytes.Count(data[offset:start], []byte("\n"))
+				offset = start
+				res = append(res, line)
+			}
+			stack = append(stack, t.Name.Local)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// matchPath returns true if the given stack of tags is equal to path
+func matchPath(stack, path []string) bool {
+	if len(stack) != len(path) {
+		return false
+	}
+	for i := range stack {
+		if stack[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
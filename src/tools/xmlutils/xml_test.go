@@ -164,3 +164,29 @@ func TestHasParentTag(t *testing.T) {
 		So(HasParentTag(field, "field"), ShouldBeFalse)
 	})
 }
+
+func TestChildLines(t *testing.T) {
+	Convey("Finding the lines of records", t, func() {
+		data := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<hexya>
+	<data>
+		<view id="a" model="User">
+			<form>
+				<view/>
+			</form>
+		</view>
+
+		<action id="b"/>
+	</data>
+	<data>
+		<menuitem id="c"/>
+	</data>
+</hexya>
+`)
+		lines, err := ChildLines(data, "hexya", "data")
+		So(err, ShouldBeNil)
+		So(lines, ShouldResemble, []int{4, 10, 13})
+		_, err = ChildLines([]byte("<hexya><data></hexya>"), "hexya", "data")
+		So(err, ShouldNotBeNil)
+	})
+}
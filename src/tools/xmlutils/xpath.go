@@ -0,0 +1,184 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package xmlutils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+)
+
+// compilePath compiles the given XPath expression, returning
+// an error instead of panicking if it is invalid.
+func compilePath(expr string) (path etree.Path, err error) {
+	defer func() {
+		// etree panics on some malformed expressions
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid xpath expression '%s': %v", expr, r)
+		}
+	}()
+	path, err = etree.CompilePath(expr)
+	if err != nil {
+		return etree.Path{}, fmt.Errorf("invalid xpath expression '%s': %s", expr, err)
+	}
+	return path, nil
+}
+
+// FindAll returns the elements matching the given XPath expression,
+// evaluated from the given element.
+//
+// The supported syntax is the one of the etree package, which is a subset
+// of XPath: tags, '*', '.', '..', '/', '//', and filters by position
+// ([1], [last()]), attribute ([@name], [@name='value']), child ([tag],
+// [tag='text']) or text ([text()='text']).
+func FindAll(element *etree.Element, expr string) ([]*etree.Element, error) {
+	path, err := compilePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return element.FindElementsPath(path), nil
+}
+
+// FindOne returns the first element matching the given XPath expression,
+// evaluated from the given element, or nil if there is none.
+func FindOne(element *etree.Element, expr string) (*etree.Element, error) {
+	path, err := compilePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return element.FindElementPath(path), nil
+}
+
+// Values returns the values matched by the given XPath expression,
+// evaluated from the given element. If the last step of the expression
+// is an attribute (e.g. '//field/@name'), the values of this attribute
+// are returned. Otherwise, the texts of the matching elements are returned.
+func Values(element *etree.Element, expr string) ([]string, error) {
+	var attr string
+	if i := strings.LastIndex(expr, "@"); i >= 0 && (i == 0 || expr[i-1] == '/') && !strings.ContainsAny(expr[i:], "[]") {
+		expr, attr = strings.TrimSuffix(expr[:i], "/"), expr[i+1:]
+		if expr == "" || strings.HasSuffix(expr, "/") {
+			expr += "."
+		}
+	}
+	elements, err := FindAll(element, expr)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, elt := range elements {
+		if attr == "" {
+			res = append(res, elt.Text())
+			continue
+		}
+		if a := elt.SelectAttr(attr); a != nil {
+			res = append(res, a.Value)
+		}
+	}
+	return res, nil
+}
+
+// CopyChildren returns deep copies of the child elements of the given element
+func CopyChildren(element *etree.Element) []*etree.Element {
+	children := element.ChildElements()
+	res := make([]*etree.Element, len(children))
+	for i, child := range children {
+		res[i] = child.Copy()
+	}
+	return res
+}
+
+// MergeAttributes sets all the attributes of src on dst,
+// overwriting the attributes of dst with the same key.
+func MergeAttributes(dst, src *etree.Element) {
+	for _, attr := range src.Attr {
+		dst.CreateAttr(attr.FullKey(), attr.Value)
+	}
+}
+
+// AddToAttribute adds the given values to the list of values of the given
+// attribute, separated by sep. Values that are already in the list are not
+// added again. The attribute is created if it does not exist.
+//
+//	AddToAttribute(elt, "class", " ", "oe_inline")
+func AddToAttribute(element *etree.Element, key, sep string, values ...string) {
+	current := splitAttribute(element.SelectAttrValue(key, ""), sep)
+	for _, val := range values {
+		val = strings.TrimSpace(val)
+		if val == "" || containsString(current, val) {
+			continue
+		}
+		current = append(current, val)
+	}
+	element.CreateAttr(key, strings.Join(current, sep))
+}
+
+// RemoveFromAttribute removes the given values from the list of values of
+// the given attribute, separated by sep. The attribute is removed if its
+// list of values becomes empty.
+func RemoveFromAttribute(element *etree.Element, key, sep string, values ...string) {
+	attr := element.SelectAttr(key)
+	if attr == nil {
+		return
+	}
+	var res []string
+	for _, val := range splitAttribute(attr.Value, sep) {
+		if !containsString(values, val) {
+			res = append(res, val)
+		}
+	}
+	if len(res) == 0 {
+		element.RemoveAttr(key)
+		return
+	}
+	element.CreateAttr(key, strings.Join(res, sep))
+}
+
+// splitAttribute returns the non empty trimmed values of the given attribute value separated by sep
+func splitAttribute(value, sep string) []string {
+	var res []string
+	if strings.TrimSpace(sep) == "" {
+		return strings.Fields(value)
+	}
+	for _, val := range strings.Split(value, sep) {
+		if val = strings.TrimSpace(val); val != "" {
+			res = append(res, val)
+		}
+	}
+	return res
+}
+
+// containsString returns true if the given list contains str
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalXML returns the given element and its children as indented XML,
+// with attributes sorted by key and whitespace only texts removed, so that
+// two equivalent elements give the same result regardless of their formatting.
+func CanonicalXML(element *etree.Element) ([]byte, error) {
+	elt := CopyElement(element)
+	canonicalize(elt)
+	return ElementToXML(elt)
+}
+
+// canonicalize sorts the attributes of the given element and removes
+// its whitespace only texts, recursively.
+func canonicalize(element *etree.Element) {
+	element.SortAttrs()
+	for _, token := range append([]etree.Token(nil), element.Child...) {
+		if cd, ok := token.(*etree.CharData); ok && strings.TrimSpace(cd.Data) == "" {
+			element.RemoveChild(cd)
+		}
+	}
+	for _, child := range element.ChildElements() {
+		canonicalize(child)
+	}
+}
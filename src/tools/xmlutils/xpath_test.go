@@ -0,0 +1,99 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package xmlutils
+
+import (
+	"testing"
+
+	"github.com/beevik/etree"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestXPath(t *testing.T) {
+	Convey("Testing xpath helpers", t, func() {
+		baseElem, _ := XMLToElement(baseXML)
+		Convey("Evaluating xpath expressions", func() {
+			fields, err := FindAll(baseElem, "//field")
+			So(err, ShouldBeNil)
+			So(fields, ShouldHaveLength, 3)
+			group, err := FindOne(baseElem, "//field[@name='Email']/..")
+			So(err, ShouldBeNil)
+			So(group.SelectAttrValue("name", ""), ShouldEqual, "contact_data")
+			none, err := FindOne(baseElem, "//field[@name='Unknown']")
+			So(err, ShouldBeNil)
+			So(none, ShouldBeNil)
+			_, err = FindAll(baseElem, "//field[@name='Email'")
+			So(err, ShouldNotBeNil)
+			_, err = ApplyExtensions(baseElem, mustDocument(`<xpath expr="//field[" position="inside"/>`))
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Selecting values", func() {
+			names, err := Values(baseElem, "//group/field/@name")
+			So(err, ShouldBeNil)
+			So(names, ShouldResemble, []string{"Function", "Email"})
+			names, err = Values(baseElem.FindElement("//group"), "@name")
+			So(err, ShouldBeNil)
+			So(names, ShouldResemble, []string{"position_info"})
+			texts, err := Values(mustElement(`<a><b>x</b><b>y</b></a>`), "b")
+			So(err, ShouldBeNil)
+			So(texts, ShouldResemble, []string{"x", "y"})
+		})
+		Convey("Copying and merging", func() {
+			form := baseElem
+			children := CopyChildren(form)
+			So(children, ShouldHaveLength, len(form.ChildElements()))
+			So(children[0], ShouldNotEqual, form.ChildElements()[0])
+			dst := mustElement(`<field name="Name" string="Name"/>`)
+			MergeAttributes(dst, mustElement(`<field string="Full Name" required="1"/>`))
+			So(dst.SelectAttrValue("name", ""), ShouldEqual, "Name")
+			So(dst.SelectAttrValue("string", ""), ShouldEqual, "Full Name")
+			So(dst.SelectAttrValue("required", ""), ShouldEqual, "1")
+		})
+		Convey("Adding and removing attribute values", func() {
+			elt := mustElement(`<div class="oe_left  oe_inline"/>`)
+			AddToAttribute(elt, "class", " ", "oe_inline", "oe_bold")
+			So(elt.SelectAttrValue("class", ""), ShouldEqual, "oe_left oe_inline oe_bold")
+			RemoveFromAttribute(elt, "class", " ", "oe_left", "oe_inline", "oe_bold")
+			So(elt.SelectAttr("class"), ShouldBeNil)
+			res, err := ApplyExtensions(mustElement(`<form><field name="A" groups="g1,g2"/></form>`), mustDocument(`
+<field name="A" position="attributes">
+	<attribute name="groups" add="g3" remove="g1"/>
+	<attribute name="invisible">1</attribute>
+</field>`))
+			So(err, ShouldBeNil)
+			So(res.FindElement("field").SelectAttrValue("groups", ""), ShouldEqual, "g2,g3")
+			So(res.FindElement("field").SelectAttrValue("invisible", ""), ShouldEqual, "1")
+		})
+		Convey("Canonical XML should not depend on formatting", func() {
+			a, err := CanonicalXML(mustElement(`<form b="2" a="1">
+	<field   name="A"/>   <field name="B" string="b"/>
+</form>`))
+			So(err, ShouldBeNil)
+			b, err := CanonicalXML(mustElement(`<form a="1" b="2"><field name="A"/><field string="b" name="B"/></form>`))
+			So(err, ShouldBeNil)
+			So(string(a), ShouldEqual, string(b))
+			So(string(a), ShouldEqual, `<form a="1" b="2">
+	<field name="A"/>
+	<field name="B" string="b"/>
+</form>
+`)
+		})
+	})
+}
+
+func mustElement(xml string) *etree.Element {
+	elt, err := XMLToElement(xml)
+	if err != nil {
+		panic(err)
+	}
+	return elt
+}
+
+func mustDocument(xml string) *etree.Document {
+	doc, err := XMLToDocument(xml)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
@@ -0,0 +1,25 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package uom is a Hexya module that provides units of measure.
+//
+// Units are grouped in categories (e.g. weight or length). Each category has a
+// reference unit and other units are defined by their factor relatively to it,
+// so that quantities can be converted between units of the same category.
+package uom
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "uom"
+)
+
+func init() {
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package uom
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+)
+
+// Types of units of measure
+const (
+	// TypeReference is the type of the reference unit of a category
+	TypeReference = "reference"
+	// TypeBigger is the type of units bigger than the reference unit
+	TypeBigger = "bigger"
+	// TypeSmaller is the type of units smaller than the reference unit
+	TypeSmaller = "smaller"
+)
+
+func declareModels() {
+	uomCategory := models.NewModel("UoMCategory")
+	uom := models.NewModel("UoM")
+
+	uomCategory.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true, Translate: true},
+		"UoMs": fields.One2Many{String: "Units of Measure", RelationModel: uom, ReverseFK: "Category"},
+	})
+
+	uom.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Unit of Measure", Required: true, Translate: true},
+		"Category": fields.Many2One{RelationModel: uomCategory, Required: true, OnDelete: models.Restrict,
+			Help: "Quantities can only be converted between units of the same category"},
+		"Factor": fields.Float{String: "Ratio", Required: true, Default: models.DefaultValue(1.0),
+			Help: "How many times this unit is smaller than the reference unit of its category"},
+		"Rounding": fields.Float{String: "Rounding Precision", Required: true, Default: models.DefaultValue(0.01),
+			Help: "Quantities in this unit are rounded to a multiple of this value"},
+		"UoMType": fields.Selection{String: "Type", Required: true, Default: models.DefaultValue(TypeReference),
+			Selection: types.Selection{
+				TypeBigger:    "Bigger than the reference unit",
+				TypeReference: "Reference unit for this category",
+				TypeSmaller:   "Smaller than the reference unit",
+			}},
+		"Active": fields.Boolean{Default: models.DefaultValue(true)},
+	})
+	uom.AddSQLConstraint("factor_gt_zero", "CHECK (factor > 0)",
+		"The ratio of a unit of measure must be strictly positive")
+	uom.AddSQLConstraint("rounding_gt_zero", "CHECK (rounding > 0)",
+		"The rounding precision of a unit of measure must be strictly positive")
+}
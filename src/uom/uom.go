@@ -0,0 +1,87 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package uom
+
+import (
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/tools/nbutils"
+)
+
+// A Unit holds the conversion data of a unit of measure
+type Unit struct {
+	Name     string
+	Category int64
+	Factor   float64
+	Rounding float64
+}
+
+// UnitOf returns the Unit of the given UoM record
+func UnitOf(rs models.RecordSet) Unit {
+	rs.EnsureOne()
+	mi := rs.Collection().Model()
+	var category int64
+	if ids := rs.Get(mi.FieldName("Category")).(models.RecordSet).Ids(); len(ids) > 0 {
+		category = ids[0]
+	}
+	return Unit{
+		Name:     rs.Get(mi.FieldName("Name")).(string),
+		Category: category,
+		Factor:   rs.Get(mi.FieldName("Factor")).(float64),
+		Rounding: rs.Get(mi.FieldName("Rounding")).(float64),
+	}
+}
+
+// Round returns the given quantity rounded to the precision of this unit
+func (u Unit) Round(qty float64) float64 {
+	if u.Rounding <= 0 {
+		return qty
+	}
+	return nbutils.Round(qty, u.Rounding)
+}
+
+// checkCategory returns an error if u and to are not in the same category
+func (u Unit) checkCategory(to Unit) error {
+	if u.Category != to.Category {
+		return fmt.Errorf("unable to convert from %s to %s: units of measure are not in the same category", u.Name, to.Name)
+	}
+	return nil
+}
+
+// ConvertTo returns the given quantity in this unit converted into unit to.
+// If round is true, the result is rounded to the precision of unit to.
+// It returns an error if both units are not in the same category.
+func (u Unit) ConvertTo(qty float64, to Unit, round bool) (float64, error) {
+	if err := u.checkCategory(to); err != nil {
+		return 0, err
+	}
+	res := qty / u.Factor * to.Factor
+	if round {
+		res = to.Round(res)
+	}
+	return res, nil
+}
+
+// ConvertPriceTo returns the given price per this unit converted into a price per unit to.
+// It returns an error if both units are not in the same category.
+func (u Unit) ConvertPriceTo(price float64, to Unit) (float64, error) {
+	if err := u.checkCategory(to); err != nil {
+		return 0, err
+	}
+	return price * u.Factor / to.Factor, nil
+}
+
+// Convert returns the given quantity in the from UoM record converted into the to UoM record.
+// If round is true, the result is rounded to the precision of to.
+// It returns an error if both units are not in the same category.
+func Convert(qty float64, from, to models.RecordSet, round bool) (float64, error) {
+	return UnitOf(from).ConvertTo(qty, UnitOf(to), round)
+}
+
+// ConvertPrice returns the given price per from UoM record converted into a price per to UoM record.
+// It returns an error if both units are not in the same category.
+func ConvertPrice(price float64, from, to models.RecordSet) (float64, error) {
+	return UnitOf(from).ConvertPriceTo(price, UnitOf(to))
+}
@@ -0,0 +1,41 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package uom
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConversion(t *testing.T) {
+	kg := Unit{Name: "kg", Category: 1, Factor: 1, Rounding: 0.01}
+	gram := Unit{Name: "g", Category: 1, Factor: 1000, Rounding: 1}
+	ton := Unit{Name: "t", Category: 1, Factor: 0.001, Rounding: 0.001}
+	meter := Unit{Name: "m", Category: 2, Factor: 1, Rounding: 0.01}
+	Convey("Testing unit of measure conversion", t, func() {
+		Convey("Quantities should be converted with the factors of the units", func() {
+			res, err := gram.ConvertTo(2500, ton, false)
+			So(err, ShouldBeNil)
+			So(res, ShouldAlmostEqual, 0.0025)
+			res, err = kg.ConvertTo(1.23456, gram, true)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, 1235)
+			res, err = gram.ConvertTo(1234, kg, true)
+			So(err, ShouldBeNil)
+			So(res, ShouldEqual, 1.23)
+		})
+		Convey("Prices should be converted inversely to quantities", func() {
+			res, err := kg.ConvertPriceTo(12, gram)
+			So(err, ShouldBeNil)
+			So(res, ShouldAlmostEqual, 0.012)
+		})
+		Convey("Conversion between categories should fail", func() {
+			_, err := kg.ConvertTo(1, meter, false)
+			So(err, ShouldNotBeNil)
+			_, err = kg.ConvertPriceTo(1, meter)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package utm is a Hexya module that tracks the campaigns, sources and
+// mediums that bring visitors, for the attribution of leads and orders.
+//
+// The UTM parameters given in the query of requests (utm_campaign, utm_source
+// and utm_medium) are kept in cookies and passed to the context of the
+// Environments of the next requests of the visitor. Models whose records must
+// be attributed inherit the UtmMixin model, whose Campaign, Source and Medium
+// fields default to the records named after these parameters:
+//
+//	h.Lead().InheritModel(h.UtmMixin())
+package utm
+
+import (
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "utm"
+)
+
+func init() {
+	declareModels()
+	controllers.Registry.AddMiddleWare(Tracker())
+	server.RegisterRequestContextDefaults(ContextFromRequest)
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package utm
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	campaign := models.NewModel("UtmCampaign")
+	campaign.SetDefaultOrder("Name")
+	campaign.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Campaign Name", Required: true},
+	})
+	campaign.AddSQLConstraint("name_uniq", "unique(name)", "Campaign names must be unique")
+
+	source := models.NewModel("UtmSource")
+	source.SetDefaultOrder("Name")
+	source.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Source Name", Required: true},
+	})
+	source.AddSQLConstraint("name_uniq", "unique(name)", "Source names must be unique")
+
+	medium := models.NewModel("UtmMedium")
+	medium.SetDefaultOrder("Name")
+	medium.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Medium Name", Required: true},
+	})
+	medium.AddSQLConstraint("name_uniq", "unique(name)", "Medium names must be unique")
+
+	utmMixin := models.NewMixinModel("UtmMixin")
+	utmMixin.AddFields(map[string]models.FieldDefinition{
+		"Campaign": fields.Many2One{RelationModel: campaign, OnDelete: models.SetNull, Index: true,
+			Default: defaultFromContext(CampaignKey, campaign.Name()),
+			Help:    "Name of the marketing campaign, e.g. 'Fall_Drive' or 'Christmas_Special'"},
+		"Source": fields.Many2One{RelationModel: source, OnDelete: models.SetNull, Index: true,
+			Default: defaultFromContext(SourceKey, source.Name()),
+			Help:    "Source of the link, e.g. 'Search Engine' or 'Newsletter'"},
+		"Medium": fields.Many2One{RelationModel: medium, OnDelete: models.SetNull, Index: true,
+			Default: defaultFromContext(MediumKey, medium.Name()),
+			Help:    "Method of delivery, e.g. 'Banner' or 'Email'"},
+	})
+}
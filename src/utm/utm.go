@@ -0,0 +1,104 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package utm
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// Keys of the UTM parameters in the query of requests and in the
+// context of Environments
+const (
+	CampaignKey = "utm_campaign"
+	SourceKey   = "utm_source"
+	MediumKey   = "utm_medium"
+)
+
+// Keys are all the UTM parameters keys
+var Keys = []string{CampaignKey, SourceKey, MediumKey}
+
+// CookiePrefix is prepended to the keys of the UTM
+// parameters to get the name of their cookies
+const CookiePrefix = "hexya_"
+
+// CookieMaxAge is the duration during which the UTM parameters
+// of a visitor are kept in their cookies
+var CookieMaxAge = 30 * 24 * time.Hour
+
+// maxValueLength is the maximum length of the UTM parameters values.
+// Longer values are truncated.
+const maxValueLength = 128
+
+// nameFieldName is the name of the field holding the name of UTM records
+var nameFieldName = models.NewFieldName("Name", "name")
+
+// Tracker returns a middleware that keeps the UTM parameters given in
+// the query of requests in cookies, so that the records created during
+// the next requests of the visitor are attributed to them.
+func Tracker() server.HandlerFunc {
+	return func(c *server.Context) {
+		for _, key := range Keys {
+			if value := normalizeValue(c.Query(key)); value != "" {
+				c.SetCookie(CookiePrefix+key, value, int(CookieMaxAge.Seconds()), "/", "", c.Request.TLS != nil, true)
+			}
+		}
+		c.Next()
+	}
+}
+
+// ContextFromRequest returns the UTM parameters of the given request as
+// context values. Parameters given in the query take precedence over
+// those kept in the cookies of the visitor.
+func ContextFromRequest(c *server.Context) *types.Context {
+	ctx := types.NewContext()
+	for _, key := range Keys {
+		value := c.Query(key)
+		if value == "" {
+			value, _ = c.Cookie(CookiePrefix + key)
+		}
+		if value = normalizeValue(value); value != "" {
+			ctx = ctx.WithKey(key, value)
+		}
+	}
+	return ctx
+}
+
+// normalizeValue returns the given UTM parameter value trimmed and truncated
+func normalizeValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > maxValueLength {
+		value = strings.ToValidUTF8(value[:maxValueLength], "")
+	}
+	return value
+}
+
+// Record returns the record of the given UTM model with the given name,
+// creating it if it does not exist yet.
+func Record(env models.Environment, modelName, name string) models.RecordSet {
+	records := env.Pool(modelName).Sudo()
+	mi := records.Model()
+	res := records.Search(mi.Field(nameFieldName).Equals(name)).Limit(1)
+	if res.IsEmpty() {
+		res = records.Call("Create", models.NewModelData(mi).Set(nameFieldName, name)).(models.RecordSet).Collection()
+	}
+	return res.Sudo(env.Uid())
+}
+
+// defaultFromContext returns a default function for the UtmMixin field
+// pointing to the given model that returns the record named after the
+// value of the given key in the context.
+func defaultFromContext(key, modelName string) func(models.Environment) interface{} {
+	return func(env models.Environment) interface{} {
+		name := env.Context().GetString(key)
+		if name == "" {
+			return env.Pool(modelName)
+		}
+		return Record(env, modelName, name)
+	}
+}
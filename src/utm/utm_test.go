@@ -0,0 +1,100 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package utm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tests"
+	_ "github.com/lib/pq"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMain(m *testing.M) {
+	lead := models.NewModel("UtmTestLead")
+	lead.InheritModel(models.Registry.MustGet("UtmMixin"))
+	lead.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{},
+	})
+	tests.RunTests(m, MODULE_NAME, nil)
+}
+
+func TestUTM(t *testing.T) {
+	Convey("Testing UTM tracking", t, func() {
+		gin.SetMode(gin.ReleaseMode)
+		srv := &server.Server{Engine: gin.New()}
+		srv.AddMiddleWare(Tracker())
+		var ctxValues map[string]interface{}
+		srv.Group("/").GET("/page", func(c *server.Context) {
+			ctxValues = ContextFromRequest(c).ToMap()
+			c.String(http.StatusOK, "ok")
+		})
+		Convey("UTM parameters of the query should be kept in cookies", func() {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page?utm_campaign=Spring&utm_source=Newsletter", nil))
+			cookies := strings.Join(w.Header()["Set-Cookie"], "\n")
+			So(cookies, ShouldContainSubstring, "hexya_utm_campaign=Spring")
+			So(cookies, ShouldContainSubstring, "hexya_utm_source=Newsletter")
+			So(cookies, ShouldNotContainSubstring, "hexya_utm_medium")
+			So(ctxValues, ShouldResemble, map[string]interface{}{"utm_campaign": "Spring", "utm_source": "Newsletter"})
+		})
+		Convey("UTM parameters should be read from cookies when not in the query", func() {
+			req := httptest.NewRequest(http.MethodGet, "/page?utm_medium=Banner", nil)
+			req.AddCookie(&http.Cookie{Name: "hexya_utm_campaign", Value: "Spring"})
+			req.AddCookie(&http.Cookie{Name: "hexya_utm_medium", Value: "Email"})
+			srv.ServeHTTP(httptest.NewRecorder(), req)
+			So(ctxValues, ShouldResemble, map[string]interface{}{"utm_campaign": "Spring", "utm_medium": "Banner"})
+		})
+		Convey("Long values should be truncated", func() {
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page?utm_campaign="+strings.Repeat("a", 200), nil))
+			So(ctxValues["utm_campaign"], ShouldHaveLength, maxValueLength)
+		})
+	})
+}
+
+func TestUtmMixin(t *testing.T) {
+	Convey("Testing UtmMixin", t, func() {
+		So(models.SimulateInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+			leads := env.Pool("UtmTestLead")
+			mi := leads.Model()
+			createLead := func(rc *models.RecordCollection, name string) *models.RecordCollection {
+				return rc.Call("Create", models.NewModelData(mi).Set(nameFieldName, name)).(models.RecordSet).Collection()
+			}
+			utmName := func(lead *models.RecordCollection, field string) string {
+				return lead.Get(mi.FieldName(field)).(models.RecordSet).Collection().Get(nameFieldName).(string)
+			}
+			Convey("Records should be attributed to the UTM parameters of the context", func() {
+				rc := leads.WithContext(CampaignKey, "Spring").WithContext(SourceKey, "Newsletter")
+				lead := createLead(rc, "First lead")
+				So(utmName(lead, "Campaign"), ShouldEqual, "Spring")
+				So(utmName(lead, "Source"), ShouldEqual, "Newsletter")
+				So(lead.Get(mi.FieldName("Medium")).(models.RecordSet).IsEmpty(), ShouldBeTrue)
+				Convey("UTM records should be reused by the next records", func() {
+					other := createLead(rc.WithContext(MediumKey, "Email"), "Second lead")
+					So(other.Get(mi.FieldName("Campaign")).(models.RecordSet).Ids(), ShouldResemble,
+						lead.Get(mi.FieldName("Campaign")).(models.RecordSet).Ids())
+					So(utmName(other, "Medium"), ShouldEqual, "Email")
+					So(env.Pool("UtmCampaign").SearchAll().Len(), ShouldEqual, 1)
+					So(env.Pool("UtmMedium").SearchAll().Len(), ShouldEqual, 1)
+				})
+			})
+			Convey("Records created without UTM parameters should not be attributed", func() {
+				lead := createLead(leads, "Direct lead")
+				for _, field := range []string{"Campaign", "Source", "Medium"} {
+					So(lead.Get(mi.FieldName(field)).(models.RecordSet).IsEmpty(), ShouldBeTrue)
+				}
+				So(env.Pool("UtmCampaign").SearchAll().IsEmpty(), ShouldBeTrue)
+			})
+		}), ShouldBeNil)
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package views
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/hexya-erp/hexya/src/tools/xmlutils"
+)
+
+// DefaultBoardStyle is the style of boards that do not define one
+const DefaultBoardStyle = "2-1"
+
+// boardStyles maps the styles of boards to their number of columns.
+// Each digit of a style is the relative width of a column.
+var boardStyles = map[string]int{
+	"1":     1,
+	"1-1":   2,
+	"1-2":   2,
+	"2-1":   2,
+	"1-1-1": 3,
+}
+
+// A Board is the layout of a dashboard, as defined in the arch of a board
+// view. It displays actions in columns, the widths of which are given by
+// its style:
+//
+//	<board style="2-1">
+//	    <column>
+//	        <action name="sale_action_orders" string="My Orders" view_mode="list"/>
+//	    </column>
+//	    <column/>
+//	</board>
+type Board struct {
+	XMLName xml.Name      `json:"-" xml:"board"`
+	Style   string        `json:"style" xml:"style,attr"`
+	Columns []BoardColumn `json:"columns" xml:"column"`
+}
+
+// A BoardColumn is a column of a Board
+type BoardColumn struct {
+	Actions []BoardAction `json:"actions" xml:"action"`
+}
+
+// A BoardAction is an action displayed in a Board. Action is the ID of an
+// act_window action. The other fields override the values of the action.
+type BoardAction struct {
+	Action   string `json:"name" xml:"name,attr"`
+	String   string `json:"string" xml:"string,attr,omitempty"`
+	ViewMode string `json:"view_mode" xml:"view_mode,attr,omitempty"`
+	Domain   string `json:"domain" xml:"domain,attr,omitempty"`
+	Context  string `json:"context" xml:"context,attr,omitempty"`
+	Fold     bool   `json:"fold" xml:"fold,attr,omitempty"`
+}
+
+// ParseBoard returns the Board defined by the given arch.
+// It returns an error if the arch is not a valid board.
+func ParseBoard(arch string) (*Board, error) {
+	var board Board
+	if err := xml.Unmarshal([]byte(arch), &board); err != nil {
+		return nil, err
+	}
+	if board.Style == "" {
+		board.Style = DefaultBoardStyle
+	}
+	if err := board.Validate(); err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+// Validate returns an error if the style of this board is unknown,
+// if it has more columns than its style or if an action has no name.
+func (b *Board) Validate() error {
+	numCols, ok := boardStyles[b.Style]
+	if !ok {
+		return fmt.Errorf("unknown board style '%s'", b.Style)
+	}
+	if len(b.Columns) > numCols {
+		return fmt.Errorf("board of style '%s' cannot have %d columns", b.Style, len(b.Columns))
+	}
+	for _, col := range b.Columns {
+		for _, action := range col.Actions {
+			if action.Action == "" {
+				return errors.New("board action without name")
+			}
+		}
+	}
+	return nil
+}
+
+// ActionIDs returns the IDs of the actions of this board, column by column
+func (b *Board) ActionIDs() []string {
+	var res []string
+	for _, col := range b.Columns {
+		for _, action := range col.Actions {
+			res = append(res, action.Action)
+		}
+	}
+	return res
+}
+
+// Arch returns the XML arch of this board
+func (b *Board) Arch() string {
+	res, err := xml.Marshal(b)
+	if err != nil {
+		log.Panic("Unable to marshal board", "error", err)
+	}
+	return string(res)
+}
+
+// Board returns the Board defined by the arch of this view in the given
+// language. It returns an error if this view is not a valid board view.
+func (v *View) Board(lang string) (*Board, error) {
+	if ViewType(v.arch.Tag) != ViewTypeBoard {
+		return nil, fmt.Errorf("view '%s' is not a board view", v.ID)
+	}
+	arch, err := xmlutils.ElementToXML(v.Arch(lang))
+	if err != nil {
+		return nil, err
+	}
+	return ParseBoard(string(arch))
+}
@@ -15,6 +15,7 @@ const maxInheritanceDepth = 100
 var log logging.Logger
 
 // BootStrap makes the necessary updates to view definitions. In particular:
+// - validates the views and panics with all the errors found,
 // - sets the type of the view from the arch root.
 // - extracts embedded views
 // - populates the fields map from the views arch.
@@ -70,12 +71,16 @@ func BootStrap() {
 				Type:        baseView.Type,
 				arches:      make(map[string]*etree.Element),
 				FieldParent: baseView.FieldParent,
+				Source:      xmlView.source,
 			}
 			newView.updateViewFromXML(xmlView)
 			Registry.Add(&newView)
 			Registry.rawInheritedViews[i] = nil
 		}
 	}
+	if errs := Registry.Validate(); len(errs) > 0 {
+		log.Panic("Invalid views", "errors", errs.Error())
+	}
 	// Post-process all views
 	for _, v := range Registry.views {
 		log.Debug("Postprocessing view", "viewID", v.ID, "model", v.Model, "Type", v.Type)
@@ -96,7 +101,13 @@ func loadModelViews() {
 	}
 }
 
+// ResetRegistry replaces the Registry by an empty collection,
+// so that views can be loaded again.
+func ResetRegistry() {
+	Registry = NewCollection()
+}
+
 func init() {
 	log = logging.GetLogger("views")
-	Registry = NewCollection()
+	ResetRegistry()
 }
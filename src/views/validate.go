@@ -0,0 +1,180 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package views
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/beevik/etree"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+)
+
+// A Source is the location of the definition of a view
+type Source struct {
+	Module string
+	File   string
+	Line   int
+}
+
+// String returns the file and line of this Source
+func (s Source) String() string {
+	if s.File == "" {
+		return "unknown file"
+	}
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// A ValidationError is an error found in the arch of a view
+type ValidationError struct {
+	ViewID  string
+	Source  Source
+	Message string
+}
+
+// Error returns the message of this error with its location
+func (ve ValidationError) Error() string {
+	if ve.ViewID == "" {
+		return fmt.Sprintf("%s: %s", ve.Source, ve.Message)
+	}
+	return fmt.Sprintf("%s: view '%s': %s", ve.Source, ve.ViewID, ve.Message)
+}
+
+// ValidationErrors is a list of errors found in views
+type ValidationErrors []ValidationError
+
+// Error returns all the errors of the list, grouped by module
+func (ves ValidationErrors) Error() string {
+	byModule := make(map[string][]string)
+	var modules []string
+	for _, ve := range ves {
+		if _, exists := byModule[ve.Source.Module]; !exists {
+			modules = append(modules, ve.Source.Module)
+		}
+		byModule[ve.Source.Module] = append(byModule[ve.Source.Module], ve.Error())
+	}
+	sort.Strings(modules)
+	var buf strings.Builder
+	for _, mod := range modules {
+		name := mod
+		if name == "" {
+			name = "unknown module"
+		}
+		fmt.Fprintf(&buf, "%s:\n", name)
+		for _, msg := range byModule[mod] {
+			fmt.Fprintf(&buf, "\t%s\n", msg)
+		}
+	}
+	return buf.String()
+}
+
+// Validate checks the views of this collection and returns all the errors found.
+// It checks that:
+//
+// - inherited views exist,
+// - views models exist,
+// - fields exist in the model of the view or of the embedded view,
+// - buttons of type 'object' call existing methods,
+// - groups given in 'groups' attributes exist,
+// - board views have a valid layout.
+//
+// Models must be bootstrapped before calling Validate.
+func (vc *Collection) Validate() ValidationErrors {
+	var res ValidationErrors
+	for _, xmlView := range vc.rawInheritedViews {
+		if xmlView == nil {
+			continue
+		}
+		res = append(res, ValidationError{
+			ViewID:  xmlView.ID,
+			Source:  xmlView.source,
+			Message: fmt.Sprintf("inherited view '%s' does not exist", xmlView.InheritID),
+		})
+	}
+	ids := make([]string, 0, len(vc.views))
+	for id := range vc.views {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		res = append(res, vc.views[id].validate()...)
+	}
+	return res
+}
+
+// validate checks the arch of this view and returns the errors found
+func (v *View) validate() ValidationErrors {
+	var msgs []string
+	model, ok := models.Registry.Get(v.Model)
+	switch {
+	case !ok:
+		msgs = append(msgs, fmt.Sprintf("unknown model '%s'", v.Model))
+	case ViewType(v.arch.Tag) == ViewTypeBoard:
+		msgs = validateElement(v.arch, model)
+		if _, err := v.Board(""); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	case ViewType(v.arch.Tag) != ViewTypeQWeb:
+		msgs = validateElement(v.arch, model)
+	}
+	res := make(ValidationErrors, len(msgs))
+	for i, msg := range msgs {
+		res[i] = ValidationError{
+			ViewID:  v.ID,
+			Source:  v.Source,
+			Message: msg,
+		}
+	}
+	return res
+}
+
+// validateElement checks the given element of a view of the given model
+// and its children recursively. It returns the error messages.
+func validateElement(elt *etree.Element, model *models.Model) []string {
+	var msgs []string
+	for _, group := range strings.Split(elt.SelectAttrValue("groups", ""), ",") {
+		group = strings.TrimPrefix(strings.TrimSpace(group), "!")
+		if group != "" && security.Registry.GetGroup(group) == nil {
+			msgs = append(msgs, fmt.Sprintf("unknown group '%s' in %s element", group, elt.Tag))
+		}
+	}
+	childModel := model
+	switch elt.Tag {
+	case "field":
+		name := elt.SelectAttrValue("name", "")
+		fi, ok := model.Fields().Get(name)
+		if !ok {
+			return append(msgs, fmt.Sprintf("unknown field '%s' in model %s", name, model.Name()))
+		}
+		if len(elt.ChildElements()) == 0 {
+			break
+		}
+		relation := model.FieldsGet(model.FieldName(fi.Name()))[fi.JSON()].Relation
+		relModel, ok := models.Registry.Get(relation)
+		if !ok {
+			return append(msgs, fmt.Sprintf("field '%s' of model %s has embedded views but is not a relation field", name, model.Name()))
+		}
+		childModel = relModel
+	case "label":
+		if name := elt.SelectAttrValue("for", ""); name != "" {
+			if _, ok := model.Fields().Get(name); !ok {
+				msgs = append(msgs, fmt.Sprintf("label for unknown field '%s' in model %s", name, model.Name()))
+			}
+		}
+	case "button":
+		if elt.SelectAttrValue("type", "") != "object" {
+			break
+		}
+		name := elt.SelectAttrValue("name", "")
+		if _, ok := model.Methods().Get(name); !ok {
+			msgs = append(msgs, fmt.Sprintf("button calls unknown method '%s' of model %s", name, model.Name()))
+		}
+	}
+	for _, child := range elt.ChildElements() {
+		msgs = append(msgs, validateElement(child, childModel)...)
+	}
+	return msgs
+}
@@ -45,6 +45,7 @@ const (
 	ViewTypeKanban   ViewType = "kanban"
 	ViewTypeSearch   ViewType = "search"
 	ViewTypeQWeb     ViewType = "qweb"
+	ViewTypeBoard    ViewType = "board"
 )
 
 // translatableAttributes is the list of XML attribute names the
@@ -284,6 +285,13 @@ func (vc *Collection) GetAllViewsForModel(model string) []*View {
 // LoadFromEtree loads the given view given as Element
 // into this collection.
 func (vc *Collection) LoadFromEtree(element *etree.Element) {
+	vc.LoadFromEtreeWithSource(element, Source{})
+}
+
+// LoadFromEtreeWithSource loads the given view given as Element
+// into this collection. The given source is the location of the
+// element, which is used to report validation errors.
+func (vc *Collection) LoadFromEtreeWithSource(element *etree.Element, source Source) {
 	xmlBytes, err := xmlutils.ElementToXML(element)
 	if err != nil {
 		log.Panic("Unable to convert element to XML", "error", err)
@@ -292,6 +300,7 @@ func (vc *Collection) LoadFromEtree(element *etree.Element) {
 	if err = xml.Unmarshal(xmlBytes, &viewXML); err != nil {
 		log.Panic("Unable to unmarshal element", "error", err, "bytes", string(xmlBytes))
 	}
+	viewXML.source = source
 	if viewXML.InheritID != "" {
 		// Update an existing view.
 		// Put in raw inherited view for now, as the base view may not exist yet.
@@ -326,6 +335,7 @@ func (vc *Collection) createNewViewFromXML(viewXML *ViewXML) {
 		FieldParent: viewXML.FieldParent,
 		SubViews:    make(map[string]SubViews),
 		arches:      make(map[string]*etree.Element),
+		Source:      viewXML.source,
 	}
 	vc.Add(&view)
 }
@@ -342,6 +352,8 @@ type View struct {
 	Fields      []string
 	SubViews    map[string]SubViews
 	arches      map[string]*etree.Element
+	// Source is the location where the view is defined
+	Source Source
 }
 
 // A SubViews is a holder for embedded views of a field
@@ -538,6 +550,7 @@ type ViewXML struct {
 	Arch        string `xml:",innerxml"`
 	InheritID   string `xml:"inherit_id,attr"`
 	FieldParent string `xml:"field_parent,attr"`
+	source      Source
 }
 
 // LoadFromEtree reads the view given etree.Element, creates or updates the view
@@ -545,3 +558,10 @@ type ViewXML struct {
 func LoadFromEtree(element *etree.Element) {
 	Registry.LoadFromEtree(element)
 }
+
+// LoadFromEtreeWithSource reads the view given etree.Element defined at the
+// given source, creates or updates the view and adds it to the view registry
+// if it not already.
+func LoadFromEtreeWithSource(element *etree.Element, source Source) {
+	Registry.LoadFromEtreeWithSource(element, source)
+}
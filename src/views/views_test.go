@@ -228,6 +228,8 @@ func TestViews(t *testing.T) {
 		category := models.NewModel("Category")
 		user := models.NewModel("User")
 		partner := models.NewModel("Partner")
+		// SaleOrder has no views and is used to test default views
+		saleOrder := models.NewModel("SaleOrder")
 		user.NewMethod("OnChangeAge", func(rc *models.RecordCollection) *models.ModelData {
 			return models.NewModelData(rc.Model())
 		})
@@ -256,6 +258,9 @@ func TestViews(t *testing.T) {
 			"Fax":         fields.Char{},
 			"Address":     fields.Char{},
 		})
+		saleOrder.AddFields(map[string]models.FieldDefinition{
+			"Name": fields.Char{},
+		})
 		models.BootStrap()
 		models.Views[partner] = []string{`<view id="test_view" model="Partner"><tree><field name="Name"></tree></view>`}
 	})
@@ -502,10 +507,6 @@ func TestViews(t *testing.T) {
 		So(userFirstView.ID, ShouldEqual, "my_id")
 	})
 	Convey("Testing default views", t, func() {
-		soModel := models.NewModel("SaleOrder")
-		soModel.AddFields(map[string]models.FieldDefinition{
-			"Name": fields.Char{},
-		})
 		soSearch := Registry.GetFirstViewForModel("SaleOrder", ViewTypeSearch)
 		So(elementToXMLString(soSearch.arch), ShouldEqual, `<search>
 	<field name="name"/>
@@ -722,5 +723,85 @@ func TestViews(t *testing.T) {
 </search>
 `)
 	})
-
+	Convey("Validating views", t, func() {
+		Registry = NewCollection()
+		elt, err := xmlutils.XMLToElement(`
+<view id="invalid_view" model="User">
+	<form groups="unknown_group">
+		<field name="UserName"/>
+		<field name="Nickname"/>
+		<label for="Height"/>
+		<button type="object" name="OnChangeAge"/>
+		<button type="object" name="DoSomething"/>
+		<button type="action" name="some_action"/>
+		<field name="Groups">
+			<tree>
+				<field name="Name"/>
+				<field name="Age"/>
+			</tree>
+		</field>
+	</form>
+</view>`)
+		So(err, ShouldBeNil)
+		LoadFromEtreeWithSource(elt, Source{Module: "test", File: "views.xml", Line: 3})
+		loadView(`<view inherit_id="missing_view"><field name="Name" position="after"/></view>`)
+		errs := Registry.Validate()
+		So(errs, ShouldHaveLength, 6)
+		So(errs[0].Message, ShouldEqual, "inherited view 'missing_view' does not exist")
+		So(errs[1].Error(), ShouldEqual, "views.xml:3: view 'invalid_view': unknown group 'unknown_group' in form element")
+		So(errs[2].Message, ShouldEqual, "unknown field 'Nickname' in model User")
+		So(errs[3].Message, ShouldEqual, "label for unknown field 'Height' in model User")
+		So(errs[4].Message, ShouldEqual, "button calls unknown method 'DoSomething' of model User")
+		So(errs[5].Message, ShouldEqual, "unknown field 'Age' in model Group")
+		So(errs.Error(), ShouldStartWith, "unknown module:\n\tunknown file: inherited view 'missing_view' does not exist\ntest:\n\tviews.xml:3: view 'invalid_view': unknown group")
+		So(BootStrap, ShouldPanic)
+	})
+	Convey("Testing board views", t, func() {
+		Registry = NewCollection()
+		loadView(`<view id="my_board" model="User">
+	<board style="1-2">
+		<column>
+			<action name="action_orders" string="My Orders" view_mode="list" fold="true"/>
+		</column>
+		<column>
+			<action name="action_partners" domain="[('Age', '>', 18)]"/>
+		</column>
+	</board>
+</view>`)
+		So(Registry.Validate(), ShouldBeEmpty)
+		BootStrap()
+		board, err := Registry.GetByID("my_board").Board("")
+		So(err, ShouldBeNil)
+		So(board.Style, ShouldEqual, "1-2")
+		So(board.ActionIDs(), ShouldResemble, []string{"action_orders", "action_partners"})
+		So(board.Columns[0].Actions[0], ShouldResemble, BoardAction{Action: "action_orders", String: "My Orders", ViewMode: "list", Fold: true})
+		parsed, err := ParseBoard(board.Arch())
+		So(err, ShouldBeNil)
+		So(parsed, ShouldResemble, board)
+		Convey("Boards with a default style", func() {
+			board, err := ParseBoard(`<board><column/></board>`)
+			So(err, ShouldBeNil)
+			So(board.Style, ShouldEqual, DefaultBoardStyle)
+		})
+		Convey("Invalid boards should be rejected", func() {
+			_, err := ParseBoard(`<board style="3-1"/>`)
+			So(err, ShouldNotBeNil)
+			_, err = ParseBoard(`<board style="1"><column/><column/></board>`)
+			So(err, ShouldNotBeNil)
+			_, err = ParseBoard(`<board><column><action string="No name"/></column></board>`)
+			So(err, ShouldNotBeNil)
+			Registry = NewCollection()
+			loadView(`<view id="invalid_board" model="User"><board style="1"><column/><column/></board></view>`)
+			errs := Registry.Validate()
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Message, ShouldEqual, "board of style '1' cannot have 2 columns")
+		})
+		Convey("Other views are not boards", func() {
+			Registry = NewCollection()
+			loadView(viewDef10)
+			BootStrap()
+			_, err := Registry.GetByID("search_view").Board("")
+			So(err, ShouldNotBeNil)
+		})
+	})
 }
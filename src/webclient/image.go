@@ -0,0 +1,232 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webclient
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	// Load GIF driver
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fieldtype"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+const (
+	// maxImageSize is the maximum width or height of resized images
+	maxImageSize = 4096
+	// maxImagePixels is the maximum number of pixels of stored images that
+	// are decoded, so that small files declaring huge sizes are not decoded.
+	maxImagePixels = 50 * 1000 * 1000
+	// defaultPlaceholderSize is the size of the placeholder when no size is requested
+	defaultPlaceholderSize = 128
+	// uniqueImageMaxAge is the cache duration in seconds of images requested with a 'unique' parameter
+	uniqueImageMaxAge = 365 * 24 * 3600
+)
+
+// placeholderColor is the color of the placeholder image
+var placeholderColor = color.RGBA{R: 0xdd, G: 0xdd, B: 0xdd, A: 0xff}
+
+// errNotImageField is returned when the requested field is not a binary field
+var errNotImageField = errors.New("field is not a binary field")
+
+// loadImage returns the decoded content of the given binary field of the record
+// with the given id. Access rights and record rules of the user of env apply.
+func loadImage(env models.Environment, model string, id int64, field string) ([]byte, error) {
+	mi, ok := models.Registry.Get(model)
+	if !ok {
+		return nil, fmt.Errorf("unknown model %s", model)
+	}
+	fi, ok := mi.Fields().Get(field)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s in model %s", field, model)
+	}
+	fName := mi.FieldName(fi.Name())
+	if mi.FieldsGet(fName)[fi.JSON()].Type != fieldtype.Binary {
+		return nil, errNotImageField
+	}
+	rs := env.Pool(model).Call("Search", mi.Field(models.ID).Equals(id)).(models.RecordSet).Collection()
+	if rs.IsEmpty() {
+		return nil, fmt.Errorf("record %d of %s not found", id, model)
+	}
+	b64, _ := rs.Get(fName).(string)
+	return base64.StdEncoding.DecodeString(b64)
+}
+
+// parseImageSize parses a size in the form 'WIDTHxHEIGHT'. A zero width
+// or height means that it is computed from the image aspect ratio.
+func parseImageSize(size string) (int, int, error) {
+	if size == "" {
+		return 0, 0, nil
+	}
+	parts := strings.Split(size, "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid image size %s", size)
+	}
+	width, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	height, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if width < 0 || height < 0 || width > maxImageSize || height > maxImageSize {
+		return 0, 0, fmt.Errorf("invalid image size %s", size)
+	}
+	return width, height, nil
+}
+
+// resizeImage resizes the given image so that it fits in width x height.
+// If crop is true, the image fills the whole size and is cropped around its
+// center instead. Images are never enlarged.
+//
+// The image is returned in its original format (GIF are converted
+// to PNG) with its content type. An error is returned if data is not an
+// image or if it has more than maxImagePixels pixels.
+func resizeImage(data []byte, width, height int, crop bool) ([]byte, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxImagePixels/config.Height {
+		return nil, "", fmt.Errorf("invalid image size %dx%d", config.Width, config.Height)
+	}
+	if width == 0 && height == 0 {
+		return data, "image/" + format, nil
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	bounds := img.Bounds()
+	switch {
+	case width == 0:
+		width = bounds.Dx() * height / bounds.Dy()
+	case height == 0:
+		height = bounds.Dy() * width / bounds.Dx()
+	}
+	if crop {
+		if width > bounds.Dx() || height > bounds.Dy() {
+			ratio := float64(width) / float64(height)
+			width, height = bounds.Dx(), int(float64(bounds.Dx())/ratio)
+			if height > bounds.Dy() {
+				width, height = int(float64(bounds.Dy())*ratio), bounds.Dy()
+			}
+		}
+		img = imaging.Fill(img, width, height, imaging.Center, imaging.Lanczos)
+	} else {
+		img = imaging.Fit(img, width, height, imaging.Lanczos)
+	}
+	return encodeImage(img, format)
+}
+
+// encodeImage encodes img in the given format
+func encodeImage(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var err error
+	contentType := "image/png"
+	if format == "jpeg" {
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), contentType, err
+}
+
+// placeholderImage returns a PNG placeholder image of the given size
+func placeholderImage(width, height int) []byte {
+	switch {
+	case width == 0 && height == 0:
+		width, height = defaultPlaceholderSize, defaultPlaceholderSize
+	case width == 0:
+		width = height
+	case height == 0:
+		height = width
+	}
+	data, _, err := encodeImage(imaging.New(width, height, placeholderColor), "png")
+	if err != nil {
+		log.Panic("Unable to encode placeholder image", "error", err)
+	}
+	return data
+}
+
+// webImage serves the image stored in a binary field of a record.
+//
+// The image can be resized with the 'size' path parameter (e.g. 128x128) or
+// with the 'width' and 'height' query parameters and cropped to the exact size
+// with 'crop=true'. A placeholder is returned if the field is empty or
+// does not hold an image, so that no other content is served.
+//
+// Responses have an ETag and can be cached for a long time if the
+// request has a 'unique' query parameter (e.g. the write date).
+func webImage(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	size := ctx.Param("size")
+	if size == "" && (ctx.Query("width") != "" || ctx.Query("height") != "") {
+		size = fmt.Sprintf("%sx%s", ctx.DefaultQuery("width", "0"), ctx.DefaultQuery("height", "0"))
+	}
+	width, height, err := parseImageSize(size)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	var (
+		data []byte
+		lErr error
+	)
+	err = ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		data, lErr = loadImage(env, ctx.Param("model"), id, ctx.Param("field"))
+	})
+	if err != nil || lErr != nil {
+		// We do not tell apart missing and forbidden records
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	crop := ctx.Query("crop") == "true"
+	etag := strconv.Quote(fmt.Sprintf("%x-%dx%d-%t", sha1.Sum(data), width, height, crop))
+	ctx.Header("ETag", etag)
+	if ctx.Query("unique") != "" {
+		ctx.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", uniqueImageMaxAge))
+	} else {
+		ctx.Header("Cache-Control", "private, max-age=0")
+	}
+	if ctx.GetHeader("If-None-Match") == etag {
+		ctx.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+	if len(data) == 0 {
+		ctx.Data(http.StatusOK, "image/png", placeholderImage(width, height))
+		return
+	}
+	img, contentType, err := resizeImage(data, width, height, crop)
+	if err != nil {
+		log.Warn("Unable to resize image", "model", ctx.Param("model"), "id", id, "field", ctx.Param("field"), "error", err)
+		img, contentType = placeholderImage(width, height), "image/png"
+	}
+	ctx.Data(http.StatusOK, contentType, img)
+}
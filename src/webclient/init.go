@@ -0,0 +1,22 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webclient
+
+import (
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("webclient")
+	grp := controllers.Registry.AddGroup("/web/session")
+	grp.AddController(http.MethodPost, "/get_session_info", getSessionInfo)
+	imgGrp := controllers.Registry.AddGroup("/web/image")
+	imgGrp.AddController(http.MethodGet, "/:model/:id/:field", webImage)
+	imgGrp.AddController(http.MethodGet, "/:model/:id/:field/:size", webImage)
+}
@@ -0,0 +1,235 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package webclient provides the endpoints that a web client calls at
+// startup to bootstrap its session, in the format expected by the Odoo
+// JS client.
+package webclient
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/i18n"
+	"github.com/hexya-erp/hexya/src/i18n/format"
+	"github.com/hexya-erp/hexya/src/menus"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+// A Company is a company a user is allowed to work with
+type Company struct {
+	ID   int64
+	Name string
+}
+
+// UserData holds the data of a user that the web client needs at startup
+type UserData struct {
+	Name               string
+	Login              string
+	PartnerID          int64
+	PartnerDisplayName string
+	Company            Company
+	AllowedCompanies   []Company
+	Context            *types.Context
+}
+
+// GetUserData returns the data of the given user.
+//
+// If it is not set, the session info only holds the uid and
+// the context of the Environment.
+var GetUserData func(env models.Environment, uid int64) UserData
+
+// SessionInfo is the data sent to the web client at startup
+type SessionInfo struct {
+	UID                int64          `json:"uid"`
+	IsSystem           bool           `json:"is_system"`
+	IsAdmin            bool           `json:"is_admin"`
+	UserContext        *types.Context `json:"user_context"`
+	DB                 string         `json:"db"`
+	ServerVersion      string         `json:"server_version"`
+	ServerVersionInfo  []interface{}  `json:"server_version_info"`
+	Name               string         `json:"name"`
+	Username           string         `json:"username"`
+	PartnerDisplayName string         `json:"partner_display_name"`
+	CompanyID          int64          `json:"company_id"`
+	PartnerID          int64          `json:"partner_id"`
+	UserCompanies      interface{}    `json:"user_companies"`
+	WebBaseURL         string         `json:"web.base.url"`
+	Modules            []string       `json:"module_list"`
+	Menus              *MenuData      `json:"menus"`
+	Impersonating      bool           `json:"impersonating"`
+	RealUID            int64          `json:"real_uid,omitempty"`
+	LangParameters     *i18n.Locale   `json:"lang_parameters"`
+}
+
+// NewSessionInfo returns the SessionInfo of the user of the given Environment
+func NewSessionInfo(env models.Environment) SessionInfo {
+	uid := env.Uid()
+	var data UserData
+	if GetUserData != nil {
+		data = GetUserData(env, uid)
+	}
+	if data.Context == nil {
+		data.Context = env.Context().Copy()
+	}
+	data.Context = data.Context.WithKey("uid", uid)
+	memberships := env.Memberships()
+	isAdmin := memberships.HasMembership(uid, security.GroupAdmin)
+	res := SessionInfo{
+		UID:                uid,
+		IsSystem:           uid == security.SuperUserID || isAdmin,
+		IsAdmin:            isAdmin,
+		UserContext:        data.Context,
+		DB:                 env.DBName(),
+		ServerVersion:      server.Version,
+		ServerVersionInfo:  server.VersionInfo,
+		Name:               data.Name,
+		Username:           data.Login,
+		PartnerDisplayName: data.PartnerDisplayName,
+		CompanyID:          data.Company.ID,
+		PartnerID:          data.PartnerID,
+		UserCompanies:      false,
+		Modules:            server.Modules.Names(),
+		Menus:              LoadMenus(memberships, uid, data.Context.GetString("lang")),
+		Impersonating:      env.RealUid() != uid,
+		LangParameters:     LangParameters(env, data.Context.GetString("lang")),
+	}
+	if res.Impersonating {
+		res.RealUID = env.RealUid()
+	}
+	if len(data.AllowedCompanies) > 1 {
+		allowed := make([][]interface{}, len(data.AllowedCompanies))
+		for i, company := range data.AllowedCompanies {
+			allowed[i] = []interface{}{company.ID, company.Name}
+		}
+		res.UserCompanies = map[string]interface{}{
+			"current_company":   []interface{}{data.Company.ID, data.Company.Name},
+			"allowed_companies": allowed,
+		}
+	}
+	return res
+}
+
+// LangParameters returns the direction and the formatting parameters of the
+// given language in the database of env, so that clients can render right to
+// left layouts and localized values. The same parameters should be sent with
+// the views returned to the client so that it can lay them out accordingly.
+func LangParameters(env models.Environment, lang string) *i18n.Locale {
+	return format.Locale(env, lang)
+}
+
+// MenuData is the representation of a menu sent to the web client
+type MenuData struct {
+	ID         interface{}   `json:"id"`
+	Name       string        `json:"name"`
+	XMLID      string        `json:"xmlid"`
+	ParentID   interface{}   `json:"parent_id"`
+	Sequence   uint8         `json:"sequence"`
+	Action     interface{}   `json:"action"`
+	WebIcon    interface{}   `json:"web_icon"`
+	Children   []*MenuData   `json:"children"`
+	AllMenuIDs []interface{} `json:"all_menu_ids,omitempty"`
+}
+
+// LoadMenus returns the root of the menu tree visible by the given user,
+// with names translated in the given language. memberships are the group
+// memberships of the database of the user.
+func LoadMenus(memberships *security.MembershipCollection, uid int64, lang string) *MenuData {
+	root := MenuData{
+		ID:       false,
+		Name:     "root",
+		ParentID: []interface{}{-1, ""},
+		Action:   false,
+		WebIcon:  false,
+		Children: menuTree(menus.Registry, memberships, uid, lang),
+	}
+	var collectIDs func([]*MenuData)
+	collectIDs = func(children []*MenuData) {
+		for _, child := range children {
+			root.AllMenuIDs = append(root.AllMenuIDs, child.ID)
+			collectIDs(child.Children)
+		}
+	}
+	collectIDs(root.Children)
+	return &root
+}
+
+// menuTree returns the menus of the given collection that are visible
+// by the given user, recursively.
+func menuTree(collection *menus.Collection, memberships *security.MembershipCollection, uid int64, lang string) []*MenuData {
+	res := make([]*MenuData, 0)
+	if collection == nil {
+		return res
+	}
+	for _, menu := range collection.Menus {
+		if !menuVisible(menu, memberships, uid) {
+			continue
+		}
+		children := menuTree(menu.Children, memberships, uid, lang)
+		if !menu.HasAction && menu.HasChildren && len(children) == 0 {
+			// Do not display folders without visible items
+			continue
+		}
+		data := MenuData{
+			ID:       menu.ID,
+			Name:     menu.TranslatedName(lang),
+			XMLID:    menu.XMLID,
+			ParentID: false,
+			Sequence: menu.Sequence,
+			Action:   false,
+			WebIcon:  false,
+			Children: children,
+		}
+		if menu.Parent != nil {
+			data.ParentID = []interface{}{menu.Parent.ID, menu.Parent.TranslatedName(lang)}
+		}
+		if menu.Action != nil {
+			data.Action = fmt.Sprintf("%s,%d", menu.Action.Type, menu.Action.ID)
+		}
+		if menu.WebIcon != "" {
+			data.WebIcon = menu.WebIcon
+		}
+		res = append(res, &data)
+	}
+	return res
+}
+
+// menuVisible returns true if the given menu can be seen by the given user,
+// that is if the user belongs to one of the groups of the menu's action.
+func menuVisible(menu *menus.Menu, memberships *security.MembershipCollection, uid int64) bool {
+	if uid == security.SuperUserID || menu.Action == nil || len(menu.Action.Groups) == 0 {
+		return true
+	}
+	for _, groupID := range menu.Action.Groups {
+		group := security.Registry.GetGroup(groupID)
+		if group != nil && memberships.HasMembership(uid, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// getSessionInfo returns the SessionInfo of the logged in user as JSON-RPC
+func getSessionInfo(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	if uid == 0 {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var info SessionInfo
+	err := ctx.ExecuteInNewEnvironment(uid, func(env models.Environment) {
+		info = NewSessionInfo(env)
+	})
+	if err != nil {
+		log.Panic("Unable to get session info", "uid", uid, "error", err)
+	}
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	info.WebBaseURL = fmt.Sprintf("%s://%s", scheme, ctx.Request.Host)
+	ctx.RPC(http.StatusOK, info)
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/hexya-erp/hexya/src/actions"
+	"github.com/hexya-erp/hexya/src/menus"
+	"github.com/hexya-erp/hexya/src/models/security"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadMenus(t *testing.T) {
+	group := security.Registry.NewGroup("webclient_test_group", "Web Client Test Group")
+	folder := &menus.Menu{ID: 1, XMLID: "menu_folder", Name: "Folder", Sequence: 10}
+	menus.Registry.Add(folder)
+	menus.Registry.Add(&menus.Menu{ID: 2, XMLID: "menu_public", Name: "Public", Parent: folder, Sequence: 10,
+		Action: &actions.Action{ID: 5, Type: actions.ActionActWindow}})
+	menus.Registry.Add(&menus.Menu{ID: 3, XMLID: "menu_restricted", Name: "Restricted", Parent: folder, Sequence: 20,
+		Action: &actions.Action{ID: 6, Type: actions.ActionActWindow, Groups: []string{group.ID}}})
+	lonely := &menus.Menu{ID: 4, XMLID: "menu_lonely", Name: "Lonely", Sequence: 20}
+	menus.Registry.Add(lonely)
+	menus.Registry.Add(&menus.Menu{ID: 5, XMLID: "menu_lonely_restricted", Name: "Restricted", Parent: lonely,
+		Action: &actions.Action{ID: 7, Type: actions.ActionActWindow, Groups: []string{group.ID}}})
+	Convey("Testing menus loading", t, func() {
+		Convey("Restricted menus and empty folders should be hidden", func() {
+			root := LoadMenus(security.Registry.ForDatabase(security.MainDatabase), 2, "")
+			So(root.Children, ShouldHaveLength, 1)
+			So(root.Children[0].Name, ShouldEqual, "Folder")
+			So(root.Children[0].Children, ShouldHaveLength, 1)
+			So(root.Children[0].Children[0].Action, ShouldEqual, "ir.actions.act_window,5")
+			So(root.Children[0].Children[0].ParentID, ShouldResemble, []interface{}{int64(1), "Folder"})
+			So(root.AllMenuIDs, ShouldResemble, []interface{}{int64(1), int64(2)})
+		})
+		Convey("Group members should see restricted menus", func() {
+			security.Registry.AddMembership(2, group)
+			root := LoadMenus(security.Registry.ForDatabase(security.MainDatabase), 2, "")
+			So(root.Children, ShouldHaveLength, 2)
+			So(root.Children[0].Children, ShouldHaveLength, 2)
+			So(root.AllMenuIDs, ShouldHaveLength, 5)
+			security.Registry.RemoveMembership(2, group)
+		})
+	})
+}
+
+func TestImages(t *testing.T) {
+	Convey("Testing image resizing", t, func() {
+		var buf bytes.Buffer
+		So(png.Encode(&buf, imaging.New(400, 200, placeholderColor)), ShouldBeNil)
+		data := buf.Bytes()
+		decode := func(data []byte) image.Rectangle {
+			img, _, err := image.Decode(bytes.NewReader(data))
+			So(err, ShouldBeNil)
+			return img.Bounds()
+		}
+		Convey("Sizes should be parsed", func() {
+			w, h, err := parseImageSize("128x0")
+			So(err, ShouldBeNil)
+			So(w, ShouldEqual, 128)
+			So(h, ShouldEqual, 0)
+			_, _, err = parseImageSize("128")
+			So(err, ShouldNotBeNil)
+			_, _, err = parseImageSize("100000x10")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Images should fit in the requested size", func() {
+			res, contentType, err := resizeImage(data, 100, 100, false)
+			So(err, ShouldBeNil)
+			So(contentType, ShouldEqual, "image/png")
+			So(decode(res).Dx(), ShouldEqual, 100)
+			So(decode(res).Dy(), ShouldEqual, 50)
+			res, _, _ = resizeImage(data, 0, 100, false)
+			So(decode(res).Dx(), ShouldEqual, 200)
+		})
+		Convey("Cropped images should fill the requested size", func() {
+			res, _, err := resizeImage(data, 100, 100, true)
+			So(err, ShouldBeNil)
+			So(decode(res).Dx(), ShouldEqual, 100)
+			So(decode(res).Dy(), ShouldEqual, 100)
+		})
+		Convey("Images should not be enlarged", func() {
+			res, _, _ := resizeImage(data, 800, 800, false)
+			So(decode(res).Dx(), ShouldEqual, 400)
+			res, _, _ = resizeImage(data, 800, 800, true)
+			So(decode(res).Dx(), ShouldEqual, 200)
+			So(decode(res).Dy(), ShouldEqual, 200)
+		})
+		Convey("Non image data should be rejected", func() {
+			_, _, err := resizeImage([]byte("<svg onload=\"alert(1)\"></svg>"), 0, 0, false)
+			So(err, ShouldNotBeNil)
+			_, _, err = resizeImage([]byte("<html><script>alert(1)</script></html>"), 100, 100, false)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Original images should keep their decoded content type", func() {
+			res, contentType, err := resizeImage(data, 0, 0, false)
+			So(err, ShouldBeNil)
+			So(contentType, ShouldEqual, "image/png")
+			So(res, ShouldResemble, data)
+		})
+		Convey("Images with too many pixels should not be decoded", func() {
+			var big bytes.Buffer
+			So(png.Encode(&big, imaging.New(1, 1, placeholderColor)), ShouldBeNil)
+			header := big.Bytes()
+			// Overwrite the IHDR width and height with 100000x100000
+			binary.BigEndian.PutUint32(header[16:20], 100000)
+			binary.BigEndian.PutUint32(header[20:24], 100000)
+			_, _, err := resizeImage(header, 100, 100, false)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Placeholder should have the requested size", func() {
+			So(decode(placeholderImage(0, 0)).Dx(), ShouldEqual, defaultPlaceholderSize)
+			So(decode(placeholderImage(64, 0)).Dy(), ShouldEqual, 64)
+		})
+	})
+}
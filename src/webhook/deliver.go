@@ -0,0 +1,218 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+const (
+	// deliveryPeriod is the time between two runs of the delivery worker
+	deliveryPeriod = 30 * time.Second
+	// deliveryTimeout is the maximum time to post a payload
+	deliveryTimeout = 10 * time.Second
+	// deliveryBatch is the maximum number of deliveries sent by a run of the worker for each database
+	deliveryBatch = 100
+	// maxResponseLength is the maximum number of bytes of the responses kept in the delivery log
+	maxResponseLength = 4096
+	// firstRetryDelay is the delay before the first retry of a failed delivery
+	firstRetryDelay = time.Minute
+	// maxRetryDelay is the maximum delay between two attempts of a delivery
+	maxRetryDelay = 24 * time.Hour
+)
+
+// Headers of the requests posted to webhooks
+const (
+	HeaderEvent     = "X-Hexya-Event"
+	HeaderDelivery  = "X-Hexya-Delivery"
+	HeaderTimestamp = "X-Hexya-Timestamp"
+	HeaderSignature = "X-Hexya-Signature"
+)
+
+// client is the HTTP client with which payloads are posted
+var client = &http.Client{Timeout: deliveryTimeout}
+
+// Sign returns the signature of the given body sent at the given
+// timestamp (in seconds since the epoch) with the given secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature returns true if signature is the signature of the given
+// body sent at the given timestamp with the given secret. The signature may
+// be given with its "sha256=" prefix, as in the X-Hexya-Signature header.
+//
+// Receivers should also reject timestamps that are too old to prevent replays.
+func VerifySignature(secret string, timestamp int64, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(strings.TrimPrefix(signature, "sha256=")))
+}
+
+// A delivery is a pending delivery with the data of its webhook
+type delivery struct {
+	id          int64
+	event       string
+	payload     string
+	attempts    int
+	url         string
+	secret      string
+	maxAttempts int
+}
+
+// A deliveryResult is the result of an attempt of a delivery
+type deliveryResult struct {
+	status   int
+	response string
+	err      error
+}
+
+// send posts the payload of the given delivery to the URL of its webhook
+func send(d delivery) deliveryResult {
+	body := []byte(d.payload)
+	req, err := http.NewRequest(http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return deliveryResult{err: err}
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(d.id, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(d.secret, timestamp, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return deliveryResult{err: err}
+	}
+	defer resp.Body.Close()
+	response, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseLength))
+	res := deliveryResult{status: resp.StatusCode, response: string(response), err: err}
+	if res.err == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		res.err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return res
+}
+
+// backoff returns the delay before the next attempt of a
+// delivery that failed after the given number of attempts.
+func backoff(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// pendingDeliveries returns the deliveries of the active webhooks
+// of the given database that must be attempted now.
+func pendingDeliveries(dbName string) ([]delivery, error) {
+	var res []delivery
+	err := models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		webhooks := env.Pool(webhookModel)
+		whMI := webhooks.Model()
+		webhooks = webhooks.Search(whMI.Field(whMI.FieldName("Active")).Equals(true))
+		if webhooks.IsEmpty() {
+			return
+		}
+		deliveries := env.Pool(deliveryModel)
+		mi := deliveries.Model()
+		deliveries = deliveries.Search(mi.Field(mi.FieldName("State")).Equals(StatePending).
+			And().Field(mi.FieldName("NextAttempt")).LowerOrEqual(dates.Now()).
+			And().Field(mi.FieldName("Webhook")).In(webhooks.Ids())).
+			OrderBy("NextAttempt", "ID").Limit(deliveryBatch)
+		for _, rec := range deliveries.Records() {
+			wh := rec.Get(mi.FieldName("Webhook")).(models.RecordSet).Collection()
+			res = append(res, delivery{
+				id:          rec.Ids()[0],
+				event:       rec.Get(mi.FieldName("Event")).(string),
+				payload:     rec.Get(mi.FieldName("Payload")).(string),
+				attempts:    int(rec.Get(mi.FieldName("Attempts")).(int64)),
+				url:         wh.Get(whMI.FieldName("URL")).(string),
+				secret:      wh.Get(whMI.FieldName("Secret")).(string),
+				maxAttempts: int(wh.Get(whMI.FieldName("MaxAttempts")).(int64)),
+			})
+		}
+	})
+	return res, err
+}
+
+// recordResult updates the delivery log with the result of an attempt of the given delivery
+func recordResult(dbName string, d delivery, res deliveryResult) error {
+	return models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		rec := env.Pool(deliveryModel).Call("BrowseOne", d.id).(models.RecordSet).Collection()
+		mi := rec.Model()
+		attempts := d.attempts + 1
+		data := models.NewModelData(mi).
+			Set(mi.FieldName("Attempts"), attempts).
+			Set(mi.FieldName("LastAttempt"), dates.Now()).
+			Set(mi.FieldName("ResponseStatus"), res.status).
+			Set(mi.FieldName("Response"), res.response).
+			Set(mi.FieldName("Error"), "")
+		switch {
+		case res.err == nil:
+			data.Set(mi.FieldName("State"), StateDone)
+		case attempts >= d.maxAttempts:
+			data.Set(mi.FieldName("State"), StateFailed).
+				Set(mi.FieldName("Error"), res.err.Error())
+		default:
+			data.Set(mi.FieldName("NextAttempt"), dates.Now().Add(backoff(attempts))).
+				Set(mi.FieldName("Error"), res.err.Error())
+		}
+		rec.Call("Write", data)
+	})
+}
+
+// DeliverPending sends the pending deliveries of the given database.
+//
+// Payloads are posted outside of any transaction, and the result of
+// each attempt is recorded in the delivery log in its own transaction.
+func DeliverPending(dbName string) error {
+	deliveries, err := pendingDeliveries(dbName)
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		res := send(d)
+		if res.err != nil {
+			log.Warn("Unable to deliver webhook payload", "database", dbName, "delivery", d.id, "url", d.url, "error", res.err)
+		}
+		if err := recordResult(dbName, d, res); err != nil {
+			log.Warn("Unable to record webhook delivery", "database", dbName, "delivery", d.id, "error", err)
+		}
+	}
+	return nil
+}
+
+// deliverAll sends the pending deliveries of all connected databases
+func deliverAll() {
+	for _, dbName := range models.ConnectedDBNames() {
+		if err := DeliverPending(dbName); err != nil {
+			log.Warn("Unable to list webhook deliveries", "database", dbName, "error", err)
+		}
+	}
+}
+
+func init() {
+	models.RegisterWorker(models.NewWorkerFunction(deliverAll, deliveryPeriod))
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package webhook is a Hexya module that notifies external
+// applications of the changes of records through webhooks.
+//
+// Administrators subscribe a URL to the creation, the update, the deletion or
+// the state transitions of the records of a model with a Webhook record.
+// When such an event occurs, a WebhookDelivery is created in the same
+// transaction with the JSON payload of the event, so that only committed
+// changes are notified. Deliveries are then sent asynchronously by a worker,
+// and retried with an exponential backoff until they are acknowledged with a
+// 2xx status or until the maximum number of attempts of the webhook is reached.
+//
+// Payloads are signed with the secret of the webhook. The signature is the
+// hex encoded HMAC-SHA256 of the timestamp header, a dot and the body, sent as
+//
+//	X-Hexya-Signature: sha256=<signature>
+//
+// and can be checked by receivers with VerifySignature.
+//
+// Conversely, modules can register inbound endpoints with RegisterEndpoint
+// to receive the callbacks of third party applications (e.g. payment
+// providers) at /webhook/in/<endpoint>/<token>. Requests are authenticated
+// by the token of a WebhookToken of the endpoint and optionally by their
+// signature, and are processed in an Environment of the technical user
+// of the token, which is required.
+package webhook
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+// Module data declaration
+const (
+	MODULE_NAME string = "webhook"
+)
+
+var log logging.Logger
+
+func init() {
+	log = logging.GetLogger("webhook")
+	declareModels()
+	extendCommonMixin()
+	server.RegisterModule(&server.Module{
+		Name:    MODULE_NAME,
+		PreInit: addUserFields,
+	})
+}
@@ -0,0 +1,303 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+// Events to which webhooks can be subscribed
+const (
+	EventCreate     = "create"
+	EventWrite      = "write"
+	EventUnlink     = "unlink"
+	EventTransition = "transition"
+)
+
+// States of webhook deliveries
+const (
+	StatePending = "pending"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+const (
+	webhookModel  = "Webhook"
+	deliveryModel = "WebhookDelivery"
+	// defaultMaxAttempts is the default number of attempts of a delivery
+	defaultMaxAttempts = 5
+	// subscriptionsTTL is the time after which the subscriptions of a database are reloaded
+	subscriptionsTTL = time.Minute
+)
+
+// A Payload is the JSON body posted to the URL of a webhook
+type Payload struct {
+	Event string `json:"event"`
+	Model string `json:"model"`
+	ID    int64  `json:"id"`
+	// Values are the values given to the record on creation or update.
+	// Relation fields are given as slices of ids.
+	Values     map[string]interface{} `json:"values,omitempty"`
+	Transition *Transition            `json:"transition,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+}
+
+// A Transition is the change of the state field of a record
+type Transition struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// A subscription is an active webhook subscribed to a model
+type subscription struct {
+	id           int64
+	onCreate     bool
+	onWrite      bool
+	onUnlink     bool
+	onTransition bool
+	stateField   string
+}
+
+// subscriptions caches the active webhooks of each database by model
+var subscriptions struct {
+	sync.Mutex
+	byDB map[string]*dbSubscriptions
+}
+
+// dbSubscriptions are the active webhooks of a database by model
+type dbSubscriptions struct {
+	loaded  time.Time
+	byModel map[string][]subscription
+}
+
+// newSecret returns a new random secret for signing payloads
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("Unable to generate webhook secret", "error", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// invalidateSubscriptions clears the cached subscriptions of the given database
+func invalidateSubscriptions(dbName string) {
+	subscriptions.Lock()
+	defer subscriptions.Unlock()
+	delete(subscriptions.byDB, dbName)
+}
+
+// subscriptionsFor returns the active webhooks subscribed to the model of rc.
+// The subscriptions of the database are loaded from the environment of rc if
+// they are not cached yet.
+func subscriptionsFor(rc *models.RecordCollection) []subscription {
+	dbName := rc.Env().DBName()
+	subscriptions.Lock()
+	subs, ok := subscriptions.byDB[dbName]
+	subscriptions.Unlock()
+	if !ok || time.Since(subs.loaded) > subscriptionsTTL {
+		subs = loadSubscriptions(rc.Env())
+		subscriptions.Lock()
+		if subscriptions.byDB == nil {
+			subscriptions.byDB = make(map[string]*dbSubscriptions)
+		}
+		subscriptions.byDB[dbName] = subs
+		subscriptions.Unlock()
+	}
+	return subs.byModel[rc.ModelName()]
+}
+
+// loadSubscriptions loads the active webhooks of the database of env
+func loadSubscriptions(env models.Environment) *dbSubscriptions {
+	res := &dbSubscriptions{
+		loaded:  time.Now(),
+		byModel: make(map[string][]subscription),
+	}
+	webhooks := env.Pool(webhookModel).Sudo()
+	mi := webhooks.Model()
+	for _, rec := range webhooks.Search(mi.Field(mi.FieldName("Active")).Equals(true)).Records() {
+		modelName := rec.Get(mi.FieldName("Model")).(string)
+		res.byModel[modelName] = append(res.byModel[modelName], subscription{
+			id:           rec.Ids()[0],
+			onCreate:     rec.Get(mi.FieldName("OnCreate")).(bool),
+			onWrite:      rec.Get(mi.FieldName("OnWrite")).(bool),
+			onUnlink:     rec.Get(mi.FieldName("OnUnlink")).(bool),
+			onTransition: rec.Get(mi.FieldName("OnTransition")).(bool),
+			stateField:   rec.Get(mi.FieldName("StateField")).(string),
+		})
+	}
+	return res
+}
+
+// notifiable returns true if the changes of the records of rc may be notified
+func notifiable(rc *models.RecordCollection) bool {
+	switch {
+	case rc.ModelName() == webhookModel, rc.ModelName() == deliveryModel, rc.ModelName() == tokenModel:
+		return false
+	case rc.Model().IsTransient(), rc.Model().IsMixin():
+		return false
+	}
+	return true
+}
+
+// payloadValues returns the given values with JSON field names
+// of the model of rc and with relations converted to ids.
+func payloadValues(rc *models.RecordCollection, values models.FieldMap) map[string]interface{} {
+	res := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if fi, ok := rc.Model().Fields().Get(key); ok {
+			key = fi.JSON()
+		}
+		res[key] = payloadValue(value)
+	}
+	return res
+}
+
+// payloadValue returns the given field value as it is marshalled in payloads
+func payloadValue(value interface{}) interface{} {
+	switch val := value.(type) {
+	case *models.RecordCollection:
+		if val == nil {
+			return []int64{}
+		}
+		return payloadValue(models.RecordSet(val))
+	case models.RecordSet:
+		ids := val.Collection().Ids()
+		if ids == nil {
+			ids = []int64{}
+		}
+		return ids
+	}
+	return value
+}
+
+// enqueue creates a pending delivery of the given payload for the given webhook
+func enqueue(rc *models.RecordCollection, webhookID int64, payload Payload) {
+	payload.Model = rc.ModelName()
+	payload.Timestamp = time.Now().UTC()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Panic("Unable to marshal webhook payload", "webhook", webhookID, "model", payload.Model, "id", payload.ID, "error", err)
+	}
+	deliveries := rc.Env().Pool(deliveryModel).Sudo()
+	mi := deliveries.Model()
+	deliveries.Call("Create", models.NewModelData(mi).
+		Set(mi.FieldName("Webhook"), rc.Env().Pool(webhookModel).Call("BrowseOne", webhookID)).
+		Set(mi.FieldName("Event"), payload.Event).
+		Set(mi.FieldName("ResModel"), payload.Model).
+		Set(mi.FieldName("ResID"), payload.ID).
+		Set(mi.FieldName("Payload"), string(body)).
+		Set(mi.FieldName("NextAttempt"), dates.Now()))
+}
+
+// states returns the values of the given field for each record of rc
+func states(rc *models.RecordCollection, field string) map[int64]interface{} {
+	res := make(map[int64]interface{})
+	for _, rec := range rc.Records() {
+		res[rec.Ids()[0]] = payloadValue(rec.Get(rc.Model().FieldName(field)))
+	}
+	return res
+}
+
+// extendCommonMixin extends the CRUD methods of all models
+// to create the deliveries of the subscribed webhooks.
+func extendCommonMixin() {
+	commonMixin := models.Registry.MustGet("CommonMixin")
+	commonMixin.Methods().MustGet("Create").Extend(commonMixin_Create)
+	commonMixin.Methods().MustGet("Write").Extend(commonMixin_Write)
+	commonMixin.Methods().MustGet("Unlink").Extend(commonMixin_Unlink)
+}
+
+// commonMixin_Create notifies the webhooks subscribed to the creation of records
+func commonMixin_Create(rc *models.RecordCollection, data models.RecordData) *models.RecordCollection {
+	res := rc.Super().Call("Create", data).(models.RecordSet).Collection()
+	if !notifiable(rc) {
+		return res
+	}
+	for _, sub := range subscriptionsFor(rc) {
+		if !sub.onCreate {
+			continue
+		}
+		enqueue(res, sub.id, Payload{
+			Event:  EventCreate,
+			ID:     res.Ids()[0],
+			Values: payloadValues(res, data.Underlying().FieldMap),
+		})
+	}
+	return res
+}
+
+// commonMixin_Write notifies the webhooks subscribed to the update of
+// records, and to the transitions of their state field.
+func commonMixin_Write(rc *models.RecordCollection, data models.RecordData) bool {
+	if !notifiable(rc) || rc.IsEmpty() {
+		return rc.Super().Call("Write", data).(bool)
+	}
+	subs := subscriptionsFor(rc)
+	values := data.Underlying().FieldMap
+	before := make(map[string]map[int64]interface{})
+	for _, sub := range subs {
+		if !sub.onTransition || before[sub.stateField] != nil {
+			continue
+		}
+		if _, ok := values.Get(rc.Model().FieldName(sub.stateField)); ok {
+			before[sub.stateField] = states(rc, sub.stateField)
+		}
+	}
+	res := rc.Super().Call("Write", data).(bool)
+	after := make(map[string]map[int64]interface{})
+	for field := range before {
+		after[field] = states(rc, field)
+	}
+	for _, sub := range subs {
+		for _, id := range rc.Ids() {
+			if sub.onWrite {
+				enqueue(rc, sub.id, Payload{
+					Event:  EventWrite,
+					ID:     id,
+					Values: payloadValues(rc, values),
+				})
+			}
+			if !sub.onTransition || before[sub.stateField] == nil {
+				continue
+			}
+			from, to := before[sub.stateField][id], after[sub.stateField][id]
+			if reflect.DeepEqual(from, to) {
+				continue
+			}
+			enqueue(rc, sub.id, Payload{
+				Event:      EventTransition,
+				ID:         id,
+				Transition: &Transition{Field: rc.Model().FieldName(sub.stateField).JSON(), From: from, To: to},
+			})
+		}
+	}
+	return res
+}
+
+// commonMixin_Unlink notifies the webhooks subscribed to the deletion of records
+func commonMixin_Unlink(rc *models.RecordCollection) int64 {
+	if !notifiable(rc) || rc.IsEmpty() {
+		return rc.Super().Call("Unlink").(int64)
+	}
+	ids := rc.Ids()
+	res := rc.Super().Call("Unlink").(int64)
+	for _, sub := range subscriptionsFor(rc) {
+		if !sub.onUnlink {
+			continue
+		}
+		for _, id := range ids {
+			enqueue(rc, sub.id, Payload{Event: EventUnlink, ID: id})
+		}
+	}
+	return res
+}
@@ -0,0 +1,248 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/server"
+)
+
+const (
+	tokenModel = "WebhookToken"
+	// maxInboundBodySize is the maximum size of the body of inbound requests
+	maxInboundBodySize = 1 << 20
+	// TimestampTolerance is the maximum difference between the timestamp of
+	// a signed request and the time at which it is received.
+	TimestampTolerance = 5 * time.Minute
+)
+
+// An InboundRequest is a request received on an inbound endpoint
+type InboundRequest struct {
+	// Endpoint is the name of the endpoint on which the request is received
+	Endpoint string
+	// TokenID is the ID of the WebhookToken with which the request is authenticated
+	TokenID int64
+	Method  string
+	Header  http.Header
+	Query   url.Values
+	Body    []byte
+}
+
+// An InboundHandler processes the requests received on an endpoint, in an
+// Environment of the user of the token of the request. The returned value is
+// sent back as JSON, or a 204 No Content status is returned if it is nil.
+//
+// Handlers should panic if the request cannot be processed, so that the
+// transaction is rolled back and a 500 status is returned.
+type InboundHandler func(env models.Environment, req *InboundRequest) interface{}
+
+// A SignatureVerifier returns true if the given request is signed with the given secret
+type SignatureVerifier func(req *InboundRequest, secret string) bool
+
+// An Endpoint receives the callbacks of a third party application at
+//
+//	/webhook/in/<name>/<token>
+//
+// where token is the Token of an active WebhookToken of the endpoint.
+type Endpoint struct {
+	Name    string
+	Handler InboundHandler
+	// Verify checks the signature of the requests with the secret of the
+	// token. If Verify is nil, requests are only authenticated by their token.
+	Verify SignatureVerifier
+}
+
+// endpoints are the registered inbound endpoints by name
+var endpoints struct {
+	sync.RWMutex
+	byName map[string]Endpoint
+}
+
+// RegisterEndpoint registers the given inbound endpoint.
+// It panics if an endpoint with the same name is already registered.
+func RegisterEndpoint(endpoint Endpoint) {
+	endpoints.Lock()
+	defer endpoints.Unlock()
+	if endpoint.Name == "" || endpoint.Handler == nil {
+		log.Panic("Inbound endpoints must have a name and a handler", "endpoint", endpoint.Name)
+	}
+	if _, exists := endpoints.byName[endpoint.Name]; exists {
+		log.Panic("Inbound endpoint already registered", "endpoint", endpoint.Name)
+	}
+	if endpoints.byName == nil {
+		endpoints.byName = make(map[string]Endpoint)
+	}
+	endpoints.byName[endpoint.Name] = endpoint
+}
+
+// getEndpoint returns the registered endpoint with the given name
+func getEndpoint(name string) (Endpoint, bool) {
+	endpoints.RLock()
+	defer endpoints.RUnlock()
+	endpoint, ok := endpoints.byName[name]
+	return endpoint, ok
+}
+
+// VerifyHMAC returns true if signature is the HMAC of body with the given
+// secret and hash function. The signature may be hex or base64 encoded.
+func VerifyHMAC(h func() hash.Hash, secret string, body []byte, signature string) bool {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	if sig, err := hex.DecodeString(signature); err == nil && hmac.Equal(sig, expected) {
+		return true
+	}
+	if sig, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(sig, expected) {
+		return true
+	}
+	return false
+}
+
+// HMACVerifier returns a SignatureVerifier that checks that the given header
+// is the HMAC of the body of the request with the given hash function. The
+// given prefix (e.g. "sha256=") is removed from the header before checking.
+func HMACVerifier(h func() hash.Hash, header, prefix string) SignatureVerifier {
+	return func(req *InboundRequest, secret string) bool {
+		signature := req.Header.Get(header)
+		if signature == "" || !strings.HasPrefix(signature, prefix) {
+			return false
+		}
+		return VerifyHMAC(h, secret, req.Body, strings.TrimPrefix(signature, prefix))
+	}
+}
+
+// HexyaVerifier is a SignatureVerifier of the requests signed as the webhook
+// payloads of this module, so that Hexya servers can notify each other.
+// Requests whose timestamp is not within TimestampTolerance are rejected.
+func HexyaVerifier(req *InboundRequest, secret string) bool {
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return false
+	}
+	if delay := time.Since(time.Unix(timestamp, 0)); delay > TimestampTolerance || delay < -TimestampTolerance {
+		return false
+	}
+	return VerifySignature(secret, timestamp, req.Body, req.Header.Get(HeaderSignature))
+}
+
+// webhookToken_CheckEndpoint checks that tokens are given to registered endpoints
+func webhookToken_CheckEndpoint(rs *models.RecordCollection) {
+	for _, rec := range rs.Records() {
+		name := rec.Get(rs.Model().FieldName("Endpoint")).(string)
+		if _, ok := getEndpoint(name); !ok {
+			log.Panic("Unknown inbound endpoint", "token", rec.Ids()[0], "endpoint", name)
+		}
+	}
+}
+
+// webhookToken_RegenerateToken replaces the tokens by new random tokens, so
+// that the former URLs of the endpoints are revoked.
+func webhookToken_RegenerateToken(rs *models.RecordCollection) {
+	for _, rec := range rs.Records() {
+		rec.Set(rs.Model().FieldName("Token"), newSecret())
+	}
+}
+
+// findToken returns the ID, the secret and the user of the
+// active WebhookToken of the given endpoint with the given token.
+//
+// Tokens without user are not returned, so that inbound requests are
+// never processed as the superuser by default.
+func findToken(dbName, endpoint, token string) (id int64, secret string, uid int64, err error) {
+	err = models.ExecuteInTenantEnvironment(dbName, security.SuperUserID, func(env models.Environment) {
+		tokens := env.Pool(tokenModel)
+		mi := tokens.Model()
+		rec := tokens.Search(mi.Field(mi.FieldName("Token")).Equals(token).
+			And().Field(mi.FieldName("Endpoint")).Equals(endpoint).
+			And().Field(mi.FieldName("Active")).Equals(true)).Limit(1)
+		if rec.IsEmpty() {
+			return
+		}
+		var user models.RecordSet
+		if _, exists := mi.Fields().Get("User"); exists {
+			user, _ = rec.Get(mi.FieldName("User")).(models.RecordSet)
+		}
+		if user == nil || user.IsEmpty() {
+			log.Warn("Inbound token has no technical user", "token", rec.Ids()[0], "endpoint", endpoint)
+			return
+		}
+		id = rec.Ids()[0]
+		secret = rec.Get(mi.FieldName("Secret")).(string)
+		uid = user.Ids()[0]
+	})
+	return
+}
+
+// receive is the controller of inbound endpoints. It authenticates the
+// request with its token and signature, and then calls the handler of the
+// endpoint in an Environment of the user of the token.
+func receive(ctx *server.Context) {
+	endpoint, ok := getEndpoint(ctx.Param("endpoint"))
+	token := ctx.Param("token")
+	if !ok || token == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request.Body, maxInboundBodySize+1))
+	if err != nil || len(body) > maxInboundBodySize {
+		ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return
+	}
+	tokenID, secret, uid, err := findToken(ctx.DBName(), endpoint.Name, token)
+	if err != nil || tokenID == 0 {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	req := &InboundRequest{
+		Endpoint: endpoint.Name,
+		TokenID:  tokenID,
+		Method:   ctx.Request.Method,
+		Header:   ctx.Request.Header,
+		Query:    ctx.Request.URL.Query(),
+		Body:     body,
+	}
+	if endpoint.Verify != nil && !endpoint.Verify(req, secret) {
+		log.Warn("Invalid signature of inbound request", "endpoint", endpoint.Name, "token", tokenID, "ip", ctx.ClientIP())
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var res interface{}
+	err = models.ExecuteInTenantEnvironment(ctx.DBName(), uid, func(env models.Environment) {
+		res = endpoint.Handler(env, req)
+		env.Pool(tokenModel).Call("BrowseOne", tokenID).(models.RecordSet).Collection().Sudo().
+			Set(models.Registry.MustGet(tokenModel).FieldName("LastCall"), dates.Now())
+	})
+	if err != nil {
+		log.Warn("Unable to process inbound request", "endpoint", endpoint.Name, "token", tokenID, "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if res == nil {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup("/webhook/in")
+	grp.AddController(http.MethodGet, "/:endpoint/:token", receive)
+	grp.AddController(http.MethodPost, "/:endpoint/:token", receive)
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webhook
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+	"github.com/hexya-erp/hexya/src/models/types"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+)
+
+func declareModels() {
+	webhook := models.NewModel("Webhook")
+	webhook.SetDefaultOrder("Name")
+	webhook.NewMethod("CheckModel", webhook_CheckModel)
+	webhook.NewMethod("RegenerateSecret", webhook_RegenerateSecret)
+	webhook.AddEmptyMethod("Create").Extend(webhook_Create)
+	webhook.AddEmptyMethod("Write").Extend(webhook_Write)
+	webhook.AddEmptyMethod("Unlink").Extend(webhook_Unlink)
+	webhook.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true},
+		"Model": fields.Char{Required: true, Index: true, Constraint: webhook.Methods().MustGet("CheckModel"),
+			Help: "Name of the model whose records are notified (e.g. Partner)"},
+		"URL": fields.Char{String: "URL", Required: true,
+			Help: "URL to which the JSON payloads of the events are posted"},
+		"Secret": fields.Char{NoCopy: true,
+			Help: "Secret with which the payloads are signed",
+			Default: func(env models.Environment) interface{} {
+				return newSecret()
+			}},
+		"Active":       fields.Boolean{Default: models.DefaultValue(true)},
+		"OnCreate":     fields.Boolean{String: "On Creation"},
+		"OnWrite":      fields.Boolean{String: "On Update"},
+		"OnUnlink":     fields.Boolean{String: "On Deletion"},
+		"OnTransition": fields.Boolean{String: "On State Transition", Constraint: webhook.Methods().MustGet("CheckModel")},
+		"StateField": fields.Char{Default: models.DefaultValue("State"), Constraint: webhook.Methods().MustGet("CheckModel"),
+			Help: "Field of the model whose changes are state transitions"},
+		"MaxAttempts": fields.Integer{Default: models.DefaultValue(defaultMaxAttempts),
+			Help: "Number of attempts after which a delivery is considered as failed"},
+	})
+
+	delivery := models.NewModel("WebhookDelivery")
+	delivery.SetDefaultOrder("ID desc")
+	delivery.NewMethod("Retry", webhookDelivery_Retry)
+	delivery.AddFields(map[string]models.FieldDefinition{
+		"Webhook":  fields.Many2One{RelationModel: webhook, Required: true, Index: true, OnDelete: models.Cascade},
+		"Event":    fields.Char{Required: true},
+		"ResModel": fields.Char{String: "Related Document Model", Index: true},
+		"ResID":    fields.Integer{String: "Related Document ID", Index: true},
+		"Payload":  fields.Text{},
+		"State": fields.Selection{Required: true, Index: true,
+			Selection: types.Selection{StatePending: "Pending", StateDone: "Delivered", StateFailed: "Failed"},
+			Default:   models.DefaultValue(StatePending)},
+		"Attempts":       fields.Integer{},
+		"NextAttempt":    fields.DateTime{Index: true},
+		"LastAttempt":    fields.DateTime{},
+		"ResponseStatus": fields.Integer{},
+		"Response":       fields.Text{},
+		"Error":          fields.Char{},
+	})
+	webhook.AddFields(map[string]models.FieldDefinition{
+		"Deliveries": fields.One2Many{RelationModel: delivery, ReverseFK: "Webhook"},
+	})
+
+	token := models.NewModel("WebhookToken")
+	token.SetDefaultOrder("Endpoint", "Name")
+	token.NewMethod("CheckEndpoint", webhookToken_CheckEndpoint)
+	token.NewMethod("RegenerateToken", webhookToken_RegenerateToken)
+	token.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{Required: true},
+		"Endpoint": fields.Char{Required: true, Index: true, Constraint: token.Methods().MustGet("CheckEndpoint"),
+			Help: "Name of the inbound endpoint that can be called with this token"},
+		"Token": fields.Char{Required: true, Unique: true, NoCopy: true,
+			Help: "Secret token of the URL of the endpoint",
+			Default: func(env models.Environment) interface{} {
+				return newSecret()
+			}},
+		"Secret": fields.Char{NoCopy: true,
+			Help: "Secret shared with the third party application to verify the signature of its requests"},
+		"Active":   fields.Boolean{Default: models.DefaultValue(true)},
+		"LastCall": fields.DateTime{NoCopy: true},
+	})
+}
+
+// addUserFields adds the technical user of inbound tokens. Without a User
+// model, tokens have no user and inbound requests are refused.
+func addUserFields() {
+	user, ok := models.Registry.Get("User")
+	if !ok {
+		return
+	}
+	models.Registry.MustGet("WebhookToken").AddFields(map[string]models.FieldDefinition{
+		"User": fields.Many2One{String: "Technical User", RelationModel: user, Required: true, OnDelete: models.Restrict,
+			Help: "User under which the requests of the endpoint are processed"},
+	})
+}
+
+// webhook_CheckModel checks that webhooks are subscribed to existing models and state fields
+func webhook_CheckModel(rs *models.RecordCollection) {
+	mi := rs.Model()
+	for _, rec := range rs.Records() {
+		modelName := rec.Get(mi.FieldName("Model")).(string)
+		model, ok := models.Registry.Get(modelName)
+		if !ok || model.IsMixin() || model.IsTransient() {
+			log.Panic("Unknown model for webhook", "webhook", rec.Ids()[0], "model", modelName)
+		}
+		if model.Name() == webhookModel || model.Name() == deliveryModel || model.Name() == tokenModel {
+			log.Panic("Webhooks cannot be subscribed to webhook models", "webhook", rec.Ids()[0])
+		}
+		if !rec.Get(mi.FieldName("OnTransition")).(bool) {
+			continue
+		}
+		stateField := rec.Get(mi.FieldName("StateField")).(string)
+		if _, ok := model.Fields().Get(stateField); !ok {
+			log.Panic("Unknown state field for webhook", "webhook", rec.Ids()[0], "model", modelName, "field", stateField)
+		}
+	}
+}
+
+// webhook_RegenerateSecret replaces the secret of the webhooks by a new random secret
+func webhook_RegenerateSecret(rs *models.RecordCollection) {
+	for _, rec := range rs.Records() {
+		rec.Set(rs.Model().FieldName("Secret"), newSecret())
+	}
+}
+
+// webhook_Create invalidates the cache of subscriptions
+func webhook_Create(rc *models.RecordCollection, data models.RecordData) *models.RecordCollection {
+	res := rc.Super().Call("Create", data).(models.RecordSet).Collection()
+	invalidateSubscriptions(rc.Env().DBName())
+	return res
+}
+
+// webhook_Write invalidates the cache of subscriptions
+func webhook_Write(rc *models.RecordCollection, data models.RecordData) bool {
+	res := rc.Super().Call("Write", data).(bool)
+	invalidateSubscriptions(rc.Env().DBName())
+	return res
+}
+
+// webhook_Unlink invalidates the cache of subscriptions
+func webhook_Unlink(rc *models.RecordCollection) int64 {
+	res := rc.Super().Call("Unlink").(int64)
+	invalidateSubscriptions(rc.Env().DBName())
+	return res
+}
+
+// webhookDelivery_Retry sends the deliveries again with the next run of the delivery worker
+func webhookDelivery_Retry(rs *models.RecordCollection) {
+	rs.Call("Write", models.NewModelData(rs.Model()).
+		Set(rs.Model().FieldName("State"), StatePending).
+		Set(rs.Model().FieldName("Attempts"), 0).
+		Set(rs.Model().FieldName("NextAttempt"), dates.Now()))
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hexya-erp/hexya/src/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSignature(t *testing.T) {
+	Convey("Testing payload signatures", t, func() {
+		body := []byte(`{"event":"create","model":"Partner","id":1}`)
+		signature := Sign("secret", 1552640400, body)
+		So(signature, ShouldHaveLength, 64)
+		So(VerifySignature("secret", 1552640400, body, signature), ShouldBeTrue)
+		So(VerifySignature("secret", 1552640400, body, "sha256="+signature), ShouldBeTrue)
+		So(VerifySignature("other", 1552640400, body, signature), ShouldBeFalse)
+		So(VerifySignature("secret", 1552640401, body, signature), ShouldBeFalse)
+		So(VerifySignature("secret", 1552640400, []byte(`{}`), signature), ShouldBeFalse)
+		So(newSecret(), ShouldNotEqual, newSecret())
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	Convey("Testing the delays between delivery attempts", t, func() {
+		So(backoff(1), ShouldEqual, time.Minute)
+		So(backoff(2), ShouldEqual, 2*time.Minute)
+		So(backoff(5), ShouldEqual, 16*time.Minute)
+		So(backoff(20), ShouldEqual, 24*time.Hour)
+	})
+}
+
+func TestPayloadValue(t *testing.T) {
+	Convey("Testing payload values", t, func() {
+		So(payloadValue("draft"), ShouldEqual, "draft")
+		So(payloadValue(int64(3)), ShouldEqual, 3)
+		So(payloadValue(nil), ShouldBeNil)
+	})
+}
+
+func TestSend(t *testing.T) {
+	Convey("Testing the delivery of payloads", t, func() {
+		var (
+			status  = http.StatusOK
+			headers http.Header
+			body    []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = r.Header
+			body, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(status)
+			w.Write([]byte("received"))
+		}))
+		defer server.Close()
+		d := delivery{id: 7, event: EventCreate, payload: `{"event":"create"}`, url: server.URL, secret: "secret"}
+		Convey("Payloads should be posted with their signature", func() {
+			res := send(d)
+			So(res.err, ShouldBeNil)
+			So(res.status, ShouldEqual, http.StatusOK)
+			So(res.response, ShouldEqual, "received")
+			So(string(body), ShouldEqual, d.payload)
+			So(headers.Get("Content-Type"), ShouldEqual, "application/json")
+			So(headers.Get(HeaderEvent), ShouldEqual, EventCreate)
+			So(headers.Get(HeaderDelivery), ShouldEqual, "7")
+			timestamp, err := strconv.ParseInt(headers.Get(HeaderTimestamp), 10, 64)
+			So(err, ShouldBeNil)
+			So(VerifySignature("secret", timestamp, body, headers.Get(HeaderSignature)), ShouldBeTrue)
+		})
+		Convey("Non 2xx responses should be errors", func() {
+			status = http.StatusServiceUnavailable
+			res := send(d)
+			So(res.err, ShouldNotBeNil)
+			So(res.status, ShouldEqual, http.StatusServiceUnavailable)
+			So(res.response, ShouldEqual, "received")
+		})
+		Convey("Unreachable URLs should be errors", func() {
+			server.Close()
+			res := send(d)
+			So(res.err, ShouldNotBeNil)
+			So(res.status, ShouldEqual, 0)
+		})
+	})
+}
+
+func TestInboundSignatures(t *testing.T) {
+	Convey("Testing the signature verifiers of inbound endpoints", t, func() {
+		body := []byte(`{"status":"paid"}`)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		sum := mac.Sum(nil)
+		Convey("HMAC signatures should be checked in hex and base64", func() {
+			So(VerifyHMAC(sha256.New, "secret", body, hex.EncodeToString(sum)), ShouldBeTrue)
+			So(VerifyHMAC(sha256.New, "secret", body, base64.StdEncoding.EncodeToString(sum)), ShouldBeTrue)
+			So(VerifyHMAC(sha256.New, "other", body, hex.EncodeToString(sum)), ShouldBeFalse)
+			So(VerifyHMAC(sha256.New, "secret", body, "invalid"), ShouldBeFalse)
+		})
+		Convey("HMAC verifiers should read the signature header", func() {
+			verify := HMACVerifier(sha256.New, "X-Signature", "sha256=")
+			req := &InboundRequest{Header: http.Header{}, Body: body}
+			So(verify(req, "secret"), ShouldBeFalse)
+			req.Header.Set("X-Signature", hex.EncodeToString(sum))
+			So(verify(req, "secret"), ShouldBeFalse)
+			req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(sum))
+			So(verify(req, "secret"), ShouldBeTrue)
+			So(verify(req, "other"), ShouldBeFalse)
+		})
+		Convey("Hexya signatures should be checked with their timestamp", func() {
+			req := &InboundRequest{Header: http.Header{}, Body: body}
+			sign := func(timestamp int64) {
+				req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+				req.Header.Set(HeaderSignature, "sha256="+Sign("secret", timestamp, body))
+			}
+			So(HexyaVerifier(req, "secret"), ShouldBeFalse)
+			sign(time.Now().Unix())
+			So(HexyaVerifier(req, "secret"), ShouldBeTrue)
+			So(HexyaVerifier(req, "other"), ShouldBeFalse)
+			sign(time.Now().Add(-time.Hour).Unix())
+			So(HexyaVerifier(req, "secret"), ShouldBeFalse)
+		})
+	})
+}
+
+func TestRegisterEndpoint(t *testing.T) {
+	Convey("Testing the registration of inbound endpoints", t, func() {
+		handler := func(env models.Environment, req *InboundRequest) interface{} { return nil }
+		RegisterEndpoint(Endpoint{Name: "test_endpoint", Handler: handler, Verify: HexyaVerifier})
+		endpoint, ok := getEndpoint("test_endpoint")
+		So(ok, ShouldBeTrue)
+		So(endpoint.Verify, ShouldNotBeNil)
+		_, ok = getEndpoint("unknown")
+		So(ok, ShouldBeFalse)
+		So(func() { RegisterEndpoint(Endpoint{Name: "test_endpoint", Handler: handler}) }, ShouldPanic)
+		So(func() { RegisterEndpoint(Endpoint{Name: "no_handler"}) }, ShouldPanic)
+	})
+}
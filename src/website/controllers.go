@@ -0,0 +1,97 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package website
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/security"
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/settings"
+)
+
+// servePage is the controller of the pages of the website. It renders the
+// page whose URL is the path of the request. Pages that are not visible to
+// visitors can be previewed by logged in users.
+func servePage(ctx *server.Context) {
+	uid, _ := ctx.Session().Get("uid").(int64)
+	var (
+		res   []byte
+		found bool
+	)
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		page := FindPage(env, ctx.Param("url"))
+		if page.IsEmpty() || (uid == 0 && !visiblePage(page)) {
+			return
+		}
+		found = true
+		var rErr error
+		res, rErr = Render(page)
+		if rErr != nil {
+			log.Panic("Unable to render page", "url", ctx.Param("url"), "error", rErr)
+		}
+	})
+	if err != nil {
+		log.Warn("Unable to serve page", "url", ctx.Param("url"), "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", res)
+}
+
+// baseURL returns the web.base.url configuration parameter, or
+// the base URL of the given request if it is not set
+func baseURL(ctx *server.Context, env models.Environment) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	return settings.GetParam(env, "web.base.url", fmt.Sprintf("%s://%s", scheme, ctx.Request.Host))
+}
+
+// sitemap is the controller of sitemap.xml
+func sitemap(ctx *server.Context) {
+	var res []byte
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		var sErr error
+		res, sErr = SitemapXML(SitemapEntries(env, baseURL(ctx, env)))
+		if sErr != nil {
+			log.Panic("Unable to generate sitemap", "error", sErr)
+		}
+	})
+	if err != nil {
+		log.Warn("Unable to serve sitemap", "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", res)
+}
+
+// robots is the controller of robots.txt
+func robots(ctx *server.Context) {
+	var res string
+	err := ctx.ExecuteInNewEnvironment(security.SuperUserID, func(env models.Environment) {
+		res = Robots(env, baseURL(ctx, env))
+	})
+	if err != nil {
+		log.Warn("Unable to serve robots.txt", "error", err)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.String(http.StatusOK, res)
+}
+
+func init() {
+	grp := controllers.Registry.AddGroup(PathPrefix)
+	grp.AddController(http.MethodGet, "/*url", servePage)
+	controllers.Registry.AddController(http.MethodGet, "/sitemap.xml", sitemap)
+	controllers.Registry.AddController(http.MethodGet, "/robots.txt", robots)
+}
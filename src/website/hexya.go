@@ -0,0 +1,46 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+// Package website is a Hexya module that serves simple public web pages.
+//
+// A Page holds a QWeb template of its content in its Arch field. It is served
+// under PathPrefix at its URL, which defaults to the slug of its title:
+//
+//	/website/about-us
+//
+// Pages are visible to visitors once published with Publish, from their
+// publishing date. Unpublished pages can be previewed by logged in users.
+//
+// The content of a page is rendered within a layout template, given by the
+// Layout field of the page. The default layout is the website_layout template,
+// which displays the WebsiteMenu records. Modules customize it by extending it,
+// or define new layouts as named extensions:
+//
+//	<template id="shop_layout" inherit_id="website_layout">
+//		<footer name="footer" position="inside">...</footer>
+//	</template>
+//
+// The website also serves sitemap.xml, which lists the visible pages and the
+// routes added to the sitemap with controllers.Group.AddToSitemap, and
+// robots.txt, whose content can be set in the website.robots_txt parameter.
+package website
+
+import (
+	"github.com/hexya-erp/hexya/src/server"
+	"github.com/hexya-erp/hexya/src/tools/logging"
+)
+
+var log logging.Logger
+
+// Module data declaration
+const (
+	MODULE_NAME string = "website"
+)
+
+func init() {
+	log = logging.GetLogger("website")
+	declareModels()
+	server.RegisterModule(&server.Module{
+		Name: MODULE_NAME,
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package website
+
+import (
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/fields"
+)
+
+func declareModels() {
+	page := models.NewModel("Page")
+	page.SetDefaultOrder("URL")
+	page.NewMethod("CheckURL", page_CheckURL)
+	page.NewMethod("Publish", page_Publish)
+	page.NewMethod("Unpublish", page_Unpublish)
+	page.NewMethod("Render", page_Render)
+	page.Methods().MustGet("Create").Extend(page_Create)
+	page.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Title", Required: true, Translate: true},
+		"URL": fields.Char{Unique: true, NoCopy: true, Constraint: page.Methods().MustGet("CheckURL"),
+			Help: "Path of the page, e.g. /about-us. It defaults to the slug of the title."},
+		"Arch": fields.Text{String: "Content", Translate: true,
+			Help: "QWeb template of the content of the page"},
+		"Layout": fields.Char{Help: "ID of the template of the layout of the page. The default layout is used if empty."},
+		"Published": fields.Boolean{Index: true, NoCopy: true,
+			Help: "Whether the page is visible to visitors"},
+		"PublishDate": fields.DateTime{String: "Publishing Date", NoCopy: true,
+			Help: "Time from which a published page is visible to visitors"},
+		"Active": fields.Boolean{Default: models.DefaultValue(true)},
+	})
+
+	menu := models.NewModel("WebsiteMenu")
+	menu.SetDefaultOrder("Sequence", "ID")
+	menu.AddFields(map[string]models.FieldDefinition{
+		"Name": fields.Char{String: "Menu", Required: true, Translate: true},
+		"Page": fields.Many2One{RelationModel: page, OnDelete: models.Cascade,
+			Help: "Page to which the menu links. The menu is hidden while the page is not visible."},
+		"URL":      fields.Char{Help: "Address to which the menu links if it does not link to a page"},
+		"Sequence": fields.Integer{Default: models.DefaultValue(10)},
+		"Parent":   fields.Many2One{String: "Parent Menu", RelationModel: menu, Index: true, OnDelete: models.Cascade},
+		"Children": fields.One2Many{String: "Child Menus", RelationModel: menu, ReverseFK: "Parent"},
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package website
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/hexya-erp/hexya/src/controllers"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/settings"
+)
+
+// RobotsParam is the configuration parameter holding the content of
+// robots.txt. If it is not set, robots are allowed everywhere but in the
+// web client, and pointed to the sitemap.
+const RobotsParam = "website.robots_txt"
+
+// sitemapNamespace is the XML namespace of sitemaps
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// A SitemapEntry is a URL listed in the sitemap of the website
+type SitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// sitemapURLSet is the root element of sitemaps
+type sitemapURLSet struct {
+	XMLName xml.Name       `xml:"urlset"`
+	XMLNS   string         `xml:"xmlns,attr"`
+	URLs    []SitemapEntry `xml:"url"`
+}
+
+// SitemapEntries returns the entries of the sitemap of the website, that is
+// the pages that are visible to visitors and the routes added to the sitemap
+// with controllers.Group.AddToSitemap. baseURL is prepended to all paths.
+func SitemapEntries(env models.Environment, baseURL string) []SitemapEntry {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	var res []SitemapEntry
+	for _, p := range controllers.Registry.SitemapPaths() {
+		res = append(res, SitemapEntry{Loc: baseURL + p})
+	}
+	pages := env.Pool("Page").Sudo()
+	mi := pages.Model()
+	pages = pages.Search(mi.Field(mi.FieldName("Published")).Equals(true).
+		And().Field(mi.FieldName("Active")).Equals(true))
+	for _, page := range pages.Records() {
+		if !visiblePage(page) {
+			continue
+		}
+		res = append(res, SitemapEntry{
+			Loc:     baseURL + PageURL(page.Get(mi.FieldName("URL")).(string)),
+			LastMod: page.Get(mi.FieldName("LastUpdate")).(dates.DateTime).Format("2006-01-02"),
+		})
+	}
+	return res
+}
+
+// SitemapXML returns the sitemap XML document listing the given entries
+func SitemapXML(entries []SitemapEntry) ([]byte, error) {
+	res, err := xml.MarshalIndent(sitemapURLSet{XMLNS: sitemapNamespace, URLs: entries}, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), res...), nil
+}
+
+// Robots returns the content of robots.txt, given by the RobotsParam
+// configuration parameter. baseURL is the base URL of the sitemap.
+func Robots(env models.Environment, baseURL string) string {
+	if robots := settings.GetParam(env, RobotsParam, ""); robots != "" {
+		return robots
+	}
+	return fmt.Sprintf("User-agent: *\nDisallow: /web/\nSitemap: %s/sitemap.xml\n", strings.TrimSuffix(baseURL, "/"))
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package website
+
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/hexya-erp/hexya/src/i18n/format"
+	"github.com/hexya-erp/hexya/src/models"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/templates"
+	"github.com/hexya-erp/hexya/src/tools/hweb"
+)
+
+// DefaultLayoutID is the ID of the template of the
+// layout of the pages that do not define their own
+const DefaultLayoutID = "website_layout"
+
+// PathPrefix is the path under which pages are served
+const PathPrefix = "/website"
+
+// Slug returns the given text lowercased, with the
+// sequences of characters other than letters and digits
+// replaced by a dash, so that it can be used in URLs.
+func Slug(text string) string {
+	var res strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && res.Len() > 0 {
+				res.WriteRune('-')
+			}
+			res.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return res.String()
+}
+
+// NormalizeURL returns the given page URL as it is stored, that is
+// with a leading slash and each of its segments slugified.
+func NormalizeURL(url string) string {
+	var segments []string
+	for _, segment := range strings.Split(url, "/") {
+		if segment = Slug(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// PageURL returns the address at which the page
+// with the given URL is served
+func PageURL(url string) string {
+	return path.Join(PathPrefix, url)
+}
+
+// Visible returns true if a page with the given publication
+// values is visible to visitors at the given time
+func Visible(published bool, publishDate, now dates.DateTime) bool {
+	return published && (publishDate.IsZero() || publishDate.LowerEqual(now))
+}
+
+// visiblePage returns true if the given page is visible to visitors
+func visiblePage(page *models.RecordCollection) bool {
+	mi := page.Model()
+	return Visible(page.Get(mi.FieldName("Published")).(bool), page.Get(mi.FieldName("PublishDate")).(dates.DateTime), dates.Now())
+}
+
+// FindPage returns the active page with the given URL,
+// or an empty RecordSet if there is none.
+func FindPage(env models.Environment, url string) *models.RecordCollection {
+	pages := env.Pool("Page")
+	mi := pages.Model()
+	return pages.Search(mi.Field(mi.FieldName("URL")).Equals(NormalizeURL(url)).
+		And().Field(mi.FieldName("Active")).Equals(true)).Limit(1)
+}
+
+// A MenuItem is an entry of the menu of the website
+type MenuItem struct {
+	Name     string
+	URL      string
+	Children []MenuItem
+}
+
+// menuItems returns the items of the given menus and of their children.
+// Menus linking to pages that are not visible are skipped.
+func menuItems(menus *models.RecordCollection) []MenuItem {
+	mi := menus.Model()
+	var res []MenuItem
+	for _, menu := range menus.Records() {
+		item := MenuItem{
+			Name: menu.Get(mi.FieldName("Name")).(string),
+			URL:  menu.Get(mi.FieldName("URL")).(string),
+		}
+		if page := menu.Get(mi.FieldName("Page")).(models.RecordSet).Collection(); !page.IsEmpty() {
+			if !visiblePage(page) {
+				continue
+			}
+			item.URL = PageURL(page.Get(page.Model().FieldName("URL")).(string))
+		}
+		item.Children = menuItems(menu.Get(mi.FieldName("Children")).(models.RecordSet).Collection())
+		res = append(res, item)
+	}
+	return res
+}
+
+// Menus returns the menu of the website
+func Menus(env models.Environment) []MenuItem {
+	menus := env.Pool("WebsiteMenu").Sudo()
+	mi := menus.Model()
+	return menuItems(menus.Search(mi.Field(mi.FieldName("Parent")).IsNull()))
+}
+
+// Render renders the given page within its layout.
+//
+// The content of the page and its layout are rendered with the page as 'page',
+// its title as 'title' and the items of the menu of the website as 'menus'. The
+// rendered content of the page is given to the layout as 'body'.
+func Render(page *models.RecordCollection) ([]byte, error) {
+	page.EnsureOne()
+	mi := page.Model()
+	env := page.Env()
+	formatter := format.NewFormatter(env)
+	context := hweb.Context{
+		"page":    page,
+		"title":   page.Get(mi.FieldName("Name")).(string),
+		"url":     PageURL(page.Get(mi.FieldName("URL")).(string)),
+		"menus":   Menus(env),
+		"lang":    env.Lang(),
+		"format":  formatter,
+		"t_field": formatter.Field,
+	}
+	tmpl, err := templates.Registry.FromQWebString(page.Get(mi.FieldName("Arch")).(string))
+	if err != nil {
+		return nil, err
+	}
+	body, err := tmpl.Execute(context)
+	if err != nil {
+		return nil, err
+	}
+	layoutID := page.Get(mi.FieldName("Layout")).(string)
+	if layoutID == "" {
+		layoutID = DefaultLayoutID
+	}
+	layout, err := templates.Registry.FromCache(path.Join(env.Lang(), layoutID))
+	if err != nil {
+		return nil, err
+	}
+	context["body"] = body
+	return layout.ExecuteBytes(context)
+}
+
+// page_Create sets the URL of the page from its title if it is not given
+func page_Create(rc *models.RecordCollection, data models.RecordData) *models.RecordCollection {
+	mi := rc.Model()
+	values := data.Underlying().Copy()
+	url, _ := values.Get(mi.FieldName("URL")).(string)
+	if url == "" {
+		url, _ = values.Get(mi.FieldName("Name")).(string)
+	}
+	values.Set(mi.FieldName("URL"), NormalizeURL(url))
+	return rc.Super().Call("Create", values).(models.RecordSet).Collection()
+}
+
+// CheckURL checks that the URLs of the pages are normalized
+func page_CheckURL(rc *models.RecordCollection) {
+	for _, rec := range rc.Records() {
+		url := rec.Get(rc.Model().FieldName("URL")).(string)
+		if url == "/" || url != NormalizeURL(url) {
+			log.Panic("Invalid page URL", "page", rec.Ids()[0], "url", url)
+		}
+	}
+}
+
+// Publish makes the pages of this RecordSet visible to visitors.
+// Pages without publishing date are published now.
+func page_Publish(rc *models.RecordCollection) {
+	mi := rc.Model()
+	for _, rec := range rc.Records() {
+		data := models.NewModelData(mi).Set(mi.FieldName("Published"), true)
+		if rec.Get(mi.FieldName("PublishDate")).(dates.DateTime).IsZero() {
+			data.Set(mi.FieldName("PublishDate"), dates.Now())
+		}
+		rec.Call("Write", data)
+	}
+}
+
+// Unpublish hides the pages of this RecordSet from visitors
+func page_Unpublish(rc *models.RecordCollection) {
+	rc.Set(rc.Model().FieldName("Published"), false)
+}
+
+// Render returns the HTML of this page within its layout
+func page_Render(rc *models.RecordCollection) string {
+	res, err := Render(rc)
+	if err != nil {
+		log.Panic("Unable to render page", "page", rc.Ids()[0], "error", err)
+	}
+	return string(res)
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 NDP Systèmes. All Rights Reserved.
+// See LICENSE file for full licensing details.
+
+package website
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/hexya-erp/hexya/src/models/types/dates"
+	"github.com/hexya-erp/hexya/src/templates"
+	"github.com/hexya-erp/hexya/src/tools/hweb"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWebsite(t *testing.T) {
+	Convey("Testing website pages", t, func() {
+		Convey("Slugs should only keep letters and digits", func() {
+			So(Slug("About us!"), ShouldEqual, "about-us")
+			So(Slug("  Été 2019 -- Offers "), ShouldEqual, "été-2019-offers")
+			So(Slug("?!"), ShouldBeEmpty)
+		})
+		Convey("URLs should be normalized segment by segment", func() {
+			So(NormalizeURL("Company/Our Team/"), ShouldEqual, "/company/our-team")
+			So(NormalizeURL("//about-us"), ShouldEqual, "/about-us")
+			So(PageURL("/about-us"), ShouldEqual, "/website/about-us")
+		})
+		Convey("Pages should be visible from their publishing date", func() {
+			now := dates.DateTime{Time: time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)}
+			So(Visible(false, dates.DateTime{}, now), ShouldBeFalse)
+			So(Visible(true, dates.DateTime{}, now), ShouldBeTrue)
+			So(Visible(true, now.AddDate(0, 0, -1), now), ShouldBeTrue)
+			So(Visible(true, now.AddDate(0, 0, 1), now), ShouldBeFalse)
+		})
+		Convey("Sitemaps should list the given entries", func() {
+			res, err := SitemapXML([]SitemapEntry{
+				{Loc: "http://example.com/shop"},
+				{Loc: "http://example.com/website/about-us", LastMod: "2019-06-01"},
+			})
+			So(err, ShouldBeNil)
+			So(string(res), ShouldStartWith, `<?xml version="1.0" encoding="UTF-8"?>`)
+			So(string(res), ShouldContainSubstring, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+			So(string(res), ShouldContainSubstring, "<url>\n\t\t<loc>http://example.com/shop</loc>\n\t</url>")
+			So(string(res), ShouldContainSubstring, "<lastmod>2019-06-01</lastmod>")
+		})
+		Convey("The default layout should display the menus and the body", func() {
+			content, err := ioutil.ReadFile("resources/website.xml")
+			So(err, ShouldBeNil)
+			doc := etree.NewDocument()
+			So(doc.ReadFromBytes(content), ShouldBeNil)
+			for _, tmpl := range doc.FindElements("hexya/data/template") {
+				templates.LoadFromEtree(tmpl)
+			}
+			templates.BootStrap()
+			layout, err := templates.Registry.FromCache(DefaultLayoutID)
+			So(err, ShouldBeNil)
+			res, err := layout.Execute(hweb.Context{
+				"title": "About us",
+				"lang":  "en",
+				"body":  "<p>Hello</p>",
+				"menus": []MenuItem{{Name: "Company", URL: "/website/company",
+					Children: []MenuItem{{Name: "Team", URL: "/website/company/team"}}}},
+			})
+			So(err, ShouldBeNil)
+			So(res, ShouldContainSubstring, "<title>About us</title>")
+			So(res, ShouldContainSubstring, `<a href="/website/company/team">Team</a>`)
+			So(res, ShouldContainSubstring, "<main><p>Hello</p></main>")
+		})
+	})
+}